@@ -3,6 +3,7 @@ package cache
 
 import (
 	"context"
+	"io"
 	"reflect"
 	"strings"
 	"sync"
@@ -177,6 +178,56 @@ func (c *Client) SetRedisClient(cli redisClient) {
 	c.Unlock()
 }
 
+// Close closes the connections of the client to the cache servers, and stops
+// the discovery of memcache servers.
+func (c *Client) Close() error {
+	c.Lock()
+	defer c.Unlock()
+
+	if mc, ok := c.mc.(*memcache.Client); ok && mc.StopPolling != nil {
+		mc.StopPolling()
+	}
+
+	if rc, ok := c.rc.(io.Closer); ok {
+		if err := rc.Close(); err != nil {
+			return errors.Wrap(err, errors.ErrCache,
+				"unable to close cache client")
+		}
+	}
+
+	return nil
+}
+
+// Ping checks that the cache servers can be reached. Both memcache and redis
+// servers are checked, if connected, and the errors of each are returned.
+func (c *Client) Ping(ctx context.Context) error {
+	c.RLock()
+
+	rc, mc := c.rc, c.mc
+
+	c.RUnlock()
+
+	var errs []error
+
+	if p, ok := mc.(interface{ Ping() error }); ok {
+		if err := p.Ping(); err != nil {
+			errs = append(errs, errors.Wrap(err, errors.ErrCache,
+				"unable to reach memcache servers"))
+		}
+	}
+
+	if p, ok := rc.(interface {
+		Ping(ctx context.Context) *redis.StatusCmd
+	}); ok {
+		if err := p.Ping(ctx).Err(); err != nil {
+			errs = append(errs, errors.Wrap(err, errors.ErrCache,
+				"unable to reach redis servers"))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
 // Get attempts to retrieve the value of the specified key.
 func (c *Client) Get(ctx context.Context, key string) (*Item, error) {
 	c.RLock()
@@ -8,6 +8,7 @@ import (
 
 	"github.com/dhaifley/game2d/cache"
 	"github.com/dhaifley/game2d/config"
+	"github.com/dhaifley/game2d/errors"
 	"github.com/google/gomemcache/memcache"
 	"github.com/redis/go-redis/v9"
 )
@@ -53,7 +54,31 @@ func (m *mockMemcacheClient) Delete(key string) error {
 	return nil
 }
 
-type mockRedisClient struct{}
+type mockPingMemcacheClient struct {
+	mockMemcacheClient
+	pingErr error
+}
+
+func (m *mockPingMemcacheClient) Ping() error {
+	return m.pingErr
+}
+
+type mockRedisClient struct {
+	closeErr error
+	pingErr  error
+}
+
+func (m *mockRedisClient) Close() error {
+	return m.closeErr
+}
+
+func (m *mockRedisClient) Ping(ctx context.Context) *redis.StatusCmd {
+	cmd := redis.NewStatusCmd(ctx)
+
+	cmd.SetErr(m.pingErr)
+
+	return cmd
+}
 
 func (m *mockRedisClient) Get(ctx context.Context,
 	key string,
@@ -194,4 +219,91 @@ func TestClient(t *testing.T) {
 	if err != nil {
 		t.Errorf("Unexpected error from delete: %v", err.Error())
 	}
+
+	if err := mp.Ping(context.Background()); err != nil {
+		t.Errorf("Unexpected error from ping: %v", err.Error())
+	}
+
+	mp.SetRedisClient(&mockRedisClient{
+		closeErr: redis.ErrClosed,
+		pingErr:  redis.ErrClosed,
+	})
+
+	if err := mp.Ping(context.Background()); err == nil {
+		t.Error("Expected ping error, got: nil")
+	}
+
+	if err := mp.Close(); err == nil {
+		t.Error("Expected close error, got: nil")
+	}
+}
+
+func TestClientPing(t *testing.T) {
+	t.Parallel()
+
+	errMemcache := fmt.Errorf("memcache unavailable")
+	errRedis := fmt.Errorf("redis unavailable")
+
+	tests := []struct {
+		name   string
+		mcErr  error
+		rcErr  error
+		expErr []error
+	}{{
+		name: "available",
+	}, {
+		name:   "memcache unavailable",
+		mcErr:  errMemcache,
+		expErr: []error{errMemcache},
+	}, {
+		name:   "redis unavailable",
+		rcErr:  errRedis,
+		expErr: []error{errRedis},
+	}, {
+		name:   "both unavailable",
+		mcErr:  errMemcache,
+		rcErr:  errRedis,
+		expErr: []error{errMemcache, errRedis},
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			cfg := &config.Config{}
+
+			cfg.SetCache(&config.CacheConfig{
+				Type:       cache.CacheTypeMemcache,
+				Servers:    []string{"localhost:11211"},
+				Expiration: time.Second,
+			})
+
+			mp := cache.NewClient(cfg, nil, nil, nil)
+			if mp == nil {
+				t.Fatal("Unable to initialize cache client")
+			}
+
+			mp.SetMemcacheClient(&mockPingMemcacheClient{pingErr: tt.mcErr})
+			mp.SetRedisClient(&mockRedisClient{pingErr: tt.rcErr})
+
+			err := mp.Ping(context.Background())
+			if len(tt.expErr) == 0 {
+				if err != nil {
+					t.Errorf("Unexpected error from ping: %v", err)
+				}
+
+				return
+			}
+
+			if !errors.Has(err, errors.ErrCache) {
+				t.Errorf("Expected cache error, got: %v", err)
+			}
+
+			for _, exp := range tt.expErr {
+				if !errors.Is(err, exp) {
+					t.Errorf("Expected error: %v, got: %v", exp, err)
+				}
+			}
+		})
+	}
 }
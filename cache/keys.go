@@ -24,3 +24,8 @@ func KeyToken(token string) string {
 func KeyGame(id string) string {
 	return "Game::" + id
 }
+
+// KeyGameStats returns a cache key to be used for game statistics values.
+func KeyGameStats(id string) string {
+	return "Game::Stats::" + id
+}
@@ -33,6 +33,10 @@ func TestCacheKeys(t *testing.T) {
 			exp: "Game::test",
 			run: func() string { return cache.KeyGame("test") },
 		},
+		{
+			exp: "Game::Stats::test",
+			run: func() string { return cache.KeyGameStats("test") },
+		},
 	}
 
 	for _, tt := range tests {
@@ -0,0 +1,303 @@
+// Package cbor implements encoding and decoding of the Concise Binary Object
+// Representation (CBOR) data format, defined in RFC 8949. Values are mapped to
+// and from CBOR in the same way encoding/json maps them to and from JSON, and
+// struct field names are taken from json tags, unless a cbor tag is present.
+package cbor
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/dhaifley/game2d/errors"
+)
+
+// ContentType is the media type of CBOR encoded data.
+const ContentType = "application/cbor"
+
+// CBOR major types.
+const (
+	majorUint   = 0
+	majorNegInt = 1
+	majorBytes  = 2
+	majorText   = 3
+	majorArray  = 4
+	majorMap    = 5
+	majorTag    = 6
+	majorSimple = 7
+)
+
+// CBOR simple values.
+const (
+	simpleFalse     = 0xf4
+	simpleTrue      = 0xf5
+	simpleNull      = 0xf6
+	simpleUndefined = 0xf7
+	simpleFloat32   = 0xfa
+	simpleFloat64   = 0xfb
+)
+
+// Marshaler values can encode themselves into CBOR.
+type Marshaler interface {
+	MarshalCBOR() ([]byte, error)
+}
+
+// Unmarshaler values can decode a CBOR data item into themselves.
+type Unmarshaler interface {
+	UnmarshalCBOR(data []byte) error
+}
+
+var (
+	marshalerType   = reflect.TypeFor[Marshaler]()
+	unmarshalerType = reflect.TypeFor[Unmarshaler]()
+)
+
+// field values describe how a struct field is encoded.
+type field struct {
+	name      string
+	index     []int
+	omitEmpty bool
+}
+
+// fieldCache contains the encoded fields of struct types.
+var fieldCache sync.Map
+
+// structFields returns the encoded fields of a struct type. Fields of embedded
+// structs without tags are promoted, as they are by encoding/json.
+func structFields(t reflect.Type) []field {
+	if v, ok := fieldCache.Load(t); ok {
+		return v.([]field)
+	}
+
+	var res []field
+
+	for i := range t.NumField() {
+		sf := t.Field(i)
+
+		tag, ok := sf.Tag.Lookup("cbor")
+		if !ok {
+			tag = sf.Tag.Get("json")
+		}
+
+		if tag == "-" {
+			continue
+		}
+
+		name, opts, _ := strings.Cut(tag, ",")
+
+		if sf.Anonymous && name == "" {
+			ft := sf.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+
+			if ft.Kind() == reflect.Struct {
+				for _, f := range structFields(ft) {
+					f.index = append([]int{i}, f.index...)
+					res = append(res, f)
+				}
+
+				continue
+			}
+		}
+
+		if !sf.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = sf.Name
+		}
+
+		res = append(res, field{
+			name:      name,
+			index:     []int{i},
+			omitEmpty: slices.Contains(strings.Split(opts, ","), "omitempty"),
+		})
+	}
+
+	fieldCache.Store(t, res)
+
+	return res
+}
+
+// fieldByIndex returns a struct field, allocating any nil embedded pointers
+// when alloc is true. An invalid value is returned if an embedded pointer is
+// nil and alloc is false.
+func fieldByIndex(v reflect.Value, index []int, alloc bool) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				if !alloc {
+					return reflect.Value{}
+				}
+
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+
+			v = v.Elem()
+		}
+
+		v = v.Field(x)
+	}
+
+	return v
+}
+
+// isEmpty returns whether a value is empty, for the purpose of omitting it.
+func isEmpty(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32,
+		reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64,
+		reflect.Interface, reflect.Pointer:
+		return v.IsZero()
+	default:
+		return false
+	}
+}
+
+// appendHead appends the head of a data item, consisting of its major type and
+// argument, to a byte slice.
+func appendHead(b []byte, major byte, n uint64) []byte {
+	major <<= 5
+
+	switch {
+	case n < 24:
+		return append(b, major|byte(n))
+	case n <= math.MaxUint8:
+		return append(b, major|24, byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, major|25), uint16(n))
+	case n <= math.MaxUint32:
+		return binary.BigEndian.AppendUint32(append(b, major|26), uint32(n))
+	default:
+		return binary.BigEndian.AppendUint64(append(b, major|27), n)
+	}
+}
+
+// maxPooledSize is the largest encoder buffer, in bytes, which is reused, so
+// that the pool does not retain the memory used to encode very large values.
+const maxPooledSize = 16 * 1024 * 1024
+
+// encoderPool contains encoders whose buffers can be reused.
+var encoderPool = sync.Pool{New: func() any { return &encoder{} }}
+
+// getEncoder returns an encoder, with an empty buffer, from the pool.
+func getEncoder() *encoder {
+	e := encoderPool.Get().(*encoder)
+
+	e.buf = e.buf[:0]
+
+	return e
+}
+
+// putEncoder returns an encoder to the pool.
+func putEncoder(e *encoder) {
+	if cap(e.buf) <= maxPooledSize {
+		encoderPool.Put(e)
+	}
+}
+
+// Marshal returns the CBOR encoding of a value.
+func Marshal(v any) ([]byte, error) {
+	e := getEncoder()
+	defer putEncoder(e)
+
+	if err := e.encode(reflect.ValueOf(v)); err != nil {
+		return nil, err
+	}
+
+	return bytes.Clone(e.buf), nil
+}
+
+// Encoder values write CBOR encoded values to an output stream.
+type Encoder struct {
+	w io.Writer
+}
+
+// NewEncoder returns a new encoder that writes to w.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: w}
+}
+
+// Encode writes the CBOR encoding of a value to the stream. The encoding is
+// written using a reused buffer, rather than one allocated for each value, and
+// the elements of slices are written as they are encoded, so that large arrays
+// are not held in memory.
+func (enc *Encoder) Encode(v any) error {
+	e := getEncoder()
+	defer putEncoder(e)
+
+	rv := reflect.ValueOf(v)
+
+	if rv.Kind() != reflect.Slice || rv.IsNil() ||
+		rv.Type().Elem().Kind() == reflect.Uint8 ||
+		rv.Type().Implements(marshalerType) {
+		if err := e.encode(rv); err != nil {
+			return err
+		}
+
+		return enc.flush(e)
+	}
+
+	e.buf = appendHead(e.buf, majorArray, uint64(rv.Len()))
+
+	for i := range rv.Len() {
+		if err := e.encode(rv.Index(i)); err != nil {
+			return err
+		}
+
+		if err := enc.flush(e); err != nil {
+			return err
+		}
+	}
+
+	return enc.flush(e)
+}
+
+// flush writes the contents of an encoder buffer to the stream, and empties
+// the buffer.
+func (enc *Encoder) flush(e *encoder) error {
+	if len(e.buf) == 0 {
+		return nil
+	}
+
+	_, err := enc.w.Write(e.buf)
+
+	e.buf = e.buf[:0]
+
+	return err
+}
+
+// Unmarshal decodes CBOR data into the value pointed to by v.
+func Unmarshal(data []byte, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return errors.New(errors.ErrInvalidRequest,
+			"unable to decode CBOR into non-pointer value")
+	}
+
+	d := &decoder{data: data}
+
+	if err := d.decode(rv.Elem()); err != nil {
+		return err
+	}
+
+	if d.off != len(d.data) {
+		return errors.New(errors.ErrInvalidRequest,
+			"invalid CBOR data after top-level value",
+			"offset", d.off)
+	}
+
+	return nil
+}
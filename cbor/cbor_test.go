@@ -0,0 +1,284 @@
+package cbor_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/dhaifley/game2d/cbor"
+)
+
+type testStruct struct {
+	Name    string            `json:"name"`
+	Count   int64             `json:"count,omitempty"`
+	Ratio   float64           `json:"ratio"`
+	Enabled bool              `json:"enabled"`
+	Tags    []string          `json:"tags"`
+	Data    map[string]any    `json:"data"`
+	Labels  map[string]string `json:"labels,omitempty"`
+	Raw     []byte            `json:"raw"`
+	Skip    string            `json:"-"`
+	Child   *testStruct       `json:"child,omitempty"`
+}
+
+type testMarshaler struct {
+	Value string
+}
+
+func (t testMarshaler) MarshalCBOR() ([]byte, error) {
+	return cbor.Marshal("m:" + t.Value)
+}
+
+func (t *testMarshaler) UnmarshalCBOR(b []byte) error {
+	var v string
+
+	if err := cbor.Unmarshal(b, &v); err != nil {
+		return err
+	}
+
+	t.Value = v[2:]
+
+	return nil
+}
+
+func TestMarshal(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		v    any
+		want string
+	}{{
+		name: "uint",
+		v:    uint64(1000000),
+		want: "1a000f4240",
+	}, {
+		name: "negative int",
+		v:    -100,
+		want: "3863",
+	}, {
+		name: "float",
+		v:    1.5,
+		want: "fa3fc00000",
+	}, {
+		name: "double",
+		v:    1.1,
+		want: "fb3ff199999999999a",
+	}, {
+		name: "text",
+		v:    "IETF",
+		want: "6449455446",
+	}, {
+		name: "bytes",
+		v:    []byte{1, 2, 3, 4},
+		want: "4401020304",
+	}, {
+		name: "array",
+		v:    []int{1, 2, 3},
+		want: "83010203",
+	}, {
+		name: "map",
+		v:    map[string]any{"b": []int{2, 3}, "a": 1},
+		want: "a26161016162820203",
+	}, {
+		name: "nil",
+		v:    nil,
+		want: "f6",
+	}, {
+		name: "bool",
+		v:    true,
+		want: "f5",
+	}, {
+		name: "marshaler",
+		v:    testMarshaler{Value: "a"},
+		want: "636d3a61",
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			b, err := cbor.Marshal(tt.v)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := hex.EncodeToString(b); got != tt.want {
+				t.Errorf("Marshal() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	v := &testStruct{
+		Name:    "test",
+		Count:   -42,
+		Ratio:   0.25,
+		Enabled: true,
+		Tags:    []string{"a", "b"},
+		Data: map[string]any{
+			"x": float64(1),
+			"y": []any{"z", true, nil},
+		},
+		Raw:   []byte("raw"),
+		Skip:  "skip",
+		Child: &testStruct{Name: "child"},
+	}
+
+	b, err := cbor.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got testStruct
+
+	if err := cbor.Unmarshal(b, &got); err != nil {
+		t.Fatal(err)
+	}
+
+	v.Skip = ""
+
+	if !reflect.DeepEqual(v, &got) {
+		t.Errorf("Unmarshal() = %+v, want %+v", got, v)
+	}
+}
+
+func TestEncoder(t *testing.T) {
+	t.Parallel()
+
+	for _, v := range []any{
+		&testStruct{Name: "test", Tags: []string{"a", "b"}},
+		[]testStruct{{Name: "a"}, {Name: "b", Child: &testStruct{}}},
+		[]*testStruct{{Name: "a"}, nil},
+		[]byte("raw"),
+		[]string(nil),
+		[]any{},
+	} {
+		exp, err := cbor.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var buf bytes.Buffer
+
+		enc := cbor.NewEncoder(&buf)
+
+		for range 2 {
+			if err := enc.Encode(v); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if got, want := buf.Bytes(), append(exp, exp...); !bytes.Equal(got,
+			want) {
+			t.Errorf("Encode(%#v) = %x, want %x", v, got, want)
+		}
+	}
+}
+
+func TestUnmarshalMarshaler(t *testing.T) {
+	t.Parallel()
+
+	b, err := cbor.Marshal(map[string]testMarshaler{"k": {Value: "v"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got map[string]*testMarshaler
+
+	if err := cbor.Unmarshal(b, &got); err != nil {
+		t.Fatal(err)
+	}
+
+	if got["k"] == nil || got["k"].Value != "v" {
+		t.Errorf("Unmarshal() = %v, want v", got["k"])
+	}
+}
+
+func TestUnmarshalIntegralFloat(t *testing.T) {
+	t.Parallel()
+
+	b, err := cbor.Marshal([]float64{2, -3})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []int
+
+	if err := cbor.Unmarshal(b, &got); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(got, []int{2, -3}) {
+		t.Errorf("Unmarshal() = %v, want [2 -3]", got)
+	}
+
+	b, err = cbor.Marshal(1.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var i int
+
+	if err := cbor.Unmarshal(b, &i); err == nil {
+		t.Errorf("Expected error decoding 1.5 into int")
+	}
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		data string
+		v    any
+	}{{
+		name: "truncated",
+		data: "6449",
+		v:    new(string),
+	}, {
+		name: "type mismatch",
+		data: "6449455446",
+		v:    new(int),
+	}, {
+		name: "overflow",
+		data: "190100",
+		v:    new(int8),
+	}, {
+		name: "trailing data",
+		data: "0101",
+		v:    new(int),
+	}, {
+		name: "non-pointer",
+		data: "01",
+		v:    0,
+	}, {
+		name: "nested arrays",
+		data: strings.Repeat("81", 20000) + "00",
+		v:    new(any),
+	}, {
+		name: "nested tags",
+		data: strings.Repeat("c1", 20000) + "00",
+		v:    new(testStruct),
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			b, err := hex.DecodeString(tt.data)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err := cbor.Unmarshal(b, tt.v); err == nil {
+				t.Errorf("Expected error decoding %v", tt.data)
+			}
+		})
+	}
+}
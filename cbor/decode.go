@@ -0,0 +1,580 @@
+package cbor
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+
+	"github.com/dhaifley/game2d/errors"
+)
+
+// maxPrealloc limits the capacity preallocated for decoded arrays and maps, so
+// that invalid lengths can not exhaust memory.
+const maxPrealloc = 1024
+
+// maxDepth limits the nesting of decoded arrays, maps and tags, so that deeply
+// nested data can not exhaust the stack.
+const maxDepth = 10000
+
+// decoder values decode CBOR data.
+type decoder struct {
+	data  []byte
+	off   int
+	depth int
+}
+
+// nest increments the nesting depth of the data item being decoded, returning
+// an error if it exceeds the maximum depth. The returned function restores the
+// depth.
+func (d *decoder) nest() (func(), error) {
+	if d.depth++; d.depth > maxDepth {
+		d.depth--
+
+		return nil, errors.New(errors.ErrInvalidRequest,
+			"CBOR data exceeds maximum depth",
+			"max_depth", maxDepth,
+			"offset", d.off)
+	}
+
+	return func() { d.depth-- }, nil
+}
+
+// errEOF returns an error for unexpectedly truncated data.
+func (d *decoder) errEOF() error {
+	return errors.New(errors.ErrInvalidRequest,
+		"unexpected end of CBOR data",
+		"offset", d.off)
+}
+
+// errType returns an error for a data item which can not be decoded into a
+// value of a type.
+func (d *decoder) errType(major byte, t reflect.Type) error {
+	return errors.New(errors.ErrInvalidRequest,
+		"unable to decode CBOR major type into value",
+		"major_type", major,
+		"type", t.String(),
+		"offset", d.off)
+}
+
+// read returns the next n bytes of the data.
+func (d *decoder) read(n uint64) ([]byte, error) {
+	if n > uint64(len(d.data)-d.off) {
+		return nil, d.errEOF()
+	}
+
+	b := d.data[d.off : d.off+int(n)]
+
+	d.off += int(n)
+
+	return b, nil
+}
+
+// head reads the head of the next data item, returning its major type,
+// additional information, and argument.
+func (d *decoder) head() (byte, byte, uint64, error) {
+	if d.off >= len(d.data) {
+		return 0, 0, 0, d.errEOF()
+	}
+
+	ib := d.data[d.off]
+
+	d.off++
+
+	major, info := ib>>5, ib&0x1f
+
+	var size uint64
+
+	switch {
+	case info < 24:
+		return major, info, uint64(info), nil
+	case info == 24:
+		size = 1
+	case info == 25:
+		size = 2
+	case info == 26:
+		size = 4
+	case info == 27:
+		size = 8
+	default:
+		return 0, 0, 0, errors.New(errors.ErrInvalidRequest,
+			"unsupported CBOR additional information",
+			"info", info,
+			"offset", d.off-1)
+	}
+
+	b, err := d.read(size)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+
+	var n uint64
+
+	for _, v := range b {
+		n = n<<8 | uint64(v)
+	}
+
+	return major, info, n, nil
+}
+
+// skip advances past the next data item.
+func (d *decoder) skip() error {
+	done, err := d.nest()
+	if err != nil {
+		return err
+	}
+
+	defer done()
+
+	major, _, n, err := d.head()
+	if err != nil {
+		return err
+	}
+
+	switch major {
+	case majorBytes, majorText:
+		_, err = d.read(n)
+
+		return err
+	case majorArray, majorMap:
+		if major == majorMap {
+			n *= 2
+		}
+
+		for range n {
+			if err := d.skip(); err != nil {
+				return err
+			}
+		}
+	case majorTag:
+		return d.skip()
+	}
+
+	return nil
+}
+
+// float returns the floating point value of a simple data item.
+func float(info byte, n uint64) (float64, bool) {
+	switch info {
+	case 25:
+		return float16(uint16(n)), true
+	case 26:
+		return float64(math.Float32frombits(uint32(n))), true
+	case 27:
+		return math.Float64frombits(n), true
+	default:
+		return 0, false
+	}
+}
+
+// float16 converts a half precision floating point number.
+func float16(h uint16) float64 {
+	exp, mant := int(h>>10)&0x1f, float64(h&0x3ff)
+
+	var f float64
+
+	switch exp {
+	case 0:
+		f = math.Ldexp(mant, -24)
+	case 0x1f:
+		if mant == 0 {
+			f = math.Inf(1)
+		} else {
+			f = math.NaN()
+		}
+	default:
+		f = math.Ldexp(mant+1024, exp-25)
+	}
+
+	if h&0x8000 != 0 {
+		f = -f
+	}
+
+	return f
+}
+
+// decode decodes the next data item into a value.
+func (d *decoder) decode(v reflect.Value) error {
+	done, err := d.nest()
+	if err != nil {
+		return err
+	}
+
+	defer done()
+
+	if v.CanAddr() && reflect.PointerTo(v.Type()).Implements(unmarshalerType) {
+		start := d.off
+
+		if err := d.skip(); err != nil {
+			return err
+		}
+
+		return v.Addr().Interface().(Unmarshaler).
+			UnmarshalCBOR(d.data[start:d.off])
+	}
+
+	if d.off >= len(d.data) {
+		return d.errEOF()
+	}
+
+	if ib := d.data[d.off]; ib == simpleNull || ib == simpleUndefined {
+		d.off++
+
+		switch v.Kind() {
+		case reflect.Interface, reflect.Pointer, reflect.Map, reflect.Slice:
+			v.SetZero()
+		}
+
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+
+		return d.decode(v.Elem())
+	case reflect.Interface:
+		if v.NumMethod() > 0 {
+			return errors.New(errors.ErrInvalidRequest,
+				"unable to decode CBOR into non-empty interface",
+				"type", v.Type().String())
+		}
+
+		x, err := d.value()
+		if err != nil {
+			return err
+		}
+
+		if x == nil {
+			v.SetZero()
+		} else {
+			v.Set(reflect.ValueOf(x))
+		}
+
+		return nil
+	}
+
+	major, info, n, err := d.head()
+	if err != nil {
+		return err
+	}
+
+	switch major {
+	case majorUint:
+		return d.setNumber(v, major, n, false)
+	case majorNegInt:
+		return d.setNumber(v, major, n, true)
+	case majorBytes, majorText:
+		b, err := d.read(n)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case v.Kind() == reflect.String:
+			v.SetString(string(b))
+		case v.Kind() == reflect.Slice &&
+			v.Type().Elem().Kind() == reflect.Uint8:
+			v.SetBytes(append([]byte(nil), b...))
+		default:
+			return d.errType(major, v.Type())
+		}
+	case majorArray:
+		return d.decodeArray(v, n)
+	case majorMap:
+		switch v.Kind() {
+		case reflect.Map:
+			return d.decodeMap(v, n)
+		case reflect.Struct:
+			return d.decodeStruct(v, n)
+		default:
+			return d.errType(major, v.Type())
+		}
+	case majorTag:
+		return d.decode(v)
+	case majorSimple:
+		switch info {
+		case 20, 21:
+			if v.Kind() != reflect.Bool {
+				return d.errType(major, v.Type())
+			}
+
+			v.SetBool(info == 21)
+		default:
+			f, ok := float(info, n)
+			if !ok {
+				return d.errType(major, v.Type())
+			}
+
+			switch v.Kind() {
+			case reflect.Float32, reflect.Float64:
+				v.SetFloat(f)
+			default:
+				// As with JSON, integral floating point numbers can be
+				// decoded into integers.
+				if f != math.Trunc(f) || math.IsInf(f, 0) ||
+					math.Abs(f) >= 1<<63 {
+					return d.errType(major, v.Type())
+				}
+
+				if f < 0 {
+					return d.setNumber(v, major, uint64(-f)-1, true)
+				}
+
+				return d.setNumber(v, major, uint64(f), false)
+			}
+		}
+	}
+
+	return nil
+}
+
+// setNumber sets an integer or floating point value from an integer data item.
+func (d *decoder) setNumber(v reflect.Value,
+	major byte,
+	n uint64,
+	neg bool,
+) error {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Int64:
+		if n > math.MaxInt64 {
+			return d.errType(major, v.Type())
+		}
+
+		i := int64(n)
+		if neg {
+			i = -1 - i
+		}
+
+		if v.OverflowInt(i) {
+			return d.errType(major, v.Type())
+		}
+
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32,
+		reflect.Uint64, reflect.Uintptr:
+		if neg || v.OverflowUint(n) {
+			return d.errType(major, v.Type())
+		}
+
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f := float64(n)
+		if neg {
+			f = -1 - f
+		}
+
+		v.SetFloat(f)
+	default:
+		return d.errType(major, v.Type())
+	}
+
+	return nil
+}
+
+// decodeArray decodes an array of n items into a slice or array.
+func (d *decoder) decodeArray(v reflect.Value, n uint64) error {
+	switch v.Kind() {
+	case reflect.Slice:
+		v.Set(reflect.MakeSlice(v.Type(), 0, int(min(n, maxPrealloc))))
+
+		for range n {
+			ev := reflect.New(v.Type().Elem()).Elem()
+
+			if err := d.decode(ev); err != nil {
+				return err
+			}
+
+			v.Set(reflect.Append(v, ev))
+		}
+	case reflect.Array:
+		for i := range n {
+			if i >= uint64(v.Len()) {
+				if err := d.skip(); err != nil {
+					return err
+				}
+
+				continue
+			}
+
+			if err := d.decode(v.Index(int(i))); err != nil {
+				return err
+			}
+		}
+	default:
+		return d.errType(majorArray, v.Type())
+	}
+
+	return nil
+}
+
+// decodeMap decodes a map of n entries into a map.
+func (d *decoder) decodeMap(v reflect.Value, n uint64) error {
+	t := v.Type()
+
+	if v.IsNil() {
+		v.Set(reflect.MakeMapWithSize(t, int(min(n, maxPrealloc))))
+	}
+
+	for range n {
+		kv := reflect.New(t.Key()).Elem()
+
+		if err := d.decode(kv); err != nil {
+			return err
+		}
+
+		ev := reflect.New(t.Elem()).Elem()
+
+		if err := d.decode(ev); err != nil {
+			return err
+		}
+
+		v.SetMapIndex(kv, ev)
+	}
+
+	return nil
+}
+
+// decodeStruct decodes a map of n entries into the fields of a struct. Keys
+// which do not match a field are ignored.
+func (d *decoder) decodeStruct(v reflect.Value, n uint64) error {
+	fields := structFields(v.Type())
+
+	for range n {
+		var key string
+
+		if err := d.decode(reflect.ValueOf(&key).Elem()); err != nil {
+			return err
+		}
+
+		var f *field
+
+		for i := range fields {
+			if fields[i].name == key {
+				f = &fields[i]
+
+				break
+			}
+		}
+
+		if f == nil {
+			for i := range fields {
+				if strings.EqualFold(fields[i].name, key) {
+					f = &fields[i]
+
+					break
+				}
+			}
+		}
+
+		if f == nil {
+			if err := d.skip(); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		if err := d.decode(fieldByIndex(v, f.index, true)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// value decodes the next data item into a generic value. As with encoding/json,
+// numbers are decoded as float64, arrays as []any, and maps as map[string]any.
+func (d *decoder) value() (any, error) {
+	done, err := d.nest()
+	if err != nil {
+		return nil, err
+	}
+
+	defer done()
+
+	major, info, n, err := d.head()
+	if err != nil {
+		return nil, err
+	}
+
+	switch major {
+	case majorUint:
+		return float64(n), nil
+	case majorNegInt:
+		return -1 - float64(n), nil
+	case majorBytes:
+		b, err := d.read(n)
+		if err != nil {
+			return nil, err
+		}
+
+		return append([]byte(nil), b...), nil
+	case majorText:
+		b, err := d.read(n)
+		if err != nil {
+			return nil, err
+		}
+
+		return string(b), nil
+	case majorArray:
+		res := make([]any, 0, min(n, maxPrealloc))
+
+		for range n {
+			x, err := d.value()
+			if err != nil {
+				return nil, err
+			}
+
+			res = append(res, x)
+		}
+
+		return res, nil
+	case majorMap:
+		res := make(map[string]any, min(n, maxPrealloc))
+
+		for range n {
+			k, err := d.value()
+			if err != nil {
+				return nil, err
+			}
+
+			x, err := d.value()
+			if err != nil {
+				return nil, err
+			}
+
+			ks, ok := k.(string)
+			if !ok {
+				ks = fmt.Sprint(k)
+			}
+
+			res[ks] = x
+		}
+
+		return res, nil
+	case majorTag:
+		return d.value()
+	default:
+		switch info {
+		case 20:
+			return false, nil
+		case 21:
+			return true, nil
+		case 22, 23:
+			return nil, nil
+		}
+
+		if f, ok := float(info, n); ok {
+			return f, nil
+		}
+
+		return nil, errors.New(errors.ErrInvalidRequest,
+			"unsupported CBOR simple value",
+			"info", info,
+			"offset", d.off)
+	}
+}
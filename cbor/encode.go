@@ -0,0 +1,205 @@
+package cbor
+
+import (
+	"cmp"
+	"encoding/binary"
+	"math"
+	"reflect"
+	"slices"
+
+	"github.com/dhaifley/game2d/errors"
+)
+
+// encoder values encode values as CBOR.
+type encoder struct {
+	buf []byte
+}
+
+// encode appends the CBOR encoding of a value to the encoder buffer.
+func (e *encoder) encode(v reflect.Value) error {
+	if !v.IsValid() {
+		e.buf = append(e.buf, simpleNull)
+
+		return nil
+	}
+
+	if v.Type().Implements(marshalerType) {
+		if v.Kind() == reflect.Pointer && v.IsNil() {
+			e.buf = append(e.buf, simpleNull)
+
+			return nil
+		}
+
+		return e.marshal(v.Interface().(Marshaler))
+	}
+
+	if v.CanAddr() && reflect.PointerTo(v.Type()).Implements(marshalerType) {
+		return e.marshal(v.Addr().Interface().(Marshaler))
+	}
+
+	switch v.Kind() {
+	case reflect.Interface, reflect.Pointer:
+		if v.IsNil() {
+			e.buf = append(e.buf, simpleNull)
+
+			return nil
+		}
+
+		return e.encode(v.Elem())
+	case reflect.Bool:
+		if v.Bool() {
+			e.buf = append(e.buf, simpleTrue)
+		} else {
+			e.buf = append(e.buf, simpleFalse)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Int64:
+		if n := v.Int(); n >= 0 {
+			e.buf = appendHead(e.buf, majorUint, uint64(n))
+		} else {
+			e.buf = appendHead(e.buf, majorNegInt, uint64(-1-n))
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32,
+		reflect.Uint64, reflect.Uintptr:
+		e.buf = appendHead(e.buf, majorUint, v.Uint())
+	case reflect.Float32, reflect.Float64:
+		e.encodeFloat(v.Float())
+	case reflect.String:
+		e.buf = appendHead(e.buf, majorText, uint64(v.Len()))
+		e.buf = append(e.buf, v.String()...)
+	case reflect.Slice:
+		if v.IsNil() {
+			e.buf = append(e.buf, simpleNull)
+
+			return nil
+		}
+
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			e.buf = appendHead(e.buf, majorBytes, uint64(v.Len()))
+			e.buf = append(e.buf, v.Bytes()...)
+
+			return nil
+		}
+
+		return e.encodeArray(v)
+	case reflect.Array:
+		return e.encodeArray(v)
+	case reflect.Map:
+		if v.IsNil() {
+			e.buf = append(e.buf, simpleNull)
+
+			return nil
+		}
+
+		return e.encodeMap(v)
+	case reflect.Struct:
+		return e.encodeStruct(v)
+	default:
+		return errors.New(errors.ErrInvalidRequest,
+			"unable to encode value as CBOR",
+			"type", v.Type().String())
+	}
+
+	return nil
+}
+
+// marshal appends the encoding of a value which encodes itself.
+func (e *encoder) marshal(m Marshaler) error {
+	b, err := m.MarshalCBOR()
+	if err != nil {
+		return err
+	}
+
+	e.buf = append(e.buf, b...)
+
+	return nil
+}
+
+// encodeFloat appends a floating point number, using single precision if it
+// can be represented exactly.
+func (e *encoder) encodeFloat(f float64) {
+	if f32 := float32(f); float64(f32) == f {
+		e.buf = binary.BigEndian.AppendUint32(append(e.buf, simpleFloat32),
+			math.Float32bits(f32))
+
+		return
+	}
+
+	e.buf = binary.BigEndian.AppendUint64(append(e.buf, simpleFloat64),
+		math.Float64bits(f))
+}
+
+// encodeArray appends a slice or array.
+func (e *encoder) encodeArray(v reflect.Value) error {
+	e.buf = appendHead(e.buf, majorArray, uint64(v.Len()))
+
+	for i := range v.Len() {
+		if err := e.encode(v.Index(i)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// encodeMap appends a map. Entries with string keys are sorted, so that the
+// encoding is deterministic.
+func (e *encoder) encodeMap(v reflect.Value) error {
+	keys := v.MapKeys()
+
+	if v.Type().Key().Kind() == reflect.String {
+		slices.SortFunc(keys, func(a, b reflect.Value) int {
+			return cmp.Compare(a.String(), b.String())
+		})
+	}
+
+	e.buf = appendHead(e.buf, majorMap, uint64(len(keys)))
+
+	for _, k := range keys {
+		if err := e.encode(k); err != nil {
+			return err
+		}
+
+		if err := e.encode(v.MapIndex(k)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// encodeStruct appends a struct as a map keyed by field name.
+func (e *encoder) encodeStruct(v reflect.Value) error {
+	fields := structFields(v.Type())
+
+	values := make([]reflect.Value, len(fields))
+
+	n := 0
+
+	for i, f := range fields {
+		fv := fieldByIndex(v, f.index, false)
+		if !fv.IsValid() || (f.omitEmpty && isEmpty(fv)) {
+			continue
+		}
+
+		values[i] = fv
+		n++
+	}
+
+	e.buf = appendHead(e.buf, majorMap, uint64(n))
+
+	for i, f := range fields {
+		if !values[i].IsValid() {
+			continue
+		}
+
+		e.buf = appendHead(e.buf, majorText, uint64(len(f.name)))
+		e.buf = append(e.buf, f.name...)
+
+		if err := e.encode(values[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,114 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/dhaifley/game2d/client/api"
+	"github.com/dhaifley/game2d/errors"
+)
+
+// apiRequest sends a request to the game2d API, at the API URL joined with
+// the path elements, and returns the JSON response body. An error is returned
+// if the response status code is not one of the expected status codes.
+func (g *Game) apiRequest(method string,
+	body io.Reader,
+	query url.Values,
+	expect []int,
+	path ...string,
+) ([]byte, error) {
+	return g.apiRequestAccept(method, "application/json", body, query, expect,
+		path...)
+}
+
+// apiRequestAccept sends a request to the game2d API, as apiRequest does, but
+// accepting response bodies of the specified media types.
+func (g *Game) apiRequestAccept(method, accept string,
+	body io.Reader,
+	query url.Values,
+	expect []int,
+	path ...string,
+) ([]byte, error) {
+	var b []byte
+
+	if body != nil {
+		var err error
+
+		if b, err = io.ReadAll(body); err != nil {
+			return nil, errors.Wrap(err, errors.ErrClient,
+				"unable to read API request body",
+				"method", method)
+		}
+	}
+
+	res, err := g.apiClient().Do(context.Background(), &api.Request{
+		Method: method,
+		Path:   path,
+		Query:  query,
+		Body:   b,
+		Accept: accept,
+		Expect: expect,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return res.Body, nil
+}
+
+// apiClient returns a client for the game2d API which identifies requests as
+// sent by this game.
+func (g *Game) apiClient() *api.Client {
+	return api.New(g.apiURL,
+		api.WithToken(g.apiToken),
+		api.WithHeader("X-Game-ID", g.id))
+}
+
+// isURL returns whether a game file is an HTTP(S) URL rather than a local file
+// path.
+func isURL(file string) bool {
+	u, err := url.Parse(file)
+
+	return err == nil && (u.Scheme == "https" || u.Scheme == "http") &&
+		u.Host != ""
+}
+
+// getURL retrieves the contents of an HTTP(S) URL.
+func getURL(u string) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrClient,
+			"unable to create request",
+			"url", u)
+	}
+
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", "game2d")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrClient,
+			"unable to send request",
+			"url", u)
+	}
+
+	defer resp.Body.Close()
+
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrClient,
+			"unable to read response",
+			"url", u)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.New(errors.ErrClient,
+			"unexpected response",
+			"url", u,
+			"status_code", resp.StatusCode)
+	}
+
+	return b, nil
+}
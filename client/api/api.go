@@ -0,0 +1,392 @@
+// Package api provides a client for the game2d REST API.
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/dhaifley/game2d/errors"
+	"golang.org/x/net/websocket"
+)
+
+// Default client values.
+const (
+	DefaultUserAgent  = "game2d"
+	DefaultRetries    = 2
+	DefaultRetryWait  = time.Millisecond * 500
+	DefaultRetryLimit = time.Second * 30
+)
+
+// Client values are used to send requests to the game2d API.
+type Client struct {
+	apiURL    string
+	token     string
+	userAgent string
+	header    http.Header
+	cli       *http.Client
+	retries   int
+	retryWait time.Duration
+}
+
+// Option values are used to configure a client.
+type Option func(*Client)
+
+// WithHTTPClient sets the HTTP client used to send requests.
+func WithHTTPClient(cli *http.Client) Option {
+	return func(c *Client) {
+		if cli != nil {
+			c.cli = cli
+		}
+	}
+}
+
+// WithToken sets the bearer token used to authenticate requests.
+func WithToken(token string) Option {
+	return func(c *Client) {
+		c.token = token
+	}
+}
+
+// WithUserAgent sets the user agent sent with requests.
+func WithUserAgent(userAgent string) Option {
+	return func(c *Client) {
+		c.userAgent = userAgent
+	}
+}
+
+// WithHeader sets a header sent with every request.
+func WithHeader(key, value string) Option {
+	return func(c *Client) {
+		c.header.Set(key, value)
+	}
+}
+
+// WithRetries sets the number of times a failed request is retried, and the
+// initial wait between retries, which doubles after each attempt.
+func WithRetries(retries int, wait time.Duration) Option {
+	return func(c *Client) {
+		c.retries = max(retries, 0)
+
+		if wait > 0 {
+			c.retryWait = wait
+		}
+	}
+}
+
+// New creates a new client for the game2d API at the specified URL.
+func New(apiURL string, opts ...Option) *Client {
+	c := &Client{
+		apiURL:    apiURL,
+		userAgent: DefaultUserAgent,
+		header:    http.Header{},
+		cli:       http.DefaultClient,
+		retries:   DefaultRetries,
+		retryWait: DefaultRetryWait,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// URL returns the API URL used by the client.
+func (c *Client) URL() string {
+	return c.apiURL
+}
+
+// Token returns the bearer token used to authenticate requests.
+func (c *Client) Token() string {
+	return c.token
+}
+
+// SetToken sets the bearer token used to authenticate requests.
+func (c *Client) SetToken(token string) {
+	c.token = token
+}
+
+// Request values describe a request sent to the API. The path elements are
+// joined to the API URL. If no expected status codes are given, any 2xx
+// status code is accepted.
+type Request struct {
+	Method      string
+	Path        []string
+	Query       url.Values
+	Body        []byte
+	ContentType string
+	Accept      string
+	Header      http.Header
+	Expect      []int
+}
+
+// Response values contain a response received from the API.
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+// Decode parses the JSON response body into v.
+func (r *Response) Decode(v any) error {
+	if err := json.Unmarshal(r.Body, v); err != nil {
+		return errors.Wrap(err, errors.ErrClient,
+			"unable to decode API response",
+			"status_code", r.StatusCode)
+	}
+
+	return nil
+}
+
+// idempotent returns whether a request method may safely be retried.
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut,
+		http.MethodDelete:
+		return true
+	}
+
+	return false
+}
+
+// retryStatus returns whether a response status code indicates a request may
+// succeed if retried.
+func retryStatus(method string, statusCode int) bool {
+	switch statusCode {
+	case http.StatusTooManyRequests:
+		return true
+	case http.StatusBadGateway, http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return idempotent(method)
+	}
+
+	return false
+}
+
+// Do sends a request to the API. Requests which fail because of network errors
+// or temporary server conditions are retried. If the response status code is
+// not expected, the response is returned along with an error.
+func (c *Client) Do(ctx context.Context, r *Request) (*Response, error) {
+	u, err := url.Parse(c.apiURL)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrClient,
+			"unable to parse game2d API URL",
+			"api_url", c.apiURL)
+	}
+
+	u = u.JoinPath(r.Path...)
+
+	if len(r.Query) > 0 {
+		u.RawQuery = r.Query.Encode()
+	}
+
+	apiURL := u.String()
+
+	wait := c.retryWait
+
+	for attempt := 0; ; attempt++ {
+		res, err := c.send(ctx, r, apiURL)
+
+		retry := attempt < c.retries
+
+		switch {
+		case err != nil:
+			retry = retry && idempotent(r.Method) && ctx.Err() == nil
+		default:
+			retry = retry && retryStatus(r.Method, res.StatusCode)
+		}
+
+		if !retry {
+			if err != nil {
+				return nil, err
+			}
+
+			return res, c.check(r, res, apiURL)
+		}
+
+		d := wait
+
+		if res != nil {
+			if t := errors.ParseRetryAfter(res.Header.Get("Retry-After"),
+				time.Now()); !t.IsZero() {
+				d = min(max(time.Until(t), 0), DefaultRetryLimit)
+			}
+		}
+
+		wait *= 2
+
+		select {
+		case <-ctx.Done():
+			return nil, errors.Wrap(ctx.Err(), errors.ErrUnavailable,
+				"API request canceled",
+				"api_url", apiURL,
+				"method", r.Method)
+		case <-time.After(d):
+		}
+	}
+}
+
+// send sends a single attempt of a request to the API.
+func (c *Client) send(ctx context.Context,
+	r *Request,
+	apiURL string,
+) (*Response, error) {
+	var body io.Reader
+
+	if r.Body != nil {
+		body = bytes.NewReader(r.Body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, r.Method, apiURL, body)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrClient,
+			"unable to create API request",
+			"api_url", apiURL,
+			"method", r.Method)
+	}
+
+	for k, v := range c.header {
+		req.Header[k] = slices.Clone(v)
+	}
+
+	accept := r.Accept
+	if accept == "" {
+		accept = "application/json"
+	}
+
+	req.Header.Set("Accept", accept)
+	req.Header.Set("User-Agent", c.userAgent)
+
+	if r.Body != nil {
+		ct := r.ContentType
+		if ct == "" {
+			ct = "application/json"
+		}
+
+		req.Header.Set("Content-Type", ct)
+	}
+
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	for k, v := range r.Header {
+		req.Header[k] = slices.Clone(v)
+	}
+
+	resp, err := c.cli.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrUnavailable,
+			"unable to send API request",
+			"api_url", apiURL,
+			"method", r.Method)
+	}
+
+	defer resp.Body.Close()
+
+	rb, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrClient,
+			"unable to read API response",
+			"api_url", apiURL,
+			"method", r.Method)
+	}
+
+	return &Response{
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+		Body:       rb,
+	}, nil
+}
+
+// check returns an error if the response status code was not expected. The
+// code, reason and rate limit of an error response are preserved.
+func (c *Client) check(r *Request, res *Response, apiURL string) error {
+	if len(r.Expect) == 0 {
+		if res.StatusCode >= http.StatusOK &&
+			res.StatusCode < http.StatusMultipleChoices {
+			return nil
+		}
+	} else if slices.Contains(r.Expect, res.StatusCode) {
+		return nil
+	}
+
+	code := errors.ErrClient
+	if res.StatusCode == http.StatusNotFound {
+		code = errors.ErrNotFound
+	}
+
+	e := errors.New(code,
+		"unexpected API response",
+		"api_url", apiURL,
+		"method", r.Method,
+		"status_code", res.StatusCode,
+		"response", string(res.Body))
+
+	var se errors.Error
+
+	if err := json.Unmarshal(res.Body, &se); err == nil &&
+		se.Name != "" && se.Status == res.StatusCode {
+		e.Code = se.Code
+		e.Reason = se.Reason
+		e.Rate = se.Rate
+	}
+
+	return e
+}
+
+// Dial opens a WebSocket connection to the API, at the API URL joined with the
+// path elements, authenticated as requests are.
+func (c *Client) Dial(ctx context.Context,
+	path ...string,
+) (*websocket.Conn, error) {
+	u, err := url.Parse(c.apiURL)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrClient,
+			"unable to parse game2d API URL",
+			"api_url", c.apiURL)
+	}
+
+	origin := u.Scheme + "://" + u.Host
+
+	u = u.JoinPath(path...)
+
+	switch u.Scheme {
+	case "https":
+		u.Scheme = "wss"
+	default:
+		u.Scheme = "ws"
+	}
+
+	cfg, err := websocket.NewConfig(u.String(), origin)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrClient,
+			"unable to configure API connection",
+			"api_url", u.String())
+	}
+
+	for k, v := range c.header {
+		cfg.Header[k] = slices.Clone(v)
+	}
+
+	cfg.Header.Set("User-Agent", c.userAgent)
+
+	if c.token != "" {
+		cfg.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	ws, err := cfg.DialContext(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrUnavailable,
+			"unable to connect to API",
+			"api_url", u.String())
+	}
+
+	return ws, nil
+}
@@ -0,0 +1,296 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dhaifley/game2d/client/api"
+	"github.com/dhaifley/game2d/errors"
+	"github.com/dhaifley/game2d/request"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	TestID    = "1b2d3c4d-1b2d-3c4d-5e6f-1b2d3c4d5e6f"
+	TestName  = "test"
+	TestToken = "test-token"
+)
+
+func TestLogin(t *testing.T) {
+	t.Parallel()
+
+	ts := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/login/token" ||
+				r.FormValue("username") != TestName ||
+				r.FormValue("password") != TestName {
+				w.WriteHeader(http.StatusUnauthorized)
+
+				return
+			}
+
+			w.Write([]byte(`{"access_token":"` + TestToken +
+				`","token_type":"bearer","id":"` + TestID + `"}`))
+		}))
+
+	t.Cleanup(ts.Close)
+
+	c := api.New(ts.URL)
+
+	tok, err := c.Login(context.Background(), TestName, TestName)
+	require.NoError(t, err)
+	assert.Equal(t, TestToken, tok.AccessToken)
+	assert.Equal(t, TestID, tok.UserID)
+	assert.Equal(t, TestToken, c.Token())
+
+	_, err = c.Login(context.Background(), TestName, "invalid")
+	assert.Error(t, err)
+}
+
+func TestGames(t *testing.T) {
+	t.Parallel()
+
+	const total = 5
+
+	ts := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "Bearer "+TestToken {
+				w.WriteHeader(http.StatusUnauthorized)
+
+				return
+			}
+
+			q, err := request.ParseQuery(r.URL.Query())
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+
+				return
+			}
+
+			res := []*api.Game{}
+
+			for i := q.Skip; i < min(q.Skip+q.Size, total); i++ {
+				res = append(res, &api.Game{
+					ID: request.FieldString{
+						Set: true, Valid: true, Value: strconv.FormatInt(i, 10),
+					},
+				})
+			}
+
+			w.Header().Set("X-Total-Count", strconv.Itoa(total))
+
+			json.NewEncoder(w).Encode(res)
+		}))
+
+	t.Cleanup(ts.Close)
+
+	c := api.New(ts.URL, api.WithToken(TestToken))
+
+	page, err := c.ListGames(context.Background(), &request.Query{Size: 2})
+	require.NoError(t, err)
+	assert.Len(t, page.Games, 2)
+	assert.Equal(t, int64(total), page.Total)
+
+	ids := []string{}
+
+	for g, err := range c.Games(context.Background(),
+		&request.Query{Size: 2}) {
+		require.NoError(t, err)
+
+		ids = append(ids, g.ID.Value)
+	}
+
+	assert.Equal(t, []string{"0", "1", "2", "3", "4"}, ids)
+
+	c.SetToken("")
+
+	for _, err := range c.Games(context.Background(), nil) {
+		assert.Error(t, err)
+	}
+}
+
+func TestGetGames(t *testing.T) {
+	t.Parallel()
+
+	ts := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			res := []*api.Game{}
+
+			for _, id := range strings.Split(r.URL.Query().Get("ids"), ",") {
+				res = append(res, &api.Game{
+					ID: request.FieldString{Set: true, Valid: true, Value: id},
+				})
+			}
+
+			json.NewEncoder(w).Encode(res)
+		}))
+
+	t.Cleanup(ts.Close)
+
+	c := api.New(ts.URL, api.WithToken(TestToken))
+
+	games, err := c.GetGames(context.Background(), "a", "b")
+	require.NoError(t, err)
+	require.Len(t, games, 2)
+	assert.Equal(t, "a", games[0].ID.Value)
+	assert.Equal(t, "b", games[1].ID.Value)
+
+	games, err = c.GetGames(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, games)
+}
+
+func TestPromptHistory(t *testing.T) {
+	t.Parallel()
+
+	ts := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/games/"+TestID+"/prompts", r.URL.Path)
+			assert.Equal(t, "2", r.URL.Query().Get("size"))
+			assert.Equal(t, "1", r.URL.Query().Get("skip"))
+
+			w.Header().Set("X-Total-Count", "3")
+
+			json.NewEncoder(w).Encode([]*api.PromptHistory{
+				{Prompt: "b", Response: "2"},
+				{Prompt: "a", Response: "1"},
+			})
+		}))
+
+	t.Cleanup(ts.Close)
+
+	c := api.New(ts.URL, api.WithToken(TestToken))
+
+	res, err := c.PromptHistory(context.Background(), TestID,
+		&request.Query{Size: 2, Skip: 1})
+	require.NoError(t, err)
+	require.Len(t, res.Prompts, 2)
+	assert.Equal(t, int64(3), res.Total)
+	assert.Equal(t, "b", res.Prompts[0].Prompt)
+}
+
+func TestSimilar(t *testing.T) {
+	t.Parallel()
+
+	ts := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/games/"+TestID+"/similar", r.URL.Path)
+			assert.Equal(t, "2", r.URL.Query().Get("size"))
+
+			json.NewEncoder(w).Encode([]*api.SimilarGame{
+				{ID: "a", Score: 0.9},
+				{ID: "b", Score: 0.5},
+			})
+		}))
+
+	t.Cleanup(ts.Close)
+
+	c := api.New(ts.URL, api.WithToken(TestToken))
+
+	res, err := c.Similar(context.Background(), TestID,
+		&request.Query{Size: 2})
+	require.NoError(t, err)
+	require.Len(t, res, 2)
+	assert.Equal(t, "a", res[0].ID)
+}
+
+func TestDelta(t *testing.T) {
+	t.Parallel()
+
+	ts := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/games/"+TestID+"/delta", r.URL.Path)
+			assert.Equal(t, "abc", r.URL.Query().Get("since"))
+
+			json.NewEncoder(w).Encode(&api.GameDelta{
+				ID:  TestID,
+				Rev: "def",
+				Sections: map[string]*api.GameDeltaSection{
+					"game":   {Hash: "1", Data: []byte(`{"name":"test"}`)},
+					"images": {Hash: "2"},
+				},
+			})
+		}))
+
+	t.Cleanup(ts.Close)
+
+	c := api.New(ts.URL, api.WithToken(TestToken))
+
+	res, err := c.Delta(context.Background(), TestID, "abc")
+	require.NoError(t, err)
+	assert.Equal(t, "def", res.Rev)
+	require.Len(t, res.Sections, 2)
+	assert.JSONEq(t, `{"name":"test"}`, string(res.Sections["game"].Data))
+	assert.Nil(t, res.Sections["images"].Data)
+}
+
+func TestRetries(t *testing.T) {
+	t.Parallel()
+
+	var calls atomic.Int64
+
+	ts := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			if calls.Add(1) < 3 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+
+				return
+			}
+
+			w.Write([]byte(`["a","b"]`))
+		}))
+
+	t.Cleanup(ts.Close)
+
+	c := api.New(ts.URL, api.WithRetries(2, time.Millisecond))
+
+	tags, err := c.Tags(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, tags)
+	assert.Equal(t, int64(3), calls.Load())
+
+	calls.Store(0)
+
+	_, err = c.AddGameTags(context.Background(), TestID, []string{"a"})
+	assert.True(t, errors.Has(err, errors.ErrClient))
+	assert.Equal(t, int64(1), calls.Load())
+}
+
+func TestErrorResponse(t *testing.T) {
+	t.Parallel()
+
+	ts := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			e := errors.New(errors.ErrorRateLimit, "prompt limit reached").
+				WithReason(errors.ReasonPromptRateLimit)
+
+			w.WriteHeader(http.StatusTooManyRequests)
+
+			json.NewEncoder(w).Encode(e)
+		}))
+
+	t.Cleanup(ts.Close)
+
+	c := api.New(ts.URL, api.WithRetries(0, 0))
+
+	_, err := c.Prompt(context.Background(), &api.Prompts{
+		GameID: request.FieldString{Set: true, Valid: true, Value: TestID},
+	})
+
+	var e *errors.Error
+
+	require.ErrorAs(t, err, &e)
+	assert.True(t, errors.Has(err, errors.ErrorRateLimit))
+	assert.Equal(t, errors.ReasonPromptRateLimit, e.Reason)
+
+	err = c.DeleteGame(context.Background(), TestID)
+	assert.True(t, errors.Has(err, errors.ErrorRateLimit))
+}
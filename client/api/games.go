@@ -0,0 +1,383 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"iter"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/dhaifley/game2d/errors"
+	"github.com/dhaifley/game2d/request"
+)
+
+// defaultPageSize is the number of games requested per page when iterating
+// over games without a query size.
+const defaultPageSize = 100
+
+// queryValues converts a query into URL query parameters.
+func queryValues(q *request.Query) url.Values {
+	v := url.Values{}
+
+	if q == nil {
+		return v
+	}
+
+	if q.Search != "" {
+		v.Set("search", q.Search)
+	}
+
+	if q.Size > 0 {
+		v.Set("size", strconv.FormatInt(q.Size, 10))
+	}
+
+	if q.Skip > 0 {
+		v.Set("skip", strconv.FormatInt(q.Skip, 10))
+	}
+
+	if q.Sort != "" {
+		v.Set("sort", q.Sort)
+	}
+
+	return v
+}
+
+// call encodes a request body as JSON, sends the request, and decodes the
+// response body into res, if it is not nil.
+func (c *Client) call(ctx context.Context,
+	method string,
+	body, res any,
+	query url.Values,
+	expect []int,
+	path ...string,
+) (*Response, error) {
+	r := &Request{
+		Method: method,
+		Path:   path,
+		Query:  query,
+		Expect: expect,
+	}
+
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return nil, errors.Wrap(err, errors.ErrClient,
+				"unable to encode API request",
+				"method", method,
+				"path", path)
+		}
+
+		r.Body = b
+	}
+
+	resp, err := c.Do(ctx, r)
+	if err != nil {
+		return resp, err
+	}
+
+	if res != nil && len(resp.Body) > 0 {
+		if err := resp.Decode(res); err != nil {
+			return resp, err
+		}
+	}
+
+	return resp, nil
+}
+
+// Login authenticates with a user name and password and sets the returned
+// access token as the token used by the client.
+func (c *Client) Login(ctx context.Context,
+	username, password string,
+) (*Token, error) {
+	form := url.Values{}
+
+	form.Set("username", username)
+	form.Set("password", password)
+
+	resp, err := c.Do(ctx, &Request{
+		Method:      http.MethodPost,
+		Path:        []string{"login", "token"},
+		Body:        []byte(form.Encode()),
+		ContentType: "application/x-www-form-urlencoded",
+		Expect:      []int{http.StatusOK},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var res *Token
+
+	if err := resp.Decode(&res); err != nil {
+		return nil, err
+	}
+
+	c.SetToken(res.AccessToken)
+
+	return res, nil
+}
+
+// ListGames retrieves a single page of games matching a query.
+func (c *Client) ListGames(ctx context.Context,
+	q *request.Query,
+) (*GamesPage, error) {
+	res := &GamesPage{}
+
+	resp, err := c.call(ctx, http.MethodGet, nil, &res.Games, queryValues(q),
+		[]int{http.StatusOK}, "games")
+	if err != nil {
+		return nil, err
+	}
+
+	if v := resp.Header.Get("X-Total-Count"); v != "" {
+		res.Total, _ = strconv.ParseInt(v, 10, 64)
+	}
+
+	return res, nil
+}
+
+// Games returns an iterator over all games matching a query, retrieving them
+// one page at a time. Iteration stops after the first error.
+func (c *Client) Games(ctx context.Context,
+	q *request.Query,
+) iter.Seq2[*Game, error] {
+	return func(yield func(*Game, error) bool) {
+		pq := request.Query{Size: defaultPageSize}
+
+		if q != nil {
+			pq = *q
+
+			if pq.Size <= 0 {
+				pq.Size = defaultPageSize
+			}
+		}
+
+		for {
+			page, err := c.ListGames(ctx, &pq)
+			if err != nil {
+				yield(nil, err)
+
+				return
+			}
+
+			for _, g := range page.Games {
+				if !yield(g, nil) {
+					return
+				}
+			}
+
+			pq.Skip += int64(len(page.Games))
+
+			if int64(len(page.Games)) < pq.Size ||
+				(page.Total > 0 && pq.Skip >= page.Total) {
+				return
+			}
+		}
+	}
+}
+
+// GetGame retrieves a game by ID.
+func (c *Client) GetGame(ctx context.Context, id string) (*Game, error) {
+	var res *Game
+
+	if _, err := c.call(ctx, http.MethodGet, nil, &res, nil,
+		[]int{http.StatusOK}, "games", id); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// GetGames retrieves many games by ID in a single request. Games which are not
+// found are omitted from the result.
+func (c *Client) GetGames(ctx context.Context, ids ...string) ([]*Game, error) {
+	res := []*Game{}
+
+	if len(ids) == 0 {
+		return res, nil
+	}
+
+	if _, err := c.call(ctx, http.MethodGet, nil, &res,
+		url.Values{"ids": []string{strings.Join(ids, ",")}},
+		[]int{http.StatusOK}, "games"); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// CreateGame creates a new game.
+func (c *Client) CreateGame(ctx context.Context, g *Game) (*Game, error) {
+	var res *Game
+
+	if _, err := c.call(ctx, http.MethodPost, g, &res, nil,
+		[]int{http.StatusCreated}, "games"); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// UpdateGame updates the fields of a game which are set.
+func (c *Client) UpdateGame(ctx context.Context, g *Game) (*Game, error) {
+	var res *Game
+
+	if _, err := c.call(ctx, http.MethodPatch, g, &res, nil,
+		[]int{http.StatusOK}, "games", g.ID.Value); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// DeleteGame deletes a game by ID.
+func (c *Client) DeleteGame(ctx context.Context, id string) error {
+	_, err := c.call(ctx, http.MethodDelete, nil, nil, nil,
+		[]int{http.StatusNoContent}, "games", id)
+
+	return err
+}
+
+// Prompt sends an AI prompt for a game, and returns the resulting prompts,
+// which identify the new revision of the game.
+func (c *Client) Prompt(ctx context.Context, p *Prompts) (*Prompts, error) {
+	var res *Prompts
+
+	if _, err := c.call(ctx, http.MethodPost, p, &res, nil,
+		[]int{http.StatusCreated}, "games", "prompt"); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// PromptHistory retrieves a single page of the AI conversation history of a
+// game. Only the size and skip of the query are used.
+func (c *Client) PromptHistory(ctx context.Context,
+	id string,
+	q *request.Query,
+) (*PromptHistoryPage, error) {
+	res := &PromptHistoryPage{}
+
+	resp, err := c.call(ctx, http.MethodGet, nil, &res.Prompts,
+		queryValues(q), []int{http.StatusOK}, "games", id, "prompts")
+	if err != nil {
+		return nil, err
+	}
+
+	if v := resp.Header.Get("X-Total-Count"); v != "" {
+		res.Total, _ = strconv.ParseInt(v, 10, 64)
+	}
+
+	return res, nil
+}
+
+// Similar retrieves the games most similar to a game. Only the size of the
+// query is used.
+func (c *Client) Similar(ctx context.Context,
+	id string,
+	q *request.Query,
+) ([]*SimilarGame, error) {
+	var res []*SimilarGame
+
+	if _, err := c.call(ctx, http.MethodGet, nil, &res, queryValues(q),
+		[]int{http.StatusOK}, "games", id, "similar"); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// Recommended retrieves the public games recommended to the current user.
+// Only the size of the query is used.
+func (c *Client) Recommended(ctx context.Context,
+	q *request.Query,
+) ([]*SimilarGame, error) {
+	var res []*SimilarGame
+
+	if _, err := c.call(ctx, http.MethodGet, nil, &res, queryValues(q),
+		[]int{http.StatusOK}, "games", "recommended"); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// Delta retrieves the sections of a game definition which changed since a
+// revision of it. An empty revision retrieves every section.
+func (c *Client) Delta(ctx context.Context,
+	id, since string,
+) (*GameDelta, error) {
+	var res *GameDelta
+
+	if _, err := c.call(ctx, http.MethodGet, nil, &res,
+		url.Values{"since": []string{since}},
+		[]int{http.StatusOK}, "games", id, "delta"); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// Undo reverts the last AI prompt for a game.
+func (c *Client) Undo(ctx context.Context, p *Prompts) (*Prompts, error) {
+	var res *Prompts
+
+	if _, err := c.call(ctx, http.MethodPost, p, &res, nil,
+		[]int{http.StatusCreated}, "games", "undo"); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// Tags retrieves all tags used by games.
+func (c *Client) Tags(ctx context.Context) ([]string, error) {
+	var res []string
+
+	if _, err := c.call(ctx, http.MethodGet, nil, &res, nil,
+		[]int{http.StatusOK}, "games", "tags"); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// GameTags retrieves the tags of a game.
+func (c *Client) GameTags(ctx context.Context, id string) ([]string, error) {
+	var res []string
+
+	if _, err := c.call(ctx, http.MethodGet, nil, &res, nil,
+		[]int{http.StatusOK}, "games", id, "tags"); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// AddGameTags adds tags to a game and returns the resulting tags.
+func (c *Client) AddGameTags(ctx context.Context,
+	id string,
+	tags []string,
+) ([]string, error) {
+	var res []string
+
+	if _, err := c.call(ctx, http.MethodPost, tags, &res, nil,
+		[]int{http.StatusCreated}, "games", id, "tags"); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// DeleteGameTags removes tags from a game.
+func (c *Client) DeleteGameTags(ctx context.Context,
+	id string,
+	tags []string,
+) error {
+	_, err := c.call(ctx, http.MethodDelete, tags, nil, nil,
+		[]int{http.StatusNoContent}, "games", id, "tags")
+
+	return err
+}
@@ -0,0 +1,130 @@
+package api
+
+import (
+	"encoding/json"
+
+	"github.com/dhaifley/game2d/request"
+)
+
+// Game values represent games stored by the API.
+type Game struct {
+	AccountID   request.FieldString      `json:"account_id"  yaml:"account_id"`
+	Debug       request.FieldBool        `json:"debug"       yaml:"debug"`
+	Pause       request.FieldBool        `json:"pause"       yaml:"pause"`
+	Public      request.FieldBool        `json:"public"      yaml:"public"`
+	ReadOnly    request.FieldBool        `json:"read_only"   yaml:"read_only"`
+	W           request.FieldInt64       `json:"w"           yaml:"w"`
+	H           request.FieldInt64       `json:"h"           yaml:"h"`
+	ID          request.FieldString      `json:"id"          yaml:"id"`
+	PreviousID  request.FieldString      `json:"previous_id" yaml:"previous_id"`
+	Name        request.FieldString      `json:"name"        yaml:"name"`
+	Version     request.FieldString      `json:"version"     yaml:"version"`
+	Description request.FieldString      `json:"description" yaml:"description"`
+	Icon        request.FieldString      `json:"icon"        yaml:"icon"`
+	Controls    request.FieldString      `json:"controls"    yaml:"controls"`
+	Status      request.FieldString      `json:"status"      yaml:"status"`
+	StatusData  request.FieldJSON        `json:"status_data" yaml:"status_data"`
+	Subject     request.FieldJSON        `json:"subject"     yaml:"subject"`
+	Objects     request.FieldJSON        `json:"objects"     yaml:"objects"`
+	Images      request.FieldJSON        `json:"images"      yaml:"images"`
+	Script      request.FieldString      `json:"script"      yaml:"script"`
+	Source      request.FieldString      `json:"source"      yaml:"source"`
+	CommitHash  request.FieldString      `json:"commit_hash" yaml:"commit_hash"`
+	Revision    request.FieldInt64       `json:"revision"    yaml:"revision"`
+	Tags        request.FieldStringArray `json:"tags"        yaml:"tags"`
+	Prompts     request.FieldJSON        `json:"prompts"     yaml:"prompts"`
+	CreatedAt   request.FieldTime        `json:"created_at"  yaml:"created_at"`
+	CreatedBy   request.FieldString      `json:"created_by"  yaml:"created_by"`
+	UpdatedAt   request.FieldTime        `json:"updated_at"  yaml:"updated_at"`
+	UpdatedBy   request.FieldString      `json:"updated_by"  yaml:"updated_by"`
+}
+
+// Prompt values contain a single AI prompt and its response, and any reference
+// assets attached to the prompt.
+type Prompt struct {
+	Prompt      request.FieldString `json:"prompt"                yaml:"prompt"`
+	Response    request.FieldString `json:"response"              yaml:"response"`
+	Thinking    request.FieldString `json:"thinking"              yaml:"thinking"`
+	Attachments []PromptAttachment  `json:"attachments,omitempty" yaml:"attachments,omitempty"`
+}
+
+// PromptAttachment values contain a reference image, or example Lua script,
+// attached to a prompt. The data is base64 encoded.
+type PromptAttachment struct {
+	Name string `json:"name" yaml:"name"`
+	Type string `json:"type" yaml:"type"`
+	Data string `json:"data" yaml:"data"`
+}
+
+// Prompts values contain the AI prompt data for a game. The history of the
+// conversation is retrieved using PromptHistory.
+type Prompts struct {
+	Current   Prompt              `json:"current"           yaml:"current"`
+	History   []Prompt            `json:"history,omitempty" yaml:"history,omitempty"`
+	HistoryID request.FieldString `json:"history_id"        yaml:"history_id"`
+	Error     request.FieldString `json:"error"             yaml:"error"`
+	GameID    request.FieldString `json:"game_id"           yaml:"game_id"`
+}
+
+// PromptHistory values contain a prompt, and its response, from the history
+// of the AI conversation about a game.
+type PromptHistory struct {
+	HistoryID   string             `json:"history_id"            yaml:"history_id"`
+	GameID      string             `json:"game_id,omitempty"     yaml:"game_id,omitempty"`
+	Prompt      string             `json:"prompt"                yaml:"prompt"`
+	Response    string             `json:"response"              yaml:"response"`
+	Attachments []PromptAttachment `json:"attachments,omitempty" yaml:"attachments,omitempty"`
+	CreatedAt   int64              `json:"created_at"            yaml:"created_at"`
+}
+
+// PromptHistoryPage values contain a single page of the prompt history of a
+// game, from the newest to the oldest prompt, and the total number of prompts
+// in the history.
+type PromptHistoryPage struct {
+	Prompts []*PromptHistory `json:"prompts" yaml:"prompts"`
+	Total   int64            `json:"total"   yaml:"total"`
+}
+
+// SimilarGame values represent games which are similar to a game, or which
+// are recommended to the current user, and their similarity score.
+type SimilarGame struct {
+	ID          string   `json:"id"                    yaml:"id"`
+	Name        string   `json:"name"                  yaml:"name"`
+	Description string   `json:"description,omitempty" yaml:"description,omitempty"`
+	Icon        string   `json:"icon,omitempty"        yaml:"icon,omitempty"`
+	Tags        []string `json:"tags,omitempty"        yaml:"tags,omitempty"`
+	Public      bool     `json:"public"                yaml:"public"`
+	Score       float64  `json:"score"                 yaml:"score"`
+}
+
+// GameDeltaSection values contain the content hash of a section of a game
+// definition, and its data, if it changed.
+type GameDeltaSection struct {
+	Hash string          `json:"hash"           yaml:"hash"`
+	Data json.RawMessage `json:"data,omitempty" yaml:"data,omitempty"`
+}
+
+// GameDelta values contain the sections of a game definition which changed
+// since a revision of it, and the current revision.
+type GameDelta struct {
+	ID       string                       `json:"id"       yaml:"id"`
+	Rev      string                       `json:"rev"      yaml:"rev"`
+	Sections map[string]*GameDeltaSection `json:"sections" yaml:"sections"`
+}
+
+// Token values contain an API access token obtained by logging in.
+type Token struct {
+	AccessToken string `json:"access_token" yaml:"access_token"`
+	TokenType   string `json:"token_type"   yaml:"token_type"`
+	AccountID   string `json:"account_id"   yaml:"account_id"`
+	AccountName string `json:"account_name" yaml:"account_name"`
+	UserID      string `json:"id"           yaml:"id"`
+	Scopes      string `json:"scopes"       yaml:"scopes"`
+}
+
+// GamesPage values contain a single page of games and the total number of
+// games matching the query.
+type GamesPage struct {
+	Games []*Game `json:"games" yaml:"games"`
+	Total int64   `json:"total" yaml:"total"`
+}
@@ -0,0 +1,292 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/dhaifley/game2d/client/luaapi"
+	"github.com/dhaifley/game2d/errors"
+	"github.com/dhaifley/game2d/logger"
+	"github.com/google/uuid"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+// Bindings defaults.
+const (
+	bindingsDir       = "bindings"
+	bindingsRowHeight = 16
+	bindingsTop       = 40
+)
+
+// binder values track the key bindings of the game actions chosen by the
+// player, which are persisted locally for each game, and the key bindings
+// menu used to remap them.
+type binder struct {
+	game    string
+	player  map[string][]string
+	open    bool
+	capture bool
+	sel     int
+}
+
+// bindingsPath returns the path of the local player key bindings file for a
+// game.
+func bindingsPath(id string) (string, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return "", errors.New(errors.ErrClient,
+			"invalid game id",
+			"game_id", id)
+	}
+
+	return configPath(bindingsDir, id+".json")
+}
+
+// loadBindings retrieves the locally persisted player key bindings of the
+// game, if they have not already been retrieved.
+func (g *Game) loadBindings() {
+	if g.bnd.game == g.id {
+		return
+	}
+
+	g.bnd.game = g.id
+	g.bnd.player = map[string][]string{}
+
+	file, err := bindingsPath(g.id)
+	if err != nil {
+		return
+	}
+
+	b, err := os.ReadFile(file)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			g.log.Log(context.Background(), logger.LvlWarn,
+				"unable to read key bindings",
+				"error", err,
+				"file", file)
+		}
+
+		return
+	}
+
+	if err := json.Unmarshal(b, &g.bnd.player); err != nil {
+		g.log.Log(context.Background(), logger.LvlWarn,
+			"unable to decode key bindings",
+			"error", err,
+			"file", file)
+
+		g.bnd.player = map[string][]string{}
+	}
+}
+
+// saveBindings persists the player key bindings of the game locally.
+func (g *Game) saveBindings() error {
+	file, err := bindingsPath(g.id)
+	if err != nil {
+		return err
+	}
+
+	b, err := json.MarshalIndent(g.bnd.player, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, errors.ErrClient,
+			"unable to encode key bindings")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return errors.Wrap(err, errors.ErrClient,
+			"unable to create key bindings directory",
+			"file", file)
+	}
+
+	if err := os.WriteFile(file, b, 0o644); err != nil {
+		return errors.Wrap(err, errors.ErrClient,
+			"unable to write key bindings",
+			"file", file)
+	}
+
+	return nil
+}
+
+// Bindings returns the names of the keys bound to each game action. The keys
+// bound by the player replace the default keys of the game definition.
+func (g *Game) Bindings() map[string][]string {
+	g.loadBindings()
+
+	res := make(map[string][]string, len(g.bindings))
+
+	for action, keys := range g.bindings {
+		if pk, ok := g.bnd.player[action]; ok {
+			keys = pk
+		}
+
+		res[action] = slices.Clone(keys)
+	}
+
+	return res
+}
+
+// SetBindings sets the default key bindings of the game actions.
+func (g *Game) SetBindings(bindings map[string][]string) {
+	g.bindings = bindings
+}
+
+// SetBinding binds a game action to a list of keys, by name, for the player,
+// and persists the player key bindings locally.
+func (g *Game) SetBinding(action string, keys ...string) error {
+	if _, ok := g.bindings[action]; !ok {
+		return errors.New(errors.ErrClient,
+			"game action not found",
+			"action", action)
+	}
+
+	for _, k := range keys {
+		if _, ok := luaapi.KeyCode(k); !ok {
+			return errors.New(errors.ErrClient,
+				"invalid key name",
+				"action", action,
+				"key", k)
+		}
+	}
+
+	g.loadBindings()
+
+	g.bnd.player[action] = slices.Clone(keys)
+
+	return g.saveBindings()
+}
+
+// ResetBinding restores the default key bindings of a game action for the
+// player, and persists the player key bindings locally.
+func (g *Game) ResetBinding(action string) error {
+	g.loadBindings()
+
+	if _, ok := g.bnd.player[action]; !ok {
+		return nil
+	}
+
+	delete(g.bnd.player, action)
+
+	return g.saveBindings()
+}
+
+// actions returns the game actions which are active, because a key bound to
+// them is pressed, keyed by action name.
+func (g *Game) actions(keys []ebiten.Key) map[string]any {
+	res := map[string]any{}
+
+	for action, names := range g.Bindings() {
+		for _, name := range names {
+			if c, ok := luaapi.KeyCode(name); ok &&
+				slices.Contains(keys, ebiten.Key(c)) {
+				res[action] = true
+
+				break
+			}
+		}
+	}
+
+	return res
+}
+
+// openBindings opens the key bindings menu.
+func (g *Game) openBindings() {
+	g.bnd.open = true
+	g.bnd.capture = false
+	g.bnd.sel = 0
+}
+
+// bindingsOpen returns whether the key bindings menu is open.
+func (g *Game) bindingsOpen() bool {
+	return g.bnd.open
+}
+
+// updateBindings handles the key bindings menu keyboard navigation each
+// frame. While a key is being captured, the next key pressed, other than
+// escape, replaces the keys bound to the selected action.
+func (g *Game) updateBindings() {
+	actions := slices.Sorted(maps.Keys(g.bindings))
+
+	if g.bnd.capture {
+		for _, k := range inpututil.AppendJustPressedKeys(nil) {
+			g.bnd.capture = false
+
+			if k == ebiten.KeyEscape || g.bnd.sel >= len(actions) {
+				return
+			}
+
+			if err := g.SetBinding(actions[g.bnd.sel],
+				k.String()); err != nil {
+				g.log.Log(context.Background(), logger.LvlWarn,
+					"unable to set key binding",
+					"error", err,
+					"action", actions[g.bnd.sel])
+			}
+
+			return
+		}
+
+		return
+	}
+
+	switch {
+	case inpututil.IsKeyJustPressed(ebiten.KeyEscape):
+		g.bnd.open = false
+	case inpututil.IsKeyJustPressed(ebiten.KeyArrowUp):
+		if g.bnd.sel > 0 {
+			g.bnd.sel--
+		}
+	case inpututil.IsKeyJustPressed(ebiten.KeyArrowDown):
+		if g.bnd.sel < len(actions)-1 {
+			g.bnd.sel++
+		}
+	case inpututil.IsKeyJustPressed(ebiten.KeyEnter):
+		if g.bnd.sel < len(actions) {
+			g.bnd.capture = true
+		}
+	case inpututil.IsKeyJustPressed(ebiten.KeyBackspace):
+		if g.bnd.sel < len(actions) {
+			if err := g.ResetBinding(actions[g.bnd.sel]); err != nil {
+				g.log.Log(context.Background(), logger.LvlWarn,
+					"unable to reset key binding",
+					"error", err,
+					"action", actions[g.bnd.sel])
+			}
+		}
+	}
+}
+
+// drawBindings renders the key bindings menu.
+func (g *Game) drawBindings(screen *ebiten.Image) {
+	ebitenutil.DebugPrintAt(screen, "Key Bindings"+
+		"  [Up/Down] select  [Enter] remap  [Backspace] reset  [Esc] close",
+		8, 8)
+
+	bindings := g.Bindings()
+
+	if len(bindings) == 0 {
+		ebitenutil.DebugPrintAt(screen, "No actions defined", 8, bindingsTop)
+
+		return
+	}
+
+	for i, action := range slices.Sorted(maps.Keys(bindings)) {
+		y := bindingsTop + i*bindingsRowHeight
+
+		if i == g.bnd.sel {
+			ebitenutil.DebugPrintAt(screen, ">", 8, y)
+		}
+
+		keys := strings.Join(bindings[action], ", ")
+		if i == g.bnd.sel && g.bnd.capture {
+			keys = "press a key..."
+		}
+
+		ebitenutil.DebugPrintAt(screen, action+": "+keys, 24, y)
+	}
+}
@@ -0,0 +1,55 @@
+package client_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/dhaifley/game2d/client"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBindings(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("AppData", t.TempDir())
+
+	game := client.NewGame(nil, client.DefaultGameWidth,
+		client.DefaultGameHeight, TestID, TestName, TestDesc)
+
+	game.SetBindings(map[string][]string{
+		"jump": {"Space", "ArrowUp"},
+		"left": {"ArrowLeft"},
+	})
+
+	err := game.SetBinding("jump", "W")
+	assert.NoError(t, err)
+
+	err = game.SetBinding("jump", "NotAKey")
+	assert.Error(t, err, "Invalid key names should not be bound")
+
+	err = game.SetBinding("fly", "F")
+	assert.Error(t, err, "Unknown actions should not be bound")
+
+	exp := map[string][]string{
+		"jump": {"W"},
+		"left": {"ArrowLeft"},
+	}
+
+	assert.Equal(t, exp, game.Bindings(), "Player bindings should be used")
+
+	b, err := json.Marshal(game)
+	assert.NoError(t, err)
+
+	game2 := client.NewGame(nil, client.DefaultGameWidth,
+		client.DefaultGameHeight, game.ID(), TestName, TestDesc)
+
+	err = json.Unmarshal(b, game2)
+	assert.NoError(t, err)
+	assert.Equal(t, exp, game2.Bindings(),
+		"Player bindings should be persisted locally")
+
+	err = game2.ResetBinding("jump")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Space", "ArrowUp"}, game2.Bindings()["jump"],
+		"Default bindings should be restored")
+}
@@ -0,0 +1,79 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github.com/dhaifley/game2d/errors"
+)
+
+// deltaSectionGame is the game section containing all the fields of a game
+// which are not in a section of their own.
+const deltaSectionGame = "game"
+
+// deltaCache values contain the sections of the game definition last
+// retrieved from the API, and their revision, so that only the sections which
+// changed are downloaded again. Sections are identified by their content, so
+// they are reused by new revisions of the game created by prompts.
+type deltaCache struct {
+	sync.Mutex
+	rev      string
+	sections map[string]json.RawMessage
+}
+
+// fetchDelta retrieves the game definition from the API, downloading only the
+// sections which changed since it was last retrieved, and returns the complete
+// JSON encoded definition.
+func (g *Game) fetchDelta() ([]byte, error) {
+	if g.dc == nil {
+		return nil, errors.New(errors.ErrClient,
+			"game delta updates not enabled")
+	}
+
+	g.dc.Lock()
+	defer g.dc.Unlock()
+
+	res, err := g.apiClient().Delta(context.Background(), g.id, g.dc.rev)
+	if err != nil {
+		return nil, err
+	}
+
+	sections := make(map[string]json.RawMessage, len(res.Sections))
+
+	for name, ds := range res.Sections {
+		switch {
+		case ds.Data != nil:
+			sections[name] = ds.Data
+		case g.dc.sections[name] != nil:
+			sections[name] = g.dc.sections[name]
+		default:
+			return nil, errors.New(errors.ErrClient,
+				"missing game section",
+				"section", name)
+		}
+	}
+
+	fields := map[string]json.RawMessage{}
+
+	if err := json.Unmarshal(sections[deltaSectionGame], &fields); err != nil {
+		return nil, errors.Wrap(err, errors.ErrClient,
+			"unable to decode game section")
+	}
+
+	for name, b := range sections {
+		if name != deltaSectionGame {
+			fields[name] = b
+		}
+	}
+
+	b, err := json.Marshal(fields)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrClient,
+			"unable to encode game definition")
+	}
+
+	g.dc.rev, g.dc.sections = res.Rev, sections
+
+	return b, nil
+}
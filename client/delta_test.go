@@ -0,0 +1,86 @@
+package client_test
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/dhaifley/game2d/client"
+	"github.com/dhaifley/game2d/client/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeltaLoad(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		since []string
+	)
+
+	script, _ := json.Marshal(base64.StdEncoding.EncodeToString([]byte(
+		TestScript)))
+
+	ts := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			defer mu.Unlock()
+
+			assert.Equal(t, "/games/"+TestID+"/delta", r.URL.Path)
+
+			since = append(since, r.URL.Query().Get("since"))
+
+			name := "first"
+			if len(since) > 1 {
+				name = "second"
+			}
+
+			res := &api.GameDelta{
+				ID:  TestID,
+				Rev: strconv.Itoa(len(since)),
+				Sections: map[string]*api.GameDeltaSection{
+					"game": {Hash: name, Data: json.RawMessage(`{"w":` +
+						strconv.Itoa(client.DefaultGameWidth) + `,"h":` +
+						strconv.Itoa(client.DefaultGameHeight) +
+						`,"id":"` + TestID + `","name":"` + name + `"}`)},
+					"subject": {Hash: "subject"},
+					"objects": {Hash: "objects"},
+					"script":  {Hash: "script"},
+				},
+			}
+
+			// Unchanged sections are sent without their data.
+			if len(since) == 1 {
+				res.Sections["subject"].Data = json.RawMessage(
+					`{"id":"` + TestID + `","name":"` + TestName + `"}`)
+				res.Sections["objects"].Data = json.RawMessage(
+					`{"` + TestID + `":{"id":"` + TestID + `"}}`)
+				res.Sections["script"].Data = script
+			}
+
+			json.NewEncoder(w).Encode(res)
+		}))
+
+	t.Cleanup(ts.Close)
+
+	game := client.NewGame(nil, client.DefaultGameWidth,
+		client.DefaultGameHeight, "", TestName, TestDesc)
+
+	game.SetID(TestID)
+	game.SetAPIURL(ts.URL)
+
+	err := game.Load()
+	assert.NoError(t, err)
+	assert.Equal(t, "first", game.Name())
+
+	err = game.Load()
+	assert.NoError(t, err, "Load should reuse unchanged sections")
+	assert.Equal(t, "second", game.Name())
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	assert.Equal(t, []string{"", "1"}, since)
+}
@@ -0,0 +1,31 @@
+package client
+
+// Game events.
+const (
+	EventLoad  = "load"
+	EventPause = "pause"
+	EventScore = "score"
+)
+
+// EventHandler functions are called when game events occur, such as the game
+// being loaded or paused, or the score changing.
+type EventHandler func(event string, data map[string]any)
+
+// SetEventHandler sets the function called when game events occur.
+func (g *Game) SetEventHandler(h EventHandler) {
+	g.events = h
+}
+
+// Score returns the game score, which is set by the game script.
+func (g *Game) Score() int {
+	return g.score
+}
+
+// emit calls the event handler, if one is set, for a game event.
+func (g *Game) emit(event string, data map[string]any) {
+	if g.events == nil {
+		return
+	}
+
+	g.events(event, data)
+}
@@ -0,0 +1,35 @@
+package client_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/dhaifley/game2d/client"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEvents(t *testing.T) {
+	game := newRunningGame(t,
+		"function Update(data)\ndata.score = 5\nreturn data\nend", 1)
+
+	events := map[string]map[string]any{}
+
+	game.SetEventHandler(func(event string, data map[string]any) {
+		events[event] = data
+	})
+
+	b, err := json.Marshal(game)
+	assert.NoError(t, err)
+
+	game.SetData(b)
+
+	err = game.Load()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]any{"id": TestID, "name": TestName},
+		events[client.EventLoad])
+
+	err = game.Update()
+	assert.NoError(t, err)
+	assert.Equal(t, 5, game.Score())
+	assert.Equal(t, map[string]any{"score": 5}, events[client.EventScore])
+}
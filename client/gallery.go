@@ -0,0 +1,386 @@
+package client
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/dhaifley/game2d/errors"
+	"github.com/dhaifley/game2d/logger"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+// Gallery defaults.
+const (
+	galleryPageSize  = 100
+	galleryIconSize  = 48
+	galleryRowHeight = galleryIconSize + 8
+	galleryTop       = 40
+
+	// galleryRecommended is the number of recommended games listed first in
+	// the public gallery.
+	galleryRecommended = 5
+)
+
+// galleryEntry values represent the games listed in the gallery.
+type galleryEntry struct {
+	id, name, desc string
+	icon           *Image
+	players        int64
+	recommended    bool
+}
+
+// gallery values represent the game browser, used to switch between the games
+// available from the API without restarting the client.
+type gallery struct {
+	sync.Mutex
+	open    bool
+	public  bool
+	loading bool
+	seq     int
+	sel     int
+	entries []*galleryEntry
+	err     error
+}
+
+// OpenGallery opens the game gallery and starts retrieving the list of games
+// from the API.
+func (g *Game) OpenGallery(public bool) {
+	if g.apiURL == "" {
+		g.err = errors.New(errors.ErrClient,
+			"game gallery requires a game2d API URL")
+
+		return
+	}
+
+	g.gal.Lock()
+	defer g.gal.Unlock()
+
+	g.gal.open = true
+	g.gal.public = public
+	g.gal.loading = true
+	g.gal.sel = 0
+	g.gal.entries = nil
+	g.gal.err = nil
+	g.gal.seq++
+
+	go g.fetchGallery(g.gal.seq, public)
+}
+
+// CloseGallery closes the game gallery.
+func (g *Game) CloseGallery() {
+	g.gal.Lock()
+	defer g.gal.Unlock()
+
+	g.gal.open = false
+}
+
+// galleryOpen returns whether the game gallery is open.
+func (g *Game) galleryOpen() bool {
+	g.gal.Lock()
+	defer g.gal.Unlock()
+
+	return g.gal.open
+}
+
+// fetchGallery retrieves the list of games, either the account games or the
+// public games, from the API. The result is discarded if the gallery has been
+// opened again since the request, identified by seq, was made.
+func (g *Game) fetchGallery(seq int, public bool) {
+	entries, err := g.listGames(public)
+
+	g.gal.Lock()
+	defer g.gal.Unlock()
+
+	if g.gal.seq != seq {
+		return
+	}
+
+	g.gal.loading = false
+	g.gal.entries = entries
+	g.gal.err = err
+
+	if err != nil {
+		g.log.Log(context.Background(), logger.LvlError,
+			"unable to list games",
+			"error", err,
+			"public", public)
+	}
+}
+
+// galleryGame values represent the games listed by the API for the gallery.
+type galleryGame struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Icon        string `json:"icon"`
+}
+
+// entry returns the gallery entry of a listed game.
+func (gm *galleryGame) entry() *galleryEntry {
+	e := &galleryEntry{
+		id:   gm.ID,
+		name: gm.Name,
+		desc: gm.Description,
+	}
+
+	if ib, err := base64.StdEncoding.DecodeString(gm.Icon); err == nil &&
+		len(ib) > 0 {
+		e.icon = NewImage(gm.ID, gm.Name, ib,
+			galleryIconSize, galleryIconSize)
+	}
+
+	return e
+}
+
+// listGames retrieves the list of games from the API.
+func (g *Game) listGames(public bool) ([]*galleryEntry, error) {
+	q := url.Values{"size": []string{strconv.Itoa(galleryPageSize)}}
+
+	if public {
+		q.Set("search", `{"public":true}`)
+	}
+
+	b, err := g.apiRequest(http.MethodGet, nil, q, []int{http.StatusOK},
+		"games")
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrClient,
+			"unable to list games")
+	}
+
+	var games []*galleryGame
+
+	if err := json.Unmarshal(b, &games); err != nil {
+		return nil, errors.Wrap(err, errors.ErrClient,
+			"unable to decode games list")
+	}
+
+	entries := []*galleryEntry{}
+
+	listed := map[string]bool{}
+
+	if public {
+		for _, gm := range g.listRecommended() {
+			e := gm.entry()
+
+			e.recommended = true
+
+			entries = append(entries, e)
+
+			listed[gm.ID] = true
+		}
+	}
+
+	for _, gm := range games {
+		if !listed[gm.ID] {
+			entries = append(entries, gm.entry())
+		}
+	}
+
+	g.listLivePlayers(entries)
+
+	return entries, nil
+}
+
+// listRecommended retrieves the public games recommended to the user from the
+// API, based on the games the user has played. Recommendations are optional,
+// so failures are only logged.
+func (g *Game) listRecommended() []*galleryGame {
+	b, err := g.apiRequest(http.MethodGet, nil,
+		url.Values{"size": []string{strconv.Itoa(galleryRecommended)}},
+		[]int{http.StatusOK}, "games", "recommended")
+	if err != nil {
+		g.log.Log(context.Background(), logger.LvlDebug,
+			"unable to list recommended games",
+			"error", err)
+
+		return nil
+	}
+
+	var res []*galleryGame
+
+	if err := json.Unmarshal(b, &res); err != nil {
+		g.log.Log(context.Background(), logger.LvlDebug,
+			"unable to decode recommended games",
+			"error", err)
+
+		return nil
+	}
+
+	return res
+}
+
+// listLivePlayers retrieves the number of live players of the listed games
+// from the API. Live player counts are informational, so failures are only
+// logged.
+func (g *Game) listLivePlayers(entries []*galleryEntry) {
+	if len(entries) == 0 {
+		return
+	}
+
+	ids := make([]string, 0, len(entries))
+
+	for _, e := range entries {
+		ids = append(ids, e.id)
+	}
+
+	b, err := g.apiRequest(http.MethodGet, nil,
+		url.Values{"id": []string{strings.Join(ids, ",")}},
+		[]int{http.StatusOK}, "games", "live")
+	if err != nil {
+		g.log.Log(context.Background(), logger.LvlDebug,
+			"unable to list live players",
+			"error", err)
+
+		return
+	}
+
+	var live []struct {
+		GameID  string `json:"game_id"`
+		Players int64  `json:"players"`
+	}
+
+	if err := json.Unmarshal(b, &live); err != nil {
+		g.log.Log(context.Background(), logger.LvlDebug,
+			"unable to decode live players",
+			"error", err)
+
+		return
+	}
+
+	players := make(map[string]int64, len(live))
+
+	for _, l := range live {
+		players[l.GameID] = l.Players
+	}
+
+	for _, e := range entries {
+		e.players = players[e.id]
+	}
+}
+
+// updateGallery handles the gallery keyboard navigation each frame.
+func (g *Game) updateGallery() {
+	g.gal.Lock()
+
+	var sel *galleryEntry
+
+	switch {
+	case inpututil.IsKeyJustPressed(ebiten.KeyEscape):
+		g.gal.open = false
+	case inpututil.IsKeyJustPressed(ebiten.KeyTab):
+		public := !g.gal.public
+
+		g.gal.Unlock()
+
+		g.OpenGallery(public)
+
+		return
+	case inpututil.IsKeyJustPressed(ebiten.KeyArrowUp):
+		if g.gal.sel > 0 {
+			g.gal.sel--
+		}
+	case inpututil.IsKeyJustPressed(ebiten.KeyArrowDown):
+		if g.gal.sel < len(g.gal.entries)-1 {
+			g.gal.sel++
+		}
+	case inpututil.IsKeyJustPressed(ebiten.KeyEnter):
+		if g.gal.sel < len(g.gal.entries) {
+			sel = g.gal.entries[g.gal.sel]
+			g.gal.open = false
+		}
+	}
+
+	g.gal.Unlock()
+
+	if sel == nil {
+		return
+	}
+
+	id := g.id
+
+	g.id = sel.id
+
+	if err := g.Load(); err != nil {
+		g.log.Log(context.Background(), logger.LvlError,
+			"unable to load game",
+			"error", err,
+			"game_id", sel.id)
+
+		g.id = id
+	}
+
+	g.pause = true
+}
+
+// drawGallery renders the game gallery.
+func (g *Game) drawGallery(screen *ebiten.Image) {
+	g.gal.Lock()
+	defer g.gal.Unlock()
+
+	title := "My Games"
+	if g.gal.public {
+		title = "Public Games"
+	}
+
+	ebitenutil.DebugPrintAt(screen, title+
+		"  [Up/Down] select  [Enter] play  [Tab] switch  [Esc] close", 8, 8)
+
+	switch {
+	case g.gal.loading:
+		ebitenutil.DebugPrintAt(screen, "Loading...", 8, galleryTop)
+
+		return
+	case g.gal.err != nil:
+		ebitenutil.DebugPrintAt(screen, "Error: "+g.gal.err.Error(), 8,
+			galleryTop)
+
+		return
+	case len(g.gal.entries) == 0:
+		ebitenutil.DebugPrintAt(screen, "No games found", 8, galleryTop)
+
+		return
+	}
+
+	rows := max((screen.Bounds().Dy()-galleryTop)/galleryRowHeight, 1)
+
+	first := max(g.gal.sel-rows+1, 0)
+
+	for i := first; i < len(g.gal.entries) && i < first+rows; i++ {
+		e := g.gal.entries[i]
+
+		y := galleryTop + (i-first)*galleryRowHeight
+
+		if e.icon != nil {
+			if img := e.icon.image(); img != nil {
+				op := &ebiten.DrawImageOptions{}
+				op.GeoM.Translate(24, float64(y))
+
+				screen.DrawImage(img, op)
+			}
+		}
+
+		if i == g.gal.sel {
+			ebitenutil.DebugPrintAt(screen, ">", 8, y+galleryIconSize/2-8)
+		}
+
+		name := e.name
+		if e.recommended {
+			name += " (recommended for you)"
+		}
+
+		if e.players > 0 {
+			name += " (" + strconv.FormatInt(e.players, 10) + " playing)"
+		}
+
+		ebitenutil.DebugPrintAt(screen, name, 32+galleryIconSize, y+4)
+		ebitenutil.DebugPrintAt(screen, e.desc, 32+galleryIconSize, y+20)
+	}
+}
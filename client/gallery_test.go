@@ -0,0 +1,79 @@
+package client_test
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/dhaifley/game2d/assets"
+	"github.com/dhaifley/game2d/client"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGallery(t *testing.T) {
+	icon, err := assets.GetImage("avatar.svg")
+	assert.NoError(t, err)
+
+	queries := make(chan url.Values, 2)
+
+	ts := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/games" {
+				w.WriteHeader(http.StatusNotFound)
+
+				return
+			}
+
+			queries <- r.URL.Query()
+
+			w.Write([]byte(`[{"id":"` + TestID + `","name":"` + TestName +
+				`","icon":"` + base64.StdEncoding.EncodeToString(icon) +
+				`"}]`))
+		}))
+
+	t.Cleanup(ts.Close)
+
+	tests := []struct {
+		name   string
+		public bool
+		search string
+	}{{
+		name:   "account games",
+		public: false,
+		search: "",
+	}, {
+		name:   "public games",
+		public: true,
+		search: `{"public":true}`,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			game := client.NewGame(nil, client.DefaultGameWidth,
+				client.DefaultGameHeight, TestID, TestName, TestDesc)
+
+			game.SetAPIURL(ts.URL)
+			game.OpenGallery(tt.public)
+
+			select {
+			case q := <-queries:
+				assert.Equal(t, tt.search, q.Get("search"))
+				assert.NotEmpty(t, q.Get("size"))
+			case <-time.After(5 * time.Second):
+				t.Fatal("Timed out waiting for games request")
+			}
+
+			err := game.Update()
+			assert.NoError(t, err, "Update should not return an error")
+
+			game.Draw(ebiten.NewImage(client.DefaultGameWidth,
+				client.DefaultGameHeight))
+
+			game.CloseGallery()
+		})
+	}
+}
@@ -1,14 +1,11 @@
 package client
 
 import (
-	"bytes"
 	"context"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
-	"net/url"
 	"os"
 	"reflect"
 	"slices"
@@ -17,6 +14,7 @@ import (
 	"time"
 
 	"github.com/Shopify/go-lua"
+	"github.com/dhaifley/game2d/cbor"
 	"github.com/dhaifley/game2d/errors"
 	"github.com/dhaifley/game2d/logger"
 	"github.com/google/uuid"
@@ -32,31 +30,63 @@ var Version = ""
 const (
 	DefaultGameWidth  = 640
 	DefaultGameHeight = 480
+	DefaultGameFile   = "game2d.json"
+)
+
+// Game decoding limits, which protect the client from pathological game data,
+// such as that of untrusted users and AI responses.
+const (
+	MaxGameSize  = 64 * 1024 * 1024
+	MaxGameDepth = 64
 )
 
 // Game values represent the game state.
 type Game struct {
-	log      logger.Logger
-	debug    bool
-	pause    bool
-	public   bool
-	w, h     int
-	id       string
-	pid      string
-	name     string
-	ver      string
-	desc     string
-	icon     string
-	status   string
-	source   string
-	apiURL   string
-	apiToken string
-	lua      *lua.State
-	sub      *Object
-	obj      map[string]*Object
-	img      map[string]*Image
-	src      string
-	err      error
+	log        logger.Logger
+	debug      bool
+	pause      bool
+	public     bool
+	w, h       int
+	id         string
+	pid        string
+	name       string
+	ver        string
+	desc       string
+	icon       string
+	status     string
+	statusData map[string]any
+	source     string
+	score      int
+	apiURL     string
+	apiToken   string
+	file       string
+	data       []byte
+	fullscreen bool
+	scale      float64
+	pixel      bool
+	canvas     *ebiten.Image
+	lua        *lua.State
+	budget     int
+	compiled   bool
+	synced     bool
+	gal        gallery
+	menu       menu
+	bnd        binder
+	loc        localizer
+	wat        watcher
+	sq         syncer
+	hb         heartbeat
+	spec       spectate
+	dc         *deltaCache
+	events     EventHandler
+	sub        *Object
+	obj        map[string]*Object
+	img        map[string]*Image
+	bindings   map[string][]string
+	locales    map[string]map[string]string
+	src        string
+	trace      string
+	err        error
 }
 
 // NewGame creates and initializes a new Game object.
@@ -86,8 +116,13 @@ func NewGame(log logger.Logger, w, h int, id, name, desc string) *Game {
 		}
 	}
 
-	l := lua.NewState()
-	lua.OpenLibraries(l)
+	budget := DefaultScriptBudget
+
+	if bs := os.Getenv("GAME2D_SCRIPT_BUDGET"); bs != "" {
+		if i, err := strconv.Atoi(bs); err == nil {
+			budget = i
+		}
+	}
 
 	if _, err := uuid.Parse(id); err != nil {
 		id = ""
@@ -102,10 +137,12 @@ func NewGame(log logger.Logger, w, h int, id, name, desc string) *Game {
 		log:    log,
 		w:      w,
 		h:      h,
-		lua:    l,
+		lua:    newLuaState(),
+		budget: budget,
 		id:     id,
 		name:   name,
 		source: "app",
+		dc:     &deltaCache{},
 		obj:    make(map[string]*Object),
 		img:    make(map[string]*Image),
 	}
@@ -114,23 +151,28 @@ func NewGame(log logger.Logger, w, h int, id, name, desc string) *Game {
 // MarshalJSON serializes the game to JSON.
 func (g *Game) MarshalJSON() ([]byte, error) {
 	return json.Marshal(&struct {
-		Debug   bool               `json:"debug,omitempty"`
-		Pause   bool               `json:"pause,omitempty"`
-		Public  bool               `json:"public,omitempty"`
-		W       int                `json:"w"`
-		H       int                `json:"h"`
-		ID      string             `json:"id"`
-		PID     string             `json:"previous_id,omitempty"`
-		Name    string             `json:"name"`
-		Ver     string             `json:"version,omitempty"`
-		Desc    string             `json:"description,omitempty"`
-		Icon    string             `json:"icon,omitempty"`
-		Status  string             `json:"status,omitempty"`
-		Source  string             `json:"source,omitempty"`
-		Subject *Object            `json:"subject,omitempty"`
-		Objects map[string]*Object `json:"objects,omitempty"`
-		Images  map[string]*Image  `json:"images,omitempty"`
-		Script  string             `json:"script"`
+		Debug   bool                         `json:"debug,omitempty"`
+		Pause   bool                         `json:"pause,omitempty"`
+		Public  bool                         `json:"public,omitempty"`
+		W       int                          `json:"w"`
+		H       int                          `json:"h"`
+		ID      string                       `json:"id"`
+		PID     string                       `json:"previous_id,omitempty"`
+		Name    string                       `json:"name"`
+		Ver     string                       `json:"version,omitempty"`
+		Desc    string                       `json:"description,omitempty"`
+		Icon    string                       `json:"icon,omitempty"`
+		Status  string                       `json:"status,omitempty"`
+		StData  map[string]any               `json:"status_data,omitempty"`
+		Source  string                       `json:"source,omitempty"`
+		Score   int                          `json:"score,omitempty"`
+		Subject *Object                      `json:"subject,omitempty"`
+		Objects map[string]*Object           `json:"objects,omitempty"`
+		Images  map[string]*Image            `json:"images,omitempty"`
+		Script  string                       `json:"script"`
+		Binds   map[string][]string          `json:"bindings,omitempty"`
+		Locales map[string]map[string]string `json:"locales,omitempty"`
+		Rev     int64                        `json:"revision,omitempty"`
 	}{
 		Debug:   g.debug,
 		Pause:   g.pause,
@@ -144,43 +186,77 @@ func (g *Game) MarshalJSON() ([]byte, error) {
 		Desc:    g.desc,
 		Icon:    g.icon,
 		Status:  g.status,
+		StData:  g.statusData,
 		Source:  g.source,
+		Score:   g.score,
 		Subject: g.sub,
 		Objects: g.obj,
 		Images:  g.img,
 		Script:  base64.StdEncoding.EncodeToString([]byte(g.src)),
+		Binds:   g.bindings,
+		Locales: g.locales,
+		Rev:     g.revision(),
 	})
 }
 
 // UnmarshalJSON deserializes the game from JSON.
 func (g *Game) UnmarshalJSON(data []byte) error {
+	if err := checkGameJSON(data); err != nil {
+		return err
+	}
+
+	return g.unmarshal(data, json.Unmarshal)
+}
+
+// UnmarshalCBOR deserializes the game from CBOR.
+func (g *Game) UnmarshalCBOR(data []byte) error {
+	return g.unmarshal(data, cbor.Unmarshal)
+}
+
+// unmarshal deserializes the game using an unmarshal function.
+func (g *Game) unmarshal(data []byte,
+	unmarshal func([]byte, any) error,
+) error {
+	if len(data) > MaxGameSize {
+		return errors.New(errors.ErrClient,
+			"game data exceeds maximum size",
+			"size", len(data),
+			"max_size", MaxGameSize)
+	}
+
 	v := &struct {
-		Debug   bool               `json:"debug,omitempty"`
-		Pause   bool               `json:"pause,omitempty"`
-		Public  bool               `json:"public,omitempty"`
-		W       int                `json:"w"`
-		H       int                `json:"h"`
-		ID      string             `json:"id"`
-		PID     string             `json:"previous_id,omitempty"`
-		Name    string             `json:"name"`
-		Ver     string             `json:"version,omitempty"`
-		Desc    string             `json:"description,omitempty"`
-		Icon    string             `json:"icon,omitempty"`
-		Status  string             `json:"status,omitempty"`
-		Source  string             `json:"source,omitempty"`
-		Subject *Object            `json:"subject,omitempty"`
-		Objects map[string]*Object `json:"objects,omitempty"`
-		Images  map[string]*Image  `json:"images,omitempty"`
-		Script  string             `json:"script"`
+		Debug   bool                         `json:"debug,omitempty"`
+		Pause   bool                         `json:"pause,omitempty"`
+		Public  bool                         `json:"public,omitempty"`
+		W       int                          `json:"w"`
+		H       int                          `json:"h"`
+		ID      string                       `json:"id"`
+		PID     string                       `json:"previous_id,omitempty"`
+		Name    string                       `json:"name"`
+		Ver     string                       `json:"version,omitempty"`
+		Desc    string                       `json:"description,omitempty"`
+		Icon    string                       `json:"icon,omitempty"`
+		Status  string                       `json:"status,omitempty"`
+		StData  map[string]any               `json:"status_data,omitempty"`
+		Source  string                       `json:"source,omitempty"`
+		Score   int                          `json:"score,omitempty"`
+		Subject *Object                      `json:"subject,omitempty"`
+		Objects map[string]*Object           `json:"objects,omitempty"`
+		Images  map[string]*Image            `json:"images,omitempty"`
+		Script  string                       `json:"script"`
+		Binds   map[string][]string          `json:"bindings,omitempty"`
+		Locales map[string]map[string]string `json:"locales,omitempty"`
+		Rev     int64                        `json:"revision,omitempty"`
 	}{}
 
-	if err := json.Unmarshal(data, &v); err != nil {
+	if err := unmarshal(data, v); err != nil {
 		return err
 	}
 
 	b, err := base64.StdEncoding.DecodeString(v.Script)
 	if err != nil {
-		return err
+		return errors.Wrap(err, errors.ErrClient,
+			"unable to decode game script")
 	}
 
 	g.debug = v.Debug
@@ -193,17 +269,62 @@ func (g *Game) UnmarshalJSON(data []byte) error {
 	g.desc = v.Desc
 	g.icon = v.Icon
 	g.status = v.Status
+	g.statusData = v.StData
 	g.source = v.Source
+	g.score = v.Score
 	g.debug = v.Debug
 	g.w = v.W
 	g.h = v.H
 	g.sub = v.Subject
 	g.obj = v.Objects
 	g.img = v.Images
+	g.bindings = v.Binds
+	g.locales = v.Locales
 	g.src = string(b)
 
-	g.lua = lua.NewState()
-	lua.OpenLibraries(g.lua)
+	g.setRevision(v.Rev)
+
+	g.lua = newLuaState()
+	g.compiled = false
+	g.synced = false
+	g.loc.synced = false
+
+	if g.log == nil {
+		g.log = logger.NullLog
+	}
+
+	return nil
+}
+
+// checkGameJSON returns an error if JSON game data nests objects and arrays
+// deeper than the maximum game depth. It is checked before decoding, so that
+// pathological data is rejected without being parsed.
+func checkGameJSON(data []byte) error {
+	depth, str, esc := 0, false, false
+
+	for _, c := range data {
+		switch {
+		case esc:
+			esc = false
+		case str:
+			switch c {
+			case '\\':
+				esc = true
+			case '"':
+				str = false
+			}
+		case c == '"':
+			str = true
+		case c == '{' || c == '[':
+			if depth++; depth > MaxGameDepth {
+				return errors.New(errors.ErrClient,
+					"game data exceeds maximum depth",
+					"max_depth", MaxGameDepth)
+			}
+		case c == '}' || c == ']':
+			depth--
+		}
+	}
 
 	return nil
 }
@@ -268,9 +389,37 @@ func (g *Game) SetAPIToken(apiToken string) {
 	g.apiToken = apiToken
 }
 
+// File returns the game file path or URL.
+func (g *Game) File() string {
+	return g.file
+}
+
+// SetFile sets the game file. It may be a local file path or an HTTP(S) URL,
+// and, if set, the game is loaded from it instead of from the API.
+func (g *Game) SetFile(file string) {
+	g.file = file
+}
+
+// SetData sets a game definition, which, if set, is loaded instead of the game
+// file or the API game. It is used to play packaged games.
+func (g *Game) SetData(data []byte) {
+	g.data = data
+}
+
+// SetFullscreen sets whether the game window is fullscreen.
+func (g *Game) SetFullscreen(fullscreen bool) {
+	g.fullscreen = fullscreen
+}
+
+// SetScale sets the scale of the game window relative to the game size.
+func (g *Game) SetScale(scale float64) {
+	g.scale = scale
+}
+
 // AddSubject adds a subject to the game.
 func (g *Game) AddSubject(sub *Object) {
 	g.sub = sub
+	g.synced = false
 }
 
 // AddObject adds an object to the game.
@@ -284,6 +433,7 @@ func (g *Game) AddObject(obj *Object) {
 	}
 
 	g.obj[obj.id] = obj
+	g.synced = false
 }
 
 // AddImage adds an image to the game.
@@ -302,14 +452,74 @@ func (g *Game) AddImage(img *Image) {
 // SetScript sets the game script.
 func (g *Game) SetScript(src string) {
 	g.src = src
+	g.compiled = false
+}
+
+// SetScriptBudget sets the maximum number of instructions the game script may
+// execute each frame. A budget less than one disables the limit.
+func (g *Game) SetScriptBudget(budget int) {
+	g.budget = budget
 }
 
 // Update updates the game state each frame.
 func (g *Game) Update() error {
-	keyMap := map[string]any{}
+	defer g.recoverPanic()
+
+	g.updateHeartbeat()
+
+	if g.menu.quit {
+		return ebiten.Termination
+	}
+
+	if g.galleryOpen() {
+		g.updateGallery()
+
+		return nil
+	}
+
+	if g.bindingsOpen() {
+		g.updateBindings()
+
+		return nil
+	}
+
+	if g.menuOpen() {
+		g.updateMenu()
+
+		if g.menu.quit {
+			return ebiten.Termination
+		}
+
+		return nil
+	}
+
+	if g.Spectating() != "" {
+		g.updateSpectate()
+
+		return nil
+	}
+
+	if err := g.reload(); err != nil {
+		g.log.Log(context.Background(), logger.LvlError,
+			"unable to reload changed game definition",
+			"error", err)
+
+		g.err = err
+	}
+
+	g.updateSync()
+
+	paused, score := g.pause, g.score
+
+	keyMap, actions := map[string]any{}, map[string]any{}
 
 	debug, save, load, pause, reset := false, false, false, false, false
 
+	openMenu := gamepadJustPressed(ebiten.StandardGamepadButtonCenterRight)
+
+	gallery, bindings, fullscreen, pixelPerfect, zoom := false, false, false,
+		false, 0
+
 	if keys := inpututil.AppendPressedKeys(nil); len(keys) > 0 {
 		if slices.Contains(keys, ebiten.KeyControl) {
 			if jpk := inpututil.AppendJustPressedKeys(nil); len(jpk) > 0 {
@@ -322,16 +532,28 @@ func (g *Game) Update() error {
 					case ebiten.KeyL:
 						load = true
 					case ebiten.KeyP:
-						pause = true
+						openMenu = true
 					case ebiten.KeyQ:
 						reset = true
+					case ebiten.KeyG:
+						gallery = true
+					case ebiten.KeyB:
+						bindings = true
+					case ebiten.KeyF:
+						fullscreen = true
+					case ebiten.KeyI:
+						pixelPerfect = true
+					case ebiten.KeyEqual:
+						zoom++
+					case ebiten.KeyMinus:
+						zoom--
 					}
 				}
 			}
 		} else {
-			for i, k := range keys {
-				keyMap[strconv.Itoa(i)] = int(k)
-			}
+			keyMap = scriptKeys(keys)
+
+			actions = g.actions(keys)
 
 			if g.pause && len(keyMap) > 0 {
 				pause = true
@@ -340,65 +562,23 @@ func (g *Game) Update() error {
 	}
 
 	if !g.pause && g.src != "" {
-		objects := make(map[string]any, len(g.obj))
-
-		for k, obj := range g.obj {
-			objects[k] = obj.Map()
-		}
-
 		if g.sub == nil {
 			return errors.New(errors.ErrClient,
 				"game subject object not found",
 				"game", g)
 		}
 
-		d := map[string]any{
-			"id":      g.id,
-			"name":    g.name,
-			"debug":   g.debug,
-			"w":       g.w,
-			"h":       g.h,
-			"subject": g.sub.Map(),
-			"objects": objects,
-			"keys":    keyMap,
-		}
-
-		buf := bytes.NewBufferString(g.src)
-
-		if err := g.lua.Load(buf, "Update", "text"); err != nil {
-			return errors.Wrap(err, errors.ErrClient,
-				"unable to load script",
-				"game", g,
-				"script", g.src)
-		}
-
-		g.lua.Call(0, 0)
-
-		g.lua.Global("Update")
-
-		if !g.lua.IsFunction(-1) {
-			return errors.New(errors.ErrClient,
-				"no Update function in script",
-				"game", g,
-				"script", g.src)
-		}
-
-		pushMap(g.lua, d)
-
-		g.lua.Call(1, 1)
+		if err := g.runScript(keyMap, actions); err != nil {
+			g.log.Log(context.Background(), logger.LvlError,
+				"unable to run game script",
+				"error", err)
 
-		luaState, err := pullMap(g.lua)
-		if err != nil {
-			return errors.Wrap(err, errors.ErrClient,
-				"unable to retrieve game state from lua")
+			g.scriptFailed(err)
 		}
 
-		delete(luaState, "keys")
+		g.menu.selected = ""
 
-		if err := g.updateFromMap(luaState); err != nil {
-			return errors.Wrap(err, errors.ErrClient,
-				"unable to update game state from lua")
-		}
+		g.updatePublish()
 	}
 
 	if debug {
@@ -427,36 +607,66 @@ func (g *Game) Update() error {
 		g.pause = !g.pause
 	}
 
+	g.updateWindow(fullscreen, pixelPerfect, zoom)
+
+	if gallery {
+		g.pause = true
+
+		g.OpenGallery(false)
+	}
+
+	if bindings {
+		g.pause = true
+
+		g.openBindings()
+	}
+
+	if openMenu {
+		g.OpenMenu()
+	}
+
+	if g.pause != paused {
+		g.emit(EventPause, map[string]any{"paused": g.pause})
+	}
+
+	if g.score != score {
+		g.emit(EventScore, map[string]any{"score": g.score})
+	}
+
 	return nil
 }
 
 // Draw renders the game state and all objects each frame.
 func (g *Game) Draw(screen *ebiten.Image) {
-	zi := map[int][]*Object{}
+	defer g.recoverPanic()
 
-	for _, obj := range g.obj {
-		if obj == nil || obj.hidden {
-			continue
-		}
+	if g.galleryOpen() {
+		g.drawGallery(screen)
 
-		if _, ok := zi[obj.z]; !ok {
-			zi[obj.z] = []*Object{}
-		}
+		return
+	}
 
-		zi[obj.z] = append(zi[obj.z], obj)
+	if g.bindingsOpen() {
+		g.drawBindings(screen)
+
+		return
 	}
 
-	indexes := make([]int, 0, len(zi))
-	for i := range zi {
-		indexes = append(indexes, i)
+	if g.pixel {
+		g.drawPixelPerfect(screen)
+	} else {
+		g.drawGame(screen)
 	}
 
-	slices.Sort(indexes)
+	if g.menuOpen() {
+		g.drawMenu(screen)
+	}
+}
 
-	for i := range indexes {
-		for _, obj := range zi[i] {
-			obj.Draw(screen)
-		}
+// drawGame renders the game objects, and the debug overlay, to an image.
+func (g *Game) drawGame(screen *ebiten.Image) {
+	for _, obj := range g.drawOrder() {
+		obj.Draw(screen)
 	}
 
 	if g.sub != nil {
@@ -472,8 +682,17 @@ func (g *Game) Draw(screen *ebiten.Image) {
 	}
 }
 
-// Layout returns the game object dimensions.
+// Layout returns the game screen dimensions. Unless the game is in
+// pixel-perfect mode, the game size follows the window size, divided by the
+// window scale.
 func (g *Game) Layout(w, h int) (int, int) {
+	if g.pixel {
+		return g.layoutPixelPerfect(w, h)
+	}
+
+	w = int(float64(w) / g.windowScale())
+	h = int(float64(h) / g.windowScale())
+
 	if g.w == 0 || g.h == 0 {
 		g.w = w
 		g.h = h
@@ -506,7 +725,7 @@ func (g *Game) Save() (rErr error) {
 			g.err = rErr
 		}
 
-		ebiten.SetWindowTitle(g.name)
+		ebiten.SetWindowTitle(g.title())
 	}()
 
 	b, err := json.MarshalIndent(&g, "", "  ")
@@ -515,145 +734,137 @@ func (g *Game) Save() (rErr error) {
 			"unable to encode game save")
 	}
 
-	if g.apiURL != "" {
-		u, err := url.Parse(g.apiURL)
-		if err != nil {
-			return errors.Wrap(err, errors.ErrClient,
-				"unable to parse game2d API URL",
-				"api_url", g.apiURL)
-		}
-
-		u = u.JoinPath("games")
-
-		apiURL := u.String()
-
-		req, err := http.NewRequest(http.MethodPost, apiURL,
-			bytes.NewBuffer(b))
-		if err != nil {
-			return errors.Wrap(err, errors.ErrClient,
-				"unable to create save request",
-				"api_url", apiURL)
-		}
+	file := DefaultGameFile
 
-		req.Header.Set("Accept", "application/json")
-		req.Header.Set("User-Agent", "game2d")
-		req.Header.Set("X-Game-ID", g.id)
-
-		if g.apiToken != "" {
-			req.Header.Set("Authorization", "Bearer "+g.apiToken)
-		}
-
-		resp, err := http.DefaultClient.Do(req)
-		if err != nil {
-			return errors.Wrap(err, errors.ErrClient,
-				"unable to save game",
-				"api_url", apiURL)
-		}
-
-		defer resp.Body.Close()
+	if g.file != "" && !isURL(g.file) {
+		file = g.file
+	}
 
-		rb, err := io.ReadAll(resp.Body)
-		if err != nil {
-			return errors.Wrap(err, errors.ErrClient,
-				"unable to read save game response",
-				"api_url", apiURL)
-		}
-
-		if resp.StatusCode != http.StatusCreated &&
-			resp.StatusCode != http.StatusOK {
-			return errors.New(errors.ErrClient,
-				"unable to save game",
-				"api_url", apiURL,
-				"status_code", resp.StatusCode,
-				"response", string(rb))
+	if g.apiURL != "" && file == DefaultGameFile {
+		if err := g.saveAPI(b); err != nil {
+			return err
 		}
 	} else {
-		if err := os.WriteFile("game2d.json", b, 0o644); err != nil {
+		if err := os.WriteFile(file, b, 0o644); err != nil {
 			return errors.Wrap(err, errors.ErrClient,
 				"unable to write game save",
-				"file", g.name+".json")
+				"file", file)
 		}
 	}
 
 	return nil
 }
 
-// Load retrieves a persisted game state.
-func (g *Game) Load() (rErr error) {
-	var b []byte
+// fetch retrieves a persisted game definition, from the game data, if it is
+// set, or from the game file, if one is set, or from the API, if an API URL is
+// set, or else from the default game file.
+func (g *Game) fetch() ([]byte, error) {
+	switch {
+	case g.data != nil:
+		return g.data, nil
+	case isURL(g.file):
+		rb, err := getURL(g.file)
+		if err != nil {
+			return nil, errors.Wrap(err, errors.ErrClient,
+				"unable to load game",
+				"url", g.file)
+		}
 
-	ebiten.SetWindowTitle(g.name + " (loading...)")
+		return rb, nil
+	case g.file == "" && g.apiURL != "":
+		if qb, err := g.queued(); err == nil && qb != nil {
+			return qb, nil
+		}
 
-	defer func() {
-		ebiten.SetWindowTitle(g.name)
-		g.err = rErr
-	}()
+		// Only the sections of the game which changed since it was last
+		// retrieved are downloaded, if possible.
+		db, err := g.fetchDelta()
+		if err == nil {
+			g.setOffline(false)
 
-	if g.apiURL != "" {
-		u, err := url.Parse(g.apiURL)
-		if err != nil {
-			return errors.Wrap(err, errors.ErrClient,
-				"unable to parse game2d API URL",
-				"api_url", g.apiURL)
+			return db, nil
 		}
 
-		u = u.JoinPath("games", g.id)
+		if errors.Has(err, errors.ErrUnavailable) {
+			g.setOffline(true)
 
-		apiURL := u.String()
+			return nil, errors.Wrap(err, errors.ErrClient,
+				"unable to load game")
+		}
 
-		req, err := http.NewRequest(http.MethodGet, apiURL, nil)
+		// Games can be large, so the more compact CBOR encoding is preferred.
+		rb, err := g.apiRequestAccept(http.MethodGet,
+			"application/cbor, application/json;q=0.9", nil, nil,
+			[]int{http.StatusOK}, "games", g.id)
 		if err != nil {
-			return errors.Wrap(err, errors.ErrClient,
-				"unable to create load request",
-				"api_url", apiURL)
+			if errors.Has(err, errors.ErrUnavailable) {
+				g.setOffline(true)
+			}
+
+			return nil, errors.Wrap(err, errors.ErrClient,
+				"unable to load game")
 		}
 
-		req.Header.Set("Accept", "application/json")
-		req.Header.Set("User-Agent", "game2d")
-		req.Header.Set("X-Game-ID", g.id)
+		g.setOffline(false)
 
-		if g.apiToken != "" {
-			req.Header.Set("Authorization", "Bearer "+g.apiToken)
+		return rb, nil
+	default:
+		file := g.file
+		if file == "" {
+			file = DefaultGameFile
 		}
 
-		resp, err := http.DefaultClient.Do(req)
+		fb, err := os.ReadFile(file)
 		if err != nil {
-			return errors.Wrap(err, errors.ErrClient,
+			return nil, errors.Wrap(err, errors.ErrClient,
 				"unable to load game",
-				"api_url", apiURL)
+				"file", file)
 		}
 
-		defer resp.Body.Close()
+		return fb, nil
+	}
+}
 
-		rb, err := io.ReadAll(resp.Body)
-		if err != nil {
-			return errors.Wrap(err, errors.ErrClient,
-				"unable to read load game response",
-				"api_url", apiURL)
-		}
+// Load retrieves a persisted game state.
+func (g *Game) Load() (rErr error) {
+	ebiten.SetWindowTitle(g.name + " (loading...)")
 
-		if resp.StatusCode != http.StatusOK {
-			return errors.New(errors.ErrClient,
-				"unable to load game",
-				"api_url", apiURL,
-				"status_code", resp.StatusCode,
-				"response", string(rb))
-		}
+	defer func() {
+		ebiten.SetWindowTitle(g.title())
+		g.err = rErr
+	}()
 
-		b = rb
-	} else {
-		if fb, err := os.ReadFile("game2d.json"); err != nil {
-			return errors.Wrap(err, errors.ErrClient,
-				"unable to load game",
-				"file", g.name+".json")
-		} else {
-			b = fb
-		}
+	b, err := g.fetch()
+	if err != nil {
+		return err
+	}
+
+	if err := g.apply(b); err != nil {
+		return err
 	}
 
+	g.loaded(b)
+
+	g.emit(EventLoad, map[string]any{
+		"id":   g.id,
+		"name": g.name,
+	})
+
+	return nil
+}
+
+// apply replaces the game state with a persisted game state.
+func (g *Game) apply(b []byte) error {
 	var g2 Game
 
-	if err := json.Unmarshal(b, &g2); err != nil {
+	// Games retrieved from the API may be CBOR encoded, in which case the
+	// data begins with a map.
+	unmarshal := json.Unmarshal
+	if len(b) > 0 && b[0]>>5 == 5 {
+		unmarshal = cbor.Unmarshal
+	}
+
+	if err := unmarshal(b, &g2); err != nil {
 		return errors.Wrap(err, errors.ErrClient,
 			"unable to decode game save")
 	}
@@ -661,7 +872,7 @@ func (g *Game) Load() (rErr error) {
 	if g2.w <= 0 || g2.h <= 0 {
 		return errors.New(errors.ErrClient,
 			"game save data not found",
-			"game", g2)
+			"game", &g2)
 	}
 
 	g.debug = g2.debug
@@ -676,14 +887,20 @@ func (g *Game) Load() (rErr error) {
 	g.desc = g2.desc
 	g.icon = g2.icon
 	g.status = g2.status
+	g.statusData = g2.statusData
 	g.source = g2.source
+	g.score = g2.score
 	g.img = g2.img
+	g.bindings = g2.bindings
+	g.locales = g2.locales
 	g.src = g2.src
 
+	g.setRevision(g2.revision())
+
 	if g2.sub == nil {
 		return errors.New(errors.ErrClient,
 			"game subject object not found",
-			"game", g2)
+			"game", &g2)
 	}
 
 	g.sub = g2.sub
@@ -692,7 +909,7 @@ func (g *Game) Load() (rErr error) {
 	if len(g2.obj) == 0 {
 		return errors.New(errors.ErrClient,
 			"game objects not found",
-			"game", g2)
+			"game", &g2)
 	}
 
 	g.obj = g2.obj
@@ -705,17 +922,22 @@ func (g *Game) Load() (rErr error) {
 		g.obj[i].game = g
 	}
 
-	g.lua = lua.NewState()
-	lua.OpenLibraries(g.lua)
+	g.lua = newLuaState()
+	g.compiled = false
+	g.synced = false
+	g.loc.synced = false
 
 	return nil
 }
 
 // Run starts the game processing.
 func (g *Game) Run(ctx context.Context) error {
+	scale := g.windowScale()
+
 	ebiten.SetWindowResizingMode(ebiten.WindowResizingModeEnabled)
-	ebiten.SetWindowSize(g.w, g.h)
+	ebiten.SetWindowSize(int(float64(g.w)*scale), int(float64(g.h)*scale))
 	ebiten.SetWindowTitle(g.name)
+	ebiten.SetFullscreen(g.fullscreen)
 
 	go func() {
 		time.Sleep(50 * time.Millisecond)
@@ -726,7 +948,19 @@ func (g *Game) Run(ctx context.Context) error {
 				"error", err)
 
 			g.err = err
+
+			g.loaded(nil)
 		}
+
+		go g.Sync(ctx)
+
+		go g.Heartbeat(ctx)
+
+		go g.Publish(ctx)
+
+		go g.Spectate(ctx)
+
+		g.Watch(ctx)
 	}()
 
 	if err := ebiten.RunGame(g); err != nil {
@@ -803,10 +1037,10 @@ func tableToMap(l *lua.State, index int) (any, error) {
 	resA := make([]any, 0)
 
 	for l.Next(-2) {
-		if l.IsString(-2) {
+		if l.TypeOf(-2) == lua.TypeString {
 			key, _ := l.ToString(-2)
 			result[key] = getValue(l, -1)
-		} else if l.IsNumber(-2) {
+		} else if l.TypeOf(-2) == lua.TypeNumber {
 			resA = append(resA, getValue(l, -1))
 		} else {
 			break
@@ -848,89 +1082,104 @@ func getValue(l *lua.State, index int) any {
 	}
 }
 
-// pullMap retrieves a map from the lua global table.
-func pullMap(l *lua.State) (map[string]any, error) {
-	if !l.IsTable(-1) {
-		l.Pop(1)
-
-		return nil, errors.New(errors.ErrClient,
-			"game table not found")
-	}
+// fieldValue returns the value of the key field of the table at index on the
+// lua stack, without invoking any metamethods.
+func fieldValue(l *lua.State, index int, key string) any {
+	index = l.AbsIndex(index)
 
-	val, err := tableToMap(l, -1)
-	if err != nil {
-		return nil, err
-	}
+	l.PushString(key)
+	l.RawGet(index)
 
-	result, ok := val.(map[string]any)
-	if !ok {
-		return nil, errors.New(errors.ErrClient,
-			"invalid game definition received")
-	}
+	v := getValue(l, -1)
 
 	l.Pop(1)
 
-	return result, err
+	return v
 }
 
-// updateFromMap updates the game state from a map retrieved from lua.
-func (g *Game) updateFromMap(m map[string]any) error {
-	if m == nil {
-		return nil
-	}
+// updateFromLua updates the game state from the game table, at index on the
+// lua stack. Existing objects are updated in place.
+func (g *Game) updateFromLua(l *lua.State, index int) error {
+	index = l.AbsIndex(index)
 
-	if v, ok := m["debug"].(bool); ok {
+	if v, ok := fieldValue(l, index, "debug").(bool); ok {
 		g.debug = v
 	}
 
-	if v, ok := m["pause"].(bool); ok {
+	if v, ok := fieldValue(l, index, "pause").(bool); ok {
 		g.pause = v
 	}
 
-	if v, ok := m["id"].(string); ok {
+	if v, ok := fieldValue(l, index, "id").(string); ok {
 		g.id = v
 	}
 
-	if v, ok := m["name"].(string); ok {
+	if v, ok := fieldValue(l, index, "name").(string); ok {
 		g.name = v
 	}
 
-	if v, ok := m["w"].(int); ok {
-		g.w = v
+	if v, ok := fieldValue(l, index, "score").(float64); ok {
+		g.score = int(v)
 	}
 
-	if v, ok := m["h"].(int); ok {
-		g.h = v
+	if v, ok := fieldValue(l, index, "menu").([]any); ok {
+		g.setMenu(v)
 	}
 
-	if v, ok := m["subject"].(map[string]any); ok {
-		obj := NewObjectFromMap(v)
-		if obj == nil {
+	l.PushString("subject")
+	l.RawGet(index)
+
+	if l.IsTable(-1) {
+		sub := g.sub
+		if sub == nil {
+			sub = &Object{}
+		}
+
+		if !sub.updateFromLua(l, -1) {
+			l.Pop(1)
+
 			return errors.New(errors.ErrClient,
 				"game subject object not found",
 				"game", g)
 		}
 
-		g.sub = obj
+		g.sub = sub
 		g.sub.game = g
 	}
 
-	if v, ok := m["objects"].(map[string]any); ok {
-		g.obj = make(map[string]*Object, len(v))
+	l.Pop(1)
+
+	l.PushString("objects")
+	l.RawGet(index)
+
+	if l.IsTable(-1) {
+		objects := make(map[string]*Object, len(g.obj))
+
+		l.PushNil()
+
+		for l.Next(-2) {
+			if l.TypeOf(-2) == lua.TypeString && l.IsTable(-1) {
+				id, _ := l.ToString(-2)
 
-		for id, v := range v {
-			if vv, ok := v.(map[string]any); ok {
-				obj := NewObjectFromMap(vv)
+				obj := g.obj[id]
 				if obj == nil {
-					continue
+					obj = &Object{}
 				}
 
-				obj.game = g
+				if obj.updateFromLua(l, -1) {
+					obj.game = g
 
-				g.obj[id] = obj
+					objects[id] = obj
+				}
 			}
+
+			l.Pop(1)
 		}
+
+		g.obj = objects
 	}
 
+	l.Pop(1)
+
 	return nil
 }
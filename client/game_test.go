@@ -1,11 +1,20 @@
 package client_test
 
 import (
+	"encoding/base64"
 	"encoding/json"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"testing"
 
+	"github.com/dhaifley/game2d/assets"
+	"github.com/dhaifley/game2d/cbor"
 	"github.com/dhaifley/game2d/client"
+	"github.com/dhaifley/game2d/client/luaapi"
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/stretchr/testify/assert"
 )
@@ -36,6 +45,167 @@ func TestUpdate(t *testing.T) {
 	assert.NoError(t, err, "Update should not return an error")
 }
 
+// newRunningGame creates an unpaused game using a script and the specified
+// number of objects.
+func newRunningGame(t testing.TB, script string, objects int) *client.Game {
+	t.Helper()
+
+	obj := make(map[string]any, objects)
+
+	for i := range objects {
+		id := "obj_" + strconv.Itoa(i)
+
+		obj[id] = map[string]any{"id": id, "name": id, "x": i, "y": i}
+	}
+
+	b, err := json.Marshal(map[string]any{
+		"w":       client.DefaultGameWidth,
+		"h":       client.DefaultGameHeight,
+		"id":      TestID,
+		"name":    TestName,
+		"subject": map[string]any{"id": TestID, "name": TestName},
+		"objects": obj,
+		"script":  base64.StdEncoding.EncodeToString([]byte(script)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var game client.Game
+
+	if err := json.Unmarshal(b, &game); err != nil {
+		t.Fatal(err)
+	}
+
+	return &game
+}
+
+func TestUpdateScriptErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+	}{{
+		name:   "budget exceeded",
+		script: "function Update(data)\nwhile true do end\nend",
+	}, {
+		name:   "os library",
+		script: "function Update(data)\nos.exit(1)\nend",
+	}, {
+		name:   "io library",
+		script: "function Update(data)\nio.open(\"game2d.json\")\nend",
+	}, {
+		name:   "dofile",
+		script: "function Update(data)\ndofile(\"game2d.json\")\nend",
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			game := newRunningGame(t, tt.script, 0)
+
+			game.SetScriptBudget(1000)
+
+			err := game.Update()
+			assert.NoError(t, err, "Update should not return an error")
+
+			b, err := json.Marshal(game)
+			assert.NoError(t, err)
+
+			assert.Contains(t, string(b), `"status":"error"`,
+				"Game status should be error")
+		})
+	}
+}
+
+func TestLuaAPI(t *testing.T) {
+	script := "function Update(data)\n"
+
+	for _, lib := range luaapi.Libraries {
+		for _, f := range lib.Functions {
+			fn := lib.Name + "." + f
+			if lib.Name == "_G" {
+				fn = f
+			}
+
+			script += "assert(type(" + fn + ") == \"function\", \"" + fn +
+				"\")\n"
+		}
+	}
+
+	for _, g := range luaapi.RemovedGlobals {
+		script += "assert(" + g + " == nil, \"" + g + "\")\n"
+	}
+
+	for _, f := range luaapi.Functions {
+		script += "assert(type(" + f.Name + ") == \"function\", \"" +
+			f.Name + "\")\n"
+	}
+
+	script += "return data\nend"
+
+	game := newRunningGame(t, script, 0)
+
+	err := game.Update()
+	assert.NoError(t, err, "Update should not return an error")
+
+	b, err := json.Marshal(game)
+	assert.NoError(t, err)
+
+	m := map[string]any{}
+
+	assert.NoError(t, json.Unmarshal(b, &m))
+
+	assert.NotEqual(t, "error", m["status"],
+		"Lua environment should match its description: %v", m["status_data"])
+
+	for _, k := range luaapi.Keys {
+		assert.Equal(t, k.Name, ebiten.Key(k.Code).String(),
+			"Key code should match its description")
+	}
+
+	assert.Len(t, luaapi.Keys, int(ebiten.KeyMax)+1,
+		"All keys should be described")
+}
+
+func BenchmarkUpdate(b *testing.B) {
+	script, err := assets.GetScript("avatar.lua")
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	for _, n := range []int{10, 100, 500, 1000} {
+		b.Run(strconv.Itoa(n)+"_objects", func(b *testing.B) {
+			game := newRunningGame(b, script, n)
+
+			b.ReportAllocs()
+
+			for b.Loop() {
+				if err := game.Update(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkUpdateCompile(b *testing.B) {
+	script, err := assets.GetScript("avatar.lua")
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	game := newRunningGame(b, script, 100)
+
+	b.ReportAllocs()
+
+	for b.Loop() {
+		game.SetScript(script)
+
+		if err := game.Update(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
 func TestDraw(t *testing.T) {
 	game := client.NewGame(nil, client.DefaultGameWidth,
 		client.DefaultGameHeight, TestID, TestName, TestDesc)
@@ -77,6 +247,53 @@ func TestGameJSONMarshaling(t *testing.T) {
 		"Original and unmarshaled values should be equal")
 }
 
+func TestGameUnmarshalLimits(t *testing.T) {
+	var game client.Game
+
+	deep := `{"status_data":` + strings.Repeat(`{"a":`, client.MaxGameDepth) +
+		`1` + strings.Repeat(`}`, client.MaxGameDepth+1)
+
+	err := json.Unmarshal([]byte(deep), &game)
+	assert.Error(t, err, "Deeply nested games should be rejected")
+
+	err = game.UnmarshalJSON(make([]byte, client.MaxGameSize+1))
+	assert.Error(t, err, "Oversized games should be rejected")
+
+	err = json.Unmarshal([]byte(`{"script":"not base64!"}`), &game)
+	assert.Error(t, err, "Invalid scripts should be rejected")
+}
+
+func FuzzGameUnmarshalJSON(f *testing.F) {
+	game := client.NewGame(nil, client.DefaultGameWidth,
+		client.DefaultGameHeight, TestID, TestName, TestDesc)
+
+	game.SetScript(TestScript)
+	game.AddSubject(client.NewSubject(game, TestID, TestName, TestID, nil))
+	game.AddObject(client.NewObject(game, TestID, TestName, TestID,
+		map[string]any{"a": []any{1, "b", map[string]any{"c": true}}}))
+
+	b, err := json.Marshal(game)
+	if err != nil {
+		f.Fatal(err)
+	}
+
+	f.Add(b)
+	f.Add([]byte(`{"status_data":{"a":[[[{}]]]},"script":""}`))
+	f.Add([]byte(`{"objects":{"a":null},"subject":{"data":"\"[{"}}`))
+
+	f.Fuzz(func(t *testing.T, b []byte) {
+		var g client.Game
+
+		if err := json.Unmarshal(b, &g); err != nil {
+			return
+		}
+
+		if _, err := json.Marshal(&g); err != nil {
+			t.Errorf("Unexpected marshal error: %v", err)
+		}
+	})
+}
+
 func TestGameSaveLoad(t *testing.T) {
 	game := client.NewGame(nil, client.DefaultGameWidth,
 		client.DefaultGameHeight, TestID, TestName, TestDesc)
@@ -106,3 +323,72 @@ func TestGameSaveLoad(t *testing.T) {
 	err = game.Load()
 	assert.NoError(t, err)
 }
+
+func TestGameLoadFile(t *testing.T) {
+	game := client.NewGame(nil, client.DefaultGameWidth,
+		client.DefaultGameHeight, TestID, TestName, TestDesc)
+
+	game.SetScript(TestScript)
+	game.AddSubject(client.NewSubject(game, TestID, TestName, TestID, nil))
+	game.AddObject(client.NewObject(game, TestID, TestName, TestID, nil))
+
+	file := filepath.Join(t.TempDir(), "game.json")
+
+	game.SetFile(file)
+
+	err := game.Save()
+	assert.NoError(t, err)
+
+	b, err := os.ReadFile(file)
+	assert.NoError(t, err)
+
+	ts := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			w.Write(b)
+		}))
+
+	t.Cleanup(ts.Close)
+
+	var m map[string]any
+
+	err = json.Unmarshal(b, &m)
+	assert.NoError(t, err)
+
+	cb, err := cbor.Marshal(m)
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name string
+		file string
+		data []byte
+	}{{
+		name: "local file",
+		file: file,
+	}, {
+		name: "url",
+		file: ts.URL + "/game.json",
+	}, {
+		name: "data",
+		file: filepath.Join(t.TempDir(), "missing.json"),
+		data: b,
+	}, {
+		name: "cbor data",
+		file: filepath.Join(t.TempDir(), "missing.json"),
+		data: cb,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := client.NewGame(nil, client.DefaultGameWidth,
+				client.DefaultGameHeight, "", "", "")
+
+			g.SetFile(tt.file)
+			g.SetData(tt.data)
+
+			err := g.Load()
+			assert.NoError(t, err)
+			assert.Equal(t, game.ID(), g.ID())
+			assert.Equal(t, TestName, g.Name())
+		})
+	}
+}
@@ -2,58 +2,169 @@ package client
 
 import (
 	"bytes"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"image"
-	"io"
+	"image/color"
+	"strconv"
+	"sync"
 
+	"github.com/dhaifley/game2d/cbor"
 	"github.com/dhaifley/game2d/errors"
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/srwiley/oksvg"
 	"github.com/srwiley/rasterx"
 )
 
+// Image rasterization defaults.
+const (
+	// asyncRasterSize is the minimum size, in bytes, of SVG data which is
+	// rasterized in the background when an image is loaded.
+	asyncRasterSize = 16 * 1024
+
+	// maxCachedImages is the maximum number of rasterized images cached.
+	maxCachedImages = 1024
+)
+
+// rasterCache values cache rasterized images by the hash of their SVG data
+// and size, so that loading a game again does not rasterize every image.
+type rasterCache struct {
+	sync.RWMutex
+	m map[string]*ebiten.Image
+}
+
+// imageCache is the cache of rasterized images shared by all games.
+var imageCache = &rasterCache{m: make(map[string]*ebiten.Image)}
+
+// get retrieves a rasterized image from the cache.
+func (c *rasterCache) get(key string) *ebiten.Image {
+	c.RLock()
+	defer c.RUnlock()
+
+	return c.m[key]
+}
+
+// set adds a rasterized image to the cache, evicting an arbitrary image if the
+// cache is full.
+func (c *rasterCache) set(key string, img *ebiten.Image) {
+	c.Lock()
+	defer c.Unlock()
+
+	if _, ok := c.m[key]; !ok && len(c.m) >= maxCachedImages {
+		for k := range c.m {
+			delete(c.m, k)
+
+			break
+		}
+	}
+
+	c.m[key] = img
+}
+
+// rasterKey returns the image cache key for SVG data rasterized at a size.
+func rasterKey(data []byte, w, h int) string {
+	sum := sha256.Sum256(data)
+
+	return hex.EncodeToString(sum[:]) + ":" +
+		strconv.Itoa(w) + "x" + strconv.Itoa(h)
+}
+
 // Image values represent the images in the game.
 type Image struct {
 	id, name string
 	w, h     int
 	data     []byte
+	mu       sync.RWMutex
 	img      *ebiten.Image
 }
 
 // NewImage creates and initializes a new image object.
 func NewImage(id, name string, data []byte, w, h int) *Image {
-	var i *ebiten.Image
-
-	if len(data) > 0 {
-		img, err := svgToImage(bytes.NewBuffer(data), w, h)
-		if err == nil {
-			i = ebiten.NewImageFromImage(img)
-		}
-	}
-
-	return &Image{
+	i := &Image{
 		id:   id,
 		name: name,
+		w:    w,
+		h:    h,
 		data: data,
-		img:  i,
 	}
+
+	_ = i.load(false)
+
+	return i
 }
 
-// svgToImage converts an SVG image from an io.Reader into an image.Image.
-func svgToImage(r io.Reader, width, height int) (image.Image, error) {
-	svgData, err := io.ReadAll(r)
-	if err != nil {
-		return nil, errors.Wrap(err, errors.ErrClient,
-			"unable to read SVG data")
+// image returns the rasterized image, or a placeholder, if the image is still
+// being rasterized.
+func (i *Image) image() *ebiten.Image {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	return i.img
+}
+
+// setImage sets the rasterized image.
+func (i *Image) setImage(img *ebiten.Image) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.img = img
+}
+
+// load rasterizes the image SVG data, using the image cache. If async is true,
+// large images which are not cached are rasterized in the background, with a
+// placeholder of the same size used until rasterization is complete.
+func (i *Image) load(async bool) error {
+	if len(i.data) == 0 {
+		i.setImage(nil)
+
+		return nil
+	}
+
+	key := rasterKey(i.data, i.w, i.h)
+
+	if img := imageCache.get(key); img != nil {
+		i.setImage(img)
+
+		return nil
 	}
 
-	icon, err := oksvg.ReadIconStream(bytes.NewReader(svgData))
+	icon, err := oksvg.ReadIconStream(bytes.NewReader(i.data))
 	if err != nil {
-		return nil, errors.Wrap(err, errors.ErrClient,
+		return errors.Wrap(err, errors.ErrClient,
 			"unable to parse SVG data")
 	}
 
+	w, h := svgSize(icon, i.w, i.h)
+
+	if !async || len(i.data) < asyncRasterSize {
+		img := ebiten.NewImageFromImage(rasterizeSVG(icon, w, h))
+
+		imageCache.set(key, img)
+		i.setImage(img)
+
+		return nil
+	}
+
+	ph := ebiten.NewImage(w, h)
+	ph.Fill(color.RGBA{R: 0x40, G: 0x40, B: 0x40, A: 0x40})
+
+	i.setImage(ph)
+
+	go func() {
+		img := ebiten.NewImageFromImage(rasterizeSVG(icon, w, h))
+
+		imageCache.set(key, img)
+		i.setImage(img)
+	}()
+
+	return nil
+}
+
+// svgSize returns the size at which an SVG icon is rasterized. If either the
+// width or height is not specified, it is calculated from the icon view box.
+func svgSize(icon *oksvg.SvgIcon, width, height int) (int, int) {
 	if width <= 0 || height <= 0 {
 		w, h := int(icon.ViewBox.W), int(icon.ViewBox.H)
 
@@ -70,6 +181,11 @@ func svgToImage(r io.Reader, width, height int) (image.Image, error) {
 		}
 	}
 
+	return width, height
+}
+
+// rasterizeSVG rasterizes an SVG icon into an image.Image.
+func rasterizeSVG(icon *oksvg.SvgIcon, width, height int) image.Image {
 	icon.SetTarget(0, 0, float64(width), float64(height))
 
 	rgba := image.NewRGBA(image.Rect(0, 0, width, height))
@@ -80,7 +196,7 @@ func svgToImage(r io.Reader, width, height int) (image.Image, error) {
 
 	icon.Draw(dasher, 1.0)
 
-	return rgba, nil
+	return rgba
 }
 
 // MarshalJSON serializes the image to JSON.
@@ -102,6 +218,18 @@ func (i *Image) MarshalJSON() ([]byte, error) {
 
 // UnmarshalJSON deserializes the image from JSON.
 func (i *Image) UnmarshalJSON(data []byte) error {
+	return i.unmarshal(data, json.Unmarshal)
+}
+
+// UnmarshalCBOR deserializes the image from CBOR.
+func (i *Image) UnmarshalCBOR(data []byte) error {
+	return i.unmarshal(data, cbor.Unmarshal)
+}
+
+// unmarshal deserializes the image using an unmarshal function.
+func (i *Image) unmarshal(data []byte,
+	unmarshal func([]byte, any) error,
+) error {
 	v := &struct {
 		ID   string `json:"id"`
 		Name string `json:"name"`
@@ -110,7 +238,7 @@ func (i *Image) UnmarshalJSON(data []byte) error {
 		Data string `json:"data,omitempty"`
 	}{}
 
-	if err := json.Unmarshal(data, &v); err != nil {
+	if err := unmarshal(data, &v); err != nil {
 		return err
 	}
 
@@ -125,20 +253,15 @@ func (i *Image) UnmarshalJSON(data []byte) error {
 			"name", i.name)
 	}
 
+	i.w = v.W
+	i.h = v.H
 	i.data = b
 
-	if len(i.data) > 0 {
-		img, err := svgToImage(bytes.NewBuffer(i.data), v.W, v.H)
-		if err != nil {
-			return errors.Wrap(err, errors.ErrClient,
-				"unable to decode image",
-				"id", i.id,
-				"name", i.name)
-		}
-
-		i.img = ebiten.NewImageFromImage(img)
-	} else {
-		i.img = nil
+	if err := i.load(true); err != nil {
+		return errors.Wrap(err, errors.ErrClient,
+			"unable to decode image",
+			"id", i.id,
+			"name", i.name)
 	}
 
 	return nil
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"testing"
 
+	"github.com/dhaifley/game2d/assets"
 	"github.com/dhaifley/game2d/client"
 	"github.com/stretchr/testify/assert"
 )
@@ -35,3 +36,46 @@ func TestImageJSONMarshaling(t *testing.T) {
 	assert.JSONEq(t, string(originalJSON), string(newJSON),
 		"Original and unmarshaled images should be equal")
 }
+
+func TestImageJSONUnmarshalingSVG(t *testing.T) {
+	svg, err := assets.GetImage("avatar.svg")
+	assert.NoError(t, err)
+
+	data, err := json.Marshal(client.NewImage(TestID, TestName, svg, 64, 64))
+	assert.NoError(t, err, "Marshal should not return an error")
+
+	for range 2 {
+		var newImage client.Image
+
+		err = json.Unmarshal(data, &newImage)
+		assert.NoError(t, err, "Unmarshal should not return an error")
+
+		newData, err := json.Marshal(&newImage)
+		assert.NoError(t, err)
+
+		assert.JSONEq(t, string(data), string(newData),
+			"Original and unmarshaled images should be equal")
+	}
+}
+
+func BenchmarkImageJSONUnmarshaling(b *testing.B) {
+	svg, err := assets.GetImage("avatar.svg")
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	data, err := json.Marshal(client.NewImage(TestID, TestName, svg, 64, 64))
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+
+	for b.Loop() {
+		var img client.Image
+
+		if err := json.Unmarshal(data, &img); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package client
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/Shopify/go-lua"
+	"github.com/dhaifley/game2d/errors"
+	"github.com/dhaifley/game2d/logger"
+)
+
+// defaultLanguage is the language used for text missing from the language
+// chosen by the player.
+const defaultLanguage = "en"
+
+// menuLanguagePrefix is the prefix of the IDs of the language menu entries.
+const menuLanguagePrefix = "lang:"
+
+// scriptTextKey is the lua registry key of the table containing the text of
+// the game in the language used, which is read by the GetText function.
+const scriptTextKey = "game2d.Text"
+
+// localizer values track the language chosen by the player for the text of
+// the game, and whether the text table in the lua registry is current.
+type localizer struct {
+	lang   string
+	synced bool
+}
+
+// Languages returns the language tags of the locales of the game, sorted.
+func (g *Game) Languages() []string {
+	return slices.Sorted(maps.Keys(g.locales))
+}
+
+// SetLocales sets the text of the game in each language, keyed by language
+// tag and then by text key.
+func (g *Game) SetLocales(locales map[string]map[string]string) {
+	g.locales = locales
+	g.loc.synced = false
+}
+
+// SetLanguage sets the language tag of the language chosen by the player. The
+// language is used if the game has a locale for it, or for its base language.
+func (g *Game) SetLanguage(lang string) {
+	g.loc.lang = lang
+	g.loc.synced = false
+}
+
+// Language returns the language tag of the locale used for the text of the
+// game. This is the language chosen by the player, its base language, or the
+// default language, whichever the game has a locale for first. If the game
+// has none of them, the first of its locales is used.
+func (g *Game) Language() string {
+	if len(g.locales) == 0 {
+		return ""
+	}
+
+	base, _, _ := strings.Cut(g.loc.lang, "-")
+
+	for _, lang := range []string{g.loc.lang, base, defaultLanguage} {
+		if _, ok := g.locales[lang]; ok && lang != "" {
+			return lang
+		}
+	}
+
+	return g.Languages()[0]
+}
+
+// Texts returns the text of the game in the language used, keyed by text key.
+// Text missing from the language used is taken from the default language.
+func (g *Game) Texts() map[string]string {
+	res := maps.Clone(g.locales[defaultLanguage])
+	if res == nil {
+		res = map[string]string{}
+	}
+
+	maps.Copy(res, g.locales[g.Language()])
+
+	return res
+}
+
+// Text returns the text of the game for a text key, in the language used. The
+// key itself is returned if the text is not found.
+func (g *Game) Text(key string) string {
+	if v, ok := g.Texts()[key]; ok {
+		return v
+	}
+
+	return key
+}
+
+// pushText stores the text of the game in the language used in the lua
+// registry, so that it can be read by the GetText function.
+func (g *Game) pushText() {
+	texts := g.Texts()
+
+	m := make(map[string]any, len(texts))
+
+	for k, v := range texts {
+		m[k] = v
+	}
+
+	pushMap(g.lua, m)
+	g.lua.SetField(lua.RegistryIndex, scriptTextKey)
+
+	g.loc.synced = true
+}
+
+// scriptGetText is the GetText function available to game scripts, which
+// returns the text of the game for a text key, in the language used, or the
+// key itself if the text is not found.
+func scriptGetText(l *lua.State) int {
+	key := lua.CheckString(l, 1)
+
+	l.Field(lua.RegistryIndex, scriptTextKey)
+
+	if l.IsTable(-1) {
+		l.Field(-1, key)
+
+		if l.TypeOf(-1) == lua.TypeString {
+			return 1
+		}
+	}
+
+	l.PushString(key)
+
+	return 1
+}
+
+// languageEntries returns the menu entries used to choose the language.
+func (g *Game) languageEntries() []*MenuEntry {
+	current := g.Language()
+
+	res := make([]*MenuEntry, 0, len(g.locales))
+
+	for _, lang := range g.Languages() {
+		label := lang
+		if lang == current {
+			label += " *"
+		}
+
+		res = append(res, &MenuEntry{
+			ID:    menuLanguagePrefix + lang,
+			Label: label,
+		})
+	}
+
+	return res
+}
+
+// selectLanguage sets the language chosen from the menu, and persists it in
+// the client preferences. Failures to persist it are logged, but otherwise
+// ignored.
+func (g *Game) selectLanguage(lang string) error {
+	if _, ok := g.locales[lang]; !ok {
+		return errors.New(errors.ErrClient,
+			"language not found",
+			"language", lang)
+	}
+
+	g.closeSlots()
+
+	g.SetLanguage(lang)
+
+	g.menu.msg = "Language set to " + lang
+
+	if err := SavePreferences(g.Preferences()); err != nil {
+		g.log.Log(context.Background(), logger.LvlWarn,
+			"unable to save preferences",
+			"error", err)
+	}
+
+	return nil
+}
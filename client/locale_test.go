@@ -0,0 +1,66 @@
+package client_test
+
+import (
+	"testing"
+
+	"github.com/dhaifley/game2d/client"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocales(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("AppData", t.TempDir())
+
+	game := newRunningGame(t, `function Update(game)
+if GetText("title") == "Bonjour" then
+	game.score = game.score + 1
+end
+if GetText("missing") == "missing" then
+	game.score = game.score + 10
+end
+return game
+end`, 0)
+
+	assert.Empty(t, game.Language(), "Games without locales have no language")
+	assert.Equal(t, "title", game.Text("title"),
+		"Keys without text should be returned")
+
+	game.SetLocales(map[string]map[string]string{
+		"en": {"title": "Hello", "quit": "Quit"},
+		"fr": {"title": "Bonjour"},
+	})
+
+	game.SetLanguage("fr-CA")
+
+	assert.Equal(t, "fr", game.Language(), "Base languages should be used")
+	assert.Equal(t, "Quit", game.Text("quit"),
+		"Missing text should use the default language")
+
+	err := game.Update()
+	assert.NoError(t, err)
+	assert.Equal(t, 11, game.Score(), "GetText should return localized text")
+
+	game.SetLanguage("de")
+
+	assert.Equal(t, "en", game.Language(),
+		"Unknown languages should use the default language")
+
+	game.OpenMenu()
+
+	err = game.SelectMenuEntry(client.MenuLanguage)
+	assert.NoError(t, err)
+
+	entries := game.MenuEntries()
+	assert.Equal(t, &client.MenuEntry{ID: "lang:en", Label: "en *"},
+		entries[0], "Current language should be marked")
+
+	err = game.SelectMenuEntry(entries[1].ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "fr", game.Language(), "Selected language should be used")
+
+	prefs, err := client.LoadPreferences()
+	assert.NoError(t, err)
+	assert.Equal(t, "fr", prefs.Language,
+		"Selected language should be saved in the preferences")
+}
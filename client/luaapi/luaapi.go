@@ -0,0 +1,381 @@
+// Package luaapi describes the Lua environment in which the client runs game
+// scripts, so that script editors and prompts can stay in sync with it.
+package luaapi
+
+// Version is the version of the Lua environment description. It is
+// incremented whenever the environment changes.
+const Version = 4
+
+// Field values describe a field of a Lua table passed to game scripts.
+type Field struct {
+	Name        string `json:"name"        yaml:"name"`
+	Type        string `json:"type"        yaml:"type"`
+	Description string `json:"description" yaml:"description"`
+	ReadOnly    bool   `json:"read_only"   yaml:"read_only"`
+}
+
+// Function values describe a function called by, or available to, game
+// scripts.
+type Function struct {
+	Name        string `json:"name"        yaml:"name"`
+	Signature   string `json:"signature"   yaml:"signature"`
+	Description string `json:"description" yaml:"description"`
+}
+
+// Library values describe a standard Lua library available to game scripts.
+type Library struct {
+	Name      string   `json:"name"      yaml:"name"`
+	Functions []string `json:"functions" yaml:"functions"`
+}
+
+// Key values describe the code of a keyboard key, as found in the keys table
+// passed to game scripts.
+type Key struct {
+	Name string `json:"name" yaml:"name"`
+	Code int    `json:"code" yaml:"code"`
+}
+
+// API values describe the Lua environment in which game scripts run.
+type API struct {
+	Version        int         `json:"version"         yaml:"version"`
+	Entry          *Function   `json:"entry"           yaml:"entry"`
+	Game           []*Field    `json:"game"            yaml:"game"`
+	Object         []*Field    `json:"object"          yaml:"object"`
+	Keys           []*Key      `json:"keys"            yaml:"keys"`
+	Libraries      []*Library  `json:"libraries"       yaml:"libraries"`
+	RemovedGlobals []string    `json:"removed_globals" yaml:"removed_globals"`
+	Functions      []*Function `json:"functions"       yaml:"functions"`
+}
+
+// Entry is the function which each game script must define, and which the
+// client calls once per frame.
+var Entry = &Function{
+	Name:      "Update",
+	Signature: "Update(game) -> game",
+	Description: "Called once per frame with the game table. It returns " +
+		"the game table, which is used to update the game state.",
+}
+
+// Game describes the fields of the game table passed to the Update function.
+var Game = []*Field{{
+	Name:        "id",
+	Type:        "string",
+	Description: "The ID of the game.",
+}, {
+	Name:        "name",
+	Type:        "string",
+	Description: "The name of the game.",
+}, {
+	Name:        "debug",
+	Type:        "boolean",
+	Description: "Whether the debug overlay is shown.",
+}, {
+	Name:        "pause",
+	Type:        "boolean",
+	Description: "Set to true to pause the game.",
+}, {
+	Name:        "score",
+	Type:        "number",
+	Description: "The score of the game, truncated to an integer.",
+}, {
+	Name:        "w",
+	Type:        "number",
+	Description: "The width of the game in pixels.",
+	ReadOnly:    true,
+}, {
+	Name:        "h",
+	Type:        "number",
+	Description: "The height of the game in pixels.",
+	ReadOnly:    true,
+}, {
+	Name: "keys",
+	Type: "table",
+	Description: "The codes of the keys currently pressed, keyed by the " +
+		"strings \"0\", \"1\" and so on.",
+	ReadOnly: true,
+}, {
+	Name: "actions",
+	Type: "table",
+	Description: "The actions of the game bindings which are active, " +
+		"because a key bound to them is pressed, keyed by action name.",
+	ReadOnly: true,
+}, {
+	Name: "menu",
+	Type: "table",
+	Description: "A list of custom pause menu entries, each a table " +
+		"with id and label fields, shown after the resume entry.",
+}, {
+	Name: "menu_selected",
+	Type: "string",
+	Description: "The id of the custom pause menu entry selected by the " +
+		"player, set only for the first frame after it is selected.",
+	ReadOnly: true,
+}, {
+	Name: "language",
+	Type: "string",
+	Description: "The language tag of the locale used for the text " +
+		"returned by GetText, or an empty string if there are no locales.",
+	ReadOnly: true,
+}, {
+	Name:        "subject",
+	Type:        "object",
+	Description: "The object which represents the player.",
+}, {
+	Name:        "objects",
+	Type:        "table",
+	Description: "The objects of the game, keyed by ID.",
+}}
+
+// Object describes the fields of the object tables within the game table.
+var Object = []*Field{{
+	Name:        "id",
+	Type:        "string",
+	Description: "The ID of the object. Objects without an ID are removed.",
+}, {
+	Name:        "name",
+	Type:        "string",
+	Description: "The name of the object.",
+}, {
+	Name:        "hidden",
+	Type:        "boolean",
+	Description: "Whether the object is not drawn.",
+}, {
+	Name:        "subject",
+	Type:        "boolean",
+	Description: "Whether the object is the subject of the game.",
+}, {
+	Name:        "x",
+	Type:        "number",
+	Description: "The horizontal position, truncated to an integer.",
+}, {
+	Name:        "y",
+	Type:        "number",
+	Description: "The vertical position, truncated to an integer.",
+}, {
+	Name:        "z",
+	Type:        "number",
+	Description: "The drawing order, truncated to an integer.",
+}, {
+	Name:        "r",
+	Type:        "number",
+	Description: "The rotation in degrees, truncated to an integer.",
+}, {
+	Name:        "w",
+	Type:        "number",
+	Description: "The width in pixels, truncated to an integer.",
+}, {
+	Name:        "h",
+	Type:        "number",
+	Description: "The height in pixels, truncated to an integer.",
+}, {
+	Name:        "image",
+	Type:        "string",
+	Description: "The ID of the game image drawn for the object.",
+}, {
+	Name:        "data",
+	Type:        "table",
+	Description: "Data kept for the object between frames.",
+}}
+
+// Keys contains the codes of the keyboard keys.
+var Keys = []*Key{
+	{Name: "A", Code: 0},
+	{Name: "B", Code: 1},
+	{Name: "C", Code: 2},
+	{Name: "D", Code: 3},
+	{Name: "E", Code: 4},
+	{Name: "F", Code: 5},
+	{Name: "G", Code: 6},
+	{Name: "H", Code: 7},
+	{Name: "I", Code: 8},
+	{Name: "J", Code: 9},
+	{Name: "K", Code: 10},
+	{Name: "L", Code: 11},
+	{Name: "M", Code: 12},
+	{Name: "N", Code: 13},
+	{Name: "O", Code: 14},
+	{Name: "P", Code: 15},
+	{Name: "Q", Code: 16},
+	{Name: "R", Code: 17},
+	{Name: "S", Code: 18},
+	{Name: "T", Code: 19},
+	{Name: "U", Code: 20},
+	{Name: "V", Code: 21},
+	{Name: "W", Code: 22},
+	{Name: "X", Code: 23},
+	{Name: "Y", Code: 24},
+	{Name: "Z", Code: 25},
+	{Name: "AltLeft", Code: 26},
+	{Name: "AltRight", Code: 27},
+	{Name: "ArrowDown", Code: 28},
+	{Name: "ArrowLeft", Code: 29},
+	{Name: "ArrowRight", Code: 30},
+	{Name: "ArrowUp", Code: 31},
+	{Name: "Backquote", Code: 32},
+	{Name: "Backslash", Code: 33},
+	{Name: "Backspace", Code: 34},
+	{Name: "BracketLeft", Code: 35},
+	{Name: "BracketRight", Code: 36},
+	{Name: "CapsLock", Code: 37},
+	{Name: "Comma", Code: 38},
+	{Name: "ContextMenu", Code: 39},
+	{Name: "ControlLeft", Code: 40},
+	{Name: "ControlRight", Code: 41},
+	{Name: "Delete", Code: 42},
+	{Name: "Digit0", Code: 43},
+	{Name: "Digit1", Code: 44},
+	{Name: "Digit2", Code: 45},
+	{Name: "Digit3", Code: 46},
+	{Name: "Digit4", Code: 47},
+	{Name: "Digit5", Code: 48},
+	{Name: "Digit6", Code: 49},
+	{Name: "Digit7", Code: 50},
+	{Name: "Digit8", Code: 51},
+	{Name: "Digit9", Code: 52},
+	{Name: "End", Code: 53},
+	{Name: "Enter", Code: 54},
+	{Name: "Equal", Code: 55},
+	{Name: "Escape", Code: 56},
+	{Name: "F1", Code: 57},
+	{Name: "F2", Code: 58},
+	{Name: "F3", Code: 59},
+	{Name: "F4", Code: 60},
+	{Name: "F5", Code: 61},
+	{Name: "F6", Code: 62},
+	{Name: "F7", Code: 63},
+	{Name: "F8", Code: 64},
+	{Name: "F9", Code: 65},
+	{Name: "F10", Code: 66},
+	{Name: "F11", Code: 67},
+	{Name: "F12", Code: 68},
+	{Name: "F13", Code: 69},
+	{Name: "F14", Code: 70},
+	{Name: "F15", Code: 71},
+	{Name: "F16", Code: 72},
+	{Name: "F17", Code: 73},
+	{Name: "F18", Code: 74},
+	{Name: "F19", Code: 75},
+	{Name: "F20", Code: 76},
+	{Name: "F21", Code: 77},
+	{Name: "F22", Code: 78},
+	{Name: "F23", Code: 79},
+	{Name: "F24", Code: 80},
+	{Name: "Home", Code: 81},
+	{Name: "Insert", Code: 82},
+	{Name: "IntlBackslash", Code: 83},
+	{Name: "MetaLeft", Code: 84},
+	{Name: "MetaRight", Code: 85},
+	{Name: "Minus", Code: 86},
+	{Name: "NumLock", Code: 87},
+	{Name: "Numpad0", Code: 88},
+	{Name: "Numpad1", Code: 89},
+	{Name: "Numpad2", Code: 90},
+	{Name: "Numpad3", Code: 91},
+	{Name: "Numpad4", Code: 92},
+	{Name: "Numpad5", Code: 93},
+	{Name: "Numpad6", Code: 94},
+	{Name: "Numpad7", Code: 95},
+	{Name: "Numpad8", Code: 96},
+	{Name: "Numpad9", Code: 97},
+	{Name: "NumpadAdd", Code: 98},
+	{Name: "NumpadDecimal", Code: 99},
+	{Name: "NumpadDivide", Code: 100},
+	{Name: "NumpadEnter", Code: 101},
+	{Name: "NumpadEqual", Code: 102},
+	{Name: "NumpadMultiply", Code: 103},
+	{Name: "NumpadSubtract", Code: 104},
+	{Name: "PageDown", Code: 105},
+	{Name: "PageUp", Code: 106},
+	{Name: "Pause", Code: 107},
+	{Name: "Period", Code: 108},
+	{Name: "PrintScreen", Code: 109},
+	{Name: "Quote", Code: 110},
+	{Name: "ScrollLock", Code: 111},
+	{Name: "Semicolon", Code: 112},
+	{Name: "ShiftLeft", Code: 113},
+	{Name: "ShiftRight", Code: 114},
+	{Name: "Slash", Code: 115},
+	{Name: "Space", Code: 116},
+	{Name: "Tab", Code: 117},
+	{Name: "Alt", Code: 118},
+	{Name: "Control", Code: 119},
+	{Name: "Shift", Code: 120},
+	{Name: "Meta", Code: 121},
+}
+
+// Libraries contains the standard Lua libraries opened for game scripts, and
+// the functions available in each of them.
+var Libraries = []*Library{{
+	Name: "_G",
+	Functions: []string{
+		"assert", "collectgarbage", "error", "getmetatable", "ipairs",
+		"load", "next", "pairs", "pcall", "print", "rawequal", "rawlen",
+		"rawget", "rawset", "select", "setmetatable", "tonumber",
+		"tostring", "type", "xpcall",
+	},
+}, {
+	Name: "table",
+	Functions: []string{
+		"concat", "insert", "pack", "unpack", "remove", "sort",
+	},
+}, {
+	Name: "string",
+	Functions: []string{
+		"byte", "char", "find", "format", "len", "lower", "rep", "reverse",
+		"sub", "upper",
+	},
+}, {
+	Name: "bit32",
+	Functions: []string{
+		"arshift", "band", "bnot", "bor", "bxor", "btest", "extract",
+		"lrotate", "lshift", "replace", "rrotate", "rshift",
+	},
+}, {
+	Name: "math",
+	Functions: []string{
+		"abs", "acos", "asin", "atan2", "atan", "ceil", "cosh", "cos",
+		"deg", "exp", "floor", "fmod", "frexp", "ldexp", "log", "max",
+		"min", "modf", "pow", "rad", "random", "randomseed", "sinh", "sin",
+		"sqrt", "tanh", "tan",
+	},
+}}
+
+// RemovedGlobals contains the base library functions removed from the Lua
+// environment, because they are able to access the file system.
+var RemovedGlobals = []string{"dofile", "loadfile"}
+
+// Functions contains the helper functions provided to game scripts, in
+// addition to the standard libraries. Game scripts may define their own
+// global functions, whose names must begin with a capital letter.
+var Functions = []*Function{{
+	Name:      "GetText",
+	Signature: "GetText(key) -> string",
+	Description: "Returns the text for a key from the locales of the game, " +
+		"in the language chosen by the player, or the key itself if it " +
+		"is not found.",
+}}
+
+// KeyCode returns the code of a keyboard key by name.
+func KeyCode(name string) (int, bool) {
+	for _, k := range Keys {
+		if k.Name == name {
+			return k.Code, true
+		}
+	}
+
+	return 0, false
+}
+
+// Describe returns a description of the Lua environment.
+func Describe() *API {
+	return &API{
+		Version:        Version,
+		Entry:          Entry,
+		Game:           Game,
+		Object:         Object,
+		Keys:           Keys,
+		Libraries:      Libraries,
+		RemovedGlobals: RemovedGlobals,
+		Functions:      Functions,
+	}
+}
@@ -0,0 +1,408 @@
+package client
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/dhaifley/game2d/errors"
+	"github.com/dhaifley/game2d/logger"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+// Menu defaults.
+const (
+	menuRowHeight = 16
+	menuTop       = 40
+)
+
+// Pause menu entry IDs.
+const (
+	MenuResume   = "resume"
+	MenuRestart  = "restart"
+	MenuSave     = "save"
+	MenuLoad     = "load"
+	MenuSaveSlot = "save_slot"
+	MenuLoadSlot = "load_slot"
+	MenuDebug    = "debug"
+	MenuBindings = "bindings"
+	MenuLanguage = "language"
+	MenuQuit     = "quit"
+)
+
+// menuSlotPrefix is the prefix of the IDs of the save slot menu entries.
+const menuSlotPrefix = "slot:"
+
+// MenuEntry values represent the entries of the pause menu.
+type MenuEntry struct {
+	ID    string `json:"id"`
+	Label string `json:"label"`
+}
+
+// menu values represent the pause menu overlay. Game scripts may add custom
+// entries to the menu, and are told which custom entry was selected the next
+// time they are run. While a save slot is being chosen, the mode is the ID of
+// the entry which opened the list of save slots.
+type menu struct {
+	open     bool
+	quit     bool
+	sel      int
+	msg      string
+	mode     string
+	selected string
+	custom   []*MenuEntry
+	slots    []*SaveSlot
+}
+
+// OpenMenu pauses the game and opens the pause menu.
+func (g *Game) OpenMenu() {
+	g.pause = true
+
+	g.menu.open = true
+	g.menu.sel = 0
+	g.menu.msg = ""
+	g.menu.mode = ""
+}
+
+// CloseMenu closes the pause menu, without resuming the game.
+func (g *Game) CloseMenu() {
+	g.menu.open = false
+}
+
+// menuOpen returns whether the pause menu is open.
+func (g *Game) menuOpen() bool {
+	return g.menu.open
+}
+
+// MenuEntries returns the entries of the pause menu, including the custom
+// entries added by the game script. While a save slot or language is being
+// chosen, the entries are the save slots or languages. The language entry is
+// only included if the game has more than one locale.
+func (g *Game) MenuEntries() []*MenuEntry {
+	if g.menu.mode == MenuLanguage {
+		return g.languageEntries()
+	}
+
+	if g.menu.mode != "" {
+		return g.slotEntries()
+	}
+
+	res := []*MenuEntry{{ID: MenuResume, Label: "Resume"}}
+
+	res = append(res, g.menu.custom...)
+
+	res = append(res, []*MenuEntry{
+		{ID: MenuRestart, Label: "Restart"},
+		{ID: MenuSave, Label: "Save"},
+		{ID: MenuLoad, Label: "Load"},
+		{ID: MenuSaveSlot, Label: "Save to slot..."},
+		{ID: MenuLoadSlot, Label: "Load from slot..."},
+		{ID: MenuDebug, Label: "Toggle debug"},
+		{ID: MenuBindings, Label: "Key bindings"},
+	}...)
+
+	if len(g.locales) > 1 {
+		res = append(res, &MenuEntry{ID: MenuLanguage, Label: "Language..."})
+	}
+
+	return append(res, &MenuEntry{ID: MenuQuit, Label: "Quit"})
+}
+
+// slotEntries returns the menu entries used to choose a save slot. When
+// saving, the first entry is a new save slot.
+func (g *Game) slotEntries() []*MenuEntry {
+	res := make([]*MenuEntry, 0, len(g.menu.slots)+1)
+
+	if g.menu.mode == MenuSaveSlot {
+		slot := nextSlot(g.menu.slots)
+
+		res = append(res, &MenuEntry{
+			ID:    menuSlotPrefix + slot,
+			Label: "New slot (" + slot + ")",
+		})
+	}
+
+	for _, s := range g.menu.slots {
+		res = append(res, &MenuEntry{
+			ID: menuSlotPrefix + s.Slot,
+			Label: s.Slot + "  " +
+				time.Unix(s.UpdatedAt, 0).Format(time.DateTime),
+		})
+	}
+
+	return res
+}
+
+// openSlots lists the save slots of the game, so that one can be chosen from
+// the menu to save or load the game.
+func (g *Game) openSlots(mode string) error {
+	slots, err := g.Slots()
+	if err != nil {
+		return err
+	}
+
+	g.menu.mode = mode
+	g.menu.slots = slots
+	g.menu.sel = 0
+
+	return nil
+}
+
+// closeSlots returns from the list of save slots to the menu.
+func (g *Game) closeSlots() {
+	g.menu.mode = ""
+	g.menu.slots = nil
+	g.menu.sel = 0
+}
+
+// setMenu sets the custom pause menu entries from the menu field of the game
+// table, a list of tables containing id and label fields.
+func (g *Game) setMenu(entries []any) {
+	custom := make([]*MenuEntry, 0, len(entries))
+
+	for _, v := range entries {
+		m, ok := v.(map[string]any)
+		if !ok {
+			continue
+		}
+
+		id, _ := m["id"].(string)
+		if id == "" {
+			continue
+		}
+
+		label, _ := m["label"].(string)
+		if label == "" {
+			label = id
+		}
+
+		custom = append(custom, &MenuEntry{ID: id, Label: label})
+	}
+
+	g.menu.custom = custom
+}
+
+// SelectMenuEntry performs the action of a pause menu entry, by ID. Selecting
+// a custom entry closes the menu and resumes the game, and the entry ID is
+// passed to the game script as the menu_selected field of the game table.
+func (g *Game) SelectMenuEntry(id string) error {
+	g.menu.msg = ""
+
+	switch id {
+	case MenuResume:
+		g.menu.open = false
+		g.pause = false
+	case MenuRestart, MenuLoad:
+		g.menu.open = false
+
+		if err := g.Load(); err != nil {
+			return err
+		}
+
+		g.pause = true
+	case MenuSave:
+		if err := g.Save(); err != nil {
+			return err
+		}
+
+		g.menu.msg = "Game saved"
+	case MenuSaveSlot, MenuLoadSlot:
+		return g.openSlots(id)
+	case MenuDebug:
+		g.debug = !g.debug
+	case MenuBindings:
+		g.menu.open = false
+
+		g.openBindings()
+	case MenuLanguage:
+		g.menu.mode = MenuLanguage
+		g.menu.sel = 0
+	case MenuQuit:
+		g.menu.open = false
+		g.menu.quit = true
+	default:
+		if lang, ok := strings.CutPrefix(id, menuLanguagePrefix); ok &&
+			g.menu.mode == MenuLanguage {
+			return g.selectLanguage(lang)
+		}
+
+		if slot, ok := strings.CutPrefix(id, menuSlotPrefix); ok &&
+			g.menu.mode != "" {
+			return g.selectSlot(slot)
+		}
+
+		for _, e := range g.menu.custom {
+			if e.ID == id {
+				g.menu.open = false
+				g.menu.selected = id
+				g.pause = false
+
+				return nil
+			}
+		}
+
+		return errors.New(errors.ErrClient,
+			"menu entry not found",
+			"id", id)
+	}
+
+	return nil
+}
+
+// selectSlot saves the game to, or loads the game from, a save slot, depending
+// on which entry opened the list of save slots.
+func (g *Game) selectSlot(slot string) error {
+	mode := g.menu.mode
+
+	g.closeSlots()
+
+	if mode == MenuLoadSlot {
+		g.menu.open = false
+
+		if err := g.LoadSlot(slot); err != nil {
+			return err
+		}
+
+		g.pause = true
+
+		return nil
+	}
+
+	if err := g.SaveSlot(slot); err != nil {
+		return err
+	}
+
+	g.menu.msg = "Game saved to " + slot
+
+	return nil
+}
+
+// gamepadJustPressed returns whether a standard gamepad button has just been
+// pressed on any connected gamepad.
+func gamepadJustPressed(button ebiten.StandardGamepadButton) bool {
+	for _, id := range ebiten.AppendGamepadIDs(nil) {
+		if inpututil.IsStandardGamepadButtonJustPressed(id, button) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// updateMenu handles the pause menu keyboard and gamepad navigation each
+// frame.
+func (g *Game) updateMenu() {
+	entries := g.MenuEntries()
+
+	paused := g.pause
+
+	switch {
+	case g.menu.mode != "" && (inpututil.IsKeyJustPressed(ebiten.KeyEscape) ||
+		gamepadJustPressed(ebiten.StandardGamepadButtonRightRight)):
+		g.closeSlots()
+	case g.menu.mode != "" && inpututil.IsKeyJustPressed(ebiten.KeyDelete):
+		if g.menu.sel < len(entries) {
+			g.deleteSlotEntry(entries[g.menu.sel].ID)
+		}
+	case inpututil.IsKeyJustPressed(ebiten.KeyEscape),
+		gamepadJustPressed(ebiten.StandardGamepadButtonRightRight),
+		gamepadJustPressed(ebiten.StandardGamepadButtonCenterRight):
+		g.menu.open = false
+		g.pause = false
+	case inpututil.IsKeyJustPressed(ebiten.KeyArrowUp),
+		gamepadJustPressed(ebiten.StandardGamepadButtonLeftTop):
+		if g.menu.sel > 0 {
+			g.menu.sel--
+		}
+	case inpututil.IsKeyJustPressed(ebiten.KeyArrowDown),
+		gamepadJustPressed(ebiten.StandardGamepadButtonLeftBottom):
+		if g.menu.sel < len(entries)-1 {
+			g.menu.sel++
+		}
+	case inpututil.IsKeyJustPressed(ebiten.KeyEnter),
+		gamepadJustPressed(ebiten.StandardGamepadButtonRightBottom):
+		if g.menu.sel < len(entries) {
+			id := entries[g.menu.sel].ID
+
+			if err := g.SelectMenuEntry(id); err != nil {
+				g.log.Log(context.Background(), logger.LvlError,
+					"unable to perform menu action",
+					"error", err,
+					"id", id)
+
+				g.menu.msg = "Error: " + err.Error()
+			}
+		}
+	}
+
+	if g.pause != paused {
+		g.emit(EventPause, map[string]any{"paused": g.pause})
+	}
+}
+
+// deleteSlotEntry deletes the save slot of a menu entry, and refreshes the
+// list of save slots.
+func (g *Game) deleteSlotEntry(id string) {
+	slot, _ := strings.CutPrefix(id, menuSlotPrefix)
+
+	if !slices.ContainsFunc(g.menu.slots, func(s *SaveSlot) bool {
+		return s.Slot == slot
+	}) {
+		return
+	}
+
+	if err := g.DeleteSlot(slot); err != nil {
+		g.log.Log(context.Background(), logger.LvlError,
+			"unable to delete save slot",
+			"error", err,
+			"slot", slot)
+
+		g.menu.msg = "Error: " + err.Error()
+
+		return
+	}
+
+	if err := g.openSlots(g.menu.mode); err != nil {
+		g.menu.msg = "Error: " + err.Error()
+	}
+}
+
+// drawMenu renders the pause menu over the game.
+func (g *Game) drawMenu(screen *ebiten.Image) {
+	switch g.menu.mode {
+	case MenuSaveSlot:
+		ebitenutil.DebugPrintAt(screen, "Save to slot"+
+			"  [Up/Down] select  [Enter] save  [Del] delete  [Esc] back",
+			8, 8)
+	case MenuLoadSlot:
+		ebitenutil.DebugPrintAt(screen, "Load from slot"+
+			"  [Up/Down] select  [Enter] load  [Del] delete  [Esc] back",
+			8, 8)
+	case MenuLanguage:
+		ebitenutil.DebugPrintAt(screen, "Language"+
+			"  [Up/Down] select  [Enter] choose  [Esc] back", 8, 8)
+	default:
+		ebitenutil.DebugPrintAt(screen, "Paused"+
+			"  [Up/Down] select  [Enter] choose  [Esc] resume", 8, 8)
+	}
+
+	entries := g.MenuEntries()
+
+	for i, e := range entries {
+		y := menuTop + i*menuRowHeight
+
+		if i == g.menu.sel {
+			ebitenutil.DebugPrintAt(screen, ">", 8, y)
+		}
+
+		ebitenutil.DebugPrintAt(screen, e.Label, 24, y)
+	}
+
+	if g.menu.msg != "" {
+		ebitenutil.DebugPrintAt(screen, g.menu.msg, 8,
+			menuTop+(len(entries)+1)*menuRowHeight)
+	}
+}
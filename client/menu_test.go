@@ -0,0 +1,50 @@
+package client_test
+
+import (
+	"testing"
+
+	"github.com/dhaifley/game2d/client"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMenu(t *testing.T) {
+	game := newRunningGame(t, `function Update(game)
+game.menu = {{id = "hard", label = "Hard mode"}}
+if game.menu_selected == "hard" then
+	game.score = game.score + 1
+end
+return game
+end`, 0)
+
+	err := game.Update()
+	assert.NoError(t, err)
+
+	entries := game.MenuEntries()
+	assert.Equal(t, client.MenuResume, entries[0].ID,
+		"Resume should be the first menu entry")
+	assert.Equal(t, &client.MenuEntry{ID: "hard", Label: "Hard mode"},
+		entries[1], "Custom menu entries should follow resume")
+
+	game.OpenMenu()
+
+	err = game.SelectMenuEntry("hard")
+	assert.NoError(t, err)
+
+	err = game.Update()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, game.Score(), "Selected entry should be passed once")
+
+	err = game.Update()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, game.Score(), "Selected entry should be passed once")
+
+	err = game.SelectMenuEntry("missing")
+	assert.Error(t, err, "Unknown menu entries should not be selected")
+
+	err = game.SelectMenuEntry(client.MenuQuit)
+	assert.NoError(t, err)
+
+	err = game.Update()
+	assert.ErrorIs(t, err, ebiten.Termination, "Quit should end the game")
+}
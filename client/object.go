@@ -3,6 +3,8 @@ package client
 import (
 	"encoding/json"
 
+	"github.com/Shopify/go-lua"
+	"github.com/dhaifley/game2d/cbor"
 	"github.com/hajimehoshi/ebiten/v2"
 )
 
@@ -24,9 +26,11 @@ func NewObject(
 	w, h := 0, 0
 
 	if img != "" && game != nil {
-		if i, ok := game.img[img]; ok && i != nil && i.img != nil {
-			w = i.img.Bounds().Size().X
-			h = i.img.Bounds().Size().Y
+		if i, ok := game.img[img]; ok && i != nil {
+			if ii := i.image(); ii != nil {
+				w = ii.Bounds().Size().X
+				h = ii.Bounds().Size().Y
+			}
 		}
 	}
 
@@ -57,58 +61,88 @@ func NewSubject(
 // SetHidden sets the object hidden state.
 func (o *Object) SetHidden(hidden bool) {
 	o.hidden = hidden
+
+	o.changed()
 }
 
 // SetName sets the object name.
 func (o *Object) SetName(name string) {
 	o.name = name
+
+	o.changed()
 }
 
 // SetX sets the object x-coordinate.
 func (o *Object) SetX(x int) {
 	o.x = x
+
+	o.changed()
 }
 
 // SetY sets the object y-coordinate.
 func (o *Object) SetY(y int) {
 	o.y = y
+
+	o.changed()
 }
 
 // SetZ sets the object z-index.
 func (o *Object) SetZ(z int) {
 	o.z = z
+
+	o.changed()
 }
 
 // SetR sets the object rotation.
 func (o *Object) SetR(r int) {
 	o.r = r
+
+	o.changed()
 }
 
 // SetW sets the object width.
 func (o *Object) SetW(w int) {
 	o.w = w
+
+	o.changed()
 }
 
 // SetH sets the object height.
 func (o *Object) SetH(h int) {
 	o.h = h
+
+	o.changed()
 }
 
 // SetImage sets the object image.
 func (o *Object) SetImage(img string) {
 	o.img = img
 
-	if i, ok := o.game.img[img]; ok && i != nil && i.img != nil {
-		o.w = i.img.Bounds().Size().X
-		o.h = i.img.Bounds().Size().Y
+	if i, ok := o.game.img[img]; ok && i != nil {
+		if ii := i.image(); ii != nil {
+			o.w = ii.Bounds().Size().X
+			o.h = ii.Bounds().Size().Y
+		}
 	}
+
+	o.changed()
 }
 
 // SetData sets the object data.
 func (o *Object) SetData(data map[string]any) {
 	o.data = data
+
+	o.changed()
+}
+
+// changed marks the game state as changed outside of the game script.
+func (o *Object) changed() {
+	if o.game != nil {
+		o.game.synced = false
+	}
 }
 
+// Map returns the object as a map.
 func (o *Object) Map() map[string]any {
 	return map[string]any{
 		"id":      o.id,
@@ -161,6 +195,43 @@ func NewObjectFromMap(m map[string]any) *Object {
 	}
 }
 
+// updateFromLua updates the object in place from the object table, at index on
+// the lua stack. It returns false, leaving the object unchanged, if the table
+// does not contain an object ID.
+func (o *Object) updateFromLua(l *lua.State, index int) bool {
+	id, _ := fieldValue(l, index, "id").(string)
+	if id == "" {
+		return false
+	}
+
+	hidden, _ := fieldValue(l, index, "hidden").(bool)
+	name, _ := fieldValue(l, index, "name").(string)
+	img, _ := fieldValue(l, index, "image").(string)
+	data, _ := fieldValue(l, index, "data").(map[string]any)
+	sub, _ := fieldValue(l, index, "subject").(bool)
+	x, _ := fieldValue(l, index, "x").(float64)
+	y, _ := fieldValue(l, index, "y").(float64)
+	z, _ := fieldValue(l, index, "z").(float64)
+	r, _ := fieldValue(l, index, "r").(float64)
+	w, _ := fieldValue(l, index, "w").(float64)
+	h, _ := fieldValue(l, index, "h").(float64)
+
+	o.id = id
+	o.name = name
+	o.hidden = hidden
+	o.img = img
+	o.data = data
+	o.sub = sub
+	o.x = int(x)
+	o.y = int(y)
+	o.z = int(z)
+	o.r = int(r)
+	o.w = int(w)
+	o.h = int(h)
+
+	return true
+}
+
 // MarshalJSON serializes the object to JSON.
 func (o *Object) MarshalJSON() ([]byte, error) {
 	return json.Marshal(&struct {
@@ -192,6 +263,18 @@ func (o *Object) MarshalJSON() ([]byte, error) {
 
 // UnmarshalJSON deserializes the object from JSON.
 func (o *Object) UnmarshalJSON(data []byte) error {
+	return o.unmarshal(data, json.Unmarshal)
+}
+
+// UnmarshalCBOR deserializes the object from CBOR.
+func (o *Object) UnmarshalCBOR(data []byte) error {
+	return o.unmarshal(data, cbor.Unmarshal)
+}
+
+// unmarshal deserializes the object using an unmarshal function.
+func (o *Object) unmarshal(data []byte,
+	unmarshal func([]byte, any) error,
+) error {
 	v := &struct {
 		ID     string         `json:"id"`
 		Name   string         `json:"name"`
@@ -206,7 +289,7 @@ func (o *Object) UnmarshalJSON(data []byte) error {
 		Data   map[string]any `json:"data,omitempty"`
 	}{}
 
-	if err := json.Unmarshal(data, &v); err != nil {
+	if err := unmarshal(data, &v); err != nil {
 		return err
 	}
 
@@ -246,11 +329,13 @@ func (o *Object) Draw(screen *ebiten.Image) {
 	op := &ebiten.DrawImageOptions{GeoM: geo}
 
 	img := o.game.img[o.img]
-	if img == nil || img.img == nil {
+	if img == nil {
 		return
 	}
 
-	screen.DrawImage(img.img, op)
+	if ii := img.image(); ii != nil {
+		screen.DrawImage(ii, op)
+	}
 }
 
 // Layout returns the object dimensions.
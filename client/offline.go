@@ -0,0 +1,373 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/dhaifley/game2d/errors"
+	"github.com/dhaifley/game2d/logger"
+	"github.com/google/uuid"
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// Offline defaults.
+const (
+	DefaultSyncInterval = 30 * time.Second
+	queueDir            = "queue"
+)
+
+// syncer values track the API revision of the game, and whether the API is
+// reachable, so that game saves made while offline can be queued locally and
+// sent to the API when it is reachable again.
+type syncer struct {
+	sync.Mutex
+	send     sync.Mutex
+	interval time.Duration
+	offline  bool
+	changed  bool
+	rev      int64
+	err      error
+}
+
+// SetSyncInterval sets the interval at which queued game saves are sent to the
+// API, once it is reachable again.
+func (g *Game) SetSyncInterval(interval time.Duration) {
+	g.sq.Lock()
+	defer g.sq.Unlock()
+
+	g.sq.interval = interval
+}
+
+// Offline returns whether the API was unreachable the last time the game was
+// saved, loaded, or synchronized.
+func (g *Game) Offline() bool {
+	g.sq.Lock()
+	defer g.sq.Unlock()
+
+	return g.sq.offline
+}
+
+// setOffline records whether the API is reachable.
+func (g *Game) setOffline(offline bool) {
+	g.sq.Lock()
+	defer g.sq.Unlock()
+
+	if g.sq.offline != offline {
+		g.sq.offline = offline
+		g.sq.changed = true
+	}
+}
+
+// revision returns the API revision of the game which the game state is
+// based on.
+func (g *Game) revision() int64 {
+	g.sq.Lock()
+	defer g.sq.Unlock()
+
+	return g.sq.rev
+}
+
+// setRevision sets the API revision of the game which the game state is
+// based on.
+func (g *Game) setRevision(rev int64) {
+	g.sq.Lock()
+	defer g.sq.Unlock()
+
+	g.sq.rev = rev
+}
+
+// saved records the API revision of the game from an API save response.
+func (g *Game) saved(b []byte) {
+	var res struct {
+		Revision int64 `json:"revision"`
+	}
+
+	if err := json.Unmarshal(b, &res); err == nil {
+		g.setRevision(res.Revision)
+	}
+
+	g.setOffline(false)
+}
+
+// title returns the game window title, which shows whether the game is
+// offline, or spectating.
+func (g *Game) title() string {
+	if g.Offline() {
+		return g.name + " (offline)"
+	}
+
+	if g.Spectating() != "" {
+		return g.name + " (spectating)"
+	}
+
+	return g.name
+}
+
+// queuePath returns the path of the local queue file for a game.
+func queuePath(id string, suffix string) (string, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return "", errors.New(errors.ErrClient,
+			"invalid game id",
+			"game_id", id)
+	}
+
+	return configPath(queueDir, id+suffix+".json")
+}
+
+// queued returns the queued game save, or nil if none is queued.
+func (g *Game) queued() ([]byte, error) {
+	file, err := queuePath(g.id, "")
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := os.ReadFile(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+
+		return nil, errors.Wrap(err, errors.ErrClient,
+			"unable to read queued game save",
+			"file", file)
+	}
+
+	return b, nil
+}
+
+// queueSave stores a game save locally, to be sent to the API when it is
+// reachable.
+func (g *Game) queueSave(b []byte) error {
+	g.sq.send.Lock()
+	defer g.sq.send.Unlock()
+
+	file, err := queuePath(g.id, "")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return errors.Wrap(err, errors.ErrClient,
+			"unable to create queue directory",
+			"file", file)
+	}
+
+	tmp := file + ".tmp"
+
+	if err := os.WriteFile(tmp, b, 0o644); err != nil {
+		return errors.Wrap(err, errors.ErrClient,
+			"unable to write queued game save",
+			"file", tmp)
+	}
+
+	if err := os.Rename(tmp, file); err != nil {
+		return errors.Wrap(err, errors.ErrClient,
+			"unable to write queued game save",
+			"file", file)
+	}
+
+	return nil
+}
+
+// saveAPI sends a game save to the API. If the API is unreachable, the save is
+// queued locally instead. Once a save is queued, later saves are also queued,
+// and then sent in order to keep conflict detection intact.
+func (g *Game) saveAPI(b []byte) error {
+	if q, err := g.queued(); err == nil && q == nil {
+		rb, err := g.apiRequest(http.MethodPost, bytes.NewReader(b), nil,
+			[]int{http.StatusCreated, http.StatusOK}, "games")
+		if err == nil {
+			g.saved(rb)
+
+			return nil
+		}
+
+		if !errors.Has(err, errors.ErrUnavailable) {
+			return errors.Wrap(err, errors.ErrClient,
+				"unable to save game")
+		}
+
+		g.log.Log(context.Background(), logger.LvlWarn,
+			"game2d API unreachable, queueing game save",
+			"error", err,
+			"game_id", g.id)
+
+		g.setOffline(true)
+
+		return g.queueSave(b)
+	}
+
+	if err := g.queueSave(b); err != nil {
+		return err
+	}
+
+	if err := g.flushQueue(g); err != nil &&
+		!errors.Has(err, errors.ErrUnavailable) {
+		return err
+	}
+
+	return nil
+}
+
+// flushQueue sends the queued game save to the API, using the API settings of
+// the remote game. If the game has been changed in the API since the revision
+// which the queued save is based on, the queued save is not sent, but kept in
+// a conflict file instead, and a conflict error is returned.
+func (g *Game) flushQueue(remote *Game) error {
+	g.sq.send.Lock()
+	defer g.sq.send.Unlock()
+
+	b, err := remote.queued()
+	if err != nil || b == nil {
+		return err
+	}
+
+	var q struct {
+		Revision int64 `json:"revision"`
+	}
+
+	if err := json.Unmarshal(b, &q); err != nil {
+		return errors.Wrap(err, errors.ErrClient,
+			"unable to decode queued game save",
+			"game_id", remote.id)
+	}
+
+	rb, err := remote.apiRequest(http.MethodGet, nil, nil,
+		[]int{http.StatusOK}, "games", remote.id)
+	if err != nil && !errors.Has(err, errors.ErrNotFound) {
+		if errors.Has(err, errors.ErrUnavailable) {
+			g.setOffline(true)
+		}
+
+		return errors.Wrap(err, errors.ErrClient,
+			"unable to check game revision",
+			"game_id", remote.id)
+	}
+
+	file, _ := queuePath(remote.id, "")
+
+	if err == nil {
+		var cur struct {
+			Revision int64 `json:"revision"`
+		}
+
+		if err := json.Unmarshal(rb, &cur); err != nil {
+			return errors.Wrap(err, errors.ErrClient,
+				"unable to decode game",
+				"game_id", remote.id)
+		}
+
+		if cur.Revision != q.Revision {
+			g.setOffline(false)
+
+			cf, _ := queuePath(remote.id, ".conflict")
+
+			if err := os.Rename(file, cf); err != nil {
+				return errors.Wrap(err, errors.ErrClient,
+					"unable to keep conflicting game save",
+					"file", cf)
+			}
+
+			return errors.New(errors.ErrConflict,
+				"game changed while offline, local game save kept",
+				"game_id", remote.id,
+				"revision", q.Revision,
+				"api_revision", cur.Revision,
+				"file", cf)
+		}
+	}
+
+	rb, err = remote.apiRequest(http.MethodPost, bytes.NewReader(b), nil,
+		[]int{http.StatusCreated, http.StatusOK}, "games")
+	if err != nil {
+		if errors.Has(err, errors.ErrUnavailable) {
+			g.setOffline(true)
+		}
+
+		return errors.Wrap(err, errors.ErrClient,
+			"unable to send queued game save",
+			"game_id", remote.id)
+	}
+
+	g.saved(rb)
+
+	if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+		return errors.Wrap(err, errors.ErrClient,
+			"unable to remove queued game save",
+			"file", file)
+	}
+
+	return nil
+}
+
+// Sync sends queued game saves to the API at the sync interval, until the
+// context is done. Errors, such as conflicts, are reported by the next Update.
+func (g *Game) Sync(ctx context.Context) {
+	g.sq.Lock()
+	interval := g.sq.interval
+	g.sq.Unlock()
+
+	if interval <= 0 {
+		interval = DefaultSyncInterval
+	}
+
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+
+		g.wat.Lock()
+		remote := g.wat.src
+		g.wat.Unlock()
+
+		if remote == nil || remote.apiURL == "" || remote.file != "" {
+			continue
+		}
+
+		if err := g.flushQueue(remote); err != nil {
+			if errors.Has(err, errors.ErrUnavailable) {
+				g.log.Log(ctx, logger.LvlDebug,
+					"game2d API unreachable, keeping queued game save",
+					"error", err)
+
+				continue
+			}
+
+			g.log.Log(ctx, logger.LvlError,
+				"unable to send queued game save",
+				"error", err)
+
+			g.sq.Lock()
+			g.sq.err = err
+			g.sq.Unlock()
+		}
+	}
+}
+
+// updateSync applies changes to the sync state, made in the background, to
+// the game each frame.
+func (g *Game) updateSync() {
+	g.sq.Lock()
+	err, changed := g.sq.err, g.sq.changed
+	g.sq.err, g.sq.changed = nil, false
+	g.sq.Unlock()
+
+	if err != nil {
+		g.err = err
+	}
+
+	if changed {
+		ebiten.SetWindowTitle(g.title())
+	}
+}
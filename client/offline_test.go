@@ -0,0 +1,154 @@
+package client_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dhaifley/game2d/client"
+	"github.com/stretchr/testify/assert"
+)
+
+// testAPI values represent a game2d API which stores a single game, and can
+// be made unreachable.
+type testAPI struct {
+	sync.Mutex
+	down  bool
+	posts int
+	game  map[string]any
+}
+
+func (a *testAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	a.Lock()
+	defer a.Unlock()
+
+	if a.down {
+		if hj, ok := w.(http.Hijacker); ok {
+			if conn, _, err := hj.Hijack(); err == nil {
+				conn.Close()
+			}
+		}
+
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		if a.game == nil {
+			w.WriteHeader(http.StatusNotFound)
+
+			return
+		}
+
+		json.NewEncoder(w).Encode(a.game)
+	case http.MethodPost:
+		b, _ := io.ReadAll(r.Body)
+
+		rev, _ := a.game["revision"].(float64)
+
+		a.game = map[string]any{}
+
+		json.Unmarshal(b, &a.game)
+
+		a.game["revision"] = rev + 1
+		a.posts++
+
+		w.WriteHeader(http.StatusCreated)
+
+		json.NewEncoder(w).Encode(a.game)
+	}
+}
+
+func (a *testAPI) set(fn func(a *testAPI)) {
+	a.Lock()
+	defer a.Unlock()
+
+	fn(a)
+}
+
+func TestOfflineSync(t *testing.T) {
+	dir := t.TempDir()
+
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("HOME", dir)
+
+	api := &testAPI{}
+
+	ts := httptest.NewServer(api)
+
+	t.Cleanup(ts.Close)
+
+	game := client.NewGame(nil, client.DefaultGameWidth,
+		client.DefaultGameHeight, "", TestName, TestDesc)
+
+	game.SetScript(TestScript)
+	game.AddSubject(client.NewSubject(game, TestID, TestName, TestID, nil))
+	game.AddObject(client.NewObject(game, TestID, TestName, TestID, nil))
+	game.SetAPIURL(ts.URL)
+
+	err := game.Save()
+	assert.NoError(t, err)
+	assert.False(t, game.Offline())
+
+	err = game.Load()
+	assert.NoError(t, err)
+
+	api.set(func(a *testAPI) { a.down = true })
+
+	err = game.Save()
+	assert.NoError(t, err, "Save should queue the game while offline")
+	assert.True(t, game.Offline())
+
+	err = game.Load()
+	assert.NoError(t, err, "Load should use the queued game while offline")
+
+	api.set(func(a *testAPI) { a.down = false })
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	t.Cleanup(cancel)
+
+	game.SetSyncInterval(10 * time.Millisecond)
+
+	go game.Sync(ctx)
+
+	assert.Eventually(t, func() bool {
+		api.Lock()
+		defer api.Unlock()
+
+		return !game.Offline() && api.posts == 2
+	}, 5*time.Second, 10*time.Millisecond,
+		"Queued game save should be sent when online")
+
+	api.set(func(a *testAPI) { a.down = true })
+
+	err = game.Save()
+	assert.NoError(t, err)
+
+	api.set(func(a *testAPI) {
+		a.game["revision"] = float64(10)
+		a.down = false
+	})
+
+	conflict := filepath.Join(dir, "game2d", "queue",
+		game.ID()+".conflict.json")
+
+	assert.Eventually(t, func() bool {
+		_, err := os.Stat(conflict)
+
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond,
+		"Conflicting game save should be kept locally")
+
+	api.Lock()
+	assert.Equal(t, 2, api.posts,
+		"Conflicting game save should not be sent")
+	api.Unlock()
+}
@@ -0,0 +1,401 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/png"
+	"math"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+
+	"github.com/Shopify/go-lua"
+	"github.com/dhaifley/game2d/client/luaapi"
+	"github.com/dhaifley/game2d/errors"
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// DefaultTestFile is the file from which visual regression tests are loaded,
+// if no other file is specified.
+const DefaultTestFile = "game2d_test.json"
+
+// DefaultGoldenDir is the directory containing the golden images of visual
+// regression tests, if no other directory is specified.
+const DefaultGoldenDir = "golden"
+
+// TestInput values represent the keys held down, by name, for a number of
+// frames of a visual regression test, starting at a frame. Frames are
+// numbered from one.
+type TestInput struct {
+	Frame  int      `json:"frame"`
+	Frames int      `json:"frames,omitempty"`
+	Keys   []string `json:"keys"`
+}
+
+// TestScript values represent visual regression tests of games. The game is
+// run without a display for a number of frames, using the inputs of the test,
+// with lua random numbers generated from the seed of the test. After each of
+// the snapshot frames, the game is rendered and the image compared to the
+// golden image of the frame. The threshold is the number of pixels allowed
+// to differ from the golden image.
+type TestScript struct {
+	Seed      int64        `json:"seed"`
+	Frames    int          `json:"frames"`
+	Inputs    []*TestInput `json:"inputs,omitempty"`
+	Snapshots []int        `json:"snapshots"`
+	Threshold int          `json:"threshold,omitempty"`
+}
+
+// TestResult values represent the result of comparing a frame rendered by a
+// visual regression test to its golden image.
+type TestResult struct {
+	Frame   int    `json:"frame"`
+	Golden  string `json:"golden"`
+	Pixels  int    `json:"pixels"`
+	Updated bool   `json:"updated,omitempty"`
+	Failure string `json:"failure,omitempty"`
+}
+
+// Passed returns whether the rendered frame matched its golden image.
+func (r *TestResult) Passed() bool {
+	return r.Failure == ""
+}
+
+// LoadTestScript reads a visual regression test from a JSON file.
+func LoadTestScript(file string) (*TestScript, error) {
+	if file == "" {
+		file = DefaultTestFile
+	}
+
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrClient,
+			"unable to read test script",
+			"file", file)
+	}
+
+	ts := &TestScript{}
+
+	if err := json.Unmarshal(b, ts); err != nil {
+		return nil, errors.Wrap(err, errors.ErrClient,
+			"unable to decode test script",
+			"file", file)
+	}
+
+	if err := ts.Validate(); err != nil {
+		return nil, err
+	}
+
+	return ts, nil
+}
+
+// Validate checks that the test frames and inputs are valid.
+func (ts *TestScript) Validate() error {
+	if ts.Frames <= 0 {
+		return errors.New(errors.ErrClient,
+			"test frames must be greater than zero",
+			"frames", ts.Frames)
+	}
+
+	if len(ts.Snapshots) == 0 {
+		return errors.New(errors.ErrClient,
+			"test has no snapshot frames")
+	}
+
+	for _, f := range ts.Snapshots {
+		if f < 1 || f > ts.Frames {
+			return errors.New(errors.ErrClient,
+				"test snapshot frame out of range",
+				"frame", f,
+				"frames", ts.Frames)
+		}
+	}
+
+	for _, in := range ts.Inputs {
+		if in == nil || in.Frame < 1 || in.Frame > ts.Frames ||
+			in.Frames < 0 {
+			return errors.New(errors.ErrClient,
+				"invalid test input frames",
+				"input", in)
+		}
+
+		for _, k := range in.Keys {
+			if _, ok := luaapi.KeyCode(k); !ok {
+				return errors.New(errors.ErrClient,
+					"invalid test input key",
+					"key", k)
+			}
+		}
+	}
+
+	return nil
+}
+
+// keys returns the keys held down during a frame of the test.
+func (ts *TestScript) keys(frame int) []ebiten.Key {
+	res := []ebiten.Key{}
+
+	for _, in := range ts.Inputs {
+		if frame < in.Frame || frame >= in.Frame+max(in.Frames, 1) {
+			continue
+		}
+
+		for _, k := range in.Keys {
+			c, _ := luaapi.KeyCode(k)
+
+			if !slices.Contains(res, ebiten.Key(c)) {
+				res = append(res, ebiten.Key(c))
+			}
+		}
+	}
+
+	slices.Sort(res)
+
+	return res
+}
+
+// seedScript replaces the lua random number functions with ones generating
+// numbers from a seed, so that games using them run deterministically.
+func (g *Game) seedScript(seed int64) {
+	rng := rand.New(rand.NewPCG(uint64(seed), 0))
+
+	g.lua.Global("math")
+
+	g.lua.PushGoFunction(func(l *lua.State) int {
+		r := rng.Float64()
+
+		switch l.Top() {
+		case 0:
+			l.PushNumber(r)
+		case 1:
+			u := lua.CheckNumber(l, 1)
+			lua.ArgumentCheck(l, 1.0 <= u, 1, "interval is empty")
+			l.PushNumber(math.Floor(r*u) + 1.0)
+		case 2:
+			lo, u := lua.CheckNumber(l, 1), lua.CheckNumber(l, 2)
+			lua.ArgumentCheck(l, lo <= u, 2, "interval is empty")
+			l.PushNumber(math.Floor(r*(u-lo+1)) + lo)
+		default:
+			lua.Errorf(l, "wrong number of arguments")
+		}
+
+		return 1
+	})
+	g.lua.SetField(-2, "random")
+
+	g.lua.PushGoFunction(func(l *lua.State) int {
+		rng = rand.New(rand.NewPCG(uint64(lua.CheckUnsigned(l, 1)), 0))
+
+		return 0
+	})
+	g.lua.SetField(-2, "randomseed")
+
+	g.lua.Pop(1)
+}
+
+// scriptKeys returns the keys table passed to game scripts, containing the
+// codes of the keys pressed, keyed by the strings "0", "1" and so on.
+func scriptKeys(keys []ebiten.Key) map[string]any {
+	res := make(map[string]any, len(keys))
+
+	for i, k := range keys {
+		res[strconv.Itoa(i)] = int(k)
+	}
+
+	return res
+}
+
+// step runs the game script for a frame, with keys held down, without reading
+// input from the keyboard. As when playing, pressing a key while the game is
+// paused resumes it.
+func (g *Game) step(keys []ebiten.Key) error {
+	if g.pause {
+		if len(keys) > 0 {
+			g.pause = false
+		}
+
+		return nil
+	}
+
+	if g.src == "" {
+		return nil
+	}
+
+	if g.sub == nil {
+		return errors.New(errors.ErrClient,
+			"game subject object not found",
+			"game", g)
+	}
+
+	if err := g.runScript(scriptKeys(keys), g.actions(keys)); err != nil {
+		g.scriptFailed(err)
+
+		return err
+	}
+
+	g.menu.selected = ""
+
+	return nil
+}
+
+// RunTest runs a visual regression test of the game, which must already be
+// loaded, comparing the rendered snapshot frames to the golden images in a
+// directory. If update is true, the golden images are written instead. When a
+// frame does not match its golden image, the rendered frame is written next
+// to it, with an _actual suffix, for inspection.
+func (g *Game) RunTest(ts *TestScript, dir string, update bool,
+) ([]*TestResult, error) {
+	if err := ts.Validate(); err != nil {
+		return nil, err
+	}
+
+	if dir == "" {
+		dir = DefaultGoldenDir
+	}
+
+	g.pause = false
+
+	g.seedScript(ts.Seed)
+
+	res := make([]*TestResult, 0, len(ts.Snapshots))
+
+	for f := 1; f <= ts.Frames; f++ {
+		if err := g.step(ts.keys(f)); err != nil {
+			return res, errors.Wrap(err, errors.ErrClient,
+				"unable to run test frame",
+				"frame", f)
+		}
+
+		if !slices.Contains(ts.Snapshots, f) {
+			continue
+		}
+
+		img, err := g.Render()
+		if err != nil {
+			return res, errors.Wrap(err, errors.ErrClient,
+				"unable to render test frame",
+				"frame", f)
+		}
+
+		r, err := compareGolden(img, dir, f, ts.Threshold, update)
+		if err != nil {
+			return res, err
+		}
+
+		res = append(res, r)
+	}
+
+	return res, nil
+}
+
+// goldenFile returns the path of the golden image of a frame.
+func goldenFile(dir string, frame int, suffix string) string {
+	return filepath.Join(dir, fmt.Sprintf("frame_%05d%s.png", frame, suffix))
+}
+
+// compareGolden compares a rendered frame to its golden image, or writes the
+// golden image, if update is true.
+func compareGolden(img *image.RGBA,
+	dir string,
+	frame, threshold int,
+	update bool,
+) (*TestResult, error) {
+	res := &TestResult{Frame: frame, Golden: goldenFile(dir, frame, "")}
+
+	if update {
+		if err := writePNG(res.Golden, img); err != nil {
+			return nil, err
+		}
+
+		res.Updated = true
+
+		return res, nil
+	}
+
+	b, err := os.ReadFile(res.Golden)
+	if err != nil {
+		res.Failure = "golden image not found"
+
+		return res, nil
+	}
+
+	golden, err := png.Decode(bytes.NewReader(b))
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrClient,
+			"unable to decode golden image",
+			"file", res.Golden)
+	}
+
+	res.Pixels = diffPixels(img, golden)
+
+	switch {
+	case res.Pixels < 0:
+		res.Failure = "image size differs from golden image " +
+			golden.Bounds().Size().String()
+	case res.Pixels > threshold:
+		res.Failure = strconv.Itoa(res.Pixels) +
+			" pixels differ from golden image"
+	default:
+		return res, nil
+	}
+
+	if err := writePNG(goldenFile(dir, frame, "_actual"), img); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// diffPixels returns the number of pixels which differ between two images, or
+// -1 if their sizes differ.
+func diffPixels(a *image.RGBA, b image.Image) int {
+	if a.Bounds().Size() != b.Bounds().Size() {
+		return -1
+	}
+
+	n := 0
+
+	for y := range a.Bounds().Dy() {
+		for x := range a.Bounds().Dx() {
+			ar, ag, ab, aa := a.At(a.Bounds().Min.X+x,
+				a.Bounds().Min.Y+y).RGBA()
+			br, bg, bb, ba := b.At(b.Bounds().Min.X+x,
+				b.Bounds().Min.Y+y).RGBA()
+
+			if ar != br || ag != bg || ab != bb || aa != ba {
+				n++
+			}
+		}
+	}
+
+	return n
+}
+
+// writePNG writes an image to a PNG file, creating its directory if needed.
+func writePNG(file string, img image.Image) error {
+	buf := &bytes.Buffer{}
+
+	if err := png.Encode(buf, img); err != nil {
+		return errors.Wrap(err, errors.ErrClient,
+			"unable to encode image",
+			"file", file)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return errors.Wrap(err, errors.ErrClient,
+			"unable to create image directory",
+			"file", file)
+	}
+
+	if err := os.WriteFile(file, buf.Bytes(), 0o644); err != nil {
+		return errors.Wrap(err, errors.ErrClient,
+			"unable to write image",
+			"file", file)
+	}
+
+	return nil
+}
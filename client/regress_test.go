@@ -0,0 +1,114 @@
+package client_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dhaifley/game2d/assets"
+	"github.com/dhaifley/game2d/client"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestGame creates a game with a subject which moves right while the right
+// action is active, and a randomly rotated object.
+func newTestGame(t *testing.T) *client.Game {
+	t.Helper()
+
+	svg, err := assets.GetImage("avatar.svg")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	game := client.NewGame(nil, client.DefaultGameWidth,
+		client.DefaultGameHeight, TestID, TestName, TestDesc)
+
+	game.AddImage(client.NewImage("p1", "avatar.svg", svg, 64, 64))
+
+	sub := client.NewSubject(game, "p1", "Player 1", "p1", nil)
+	sub.SetX(100)
+	sub.SetY(100)
+
+	game.AddSubject(sub)
+	game.AddObject(client.NewObject(game, "obj", "Object", "p1", nil))
+
+	game.SetBindings(map[string][]string{"right": {"ArrowRight"}})
+
+	game.SetScript(`function Update(game)
+if game.actions.right then
+	game.subject.x = game.subject.x + 4
+end
+game.objects.obj.r = math.random(0, 359)
+return game
+end`)
+
+	return game
+}
+
+func TestRunTest(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("AppData", t.TempDir())
+
+	dir := t.TempDir()
+
+	ts := &client.TestScript{
+		Seed:   1,
+		Frames: 10,
+		Inputs: []*client.TestInput{{
+			Frame: 2, Frames: 5, Keys: []string{"ArrowRight"},
+		}},
+		Snapshots: []int{1, 10},
+	}
+
+	res, err := newTestGame(t).RunTest(ts, dir, true)
+	assert.NoError(t, err)
+	assert.Len(t, res, 2, "Each snapshot frame should have a result")
+
+	for _, r := range res {
+		assert.True(t, r.Updated, "Golden images should be updated")
+		assert.FileExists(t, r.Golden)
+	}
+
+	res, err = newTestGame(t).RunTest(ts, dir, false)
+	assert.NoError(t, err)
+
+	for _, r := range res {
+		assert.True(t, r.Passed(), "Test runs should be deterministic: %s",
+			r.Failure)
+	}
+
+	ts.Inputs[0].Frames = 1
+
+	res, err = newTestGame(t).RunTest(ts, dir, false)
+	assert.NoError(t, err)
+	assert.True(t, res[0].Passed(), "Unchanged frames should pass")
+	assert.False(t, res[1].Passed(), "Changed frames should fail")
+	assert.Positive(t, res[1].Pixels, "Changed pixels should be counted")
+	assert.FileExists(t, filepath.Join(dir, "frame_00010_actual.png"),
+		"Changed frames should be written")
+
+	ts.Inputs[0].Keys = []string{"NotAKey"}
+
+	_, err = newTestGame(t).RunTest(ts, dir, false)
+	assert.Error(t, err, "Invalid keys should not be used")
+}
+
+func TestLoadTestScript(t *testing.T) {
+	file := filepath.Join(t.TempDir(), client.DefaultTestFile)
+
+	err := os.WriteFile(file, []byte(`{"seed": 1, "frames": 5,
+"inputs": [{"frame": 1, "keys": ["Space"]}], "snapshots": [5]}`), 0o644)
+	assert.NoError(t, err)
+
+	ts, err := client.LoadTestScript(file)
+	assert.NoError(t, err)
+	assert.Equal(t, 5, ts.Frames)
+
+	err = os.WriteFile(file, []byte(`{"frames": 5, "snapshots": [6]}`),
+		0o644)
+	assert.NoError(t, err)
+
+	_, err = client.LoadTestScript(file)
+	assert.Error(t, err, "Snapshots after the last frame should be invalid")
+}
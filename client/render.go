@@ -0,0 +1,167 @@
+package client
+
+import (
+	"bytes"
+	"cmp"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"slices"
+
+	"github.com/dhaifley/game2d/errors"
+	"github.com/srwiley/oksvg"
+)
+
+// drawOrder returns the visible objects of the game, other than the subject,
+// in the order in which they are drawn: by z index, and then by ID, so that
+// overlapping objects are always drawn in the same order.
+func (g *Game) drawOrder() []*Object {
+	res := make([]*Object, 0, len(g.obj))
+
+	for _, obj := range g.obj {
+		if obj == nil || obj.hidden {
+			continue
+		}
+
+		res = append(res, obj)
+	}
+
+	slices.SortFunc(res, func(a, b *Object) int {
+		return cmp.Or(cmp.Compare(a.z, b.z), cmp.Compare(a.id, b.id))
+	})
+
+	return res
+}
+
+// raster rasterizes the image SVG data, without using the GPU, for software
+// rendering.
+func (i *Image) raster() (image.Image, error) {
+	if len(i.data) == 0 {
+		return nil, nil
+	}
+
+	icon, err := oksvg.ReadIconStream(bytes.NewReader(i.data))
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrClient,
+			"unable to parse SVG data",
+			"image", i.id)
+	}
+
+	w, h := svgSize(icon, i.w, i.h)
+
+	return rasterizeSVG(icon, w, h), nil
+}
+
+// Render renders the game objects to an image using a software rasterizer,
+// which, unlike Draw, requires neither a display nor a running game loop, and
+// renders identical images on every platform. The debug overlay and the menus
+// are not rendered.
+func (g *Game) Render() (*image.RGBA, error) {
+	w, h := g.w, g.h
+
+	if w <= 0 || h <= 0 {
+		w, h = DefaultGameWidth, DefaultGameHeight
+	}
+
+	screen := image.NewRGBA(image.Rect(0, 0, w, h))
+
+	rasters := map[string]image.Image{}
+
+	objects := g.drawOrder()
+
+	if g.sub != nil && !g.sub.hidden {
+		objects = append(objects, g.sub)
+	}
+
+	for _, obj := range objects {
+		if obj.img == "" || obj.x < 0 || obj.x > w || obj.y < 0 || obj.y > h {
+			continue
+		}
+
+		img := g.img[obj.img]
+		if img == nil {
+			continue
+		}
+
+		src, ok := rasters[obj.img]
+		if !ok {
+			var err error
+
+			if src, err = img.raster(); err != nil {
+				return nil, err
+			}
+
+			rasters[obj.img] = src
+		}
+
+		if src != nil {
+			renderImage(screen, src, obj.x, obj.y, obj.r)
+		}
+	}
+
+	return screen, nil
+}
+
+// renderImage draws an image over the screen at a position, rotated about its
+// top left corner by an angle in degrees, in the same way as Object.Draw.
+// Rotated images are sampled using the nearest pixel.
+func renderImage(screen *image.RGBA, src image.Image, x, y, r int) {
+	sb := src.Bounds()
+
+	if r == 0 {
+		draw.Draw(screen, sb.Sub(sb.Min).Add(image.Pt(x, y)), src, sb.Min,
+			draw.Over)
+
+		return
+	}
+
+	sin, cos := math.Sincos(float64(r) * (3.14 / 180))
+
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+
+	for _, p := range []image.Point{
+		{0, 0}, {sb.Dx(), 0}, {0, sb.Dy()}, {sb.Dx(), sb.Dy()},
+	} {
+		px := float64(p.X)*cos - float64(p.Y)*sin + float64(x)
+		py := float64(p.X)*sin + float64(p.Y)*cos + float64(y)
+
+		minX, maxX = min(minX, px), max(maxX, px)
+		minY, maxY = min(minY, py), max(maxY, py)
+	}
+
+	db := image.Rect(int(math.Floor(minX)), int(math.Floor(minY)),
+		int(math.Ceil(maxX)), int(math.Ceil(maxY))).Intersect(screen.Bounds())
+
+	for dy := db.Min.Y; dy < db.Max.Y; dy++ {
+		for dx := db.Min.X; dx < db.Max.X; dx++ {
+			px, py := float64(dx)+0.5-float64(x), float64(dy)+0.5-float64(y)
+
+			sx := int(math.Floor(px*cos + py*sin))
+			sy := int(math.Floor(-px*sin + py*cos))
+
+			if sx < 0 || sx >= sb.Dx() || sy < 0 || sy >= sb.Dy() {
+				continue
+			}
+
+			sc := color.RGBAModel.Convert(
+				src.At(sb.Min.X+sx, sb.Min.Y+sy)).(color.RGBA)
+
+			if sc.A == 0 {
+				continue
+			}
+
+			dc := screen.RGBAAt(dx, dy)
+
+			a := 255 - uint32(sc.A)
+
+			screen.SetRGBA(dx, dy, color.RGBA{
+				R: uint8(uint32(sc.R) + uint32(dc.R)*a/255),
+				G: uint8(uint32(sc.G) + uint32(dc.G)*a/255),
+				B: uint8(uint32(sc.B) + uint32(dc.B)*a/255),
+				A: uint8(uint32(sc.A) + uint32(dc.A)*a/255),
+			})
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+
+	"github.com/dhaifley/game2d/errors"
+	"github.com/dhaifley/game2d/logger"
+)
+
+// Error report limits, matching those of the API.
+const (
+	maxReportError = 4096
+	maxReportStack = 32768
+)
+
+// errorReport values represent a client error reported to the API.
+type errorReport struct {
+	SessionID string `json:"session_id,omitempty"`
+	Error     string `json:"error"`
+	Stack     string `json:"stack,omitempty"`
+	Revision  int64  `json:"revision"`
+	Platform  string `json:"platform"`
+	Panic     bool   `json:"panic"`
+}
+
+// apiGame returns whether the game is played from the API, rather than from a
+// local file or embedded data.
+func (g *Game) apiGame() bool {
+	return g.apiURL != "" && g.file == "" && g.data == nil
+}
+
+// truncate shortens a string to at most n bytes.
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+
+	return s
+}
+
+// newErrorReport creates an error report for the game.
+func (g *Game) newErrorReport(err error, stack string,
+	panicked bool,
+) *errorReport {
+	return &errorReport{
+		SessionID: g.Session(),
+		Error:     truncate(err.Error(), maxReportError),
+		Stack:     truncate(stack, maxReportStack),
+		Revision:  g.revision(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+		Panic:     panicked,
+	}
+}
+
+// sendErrorReport sends an error report for the game to the API.
+func (g *Game) sendErrorReport(id string, r *errorReport) error {
+	b, err := json.Marshal(r)
+	if err != nil {
+		return errors.Wrap(err, errors.ErrClient,
+			"unable to encode error report")
+	}
+
+	if _, err := g.apiRequest(http.MethodPost, bytes.NewReader(b), nil,
+		[]int{http.StatusCreated}, "games", id, "errors"); err != nil {
+		return errors.Wrap(err, errors.ErrClient,
+			"unable to send error report",
+			"game_id", id)
+	}
+
+	return nil
+}
+
+// reportError reports a script error of a game played from the API, so that
+// the creator of the game can see why it failed. The report is sent in the
+// background and failures are only logged.
+func (g *Game) reportError(err error, stack string, panicked bool) {
+	if err == nil || !g.apiGame() {
+		return
+	}
+
+	id, r := g.id, g.newErrorReport(err, stack, panicked)
+
+	go func() {
+		if err := g.sendErrorReport(id, r); err != nil {
+			g.log.Log(context.Background(), logger.LvlDebug,
+				"unable to report game error",
+				"error", err,
+				"game_id", id)
+		}
+	}()
+}
+
+// recoverPanic recovers from a panic while running a game played from the
+// API, reports it, then panics again, so that the client still crashes. It
+// must be deferred.
+func (g *Game) recoverPanic() {
+	v := recover()
+	if v == nil {
+		return
+	}
+
+	if g.apiGame() {
+		err := errors.New(errors.ErrClient, fmt.Sprint(v))
+
+		if rErr := g.sendErrorReport(g.id, g.newErrorReport(err,
+			string(debug.Stack()), true)); rErr != nil {
+			g.log.Log(context.Background(), logger.LvlError,
+				"unable to report game panic",
+				"error", rErr,
+				"game_id", g.id)
+		}
+	}
+
+	panic(v)
+}
@@ -0,0 +1,58 @@
+package client_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReportError(t *testing.T) {
+	game := newRunningGame(t, "function Update(data)\nerror(\"boom\")\nend", 0)
+
+	reports := make(chan map[string]any, 1)
+
+	ts := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPost ||
+				r.URL.Path != "/games/"+game.ID()+"/errors" {
+				w.WriteHeader(http.StatusNotFound)
+
+				return
+			}
+
+			var rep map[string]any
+
+			if err := json.NewDecoder(r.Body).Decode(&rep); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+
+				return
+			}
+
+			w.WriteHeader(http.StatusCreated)
+
+			reports <- rep
+		}))
+
+	t.Cleanup(ts.Close)
+
+	game.SetAPIURL(ts.URL)
+
+	err := game.Update()
+	assert.NoError(t, err, "Update should not return an error")
+
+	select {
+	case rep := <-reports:
+		assert.Contains(t, rep["error"], "boom")
+		assert.True(t, strings.Contains(rep["stack"].(string), "traceback"),
+			"Report should contain the script traceback")
+		assert.NotEmpty(t, rep["platform"])
+		assert.Equal(t, false, rep["panic"])
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for error report")
+	}
+}
@@ -0,0 +1,297 @@
+package client
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/dhaifley/game2d/errors"
+	"github.com/google/uuid"
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// Save slot defaults.
+const (
+	savesDir = "saves"
+)
+
+// saveSlotPattern matches valid save slot names.
+var saveSlotPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// SaveSlot values describe the named save slots of a game.
+type SaveSlot struct {
+	Slot      string `json:"slot"`
+	Size      int64  `json:"size"`
+	UpdatedAt int64  `json:"updated_at"`
+}
+
+// apiSaves returns whether save slots are stored by the API, rather than in
+// local files.
+func (g *Game) apiSaves() bool {
+	return g.apiURL != "" && g.file == "" && g.data == nil
+}
+
+// slotPath returns the path of the local file of a save slot for a game.
+func slotPath(id, slot string) (string, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return "", errors.New(errors.ErrClient,
+			"invalid game id",
+			"game_id", id)
+	}
+
+	if !saveSlotPattern.MatchString(slot) {
+		return "", errors.New(errors.ErrClient,
+			"invalid save slot",
+			"slot", slot)
+	}
+
+	return configPath(savesDir, id, slot+".json")
+}
+
+// SaveSlot persists the game state in a named save slot, using the API, if
+// an API URL is set, or else a local file.
+func (g *Game) SaveSlot(slot string) (rErr error) {
+	ebiten.SetWindowTitle(g.name + " (saving...)")
+
+	defer func() {
+		if rErr != nil {
+			g.err = rErr
+		}
+
+		ebiten.SetWindowTitle(g.title())
+	}()
+
+	b, err := json.Marshal(g)
+	if err != nil {
+		return errors.Wrap(err, errors.ErrClient,
+			"unable to encode game save")
+	}
+
+	if g.apiSaves() {
+		if !saveSlotPattern.MatchString(slot) {
+			return errors.New(errors.ErrClient,
+				"invalid save slot",
+				"slot", slot)
+		}
+
+		rb, err := json.Marshal(map[string]any{"data": json.RawMessage(b)})
+		if err != nil {
+			return errors.Wrap(err, errors.ErrClient,
+				"unable to encode game save")
+		}
+
+		if _, err := g.apiRequest(http.MethodPut, bytes.NewReader(rb), nil,
+			[]int{http.StatusOK}, "games", g.id, "saves", slot); err != nil {
+			return errors.Wrap(err, errors.ErrClient,
+				"unable to save game to slot",
+				"slot", slot)
+		}
+
+		return nil
+	}
+
+	file, err := slotPath(g.id, slot)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return errors.Wrap(err, errors.ErrClient,
+			"unable to create save slot directory",
+			"file", file)
+	}
+
+	if err := os.WriteFile(file, b, 0o644); err != nil {
+		return errors.Wrap(err, errors.ErrClient,
+			"unable to write game save",
+			"file", file)
+	}
+
+	return nil
+}
+
+// LoadSlot retrieves the game state persisted in a named save slot.
+func (g *Game) LoadSlot(slot string) (rErr error) {
+	ebiten.SetWindowTitle(g.name + " (loading...)")
+
+	defer func() {
+		ebiten.SetWindowTitle(g.title())
+		g.err = rErr
+	}()
+
+	var b []byte
+
+	if g.apiSaves() {
+		if !saveSlotPattern.MatchString(slot) {
+			return errors.New(errors.ErrClient,
+				"invalid save slot",
+				"slot", slot)
+		}
+
+		rb, err := g.apiRequest(http.MethodGet, nil, nil,
+			[]int{http.StatusOK}, "games", g.id, "saves", slot)
+		if err != nil {
+			return errors.Wrap(err, errors.ErrClient,
+				"unable to load game from slot",
+				"slot", slot)
+		}
+
+		var res struct {
+			Data json.RawMessage `json:"data"`
+		}
+
+		if err := json.Unmarshal(rb, &res); err != nil {
+			return errors.Wrap(err, errors.ErrClient,
+				"unable to decode game save",
+				"slot", slot)
+		}
+
+		b = res.Data
+	} else {
+		file, err := slotPath(g.id, slot)
+		if err != nil {
+			return err
+		}
+
+		if b, err = os.ReadFile(file); err != nil {
+			return errors.Wrap(err, errors.ErrClient,
+				"unable to load game from slot",
+				"file", file)
+		}
+	}
+
+	if err := g.apply(b); err != nil {
+		return err
+	}
+
+	g.emit(EventLoad, map[string]any{
+		"id":   g.id,
+		"name": g.name,
+		"slot": slot,
+	})
+
+	return nil
+}
+
+// Slots lists the save slots of the game, from the most recently saved.
+func (g *Game) Slots() ([]*SaveSlot, error) {
+	if g.apiSaves() {
+		b, err := g.apiRequest(http.MethodGet, nil, nil,
+			[]int{http.StatusOK}, "games", g.id, "saves")
+		if err != nil {
+			return nil, errors.Wrap(err, errors.ErrClient,
+				"unable to list save slots")
+		}
+
+		res := []*SaveSlot{}
+
+		if err := json.Unmarshal(b, &res); err != nil {
+			return nil, errors.Wrap(err, errors.ErrClient,
+				"unable to decode save slots")
+		}
+
+		return res, nil
+	}
+
+	file, err := slotPath(g.id, "slot")
+	if err != nil {
+		return nil, err
+	}
+
+	dir := filepath.Dir(file)
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []*SaveSlot{}, nil
+		}
+
+		return nil, errors.Wrap(err, errors.ErrClient,
+			"unable to list save slots",
+			"dir", dir)
+	}
+
+	res := make([]*SaveSlot, 0, len(entries))
+
+	for _, e := range entries {
+		slot, ok := strings.CutSuffix(e.Name(), ".json")
+		if !ok || e.IsDir() || !saveSlotPattern.MatchString(slot) {
+			continue
+		}
+
+		fi, err := e.Info()
+		if err != nil {
+			continue
+		}
+
+		res = append(res, &SaveSlot{
+			Slot:      slot,
+			Size:      fi.Size(),
+			UpdatedAt: fi.ModTime().Unix(),
+		})
+	}
+
+	slices.SortFunc(res, func(a, b *SaveSlot) int {
+		if c := cmp.Compare(b.UpdatedAt, a.UpdatedAt); c != 0 {
+			return c
+		}
+
+		return strings.Compare(a.Slot, b.Slot)
+	})
+
+	return res, nil
+}
+
+// DeleteSlot deletes a named save slot of the game.
+func (g *Game) DeleteSlot(slot string) error {
+	if g.apiSaves() {
+		if !saveSlotPattern.MatchString(slot) {
+			return errors.New(errors.ErrClient,
+				"invalid save slot",
+				"slot", slot)
+		}
+
+		if _, err := g.apiRequest(http.MethodDelete, nil, nil,
+			[]int{http.StatusNoContent}, "games", g.id, "saves",
+			slot); err != nil {
+			return errors.Wrap(err, errors.ErrClient,
+				"unable to delete save slot",
+				"slot", slot)
+		}
+
+		return nil
+	}
+
+	file, err := slotPath(g.id, slot)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(file); err != nil {
+		return errors.Wrap(err, errors.ErrClient,
+			"unable to delete save slot",
+			"file", file)
+	}
+
+	return nil
+}
+
+// nextSlot returns the name of an unused save slot, from a list of slots.
+func nextSlot(slots []*SaveSlot) string {
+	for i := 1; ; i++ {
+		name := "slot" + strconv.Itoa(i)
+
+		if !slices.ContainsFunc(slots, func(s *SaveSlot) bool {
+			return s.Slot == name
+		}) {
+			return name
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package client_test
+
+import (
+	"testing"
+
+	"github.com/dhaifley/game2d/client"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSaveSlots(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("AppData", t.TempDir())
+
+	game := newRunningGame(t, `function Update(game)
+game.score = game.score + 1
+return game
+end`, 1)
+
+	game.SetID(uuid.NewString())
+
+	err := game.Update()
+	assert.NoError(t, err)
+
+	err = game.SaveSlot("a")
+	assert.NoError(t, err)
+
+	err = game.SaveSlot("bad.slot")
+	assert.Error(t, err, "Invalid slot names should not be saved")
+
+	err = game.Update()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, game.Score())
+
+	slots, err := game.Slots()
+	assert.NoError(t, err)
+	assert.Len(t, slots, 1)
+	assert.Equal(t, "a", slots[0].Slot)
+
+	err = game.LoadSlot("a")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, game.Score(), "Saved score should be loaded")
+
+	game.OpenMenu()
+
+	err = game.SelectMenuEntry(client.MenuSaveSlot)
+	assert.NoError(t, err)
+
+	entries := game.MenuEntries()
+	assert.Len(t, entries, 2, "New and existing slots should be listed")
+	assert.Equal(t, "slot:slot1", entries[0].ID)
+
+	err = game.SelectMenuEntry(entries[0].ID)
+	assert.NoError(t, err)
+
+	slots, err = game.Slots()
+	assert.NoError(t, err)
+	assert.Len(t, slots, 2)
+
+	err = game.DeleteSlot("a")
+	assert.NoError(t, err)
+
+	err = game.LoadSlot("a")
+	assert.Error(t, err, "Deleted slots should not be loaded")
+}
@@ -0,0 +1,251 @@
+package client
+
+import (
+	"bytes"
+
+	"github.com/Shopify/go-lua"
+	"github.com/dhaifley/game2d/client/luaapi"
+	"github.com/dhaifley/game2d/errors"
+)
+
+// Script defaults.
+const (
+	DefaultScriptBudget = 10000000
+)
+
+// gameStatusError is the game status reported when the game script fails.
+const gameStatusError = "error"
+
+// Lua registry keys used to keep script values between frames.
+const (
+	scriptUpdateKey = "game2d.Update"
+	scriptStateKey  = "game2d.State"
+)
+
+// luaLibraries contains the functions used to open the lua libraries which
+// may be described as available to game scripts by the luaapi package.
+var luaLibraries = map[string]lua.Function{
+	"_G":     lua.BaseOpen,
+	"table":  lua.TableOpen,
+	"string": lua.StringOpen,
+	"bit32":  lua.Bit32Open,
+	"math":   lua.MathOpen,
+}
+
+// newLuaState creates a lua state with only the libraries safe to expose to
+// game scripts, as described by the luaapi package. The io, os, package and
+// debug libraries are never opened, and the base library functions able to
+// access the file system are removed.
+func newLuaState() *lua.State {
+	l := lua.NewState()
+
+	for _, lib := range luaapi.Libraries {
+		if f, ok := luaLibraries[lib.Name]; ok {
+			lua.Require(l, lib.Name, f, true)
+			l.Pop(1)
+		}
+	}
+
+	for _, name := range luaapi.RemovedGlobals {
+		l.PushNil()
+		l.SetGlobal(name)
+	}
+
+	l.Register("GetText", scriptGetText)
+
+	return l
+}
+
+// callScript calls the function on the lua stack in protected mode, aborting
+// it with an error if it executes more than the game script budget number of
+// instructions.
+func (g *Game) callScript(args, results int) error {
+	if g.budget > 0 {
+		lua.SetDebugHook(g.lua, func(l *lua.State, _ lua.Debug) {
+			lua.Errorf(l, "script instruction budget of %d exceeded",
+				g.budget)
+		}, lua.MaskCount, g.budget)
+
+		defer lua.SetDebugHook(g.lua, nil, 0, 0)
+	}
+
+	base := g.lua.Top() - args
+
+	g.lua.PushGoFunction(scriptTraceback)
+	g.lua.Insert(base)
+
+	if err := g.lua.ProtectedCall(args, results, base); err != nil {
+		g.trace, _ = g.lua.ToString(-1)
+
+		g.lua.SetTop(0)
+
+		return errors.Wrap(err, errors.ErrClient,
+			"unable to run script",
+			"budget", g.budget)
+	}
+
+	g.lua.Remove(base)
+
+	return nil
+}
+
+// scriptTraceback is the lua message handler used to add a stack traceback to
+// script errors, so that it can be included in error reports.
+func scriptTraceback(l *lua.State) int {
+	msg, _ := l.ToString(1)
+
+	lua.Traceback(l, l, msg, 1)
+
+	return 1
+}
+
+// scriptFailed records a script error, so that it is shown in the debug
+// overlay, reported in the game status and to the API, and pauses the game.
+func (g *Game) scriptFailed(err error) {
+	g.reportError(err, g.trace, false)
+
+	g.err = err
+	g.pause = true
+	g.synced = false
+	g.status = gameStatusError
+	g.statusData = map[string]any{
+		"error": err.Error(),
+	}
+}
+
+// compileScript loads and runs the game script, storing its Update function
+// in the lua registry so that it can be called each frame without being
+// compiled again.
+func (g *Game) compileScript() error {
+	g.compiled = false
+
+	buf := bytes.NewBufferString(g.src)
+
+	if err := g.lua.Load(buf, "Update", "text"); err != nil {
+		g.lua.SetTop(0)
+
+		return errors.Wrap(err, errors.ErrClient,
+			"unable to load script",
+			"script", g.src)
+	}
+
+	if err := g.callScript(0, 0); err != nil {
+		return err
+	}
+
+	g.lua.Global("Update")
+
+	if !g.lua.IsFunction(-1) {
+		g.lua.SetTop(0)
+
+		return errors.New(errors.ErrClient,
+			"no Update function in script",
+			"script", g.src)
+	}
+
+	g.lua.SetField(lua.RegistryIndex, scriptUpdateKey)
+
+	g.compiled = true
+
+	return nil
+}
+
+// pushState pushes the game state table to the lua stack. The table is kept in
+// the lua registry between frames and is only rebuilt when the game state has
+// been changed outside of the script. Otherwise, only the values which may
+// change every frame are set in the existing table.
+func (g *Game) pushState(keys, actions map[string]any) {
+	l := g.lua
+
+	if g.synced {
+		l.Field(lua.RegistryIndex, scriptStateKey)
+
+		if !l.IsTable(-1) {
+			l.Pop(1)
+
+			g.synced = false
+		}
+	}
+
+	if !g.synced {
+		objects := make(map[string]any, len(g.obj))
+
+		for k, obj := range g.obj {
+			objects[k] = obj.Map()
+		}
+
+		pushMap(l, map[string]any{
+			"subject": g.sub.Map(),
+			"objects": objects,
+		})
+
+		g.synced = true
+	}
+
+	var selected any
+
+	if g.menu.selected != "" {
+		selected = g.menu.selected
+	}
+
+	for k, v := range map[string]any{
+		"id":            g.id,
+		"name":          g.name,
+		"debug":         g.debug,
+		"score":         g.score,
+		"w":             g.w,
+		"h":             g.h,
+		"keys":          keys,
+		"actions":       actions,
+		"menu_selected": selected,
+		"language":      g.Language(),
+	} {
+		l.PushString(k)
+		pushValue(l, v)
+		l.RawSet(-3)
+	}
+}
+
+// runScript calls the compiled game script Update function with the game
+// state and updates the game from the state it returns. The script is
+// compiled first, if it has changed since it was last compiled.
+func (g *Game) runScript(keys, actions map[string]any) error {
+	if !g.loc.synced {
+		g.pushText()
+	}
+
+	if !g.compiled {
+		if err := g.compileScript(); err != nil {
+			return err
+		}
+	}
+
+	g.lua.Field(lua.RegistryIndex, scriptUpdateKey)
+
+	g.pushState(keys, actions)
+
+	if err := g.callScript(1, 1); err != nil {
+		return err
+	}
+
+	if !g.lua.IsTable(-1) {
+		g.lua.SetTop(0)
+
+		return errors.New(errors.ErrClient,
+			"game table not found")
+	}
+
+	g.lua.PushValue(-1)
+	g.lua.SetField(lua.RegistryIndex, scriptStateKey)
+
+	err := g.updateFromLua(g.lua, -1)
+
+	g.lua.Pop(1)
+
+	if err != nil {
+		return errors.Wrap(err, errors.ErrClient,
+			"unable to update game state from lua")
+	}
+
+	return nil
+}
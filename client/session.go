@@ -0,0 +1,155 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/dhaifley/game2d/errors"
+	"github.com/dhaifley/game2d/logger"
+	"github.com/google/uuid"
+)
+
+// Session defaults.
+const (
+	DefaultHeartbeatInterval = 30 * time.Second
+)
+
+// heartbeat values track the play session of the game, which is reported to
+// the API periodically while the game is being played, so that the API can
+// count the live players of each game.
+type heartbeat struct {
+	sync.Mutex
+	interval time.Duration
+	active   bool
+	id       string
+	session  string
+}
+
+// SetHeartbeatInterval sets the interval at which play session heartbeats are
+// sent to the API.
+func (g *Game) SetHeartbeatInterval(interval time.Duration) {
+	g.hb.Lock()
+	defer g.hb.Unlock()
+
+	g.hb.interval = interval
+}
+
+// Session returns the ID of the current play session, or an empty string if
+// the game is not being played from the API.
+func (g *Game) Session() string {
+	g.hb.Lock()
+	defer g.hb.Unlock()
+
+	if !g.hb.active {
+		return ""
+	}
+
+	return g.hb.session
+}
+
+// updateHeartbeat records the play session state of the game each frame. A new
+// session is started whenever a different game is played.
+func (g *Game) updateHeartbeat() {
+	active := g.apiGame() && !g.galleryOpen() && g.Spectating() == ""
+
+	g.hb.Lock()
+	defer g.hb.Unlock()
+
+	g.hb.active = active
+
+	if g.hb.id != g.id || g.hb.session == "" {
+		g.hb.id = g.id
+		g.hb.session = uuid.NewString()
+	}
+}
+
+// sendHeartbeat sends a play session heartbeat to the API. If ended is true,
+// the API is notified that the session has ended.
+func (g *Game) sendHeartbeat(id, session string, ended bool) error {
+	b, err := json.Marshal(map[string]any{
+		"session_id": session,
+		"ended":      ended,
+	})
+	if err != nil {
+		return errors.Wrap(err, errors.ErrClient,
+			"unable to encode heartbeat")
+	}
+
+	if _, err := g.apiRequest(http.MethodPost, bytes.NewReader(b), nil,
+		[]int{http.StatusOK}, "games", id, "heartbeat"); err != nil {
+		return errors.Wrap(err, errors.ErrClient,
+			"unable to send heartbeat",
+			"game_id", id)
+	}
+
+	return nil
+}
+
+// Heartbeat sends play session heartbeats to the API at the heartbeat
+// interval, while the game is being played, until the context is done. When a
+// session ends, the API is notified, so that it is no longer counted.
+func (g *Game) Heartbeat(ctx context.Context) {
+	g.hb.Lock()
+	interval := g.hb.interval
+	g.hb.Unlock()
+
+	if interval <= 0 {
+		interval = DefaultHeartbeatInterval
+	}
+
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	var last struct{ id, session string }
+
+	for {
+		done := false
+
+		select {
+		case <-ctx.Done():
+			done = true
+		case <-t.C:
+		}
+
+		g.hb.Lock()
+		active, id, session := g.hb.active, g.hb.id, g.hb.session
+		g.hb.Unlock()
+
+		if done || !active || session != last.session {
+			if last.session != "" {
+				if err := g.sendHeartbeat(last.id, last.session,
+					true); err != nil {
+					g.log.Log(ctx, logger.LvlDebug,
+						"unable to end play session",
+						"error", err,
+						"session_id", last.session)
+				}
+
+				last.id, last.session = "", ""
+			}
+		}
+
+		if done {
+			return
+		}
+
+		if !active {
+			continue
+		}
+
+		if err := g.sendHeartbeat(id, session, false); err != nil {
+			g.log.Log(ctx, logger.LvlDebug,
+				"unable to send play session heartbeat",
+				"error", err,
+				"session_id", session)
+
+			continue
+		}
+
+		last.id, last.session = id, session
+	}
+}
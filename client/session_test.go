@@ -0,0 +1,98 @@
+package client_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dhaifley/game2d/client"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHeartbeat(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		beats []map[string]any
+	)
+
+	game := client.NewGame(nil, client.DefaultGameWidth,
+		client.DefaultGameHeight, TestID, TestName, TestDesc)
+
+	ts := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPost ||
+				r.URL.Path != "/games/"+game.ID()+"/heartbeat" {
+				w.WriteHeader(http.StatusNotFound)
+
+				return
+			}
+
+			var hb map[string]any
+
+			if err := json.NewDecoder(r.Body).Decode(&hb); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+
+				return
+			}
+
+			mu.Lock()
+			beats = append(beats, hb)
+			mu.Unlock()
+
+			w.Write([]byte(`{"game_id":"` + game.ID() + `","players":1}`))
+		}))
+
+	t.Cleanup(ts.Close)
+
+	game.SetScript(TestScript)
+	game.SetAPIURL(ts.URL)
+	game.SetHeartbeatInterval(10 * time.Millisecond)
+
+	assert.Empty(t, game.Session(), "Session should not start before play")
+
+	err := game.Update()
+	assert.NoError(t, err, "Update should not return an error")
+
+	session := game.Session()
+	assert.NotEmpty(t, session, "Session should start when played")
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan struct{})
+
+	go func() {
+		game.Heartbeat(ctx)
+		close(done)
+	}()
+
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return len(beats) > 0
+	}, 5*time.Second, 10*time.Millisecond,
+		"Heartbeats should be sent while playing")
+
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for heartbeats to stop")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	assert.Equal(t, session, beats[0]["session_id"])
+	assert.Equal(t, false, beats[0]["ended"])
+
+	last := beats[len(beats)-1]
+
+	assert.Equal(t, session, last["session_id"])
+	assert.Equal(t, true, last["ended"], "Session should end when done")
+}
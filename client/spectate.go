@@ -0,0 +1,470 @@
+package client
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/dhaifley/game2d/client/api"
+	"github.com/dhaifley/game2d/errors"
+	"github.com/dhaifley/game2d/logger"
+	"github.com/google/uuid"
+	"golang.org/x/net/websocket"
+)
+
+// Spectate defaults.
+const (
+	DefaultSnapshotInterval = 200 * time.Millisecond
+	maxSnapshotSize         = 1 << 20
+	snapshotKeyframes       = 25
+	spectateIdle            = 10 * time.Second
+	spectateRetryWait       = 5 * time.Second
+)
+
+// SpectateStream values describe the live streams of the play sessions of a
+// game, which may be watched by spectators.
+type SpectateStream struct {
+	UserID     string `json:"user_id"`
+	SessionID  string `json:"session_id"`
+	Spectators int    `json:"spectators"`
+	StartedAt  int64  `json:"started_at"`
+	UpdatedAt  int64  `json:"updated_at"`
+}
+
+// snapshot values contain the state of a game which changes while it is being
+// played. Images are only included in keyframe snapshots, since they rarely
+// change.
+type snapshot struct {
+	W       int                `json:"w"`
+	H       int                `json:"h"`
+	Pause   bool               `json:"pause,omitempty"`
+	Status  string             `json:"status,omitempty"`
+	StData  map[string]any     `json:"status_data,omitempty"`
+	Score   int                `json:"score,omitempty"`
+	Subject *Object            `json:"subject,omitempty"`
+	Objects map[string]*Object `json:"objects,omitempty"`
+	Images  map[string]*Image  `json:"images,omitempty"`
+}
+
+// published values are compressed snapshots waiting to be published for a
+// play session.
+type published struct {
+	cli     *api.Client
+	id      string
+	session string
+	data    []byte
+}
+
+// spectate values track the live streaming of the game state. A game being
+// played from the API may publish snapshots of its state, and a game may
+// instead spectate the play session of another player, rendering the
+// snapshots it receives without running the game script.
+type spectate struct {
+	sync.Mutex
+	publish  bool
+	interval time.Duration
+	last     time.Time
+	frames   int
+	out      chan *published
+	cli      *api.Client
+	id       string
+	session  string
+	in       []byte
+}
+
+// SetPublish sets whether snapshots of the game state are published while the
+// game is being played from the API, so that other players may spectate.
+func (g *Game) SetPublish(publish bool) {
+	g.spec.Lock()
+	defer g.spec.Unlock()
+
+	g.spec.publish = publish
+}
+
+// SetSnapshotInterval sets the interval at which snapshots of the game state
+// are published.
+func (g *Game) SetSnapshotInterval(interval time.Duration) {
+	g.spec.Lock()
+	defer g.spec.Unlock()
+
+	g.spec.interval = interval
+}
+
+// SetSpectate sets the ID of a play session of the game to spectate. While
+// spectating, the game renders the snapshots published for the session, and
+// does not run the game script. If the session is empty, spectating stops.
+func (g *Game) SetSpectate(session string) error {
+	if session != "" {
+		if _, err := uuid.Parse(session); err != nil {
+			return errors.New(errors.ErrClient,
+				"invalid session id",
+				"session_id", session)
+		}
+	}
+
+	g.spec.Lock()
+	defer g.spec.Unlock()
+
+	g.spec.session = session
+	g.spec.in = nil
+
+	return nil
+}
+
+// Spectating returns the ID of the play session being spectated, or an empty
+// string if the game is not spectating.
+func (g *Game) Spectating() string {
+	g.spec.Lock()
+	defer g.spec.Unlock()
+
+	return g.spec.session
+}
+
+// SpectateStreams lists the live streams of the game which may be spectated.
+func (g *Game) SpectateStreams() ([]*SpectateStream, error) {
+	b, err := g.apiRequest(http.MethodGet, nil, nil,
+		[]int{http.StatusOK}, "games", g.id, "spectate")
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrClient,
+			"unable to list spectate streams")
+	}
+
+	res := []*SpectateStream{}
+
+	if err := json.Unmarshal(b, &res); err != nil {
+		return nil, errors.Wrap(err, errors.ErrClient,
+			"unable to decode spectate streams")
+	}
+
+	return res, nil
+}
+
+// snapshot returns a compressed snapshot of the game state.
+func (g *Game) snapshot(keyframe bool) ([]byte, error) {
+	v := &snapshot{
+		W:       g.w,
+		H:       g.h,
+		Pause:   g.pause,
+		Status:  g.status,
+		StData:  g.statusData,
+		Score:   g.score,
+		Subject: g.sub,
+		Objects: g.obj,
+	}
+
+	if keyframe {
+		v.Images = g.img
+	}
+
+	var buf bytes.Buffer
+
+	zw := gzip.NewWriter(&buf)
+
+	if err := json.NewEncoder(zw).Encode(v); err != nil {
+		return nil, errors.Wrap(err, errors.ErrClient,
+			"unable to encode game snapshot")
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, errors.Wrap(err, errors.ErrClient,
+			"unable to compress game snapshot")
+	}
+
+	return buf.Bytes(), nil
+}
+
+// applySnapshot replaces the game state with a compressed snapshot. The game
+// images are kept, unless the snapshot contains images.
+func (g *Game) applySnapshot(b []byte) error {
+	zr, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		return errors.Wrap(err, errors.ErrClient,
+			"unable to decompress game snapshot")
+	}
+
+	defer zr.Close()
+
+	var v snapshot
+
+	if err := json.NewDecoder(io.LimitReader(zr,
+		maxSnapshotSize*16)).Decode(&v); err != nil {
+		return errors.Wrap(err, errors.ErrClient,
+			"unable to decode game snapshot")
+	}
+
+	if v.W <= 0 || v.H <= 0 || v.Subject == nil {
+		return errors.New(errors.ErrClient,
+			"invalid game snapshot")
+	}
+
+	g.w = v.W
+	g.h = v.H
+	g.pause = v.Pause
+	g.status = v.Status
+	g.statusData = v.StData
+	g.score = v.Score
+
+	if v.Images != nil {
+		g.img = v.Images
+	}
+
+	g.sub = v.Subject
+	g.sub.game = g
+
+	g.obj = v.Objects
+	if g.obj == nil {
+		g.obj = map[string]*Object{}
+	}
+
+	for _, o := range g.obj {
+		if o != nil {
+			o.game = g
+		}
+	}
+
+	return nil
+}
+
+// updateSpectate applies the most recent snapshot received for the spectated
+// play session each frame.
+func (g *Game) updateSpectate() {
+	g.spec.Lock()
+	b := g.spec.in
+	g.spec.in = nil
+
+	if g.spec.cli == nil || g.spec.id != g.id {
+		g.spec.cli = g.apiClient()
+		g.spec.id = g.id
+	}
+
+	g.spec.Unlock()
+
+	if b == nil {
+		return
+	}
+
+	if err := g.applySnapshot(b); err != nil {
+		g.log.Log(context.Background(), logger.LvlError,
+			"unable to apply game snapshot",
+			"error", err)
+
+		g.err = err
+	}
+}
+
+// updatePublish queues a snapshot of the game state to be published, at the
+// snapshot interval, while the game is being played from the API.
+func (g *Game) updatePublish() {
+	session := g.Session()
+
+	g.spec.Lock()
+	defer g.spec.Unlock()
+
+	if !g.spec.publish || session == "" {
+		return
+	}
+
+	interval := g.spec.interval
+	if interval <= 0 {
+		interval = DefaultSnapshotInterval
+	}
+
+	if time.Since(g.spec.last) < interval {
+		return
+	}
+
+	g.spec.last = time.Now()
+
+	b, err := g.snapshot(g.spec.frames%snapshotKeyframes == 0)
+	if err != nil {
+		g.log.Log(context.Background(), logger.LvlError,
+			"unable to create game snapshot",
+			"error", err)
+
+		return
+	}
+
+	g.spec.frames++
+
+	if g.spec.out == nil {
+		g.spec.out = make(chan *published, 1)
+	}
+
+	select {
+	case <-g.spec.out:
+	default:
+	}
+
+	g.spec.out <- &published{
+		cli:     g.apiClient(),
+		id:      g.id,
+		session: session,
+		data:    b,
+	}
+}
+
+// publishChannel returns the channel of snapshots waiting to be published.
+func (g *Game) publishChannel() chan *published {
+	g.spec.Lock()
+	defer g.spec.Unlock()
+
+	if g.spec.out == nil {
+		g.spec.out = make(chan *published, 1)
+	}
+
+	return g.spec.out
+}
+
+// Publish sends the snapshots of the game state to the API as they are
+// created, until the context is done. The connection to the API is closed
+// whenever the play session changes, or no snapshots are created for a while.
+func (g *Game) Publish(ctx context.Context) {
+	out := g.publishChannel()
+
+	var (
+		ws      *websocket.Conn
+		session string
+		failed  time.Time
+	)
+
+	disconnect := func() {
+		if ws != nil {
+			_ = ws.Close()
+		}
+
+		ws, session = nil, ""
+	}
+
+	defer disconnect()
+
+	for {
+		var p *published
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(spectateIdle):
+			disconnect()
+
+			continue
+		case p = <-out:
+		}
+
+		if ws != nil && session != p.session {
+			disconnect()
+		}
+
+		if ws == nil {
+			if time.Since(failed) < spectateRetryWait {
+				continue
+			}
+
+			c, err := p.cli.Dial(ctx, "games", p.id, "spectate",
+				p.session, "publish")
+			if err != nil {
+				g.log.Log(ctx, logger.LvlDebug,
+					"unable to publish game snapshots",
+					"error", err,
+					"session_id", p.session)
+
+				failed = time.Now()
+
+				continue
+			}
+
+			c.PayloadType = websocket.BinaryFrame
+
+			ws, session = c, p.session
+		}
+
+		if err := websocket.Message.Send(ws, p.data); err != nil {
+			g.log.Log(ctx, logger.LvlDebug,
+				"unable to send game snapshot",
+				"error", err,
+				"session_id", p.session)
+
+			disconnect()
+
+			failed = time.Now()
+		}
+	}
+}
+
+// Spectate receives the snapshots published for the spectated play session
+// from the API, until the context is done. The connection is retried if it
+// fails, or the stream has not started yet.
+func (g *Game) Spectate(ctx context.Context) {
+	for {
+		g.spec.Lock()
+		cli, id, session := g.spec.cli, g.spec.id, g.spec.session
+		g.spec.Unlock()
+
+		if cli != nil && session != "" {
+			if err := g.spectate(ctx, cli, id, session); err != nil {
+				g.log.Log(ctx, logger.LvlDebug,
+					"unable to spectate play session",
+					"error", err,
+					"session_id", session)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(spectateRetryWait):
+		}
+	}
+}
+
+// spectate receives the snapshots published for a play session, until the
+// connection is closed, or the spectated session changes.
+func (g *Game) spectate(ctx context.Context,
+	cli *api.Client,
+	id, session string,
+) error {
+	ws, err := cli.Dial(ctx, "games", id, "spectate", session)
+	if err != nil {
+		return err
+	}
+
+	stop := context.AfterFunc(ctx, func() { _ = ws.Close() })
+
+	defer func() {
+		stop()
+
+		_ = ws.Close()
+	}()
+
+	ws.MaxPayloadBytes = maxSnapshotSize
+
+	for {
+		var b []byte
+
+		if err := websocket.Message.Receive(ws, &b); err != nil {
+			if errors.Is(err, io.EOF) || ctx.Err() != nil {
+				return nil
+			}
+
+			return errors.Wrap(err, errors.ErrClient,
+				"unable to receive game snapshot",
+				"session_id", session)
+		}
+
+		g.spec.Lock()
+
+		if g.spec.session != session {
+			g.spec.Unlock()
+
+			return nil
+		}
+
+		g.spec.in = b
+
+		g.spec.Unlock()
+	}
+}
@@ -0,0 +1,125 @@
+package client_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/websocket"
+)
+
+func TestSpectate(t *testing.T) {
+	var (
+		mu   sync.Mutex
+		last []byte
+		subs []chan []byte
+	)
+
+	ts := httptest.NewServer(websocket.Server{
+		Handler: func(ws *websocket.Conn) {
+			if strings.HasSuffix(ws.Request().URL.Path, "/publish") {
+				for {
+					var b []byte
+
+					if err := websocket.Message.Receive(ws, &b); err != nil {
+						return
+					}
+
+					mu.Lock()
+					last = b
+
+					for _, ch := range subs {
+						select {
+						case ch <- b:
+						default:
+						}
+					}
+					mu.Unlock()
+				}
+			}
+
+			ch := make(chan []byte, 16)
+
+			mu.Lock()
+			if last != nil {
+				ch <- last
+			}
+
+			subs = append(subs, ch)
+			mu.Unlock()
+
+			for b := range ch {
+				if err := websocket.Message.Send(ws, b); err != nil {
+					return
+				}
+			}
+		},
+	})
+
+	t.Cleanup(ts.Close)
+
+	player := newRunningGame(t, `function Update(game)
+game.score = game.score + 1
+return game
+end`, 2)
+
+	player.SetAPIURL(ts.URL)
+	player.SetPublish(true)
+	player.SetSnapshotInterval(time.Millisecond)
+
+	err := player.Update()
+	assert.NoError(t, err)
+
+	session := player.Session()
+	assert.NotEmpty(t, session, "Session should start when played")
+
+	spectator := newRunningGame(t, `function Update(game)
+game.score = -100
+return game
+end`, 0)
+
+	spectator.SetAPIURL(ts.URL)
+
+	err = spectator.SetSpectate("invalid")
+	assert.Error(t, err, "Invalid session IDs should not be spectated")
+
+	err = spectator.SetSpectate(session)
+	assert.NoError(t, err)
+	assert.Equal(t, session, spectator.Spectating())
+
+	err = spectator.Update()
+	assert.NoError(t, err)
+	assert.Empty(t, spectator.Session(),
+		"Spectators should not start play sessions")
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	t.Cleanup(cancel)
+
+	go player.Publish(ctx)
+
+	go spectator.Spectate(ctx)
+
+	assert.Eventually(t, func() bool {
+		if err := player.Update(); err != nil {
+			return false
+		}
+
+		if err := spectator.Update(); err != nil {
+			return false
+		}
+
+		return spectator.Score() > 1
+	}, 5*time.Second, 10*time.Millisecond,
+		"Spectators should render the published game state")
+
+	assert.LessOrEqual(t, spectator.Score(), player.Score())
+
+	err = spectator.SetSpectate("")
+	assert.NoError(t, err)
+	assert.Empty(t, spectator.Spectating())
+}
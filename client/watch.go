@@ -0,0 +1,207 @@
+package client
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/dhaifley/game2d/errors"
+	"github.com/dhaifley/game2d/logger"
+)
+
+// watcher values track the game definition, so that changes to it can be
+// detected and applied to the running game.
+type watcher struct {
+	sync.Mutex
+	interval time.Duration
+	src      *Game
+	sum      [sha256.Size]byte
+	pending  []byte
+	defs     map[string]string
+	subDef   string
+}
+
+// SetWatch sets the interval at which the game definition is checked for
+// changes while the game is running. Changes are applied to the running game
+// without restarting it. An interval less than or equal to zero disables
+// watching for changes.
+func (g *Game) SetWatch(interval time.Duration) {
+	g.wat.Lock()
+	defer g.wat.Unlock()
+
+	g.wat.interval = interval
+}
+
+// definitionHash returns a hash of the definition of an object.
+func definitionHash(obj *Object) string {
+	if obj == nil {
+		return ""
+	}
+
+	b, err := json.Marshal(obj)
+	if err != nil {
+		return ""
+	}
+
+	sum := sha256.Sum256(b)
+
+	return hex.EncodeToString(sum[:])
+}
+
+// loaded records the game definition which has been loaded, so that the
+// watcher can detect changes to it.
+func (g *Game) loaded(b []byte) {
+	g.wat.Lock()
+	defer g.wat.Unlock()
+
+	g.wat.src = &Game{
+		log:      g.log,
+		id:       g.id,
+		apiURL:   g.apiURL,
+		apiToken: g.apiToken,
+		file:     g.file,
+		data:     g.data,
+		dc:       g.dc,
+	}
+
+	g.wat.sum = sha256.Sum256(b)
+	g.wat.pending = nil
+	g.wat.subDef = definitionHash(g.sub)
+	g.wat.defs = make(map[string]string, len(g.obj))
+
+	for id, obj := range g.obj {
+		g.wat.defs[id] = definitionHash(obj)
+	}
+}
+
+// Watch checks the game definition for changes at the watch interval, until
+// the context is done. Changed definitions are applied by the next Update.
+func (g *Game) Watch(ctx context.Context) {
+	g.wat.Lock()
+	interval := g.wat.interval
+	g.wat.Unlock()
+
+	if interval <= 0 {
+		return
+	}
+
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+
+		g.wat.Lock()
+		src := g.wat.src
+		g.wat.Unlock()
+
+		if src == nil {
+			continue
+		}
+
+		b, err := src.fetch()
+		if err != nil {
+			g.log.Log(ctx, logger.LvlDebug,
+				"unable to check game definition for changes",
+				"error", err)
+
+			continue
+		}
+
+		sum := sha256.Sum256(b)
+
+		g.wat.Lock()
+
+		if g.wat.src == src && g.wat.sum != sum {
+			g.wat.sum = sum
+			g.wat.pending = b
+		}
+
+		g.wat.Unlock()
+	}
+}
+
+// reload applies a changed game definition, detected by the watcher, to the
+// running game. The images and script are replaced, while objects whose
+// definitions have not changed keep their current state.
+func (g *Game) reload() error {
+	g.wat.Lock()
+	b := g.wat.pending
+	g.wat.pending = nil
+	g.wat.Unlock()
+
+	if b == nil {
+		return nil
+	}
+
+	var g2 Game
+
+	if err := json.Unmarshal(b, &g2); err != nil {
+		return errors.Wrap(err, errors.ErrClient,
+			"unable to decode changed game definition")
+	}
+
+	if g2.sub == nil {
+		return errors.New(errors.ErrClient,
+			"game subject object not found",
+			"game", &g2)
+	}
+
+	g.wat.Lock()
+	defer g.wat.Unlock()
+
+	g.name = g2.name
+	g.ver = g2.ver
+	g.desc = g2.desc
+	g.icon = g2.icon
+	g.img = g2.img
+	g.bindings = g2.bindings
+
+	g.SetLocales(g2.locales)
+
+	if g2.src != g.src {
+		g.SetScript(g2.src)
+	}
+
+	subDef := definitionHash(g2.sub)
+
+	if g.sub == nil || subDef != g.wat.subDef {
+		g.sub = g2.sub
+		g.sub.game = g
+	}
+
+	defs := make(map[string]string, len(g2.obj))
+	objects := make(map[string]*Object, len(g2.obj))
+
+	for id, obj := range g2.obj {
+		if obj == nil {
+			continue
+		}
+
+		defs[id] = definitionHash(obj)
+
+		if cur := g.obj[id]; cur != nil && defs[id] == g.wat.defs[id] {
+			objects[id] = cur
+
+			continue
+		}
+
+		obj.game = g
+
+		objects[id] = obj
+	}
+
+	g.obj = objects
+	g.synced = false
+	g.wat.subDef = subDef
+	g.wat.defs = defs
+
+	return nil
+}
@@ -0,0 +1,65 @@
+package client_test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dhaifley/game2d/client"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWatch(t *testing.T) {
+	game := client.NewGame(nil, client.DefaultGameWidth,
+		client.DefaultGameHeight, TestID, TestName, TestDesc)
+
+	game.SetScript(TestScript)
+	game.AddSubject(client.NewSubject(game, TestID, TestName, TestID, nil))
+	game.AddObject(client.NewObject(game, TestID, TestName, TestID, nil))
+
+	file := filepath.Join(t.TempDir(), "game.json")
+
+	game.SetFile(file)
+
+	err := game.Save()
+	assert.NoError(t, err)
+
+	err = game.Load()
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	t.Cleanup(cancel)
+
+	game.SetWatch(10 * time.Millisecond)
+
+	go game.Watch(ctx)
+
+	b, err := os.ReadFile(file)
+	assert.NoError(t, err)
+
+	var def map[string]any
+
+	err = json.Unmarshal(b, &def)
+	assert.NoError(t, err)
+
+	def["name"] = "changed"
+
+	b, err = json.Marshal(def)
+	assert.NoError(t, err)
+
+	err = os.WriteFile(file, b, 0o644)
+	assert.NoError(t, err)
+
+	assert.Eventually(t, func() bool {
+		if err := game.Update(); err != nil {
+			return false
+		}
+
+		return game.Name() == "changed"
+	}, 5*time.Second, 10*time.Millisecond,
+		"Changed game definition should be applied")
+}
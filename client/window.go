@@ -0,0 +1,203 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"github.com/dhaifley/game2d/errors"
+	"github.com/dhaifley/game2d/logger"
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// Window defaults.
+const (
+	DefaultScale    = 1.0
+	MaxScale        = 8.0
+	preferencesFile = "preferences.json"
+)
+
+// Preferences values represent the display and language preferences of the
+// client, which are persisted locally so that games look consistent across
+// displays.
+type Preferences struct {
+	Fullscreen   bool    `json:"fullscreen"`
+	PixelPerfect bool    `json:"pixel_perfect"`
+	Scale        float64 `json:"scale"`
+	Language     string  `json:"language,omitempty"`
+}
+
+// configPath returns the path of a file in the local client configuration
+// directory.
+func configPath(elem ...string) (string, error) {
+	if runtime.GOOS == "js" {
+		return "", errors.New(errors.ErrClient,
+			"local configuration is not supported on this platform")
+	}
+
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", errors.Wrap(err, errors.ErrClient,
+			"unable to find user configuration directory")
+	}
+
+	return filepath.Join(append([]string{dir, "game2d"}, elem...)...), nil
+}
+
+// LoadPreferences retrieves the locally persisted client preferences.
+func LoadPreferences() (*Preferences, error) {
+	p := &Preferences{Scale: DefaultScale}
+
+	file, err := configPath(preferencesFile)
+	if err != nil {
+		return p, err
+	}
+
+	b, err := os.ReadFile(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return p, nil
+		}
+
+		return p, errors.Wrap(err, errors.ErrClient,
+			"unable to read preferences",
+			"file", file)
+	}
+
+	if err := json.Unmarshal(b, p); err != nil {
+		return &Preferences{Scale: DefaultScale}, errors.Wrap(err,
+			errors.ErrClient,
+			"unable to decode preferences",
+			"file", file)
+	}
+
+	if p.Scale <= 0 || p.Scale > MaxScale {
+		p.Scale = DefaultScale
+	}
+
+	return p, nil
+}
+
+// SavePreferences persists the client preferences locally.
+func SavePreferences(p *Preferences) error {
+	file, err := configPath(preferencesFile)
+	if err != nil {
+		return err
+	}
+
+	b, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, errors.ErrClient,
+			"unable to encode preferences")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return errors.Wrap(err, errors.ErrClient,
+			"unable to create preferences directory",
+			"file", file)
+	}
+
+	if err := os.WriteFile(file, b, 0o644); err != nil {
+		return errors.Wrap(err, errors.ErrClient,
+			"unable to write preferences",
+			"file", file)
+	}
+
+	return nil
+}
+
+// SetPixelPerfect sets whether the game is rendered in pixel-perfect mode. In
+// pixel-perfect mode, the game size does not follow the window size, and the
+// game is drawn scaled by the largest whole number which fits the window.
+func (g *Game) SetPixelPerfect(pixelPerfect bool) {
+	g.pixel = pixelPerfect
+}
+
+// Preferences returns the current display and language preferences of the
+// game.
+func (g *Game) Preferences() *Preferences {
+	return &Preferences{
+		Fullscreen:   g.fullscreen,
+		PixelPerfect: g.pixel,
+		Scale:        g.windowScale(),
+		Language:     g.loc.lang,
+	}
+}
+
+// windowScale returns the scale of the game window relative to the game size.
+func (g *Game) windowScale() float64 {
+	if g.scale <= 0 {
+		return DefaultScale
+	}
+
+	return g.scale
+}
+
+// updateWindow applies display preference changes requested by the keyboard
+// shortcuts, and persists the changed preferences.
+func (g *Game) updateWindow(fullscreen, pixelPerfect bool, zoom int) {
+	if !fullscreen && !pixelPerfect && zoom == 0 {
+		return
+	}
+
+	if fullscreen {
+		g.fullscreen = !ebiten.IsFullscreen()
+
+		ebiten.SetFullscreen(g.fullscreen)
+	}
+
+	if pixelPerfect {
+		g.pixel = !g.pixel
+	}
+
+	if zoom != 0 {
+		g.scale = math.Min(math.Max(math.Floor(g.windowScale())+
+			float64(zoom), DefaultScale), MaxScale)
+
+		ebiten.SetWindowSize(int(float64(g.w)*g.scale),
+			int(float64(g.h)*g.scale))
+	}
+
+	if err := SavePreferences(g.Preferences()); err != nil {
+		g.log.Log(context.Background(), logger.LvlWarn,
+			"unable to save preferences",
+			"error", err)
+	}
+}
+
+// layoutPixelPerfect returns the screen size, in device pixels, used to
+// render the game in pixel-perfect mode.
+func (g *Game) layoutPixelPerfect(w, h int) (int, int) {
+	s := ebiten.Monitor().DeviceScaleFactor()
+
+	return max(int(float64(w)*s), 1), max(int(float64(h)*s), 1)
+}
+
+// drawPixelPerfect renders the game to an image of the game size, and draws
+// it on the screen, centered and scaled by the largest whole number which
+// fits the screen.
+func (g *Game) drawPixelPerfect(screen *ebiten.Image) {
+	w, h := max(g.w, 1), max(g.h, 1)
+
+	if g.canvas == nil || g.canvas.Bounds().Dx() != w ||
+		g.canvas.Bounds().Dy() != h {
+		g.canvas = ebiten.NewImage(w, h)
+	}
+
+	g.canvas.Clear()
+
+	g.drawGame(g.canvas)
+
+	sw, sh := screen.Bounds().Dx(), screen.Bounds().Dy()
+
+	s := max(min(sw/w, sh/h), 1)
+
+	op := &ebiten.DrawImageOptions{Filter: ebiten.FilterNearest}
+	op.GeoM.Scale(float64(s), float64(s))
+	op.GeoM.Translate(float64((sw-w*s)/2), float64((sh-h*s)/2))
+
+	screen.DrawImage(g.canvas, op)
+}
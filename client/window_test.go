@@ -0,0 +1,59 @@
+package client_test
+
+import (
+	"testing"
+
+	"github.com/dhaifley/game2d/client"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPreferences(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("AppData", t.TempDir())
+
+	p, err := client.LoadPreferences()
+	assert.NoError(t, err)
+	assert.Equal(t, &client.Preferences{Scale: client.DefaultScale}, p,
+		"Default preferences should be returned")
+
+	exp := &client.Preferences{Fullscreen: true, PixelPerfect: true, Scale: 2}
+
+	err = client.SavePreferences(exp)
+	assert.NoError(t, err)
+
+	p, err = client.LoadPreferences()
+	assert.NoError(t, err)
+	assert.Equal(t, exp, p, "Saved preferences should be returned")
+}
+
+func TestLayoutScale(t *testing.T) {
+	game := client.NewGame(nil, client.DefaultGameWidth,
+		client.DefaultGameHeight, TestID, TestName, TestDesc)
+
+	game.SetScale(2)
+
+	w, h := game.Layout(client.DefaultGameWidth*2, client.DefaultGameHeight*2)
+	assert.Equal(t, client.DefaultGameWidth, w, "Width should be %d",
+		client.DefaultGameWidth)
+	assert.Equal(t, client.DefaultGameHeight, h, "Height should be %d",
+		client.DefaultGameHeight)
+}
+
+func TestPixelPerfect(t *testing.T) {
+	game := client.NewGame(nil, client.DefaultGameWidth,
+		client.DefaultGameHeight, TestID, TestName, TestDesc)
+
+	game.SetPixelPerfect(true)
+
+	w, h := game.Layout(client.DefaultGameWidth*3, client.DefaultGameHeight*3)
+	assert.GreaterOrEqual(t, w, client.DefaultGameWidth*3)
+	assert.GreaterOrEqual(t, h, client.DefaultGameHeight*3)
+	assert.Equal(t, client.DefaultGameWidth, game.W(),
+		"Game width should not follow the window width")
+	assert.Equal(t, client.DefaultGameHeight, game.H(),
+		"Game height should not follow the window height")
+
+	game.Draw(ebiten.NewImage(w, h))
+}
@@ -0,0 +1,276 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/dhaifley/game2d/client/api"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultConcurrency is the default number of batch operations performed
+// concurrently.
+const DefaultConcurrency = 4
+
+// Operation values describe a single API request performed by the batch
+// command. The body may be given inline, or in a file, relative to the batch
+// file. Body files in YAML format are sent as JSON.
+type Operation struct {
+	Method   string            `json:"method"              yaml:"method"`
+	Resource string            `json:"resource"            yaml:"resource"`
+	Query    map[string]string `json:"query,omitempty"     yaml:"query,omitempty"`
+	Body     any               `json:"body,omitempty"      yaml:"body,omitempty"`
+	BodyFile string            `json:"body_file,omitempty" yaml:"body_file,omitempty"`
+}
+
+// Result values contain the result of a batch operation.
+type Result struct {
+	Operation int    `json:"operation"        yaml:"operation"`
+	Method    string `json:"method"           yaml:"method"`
+	Resource  string `json:"resource"         yaml:"resource"`
+	Status    int    `json:"status,omitempty" yaml:"status,omitempty"`
+	Error     string `json:"error,omitempty"  yaml:"error,omitempty"`
+	code      int
+}
+
+// readOperations reads the operations of a batch file, in YAML or JSON format,
+// or from standard input, if the file is "-".
+func readOperations(file string) ([]*Operation, error) {
+	var (
+		b   []byte
+		err error
+	)
+
+	if file == "-" {
+		b, err = io.ReadAll(os.Stdin)
+	} else {
+		b, err = os.ReadFile(file)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("unable to read batch file %s: %w", file, err)
+	}
+
+	var res []*Operation
+
+	if err := yaml.Unmarshal(b, &res); err != nil {
+		return nil, fmt.Errorf("unable to parse batch file %s: %w", file, err)
+	}
+
+	// Null or empty list entries do not describe an operation.
+	for i, o := range res {
+		if o == nil {
+			return nil, fmt.Errorf("invalid batch file %s: operation %d is empty",
+				file, i+1)
+		}
+	}
+
+	return res, nil
+}
+
+// request returns the API request performed by an operation.
+func (o *Operation) request(dir string, cfg *Config) (*api.Request, error) {
+	req := &api.Request{
+		Method: strings.ToUpper(strings.TrimSpace(o.Method)),
+		Path:   []string{o.Resource},
+		Header: cfg.header(),
+	}
+
+	switch req.Method {
+	case CmdCreate:
+		req.Method = http.MethodPost
+	case CmdUpdate:
+		req.Method = http.MethodPut
+	case CmdGet, CmdPost, CmdPut, CmdPatch, CmdDelete, CmdOptions, CmdHead:
+	default:
+		return nil, fmt.Errorf("invalid method: %s", o.Method)
+	}
+
+	if o.Resource == "" {
+		return nil, fmt.Errorf("missing resource")
+	}
+
+	if len(o.Query) > 0 {
+		req.Query = url.Values{}
+
+		for k, v := range o.Query {
+			req.Query.Set(k, v)
+		}
+	}
+
+	switch {
+	case o.BodyFile != "":
+		fn := o.BodyFile
+		if !filepath.IsAbs(fn) {
+			fn = filepath.Join(dir, fn)
+		}
+
+		b, err := os.ReadFile(fn)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read body file %s: %w", fn, err)
+		}
+
+		switch strings.ToLower(filepath.Ext(fn)) {
+		case ".yaml", ".yml":
+			var v any
+
+			if err := yaml.Unmarshal(b, &v); err != nil {
+				return nil, fmt.Errorf("unable to parse body file %s: %w",
+					fn, err)
+			}
+
+			if b, err = json.Marshal(v); err != nil {
+				return nil, fmt.Errorf("unable to format body file %s: %w",
+					fn, err)
+			}
+		}
+
+		req.Body = b
+	case o.Body != nil:
+		b, err := json.Marshal(o.Body)
+		if err != nil {
+			return nil, fmt.Errorf("unable to format body: %w", err)
+		}
+
+		req.Body = b
+	}
+
+	return req, nil
+}
+
+// responseError returns the message of an error response, or its status, if
+// the response does not contain one.
+func responseError(res *api.Response) string {
+	var e struct {
+		Message string `json:"message"`
+	}
+
+	if err := json.Unmarshal(res.Body, &e); err == nil && e.Message != "" {
+		return e.Message
+	}
+
+	return http.StatusText(res.StatusCode)
+}
+
+// perform performs a batch operation, returning its result.
+func (o *Operation) perform(ctx context.Context,
+	cli *api.Client,
+	cfg *Config,
+	dir string,
+	n int,
+) *Result {
+	res := &Result{Operation: n, Method: o.Method, Resource: o.Resource}
+
+	req, err := o.request(dir, cfg)
+	if err != nil {
+		res.Error, res.code = err.Error(), 1
+
+		return res
+	}
+
+	r, err := cli.Do(ctx, req)
+	if r == nil {
+		res.Error, res.code = err.Error(), 1
+
+		return res
+	}
+
+	res.Status = r.StatusCode
+
+	switch {
+	case r.StatusCode >= http.StatusInternalServerError:
+		res.Error, res.code = responseError(r), 3
+	case r.StatusCode >= http.StatusBadRequest:
+		res.Error, res.code = responseError(r), 2
+	}
+
+	return res
+}
+
+// Batch performs the operations listed in a batch file, with the configured
+// concurrency, and displays the result of each operation, in order, and a
+// summary. It returns the highest exit code of the operations.
+func Batch(ctx context.Context, args *Args, cfg *Config) int {
+	if args.File == "" {
+		fmt.Println("ERROR: missing batch file")
+
+		return 1
+	}
+
+	ops, err := readOperations(args.File)
+	if err != nil {
+		fmt.Println("ERROR: ", err.Error())
+
+		return 1
+	}
+
+	n := args.Concurrency
+	if n <= 0 {
+		n = DefaultConcurrency
+	}
+
+	cli, dir := cfg.client(), filepath.Dir(args.File)
+
+	results := make([]*Result, len(ops))
+
+	sem := make(chan struct{}, n)
+
+	var wg sync.WaitGroup
+
+	for i, o := range ops {
+		wg.Add(1)
+
+		sem <- struct{}{}
+
+		go func() {
+			defer func() {
+				<-sem
+
+				wg.Done()
+			}()
+
+			results[i] = o.perform(ctx, cli, cfg, dir, i+1)
+		}()
+	}
+
+	wg.Wait()
+
+	var b []byte
+
+	if cfg.Format == FmtYAML {
+		b, err = yaml.Marshal(results)
+	} else {
+		b, err = json.Marshal(results)
+	}
+
+	if err != nil {
+		fmt.Println("ERROR: unable to format results: ", err.Error())
+
+		return 1
+	}
+
+	fmt.Println(strings.TrimSpace(string(b)))
+
+	ec, failed := 0, 0
+
+	for _, r := range results {
+		if r.code > 0 {
+			failed++
+		}
+
+		ec = max(ec, r.code)
+	}
+
+	fmt.Fprintf(os.Stderr, "%d operations: %d succeeded, %d failed\n",
+		len(results), len(results)-failed, failed)
+
+	return ec
+}
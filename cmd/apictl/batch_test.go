@@ -0,0 +1,294 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeFile(t *testing.T, dir, name, data string) string {
+	t.Helper()
+
+	fn := filepath.Join(dir, name)
+
+	if err := os.WriteFile(fn, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	return fn
+}
+
+func TestReadOperations(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	tests := []struct {
+		name string
+		data string
+		exp  int
+		err  bool
+	}{{
+		name: "yaml",
+		data: "- method: get\n  resource: games\n" +
+			"- method: delete\n  resource: games/1\n",
+		exp: 2,
+	}, {
+		name: "json",
+		data: `[{"method":"get","resource":"games"}]`,
+		exp:  1,
+	}, {
+		name: "empty",
+		data: "",
+		exp:  0,
+	}, {
+		name: "null entry",
+		data: "- method: get\n  resource: games\n- null\n",
+		err:  true,
+	}, {
+		name: "empty entry",
+		data: "- method: get\n  resource: games\n-\n",
+		err:  true,
+	}, {
+		name: "invalid",
+		data: "method: get\n",
+		err:  true,
+	}}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			fn := writeFile(t, dir, "batch"+string(rune('a'+i))+".yaml",
+				tt.data)
+
+			ops, err := readOperations(fn)
+			if tt.err {
+				if err == nil {
+					t.Fatalf("Expected error, got: %v", ops)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(ops) != tt.exp {
+				t.Errorf("Expected operations: %v, got: %v", tt.exp, len(ops))
+			}
+
+			for _, o := range ops {
+				if o == nil {
+					t.Error("Expected operation, got: nil")
+				}
+			}
+		})
+	}
+
+	if _, err := readOperations(filepath.Join(dir, "missing.yaml")); err == nil {
+		t.Error("Expected error reading missing batch file")
+	}
+}
+
+func TestOperationRequest(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	writeFile(t, dir, "body.yaml", "name: test\n")
+	writeFile(t, dir, "body.json", `{"name":"test"}`)
+
+	h := http.Header{"X-Test": {"test"}}
+
+	cfg := &Config{Headers: &h}
+
+	tests := []struct {
+		name   string
+		op     *Operation
+		method string
+		query  string
+		body   string
+		err    bool
+	}{{
+		name:   "get",
+		op:     &Operation{Method: " get ", Resource: "games"},
+		method: http.MethodGet,
+	}, {
+		name:   "create",
+		op:     &Operation{Method: "create", Resource: "games"},
+		method: http.MethodPost,
+	}, {
+		name:   "update",
+		op:     &Operation{Method: "UPDATE", Resource: "games/1"},
+		method: http.MethodPut,
+	}, {
+		name: "query",
+		op: &Operation{
+			Method:   "get",
+			Resource: "games",
+			Query:    map[string]string{"size": "10"},
+		},
+		method: http.MethodGet,
+		query:  "size=10",
+	}, {
+		name: "body",
+		op: &Operation{
+			Method:   "post",
+			Resource: "games",
+			Body:     map[string]any{"name": "test"},
+		},
+		method: http.MethodPost,
+		body:   `{"name":"test"}`,
+	}, {
+		name: "yaml body file",
+		op: &Operation{
+			Method:   "post",
+			Resource: "games",
+			BodyFile: "body.yaml",
+		},
+		method: http.MethodPost,
+		body:   `{"name":"test"}`,
+	}, {
+		name: "json body file",
+		op: &Operation{
+			Method:   "post",
+			Resource: "games",
+			BodyFile: filepath.Join(dir, "body.json"),
+		},
+		method: http.MethodPost,
+		body:   `{"name":"test"}`,
+	}, {
+		name: "missing body file",
+		op: &Operation{
+			Method:   "post",
+			Resource: "games",
+			BodyFile: "missing.json",
+		},
+		err: true,
+	}, {
+		name: "invalid method",
+		op:   &Operation{Method: "fetch", Resource: "games"},
+		err:  true,
+	}, {
+		name: "missing resource",
+		op:   &Operation{Method: "get"},
+		err:  true,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			req, err := tt.op.request(dir, cfg)
+			if tt.err {
+				if err == nil {
+					t.Fatalf("Expected error, got: %v", req)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if req.Method != tt.method {
+				t.Errorf("Expected method: %v, got: %v", tt.method, req.Method)
+			}
+
+			if len(req.Path) != 1 || req.Path[0] != tt.op.Resource {
+				t.Errorf("Expected path: %v, got: %v", tt.op.Resource, req.Path)
+			}
+
+			if q := req.Query.Encode(); q != tt.query {
+				t.Errorf("Expected query: %v, got: %v", tt.query, q)
+			}
+
+			if string(req.Body) != tt.body {
+				t.Errorf("Expected body: %v, got: %s", tt.body, req.Body)
+			}
+
+			if v := req.Header.Get("X-Test"); v != "test" {
+				t.Errorf("Expected header: test, got: %v", v)
+			}
+		})
+	}
+}
+
+func TestBatch(t *testing.T) {
+	t.Parallel()
+
+	ts := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/games":
+				w.WriteHeader(http.StatusOK)
+			case "/missing":
+				w.WriteHeader(http.StatusNotFound)
+			default:
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+		}))
+
+	t.Cleanup(ts.Close)
+
+	dir := t.TempDir()
+
+	tests := []struct {
+		name string
+		data string
+		exp  int
+	}{{
+		name: "succeeded",
+		data: "- method: get\n  resource: games\n" +
+			"- method: post\n  resource: games\n",
+		exp: 0,
+	}, {
+		name: "invalid operation",
+		data: "- method: get\n  resource: games\n" +
+			"- method: fetch\n  resource: games\n",
+		exp: 1,
+	}, {
+		name: "client error",
+		data: "- method: get\n  resource: missing\n" +
+			"- method: fetch\n  resource: games\n" +
+			"- method: get\n  resource: games\n",
+		exp: 2,
+	}, {
+		name: "server error",
+		data: "- method: get\n  resource: missing\n" +
+			"- method: post\n  resource: broken\n" +
+			"- method: fetch\n  resource: games\n",
+		exp: 3,
+	}, {
+		name: "null entry",
+		data: "- method: get\n  resource: games\n- null\n",
+		exp:  1,
+	}}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			fn := writeFile(t, dir, "batch"+string(rune('a'+i))+".yaml",
+				tt.data)
+
+			ec := Batch(context.Background(),
+				&Args{File: fn, Concurrency: 2},
+				&Config{Endpoint: ts.URL, Format: FmtJSON})
+			if ec != tt.exp {
+				t.Errorf("Expected exit code: %v, got: %v", tt.exp, ec)
+			}
+		})
+	}
+
+	if ec := Batch(context.Background(), &Args{},
+		&Config{Endpoint: ts.URL}); ec != 1 {
+		t.Errorf("Expected exit code: 1, got: %v", ec)
+	}
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Shells supported by the completion command.
+const (
+	ShellBash = "bash"
+	ShellZsh  = "zsh"
+	ShellFish = "fish"
+)
+
+// completionCommands are the commands suggested by shell completion.
+const completionCommands = "get post create put update patch delete options " +
+	"head diff apply batch describe resources completion"
+
+// completionOptions are the options suggested by shell completion.
+const completionOptions = "--help --version --config.endpoint= " +
+	"--config.format= --config.headers= --config.tls= --body-file= --out= " +
+	"--concurrency= --verbose --timing --trace -f"
+
+// bashCompletion is the bash completion script. The command line is split
+// without the completion word breaks, so that option values containing = and
+// : are not split.
+const bashCompletion = `# bash completion for apictl
+_apictl() {
+  local line="${COMP_LINE:0:COMP_POINT}" cur="" prev="" cmd="" skip="" w
+  local -a words=() cfg=()
+
+  read -ra words <<< "$line"
+
+  if [[ $line != *[[:space:]] ]]; then
+    cur="${words[${#words[@]}-1]}"
+    unset 'words[${#words[@]}-1]'
+  fi
+
+  for w in "${words[@]:1}"; do
+    if [[ -n $skip ]]; then
+      skip=""
+      continue
+    fi
+
+    case "$w" in
+      -f|--body-file|--out|--concurrency) skip=1 ;;
+      --config.*) cfg+=("$w") ;;
+      -*) ;;
+      *) [[ -z $cmd ]] && cmd="$w" ;;
+    esac
+  done
+
+  [[ ${#words[@]} -gt 1 ]] && prev="${words[${#words[@]}-1]}"
+
+  case "$prev" in
+    -f|--body-file|--out)
+      COMPREPLY=($(compgen -f -- "$cur"))
+      return
+      ;;
+    --concurrency)
+      return
+      ;;
+  esac
+
+  if [[ $cur == -* ]]; then
+    COMPREPLY=($(compgen -W "%s" -- "$cur"))
+    [[ ${#COMPREPLY[@]} -eq 1 && ${COMPREPLY[0]} == *= ]] && compopt -o nospace
+  elif [[ -z $cmd ]]; then
+    COMPREPLY=($(compgen -W "%s" -- "$cur"))
+  elif [[ $cmd == completion ]]; then
+    COMPREPLY=($(compgen -W "bash zsh fish" -- "$cur"))
+  else
+    COMPREPLY=($(compgen -W "$("${words[0]}" "${cfg[@]}" resources \
+      2>/dev/null)" -- "$cur"))
+  fi
+}
+
+complete -F _apictl apictl
+`
+
+// zshCompletion is the zsh completion script.
+const zshCompletion = `#compdef apictl
+# zsh completion for apictl
+_apictl() {
+  local cmd="" skip="" w
+  local -a cfg=()
+
+  for w in "${(@)words[2,CURRENT-1]}"; do
+    if [[ -n $skip ]]; then
+      skip=""
+      continue
+    fi
+
+    case "$w" in
+      -f|--body-file|--out|--concurrency) skip=1 ;;
+      --config.*) cfg+=("$w") ;;
+      -*) ;;
+      *) [[ -z $cmd ]] && cmd="$w" ;;
+    esac
+  done
+
+  case "${words[CURRENT-1]}" in
+    -f|--body-file|--out)
+      _files
+      return
+      ;;
+    --concurrency)
+      return
+      ;;
+  esac
+
+  if [[ $PREFIX == -* ]]; then
+    compadd -S '' -- %s
+  elif [[ -z $cmd ]]; then
+    compadd -- %s
+  elif [[ $cmd == completion ]]; then
+    compadd -- bash zsh fish
+  else
+    compadd -- ${(f)"$(${words[1]} "${cfg[@]}" resources 2>/dev/null)"}
+  fi
+}
+
+compdef _apictl apictl
+`
+
+// fishCompletion is the fish completion script.
+const fishCompletion = `# fish completion for apictl
+function __apictl_command
+  set -l skip 0
+
+  for w in (commandline -opc)[2..-1]
+    if test $skip -eq 1
+      set skip 0
+      continue
+    end
+
+    switch $w
+      case -f --body-file --out --concurrency
+        set skip 1
+      case '-*'
+      case '*'
+        echo $w
+        return 0
+    end
+  end
+
+  return 1
+end
+
+function __apictl_resources
+  set -l cfg (string match -- '--config.*' (commandline -opc))
+
+  apictl $cfg resources 2>/dev/null
+end
+
+complete -c apictl -f
+complete -c apictl -n 'not __apictl_command' -a '%s'
+complete -c apictl -n '__apictl_command | string match -q completion' \
+  -a 'bash zsh fish'
+complete -c apictl -n '__apictl_command | string match -qv completion' \
+  -a '(__apictl_resources)'
+complete -c apictl -l help -d 'Display the usage message'
+complete -c apictl -l version -d 'Display the command version'
+complete -c apictl -l config.endpoint -r -d 'Base endpoint URL of the API'
+complete -c apictl -l config.format -r -a 'json yaml' \
+  -d 'Format of the command input and output'
+complete -c apictl -l config.headers -r -d 'HTTP headers of API requests'
+complete -c apictl -l config.tls -r -d 'TLS options of API requests'
+complete -c apictl -l body-file -r -F -d 'File containing the request body'
+complete -c apictl -l out -r -F -d 'File to which the response is written'
+complete -c apictl -l concurrency -r -d 'Number of concurrent batch operations'
+complete -c apictl -l verbose -d 'Display the headers of API requests'
+complete -c apictl -l timing -d 'Display the timing of API requests'
+complete -c apictl -l trace -d 'Send a trace context with API requests'
+complete -c apictl -s f -r -F -d 'Game, directory, or batch file'
+`
+
+// Completion displays the completion script of a shell, returning the exit
+// code of the command.
+func Completion(args *Args) int {
+	switch strings.ToLower(args.Resource) {
+	case ShellBash:
+		fmt.Printf(bashCompletion, completionOptions, completionCommands)
+	case ShellZsh:
+		fmt.Printf(zshCompletion, completionOptions, completionCommands)
+	case ShellFish:
+		fmt.Printf(fishCompletion, completionCommands)
+	case "":
+		fmt.Println("ERROR: missing shell")
+
+		return 1
+	default:
+		fmt.Println("ERROR: unsupported shell: ", args.Resource)
+
+		return 1
+	}
+
+	return 0
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestCompletion(t *testing.T) {
+	t.Parallel()
+
+	for _, s := range []string{
+		fmt.Sprintf(bashCompletion, completionOptions, completionCommands),
+		fmt.Sprintf(zshCompletion, completionOptions, completionCommands),
+		fmt.Sprintf(fishCompletion, completionCommands),
+	} {
+		if strings.Contains(s, "%!") {
+			t.Errorf("Expected formatted completion script, got: %v", s)
+		}
+
+		for _, c := range strings.Fields(completionCommands) {
+			if !strings.Contains(s, c) {
+				t.Errorf("Expected completion command: %v", c)
+			}
+		}
+
+		for _, o := range []string{"verbose", "timing", "trace"} {
+			if !strings.Contains(s, o) {
+				t.Errorf("Expected completion option: %v", o)
+			}
+		}
+	}
+
+	for _, tt := range []struct {
+		shell string
+		exp   int
+	}{
+		{ShellBash, 0},
+		{"ZSH", 0},
+		{ShellFish, 0},
+		{"", 1},
+		{"powershell", 1},
+	} {
+		if ec := Completion(&Args{Resource: tt.shell}); ec != tt.exp {
+			t.Errorf("Expected exit code for %v: %v, got: %v", tt.shell,
+				tt.exp, ec)
+		}
+	}
+}
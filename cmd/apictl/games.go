@@ -0,0 +1,442 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/dhaifley/game2d/client/api"
+	"gopkg.in/yaml.v3"
+)
+
+// ExitDiff is the exit code of the diff command when the local and server
+// copies of a game differ.
+const ExitDiff = 4
+
+// maxDiffValue is the maximum length of the values displayed in diffs. Longer
+// values, such as image data, are truncated.
+const maxDiffValue = 60
+
+// Change values describe a difference between the server and local copies of
+// a game, at a path of dot separated fields.
+type Change struct {
+	Op   string
+	Path string
+	Old  any
+	New  any
+}
+
+// Change operations.
+const (
+	OpAdd    = "+"
+	OpRemove = "-"
+	OpChange = "~"
+)
+
+// String returns the change as a line of a diff.
+func (c *Change) String() string {
+	switch c.Op {
+	case OpAdd:
+		return OpAdd + " " + c.Path + ": " + diffValue(c.New)
+	case OpRemove:
+		return OpRemove + " " + c.Path + ": " + diffValue(c.Old)
+	}
+
+	return OpChange + " " + c.Path + ": " + diffValue(c.Old) + " => " +
+		diffValue(c.New)
+}
+
+// diffValue formats a value for display in a diff.
+func diffValue(v any) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+
+	if len(b) > maxDiffValue {
+		return string(b[:maxDiffValue]) + "... (" + strconv.Itoa(len(b)) +
+			" bytes)"
+	}
+
+	return string(b)
+}
+
+// diffValues appends the differences between two decoded JSON values to a
+// list of changes. Objects and equal length arrays are compared by element.
+func diffValues(p string, old, cur any, changes []*Change) []*Change {
+	om, ok1 := old.(map[string]any)
+	cm, ok2 := cur.(map[string]any)
+
+	if ok1 && ok2 {
+		keys := make([]string, 0, len(om)+len(cm))
+
+		for k := range om {
+			keys = append(keys, k)
+		}
+
+		for k := range cm {
+			if _, ok := om[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+
+		slices.Sort(keys)
+
+		for _, k := range keys {
+			kp := k
+			if p != "" {
+				kp = p + "." + k
+			}
+
+			ov, ok1 := om[k]
+			cv, ok2 := cm[k]
+
+			switch {
+			case !ok1:
+				changes = append(changes, &Change{Op: OpAdd, Path: kp, New: cv})
+			case !ok2:
+				changes = append(changes, &Change{
+					Op: OpRemove, Path: kp, Old: ov,
+				})
+			default:
+				changes = diffValues(kp, ov, cv, changes)
+			}
+		}
+
+		return changes
+	}
+
+	oa, ok1 := old.([]any)
+	ca, ok2 := cur.([]any)
+
+	if ok1 && ok2 && len(oa) == len(ca) {
+		for i := range oa {
+			changes = diffValues(p+"."+strconv.Itoa(i), oa[i], ca[i], changes)
+		}
+
+		return changes
+	}
+
+	if !reflect.DeepEqual(old, cur) {
+		changes = append(changes, &Change{
+			Op: OpChange, Path: p, Old: old, New: cur,
+		})
+	}
+
+	return changes
+}
+
+// diffGame returns the differences between the server and local copies of a
+// game. Only the fields of the local copy are compared, so fields managed by
+// the server, such as timestamps, are ignored.
+func diffGame(server, local map[string]any) []*Change {
+	var changes []*Change
+
+	keys := make([]string, 0, len(local))
+
+	for k := range local {
+		keys = append(keys, k)
+	}
+
+	slices.Sort(keys)
+
+	for _, k := range keys {
+		sv, ok := server[k]
+		if !ok {
+			changes = append(changes, &Change{
+				Op: OpAdd, Path: k, New: local[k],
+			})
+
+			continue
+		}
+
+		changes = diffValues(k, sv, local[k], changes)
+	}
+
+	return changes
+}
+
+// readGame reads a local game file, in YAML or JSON format, and returns it
+// decoded as JSON, so that it can be compared with the server copy.
+func readGame(file string) (map[string]any, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read game file %s: %w", file, err)
+	}
+
+	var v any
+
+	if err := yaml.Unmarshal(b, &v); err != nil {
+		return nil, fmt.Errorf("unable to parse game file %s: %w", file, err)
+	}
+
+	if b, err = json.Marshal(v); err != nil {
+		return nil, fmt.Errorf("unable to format game file %s: %w", file, err)
+	}
+
+	res := map[string]any{}
+
+	if err := json.Unmarshal(b, &res); err != nil {
+		return nil, fmt.Errorf("invalid game file %s: %w", file, err)
+	}
+
+	return res, nil
+}
+
+// gameFiles returns the game files found in a directory, or the file itself,
+// if it is not a directory. Files are found recursively, in lexical order.
+func gameFiles(name string) ([]string, error) {
+	fi, err := os.Stat(name)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read %s: %w", name, err)
+	}
+
+	if !fi.IsDir() {
+		return []string{name}, nil
+	}
+
+	var res []string
+
+	if err := filepath.WalkDir(name, func(p string, d fs.DirEntry,
+		err error,
+	) error {
+		if err != nil {
+			return err
+		}
+
+		switch strings.ToLower(filepath.Ext(p)) {
+		case ".yaml", ".yml", ".json":
+			if !d.IsDir() {
+				res = append(res, p)
+			}
+		}
+
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("unable to read directory %s: %w", name, err)
+	}
+
+	return res, nil
+}
+
+// getGame retrieves the server copy of a game, or nil, if it does not exist.
+func getGame(ctx context.Context,
+	cli *api.Client,
+	cfg *Config,
+	id string,
+) (map[string]any, error) {
+	res, err := cli.Do(ctx, &api.Request{
+		Method: http.MethodGet,
+		Path:   []string{"games", id},
+		Header: cfg.header(),
+		Expect: []int{http.StatusOK, http.StatusNotFound},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to get game %s: %w", id, err)
+	}
+
+	if res.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+
+	g := map[string]any{}
+
+	if err := res.Decode(&g); err != nil {
+		return nil, fmt.Errorf("unable to get game %s: %w", id, err)
+	}
+
+	return g, nil
+}
+
+// gameID returns the ID of a game from a resource path, such as games/{id},
+// or from the game itself, if the path does not contain one.
+func gameID(resource string, g map[string]any) (string, error) {
+	p := strings.Split(strings.Trim(path.Clean(resource), "/"), "/")
+
+	if p[0] != "games" || len(p) > 2 {
+		return "", fmt.Errorf("invalid resource: %s", resource)
+	}
+
+	id, _ := g["id"].(string)
+
+	if len(p) == 2 {
+		if id != "" && id != p[1] {
+			return "", fmt.Errorf("game file id %s does not match: %s",
+				id, p[1])
+		}
+
+		id = p[1]
+	}
+
+	if id == "" {
+		return "", fmt.Errorf("missing game id")
+	}
+
+	return id, nil
+}
+
+// Diff displays the differences between a local game file and the server copy
+// of the game, returning the exit code of the command.
+func Diff(ctx context.Context, args *Args, cfg *Config) int {
+	if args.File == "" {
+		fmt.Println("ERROR: missing game file")
+
+		return 1
+	}
+
+	local, err := readGame(args.File)
+	if err != nil {
+		fmt.Println("ERROR: ", err.Error())
+
+		return 1
+	}
+
+	id, err := gameID(args.Resource, local)
+	if err != nil {
+		fmt.Println("ERROR: ", err.Error())
+
+		return 1
+	}
+
+	server, err := getGame(ctx, cfg.client(), cfg, id)
+	if err != nil {
+		fmt.Println("ERROR: ", err.Error())
+
+		return 1
+	}
+
+	if server == nil {
+		server = map[string]any{}
+	}
+
+	changes := diffGame(server, local)
+
+	for _, c := range changes {
+		fmt.Println(c.String())
+	}
+
+	if len(changes) > 0 {
+		return ExitDiff
+	}
+
+	return 0
+}
+
+// Apply creates or updates the games in local game files, found in a file or
+// directory, so that the server copies match them. Games which already match
+// are not updated, so it may be repeated safely. It returns the exit code of
+// the command.
+func Apply(ctx context.Context, args *Args, cfg *Config) int {
+	if args.File == "" {
+		fmt.Println("ERROR: missing game file or directory")
+
+		return 1
+	}
+
+	if args.Resource != "" && path.Clean(args.Resource) != "games" {
+		fmt.Println("ERROR: invalid resource: ", args.Resource)
+
+		return 1
+	}
+
+	files, err := gameFiles(args.File)
+	if err != nil {
+		fmt.Println("ERROR: ", err.Error())
+
+		return 1
+	}
+
+	cli := cfg.client()
+
+	ec := 0
+
+	for _, file := range files {
+		if c := applyGame(ctx, cli, cfg, file); c > ec {
+			ec = c
+		}
+	}
+
+	return ec
+}
+
+// applyGame creates or updates the game in a local game file, returning the
+// exit code of the operation.
+func applyGame(ctx context.Context,
+	cli *api.Client,
+	cfg *Config,
+	file string,
+) int {
+	local, err := readGame(file)
+	if err != nil {
+		fmt.Println("ERROR: ", err.Error())
+
+		return 1
+	}
+
+	id, _ := local["id"].(string)
+	if id == "" {
+		fmt.Println("ERROR: missing game id: ", file)
+
+		return 1
+	}
+
+	server, err := getGame(ctx, cli, cfg, id)
+	if err != nil {
+		fmt.Println("ERROR: ", err.Error())
+
+		return 1
+	}
+
+	req := &api.Request{
+		Method: http.MethodPost,
+		Path:   []string{"games"},
+		Header: cfg.header(),
+	}
+
+	status := "created"
+
+	if server != nil {
+		if len(diffGame(server, local)) == 0 {
+			fmt.Printf("games/%s unchanged (%s)\n", id, file)
+
+			return 0
+		}
+
+		req.Method, req.Path, status = http.MethodPut,
+			[]string{"games", id}, "updated"
+	}
+
+	if req.Body, err = json.Marshal(local); err != nil {
+		fmt.Println("ERROR: unable to format game: ", err.Error())
+
+		return 1
+	}
+
+	res, err := cli.Do(ctx, req)
+	if err != nil {
+		fmt.Printf("ERROR: unable to apply games/%s (%s): %s\n", id, file,
+			err.Error())
+
+		switch {
+		case res == nil:
+			return 1
+		case res.StatusCode >= http.StatusInternalServerError:
+			return 3
+		}
+
+		return 2
+	}
+
+	fmt.Printf("games/%s %s (%s)\n", id, status, file)
+
+	return 0
+}
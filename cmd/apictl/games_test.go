@@ -0,0 +1,340 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestDiffGame(t *testing.T) {
+	t.Parallel()
+
+	server := map[string]any{
+		"id":         "1",
+		"name":       "test",
+		"created_at": 1,
+		"tags":       []any{"a", "b"},
+		"object":     map[string]any{"x": 1.0, "y": 2.0},
+	}
+
+	local := map[string]any{
+		"id":          "1",
+		"name":        "changed",
+		"description": "new",
+		"tags":        []any{"a", "c"},
+		"object":      map[string]any{"x": 1.0, "z": 3.0},
+	}
+
+	exp := []string{
+		`+ description: "new"`,
+		`+ object.z: 3`,
+		`- object.y: 2`,
+		`~ name: "test" => "changed"`,
+		`~ tags.1: "b" => "c"`,
+	}
+
+	changes := diffGame(server, local)
+
+	res := make([]string, 0, len(changes))
+
+	for _, c := range changes {
+		res = append(res, c.String())
+	}
+
+	for _, e := range exp {
+		found := false
+
+		for _, r := range res {
+			if r == e {
+				found = true
+			}
+		}
+
+		if !found {
+			t.Errorf("Expected change: %v, got: %v", e, res)
+		}
+	}
+
+	if len(res) != len(exp) {
+		t.Errorf("Expected changes: %v, got: %v", exp, res)
+	}
+
+	if changes := diffGame(server, map[string]any{
+		"id": "1", "name": "test",
+	}); len(changes) != 0 {
+		t.Errorf("Expected no changes, got: %v", changes)
+	}
+}
+
+func TestDiffValue(t *testing.T) {
+	t.Parallel()
+
+	if v := diffValue("test"); v != `"test"` {
+		t.Errorf("Expected value: %q, got: %v", `"test"`, v)
+	}
+
+	v := diffValue(strings.Repeat("a", 100))
+	if !strings.HasSuffix(v, "... (102 bytes)") ||
+		len(v) != maxDiffValue+len("... (102 bytes)") {
+		t.Errorf("Expected truncated value, got: %v", v)
+	}
+}
+
+func TestGameID(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		resource string
+		game     map[string]any
+		exp      string
+		err      bool
+	}{{
+		name:     "resource",
+		resource: "games/1",
+		game:     map[string]any{},
+		exp:      "1",
+	}, {
+		name:     "game",
+		resource: "games",
+		game:     map[string]any{"id": "1"},
+		exp:      "1",
+	}, {
+		name:     "both",
+		resource: "/games/1/",
+		game:     map[string]any{"id": "1"},
+		exp:      "1",
+	}, {
+		name:     "mismatch",
+		resource: "games/1",
+		game:     map[string]any{"id": "2"},
+		err:      true,
+	}, {
+		name:     "missing",
+		resource: "games",
+		game:     map[string]any{},
+		err:      true,
+	}, {
+		name:     "invalid resource",
+		resource: "accounts/1",
+		game:     map[string]any{"id": "1"},
+		err:      true,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			id, err := gameID(tt.resource, tt.game)
+			if tt.err {
+				if err == nil {
+					t.Fatalf("Expected error, got: %v", id)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if id != tt.exp {
+				t.Errorf("Expected id: %v, got: %v", tt.exp, id)
+			}
+		})
+	}
+}
+
+func TestGameFiles(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	if err := os.Mkdir(filepath.Join(dir, "sub"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+
+	writeFile(t, dir, "b.yaml", "id: b\n")
+	writeFile(t, dir, "a.json", `{"id":"a"}`)
+	writeFile(t, dir, "readme.txt", "test")
+	writeFile(t, filepath.Join(dir, "sub"), "c.yml", "id: c\n")
+
+	files, err := gameFiles(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exp := []string{
+		filepath.Join(dir, "a.json"),
+		filepath.Join(dir, "b.yaml"),
+		filepath.Join(dir, "sub", "c.yml"),
+	}
+
+	if strings.Join(files, ",") != strings.Join(exp, ",") {
+		t.Errorf("Expected files: %v, got: %v", exp, files)
+	}
+
+	fn := filepath.Join(dir, "b.yaml")
+
+	if files, err := gameFiles(fn); err != nil || len(files) != 1 ||
+		files[0] != fn {
+		t.Errorf("Expected file: %v, got: %v, %v", fn, files, err)
+	}
+
+	if _, err := gameFiles(filepath.Join(dir, "missing")); err == nil {
+		t.Error("Expected error reading missing file")
+	}
+}
+
+// testGameServer serves games from memory, recording the requests which
+// change them.
+type testGameServer struct {
+	sync.Mutex
+	games map[string]map[string]any
+	reqs  []string
+}
+
+func (s *testGameServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.Lock()
+	defer s.Unlock()
+
+	id := strings.TrimPrefix(r.URL.Path, "/games/")
+
+	switch r.Method {
+	case http.MethodGet:
+		g, ok := s.games[id]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+
+		_ = json.NewEncoder(w).Encode(g)
+
+		return
+	case http.MethodPost, http.MethodPut:
+		b, _ := io.ReadAll(r.Body)
+
+		g := map[string]any{}
+
+		if err := json.Unmarshal(b, &g); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+
+			return
+		}
+
+		id, _ = g["id"].(string)
+
+		g["created_at"] = 1
+
+		s.games[id] = g
+
+		s.reqs = append(s.reqs, r.Method+" "+r.URL.Path)
+
+		w.WriteHeader(http.StatusOK)
+
+		_ = json.NewEncoder(w).Encode(g)
+
+		return
+	}
+
+	w.WriteHeader(http.StatusMethodNotAllowed)
+}
+
+// requests returns the requests which changed games.
+func (s *testGameServer) requests() string {
+	s.Lock()
+	defer s.Unlock()
+
+	return strings.Join(s.reqs, ",")
+}
+
+func TestDiffApply(t *testing.T) {
+	t.Parallel()
+
+	svr := &testGameServer{games: map[string]map[string]any{
+		"1": {"id": "1", "name": "test", "created_at": 1},
+	}}
+
+	ts := httptest.NewServer(svr)
+
+	t.Cleanup(ts.Close)
+
+	cfg := &Config{Endpoint: ts.URL}
+
+	dir := t.TempDir()
+
+	unchanged := writeFile(t, dir, "1.yaml", "id: \"1\"\nname: test\n")
+	created := writeFile(t, dir, "2.yaml", "id: \"2\"\nname: new\n")
+
+	ctx := context.Background()
+
+	if ec := Diff(ctx, &Args{File: unchanged, Resource: "games"},
+		cfg); ec != 0 {
+		t.Errorf("Expected exit code: 0, got: %v", ec)
+	}
+
+	if ec := Diff(ctx, &Args{File: created, Resource: "games"},
+		cfg); ec != ExitDiff {
+		t.Errorf("Expected exit code: %v, got: %v", ExitDiff, ec)
+	}
+
+	if ec := Diff(ctx, &Args{File: created, Resource: "games/3"},
+		cfg); ec != 1 {
+		t.Errorf("Expected exit code: 1, got: %v", ec)
+	}
+
+	if ec := Apply(ctx, &Args{File: dir, Resource: "games"}, cfg); ec != 0 {
+		t.Errorf("Expected exit code: 0, got: %v", ec)
+	}
+
+	if exp := "POST /games"; svr.requests() != exp {
+		t.Errorf("Expected requests: %v, got: %v", exp, svr.requests())
+	}
+
+	writeFile(t, dir, "1.yaml", "id: \"1\"\nname: changed\n")
+
+	if ec := Diff(ctx, &Args{File: unchanged, Resource: "games"},
+		cfg); ec != ExitDiff {
+		t.Errorf("Expected exit code: %v, got: %v", ExitDiff, ec)
+	}
+
+	if ec := Apply(ctx, &Args{File: dir}, cfg); ec != 0 {
+		t.Errorf("Expected exit code: 0, got: %v", ec)
+	}
+
+	exp := "POST /games,PUT /games/1"
+
+	if svr.requests() != exp {
+		t.Errorf("Expected requests: %v, got: %v", exp, svr.requests())
+	}
+
+	// Applying again is safe, since every game matches.
+	if ec := Apply(ctx, &Args{File: dir}, cfg); ec != 0 {
+		t.Errorf("Expected exit code: 0, got: %v", ec)
+	}
+
+	if svr.requests() != exp {
+		t.Errorf("Expected requests: %v, got: %v", exp, svr.requests())
+	}
+
+	writeFile(t, dir, "3.yaml", "name: missing id\n")
+
+	if ec := Apply(ctx, &Args{File: dir}, cfg); ec != 1 {
+		t.Errorf("Expected exit code: 1, got: %v", ec)
+	}
+
+	if ec := Apply(ctx, &Args{File: dir, Resource: "accounts"},
+		cfg); ec != 1 {
+		t.Errorf("Expected exit code: 1, got: %v", ec)
+	}
+}
@@ -2,7 +2,6 @@
 package main
 
 import (
-	"bytes"
 	"context"
 	"crypto/tls"
 	"encoding/json"
@@ -12,8 +11,10 @@ import (
 	"net/url"
 	"os"
 	"path"
+	"strconv"
 	"strings"
 
+	"github.com/dhaifley/game2d/client/api"
 	"gopkg.in/yaml.v3"
 )
 
@@ -22,6 +23,12 @@ var Version = "0.1.1"
 
 // Usage details.
 const Usage = `Usage: apictl [<option>] <command> <resource> [<query>]
+       apictl [<option>] diff games [<id>] -f <file>
+       apictl [<option>] apply -f <file|directory>
+       apictl [<option>] batch -f <file> [--concurrency=<n>]
+       apictl [<option>] describe <resource>
+       apictl [<option>] resources [<resource>]
+       apictl completion (bash|zsh|fish)
 
 Options:
   --help = Display this usage message
@@ -30,6 +37,17 @@ Options:
   --config.format = (json|yaml) Format of the command input and output
   --config.headers = Optional, HTTP headers to include with the API request
   --config.tls = Optional, TLS options to use for the API request
+  --body-file = Optional, file containing the request body, which is otherwise
+read from standard input
+  --out = Optional, file to which the response body is written, instead of
+standard output
+  --concurrency = Optional, number of operations performed at once by the
+batch command, defaults to 4
+  --verbose = Display the headers of API requests and responses
+  --timing = Display the DNS, connect, TLS, time to first byte, and total
+durations of API requests
+  --trace = Send a generated W3C traceparent header with API requests, and
+display its trace ID, to correlate the requests with the server traces
   
 Commands:
   get
@@ -38,6 +56,27 @@ Commands:
   patch
   delete
   option, head
+  diff = Display the differences between a local game file and the server
+copy of the game, exiting with status 4 if they differ
+  apply = Create or update the games in local game files, or in the files of
+a directory, so that the server copies match them. Games which already match
+are not updated
+  batch = Perform the list of operations in a local file, displaying the result
+of each, and exiting with the highest status of the failed operations
+  describe = Display the operations of a resource, such as games/{id}, and the
+schemas of their request bodies, from the OpenAPI document of the API
+  resources = Display the resources of the API, from its OpenAPI document,
+optionally only those beginning with a resource
+  completion = Display the completion script of a shell, which suggests
+commands, options, and the resources of the API. For example, add
+source <(apictl completion bash) to ~/.bashrc
+
+Files:
+  -f = The local YAML or JSON game file, or directory of game files, used by
+the diff and apply commands. Games are identified by the id in each file. For
+the batch command, the YAML or JSON file, or - for standard input, containing
+a list of operations, each with a method, resource, and optional query, body,
+or body_file, relative to the batch file
 
 Resources:
   Any resource or ID provided by the API. Multiple parameters will be combined
@@ -55,15 +94,21 @@ parameters are:
 
 // Commands.
 const (
-	CmdGet     = http.MethodGet
-	CmdCreate  = "CREATE"
-	CmdPost    = http.MethodPost
-	CmdUpdate  = "UPDATE"
-	CmdPut     = http.MethodPut
-	CmdPatch   = http.MethodPatch
-	CmdDelete  = http.MethodDelete
-	CmdOptions = http.MethodOptions
-	CmdHead    = http.MethodHead
+	CmdGet        = http.MethodGet
+	CmdCreate     = "CREATE"
+	CmdPost       = http.MethodPost
+	CmdUpdate     = "UPDATE"
+	CmdPut        = http.MethodPut
+	CmdPatch      = http.MethodPatch
+	CmdDelete     = http.MethodDelete
+	CmdOptions    = http.MethodOptions
+	CmdHead       = http.MethodHead
+	CmdDiff       = "DIFF"
+	CmdApply      = "APPLY"
+	CmdBatch      = "BATCH"
+	CmdDescribe   = "DESCRIBE"
+	CmdResources  = "RESOURCES"
+	CmdCompletion = "COMPLETION"
 )
 
 // Formats.
@@ -74,9 +119,16 @@ const (
 
 // Args values are used to represent the arguments to the command.
 type Args struct {
-	Method   string      `json:"method"   yaml:"method"`
-	Resource string      `json:"resource" yaml:"resource"`
-	Query    *url.Values `json:"query"    yaml:"query"`
+	Method      string      `json:"method"      yaml:"method"`
+	Resource    string      `json:"resource"    yaml:"resource"`
+	Query       *url.Values `json:"query"       yaml:"query"`
+	File        string      `json:"file"        yaml:"file"`
+	BodyFile    string      `json:"body_file"   yaml:"body_file"`
+	Out         string      `json:"out"         yaml:"out"`
+	Concurrency int         `json:"concurrency" yaml:"concurrency"`
+	Verbose     bool        `json:"verbose"     yaml:"verbose"`
+	Timing      bool        `json:"timing"      yaml:"timing"`
+	Trace       bool        `json:"trace"       yaml:"trace"`
 }
 
 // Config values are used to configure the API requests.
@@ -85,6 +137,10 @@ type Config struct {
 	Headers  *http.Header `json:"headers"  yaml:"headers"`
 	TLS      *tls.Config  `json:"tls"      yaml:"tls"`
 	Format   string       `json:"format"   yaml:"format"`
+	progress bool
+	verbose  bool
+	timing   bool
+	traceID  string
 }
 
 // LoadEnvironment loads missing configuration from the environment.
@@ -97,17 +153,13 @@ func (c *Config) LoadEnvironment() error {
 		c.Format = os.Getenv("APICTL_CONFIG_FORMAT")
 	}
 
-	if c.Headers == nil {
-		v := os.Getenv("APICTL_CONFIG_HEADERS")
-
+	if v := os.Getenv("APICTL_CONFIG_HEADERS"); c.Headers == nil && v != "" {
 		if err := json.Unmarshal([]byte(v), &c.Headers); err != nil {
 			return fmt.Errorf("unable to parse APICTL_CONFIG_HEADERS: %w", err)
 		}
 	}
 
-	if c.TLS == nil {
-		v := os.Getenv("APICTL_CONFIG_TLS")
-
+	if v := os.Getenv("APICTL_CONFIG_TLS"); c.TLS == nil && v != "" {
 		if err := json.Unmarshal([]byte(v), &c.TLS); err != nil {
 			return fmt.Errorf("unable to parse APICTL_CONFIG_TLS: %w", err)
 		}
@@ -116,6 +168,46 @@ func (c *Config) LoadEnvironment() error {
 	return nil
 }
 
+// client returns an API client using the configuration.
+func (c *Config) client() *api.Client {
+	opts := []api.Option{api.WithUserAgent("apictl/" + Version)}
+
+	var rt http.RoundTripper = http.DefaultTransport
+
+	if c.TLS != nil {
+		rt = &http.Transport{TLSClientConfig: c.TLS}
+	}
+
+	if c.progress {
+		rt = &progressTransport{base: rt, w: os.Stderr}
+	}
+
+	if c.verbose || c.timing || c.traceID != "" {
+		rt = &traceTransport{
+			base:    rt,
+			w:       os.Stderr,
+			verbose: c.verbose,
+			timing:  c.timing,
+			traceID: c.traceID,
+		}
+	}
+
+	if rt != http.DefaultTransport {
+		opts = append(opts, api.WithHTTPClient(&http.Client{Transport: rt}))
+	}
+
+	return api.New(c.Endpoint, opts...)
+}
+
+// header returns the HTTP headers included with API requests.
+func (c *Config) header() http.Header {
+	if c.Headers == nil {
+		return nil
+	}
+
+	return *c.Headers
+}
+
 // ParseArgs is used to parse the arguments to the command into the required
 // data structures.
 func ParseArgs() (*Args, *Config, error) {
@@ -125,11 +217,48 @@ func ParseArgs() (*Args, *Config, error) {
 
 	cfgMap := map[string]any{}
 
+	var next *string
+
+	concurrency := ""
+
 	for n, arg := range os.Args {
 		if n == 0 {
 			continue
 		}
 
+		if next != nil {
+			*next, next = arg, nil
+
+			continue
+		}
+
+		// Value options may be followed by their value, or include it.
+		for _, o := range []struct {
+			name  string
+			value *string
+		}{
+			{"-f", &args.File},
+			{"--body-file", &args.BodyFile},
+			{"--out", &args.Out},
+			{"--concurrency", &concurrency},
+		} {
+			if arg == o.name {
+				next = o.value
+
+				break
+			}
+
+			if v, ok := strings.CutPrefix(arg, o.name+"="); ok {
+				*o.value, arg = v, ""
+
+				break
+			}
+		}
+
+		if next != nil || arg == "" {
+			continue
+		}
+
 		if n == 1 {
 			switch v := strings.TrimSpace(arg); v {
 			case "--version":
@@ -145,6 +274,12 @@ func ParseArgs() (*Args, *Config, error) {
 
 		if strings.HasPrefix(arg, "--") {
 			switch {
+			case arg == "--verbose":
+				args.Verbose = true
+			case arg == "--timing":
+				args.Timing = true
+			case arg == "--trace":
+				args.Trace = true
 			case strings.HasPrefix(arg, "--config."):
 				v := strings.TrimPrefix(arg, "--config.")
 
@@ -190,7 +325,8 @@ func ParseArgs() (*Args, *Config, error) {
 		if args.Method == "" {
 			switch v := strings.TrimSpace(strings.ToUpper(arg)); v {
 			case CmdGet, CmdCreate, CmdPost, CmdUpdate, CmdPut, CmdPatch,
-				CmdDelete, CmdOptions, CmdHead:
+				CmdDelete, CmdOptions, CmdHead, CmdDiff, CmdApply, CmdBatch,
+				CmdDescribe, CmdResources, CmdCompletion:
 				args.Method = v
 			default:
 				return nil, nil, fmt.Errorf("invalid command: %s", v)
@@ -219,8 +355,17 @@ func ParseArgs() (*Args, *Config, error) {
 		}
 	}
 
-	if cfg.Endpoint == "" {
-		return nil, nil, fmt.Errorf("missing config.endpoint")
+	if next != nil {
+		return nil, nil, fmt.Errorf("missing option value")
+	}
+
+	if concurrency != "" {
+		n, err := strconv.Atoi(concurrency)
+		if err != nil || n < 1 {
+			return nil, nil, fmt.Errorf("invalid concurrency: %s", concurrency)
+		}
+
+		args.Concurrency = n
 	}
 
 	switch cfg.Format {
@@ -250,33 +395,60 @@ func main() {
 		os.Exit(1)
 	}
 
+	if args.Method == CmdCompletion {
+		os.Exit(Completion(args))
+	}
+
 	if err := cfg.LoadEnvironment(); err != nil {
 		fmt.Println("ERROR: ", err.Error())
 
 		os.Exit(1)
 	}
 
-	ctx := context.Background()
-
-	ur, err := url.Parse(cfg.Endpoint)
-	if err != nil {
-		fmt.Println("ERROR: invalid endpoint: ", cfg.Endpoint,
-			": ", err.Error())
+	if cfg.Endpoint == "" {
+		fmt.Println("ERROR: missing config.endpoint")
 
 		os.Exit(1)
 	}
 
-	ur.Path = path.Join(ur.Path, args.Resource)
+	ctx := context.Background()
 
-	if args.Query != nil {
-		ur.RawQuery = args.Query.Encode()
+	cfg.progress = (args.BodyFile != "" || args.Out != "") &&
+		terminal(os.Stderr)
+
+	cfg.verbose, cfg.timing = args.Verbose, args.Timing
+
+	if args.Trace {
+		cfg.traceID = newTraceID()
+
+		fmt.Fprintln(os.Stderr, "* trace_id:", cfg.traceID)
+	}
+
+	switch args.Method {
+	case CmdDiff:
+		os.Exit(Diff(ctx, args, cfg))
+	case CmdApply:
+		os.Exit(Apply(ctx, args, cfg))
+	case CmdBatch:
+		os.Exit(Batch(ctx, args, cfg))
+	case CmdDescribe:
+		os.Exit(Describe(ctx, args, cfg))
+	case CmdResources:
+		os.Exit(Resources(ctx, args, cfg))
 	}
 
-	var buf *bytes.Buffer
+	var body []byte
 
 	switch args.Method {
 	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
-		b, err := io.ReadAll(os.Stdin)
+		var b []byte
+
+		if args.BodyFile != "" {
+			b, err = os.ReadFile(args.BodyFile)
+		} else {
+			b, err = io.ReadAll(os.Stdin)
+		}
+
 		if err != nil {
 			fmt.Println("ERROR: unable to read input: ", err.Error())
 
@@ -301,44 +473,27 @@ func main() {
 			}
 		}
 
-		buf = bytes.NewBuffer(b)
-	}
-
-	var req *http.Request
-
-	if buf != nil {
-		req, err = http.NewRequestWithContext(ctx, args.Method, ur.String(),
-			buf)
-	} else {
-		req, err = http.NewRequestWithContext(ctx, args.Method, ur.String(),
-			nil)
-	}
-
-	if err != nil || req == nil {
-		fmt.Println("ERROR: unable to create request: ", err.Error())
-
-		os.Exit(1)
+		body = b
 	}
 
-	if cfg.Headers != nil {
-		req.Header = *cfg.Headers
+	req := &api.Request{
+		Method: args.Method,
+		Path:   []string{args.Resource},
+		Body:   body,
+		Header: cfg.header(),
 	}
 
-	cli := &http.Client{}
-
-	if cfg.TLS != nil {
-		cli.Transport = &http.Transport{TLSClientConfig: cfg.TLS}
+	if args.Query != nil {
+		req.Query = *args.Query
 	}
 
-	res, err := cli.Do(req)
-	if err != nil {
+	res, err := cfg.client().Do(ctx, req)
+	if res == nil {
 		fmt.Println("ERROR: unable to perform request: ", err.Error())
 
 		os.Exit(1)
 	}
 
-	defer res.Body.Close()
-
 	if args.Method == CmdOptions || args.Method == CmdHead {
 		var b []byte
 
@@ -361,12 +516,7 @@ func main() {
 		os.Exit(0)
 	}
 
-	b, err := io.ReadAll(res.Body)
-	if err != nil {
-		fmt.Println("ERROR: unable to read response body: ", err.Error())
-
-		os.Exit(1)
-	}
+	b := res.Body
 
 	ec := 0
 
@@ -401,7 +551,13 @@ func main() {
 			}
 		}
 
-		fmt.Print(string(b))
+		if args.Out == "" {
+			fmt.Print(string(b))
+		} else if err := os.WriteFile(args.Out, b, 0o644); err != nil {
+			fmt.Println("ERROR: unable to write output: ", err.Error())
+
+			os.Exit(1)
+		}
 	}
 
 	os.Exit(ec)
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+// parseArgs parses command arguments, as given on the command line.
+func parseArgs(t *testing.T, arg ...string) (*Args, *Config, error) {
+	t.Helper()
+
+	orig := os.Args
+
+	t.Cleanup(func() { os.Args = orig })
+
+	os.Args = append([]string{"apictl"}, arg...)
+
+	return ParseArgs()
+}
+
+func TestParseArgs(t *testing.T) {
+	args, cfg, err := parseArgs(t, "post", "games", "--body-file",
+		"game.yaml", "--out=out.json", "--config.format=yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if args.Method != "POST" || args.Resource != "games" {
+		t.Errorf("Expected command: POST games, got: %v %v", args.Method,
+			args.Resource)
+	}
+
+	if args.BodyFile != "game.yaml" {
+		t.Errorf("Expected body file: game.yaml, got: %v", args.BodyFile)
+	}
+
+	if args.Out != "out.json" {
+		t.Errorf("Expected output file: out.json, got: %v", args.Out)
+	}
+
+	if cfg.Format != FmtYAML {
+		t.Errorf("Expected format: %v, got: %v", FmtYAML, cfg.Format)
+	}
+
+	args, _, err = parseArgs(t, "--verbose", "get", "games", "--timing",
+		"--trace", "--size=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !args.Verbose || !args.Timing || !args.Trace {
+		t.Errorf("Expected verbose, timing and trace output, got: %+v", args)
+	}
+
+	if q := args.Query.Encode(); q != "size=1" {
+		t.Errorf("Expected query: size=1, got: %v", q)
+	}
+
+	if _, _, err := parseArgs(t, "get", "games", "--out"); err == nil {
+		t.Error("Expected error for missing option value")
+	}
+
+	if _, _, err := parseArgs(t, "fetch", "games"); err == nil {
+		t.Error("Expected error for invalid command")
+	}
+}
@@ -0,0 +1,390 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/dhaifley/game2d/client/api"
+	"gopkg.in/yaml.v3"
+)
+
+// openAPICacheTTL is the time for which the OpenAPI document of the server is
+// cached, so that shell completion does not request it for every suggestion.
+const openAPICacheTTL = time.Hour
+
+// openAPITimeout is the maximum time for which retrieving the OpenAPI document
+// may delay a suggestion.
+const openAPITimeout = time.Second * 5
+
+// openAPIMethods are the operation methods of the OpenAPI document, in the
+// order in which they are described.
+var openAPIMethods = []string{
+	"get", "post", "put", "patch", "delete", "options", "head",
+}
+
+// openAPI values contain the OpenAPI document of the server, and the path
+// prefix of its endpoint, which is removed from paths to obtain resources.
+type openAPI struct {
+	doc    map[string]any
+	prefix string
+}
+
+// ResourceOperation values describe an operation which may be performed on a
+// resource, and the schema of its request body, if it has one.
+type ResourceOperation struct {
+	Method      string   `json:"method"                 yaml:"method"`
+	Summary     string   `json:"summary,omitempty"      yaml:"summary,omitempty"`
+	Description string   `json:"description,omitempty"  yaml:"description,omitempty"`
+	Query       []string `json:"query,omitempty"        yaml:"query,omitempty"`
+	ContentType string   `json:"content_type,omitempty" yaml:"content_type,omitempty"`
+	Body        any      `json:"body,omitempty"         yaml:"body,omitempty"`
+}
+
+// ResourceDescription values describe the operations of a resource.
+type ResourceDescription struct {
+	Resource   string               `json:"resource"   yaml:"resource"`
+	Operations []*ResourceOperation `json:"operations" yaml:"operations"`
+}
+
+// openAPICacheFile returns the file in which the OpenAPI document of an
+// endpoint is cached, or an empty string, if there is no cache directory.
+func openAPICacheFile(endpoint string) string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+
+	h := sha256.Sum256([]byte(endpoint))
+
+	return filepath.Join(dir, "apictl", hex.EncodeToString(h[:8])+".json")
+}
+
+// parseOpenAPI parses the OpenAPI document of an endpoint.
+func parseOpenAPI(b []byte, endpoint string) (*openAPI, error) {
+	res := &openAPI{}
+
+	if err := json.Unmarshal(b, &res.doc); err != nil {
+		return nil, fmt.Errorf("unable to parse OpenAPI document: %w", err)
+	}
+
+	if _, ok := res.doc["paths"].(map[string]any); !ok {
+		return nil, fmt.Errorf("invalid OpenAPI document: missing paths")
+	}
+
+	if u, err := url.Parse(endpoint); err == nil {
+		res.prefix = strings.TrimSuffix(u.Path, "/")
+	}
+
+	return res, nil
+}
+
+// loadOpenAPI returns the OpenAPI document of the server, from the cache, if
+// it was retrieved recently. An expired copy is used if the server is not
+// available.
+func loadOpenAPI(ctx context.Context, cfg *Config) (*openAPI, error) {
+	fn := openAPICacheFile(cfg.Endpoint)
+
+	if fn != "" {
+		if fi, err := os.Stat(fn); err == nil &&
+			time.Since(fi.ModTime()) < openAPICacheTTL {
+			if b, err := os.ReadFile(fn); err == nil {
+				if res, err := parseOpenAPI(b, cfg.Endpoint); err == nil {
+					return res, nil
+				}
+			}
+		}
+	}
+
+	r, err := cfg.client().Do(ctx, &api.Request{
+		Method: http.MethodGet,
+		Path:   []string{"openapi.json"},
+		Header: cfg.header(),
+	})
+	if err != nil {
+		if fn != "" {
+			if b, rErr := os.ReadFile(fn); rErr == nil {
+				if res, pErr := parseOpenAPI(b, cfg.Endpoint); pErr == nil {
+					return res, nil
+				}
+			}
+		}
+
+		return nil, fmt.Errorf("unable to get OpenAPI document: %w", err)
+	}
+
+	res, err := parseOpenAPI(r.Body, cfg.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	if fn != "" {
+		if err := os.MkdirAll(filepath.Dir(fn), 0o755); err == nil {
+			_ = os.WriteFile(fn, r.Body, 0o644)
+		}
+	}
+
+	return res, nil
+}
+
+// paths returns the paths of the document, in lexical order.
+func (o *openAPI) paths() []string {
+	ps, _ := o.doc["paths"].(map[string]any)
+
+	res := make([]string, 0, len(ps))
+
+	for p := range ps {
+		res = append(res, p)
+	}
+
+	slices.Sort(res)
+
+	return res
+}
+
+// resource returns the resource of a path, relative to the endpoint.
+func (o *openAPI) resource(p string) string {
+	return strings.Trim(strings.TrimPrefix(p, o.prefix), "/")
+}
+
+// match returns the path of the document matching a resource. Path parameters
+// match any segment, and paths with more matching literal segments are
+// preferred, so that games/import matches it, rather than games/{id}.
+func (o *openAPI) match(resource string) string {
+	rs := strings.Split(strings.Trim(path.Clean("/"+resource), "/"), "/")
+
+	res, best := "", -1
+
+	for _, p := range o.paths() {
+		ps := strings.Split(o.resource(p), "/")
+
+		if len(ps) != len(rs) {
+			continue
+		}
+
+		score := 0
+
+		for i, s := range ps {
+			switch {
+			case s == rs[i]:
+				score++
+			case strings.HasPrefix(s, "{") && strings.HasSuffix(s, "}"):
+			default:
+				score = -1
+			}
+
+			if score < 0 {
+				break
+			}
+		}
+
+		if score > best {
+			res, best = p, score
+		}
+	}
+
+	return res
+}
+
+// ref returns the value of the document referenced by a local JSON pointer,
+// such as #/components/schemas/game, or nil, if it is not found.
+func (o *openAPI) ref(ref string) any {
+	p, ok := strings.CutPrefix(ref, "#/")
+	if !ok {
+		return nil
+	}
+
+	var v any = o.doc
+
+	for _, s := range strings.Split(p, "/") {
+		m, ok := v.(map[string]any)
+		if !ok {
+			return nil
+		}
+
+		s = strings.ReplaceAll(strings.ReplaceAll(s, "~1", "/"), "~0", "~")
+
+		if v, ok = m[s]; !ok {
+			return nil
+		}
+	}
+
+	return v
+}
+
+// resolve returns a value of the document with its references replaced by the
+// values they reference. Recursive references are left unresolved.
+func (o *openAPI) resolve(v any, seen []string) any {
+	switch t := v.(type) {
+	case map[string]any:
+		if ref, ok := t["$ref"].(string); ok {
+			r := o.ref(ref)
+			if r == nil || slices.Contains(seen, ref) {
+				return t
+			}
+
+			return o.resolve(r, append(slices.Clip(seen), ref))
+		}
+
+		res := make(map[string]any, len(t))
+
+		for k, e := range t {
+			res[k] = o.resolve(e, seen)
+		}
+
+		return res
+	case []any:
+		res := make([]any, len(t))
+
+		for i, e := range t {
+			res[i] = o.resolve(e, seen)
+		}
+
+		return res
+	}
+
+	return v
+}
+
+// queryParameters returns the names of the query parameters in a list of
+// parameters.
+func (o *openAPI) queryParameters(params any) []string {
+	ps, _ := o.resolve(params, nil).([]any)
+
+	var res []string
+
+	for _, p := range ps {
+		m, _ := p.(map[string]any)
+
+		if name, _ := m["name"].(string); name != "" && m["in"] == "query" {
+			res = append(res, name)
+		}
+	}
+
+	return res
+}
+
+// describe returns the description of the operations of a path.
+func (o *openAPI) describe(p string) *ResourceDescription {
+	ps, _ := o.doc["paths"].(map[string]any)
+	item, _ := o.resolve(ps[p], nil).(map[string]any)
+
+	res := &ResourceDescription{
+		Resource:   o.resource(p),
+		Operations: []*ResourceOperation{},
+	}
+
+	common := o.queryParameters(item["parameters"])
+
+	for _, m := range openAPIMethods {
+		op, ok := item[m].(map[string]any)
+		if !ok {
+			continue
+		}
+
+		ro := &ResourceOperation{Method: strings.ToUpper(m)}
+
+		ro.Summary, _ = op["summary"].(string)
+		ro.Description, _ = op["description"].(string)
+		ro.Description = strings.TrimSpace(ro.Description)
+
+		ro.Query = append(slices.Clone(common),
+			o.queryParameters(op["parameters"])...)
+
+		slices.Sort(ro.Query)
+
+		ro.Query = slices.Compact(ro.Query)
+
+		body, _ := op["requestBody"].(map[string]any)
+		content, _ := body["content"].(map[string]any)
+
+		for _, ct := range []string{"application/json", "application/yaml"} {
+			if c, ok := content[ct].(map[string]any); ok {
+				ro.ContentType, ro.Body = ct, c["schema"]
+
+				break
+			}
+		}
+
+		res.Operations = append(res.Operations, ro)
+	}
+
+	return res
+}
+
+// Resources displays the resources of the API, found in its OpenAPI document,
+// which begin with the requested resource, if any. Path parameters are shown
+// in braces, such as games/{id}. It returns the exit code of the command.
+func Resources(ctx context.Context, args *Args, cfg *Config) int {
+	ctx, cancel := context.WithTimeout(ctx, openAPITimeout)
+	defer cancel()
+
+	doc, err := loadOpenAPI(ctx, cfg)
+	if err != nil {
+		fmt.Println("ERROR: ", err.Error())
+
+		return 1
+	}
+
+	for _, p := range doc.paths() {
+		if r := doc.resource(p); r != "" &&
+			strings.HasPrefix(r, args.Resource) {
+			fmt.Println(r)
+		}
+	}
+
+	return 0
+}
+
+// Describe displays the operations which may be performed on a resource, and
+// the schemas of their request bodies, found in the OpenAPI document of the
+// API. It returns the exit code of the command.
+func Describe(ctx context.Context, args *Args, cfg *Config) int {
+	if args.Resource == "" {
+		fmt.Println("ERROR: missing resource")
+
+		return 1
+	}
+
+	doc, err := loadOpenAPI(ctx, cfg)
+	if err != nil {
+		fmt.Println("ERROR: ", err.Error())
+
+		return 1
+	}
+
+	p := doc.match(args.Resource)
+	if p == "" {
+		fmt.Println("ERROR: unknown resource: ", args.Resource)
+
+		return 2
+	}
+
+	var b []byte
+
+	if cfg.Format == FmtYAML {
+		b, err = yaml.Marshal(doc.describe(p))
+	} else {
+		b, err = json.MarshalIndent(doc.describe(p), "", "  ")
+	}
+
+	if err != nil {
+		fmt.Println("ERROR: unable to format description: ", err.Error())
+
+		return 1
+	}
+
+	fmt.Println(strings.TrimSpace(string(b)))
+
+	return 0
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const testOpenAPI = `{
+	"openapi": "3.1.0",
+	"paths": {
+		"/api/v1/games": {
+			"parameters": [{"$ref": "#/components/parameters/size"}],
+			"get": {
+				"summary": "Search games",
+				"parameters": [
+					{"name": "search", "in": "query"},
+					{"name": "size", "in": "query"},
+					{"name": "X-Test", "in": "header"}
+				]
+			},
+			"post": {
+				"summary": "Create a game",
+				"description": "  Creates a game.\n",
+				"requestBody": {"content": {"application/json": {
+					"schema": {"$ref": "#/components/schemas/game"}
+				}}}
+			}
+		},
+		"/api/v1/games/{id}": {
+			"get": {"summary": "Get a game"},
+			"delete": {"summary": "Delete a game"}
+		},
+		"/api/v1/games/import": {
+			"post": {"summary": "Import games"}
+		}
+	},
+	"components": {
+		"parameters": {"size": {"name": "size", "in": "query"}},
+		"schemas": {
+			"game": {
+				"type": "object",
+				"properties": {
+					"name": {"type": "string"},
+					"parent": {"$ref": "#/components/schemas/game"}
+				}
+			}
+		}
+	}
+}`
+
+func TestParseOpenAPI(t *testing.T) {
+	t.Parallel()
+
+	doc, err := parseOpenAPI([]byte(testOpenAPI),
+		"http://localhost:8080/api/v1/")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if doc.prefix != "/api/v1" {
+		t.Errorf("Expected prefix: /api/v1, got: %v", doc.prefix)
+	}
+
+	exp := []string{
+		"/api/v1/games", "/api/v1/games/import", "/api/v1/games/{id}",
+	}
+
+	if ps := doc.paths(); !slices.Equal(ps, exp) {
+		t.Errorf("Expected paths: %v, got: %v", exp, ps)
+	}
+
+	if _, err := parseOpenAPI([]byte(`{}`), ""); err == nil {
+		t.Error("Expected error for missing paths")
+	}
+
+	if _, err := parseOpenAPI([]byte(`invalid`), ""); err == nil {
+		t.Error("Expected error for invalid document")
+	}
+}
+
+func TestOpenAPIMatch(t *testing.T) {
+	t.Parallel()
+
+	doc, err := parseOpenAPI([]byte(testOpenAPI), "http://localhost/api/v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		resource string
+		exp      string
+	}{
+		{"games", "/api/v1/games"},
+		{"/games/", "/api/v1/games"},
+		{"games/1", "/api/v1/games/{id}"},
+		{"games/import", "/api/v1/games/import"},
+		{"games/1/import", ""},
+		{"accounts", ""},
+	}
+
+	for _, tt := range tests {
+		if p := doc.match(tt.resource); p != tt.exp {
+			t.Errorf("Expected match for %v: %v, got: %v", tt.resource,
+				tt.exp, p)
+		}
+	}
+}
+
+func TestOpenAPIDescribe(t *testing.T) {
+	t.Parallel()
+
+	doc, err := parseOpenAPI([]byte(testOpenAPI), "http://localhost/api/v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res := doc.describe("/api/v1/games")
+
+	if res.Resource != "games" {
+		t.Errorf("Expected resource: games, got: %v", res.Resource)
+	}
+
+	if len(res.Operations) != 2 {
+		t.Fatalf("Expected operations: 2, got: %v", len(res.Operations))
+	}
+
+	get, post := res.Operations[0], res.Operations[1]
+
+	if get.Method != http.MethodGet || get.Summary != "Search games" {
+		t.Errorf("Expected get operation, got: %+v", get)
+	}
+
+	if exp := []string{"search", "size"}; !slices.Equal(get.Query, exp) {
+		t.Errorf("Expected query: %v, got: %v", exp, get.Query)
+	}
+
+	if post.Method != http.MethodPost || post.Description != "Creates a game." {
+		t.Errorf("Expected post operation, got: %+v", post)
+	}
+
+	if post.ContentType != "application/json" {
+		t.Errorf("Expected content type: application/json, got: %v",
+			post.ContentType)
+	}
+
+	// The recursive reference of the schema is left unresolved.
+	body, _ := post.Body.(map[string]any)
+	props, _ := body["properties"].(map[string]any)
+	parent, _ := props["parent"].(map[string]any)
+
+	if body["type"] != "object" ||
+		parent["$ref"] != "#/components/schemas/game" {
+		t.Errorf("Expected resolved schema, got: %v", post.Body)
+	}
+
+	if v := doc.ref("#/components/schemas/missing"); v != nil {
+		t.Errorf("Expected missing reference, got: %v", v)
+	}
+
+	if v := doc.ref("other.json#/schemas/game"); v != nil {
+		t.Errorf("Expected unsupported reference, got: %v", v)
+	}
+}
+
+func TestLoadOpenAPI(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+
+	var reqs atomic.Int32
+
+	ts := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			reqs.Add(1)
+
+			if r.URL.Path != "/api/v1/openapi.json" {
+				w.WriteHeader(http.StatusNotFound)
+
+				return
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+
+			_, _ = w.Write([]byte(testOpenAPI))
+		}))
+
+	cfg := &Config{Endpoint: ts.URL + "/api/v1"}
+
+	ctx := context.Background()
+
+	if _, err := loadOpenAPI(ctx, cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	fn := openAPICacheFile(cfg.Endpoint)
+	if fn == "" {
+		t.Fatal("Expected cache file")
+	}
+
+	if _, err := os.Stat(fn); err != nil {
+		t.Fatalf("Expected cached document: %v", err)
+	}
+
+	// The cached document is used, rather than requesting it again.
+	if _, err := loadOpenAPI(ctx, cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := reqs.Load(); n != 1 {
+		t.Errorf("Expected requests: 1, got: %v", n)
+	}
+
+	ts.Close()
+
+	expired := time.Now().Add(-2 * openAPICacheTTL)
+
+	if err := os.Chtimes(fn, expired, expired); err != nil {
+		t.Fatal(err)
+	}
+
+	// An expired copy is used if the server is not available.
+	doc, err := loadOpenAPI(ctx, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if p := doc.match("games/1"); p != "/api/v1/games/{id}" {
+		t.Errorf("Expected match: /api/v1/games/{id}, got: %v", p)
+	}
+
+	cfg.Endpoint += "/other"
+
+	if _, err := loadOpenAPI(ctx, cfg); err == nil {
+		t.Error("Expected error for unavailable server")
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+)
+
+// progressInterval is the minimum time between progress updates.
+const progressInterval = time.Millisecond * 100
+
+// progress values display the progress of a transfer on a terminal.
+type progress struct {
+	w      io.Writer
+	label  string
+	total  int64
+	n      int64
+	last   time.Time
+	closed bool
+}
+
+// update adds transferred bytes to the progress, and displays it, if it was
+// not displayed recently, or the transfer is done.
+func (p *progress) update(n int, done bool) {
+	p.n += int64(n)
+
+	if p.total > 0 && p.n >= p.total {
+		done = true
+	}
+
+	if p.closed || (!done && time.Since(p.last) < progressInterval) {
+		return
+	}
+
+	p.last = time.Now()
+
+	if p.total > 0 {
+		fmt.Fprintf(p.w, "\r%s %s / %s (%d%%)", p.label, byteSize(p.n),
+			byteSize(p.total), p.n*100/p.total)
+	} else {
+		fmt.Fprintf(p.w, "\r%s %s", p.label, byteSize(p.n))
+	}
+
+	if done {
+		fmt.Fprintln(p.w)
+
+		p.closed = true
+	}
+}
+
+// byteSize formats a number of bytes for display.
+func byteSize(n int64) string {
+	const unit = 1024
+
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+
+	d, e := int64(unit), 0
+
+	for v := n / unit; v >= unit; v /= unit {
+		d *= unit
+		e++
+	}
+
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(d), "KMGTPE"[e])
+}
+
+// progressReader values display the progress of reading a request or
+// response body.
+type progressReader struct {
+	io.ReadCloser
+	p *progress
+}
+
+// Read reads from the body, updating the progress.
+func (r *progressReader) Read(b []byte) (int, error) {
+	n, err := r.ReadCloser.Read(b)
+
+	r.p.update(n, err == io.EOF)
+
+	return n, err
+}
+
+// progressTransport values are HTTP transports which display the progress of
+// request and response bodies.
+type progressTransport struct {
+	base http.RoundTripper
+	w    io.Writer
+}
+
+// RoundTrip sends a request, displaying the progress of its body, and of the
+// body of the response.
+func (t *progressTransport) RoundTrip(req *http.Request) (*http.Response,
+	error,
+) {
+	if req.Body != nil && req.Body != http.NoBody {
+		req = req.Clone(req.Context())
+
+		req.Body = &progressReader{
+			ReadCloser: req.Body,
+			p: &progress{
+				w: t.w, label: "upload", total: req.ContentLength,
+			},
+		}
+	}
+
+	res, err := t.base.RoundTrip(req)
+	if err != nil || res.ContentLength == 0 {
+		return res, err
+	}
+
+	res.Body = &progressReader{
+		ReadCloser: res.Body,
+		p: &progress{
+			w: t.w, label: "download", total: res.ContentLength,
+		},
+	}
+
+	return res, nil
+}
+
+// terminal returns whether a file is a terminal, on which progress can be
+// displayed.
+func terminal(f *os.File) bool {
+	fi, err := f.Stat()
+
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
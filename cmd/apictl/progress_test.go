@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestByteSize(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		n   int64
+		exp string
+	}{
+		{0, "0 B"},
+		{1023, "1023 B"},
+		{1024, "1.0 KiB"},
+		{1536, "1.5 KiB"},
+		{1 << 20, "1.0 MiB"},
+		{5 << 30, "5.0 GiB"},
+	}
+
+	for _, tt := range tests {
+		if v := byteSize(tt.n); v != tt.exp {
+			t.Errorf("Expected size of %d: %v, got: %v", tt.n, tt.exp, v)
+		}
+	}
+}
+
+func TestProgress(t *testing.T) {
+	t.Parallel()
+
+	buf := &bytes.Buffer{}
+
+	p := &progress{w: buf, label: "download", total: 2048}
+
+	p.update(1024, false)
+
+	if exp := "\rdownload 1.0 KiB / 2.0 KiB (50%)"; buf.String() != exp {
+		t.Errorf("Expected progress: %q, got: %q", exp, buf.String())
+	}
+
+	// Updates are not displayed more often than the progress interval.
+	p.update(1, false)
+
+	if exp := "\rdownload 1.0 KiB / 2.0 KiB (50%)"; buf.String() != exp {
+		t.Errorf("Expected progress: %q, got: %q", exp, buf.String())
+	}
+
+	p.update(1023, false)
+
+	if !strings.HasSuffix(buf.String(),
+		"\rdownload 2.0 KiB / 2.0 KiB (100%)\n") {
+		t.Errorf("Expected completed progress, got: %q", buf.String())
+	}
+
+	n := buf.Len()
+
+	p.update(0, true)
+
+	if buf.Len() != n {
+		t.Errorf("Expected no progress after completion, got: %q",
+			buf.String())
+	}
+
+	buf.Reset()
+
+	p = &progress{w: buf, label: "upload", last: time.Now()}
+
+	p.update(10, false)
+
+	if buf.Len() != 0 {
+		t.Errorf("Expected no progress, got: %q", buf.String())
+	}
+
+	p.update(0, true)
+
+	if exp := "\rupload 10 B\n"; buf.String() != exp {
+		t.Errorf("Expected progress: %q, got: %q", exp, buf.String())
+	}
+}
+
+func TestProgressTransport(t *testing.T) {
+	t.Parallel()
+
+	ts := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			b, _ := io.ReadAll(r.Body)
+
+			_, _ = w.Write(b)
+		}))
+
+	t.Cleanup(ts.Close)
+
+	buf := &bytes.Buffer{}
+
+	cli := &http.Client{Transport: &progressTransport{
+		base: http.DefaultTransport, w: buf,
+	}}
+
+	res, err := cli.Post(ts.URL, "text/plain", strings.NewReader("test"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_ = res.Body.Close()
+
+	if string(b) != "test" {
+		t.Errorf("Expected body: test, got: %s", b)
+	}
+
+	exp := "\rupload 4 B / 4 B (100%)\n\rdownload 4 B / 4 B (100%)\n"
+	if buf.String() != exp {
+		t.Errorf("Expected progress: %q, got: %q", exp, buf.String())
+	}
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// redactedHeaders are the headers whose values are not displayed by verbose
+// output, because they contain credentials.
+var redactedHeaders = []string{"Authorization", "Cookie", "Set-Cookie"}
+
+// newTraceID returns a random W3C trace context trace ID.
+func newTraceID() string {
+	b := make([]byte, 16)
+
+	_, _ = rand.Read(b)
+
+	return hex.EncodeToString(b)
+}
+
+// traceParent returns a W3C traceparent header value for a request of a
+// trace, with a random parent ID, so that the trace is sampled by the server.
+func traceParent(traceID string) string {
+	b := make([]byte, 8)
+
+	_, _ = rand.Read(b)
+
+	return "00-" + traceID + "-" + hex.EncodeToString(b) + "-01"
+}
+
+// writeHeader displays the headers of a request or response, in order, each
+// with a prefix.
+func writeHeader(w io.Writer, prefix string, h http.Header) {
+	keys := make([]string, 0, len(h))
+
+	for k := range h {
+		keys = append(keys, k)
+	}
+
+	slices.Sort(keys)
+
+	for _, k := range keys {
+		for _, v := range h[k] {
+			if slices.Contains(redactedHeaders, k) {
+				v = "[redacted]"
+			}
+
+			fmt.Fprintf(w, "%s %s: %s\n", prefix, k, v)
+		}
+	}
+}
+
+// timing values record the times of the phases of a request.
+type timing struct {
+	sync.Mutex
+	start     time.Time
+	dnsStart  time.Time
+	dnsDone   time.Time
+	connStart time.Time
+	connDone  time.Time
+	tlsStart  time.Time
+	tlsDone   time.Time
+	firstByte time.Time
+	reused    bool
+}
+
+// clientTrace returns an HTTP client trace recording the times of a request.
+func (t *timing) clientTrace() *httptrace.ClientTrace {
+	now := func(v *time.Time) {
+		t.Lock()
+		defer t.Unlock()
+
+		*v = time.Now()
+	}
+
+	return &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) { now(&t.dnsStart) },
+		DNSDone:  func(httptrace.DNSDoneInfo) { now(&t.dnsDone) },
+		ConnectStart: func(string, string) {
+			t.Lock()
+			defer t.Unlock()
+
+			// Only the first connection attempt is timed.
+			if t.connStart.IsZero() {
+				t.connStart = time.Now()
+			}
+		},
+		ConnectDone:       func(string, string, error) { now(&t.connDone) },
+		TLSHandshakeStart: func() { now(&t.tlsStart) },
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			now(&t.tlsDone)
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.Lock()
+			defer t.Unlock()
+
+			t.reused = info.Reused
+		},
+		GotFirstResponseByte: func() { now(&t.firstByte) },
+	}
+}
+
+// String returns the durations of the phases of a request, which completed at
+// a time. Phases which did not occur, such as connecting on a reused
+// connection, are omitted.
+func (t *timing) String(done time.Time) string {
+	t.Lock()
+	defer t.Unlock()
+
+	var res []string
+
+	phase := func(name string, start, end time.Time) {
+		if !start.IsZero() && !end.IsZero() {
+			res = append(res, name+" "+end.Sub(start).Round(
+				time.Microsecond).String())
+		}
+	}
+
+	if t.reused {
+		res = append(res, "reused connection")
+	}
+
+	phase("dns", t.dnsStart, t.dnsDone)
+	phase("connect", t.connStart, t.connDone)
+	phase("tls", t.tlsStart, t.tlsDone)
+	phase("ttfb", t.start, t.firstByte)
+	phase("total", t.start, done)
+
+	return strings.Join(res, ", ")
+}
+
+// timingBody values display the timing of a request once its response body
+// is closed, so that the total includes the transfer of the body.
+type timingBody struct {
+	io.ReadCloser
+	w    io.Writer
+	t    *timing
+	once sync.Once
+}
+
+// Close closes the response body, and displays the timing of the request.
+func (b *timingBody) Close() error {
+	err := b.ReadCloser.Close()
+
+	b.once.Do(func() {
+		fmt.Fprintln(b.w, "* timing:", b.t.String(time.Now()))
+	})
+
+	return err
+}
+
+// traceTransport values are HTTP transports which display the headers and
+// timing of requests, and propagate a trace context, so that requests can be
+// correlated with the traces of the server.
+type traceTransport struct {
+	base    http.RoundTripper
+	w       io.Writer
+	verbose bool
+	timing  bool
+	traceID string
+}
+
+// RoundTrip sends a request, displaying its headers, and those of the
+// response, and the timing of the request.
+func (t *traceTransport) RoundTrip(req *http.Request) (*http.Response,
+	error,
+) {
+	req = req.Clone(req.Context())
+
+	if t.traceID != "" {
+		req.Header.Set("traceparent", traceParent(t.traceID))
+	}
+
+	var tm *timing
+
+	if t.timing {
+		tm = &timing{start: time.Now()}
+
+		req = req.WithContext(httptrace.WithClientTrace(req.Context(),
+			tm.clientTrace()))
+	}
+
+	if t.verbose {
+		fmt.Fprintf(t.w, "> %s %s %s\n", req.Method, req.URL.RequestURI(),
+			req.Proto)
+		fmt.Fprintf(t.w, "> Host: %s\n", req.URL.Host)
+
+		writeHeader(t.w, ">", req.Header)
+
+		fmt.Fprintln(t.w, ">")
+	}
+
+	res, err := t.base.RoundTrip(req)
+	if err != nil {
+		if tm != nil {
+			fmt.Fprintln(t.w, "* timing:", tm.String(time.Now()))
+		}
+
+		return res, err
+	}
+
+	if t.verbose {
+		fmt.Fprintf(t.w, "< %s %s\n", res.Proto, res.Status)
+
+		writeHeader(t.w, "<", res.Header)
+
+		fmt.Fprintln(t.w, "<")
+	}
+
+	if tm != nil {
+		res.Body = &timingBody{ReadCloser: res.Body, w: t.w, t: tm}
+	}
+
+	return res, nil
+}
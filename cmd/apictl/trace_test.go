@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTraceParent(t *testing.T) {
+	t.Parallel()
+
+	id := newTraceID()
+
+	if !regexp.MustCompile(`^[0-9a-f]{32}$`).MatchString(id) {
+		t.Errorf("Expected trace ID, got: %v", id)
+	}
+
+	if id == newTraceID() {
+		t.Error("Expected random trace IDs")
+	}
+
+	tp := traceParent(id)
+
+	if !regexp.MustCompile(`^00-` + id + `-[0-9a-f]{16}-01$`).
+		MatchString(tp) {
+		t.Errorf("Expected traceparent of trace %v, got: %v", id, tp)
+	}
+}
+
+func TestWriteHeader(t *testing.T) {
+	t.Parallel()
+
+	buf := &bytes.Buffer{}
+
+	writeHeader(buf, ">", http.Header{
+		"X-Test":        {"b", "a"},
+		"Authorization": {"Bearer test"},
+		"Accept":        {"application/json"},
+	})
+
+	exp := "> Accept: application/json\n" +
+		"> Authorization: [redacted]\n" +
+		"> X-Test: b\n" +
+		"> X-Test: a\n"
+
+	if buf.String() != exp {
+		t.Errorf("Expected headers: %q, got: %q", exp, buf.String())
+	}
+}
+
+func TestTimingString(t *testing.T) {
+	t.Parallel()
+
+	start := time.Now()
+
+	tm := &timing{
+		start:     start,
+		dnsStart:  start,
+		dnsDone:   start.Add(time.Millisecond),
+		connStart: start.Add(time.Millisecond),
+		connDone:  start.Add(3 * time.Millisecond),
+		firstByte: start.Add(10 * time.Millisecond),
+	}
+
+	exp := "dns 1ms, connect 2ms, ttfb 10ms, total 12ms"
+
+	if s := tm.String(start.Add(12 * time.Millisecond)); s != exp {
+		t.Errorf("Expected timing: %v, got: %v", exp, s)
+	}
+
+	tm = &timing{start: start, reused: true}
+
+	exp = "reused connection, total 5ms"
+
+	if s := tm.String(start.Add(5 * time.Millisecond)); s != exp {
+		t.Errorf("Expected timing: %v, got: %v", exp, s)
+	}
+}
+
+func TestTraceTransport(t *testing.T) {
+	t.Parallel()
+
+	traceParents := make(chan string, 1)
+
+	ts := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			traceParents <- r.Header.Get("traceparent")
+
+			w.Header().Set("Set-Cookie", "session=test")
+			w.Header().Set("X-Test", "test")
+
+			_, _ = w.Write([]byte("test"))
+		}))
+
+	t.Cleanup(ts.Close)
+
+	buf := &bytes.Buffer{}
+
+	id := newTraceID()
+
+	cli := &http.Client{Transport: &traceTransport{
+		base:    http.DefaultTransport,
+		w:       buf,
+		verbose: true,
+		timing:  true,
+		traceID: id,
+	}}
+
+	req, err := http.NewRequest(http.MethodGet, ts.URL+"/games?size=1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req.Header.Set("Authorization", "Bearer test")
+
+	res, err := cli.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := io.ReadAll(res.Body); err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Contains(buf.String(), "* timing:") {
+		t.Error("Expected timing to be displayed when the body is closed")
+	}
+
+	_ = res.Body.Close()
+	_ = res.Body.Close()
+
+	if tp := <-traceParents; !strings.HasPrefix(tp, "00-"+id+"-") {
+		t.Errorf("Expected traceparent of trace %v, got: %v", id, tp)
+	}
+
+	if req.Header.Get("traceparent") != "" {
+		t.Error("Expected request not to be modified")
+	}
+
+	out := buf.String()
+
+	for _, exp := range []string{
+		"> GET /games?size=1 HTTP/1.1\n",
+		"> Authorization: [redacted]\n",
+		"> Traceparent: 00-" + id + "-",
+		"< HTTP/1.1 200 OK\n",
+		"< Set-Cookie: [redacted]\n",
+		"< X-Test: test\n",
+		"* timing: ",
+	} {
+		if !strings.Contains(out, exp) {
+			t.Errorf("Expected output to contain: %q, got: %q", exp, out)
+		}
+	}
+
+	if n := strings.Count(out, "* timing: "); n != 1 {
+		t.Errorf("Expected timing once, got: %v", n)
+	}
+
+	if strings.Contains(out, "Bearer test") ||
+		strings.Contains(out, "session=test") {
+		t.Errorf("Expected credentials to be redacted, got: %q", out)
+	}
+}
@@ -4,12 +4,14 @@ package main
 import (
 	"context"
 	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
 	"reflect"
 	"syscall"
+	"text/tabwriter"
 
 	"github.com/dhaifley/game2d/config"
 	"github.com/dhaifley/game2d/errors"
@@ -62,6 +64,61 @@ func (s *Service) Handler() http.HandlerFunc {
 	return s.svr.Mux
 }
 
+// CheckConfig writes the effective configuration, with the source of each
+// setting, followed by any configuration problems found. It returns whether
+// the configuration is valid.
+func (s *Service) CheckConfig(w io.Writer) bool {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+
+	fmt.Fprintln(tw, "SETTING\tVALUE\tSOURCE")
+
+	for _, st := range s.cfg.Settings() {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", st.Env, st.Value, st.Source)
+	}
+
+	tw.Flush()
+
+	err := s.cfg.Validate()
+	if err == nil {
+		fmt.Fprintln(w, "\nconfiguration valid")
+
+		return true
+	}
+
+	fmt.Fprintln(w, "\nconfiguration invalid:")
+
+	var e *errors.Error
+
+	if errors.As(err, &e) {
+		for _, ev := range e.Errors {
+			fmt.Fprintf(w, "  %s: %s: %s\n", ev.Reason, ev.Data["env"], ev.Msg)
+		}
+	}
+
+	return false
+}
+
+// ExportSite exports the public gallery of an account, the pages of its public
+// games, and their embeds, as a static site in a directory.
+func (s *Service) ExportSite(ctx context.Context, accountID, dir string) error {
+	svr, err := server.NewServer(s.cfg, s.log, nil, nil)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	n, err := svr.ExportSite(ctx, accountID, dir)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("exported %d games to %s\n", n, dir)
+
+	return nil
+}
+
 // Start begins service operations.
 func (s *Service) Start(ctx context.Context) error {
 	var (
@@ -92,23 +149,20 @@ func (s *Service) Start(ctx context.Context) error {
 		}
 	}
 
+	if err := s.cfg.Validate(); err != nil {
+		s.log.Log(ctx, logger.LvlError,
+			"invalid configuration",
+			"error", err)
+	}
+
 	s.svr, err = server.NewServer(s.cfg, s.log, mr, tr)
 	if err != nil {
 		return err
 	}
 
-	go func(ctx context.Context, svr *server.Server) {
-		if err := svr.UpdateMetrics(ctx); err != nil {
-			s.log.Log(ctx, logger.LvlError,
-				"unable to emit server metrics",
-				"error", err)
-		}
-
-		svr.ConnectDB()
-		svr.UpdateAuthConfig()
-		svr.UpdateGameImports()
-		svr.UpdateGamePrompts()
-	}(ctx, s.svr)
+	if err := s.svr.Start(ctx); err != nil {
+		return err
+	}
 
 	return s.svr.Serve()
 }
@@ -246,6 +300,31 @@ func main() {
 		os.Exit(0)
 	}
 
+	if len(os.Args) > 1 && os.Args[1] == "check-config" {
+		if !svc.CheckConfig(os.Stdout) {
+			os.Exit(1)
+		}
+
+		os.Exit(0)
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "export-site" {
+		if len(os.Args) != 4 {
+			fmt.Fprintln(os.Stderr,
+				"usage: game2d-api export-site <account_id> <directory>")
+
+			os.Exit(2)
+		}
+
+		if err := svc.ExportSite(ctx, os.Args[2], os.Args[3]); err != nil {
+			slog.Error("export error", "error", err)
+
+			os.Exit(1)
+		}
+
+		os.Exit(0)
+	}
+
 	errCh := make(chan error, 1)
 
 	go func(ctx context.Context, errCh chan error) {
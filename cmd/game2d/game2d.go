@@ -2,6 +2,9 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
+	"fmt"
 	"os"
 	"strconv"
 
@@ -15,10 +18,29 @@ import (
 func main() {
 	ctx := context.Background()
 
+	prefs, _ := client.LoadPreferences()
+
+	opts, err := parseOptions(os.Args[1:], prefs)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(0)
+		}
+
+		fmt.Fprintln(os.Stderr, "ERROR:", err.Error())
+
+		os.Exit(2)
+	}
+
+	if opts.version {
+		fmt.Println(client.Version)
+
+		os.Exit(0)
+	}
+
 	log := logger.New(logger.OutStderr, logger.FmtJSON,
 		logger.LvlDebug)
 
-	gameID := os.Getenv("GAME2D_GAME_ID")
+	gameID := opts.gameID
 
 	if gameID == "" {
 		gameID = uuid.NewString()
@@ -26,8 +48,55 @@ func main() {
 
 	g := client.NewGame(log, -1, -1, gameID, "game2d", "A 2D gaming framework")
 
-	g.SetAPIURL(os.Getenv("GAME2D_API_URL"))
-	g.SetAPIToken(os.Getenv("GAME2D_API_TOKEN"))
+	g.SetAPIURL(opts.apiURL)
+	g.SetAPIToken(opts.token)
+	g.SetFile(opts.file)
+	g.SetFullscreen(opts.fullscreen)
+	g.SetScale(opts.scale)
+	g.SetPixelPerfect(opts.pixel)
+	g.SetLanguage(opts.language)
+	g.SetWatch(opts.watch)
+	g.SetPublish(opts.publish)
+
+	if err := g.SetSpectate(opts.spectate); err != nil {
+		fmt.Fprintln(os.Stderr, "ERROR:", err.Error())
+
+		os.Exit(2)
+	}
+
+	if opts.command == "package" {
+		if err := packageGame(g, opts.output); err != nil {
+			fmt.Fprintln(os.Stderr, "ERROR:", err.Error())
+
+			os.Exit(1)
+		}
+
+		os.Exit(0)
+	}
+
+	if opts.command == "test" {
+		passed, err := testGame(g, opts.input, opts.golden, opts.update)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "ERROR:", err.Error())
+
+			os.Exit(2)
+		}
+
+		if !passed {
+			os.Exit(1)
+		}
+
+		os.Exit(0)
+	}
+
+	if data, err := bundledGame(); err != nil {
+		log.Log(ctx, logger.LvlWarn,
+			"unable to read bundled game",
+			"error", err)
+	} else if data != nil {
+		g.SetData(data)
+	}
+
 	initJS(g)
 
 	ib, err := assets.GetImage("avatar.svg")
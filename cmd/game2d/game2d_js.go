@@ -57,4 +57,28 @@ func initJS(g *client.Game) {
 	}
 
 	js.Global().Set("setAPIToken", js.FuncOf(setAPIToken))
+
+	setGameData := func(this js.Value, args []js.Value) any {
+		if len(args) < 1 {
+			return 1
+		}
+
+		g.SetData([]byte(args[0].String()))
+
+		return 0
+	}
+
+	js.Global().Set("setGameData", js.FuncOf(setGameData))
+
+	// Game events are posted to the parent window, so that pages which embed
+	// the game can respond to them.
+	g.SetEventHandler(func(event string, data map[string]any) {
+		msg := map[string]any{"type": "game2d:" + event}
+
+		for k, v := range data {
+			msg[k] = v
+		}
+
+		js.Global().Get("parent").Call("postMessage", msg, "*")
+	})
 }
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dhaifley/game2d/client"
+)
+
+// Usage details.
+const Usage = `Usage: game2d [<option>...] [<file>]
+       game2d package --output <output> [<option>...] [<file>]
+       game2d test [--input <input>] [--golden <golden>] [--update]
+[<option>...] [<file>]
+
+Runs a game2d game. The game is loaded, in order of precedence, from the game
+file, if one is specified, or from the game2d API, if an API URL is specified,
+or from the game2d.json file in the current directory.
+
+Commands:
+  package = Bundle the game with the client into a standalone executable,
+written to the output file, which runs the game without the game2d API
+  test = Run the visual regression test of the game, described by the input
+file, without a display, and compare the rendered frames to the golden images,
+exiting with a non-zero status if any of them differ
+
+Arguments:
+  <file> = Optional, local path or HTTPS URL of a game definition to load, the
+same as --file
+
+Options:
+  --help = Display this usage message
+  --output = Path of the standalone executable written by the package command
+  --input = Path of the JSON test script run by the test command, containing
+the seed, frame count, key inputs and snapshot frames of the test, which
+defaults to game2d_test.json
+  --golden = Directory of the golden images used by the test command, which
+defaults to golden
+  --update = Write the golden images of the test command, instead of comparing
+the rendered frames to them
+  --version = Display the client version
+  --game-id = ID of the game to load from the API (GAME2D_GAME_ID)
+  --api-url = Base URL of the game2d API (GAME2D_API_URL)
+  --token = Authentication token for the game2d API (GAME2D_API_TOKEN)
+  --file = Local path or HTTPS URL of a game definition to load
+(GAME2D_GAME_FILE)
+  --fullscreen = Start the game in fullscreen mode (GAME2D_FULLSCREEN)
+  --scale = Scale of the game window relative to the game size (GAME2D_SCALE)
+  --pixel-perfect = Render the game scaled by whole numbers only
+(GAME2D_PIXEL_PERFECT)
+  --language = Language tag of the locale used for the text of the game, for
+example fr or pt-BR, which defaults to the system language (GAME2D_LANGUAGE)
+  --watch = Interval at which to check the game definition for changes, and
+apply them to the running game, for example 1s (GAME2D_WATCH)
+  --publish = Publish the live game state, while playing from the API, so that
+other players may spectate (GAME2D_PUBLISH)
+  --spectate = ID of a play session of the game to spectate, rendering the
+live game state published by its player (GAME2D_SPECTATE)
+
+Options take precedence over the environment variables shown in parentheses,
+which take precedence over the display and language preferences saved by the
+client.
+
+Keys:
+  Ctrl+S = Save the game
+  Ctrl+L = Load the game
+  Ctrl+P = Open the pause menu
+  Ctrl+Q = Reset the game
+  Ctrl+' = Toggle debug information
+  Ctrl+G = Open the game gallery
+  Ctrl+B = Open the key bindings menu
+  Ctrl+F = Toggle fullscreen mode
+  Ctrl+I = Toggle pixel-perfect mode
+  Ctrl+= = Increase the window scale
+  Ctrl+- = Decrease the window scale`
+
+// options values represent the client command-line options.
+type options struct {
+	gameID     string
+	apiURL     string
+	token      string
+	file       string
+	fullscreen bool
+	pixel      bool
+	scale      float64
+	language   string
+	watch      time.Duration
+	publish    bool
+	spectate   string
+	command    string
+	output     string
+	input      string
+	golden     string
+	update     bool
+	version    bool
+}
+
+// parseOptions parses the client options from the command-line arguments,
+// using the values of environment variables, and then the saved display and
+// language preferences, as defaults.
+func parseOptions(args []string, prefs *client.Preferences) (*options, error) {
+	if prefs == nil {
+		prefs = &client.Preferences{Scale: client.DefaultScale}
+	}
+
+	opts := &options{
+		gameID:     os.Getenv("GAME2D_GAME_ID"),
+		apiURL:     os.Getenv("GAME2D_API_URL"),
+		token:      os.Getenv("GAME2D_API_TOKEN"),
+		file:       os.Getenv("GAME2D_GAME_FILE"),
+		spectate:   os.Getenv("GAME2D_SPECTATE"),
+		fullscreen: prefs.Fullscreen,
+		pixel:      prefs.PixelPerfect,
+		scale:      prefs.Scale,
+		language:   prefs.Language,
+	}
+
+	if opts.language == "" {
+		opts.language = systemLanguage()
+	}
+
+	if v := os.Getenv("GAME2D_LANGUAGE"); v != "" {
+		opts.language = v
+	}
+
+	if v := os.Getenv("GAME2D_FULLSCREEN"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid GAME2D_FULLSCREEN: %w", err)
+		}
+
+		opts.fullscreen = b
+	}
+
+	if v := os.Getenv("GAME2D_PIXEL_PERFECT"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid GAME2D_PIXEL_PERFECT: %w", err)
+		}
+
+		opts.pixel = b
+	}
+
+	if v := os.Getenv("GAME2D_SCALE"); v != "" {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid GAME2D_SCALE: %w", err)
+		}
+
+		opts.scale = f
+	}
+
+	if v := os.Getenv("GAME2D_WATCH"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid GAME2D_WATCH: %w", err)
+		}
+
+		opts.watch = d
+	}
+
+	if v := os.Getenv("GAME2D_PUBLISH"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid GAME2D_PUBLISH: %w", err)
+		}
+
+		opts.publish = b
+	}
+
+	if len(args) > 0 && (args[0] == "package" || args[0] == "test") {
+		opts.command = args[0]
+		args = args[1:]
+	}
+
+	fs := flag.NewFlagSet("game2d", flag.ContinueOnError)
+
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), Usage)
+	}
+
+	fs.StringVar(&opts.gameID, "game-id", opts.gameID, "")
+	fs.StringVar(&opts.apiURL, "api-url", opts.apiURL, "")
+	fs.StringVar(&opts.token, "token", opts.token, "")
+	fs.StringVar(&opts.file, "file", opts.file, "")
+	fs.BoolVar(&opts.fullscreen, "fullscreen", opts.fullscreen, "")
+	fs.Float64Var(&opts.scale, "scale", opts.scale, "")
+	fs.BoolVar(&opts.pixel, "pixel-perfect", opts.pixel, "")
+	fs.StringVar(&opts.language, "language", opts.language, "")
+	fs.DurationVar(&opts.watch, "watch", opts.watch, "")
+	fs.BoolVar(&opts.publish, "publish", opts.publish, "")
+	fs.StringVar(&opts.spectate, "spectate", opts.spectate, "")
+	fs.StringVar(&opts.output, "output", "", "")
+	fs.StringVar(&opts.input, "input", client.DefaultTestFile, "")
+	fs.StringVar(&opts.golden, "golden", client.DefaultGoldenDir, "")
+	fs.BoolVar(&opts.update, "update", false, "")
+	fs.BoolVar(&opts.version, "version", false, "")
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	switch fs.NArg() {
+	case 0:
+	case 1:
+		opts.file = fs.Arg(0)
+	default:
+		return nil, fmt.Errorf("too many arguments: %v", fs.Args())
+	}
+
+	if opts.command == "package" && opts.output == "" {
+		return nil, fmt.Errorf("missing package output file")
+	}
+
+	if opts.scale <= 0 || opts.scale > client.MaxScale {
+		return nil, fmt.Errorf("invalid scale: %v", opts.scale)
+	}
+
+	return opts, nil
+}
+
+// systemLanguage returns the language tag of the system locale, from the LANG
+// environment variable, for example fr-FR for fr_FR.UTF-8.
+func systemLanguage() string {
+	v, _, _ := strings.Cut(os.Getenv("LANG"), ".")
+	v, _, _ = strings.Cut(v, "@")
+
+	if v == "C" || v == "POSIX" {
+		return ""
+	}
+
+	return strings.ReplaceAll(v, "_", "-")
+}
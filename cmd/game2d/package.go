@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+
+	"github.com/dhaifley/game2d/client"
+)
+
+// bundleMagic identifies a game definition bundled with the client executable.
+// A bundled executable consists of the client executable, followed by the game
+// definition, its length as a little-endian uint64, and then bundleMagic.
+const bundleMagic = "game2dpk"
+
+// bundleTrailerSize is the size of the data which follows the bundled game.
+const bundleTrailerSize = 8 + len(bundleMagic)
+
+// readBundle returns the size of the client executable in a file, and the game
+// definition bundled with it, if there is one.
+func readBundle(f *os.File) (int64, []byte, error) {
+	fi, err := f.Stat()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	size := fi.Size()
+
+	if size < int64(bundleTrailerSize) {
+		return size, nil, nil
+	}
+
+	trailer := make([]byte, bundleTrailerSize)
+
+	if _, err := f.ReadAt(trailer, size-int64(bundleTrailerSize)); err != nil {
+		return 0, nil, err
+	}
+
+	if !bytes.Equal(trailer[8:], []byte(bundleMagic)) {
+		return size, nil, nil
+	}
+
+	n := int64(binary.LittleEndian.Uint64(trailer[:8]))
+
+	exe := size - int64(bundleTrailerSize) - n
+	if n <= 0 || exe < 0 {
+		return 0, nil, fmt.Errorf("invalid bundled game size: %d", n)
+	}
+
+	data := make([]byte, n)
+
+	if _, err := f.ReadAt(data, exe); err != nil {
+		return 0, nil, err
+	}
+
+	return exe, data, nil
+}
+
+// bundledGame returns the game definition bundled with the running client
+// executable, or nil if there is none.
+func bundledGame() ([]byte, error) {
+	if runtime.GOOS == "js" {
+		return nil, nil
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(exe)
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	_, data, err := readBundle(f)
+
+	return data, err
+}
+
+// writeBundle writes a standalone executable, consisting of the running client
+// executable bundled with a game definition, to the output file.
+func writeBundle(output string, data []byte) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("unable to find client executable: %w", err)
+	}
+
+	in, err := os.Open(exe)
+	if err != nil {
+		return fmt.Errorf("unable to read client executable: %w", err)
+	}
+
+	defer in.Close()
+
+	size, _, err := readBundle(in)
+	if err != nil {
+		return fmt.Errorf("unable to read client executable: %w", err)
+	}
+
+	out, err := os.OpenFile(output, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o755)
+	if err != nil {
+		return fmt.Errorf("unable to create package: %w", err)
+	}
+
+	trailer := binary.LittleEndian.AppendUint64(nil, uint64(len(data)))
+	trailer = append(trailer, bundleMagic...)
+
+	if _, err := io.Copy(out, io.NewSectionReader(in, 0, size)); err != nil {
+		out.Close()
+
+		return fmt.Errorf("unable to write package: %w", err)
+	}
+
+	if _, err := out.Write(append(data, trailer...)); err != nil {
+		out.Close()
+
+		return fmt.Errorf("unable to write package: %w", err)
+	}
+
+	if err := out.Close(); err != nil {
+		return fmt.Errorf("unable to write package: %w", err)
+	}
+
+	return nil
+}
+
+// packageGame loads a game, and writes it, bundled with the client, as a
+// standalone executable to the output file.
+func packageGame(g *client.Game, output string) error {
+	if output == "" {
+		return fmt.Errorf("missing package output file")
+	}
+
+	if err := g.Load(); err != nil {
+		return fmt.Errorf("unable to load game: %w", err)
+	}
+
+	b, err := json.Marshal(g)
+	if err != nil {
+		return fmt.Errorf("unable to encode game: %w", err)
+	}
+
+	return writeBundle(output, b)
+}
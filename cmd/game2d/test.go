@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/dhaifley/game2d/client"
+)
+
+// testGame loads a game, runs its visual regression test, and prints the
+// result of each snapshot frame. It returns whether all of the frames matched
+// their golden images.
+func testGame(g *client.Game, input, golden string, update bool,
+) (bool, error) {
+	ts, err := client.LoadTestScript(input)
+	if err != nil {
+		return false, fmt.Errorf("unable to load test: %w", err)
+	}
+
+	if err := g.Load(); err != nil {
+		return false, fmt.Errorf("unable to load game: %w", err)
+	}
+
+	res, err := g.RunTest(ts, golden, update)
+	if err != nil {
+		return false, fmt.Errorf("unable to run test: %w", err)
+	}
+
+	passed := true
+
+	for _, r := range res {
+		switch {
+		case r.Updated:
+			fmt.Printf("UPDATE frame %d: %s\n", r.Frame, r.Golden)
+		case r.Passed():
+			fmt.Printf("PASS   frame %d: %s\n", r.Frame, r.Golden)
+		default:
+			passed = false
+
+			fmt.Printf("FAIL   frame %d: %s: %s\n", r.Frame, r.Golden,
+				r.Failure)
+		}
+	}
+
+	return passed, nil
+}
@@ -0,0 +1,211 @@
+// loadtest is a command-line utility for load testing the API.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/dhaifley/game2d/client/api"
+	"github.com/dhaifley/game2d/loadtest"
+)
+
+// Version information.
+var Version = "0.1.1"
+
+// DefaultAPIURL is the base URL of the API tested, if no other URL is
+// specified.
+const DefaultAPIURL = "http://localhost:8080/api/v1"
+
+// Usage details.
+const Usage = `Usage: loadtest [<option>...]
+
+Runs a load test against the game2d API, from many concurrent workers, each of
+which creates a game, and then lists games, retrieves, saves and prompts its
+game, in proportion to the mix of operations, until the test duration has
+elapsed. The latency percentiles and error rates of each operation are then
+printed, and checked against the performance budgets. The final line of the
+output is PASS, or FAIL, in which case the exit status is 1.
+
+Options:
+  --help = Display this usage message
+  --version = Display the command version
+  --api-url = Base URL of the game2d API, which defaults to
+http://localhost:8080/api/v1 (GAME2D_API_URL)
+  --token = Authentication token for the game2d API (GAME2D_API_TOKEN)
+  --username = User name used to log in, if no token is specified
+(GAME2D_API_USERNAME)
+  --password = Password used to log in, if no token is specified
+(GAME2D_API_PASSWORD)
+  --workers = Number of concurrent workers, which defaults to 4
+  --duration = Duration of the test, which defaults to 30s
+  --requests = Optional, number of requests after which the test stops
+  --mix = Relative weights of the operations list_games, get_game, save_game
+and prompt, which defaults to list_games=40,get_game=40,save_game=15,prompt=5
+  --game-size = Size, in bytes, of the image data of saved games, which
+defaults to 1048576
+  --prompt-timeout = Time allowed for prompts to complete, which defaults to 30s
+  --budget = Performance budget of an operation, or of all operations, which
+may be repeated, for example get_game:p95=100ms,p99=250ms,errors=0.01, where
+the limits are any of p50, p90, p95, p99, max and errors, the fraction of
+requests allowed to fail, which is zero by default. Default budgets are used
+if none are specified
+  --seed = Seed of the random choice of operations
+  --format = (text|json) Format of the output`
+
+// options values represent the command-line options.
+type options struct {
+	apiURL   string
+	token    string
+	username string
+	password string
+	format   string
+	version  bool
+	test     loadtest.Options
+}
+
+// parseOptions parses the options from the command-line arguments, using the
+// values of environment variables as defaults.
+func parseOptions(args []string) (*options, error) {
+	opts := &options{
+		apiURL:   os.Getenv("GAME2D_API_URL"),
+		token:    os.Getenv("GAME2D_API_TOKEN"),
+		username: os.Getenv("GAME2D_API_USERNAME"),
+		password: os.Getenv("GAME2D_API_PASSWORD"),
+	}
+
+	if opts.apiURL == "" {
+		opts.apiURL = DefaultAPIURL
+	}
+
+	budgets := map[string]*loadtest.Budget{}
+
+	fs := flag.NewFlagSet("loadtest", flag.ContinueOnError)
+
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), Usage)
+	}
+
+	fs.StringVar(&opts.apiURL, "api-url", opts.apiURL, "")
+	fs.StringVar(&opts.token, "token", opts.token, "")
+	fs.StringVar(&opts.username, "username", opts.username, "")
+	fs.StringVar(&opts.password, "password", opts.password, "")
+	fs.IntVar(&opts.test.Workers, "workers", loadtest.DefaultWorkers, "")
+	fs.DurationVar(&opts.test.Duration, "duration", loadtest.DefaultDuration,
+		"")
+	fs.IntVar(&opts.test.Requests, "requests", 0, "")
+	fs.IntVar(&opts.test.GameSize, "game-size", loadtest.DefaultGameSize, "")
+	fs.DurationVar(&opts.test.PromptTimeout, "prompt-timeout",
+		loadtest.DefaultPromptTimeout, "")
+	fs.Int64Var(&opts.test.Seed, "seed", time.Now().UnixNano(), "")
+	fs.StringVar(&opts.format, "format", "text", "")
+	fs.BoolVar(&opts.version, "version", false, "")
+
+	fs.Func("mix", "", func(v string) error {
+		mix, err := loadtest.ParseMix(v)
+		if err != nil {
+			return err
+		}
+
+		opts.test.Mix = mix
+
+		return nil
+	})
+
+	fs.Func("budget", "", func(v string) error {
+		op, b, err := loadtest.ParseBudget(v)
+		if err != nil {
+			return err
+		}
+
+		budgets[op] = b
+
+		return nil
+	})
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if fs.NArg() > 0 {
+		return nil, fmt.Errorf("unexpected argument: %s", fs.Arg(0))
+	}
+
+	switch opts.format {
+	case "text", "json":
+	default:
+		return nil, fmt.Errorf("invalid format: %s", opts.format)
+	}
+
+	opts.test.Budgets = budgets
+
+	if len(budgets) == 0 {
+		opts.test.Budgets = loadtest.DefaultBudgets()
+	}
+
+	return opts, nil
+}
+
+// Run a load test against the API.
+func main() {
+	opts, err := parseOptions(os.Args[1:])
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(0)
+		}
+
+		fmt.Fprintln(os.Stderr, "ERROR:", err.Error())
+
+		os.Exit(2)
+	}
+
+	if opts.version {
+		fmt.Println(Version)
+
+		os.Exit(0)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	c := api.New(opts.apiURL, api.WithToken(opts.token),
+		api.WithUserAgent("loadtest/"+Version))
+
+	if opts.token == "" && opts.username != "" {
+		if _, err := c.Login(ctx, opts.username, opts.password); err != nil {
+			fmt.Fprintln(os.Stderr, "ERROR: unable to log in:", err.Error())
+
+			os.Exit(2)
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "Running load test against %s with %d workers "+
+		"for %s\n", opts.apiURL, opts.test.Workers, opts.test.Duration)
+
+	rep, err := loadtest.Run(ctx, c, &opts.test)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "ERROR:", err.Error())
+
+		os.Exit(2)
+	}
+
+	if opts.format == "json" {
+		err = rep.WriteJSON(os.Stdout)
+	} else {
+		err = rep.Write(os.Stdout)
+	}
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "ERROR:", err.Error())
+
+		os.Exit(2)
+	}
+
+	if !rep.Passed() {
+		os.Exit(1)
+	}
+}
@@ -21,6 +21,8 @@ const (
 	KeyAuthTokenIssuer           = "auth/token/issuer"
 	KeyAuthUpdateInterval        = "auth/update_interval"
 	KeyAuthIdentityDomain        = "auth/identity_domain"
+	KeyAuthBootstrapToken        = "auth/bootstrap_token"
+	KeyAuthAPIKeyExpiresIn       = "auth/api_key/expires_in"
 
 	DefaultAuthTokenJWKS             = "{}"
 	DefaultAuthTokenWellKnown        = ""
@@ -29,6 +31,8 @@ const (
 	DefaultAuthTokenIssuer           = "game2d"
 	DefaultAuthUpdateInterval        = time.Second * 30
 	DefaultAuthIdentityDomain        = ""
+	DefaultAuthBootstrapToken        = ""
+	DefaultAuthAPIKeyExpiresIn       = time.Hour * 24 * 365
 )
 
 // AuthConfig values represent authentication configuration data.
@@ -43,23 +47,27 @@ type AuthConfig struct {
 	TokenIssuer           string        `json:"token_issuer,omitempty"             yaml:"token_issuer,omitempty"`
 	UpdateInterval        time.Duration `json:"update_interval,omitempty"          yaml:"update_interval,omitempty"`
 	IdentityDomain        string        `json:"identity_domain,omitempty"          yaml:"identity_domain,omitempty"`
+	BootstrapToken        string        `json:"bootstrap_token,omitempty"          yaml:"bootstrap_token,omitempty"`
+	APIKeyExpiresIn       time.Duration `json:"api_key_expires_in,omitempty"       yaml:"api_key_expires_in,omitempty"`
 }
 
 // Load reads configuration data from environment variables and applies defaults
 // for any missing or invalid configuration data.
 func (c *AuthConfig) Load() {
-	if v := os.Getenv(ReplaceEnv(KeyAuthTokenHMACKey)); v != "" {
+	if v := getEnv(KeyAuthTokenHMACKey); v != "" {
 		if _, err := hex.Decode(c.TokenHMACKey, []byte(v)); err != nil {
 			c.TokenHMACKey = []byte{}
 		}
 	}
 
-	if v := os.Getenv(ReplaceEnv(KeyAuthTokenPrivateKey)); v != "" {
+	if v := getEnv(KeyAuthTokenPrivateKey); v != "" {
 		if _, err := hex.Decode(c.TokenPrivateKey, []byte(v)); err != nil {
 			c.TokenPrivateKey = []byte{}
 		}
 	}
 
+	// The public key file is not read as a secret file variant of the public
+	// key, since it contains the key itself rather than its hex encoding.
 	if v := os.Getenv(ReplaceEnv(KeyAuthTokenPublicKey)); v != "" {
 		if _, err := hex.Decode(c.TokenPublicKey, []byte(v)); err != nil {
 			c.TokenPublicKey = []byte{}
@@ -67,14 +75,14 @@ func (c *AuthConfig) Load() {
 	}
 
 	if len(c.TokenPublicKey) == 0 {
-		if f := os.Getenv(ReplaceEnv(KeyAuthTokenPublicKeyFile)); f != "" {
+		if f := getEnv(KeyAuthTokenPublicKeyFile); f != "" {
 			if pk, err := os.ReadFile(f); err == nil && len(pk) > 0 {
 				c.TokenPublicKey = pk
 			}
 		}
 	}
 
-	if v := os.Getenv(ReplaceEnv(KeyAuthTokenJWKS)); v != "" {
+	if v := getEnv(KeyAuthTokenJWKS); v != "" {
 		c.TokenJWKS = v
 	}
 
@@ -82,7 +90,7 @@ func (c *AuthConfig) Load() {
 		c.TokenJWKS = DefaultAuthTokenJWKS
 	}
 
-	if v := os.Getenv(ReplaceEnv(KeyAuthTokenWellKnown)); v != "" {
+	if v := getEnv(KeyAuthTokenWellKnown); v != "" {
 		c.TokenWellKnown = v
 	}
 
@@ -90,7 +98,7 @@ func (c *AuthConfig) Load() {
 		c.TokenWellKnown = DefaultAuthTokenWellKnown
 	}
 
-	if v := os.Getenv(ReplaceEnv(KeyAuthTokenExpiresIn)); v != "" {
+	if v := getEnv(KeyAuthTokenExpiresIn); v != "" {
 		v, err := time.ParseDuration(v)
 		if err != nil {
 			v = DefaultAuthTokenExpiresIn
@@ -103,7 +111,7 @@ func (c *AuthConfig) Load() {
 		c.TokenExpiresIn = DefaultAuthTokenExpiresIn
 	}
 
-	if v := os.Getenv(ReplaceEnv(KeyAuthTokenRefreshExpiresIn)); v != "" {
+	if v := getEnv(KeyAuthTokenRefreshExpiresIn); v != "" {
 		v, err := time.ParseDuration(v)
 		if err != nil {
 			v = DefaultAuthTokenRefreshExpiresIn
@@ -116,7 +124,7 @@ func (c *AuthConfig) Load() {
 		c.TokenRefreshExpiresIn = DefaultAuthTokenRefreshExpiresIn
 	}
 
-	if v := os.Getenv(ReplaceEnv(KeyAuthTokenIssuer)); v != "" {
+	if v := getEnv(KeyAuthTokenIssuer); v != "" {
 		c.TokenIssuer = v
 	}
 
@@ -124,7 +132,7 @@ func (c *AuthConfig) Load() {
 		c.TokenIssuer = DefaultAuthTokenIssuer
 	}
 
-	if v := os.Getenv(ReplaceEnv(KeyAuthUpdateInterval)); v != "" {
+	if v := getEnv(KeyAuthUpdateInterval); v != "" {
 		v, err := time.ParseDuration(v)
 		if err != nil {
 			v = DefaultAuthUpdateInterval
@@ -137,13 +145,30 @@ func (c *AuthConfig) Load() {
 		c.UpdateInterval = DefaultAuthUpdateInterval
 	}
 
-	if v := os.Getenv(ReplaceEnv(KeyAuthIdentityDomain)); v != "" {
+	if v := getEnv(KeyAuthIdentityDomain); v != "" {
 		c.IdentityDomain = v
 	}
 
 	if c.IdentityDomain == "" {
 		c.IdentityDomain = DefaultAuthIdentityDomain
 	}
+
+	if v := getEnv(KeyAuthBootstrapToken); v != "" {
+		c.BootstrapToken = v
+	}
+
+	if v := getEnv(KeyAuthAPIKeyExpiresIn); v != "" {
+		v, err := time.ParseDuration(v)
+		if err != nil {
+			v = DefaultAuthAPIKeyExpiresIn
+		}
+
+		c.APIKeyExpiresIn = v
+	}
+
+	if c.APIKeyExpiresIn == 0 {
+		c.APIKeyExpiresIn = DefaultAuthAPIKeyExpiresIn
+	}
 }
 
 // AuthTokenHMACKey returns the HMAC key used for token encryption.
@@ -301,6 +326,31 @@ func (c *Config) AuthIdentityDomain() string {
 	return c.auth.IdentityDomain
 }
 
+// AuthBootstrapToken returns the token required to bootstrap the initial
+// account and superuser. Bootstrapping is disabled if it is empty.
+func (c *Config) AuthBootstrapToken() string {
+	c.RLock()
+	defer c.RUnlock()
+
+	if c.auth == nil {
+		return DefaultAuthBootstrapToken
+	}
+
+	return c.auth.BootstrapToken
+}
+
+// AuthAPIKeyExpiresIn returns the duration of time an API key is valid.
+func (c *Config) AuthAPIKeyExpiresIn() time.Duration {
+	c.RLock()
+	defer c.RUnlock()
+
+	if c.auth == nil {
+		return DefaultAuthAPIKeyExpiresIn
+	}
+
+	return c.auth.APIKeyExpiresIn
+}
+
 // SetAuth applies authentication configuration data to the configuration.
 func (c *Config) SetAuthTokenJWKS(jwks map[string]*rsa.PublicKey) {
 	buf := &bytes.Buffer{}
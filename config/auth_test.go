@@ -26,6 +26,8 @@ func TestAuthConfig(t *testing.T) {
 		TokenIssuer:           exp,
 		UpdateInterval:        time.Second,
 		IdentityDomain:        exp,
+		BootstrapToken:        exp,
+		APIKeyExpiresIn:       time.Hour,
 	})
 
 	cfg.SetAuthTokenJWKS(map[string]*rsa.PublicKey{})
@@ -70,4 +72,14 @@ func TestAuthConfig(t *testing.T) {
 		t.Errorf("Expected identity domain: %v, got: %v",
 			exp, cfg.AuthIdentityDomain())
 	}
+
+	if cfg.AuthBootstrapToken() != exp {
+		t.Errorf("Expected bootstrap token: %v, got: %v",
+			exp, cfg.AuthBootstrapToken())
+	}
+
+	if cfg.AuthAPIKeyExpiresIn() != time.Hour {
+		t.Errorf("Expected API key expiration: 1h, got: %v",
+			cfg.AuthAPIKeyExpiresIn())
+	}
 }
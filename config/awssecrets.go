@@ -0,0 +1,198 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dhaifley/game2d/errors"
+)
+
+// SchemeAWSSecrets is the scheme of references to secrets held in AWS Secrets
+// Manager, such as awssm://game2d/db#connection. If a field is specified, the
+// secret must be a JSON object containing it.
+const SchemeAWSSecrets = "awssm"
+
+// AWS Secrets Manager request values.
+const (
+	awsSecretsService   = "secretsmanager"
+	awsSecretsAlgorithm = "AWS4-HMAC-SHA256"
+	awsSecretsTarget    = "secretsmanager.GetSecretValue"
+	awsSecretsType      = "application/x-amz-json-1.1"
+)
+
+// awsSecretsResolver values resolve secrets held in AWS Secrets Manager using
+// signed requests to its API.
+type awsSecretsResolver struct {
+	region       string
+	accessKey    string
+	secretKey    string
+	sessionToken string
+	endpoint     string
+	cli          *http.Client
+}
+
+// NewAWSSecretsResolver creates a secret resolver for AWS Secrets Manager in a
+// region, authenticating with access keys. The session token is optional.
+func NewAWSSecretsResolver(region, accessKey, secretKey,
+	sessionToken string,
+) SecretResolver {
+	return &awsSecretsResolver{
+		region:       region,
+		accessKey:    accessKey,
+		secretKey:    secretKey,
+		sessionToken: sessionToken,
+		endpoint: "https://" + awsSecretsService + "." + region +
+			".amazonaws.com",
+		cli: &http.Client{Timeout: time.Second * 10},
+	}
+}
+
+// hmacSHA256 returns the HMAC-SHA256 of data using a key.
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+
+	h.Write([]byte(data))
+
+	return h.Sum(nil)
+}
+
+// ResolveSecret resolves a reference to a secret held in AWS Secrets Manager.
+func (r *awsSecretsResolver) ResolveSecret(ctx context.Context,
+	ref string,
+) (string, error) {
+	id, field := splitSecretRef(ref)
+
+	body, err := json.Marshal(map[string]string{"SecretId": id})
+	if err != nil {
+		return "", errors.Wrap(err, errors.ErrClient,
+			"unable to encode secrets manager request",
+			"secret_id", id)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		r.endpoint+"/", bytes.NewReader(body))
+	if err != nil {
+		return "", errors.Wrap(err, errors.ErrClient,
+			"unable to create secrets manager request",
+			"secret_id", id)
+	}
+
+	r.sign(req, body, time.Now().UTC())
+
+	res, err := r.cli.Do(req)
+	if err != nil {
+		return "", errors.Wrap(err, errors.ErrClient,
+			"unable to send secrets manager request",
+			"secret_id", id)
+	}
+
+	defer res.Body.Close()
+
+	b, err := io.ReadAll(res.Body)
+	if err != nil {
+		return "", errors.Wrap(err, errors.ErrClient,
+			"unable to read secrets manager response",
+			"secret_id", id)
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return "", errors.New(errors.ErrClient,
+			"unexpected secrets manager response status",
+			"secret_id", id,
+			"status", res.StatusCode)
+	}
+
+	var v struct {
+		SecretString string `json:"SecretString"`
+	}
+
+	if err := json.Unmarshal(b, &v); err != nil {
+		return "", errors.Wrap(err, errors.ErrClient,
+			"unable to decode secrets manager response",
+			"secret_id", id)
+	}
+
+	if field == "" {
+		return v.SecretString, nil
+	}
+
+	data := map[string]any{}
+
+	if err := json.Unmarshal([]byte(v.SecretString), &data); err != nil {
+		return "", errors.Wrap(err, errors.ErrInvalidRequest,
+			"secret is not a JSON object",
+			"secret_id", id)
+	}
+
+	return secretField(data, id, field)
+}
+
+// sign adds AWS signature version 4 authentication headers to a request.
+func (r *awsSecretsResolver) sign(req *http.Request, body []byte,
+	now time.Time,
+) {
+	sum := sha256.Sum256(body)
+
+	payloadHash := hex.EncodeToString(sum[:])
+
+	amzDate := now.Format("20060102T150405Z")
+
+	scope := now.Format("20060102") + "/" + r.region + "/" +
+		awsSecretsService + "/aws4_request"
+
+	req.Header.Set("Content-Type", awsSecretsType)
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Target", awsSecretsTarget)
+
+	headers := []string{
+		"content-type:" + awsSecretsType,
+		"host:" + req.URL.Host,
+		"x-amz-date:" + amzDate,
+	}
+
+	signedHeaders := "content-type;host;x-amz-date"
+
+	if r.sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", r.sessionToken)
+
+		headers = append(headers, "x-amz-security-token:"+r.sessionToken)
+		signedHeaders += ";x-amz-security-token"
+	}
+
+	headers = append(headers, "x-amz-target:"+awsSecretsTarget)
+	signedHeaders += ";x-amz-target"
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		"/",
+		"",
+		strings.Join(headers, "\n") + "\n",
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	crh := sha256.Sum256([]byte(canonicalRequest))
+
+	stringToSign := awsSecretsAlgorithm + "\n" + amzDate + "\n" + scope +
+		"\n" + hex.EncodeToString(crh[:])
+
+	sk := hmacSHA256([]byte("AWS4"+r.secretKey), now.Format("20060102"))
+	sk = hmacSHA256(sk, r.region)
+	sk = hmacSHA256(sk, awsSecretsService)
+	sk = hmacSHA256(sk, "aws4_request")
+
+	signature := hex.EncodeToString(hmacSHA256(sk, stringToSign))
+
+	req.Header.Set("Authorization", awsSecretsAlgorithm+
+		" Credential="+r.accessKey+"/"+scope+
+		", SignedHeaders="+signedHeaders+
+		", Signature="+signature)
+}
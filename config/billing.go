@@ -0,0 +1,134 @@
+package config
+
+import (
+	"strings"
+	"time"
+)
+
+const (
+	KeyBillingStripeKey        = "billing/stripe_key"
+	KeyBillingWebhookSecret    = "billing/webhook_secret"
+	KeyBillingWebhookTolerance = "billing/webhook_tolerance"
+	KeyBillingPortalReturnURL  = "billing/portal_return_url"
+	KeyBillingPrices           = "billing/prices"
+
+	DefaultBillingStripeKey        = ""
+	DefaultBillingWebhookSecret    = ""
+	DefaultBillingWebhookTolerance = time.Minute * 5
+	DefaultBillingPortalReturnURL  = ""
+)
+
+// BillingConfig values represent billing configuration data.
+type BillingConfig struct {
+	StripeKey        string            `json:"stripe_key,omitempty"        yaml:"stripe_key,omitempty"`
+	WebhookSecret    string            `json:"webhook_secret,omitempty"    yaml:"webhook_secret,omitempty"`
+	WebhookTolerance time.Duration     `json:"webhook_tolerance,omitempty" yaml:"webhook_tolerance,omitempty"`
+	PortalReturnURL  string            `json:"portal_return_url,omitempty" yaml:"portal_return_url,omitempty"`
+	Prices           map[string]string `json:"prices,omitempty"            yaml:"prices,omitempty"`
+}
+
+// Load reads configuration data from environment variables and applies defaults
+// for any missing or invalid configuration data.
+func (c *BillingConfig) Load() {
+	if v := getEnv(KeyBillingStripeKey); v != "" {
+		c.StripeKey = v
+	}
+
+	if v := getEnv(KeyBillingWebhookSecret); v != "" {
+		c.WebhookSecret = v
+	}
+
+	if v := getEnv(KeyBillingWebhookTolerance); v != "" {
+		v, err := time.ParseDuration(v)
+		if err != nil {
+			v = DefaultBillingWebhookTolerance
+		}
+
+		c.WebhookTolerance = v
+	}
+
+	if c.WebhookTolerance == 0 {
+		c.WebhookTolerance = DefaultBillingWebhookTolerance
+	}
+
+	if v := getEnv(KeyBillingPortalReturnURL); v != "" {
+		c.PortalReturnURL = v
+	}
+
+	if v := getEnv(KeyBillingPrices); v != "" {
+		c.Prices = map[string]string{}
+
+		for _, f := range strings.Fields(v) {
+			price, plan, ok := strings.Cut(f, "=")
+			if !ok {
+				continue
+			}
+
+			c.Prices[price] = plan
+		}
+	}
+}
+
+// BillingStripeKey returns the secret API key used to make requests to Stripe.
+// Billing is disabled if it is empty.
+func (c *Config) BillingStripeKey() string {
+	c.RLock()
+	defer c.RUnlock()
+
+	if c.billing == nil {
+		return DefaultBillingStripeKey
+	}
+
+	return c.billing.StripeKey
+}
+
+// BillingWebhookSecret returns the secret used to verify the signatures of
+// Stripe webhook events.
+func (c *Config) BillingWebhookSecret() string {
+	c.RLock()
+	defer c.RUnlock()
+
+	if c.billing == nil {
+		return DefaultBillingWebhookSecret
+	}
+
+	return c.billing.WebhookSecret
+}
+
+// BillingWebhookTolerance returns the maximum age of Stripe webhook events
+// which are accepted.
+func (c *Config) BillingWebhookTolerance() time.Duration {
+	c.RLock()
+	defer c.RUnlock()
+
+	if c.billing == nil {
+		return DefaultBillingWebhookTolerance
+	}
+
+	return c.billing.WebhookTolerance
+}
+
+// BillingPortalReturnURL returns the URL to which users are returned after
+// leaving the Stripe billing portal.
+func (c *Config) BillingPortalReturnURL() string {
+	c.RLock()
+	defer c.RUnlock()
+
+	if c.billing == nil {
+		return DefaultBillingPortalReturnURL
+	}
+
+	return c.billing.PortalReturnURL
+}
+
+// BillingPrices returns the account plan assigned for each Stripe price ID.
+func (c *Config) BillingPrices() map[string]string {
+	c.RLock()
+	defer c.RUnlock()
+
+	if c.billing == nil {
+		return nil
+	}
+
+	return c.billing.Prices
+}
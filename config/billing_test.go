@@ -0,0 +1,55 @@
+package config_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dhaifley/game2d/config"
+)
+
+func TestBillingConfig(t *testing.T) {
+	t.Setenv("BILLING_PRICES", "price_1=pro price_2=team invalid")
+
+	cfg := config.New("")
+
+	cfg.Load(nil)
+
+	if cfg.BillingWebhookTolerance() != config.DefaultBillingWebhookTolerance {
+		t.Errorf("Expected billing webhook tolerance: %v, got: %v",
+			config.DefaultBillingWebhookTolerance,
+			cfg.BillingWebhookTolerance())
+	}
+
+	if p := cfg.BillingPrices(); len(p) != 2 || p["price_2"] != "team" {
+		t.Errorf("Expected billing prices: price_1=pro price_2=team, got: %v",
+			p)
+	}
+
+	cfg.SetBilling(&config.BillingConfig{
+		StripeKey:        "sk_test",
+		WebhookSecret:    "whsec_test",
+		WebhookTolerance: time.Minute,
+		PortalReturnURL:  "https://example.com/account",
+	})
+
+	if cfg.BillingStripeKey() != "sk_test" {
+		t.Errorf("Expected billing stripe key: sk_test, got: %v",
+			cfg.BillingStripeKey())
+	}
+
+	if cfg.BillingWebhookSecret() != "whsec_test" {
+		t.Errorf("Expected billing webhook secret: whsec_test, got: %v",
+			cfg.BillingWebhookSecret())
+	}
+
+	if cfg.BillingWebhookTolerance() != time.Minute {
+		t.Errorf("Expected billing webhook tolerance: 1m, got: %v",
+			cfg.BillingWebhookTolerance())
+	}
+
+	if cfg.BillingPortalReturnURL() != "https://example.com/account" {
+		t.Errorf("Expected billing portal return url: "+
+			"https://example.com/account, got: %v",
+			cfg.BillingPortalReturnURL())
+	}
+}
@@ -1,7 +1,6 @@
 package config
 
 import (
-	"os"
 	"strconv"
 	"strings"
 	"time"
@@ -38,7 +37,7 @@ type CacheConfig struct {
 // Load reads configuration data from environment variables and applies defaults
 // for any missing or invalid configuration data.
 func (c *CacheConfig) Load() {
-	if v := os.Getenv(ReplaceEnv(KeyCacheType)); v != "" {
+	if v := getEnv(KeyCacheType); v != "" {
 		c.Type = v
 	}
 
@@ -46,7 +45,7 @@ func (c *CacheConfig) Load() {
 		c.Type = DefaultCacheType
 	}
 
-	if v := os.Getenv(ReplaceEnv(KeyCacheServers)); v != "" {
+	if v := getEnv(KeyCacheServers); v != "" {
 		c.Servers = strings.Split(v, " ")
 	}
 
@@ -54,7 +53,7 @@ func (c *CacheConfig) Load() {
 		c.Servers = []string{}
 	}
 
-	if v := os.Getenv(ReplaceEnv(KeyCacheDiscovery)); v != "" {
+	if v := getEnv(KeyCacheDiscovery); v != "" {
 		v, err := strconv.ParseBool(v)
 		if err != nil {
 			v = DefaultCacheDiscovery
@@ -63,7 +62,7 @@ func (c *CacheConfig) Load() {
 		c.Discovery = v
 	}
 
-	if v := os.Getenv(ReplaceEnv(KeyCacheTimeout)); v != "" {
+	if v := getEnv(KeyCacheTimeout); v != "" {
 		v, err := time.ParseDuration(v)
 		if err != nil {
 			v = DefaultCacheTimeout
@@ -76,7 +75,7 @@ func (c *CacheConfig) Load() {
 		c.Timeout = DefaultCacheTimeout
 	}
 
-	if v := os.Getenv(ReplaceEnv(KeyCacheExpiration)); v != "" {
+	if v := getEnv(KeyCacheExpiration); v != "" {
 		v, err := time.ParseDuration(v)
 		if err != nil {
 			v = DefaultCacheExpiration
@@ -89,7 +88,7 @@ func (c *CacheConfig) Load() {
 		c.Expiration = DefaultCacheExpiration
 	}
 
-	if v := os.Getenv(ReplaceEnv(KeyCacheMaxBytes)); v != "" {
+	if v := getEnv(KeyCacheMaxBytes); v != "" {
 		v, err := strconv.ParseInt(v, 10, 32)
 		if err != nil {
 			v = DefaultCacheMaxBytes
@@ -102,7 +101,7 @@ func (c *CacheConfig) Load() {
 		c.MaxBytes = DefaultCacheMaxBytes
 	}
 
-	if v := os.Getenv(ReplaceEnv(KeyCachePoolSize)); v != "" {
+	if v := getEnv(KeyCachePoolSize); v != "" {
 		v, err := strconv.ParseInt(v, 10, 32)
 		if err != nil {
 			v = DefaultCachePoolSize
@@ -19,22 +19,30 @@ const (
 type Config struct {
 	sync.RWMutex
 	auth      *AuthConfig
+	billing   *BillingConfig
 	cache     *CacheConfig
 	db        *DBConfig
+	embedding *EmbeddingConfig
 	log       *LogConfig
+	notify    *NotifyConfig
 	telemetry *TelemetryConfig
 	server    *ServerConfig
 	service   *ServiceConfig
+	speech    *SpeechConfig
 }
 
 type configFile struct {
 	Auth      *AuthConfig      `json:"auth,omitempty"      yaml:"auth,omitempty"`
+	Billing   *BillingConfig   `json:"billing,omitempty"   yaml:"billing,omitempty"`
 	Cache     *CacheConfig     `json:"cache,omitempty"     yaml:"cache,omitempty"`
 	DB        *DBConfig        `json:"db,omitempty"        yaml:"db,omitempty"`
+	Embedding *EmbeddingConfig `json:"embedding,omitempty" yaml:"embedding,omitempty"`
 	Log       *LogConfig       `json:"log,omitempty"       yaml:"log,omitempty"`
+	Notify    *NotifyConfig    `json:"notify,omitempty"    yaml:"notify,omitempty"`
 	Telemetry *TelemetryConfig `json:"telemetry,omitempty" yaml:"telemetry,omitempty"`
 	Server    *ServerConfig    `json:"server,omitempty"    yaml:"server,omitempty"`
 	Service   *ServiceConfig   `json:"service,omitempty"   yaml:"service,omitempty"`
+	Speech    *SpeechConfig    `json:"speech,omitempty"    yaml:"speech,omitempty"`
 }
 
 // New creates a new configuration value.
@@ -63,6 +71,14 @@ func (c *Config) SetAuth(auth *AuthConfig) {
 	c.auth = auth
 }
 
+// SetBilling applies billing configuration data to the configuration.
+func (c *Config) SetBilling(billing *BillingConfig) {
+	c.Lock()
+	defer c.Unlock()
+
+	c.billing = billing
+}
+
 // SetCache applies cache configuration data to the configuration.
 func (c *Config) SetCache(cache *CacheConfig) {
 	c.Lock()
@@ -87,6 +103,14 @@ func (c *Config) SetLog(log *LogConfig) {
 	c.log = log
 }
 
+// SetNotify applies notification configuration data to the configuration.
+func (c *Config) SetNotify(notify *NotifyConfig) {
+	c.Lock()
+	defer c.Unlock()
+
+	c.notify = notify
+}
+
 // SetTelemetry applies telemetry configuration data to the configuration.
 func (c *Config) SetTelemetry(telemetry *TelemetryConfig) {
 	c.Lock()
@@ -111,6 +135,22 @@ func (c *Config) SetService(service *ServiceConfig) {
 	c.service = service
 }
 
+// SetSpeech applies speech to text configuration data to the configuration.
+func (c *Config) SetSpeech(speech *SpeechConfig) {
+	c.Lock()
+	defer c.Unlock()
+
+	c.speech = speech
+}
+
+// SetEmbedding applies embedding configuration data to the configuration.
+func (c *Config) SetEmbedding(embedding *EmbeddingConfig) {
+	c.Lock()
+	defer c.Unlock()
+
+	c.embedding = embedding
+}
+
 // Load applies provided configuration data and populates missing configuration
 // from environment variables and default values.
 func (c *Config) Load(b []byte) {
@@ -132,6 +172,12 @@ func (c *Config) Load(b []byte) {
 
 	c.auth.Load()
 
+	if c.billing == nil {
+		c.billing = &BillingConfig{}
+	}
+
+	c.billing.Load()
+
 	if c.cache == nil {
 		c.cache = &CacheConfig{}
 	}
@@ -150,6 +196,12 @@ func (c *Config) Load(b []byte) {
 
 	c.log.Load()
 
+	if c.notify == nil {
+		c.notify = &NotifyConfig{}
+	}
+
+	c.notify.Load()
+
 	if c.telemetry == nil {
 		c.telemetry = &TelemetryConfig{}
 	}
@@ -167,6 +219,18 @@ func (c *Config) Load(b []byte) {
 	}
 
 	c.service.Load()
+
+	if c.speech == nil {
+		c.speech = &SpeechConfig{}
+	}
+
+	c.speech.Load()
+
+	if c.embedding == nil {
+		c.embedding = &EmbeddingConfig{}
+	}
+
+	c.embedding.Load()
 }
 
 // LoadFiles attempts to load any available configuration files.
@@ -193,12 +257,16 @@ func (c *Config) UnmarshalJSON(b []byte) error {
 	}
 
 	c.auth = cf.Auth
+	c.billing = cf.Billing
 	c.cache = cf.Cache
 	c.db = cf.DB
+	c.embedding = cf.Embedding
 	c.log = cf.Log
+	c.notify = cf.Notify
 	c.telemetry = cf.Telemetry
 	c.server = cf.Server
 	c.service = cf.Service
+	c.speech = cf.Speech
 
 	return nil
 }
@@ -207,12 +275,16 @@ func (c *Config) UnmarshalJSON(b []byte) error {
 func (c *Config) MarshalJSON() ([]byte, error) {
 	cf := configFile{
 		Auth:      c.auth,
+		Billing:   c.billing,
 		Cache:     c.cache,
 		DB:        c.db,
+		Embedding: c.embedding,
 		Log:       c.log,
+		Notify:    c.notify,
 		Telemetry: c.telemetry,
 		Server:    c.server,
 		Service:   c.service,
+		Speech:    c.speech,
 	}
 
 	buf := &bytes.Buffer{}
@@ -233,12 +305,16 @@ func (c *Config) UnmarshalYAML(value *yaml.Node) error {
 	}
 
 	c.auth = cf.Auth
+	c.billing = cf.Billing
 	c.cache = cf.Cache
 	c.db = cf.DB
+	c.embedding = cf.Embedding
 	c.log = cf.Log
+	c.notify = cf.Notify
 	c.telemetry = cf.Telemetry
 	c.server = cf.Server
 	c.service = cf.Service
+	c.speech = cf.Speech
 
 	return nil
 }
@@ -251,12 +327,16 @@ func (c *Config) MarshalYAML() (any, error) {
 
 	cf := &configFile{
 		Auth:      c.auth,
+		Billing:   c.billing,
 		Cache:     c.cache,
 		DB:        c.db,
+		Embedding: c.embedding,
 		Log:       c.log,
+		Notify:    c.notify,
 		Telemetry: c.telemetry,
 		Server:    c.server,
 		Service:   c.service,
+		Speech:    c.speech,
 	}
 
 	return cf, nil
@@ -2,6 +2,7 @@ package config_test
 
 import (
 	"testing"
+	"time"
 
 	"github.com/dhaifley/game2d/config"
 )
@@ -22,6 +23,10 @@ func TestDatabaseConfig(t *testing.T) {
 		MaxPoolSize: 10,
 		DefaultSize: 10,
 		MaxSize:     100,
+		Timeout:     time.Second,
+		RetryMin:    time.Second,
+		RetryMax:    time.Minute,
+		Tenancy:     "database",
 	})
 
 	if cfg.DBConn() != exp {
@@ -47,4 +52,20 @@ func TestDatabaseConfig(t *testing.T) {
 	if cfg.DBMaxSize() != 100 {
 		t.Errorf("Expected max size: 100, got: %v", cfg.DBMaxSize())
 	}
+
+	if cfg.DBTimeout() != time.Second {
+		t.Errorf("Expected timeout: 1s, got: %v", cfg.DBTimeout())
+	}
+
+	if cfg.DBRetryMin() != time.Second {
+		t.Errorf("Expected retry min: 1s, got: %v", cfg.DBRetryMin())
+	}
+
+	if cfg.DBRetryMax() != time.Minute {
+		t.Errorf("Expected retry max: 1m, got: %v", cfg.DBRetryMax())
+	}
+
+	if cfg.DBTenancy() != "database" {
+		t.Errorf("Expected tenancy: database, got: %v", cfg.DBTenancy())
+	}
 }
@@ -0,0 +1,436 @@
+package config
+
+import (
+	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Configuration setting sources, in order of increasing precedence.
+const (
+	SourceDefault = "default"
+	SourceConfig  = "config"
+	SourceFile    = "file"
+	SourceEnv     = "env"
+	SourceSecret  = "secret"
+)
+
+// Redacted replaces the values of secret settings.
+const Redacted = "[REDACTED]"
+
+// Setting values describe the effective value of a configuration key, and the
+// source from which it was set.
+type Setting struct {
+	Key    string `json:"key"              yaml:"key"`
+	Env    string `json:"env"              yaml:"env"`
+	Value  string `json:"value"            yaml:"value"`
+	Source string `json:"source"           yaml:"source"`
+	Secret bool   `json:"secret,omitempty" yaml:"secret,omitempty"`
+}
+
+// setting values define a configuration key, how to retrieve its effective
+// value, and its default value.
+type setting struct {
+	key    string
+	secret bool
+	value  func(c *Config) any
+	def    any
+}
+
+// settings contains all configuration keys which are reported.
+var settings = []setting{
+	{KeyAuthTokenHMACKey, true,
+		func(c *Config) any { return c.AuthTokenHMACKey() }, []byte(nil)},
+	{KeyAuthTokenPrivateKey, true,
+		func(c *Config) any { return c.AuthTokenPrivateKey() }, []byte(nil)},
+	{KeyAuthTokenPublicKey, false,
+		func(c *Config) any { return c.AuthTokenPublicKey() }, []byte(nil)},
+	{KeyAuthTokenWellKnown, false,
+		func(c *Config) any { return c.AuthTokenWellKnown() },
+		DefaultAuthTokenWellKnown},
+	{KeyAuthTokenExpiresIn, false,
+		func(c *Config) any { return c.AuthTokenExpiresIn() },
+		DefaultAuthTokenExpiresIn},
+	{KeyAuthTokenRefreshExpiresIn, false,
+		func(c *Config) any { return c.AuthTokenRefreshExpiresIn() },
+		DefaultAuthTokenRefreshExpiresIn},
+	{KeyAuthTokenIssuer, false,
+		func(c *Config) any { return c.AuthTokenIssuer() },
+		DefaultAuthTokenIssuer},
+	{KeyAuthUpdateInterval, false,
+		func(c *Config) any { return c.AuthUpdateInterval() },
+		DefaultAuthUpdateInterval},
+	{KeyAuthIdentityDomain, false,
+		func(c *Config) any { return c.AuthIdentityDomain() },
+		DefaultAuthIdentityDomain},
+	{KeyAuthBootstrapToken, true,
+		func(c *Config) any { return c.AuthBootstrapToken() },
+		DefaultAuthBootstrapToken},
+	{KeyAuthAPIKeyExpiresIn, false,
+		func(c *Config) any { return c.AuthAPIKeyExpiresIn() },
+		DefaultAuthAPIKeyExpiresIn},
+	{KeyBillingStripeKey, true,
+		func(c *Config) any { return c.BillingStripeKey() },
+		DefaultBillingStripeKey},
+	{KeyBillingWebhookSecret, true,
+		func(c *Config) any { return c.BillingWebhookSecret() },
+		DefaultBillingWebhookSecret},
+	{KeyBillingWebhookTolerance, false,
+		func(c *Config) any { return c.BillingWebhookTolerance() },
+		DefaultBillingWebhookTolerance},
+	{KeyBillingPortalReturnURL, false,
+		func(c *Config) any { return c.BillingPortalReturnURL() },
+		DefaultBillingPortalReturnURL},
+	{KeyBillingPrices, false,
+		func(c *Config) any { return c.BillingPrices() },
+		map[string]string(nil)},
+	{KeyCacheType, false,
+		func(c *Config) any { return c.CacheType() }, DefaultCacheType},
+	{KeyCacheServers, false,
+		func(c *Config) any { return c.CacheServers() }, []string(nil)},
+	{KeyCacheDiscovery, false,
+		func(c *Config) any { return c.CacheDiscovery() },
+		DefaultCacheDiscovery},
+	{KeyCacheTimeout, false,
+		func(c *Config) any { return c.CacheTimeout() }, DefaultCacheTimeout},
+	{KeyCacheExpiration, false,
+		func(c *Config) any { return c.CacheExpiration() },
+		DefaultCacheExpiration},
+	{KeyCacheMaxBytes, false,
+		func(c *Config) any { return c.CacheMaxBytes() },
+		DefaultCacheMaxBytes},
+	{KeyCachePoolSize, false,
+		func(c *Config) any { return c.CachePoolSize() }, DefaultCachePoolSize},
+	{KeyDBConn, true,
+		func(c *Config) any { return c.DBConn() }, DefaultDBConn},
+	{KeyDBDatabase, false,
+		func(c *Config) any { return c.DBDatabase() }, DefaultDBDatabase},
+	{KeyDBDMinPoolSize, false,
+		func(c *Config) any { return c.DBMinPoolSize() },
+		DefaultDBMinPoolSize},
+	{KeyDBMaxPoolSize, false,
+		func(c *Config) any { return c.DBMaxPoolSize() },
+		DefaultDBMaxPoolSize},
+	{KeyDBDefaultSize, false,
+		func(c *Config) any { return c.DBDefaultSize() },
+		DefaultDBDefaultSize},
+	{KeyDBMaxSize, false,
+		func(c *Config) any { return c.DBMaxSize() }, DefaultDBMaxSize},
+	{KeyDBTimeout, false,
+		func(c *Config) any { return c.DBTimeout() }, DefaultDBTimeout},
+	{KeyDBRetryMin, false,
+		func(c *Config) any { return c.DBRetryMin() }, DefaultDBRetryMin},
+	{KeyDBRetryMax, false,
+		func(c *Config) any { return c.DBRetryMax() }, DefaultDBRetryMax},
+	{KeyDBTenancy, false,
+		func(c *Config) any { return c.DBTenancy() }, DefaultDBTenancy},
+	{KeyLogLevel, false,
+		func(c *Config) any { return c.LogLevel() }, DefaultLogLevel},
+	{KeyLogOut, false,
+		func(c *Config) any { return c.LogOut() }, DefaultLogOut},
+	{KeyLogFormat, false,
+		func(c *Config) any { return c.LogFormat() }, DefaultLogFormat},
+	{KeyNotifySMTPAddress, false,
+		func(c *Config) any { return c.NotifySMTPAddress() },
+		DefaultNotifySMTPAddress},
+	{KeyNotifySMTPUsername, false,
+		func(c *Config) any { return c.NotifySMTPUsername() },
+		DefaultNotifySMTPUsername},
+	{KeyNotifySMTPPassword, true,
+		func(c *Config) any { return c.NotifySMTPPassword() },
+		DefaultNotifySMTPPassword},
+	{KeyNotifySMTPFrom, false,
+		func(c *Config) any { return c.NotifySMTPFrom() },
+		DefaultNotifySMTPFrom},
+	{KeyNotifyPushPrivateKey, true,
+		func(c *Config) any { return c.NotifyPushPrivateKey() },
+		DefaultNotifyPushPrivateKey},
+	{KeyNotifyPushSubject, false,
+		func(c *Config) any { return c.NotifyPushSubject() },
+		DefaultNotifyPushSubject},
+	{KeyNotifyRetention, false,
+		func(c *Config) any { return c.NotifyRetention() },
+		DefaultNotifyRetention},
+	{KeyServerAddress, false,
+		func(c *Config) any { return c.ServerAddress() },
+		DefaultServerAddress},
+	{KeyServerCert, false,
+		func(c *Config) any { return c.ServerCert() }, DefaultServerCert},
+	{KeyServerKey, false,
+		func(c *Config) any { return c.ServerKey() }, DefaultServerKey},
+	{KeyServerTimeout, false,
+		func(c *Config) any { return c.ServerTimeout() },
+		DefaultServerTimeout},
+	{KeyServerIdleTimeout, false,
+		func(c *Config) any { return c.ServerIdleTimeout() },
+		DefaultServerIdleTimeout},
+	{KeyServerPromptTimeout, false,
+		func(c *Config) any { return c.ServerPromptTimeout() },
+		DefaultServerPromptTimeout},
+	{KeyServerPromptStreamTimeout, false,
+		func(c *Config) any { return c.ServerPromptStreamTimeout() },
+		DefaultServerPromptStreamTimeout},
+	{KeyServerHost, false,
+		func(c *Config) any { return c.ServerHost() }, DefaultServerHost},
+	{KeyServerPathPrefix, false,
+		func(c *Config) any { return c.ServerPathPrefix() },
+		DefaultServerPathPrefix},
+	{KeyServerMaxRequestSize, false,
+		func(c *Config) any { return c.ServerMaxRequestSize() },
+		DefaultServerMaxRequestSize},
+	{KeyServerMaxAuthSize, false,
+		func(c *Config) any { return c.ServerMaxAuthRequestSize() },
+		DefaultServerMaxAuthSize},
+	{KeyServerMaxGamesSize, false,
+		func(c *Config) any { return c.ServerMaxGamesRequestSize() },
+		DefaultServerMaxGamesSize},
+	{KeyServerMaxTagsSize, false,
+		func(c *Config) any { return c.ServerMaxTagsRequestSize() },
+		DefaultServerMaxTagsSize},
+	{KeyServerCORSOrigins, false,
+		func(c *Config) any { return c.ServerCORSOrigins() },
+		func(c *Config) any {
+			return DefaultServerCORSOrigins(c.ServerHost())
+		}},
+	{KeyServerCORSMethods, false,
+		func(c *Config) any { return c.ServerCORSMethods() },
+		DefaultServerCORSMethods},
+	{KeyServerCORSHeaders, false,
+		func(c *Config) any { return c.ServerCORSHeaders() },
+		DefaultServerCORSHeaders},
+	{KeyServerCORSMaxAge, false,
+		func(c *Config) any { return c.ServerCORSMaxAge() },
+		DefaultServerCORSMaxAge},
+	{KeyServerCORSNoCreds, false,
+		func(c *Config) any { return !c.ServerCORSCredentials() },
+		DefaultServerCORSNoCreds},
+	{KeyServerAutocert, false,
+		func(c *Config) any { return c.ServerAutocert() },
+		DefaultServerAutocert},
+	{KeyServerAutocertDir, false,
+		func(c *Config) any { return c.ServerAutocertDir() },
+		DefaultServerAutocertDir},
+	{KeyServerAutocertEmail, false,
+		func(c *Config) any { return c.ServerAutocertEmail() },
+		DefaultServerAutocertEmail},
+	{KeyServerRedirectAddr, false,
+		func(c *Config) any { return c.ServerRedirectAddress() },
+		DefaultServerRedirectAddr},
+	{KeyServerProtocols, false,
+		func(c *Config) any { return c.ServerProtocols() },
+		DefaultServerProtocols},
+	{KeyServerDrainDelay, false,
+		func(c *Config) any { return c.ServerDrainDelay() },
+		DefaultServerDrainDelay},
+	{KeyServerDrainTimeout, false,
+		func(c *Config) any { return c.ServerDrainTimeout() },
+		DefaultServerDrainTimeout},
+	{KeyServerOutboundTimeout, false,
+		func(c *Config) any { return c.ServerOutboundTimeout() },
+		DefaultServerOutboundTimeout},
+	{KeyServerTaskTimeout, false,
+		func(c *Config) any { return c.ServerTaskTimeout() },
+		DefaultServerTaskTimeout},
+	{KeyServerStartupGrace, false,
+		func(c *Config) any { return c.ServerStartupGrace() },
+		DefaultServerStartupGrace},
+	{KeyServerStatusWebhook, true,
+		func(c *Config) any { return c.ServerStatusWebhook() },
+		DefaultServerStatusWebhook},
+	{KeyServerHooks, false,
+		func(c *Config) any { return c.ServerHooks() }, []string(nil)},
+	{KeyServiceName, false,
+		func(c *Config) any { return c.ServiceName() }, DefaultServiceName},
+	{KeyAccountID, false,
+		func(c *Config) any { return c.AccountID() }, DefaultAccountID},
+	{KeyAccountName, false,
+		func(c *Config) any { return c.AccountName() }, DefaultAccountName},
+	{KeyServiceMaintenance, false,
+		func(c *Config) any { return c.ServiceMaintenance() },
+		DefaultServiceMaintenance},
+	{KeyImportInterval, false,
+		func(c *Config) any { return c.ImportInterval() },
+		DefaultImportInterval},
+	{KeyImportConcurrency, false,
+		func(c *Config) any { return c.ImportConcurrency() },
+		DefaultImportConcurrency},
+	{KeyImportTimeout, false,
+		func(c *Config) any { return c.ImportTimeout() },
+		DefaultImportTimeout},
+	{KeyImportFileDir, false,
+		func(c *Config) any { return c.ImportFileDir() },
+		DefaultImportFileDir},
+	{KeyBackupInterval, false,
+		func(c *Config) any { return c.BackupInterval() },
+		DefaultBackupInterval},
+	{KeyBackupURL, true,
+		func(c *Config) any { return c.BackupURL() }, DefaultBackupURL},
+	{KeyBackupRetention, false,
+		func(c *Config) any { return c.BackupRetention() },
+		DefaultBackupRetention},
+	{KeyBackupUsers, false,
+		func(c *Config) any { return c.BackupUsers() }, DefaultBackupUsers},
+	{KeyGameLimitDefault, false,
+		func(c *Config) any { return c.GameLimitDefault() },
+		DefaultGameLimitDefault},
+	{KeyStorageLimit, false,
+		func(c *Config) any { return c.StorageLimit() },
+		DefaultStorageLimit},
+	{KeyAssetGCInterval, false,
+		func(c *Config) any { return c.AssetGCInterval() },
+		DefaultAssetGCInterval},
+	{KeyAssetRetention, false,
+		func(c *Config) any { return c.AssetRetention() },
+		DefaultAssetRetention},
+	{KeyPlanDefault, false,
+		func(c *Config) any { return c.PlanDefault() },
+		DefaultPlanDefault},
+	{KeyPromptHistorySize, false,
+		func(c *Config) any { return c.PromptHistorySize() },
+		DefaultPromptHistorySize},
+	{KeyPromptGameLimit, false,
+		func(c *Config) any { return c.PromptGameLimit() },
+		DefaultPromptGameLimit},
+	{KeyPromptAccountLimit, false,
+		func(c *Config) any { return c.PromptAccountLimit() },
+		DefaultPromptAccountLimit},
+	{KeyPromptCooldown, false,
+		func(c *Config) any { return c.PromptCooldown() },
+		DefaultPromptCooldown},
+	{KeyFeatureRollout, false,
+		func(c *Config) any { return c.FeatureRollout() },
+		map[string]int(nil)},
+	{KeySecretRotate, false,
+		func(c *Config) any { return c.SecretRotateInterval() },
+		DefaultSecretRotate},
+	{KeySpeechProvider, false,
+		func(c *Config) any { return c.SpeechProvider() },
+		DefaultSpeechProvider},
+	{KeySpeechURL, false,
+		func(c *Config) any { return c.SpeechURL() }, DefaultSpeechURL},
+	{KeySpeechAPIKey, true,
+		func(c *Config) any { return c.SpeechAPIKey() }, DefaultSpeechAPIKey},
+	{KeySpeechModel, false,
+		func(c *Config) any { return c.SpeechModel() }, DefaultSpeechModel},
+	{KeySpeechMaxSize, false,
+		func(c *Config) any { return c.SpeechMaxSize() },
+		DefaultSpeechMaxSize},
+	{KeyEmbeddingProvider, false,
+		func(c *Config) any { return c.EmbeddingProvider() },
+		DefaultEmbeddingProvider},
+	{KeyEmbeddingURL, false,
+		func(c *Config) any { return c.EmbeddingURL() }, DefaultEmbeddingURL},
+	{KeyEmbeddingAPIKey, true,
+		func(c *Config) any { return c.EmbeddingAPIKey() },
+		DefaultEmbeddingAPIKey},
+	{KeyEmbeddingModel, false,
+		func(c *Config) any { return c.EmbeddingModel() },
+		DefaultEmbeddingModel},
+	{KeyMetricAddress, false,
+		func(c *Config) any { return c.MetricAddress() },
+		DefaultMetricAddress},
+	{KeyMetricInterval, false,
+		func(c *Config) any { return c.MetricInterval() },
+		DefaultMetricInterval},
+	{KeyMetricVersion, false,
+		func(c *Config) any { return c.MetricVersion() },
+		DefaultMetricVersion},
+	{KeyTraceAddress, false,
+		func(c *Config) any { return c.TraceAddress() },
+		DefaultTraceAddress},
+}
+
+// formatSetting formats a configuration value for display.
+func formatSetting(v any) string {
+	switch vv := v.(type) {
+	case string:
+		return vv
+	case []byte:
+		if len(vv) == 0 {
+			return ""
+		}
+
+		return fmt.Sprintf("(%d bytes)", len(vv))
+	case []string:
+		return strings.Join(vv, ",")
+	case map[string]int:
+		fs := make([]string, 0, len(vv))
+
+		for _, k := range slices.Sorted(maps.Keys(vv)) {
+			fs = append(fs, fmt.Sprintf("%s=%d", k, vv[k]))
+		}
+
+		return strings.Join(fs, " ")
+	case map[string]string:
+		fs := make([]string, 0, len(vv))
+
+		for _, k := range slices.Sorted(maps.Keys(vv)) {
+			fs = append(fs, k+"="+vv[k])
+		}
+
+		return strings.Join(fs, " ")
+	case slog.Level:
+		return strings.ToLower(vv.String())
+	case time.Duration:
+		return vv.String()
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// settingSource returns the source from which a configuration key was set.
+func settingSource(env, value, def string) string {
+	if v := os.Getenv(env); v != "" {
+		if secretScheme(v) != "" {
+			return SourceSecret
+		}
+
+		return SourceEnv
+	}
+
+	if v := os.Getenv(env + SecretFileSuffix); v != "" {
+		return SourceFile
+	}
+
+	if value == def {
+		return SourceDefault
+	}
+
+	return SourceConfig
+}
+
+// Settings returns the effective value of every configuration key, and the
+// source from which it was set. The values of secret settings are redacted.
+func (c *Config) Settings() []Setting {
+	res := make([]Setting, 0, len(settings))
+
+	for _, s := range settings {
+		def := s.def
+
+		if f, ok := def.(func(c *Config) any); ok {
+			def = f(c)
+		}
+
+		v := formatSetting(s.value(c))
+
+		st := Setting{
+			Key:    s.key,
+			Env:    ReplaceEnv(s.key),
+			Value:  v,
+			Source: settingSource(ReplaceEnv(s.key), v, formatSetting(def)),
+			Secret: s.secret,
+		}
+
+		if s.secret && v != "" {
+			st.Value = Redacted
+		}
+
+		res = append(res, st)
+	}
+
+	return res
+}
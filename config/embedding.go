@@ -0,0 +1,105 @@
+package config
+
+const (
+	KeyEmbeddingProvider = "embedding/provider"
+	KeyEmbeddingURL      = "embedding/url"
+	KeyEmbeddingAPIKey   = "embedding/api_key"
+	KeyEmbeddingModel    = "embedding/model"
+
+	DefaultEmbeddingProvider = "hash"
+	DefaultEmbeddingURL      = "https://api.openai.com/v1/embeddings"
+	DefaultEmbeddingAPIKey   = ""
+	DefaultEmbeddingModel    = "text-embedding-3-small"
+)
+
+// EmbeddingConfig values represent configuration data for the embeddings used
+// to find similar games.
+type EmbeddingConfig struct {
+	Provider string `json:"provider,omitempty" yaml:"provider,omitempty"`
+	URL      string `json:"url,omitempty"      yaml:"url,omitempty"`
+	APIKey   string `json:"api_key,omitempty"  yaml:"api_key,omitempty"`
+	Model    string `json:"model,omitempty"    yaml:"model,omitempty"`
+}
+
+// Load reads configuration data from environment variables and applies defaults
+// for any missing or invalid configuration data.
+func (c *EmbeddingConfig) Load() {
+	if v := getEnv(KeyEmbeddingProvider); v != "" {
+		c.Provider = v
+	}
+
+	if c.Provider == "" {
+		c.Provider = DefaultEmbeddingProvider
+	}
+
+	if v := getEnv(KeyEmbeddingURL); v != "" {
+		c.URL = v
+	}
+
+	if c.URL == "" {
+		c.URL = DefaultEmbeddingURL
+	}
+
+	if v := getEnv(KeyEmbeddingAPIKey); v != "" {
+		c.APIKey = v
+	}
+
+	if v := getEnv(KeyEmbeddingModel); v != "" {
+		c.Model = v
+	}
+
+	if c.Model == "" {
+		c.Model = DefaultEmbeddingModel
+	}
+}
+
+// EmbeddingProvider returns the provider of the embeddings used to find similar
+// games. The hash provider creates embeddings without an external service, and
+// similar games are not found if it is none.
+func (c *Config) EmbeddingProvider() string {
+	c.RLock()
+	defer c.RUnlock()
+
+	if c.embedding == nil {
+		return DefaultEmbeddingProvider
+	}
+
+	return c.embedding.Provider
+}
+
+// EmbeddingURL returns the URL of the embeddings API of the embedding provider.
+func (c *Config) EmbeddingURL() string {
+	c.RLock()
+	defer c.RUnlock()
+
+	if c.embedding == nil {
+		return DefaultEmbeddingURL
+	}
+
+	return c.embedding.URL
+}
+
+// EmbeddingAPIKey returns the secret API key used to make requests to the
+// embedding provider.
+func (c *Config) EmbeddingAPIKey() string {
+	c.RLock()
+	defer c.RUnlock()
+
+	if c.embedding == nil {
+		return DefaultEmbeddingAPIKey
+	}
+
+	return c.embedding.APIKey
+}
+
+// EmbeddingModel returns the model used by the embedding provider.
+func (c *Config) EmbeddingModel() string {
+	c.RLock()
+	defer c.RUnlock()
+
+	if c.embedding == nil {
+		return DefaultEmbeddingModel
+	}
+
+	return c.embedding.Model
+}
@@ -0,0 +1,50 @@
+package config_test
+
+import (
+	"testing"
+
+	"github.com/dhaifley/game2d/config"
+)
+
+func TestEmbeddingConfig(t *testing.T) {
+	cfg := config.New("")
+
+	cfg.Load(nil)
+
+	if cfg.EmbeddingProvider() != config.DefaultEmbeddingProvider {
+		t.Errorf("Expected embedding provider: %v, got: %v",
+			config.DefaultEmbeddingProvider, cfg.EmbeddingProvider())
+	}
+
+	if cfg.EmbeddingModel() != config.DefaultEmbeddingModel {
+		t.Errorf("Expected embedding model: %v, got: %v",
+			config.DefaultEmbeddingModel, cfg.EmbeddingModel())
+	}
+
+	cfg.SetEmbedding(&config.EmbeddingConfig{
+		Provider: "openai",
+		URL:      "https://example.com/embeddings",
+		APIKey:   "sk_test",
+		Model:    "test",
+	})
+
+	if cfg.EmbeddingProvider() != "openai" {
+		t.Errorf("Expected embedding provider: openai, got: %v",
+			cfg.EmbeddingProvider())
+	}
+
+	if cfg.EmbeddingURL() != "https://example.com/embeddings" {
+		t.Errorf("Expected embedding url: "+
+			"https://example.com/embeddings, got: %v", cfg.EmbeddingURL())
+	}
+
+	if cfg.EmbeddingAPIKey() != "sk_test" {
+		t.Errorf("Expected embedding api key: sk_test, got: %v",
+			cfg.EmbeddingAPIKey())
+	}
+
+	if cfg.EmbeddingModel() != "test" {
+		t.Errorf("Expected embedding model: test, got: %v",
+			cfg.EmbeddingModel())
+	}
+}
@@ -2,7 +2,6 @@ package config
 
 import (
 	"log/slog"
-	"os"
 	"strings"
 )
 
@@ -43,7 +42,7 @@ type LogConfig struct {
 // Load reads configuration data from environment variables and applies defaults
 // for any missing or invalid configuration data.
 func (c *LogConfig) Load() {
-	if v := os.Getenv(ReplaceEnv(KeyLogLevel)); v != "" {
+	if v := getEnv(KeyLogLevel); v != "" {
 		c.Level = v
 	}
 
@@ -53,7 +52,7 @@ func (c *LogConfig) Load() {
 		c.Level = DefaultLogLevel
 	}
 
-	if v := os.Getenv(ReplaceEnv(KeyLogOut)); v != "" {
+	if v := getEnv(KeyLogOut); v != "" {
 		c.Out = v
 	}
 
@@ -63,7 +62,7 @@ func (c *LogConfig) Load() {
 		c.Out = DefaultLogOut
 	}
 
-	if v := os.Getenv(ReplaceEnv(KeyLogFormat)); v != "" {
+	if v := getEnv(KeyLogFormat); v != "" {
 		c.Format = v
 	}
 
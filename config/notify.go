@@ -0,0 +1,161 @@
+package config
+
+import "time"
+
+const (
+	KeyNotifySMTPAddress    = "notify/smtp_address"
+	KeyNotifySMTPUsername   = "notify/smtp_username"
+	KeyNotifySMTPPassword   = "notify/smtp_password"
+	KeyNotifySMTPFrom       = "notify/smtp_from"
+	KeyNotifyPushPrivateKey = "notify/push_private_key"
+	KeyNotifyPushSubject    = "notify/push_subject"
+	KeyNotifyRetention      = "notify/retention"
+
+	DefaultNotifySMTPAddress    = ""
+	DefaultNotifySMTPUsername   = ""
+	DefaultNotifySMTPPassword   = ""
+	DefaultNotifySMTPFrom       = ""
+	DefaultNotifyPushPrivateKey = ""
+	DefaultNotifyPushSubject    = ""
+	DefaultNotifyRetention      = time.Hour * 24 * 30
+)
+
+// NotifyConfig values represent notification configuration data.
+type NotifyConfig struct {
+	SMTPAddress    string        `json:"smtp_address,omitempty"     yaml:"smtp_address,omitempty"`
+	SMTPUsername   string        `json:"smtp_username,omitempty"    yaml:"smtp_username,omitempty"`
+	SMTPPassword   string        `json:"smtp_password,omitempty"    yaml:"smtp_password,omitempty"`
+	SMTPFrom       string        `json:"smtp_from,omitempty"        yaml:"smtp_from,omitempty"`
+	PushPrivateKey string        `json:"push_private_key,omitempty" yaml:"push_private_key,omitempty"`
+	PushSubject    string        `json:"push_subject,omitempty"     yaml:"push_subject,omitempty"`
+	Retention      time.Duration `json:"retention,omitempty"        yaml:"retention,omitempty"`
+}
+
+// Load reads configuration data from environment variables and applies defaults
+// for any missing or invalid configuration data.
+func (c *NotifyConfig) Load() {
+	if v := getEnv(KeyNotifySMTPAddress); v != "" {
+		c.SMTPAddress = v
+	}
+
+	if v := getEnv(KeyNotifySMTPUsername); v != "" {
+		c.SMTPUsername = v
+	}
+
+	if v := getEnv(KeyNotifySMTPPassword); v != "" {
+		c.SMTPPassword = v
+	}
+
+	if v := getEnv(KeyNotifySMTPFrom); v != "" {
+		c.SMTPFrom = v
+	}
+
+	if v := getEnv(KeyNotifyPushPrivateKey); v != "" {
+		c.PushPrivateKey = v
+	}
+
+	if v := getEnv(KeyNotifyPushSubject); v != "" {
+		c.PushSubject = v
+	}
+
+	if v := getEnv(KeyNotifyRetention); v != "" {
+		v, err := time.ParseDuration(v)
+		if err != nil {
+			v = DefaultNotifyRetention
+		}
+
+		c.Retention = v
+	}
+
+	if c.Retention == 0 {
+		c.Retention = DefaultNotifyRetention
+	}
+}
+
+// NotifySMTPAddress returns the address of the SMTP server used to deliver
+// email notifications. Email notifications are disabled if it is empty.
+func (c *Config) NotifySMTPAddress() string {
+	c.RLock()
+	defer c.RUnlock()
+
+	if c.notify == nil {
+		return DefaultNotifySMTPAddress
+	}
+
+	return c.notify.SMTPAddress
+}
+
+// NotifySMTPUsername returns the username used to authenticate with the SMTP
+// server.
+func (c *Config) NotifySMTPUsername() string {
+	c.RLock()
+	defer c.RUnlock()
+
+	if c.notify == nil {
+		return DefaultNotifySMTPUsername
+	}
+
+	return c.notify.SMTPUsername
+}
+
+// NotifySMTPPassword returns the password used to authenticate with the SMTP
+// server.
+func (c *Config) NotifySMTPPassword() string {
+	c.RLock()
+	defer c.RUnlock()
+
+	if c.notify == nil {
+		return DefaultNotifySMTPPassword
+	}
+
+	return c.notify.SMTPPassword
+}
+
+// NotifySMTPFrom returns the sender address of email notifications.
+func (c *Config) NotifySMTPFrom() string {
+	c.RLock()
+	defer c.RUnlock()
+
+	if c.notify == nil {
+		return DefaultNotifySMTPFrom
+	}
+
+	return c.notify.SMTPFrom
+}
+
+// NotifyPushPrivateKey returns the VAPID private key used to deliver web push
+// notifications. Web push notifications are disabled if it is empty.
+func (c *Config) NotifyPushPrivateKey() string {
+	c.RLock()
+	defer c.RUnlock()
+
+	if c.notify == nil {
+		return DefaultNotifyPushPrivateKey
+	}
+
+	return c.notify.PushPrivateKey
+}
+
+// NotifyPushSubject returns the contact URL sent to web push services.
+func (c *Config) NotifyPushSubject() string {
+	c.RLock()
+	defer c.RUnlock()
+
+	if c.notify == nil {
+		return DefaultNotifyPushSubject
+	}
+
+	return c.notify.PushSubject
+}
+
+// NotifyRetention returns how long notifications are kept in user inboxes.
+func (c *Config) NotifyRetention() time.Duration {
+	c.RLock()
+	defer c.RUnlock()
+
+	if c.notify == nil {
+		return DefaultNotifyRetention
+	}
+
+	return c.notify.Retention
+}
@@ -0,0 +1,66 @@
+package config_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dhaifley/game2d/config"
+)
+
+func TestNotifyConfig(t *testing.T) {
+	t.Parallel()
+
+	cfg := config.New("")
+
+	cfg.Load(nil)
+
+	if cfg.NotifyRetention() != config.DefaultNotifyRetention {
+		t.Errorf("Expected notify retention: %v, got: %v",
+			config.DefaultNotifyRetention, cfg.NotifyRetention())
+	}
+
+	cfg.SetNotify(&config.NotifyConfig{
+		SMTPAddress:    "localhost:25",
+		SMTPUsername:   "test",
+		SMTPPassword:   "test",
+		SMTPFrom:       "test@example.com",
+		PushPrivateKey: "test",
+		PushSubject:    "mailto:test@example.com",
+		Retention:      time.Hour,
+	})
+
+	if cfg.NotifySMTPAddress() != "localhost:25" {
+		t.Errorf("Expected notify smtp address: localhost:25, got: %v",
+			cfg.NotifySMTPAddress())
+	}
+
+	if cfg.NotifySMTPUsername() != "test" {
+		t.Errorf("Expected notify smtp username: test, got: %v",
+			cfg.NotifySMTPUsername())
+	}
+
+	if cfg.NotifySMTPPassword() != "test" {
+		t.Errorf("Expected notify smtp password: test, got: %v",
+			cfg.NotifySMTPPassword())
+	}
+
+	if cfg.NotifySMTPFrom() != "test@example.com" {
+		t.Errorf("Expected notify smtp from: test@example.com, got: %v",
+			cfg.NotifySMTPFrom())
+	}
+
+	if cfg.NotifyPushPrivateKey() != "test" {
+		t.Errorf("Expected notify push private key: test, got: %v",
+			cfg.NotifyPushPrivateKey())
+	}
+
+	if cfg.NotifyPushSubject() != "mailto:test@example.com" {
+		t.Errorf("Expected notify push subject: mailto:test@example.com, "+
+			"got: %v", cfg.NotifyPushSubject())
+	}
+
+	if cfg.NotifyRetention() != time.Hour {
+		t.Errorf("Expected notify retention: 1h, got: %v",
+			cfg.NotifyRetention())
+	}
+}
@@ -0,0 +1,234 @@
+package config
+
+import (
+	"context"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dhaifley/game2d/errors"
+)
+
+const (
+	// SecretTimeout is the maximum duration of a secret resolution.
+	SecretTimeout = time.Second * 10
+
+	// SecretFileSuffix is appended to the name of a configuration environment
+	// variable to name a variable containing the path of a file from which the
+	// value is read, such as a Docker or Kubernetes secret.
+	SecretFileSuffix = "_FILE"
+)
+
+// SecretResolver values resolve references to secrets held in external secret
+// stores. A reference has the form scheme://path#field, where the field is
+// optional.
+type SecretResolver interface {
+	ResolveSecret(ctx context.Context, ref string) (string, error)
+}
+
+// SecretResolverFunc functions are used as secret resolvers.
+type SecretResolverFunc func(ctx context.Context, ref string) (string, error)
+
+// ResolveSecret resolves a reference to a secret.
+func (f SecretResolverFunc) ResolveSecret(ctx context.Context,
+	ref string,
+) (string, error) {
+	return f(ctx, ref)
+}
+
+// secretRegistry values contain the registered secret resolvers, and the last
+// value resolved for each secret reference.
+type secretRegistry struct {
+	sync.Mutex
+	once      sync.Once
+	resolvers map[string]SecretResolver
+	values    map[string]string
+	changed   bool
+	errs      []error
+}
+
+var secrets = &secretRegistry{
+	resolvers: map[string]SecretResolver{},
+	values:    map[string]string{},
+}
+
+// RegisterSecretResolver registers a resolver for secret references using a
+// scheme, such as "vault". Registering a nil resolver removes the resolver for
+// the scheme. Resolvers for Vault and AWS Secrets Manager are registered
+// automatically when their standard environment variables are set.
+func RegisterSecretResolver(scheme string, r SecretResolver) {
+	secrets.once.Do(registerDefaultResolvers)
+
+	secrets.Lock()
+	defer secrets.Unlock()
+
+	if r == nil {
+		delete(secrets.resolvers, scheme)
+
+		return
+	}
+
+	secrets.resolvers[scheme] = r
+}
+
+// registerDefaultResolvers registers the secret resolvers configured by their
+// standard environment variables.
+func registerDefaultResolvers() {
+	secrets.Lock()
+	defer secrets.Unlock()
+
+	if addr, token := os.Getenv("VAULT_ADDR"),
+		os.Getenv("VAULT_TOKEN"); addr != "" && token != "" {
+		secrets.resolvers[SchemeVault] = NewVaultResolver(addr, token)
+	}
+
+	region := os.Getenv("AWS_REGION")
+	if region == "" {
+		region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+
+	if ak, sk := os.Getenv("AWS_ACCESS_KEY_ID"),
+		os.Getenv("AWS_SECRET_ACCESS_KEY"); region != "" && ak != "" &&
+		sk != "" {
+		secrets.resolvers[SchemeAWSSecrets] = NewAWSSecretsResolver(region,
+			ak, sk, os.Getenv("AWS_SESSION_TOKEN"))
+	}
+}
+
+// secretScheme returns the scheme of a secret reference, or an empty string if
+// the value is not a secret reference.
+func secretScheme(v string) string {
+	scheme, _, ok := strings.Cut(v, "://")
+	if !ok || scheme == "" || strings.ContainsAny(scheme, " /:") {
+		return ""
+	}
+
+	return scheme
+}
+
+// splitSecretRef splits a secret reference into its path and field.
+func splitSecretRef(ref string) (string, string) {
+	_, p, _ := strings.Cut(ref, "://")
+
+	p, field, _ := strings.Cut(p, "#")
+
+	return p, field
+}
+
+// ResolveSecret resolves a value which may be a secret reference. Values which
+// are not references using the scheme of a registered resolver are returned
+// unchanged.
+func ResolveSecret(ctx context.Context, v string) (string, error) {
+	secrets.once.Do(registerDefaultResolvers)
+
+	scheme := secretScheme(v)
+	if scheme == "" {
+		return v, nil
+	}
+
+	secrets.Lock()
+
+	r, ok := secrets.resolvers[scheme]
+
+	secrets.Unlock()
+
+	if !ok {
+		return v, nil
+	}
+
+	sv, err := r.ResolveSecret(ctx, v)
+	if err != nil {
+		return "", errors.Wrap(err, errors.ErrUnavailable,
+			"unable to resolve secret",
+			"scheme", scheme)
+	}
+
+	secrets.record(v, sv)
+
+	return sv, nil
+}
+
+// record records the value of a secret, noting whether it changed since it
+// was last recorded.
+func (sr *secretRegistry) record(key, v string) {
+	sr.Lock()
+	defer sr.Unlock()
+
+	if prev, ok := sr.values[key]; ok && prev != v {
+		sr.changed = true
+	}
+
+	sr.values[key] = v
+}
+
+// getEnv returns the value of the environment variable for a configuration
+// key. If it is not set, the value is read from the file named by the variable
+// with the secret file suffix, if that is set. Secret references are resolved,
+// and an empty string is returned if they cannot be, so that the current value
+// is kept.
+func getEnv(key string) string {
+	name := ReplaceEnv(key)
+
+	v := os.Getenv(name)
+
+	if v == "" {
+		if f := os.Getenv(name + SecretFileSuffix); f != "" {
+			b, err := os.ReadFile(f)
+			if err != nil {
+				os.Stderr.WriteString("unable to read secret file: " + f +
+					": " + err.Error() + "\n")
+
+				return ""
+			}
+
+			v = strings.TrimRight(string(b), "\r\n")
+
+			secrets.record(f, v)
+		}
+	}
+
+	if secretScheme(v) == "" {
+		return v
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), SecretTimeout)
+	defer cancel()
+
+	sv, err := ResolveSecret(ctx, v)
+	if err != nil {
+		os.Stderr.WriteString("unable to resolve secret: " + name + ": " +
+			err.Error() + "\n")
+
+		secrets.Lock()
+
+		secrets.errs = append(secrets.errs, errors.Wrap(err,
+			errors.ErrUnavailable, "unable to resolve configuration secret",
+			"env", name))
+
+		secrets.Unlock()
+
+		return ""
+	}
+
+	return sv
+}
+
+// RotateSecrets reloads the configuration from the environment, reading secret
+// files and resolving secret references again. It returns whether any
+// resolved secret value changed, and any errors resolving secrets, in which
+// case the previous values are kept.
+func (c *Config) RotateSecrets() (bool, error) {
+	secrets.Lock()
+
+	secrets.changed, secrets.errs = false, nil
+
+	secrets.Unlock()
+
+	c.Load(nil)
+
+	secrets.Lock()
+	defer secrets.Unlock()
+
+	return secrets.changed, errors.Join(secrets.errs...)
+}
@@ -3,48 +3,137 @@ package config
 import (
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
 const (
-	KeyServerAddress        = "server/address"
-	KeyServerCert           = "server/certificate"
-	KeyServerKey            = "server/key"
-	KeyServerTimeout        = "server/timeout"
-	KeyServerIdleTimeout    = "server/idle_timeout"
-	KeyServerPromptTimeout  = "server/prompt_timeout"
-	KeyServerHost           = "server/host"
-	KeyServerPathPrefix     = "server/path_prefix"
-	KeyServerMaxRequestSize = "server/max_request_size"
-
-	DefaultServerAddress        = ":8080"
-	DefaultServerCert           = ""
-	DefaultServerKey            = ""
-	DefaultServerTimeout        = time.Second * 30
-	DefaultServerIdleTimeout    = time.Second * 5
-	DefaultServerPromptTimeout  = time.Minute * 15
-	DefaultServerHost           = "game2d.ai"
-	DefaultServerPathPrefix     = "/api/v1"
-	DefaultServerMaxRequestSize = int64(20 * 1024 * 1023) // 20 MB
+	KeyServerAddress             = "server/address"
+	KeyServerCert                = "server/certificate"
+	KeyServerKey                 = "server/key"
+	KeyServerTimeout             = "server/timeout"
+	KeyServerIdleTimeout         = "server/idle_timeout"
+	KeyServerPromptTimeout       = "server/prompt_timeout"
+	KeyServerPromptStreamTimeout = "server/prompt_stream_timeout"
+	KeyServerHost                = "server/host"
+	KeyServerPathPrefix          = "server/path_prefix"
+	KeyServerMaxRequestSize      = "server/max_request_size"
+	KeyServerMaxAuthSize         = "server/max_auth_request_size"
+	KeyServerMaxGamesSize        = "server/max_games_request_size"
+	KeyServerMaxTagsSize         = "server/max_tags_request_size"
+	KeyServerCORSOrigins         = "server/cors_origins"
+	KeyServerCORSMethods         = "server/cors_methods"
+	KeyServerCORSHeaders         = "server/cors_headers"
+	KeyServerCORSMaxAge          = "server/cors_max_age"
+	KeyServerCORSNoCreds         = "server/cors_no_credentials"
+	KeyServerAutocert            = "server/autocert"
+	KeyServerAutocertDir         = "server/autocert_dir"
+	KeyServerAutocertEmail       = "server/autocert_email"
+	KeyServerRedirectAddr        = "server/redirect_address"
+	KeyServerProtocols           = "server/protocols"
+	KeyServerDrainDelay          = "server/drain_delay"
+	KeyServerDrainTimeout        = "server/drain_timeout"
+	KeyServerOutboundTimeout     = "server/outbound_timeout"
+	KeyServerTaskTimeout         = "server/task_timeout"
+	KeyServerStartupGrace        = "server/startup_grace"
+	KeyServerStatusWebhook       = "server/status_webhook"
+	KeyServerHooks               = "server/hooks"
+
+	DefaultServerAddress             = ":8080"
+	DefaultServerCert                = ""
+	DefaultServerKey                 = ""
+	DefaultServerTimeout             = time.Second * 30
+	DefaultServerIdleTimeout         = time.Second * 5
+	DefaultServerPromptTimeout       = time.Minute * 15
+	DefaultServerPromptStreamTimeout = time.Minute * 10
+	DefaultServerHost                = "game2d.ai"
+	DefaultServerPathPrefix          = "/api/v1"
+	DefaultServerMaxRequestSize      = int64(20 * 1024 * 1023) // 20 MB
+	DefaultServerMaxAuthSize         = int64(64 * 1024)        // 64 KB
+	DefaultServerMaxGamesSize        = int64(20 * 1024 * 1024) // 20 MB
+	DefaultServerMaxTagsSize         = int64(16 * 1024)        // 16 KB
+	DefaultServerCORSMaxAge          = time.Minute * 10
+	DefaultServerCORSNoCreds         = false
+	DefaultServerAutocert            = false
+	DefaultServerAutocertDir         = "autocert"
+	DefaultServerAutocertEmail       = ""
+	DefaultServerRedirectAddr        = ""
+	DefaultServerDrainDelay          = time.Duration(0)
+	DefaultServerDrainTimeout        = time.Second * 30
+	DefaultServerOutboundTimeout     = time.Second * 30
+	DefaultServerTaskTimeout         = time.Minute * 5
+	DefaultServerStartupGrace        = time.Minute * 2
+	DefaultServerStatusWebhook       = ""
 )
 
+// DefaultServerProtocols are the protocols served by default. The supported
+// protocols are http1, http2, and h2c, which is unencrypted HTTP/2.
+var DefaultServerProtocols = []string{"http1", "http2"}
+
+// DefaultServerCORSMethods are the HTTP methods allowed in cross-origin
+// requests by default.
+var DefaultServerCORSMethods = []string{
+	"GET", "PUT", "PATCH", "POST", "DELETE",
+}
+
+// DefaultServerCORSHeaders are the request headers allowed in cross-origin
+// requests by default.
+var DefaultServerCORSHeaders = []string{
+	"Origin", "X-Requested-With", "X-HTTP-Method-Override", "Content-Type",
+	"Accept", "Referer", "User-Agent",
+}
+
+// DefaultServerCORSOrigins returns the origins allowed to make cross-origin
+// requests by default, which are the server host and its sub-domains.
+func DefaultServerCORSOrigins(host string) []string {
+	return []string{host, "http://" + host, "https://" + host, "*." + host}
+}
+
+// splitList splits a comma or space separated list of values.
+func splitList(v string) []string {
+	return strings.FieldsFunc(v, func(r rune) bool {
+		return r == ',' || r == ' '
+	})
+}
+
 // ServerConfig values represent telemetry configuration data.
 type ServerConfig struct {
-	Address        string        `json:"address,omitempty"          yaml:"address,omitempty"`
-	Cert           string        `json:"cert,omitempty"             yaml:"cert,omitempty"`
-	Key            string        `json:"key,omitempty"              yaml:"key,omitempty"`
-	Timeout        time.Duration `json:"timeout,omitempty"          yaml:"timeout,omitempty"`
-	IdleTimeout    time.Duration `json:"idle_timeout,omitempty"     yaml:"idle_timeout,omitempty"`
-	PromptTimeout  time.Duration `json:"prompt_timeout,omitempty"   yaml:"prompt_timeout,omitempty"`
-	Host           string        `json:"host,omitempty"             yaml:"host,omitempty"`
-	PathPrefix     string        `json:"path_prefix,omitempty"      yaml:"path_prefix,omitempty"`
-	MaxRequestSize int64         `json:"max_request_size,omitempty" yaml:"max_request_size,omitempty"`
+	Address             string        `json:"address,omitempty"                yaml:"address,omitempty"`
+	Cert                string        `json:"cert,omitempty"                   yaml:"cert,omitempty"`
+	Key                 string        `json:"key,omitempty"                    yaml:"key,omitempty"`
+	Timeout             time.Duration `json:"timeout,omitempty"                yaml:"timeout,omitempty"`
+	IdleTimeout         time.Duration `json:"idle_timeout,omitempty"           yaml:"idle_timeout,omitempty"`
+	PromptTimeout       time.Duration `json:"prompt_timeout,omitempty"         yaml:"prompt_timeout,omitempty"`
+	PromptStreamTimeout time.Duration `json:"prompt_stream_timeout,omitempty"  yaml:"prompt_stream_timeout,omitempty"`
+	Host                string        `json:"host,omitempty"                   yaml:"host,omitempty"`
+	PathPrefix          string        `json:"path_prefix,omitempty"            yaml:"path_prefix,omitempty"`
+	MaxRequestSize      int64         `json:"max_request_size,omitempty"       yaml:"max_request_size,omitempty"`
+	MaxAuthSize         int64         `json:"max_auth_request_size,omitempty"  yaml:"max_auth_request_size,omitempty"`
+	MaxGamesSize        int64         `json:"max_games_request_size,omitempty" yaml:"max_games_request_size,omitempty"`
+	MaxTagsSize         int64         `json:"max_tags_request_size,omitempty"  yaml:"max_tags_request_size,omitempty"`
+	CORSOrigins         []string      `json:"cors_origins,omitempty"           yaml:"cors_origins,omitempty"`
+	CORSMethods         []string      `json:"cors_methods,omitempty"           yaml:"cors_methods,omitempty"`
+	CORSHeaders         []string      `json:"cors_headers,omitempty"           yaml:"cors_headers,omitempty"`
+	CORSMaxAge          time.Duration `json:"cors_max_age,omitempty"           yaml:"cors_max_age,omitempty"`
+	CORSNoCreds         bool          `json:"cors_no_credentials,omitempty"    yaml:"cors_no_credentials,omitempty"`
+	Autocert            bool          `json:"autocert,omitempty"               yaml:"autocert,omitempty"`
+	AutocertDir         string        `json:"autocert_dir,omitempty"           yaml:"autocert_dir,omitempty"`
+	AutocertEmail       string        `json:"autocert_email,omitempty"         yaml:"autocert_email,omitempty"`
+	RedirectAddr        string        `json:"redirect_address,omitempty"       yaml:"redirect_address,omitempty"`
+	Protocols           []string      `json:"protocols,omitempty"              yaml:"protocols,omitempty"`
+	DrainDelay          time.Duration `json:"drain_delay,omitempty"            yaml:"drain_delay,omitempty"`
+	DrainTimeout        time.Duration `json:"drain_timeout,omitempty"          yaml:"drain_timeout,omitempty"`
+	OutboundTimeout     time.Duration `json:"outbound_timeout,omitempty"       yaml:"outbound_timeout,omitempty"`
+	TaskTimeout         time.Duration `json:"task_timeout,omitempty"           yaml:"task_timeout,omitempty"`
+	StartupGrace        time.Duration `json:"startup_grace,omitempty"          yaml:"startup_grace,omitempty"`
+	StatusWebhook       string        `json:"status_webhook,omitempty"         yaml:"status_webhook,omitempty"`
+	Hooks               []string      `json:"hooks,omitempty"                  yaml:"hooks,omitempty"`
 }
 
 // Load reads configuration data from environment variables and applies defaults
 // for any missing or invalid configuration data.
 func (c *ServerConfig) Load() {
-	if v := os.Getenv(ReplaceEnv(KeyServerAddress)); v != "" {
+	if v := getEnv(KeyServerAddress); v != "" {
 		c.Address = v
 	}
 
@@ -52,7 +141,7 @@ func (c *ServerConfig) Load() {
 		c.Address = DefaultServerAddress
 	}
 
-	if v := os.Getenv(ReplaceEnv(KeyServerCert)); v != "" {
+	if v := getEnv(KeyServerCert); v != "" {
 		c.Cert = v
 	}
 
@@ -60,7 +149,7 @@ func (c *ServerConfig) Load() {
 		c.Cert = DefaultServerCert
 	}
 
-	if v := os.Getenv(ReplaceEnv(KeyServerKey)); v != "" {
+	if v := getEnv(KeyServerKey); v != "" {
 		c.Key = v
 	}
 
@@ -68,7 +157,7 @@ func (c *ServerConfig) Load() {
 		c.Key = DefaultServerKey
 	}
 
-	if v := os.Getenv(ReplaceEnv(KeyServerTimeout)); v != "" {
+	if v := getEnv(KeyServerTimeout); v != "" {
 		v, err := time.ParseDuration(v)
 		if err != nil {
 			v = DefaultServerTimeout
@@ -81,7 +170,7 @@ func (c *ServerConfig) Load() {
 		c.Timeout = DefaultServerTimeout
 	}
 
-	if v := os.Getenv(ReplaceEnv(KeyServerIdleTimeout)); v != "" {
+	if v := getEnv(KeyServerIdleTimeout); v != "" {
 		v, err := time.ParseDuration(v)
 		if err != nil {
 			v = DefaultServerIdleTimeout
@@ -94,7 +183,7 @@ func (c *ServerConfig) Load() {
 		c.IdleTimeout = DefaultServerIdleTimeout
 	}
 
-	if v := os.Getenv(ReplaceEnv(KeyServerPromptTimeout)); v != "" {
+	if v := getEnv(KeyServerPromptTimeout); v != "" {
 		v, err := time.ParseDuration(v)
 		if err != nil {
 			v = DefaultServerPromptTimeout
@@ -107,7 +196,20 @@ func (c *ServerConfig) Load() {
 		c.PromptTimeout = DefaultServerPromptTimeout
 	}
 
-	if v := os.Getenv(ReplaceEnv(KeyServerHost)); v != "" {
+	if v := getEnv(KeyServerPromptStreamTimeout); v != "" {
+		v, err := time.ParseDuration(v)
+		if err != nil {
+			v = DefaultServerPromptStreamTimeout
+		}
+
+		c.PromptStreamTimeout = v
+	}
+
+	if c.PromptStreamTimeout == 0 {
+		c.PromptStreamTimeout = DefaultServerPromptStreamTimeout
+	}
+
+	if v := getEnv(KeyServerHost); v != "" {
 		c.Host = v
 	} else if v := os.Getenv("host"); v != "" {
 		c.Host = v
@@ -117,7 +219,7 @@ func (c *ServerConfig) Load() {
 		c.Host = DefaultServerHost
 	}
 
-	if v := os.Getenv(ReplaceEnv(KeyServerPathPrefix)); v != "" {
+	if v := getEnv(KeyServerPathPrefix); v != "" {
 		c.PathPrefix = v
 	}
 
@@ -125,7 +227,7 @@ func (c *ServerConfig) Load() {
 		c.PathPrefix = DefaultServerPathPrefix
 	}
 
-	if v := os.Getenv(ReplaceEnv(KeyServerMaxRequestSize)); v != "" {
+	if v := getEnv(KeyServerMaxRequestSize); v != "" {
 		v, err := strconv.ParseInt(v, 10, 64)
 		if err != nil {
 			v = DefaultServerMaxRequestSize
@@ -137,6 +239,193 @@ func (c *ServerConfig) Load() {
 	if c.MaxRequestSize == 0 {
 		c.MaxRequestSize = DefaultServerMaxRequestSize
 	}
+
+	if v := getEnv(KeyServerMaxAuthSize); v != "" {
+		v, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			v = DefaultServerMaxAuthSize
+		}
+
+		c.MaxAuthSize = v
+	}
+
+	if c.MaxAuthSize == 0 {
+		c.MaxAuthSize = DefaultServerMaxAuthSize
+	}
+
+	if v := getEnv(KeyServerMaxGamesSize); v != "" {
+		v, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			v = DefaultServerMaxGamesSize
+		}
+
+		c.MaxGamesSize = v
+	}
+
+	if c.MaxGamesSize == 0 {
+		c.MaxGamesSize = DefaultServerMaxGamesSize
+	}
+
+	if v := getEnv(KeyServerMaxTagsSize); v != "" {
+		v, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			v = DefaultServerMaxTagsSize
+		}
+
+		c.MaxTagsSize = v
+	}
+
+	if c.MaxTagsSize == 0 {
+		c.MaxTagsSize = DefaultServerMaxTagsSize
+	}
+
+	if v := getEnv(KeyServerCORSOrigins); v != "" {
+		c.CORSOrigins = splitList(v)
+	}
+
+	if c.CORSOrigins == nil {
+		c.CORSOrigins = DefaultServerCORSOrigins(c.Host)
+	}
+
+	if v := getEnv(KeyServerCORSMethods); v != "" {
+		c.CORSMethods = splitList(v)
+	}
+
+	if c.CORSMethods == nil {
+		c.CORSMethods = DefaultServerCORSMethods
+	}
+
+	if v := getEnv(KeyServerCORSHeaders); v != "" {
+		c.CORSHeaders = splitList(v)
+	}
+
+	if c.CORSHeaders == nil {
+		c.CORSHeaders = DefaultServerCORSHeaders
+	}
+
+	if v := getEnv(KeyServerCORSMaxAge); v != "" {
+		v, err := time.ParseDuration(v)
+		if err != nil {
+			v = DefaultServerCORSMaxAge
+		}
+
+		c.CORSMaxAge = v
+	}
+
+	if c.CORSMaxAge == 0 {
+		c.CORSMaxAge = DefaultServerCORSMaxAge
+	}
+
+	if v := getEnv(KeyServerCORSNoCreds); v != "" {
+		v, err := strconv.ParseBool(v)
+		if err != nil {
+			v = DefaultServerCORSNoCreds
+		}
+
+		c.CORSNoCreds = v
+	}
+
+	if v := getEnv(KeyServerAutocert); v != "" {
+		v, err := strconv.ParseBool(v)
+		if err != nil {
+			v = DefaultServerAutocert
+		}
+
+		c.Autocert = v
+	}
+
+	if v := getEnv(KeyServerAutocertDir); v != "" {
+		c.AutocertDir = v
+	}
+
+	if c.AutocertDir == "" {
+		c.AutocertDir = DefaultServerAutocertDir
+	}
+
+	if v := getEnv(KeyServerAutocertEmail); v != "" {
+		c.AutocertEmail = v
+	}
+
+	if v := getEnv(KeyServerRedirectAddr); v != "" {
+		c.RedirectAddr = v
+	}
+
+	if v := getEnv(KeyServerProtocols); v != "" {
+		c.Protocols = splitList(v)
+	}
+
+	if c.Protocols == nil {
+		c.Protocols = DefaultServerProtocols
+	}
+
+	if v := getEnv(KeyServerDrainDelay); v != "" {
+		v, err := time.ParseDuration(v)
+		if err != nil {
+			v = DefaultServerDrainDelay
+		}
+
+		c.DrainDelay = v
+	}
+
+	if v := getEnv(KeyServerDrainTimeout); v != "" {
+		v, err := time.ParseDuration(v)
+		if err != nil {
+			v = DefaultServerDrainTimeout
+		}
+
+		c.DrainTimeout = v
+	}
+
+	if c.DrainTimeout == 0 {
+		c.DrainTimeout = DefaultServerDrainTimeout
+	}
+
+	if v := getEnv(KeyServerOutboundTimeout); v != "" {
+		v, err := time.ParseDuration(v)
+		if err != nil {
+			v = DefaultServerOutboundTimeout
+		}
+
+		c.OutboundTimeout = v
+	}
+
+	if c.OutboundTimeout == 0 {
+		c.OutboundTimeout = DefaultServerOutboundTimeout
+	}
+
+	if v := getEnv(KeyServerTaskTimeout); v != "" {
+		v, err := time.ParseDuration(v)
+		if err != nil {
+			v = DefaultServerTaskTimeout
+		}
+
+		c.TaskTimeout = v
+	}
+
+	if c.TaskTimeout == 0 {
+		c.TaskTimeout = DefaultServerTaskTimeout
+	}
+
+	if v := getEnv(KeyServerStartupGrace); v != "" {
+		v, err := time.ParseDuration(v)
+		if err != nil {
+			v = DefaultServerStartupGrace
+		}
+
+		c.StartupGrace = v
+	}
+
+	if c.StartupGrace == 0 {
+		c.StartupGrace = DefaultServerStartupGrace
+	}
+
+	if v := getEnv(KeyServerStatusWebhook); v != "" {
+		c.StatusWebhook = v
+	}
+
+	if v := getEnv(KeyServerHooks); v != "" {
+		c.Hooks = splitList(v)
+	}
 }
 
 // ServerAddress returns the address of the collector where metrics data is
@@ -179,7 +468,8 @@ func (c *Config) ServerKey() string {
 }
 
 // ServerTimeout returns a duration representing the maximum time a server
-// request is allowed to run before timing out.
+// request is allowed to run before timing out. Longer running work, such as
+// prompts and imports, is limited by its own timeout.
 func (c *Config) ServerTimeout() time.Duration {
 	c.RLock()
 	defer c.RUnlock()
@@ -252,3 +542,277 @@ func (c *Config) ServerMaxRequestSize() int64 {
 
 	return c.server.MaxRequestSize
 }
+
+// ServerMaxAuthRequestSize returns the maximum allowable size in bytes of
+// requests to the login, account, and user routes.
+func (c *Config) ServerMaxAuthRequestSize() int64 {
+	c.RLock()
+	defer c.RUnlock()
+
+	if c.server == nil {
+		return DefaultServerMaxAuthSize
+	}
+
+	return c.server.MaxAuthSize
+}
+
+// ServerMaxGamesRequestSize returns the maximum allowable size in bytes of
+// requests to the games routes.
+func (c *Config) ServerMaxGamesRequestSize() int64 {
+	c.RLock()
+	defer c.RUnlock()
+
+	if c.server == nil {
+		return DefaultServerMaxGamesSize
+	}
+
+	return c.server.MaxGamesSize
+}
+
+// ServerMaxTagsRequestSize returns the maximum allowable size in bytes of
+// requests to the game tags routes.
+func (c *Config) ServerMaxTagsRequestSize() int64 {
+	c.RLock()
+	defer c.RUnlock()
+
+	if c.server == nil {
+		return DefaultServerMaxTagsSize
+	}
+
+	return c.server.MaxTagsSize
+}
+
+// ServerCORSOrigins returns the origins allowed to make cross-origin requests
+// to the server. Origins may contain * wildcards.
+func (c *Config) ServerCORSOrigins() []string {
+	c.RLock()
+	defer c.RUnlock()
+
+	if c.server == nil {
+		return DefaultServerCORSOrigins(DefaultServerHost)
+	}
+
+	return c.server.CORSOrigins
+}
+
+// ServerCORSMethods returns the HTTP methods allowed in cross-origin requests.
+func (c *Config) ServerCORSMethods() []string {
+	c.RLock()
+	defer c.RUnlock()
+
+	if c.server == nil {
+		return DefaultServerCORSMethods
+	}
+
+	return c.server.CORSMethods
+}
+
+// ServerCORSHeaders returns the request headers allowed in cross-origin
+// requests.
+func (c *Config) ServerCORSHeaders() []string {
+	c.RLock()
+	defer c.RUnlock()
+
+	if c.server == nil {
+		return DefaultServerCORSHeaders
+	}
+
+	return c.server.CORSHeaders
+}
+
+// ServerCORSMaxAge returns the duration for which the results of a preflight
+// request may be cached.
+func (c *Config) ServerCORSMaxAge() time.Duration {
+	c.RLock()
+	defer c.RUnlock()
+
+	if c.server == nil {
+		return DefaultServerCORSMaxAge
+	}
+
+	return c.server.CORSMaxAge
+}
+
+// ServerCORSCredentials returns whether cross-origin requests may include
+// credentials.
+func (c *Config) ServerCORSCredentials() bool {
+	c.RLock()
+	defer c.RUnlock()
+
+	if c.server == nil {
+		return !DefaultServerCORSNoCreds
+	}
+
+	return !c.server.CORSNoCreds
+}
+
+// ServerAutocert returns whether the server obtains TLS certificates for the
+// server host automatically, using ACME.
+func (c *Config) ServerAutocert() bool {
+	c.RLock()
+	defer c.RUnlock()
+
+	if c.server == nil {
+		return DefaultServerAutocert
+	}
+
+	return c.server.Autocert
+}
+
+// ServerAutocertDir returns the directory in which automatically obtained TLS
+// certificates are cached.
+func (c *Config) ServerAutocertDir() string {
+	c.RLock()
+	defer c.RUnlock()
+
+	if c.server == nil {
+		return DefaultServerAutocertDir
+	}
+
+	return c.server.AutocertDir
+}
+
+// ServerAutocertEmail returns the contact email address used when obtaining
+// TLS certificates automatically.
+func (c *Config) ServerAutocertEmail() string {
+	c.RLock()
+	defer c.RUnlock()
+
+	if c.server == nil {
+		return DefaultServerAutocertEmail
+	}
+
+	return c.server.AutocertEmail
+}
+
+// ServerRedirectAddress returns the address on which the server listens for
+// plaintext HTTP requests, which are redirected to HTTPS.
+func (c *Config) ServerRedirectAddress() string {
+	c.RLock()
+	defer c.RUnlock()
+
+	if c.server == nil {
+		return DefaultServerRedirectAddr
+	}
+
+	return c.server.RedirectAddr
+}
+
+// ServerProtocols returns the protocols served by the server.
+func (c *Config) ServerProtocols() []string {
+	c.RLock()
+	defer c.RUnlock()
+
+	if c.server == nil {
+		return DefaultServerProtocols
+	}
+
+	return c.server.Protocols
+}
+
+// ServerDrainDelay returns the duration for which the server continues to
+// accept new requests after it begins shutting down and reports itself as
+// unhealthy, so load balancers can stop routing requests to it.
+func (c *Config) ServerDrainDelay() time.Duration {
+	c.RLock()
+	defer c.RUnlock()
+
+	if c.server == nil {
+		return DefaultServerDrainDelay
+	}
+
+	return c.server.DrainDelay
+}
+
+// ServerDrainTimeout returns the maximum duration the server waits for active
+// connections to finish while shutting down, before closing them.
+func (c *Config) ServerDrainTimeout() time.Duration {
+	c.RLock()
+	defer c.RUnlock()
+
+	if c.server == nil {
+		return DefaultServerDrainTimeout
+	}
+
+	return c.server.DrainTimeout
+}
+
+// ServerOutboundTimeout returns the maximum duration of outbound calls made by
+// the server, such as webhooks, notifications and identity provider requests.
+func (c *Config) ServerOutboundTimeout() time.Duration {
+	c.RLock()
+	defer c.RUnlock()
+
+	if c.server == nil {
+		return DefaultServerOutboundTimeout
+	}
+
+	return c.server.OutboundTimeout
+}
+
+// ServerTaskTimeout returns the maximum duration of each run of a periodic
+// background task, such as updating timed out prompts for an account.
+func (c *Config) ServerTaskTimeout() time.Duration {
+	c.RLock()
+	defer c.RUnlock()
+
+	if c.server == nil {
+		return DefaultServerTaskTimeout
+	}
+
+	return c.server.TaskTimeout
+}
+
+// ServerStartupGrace returns the duration after the server starts, during
+// which it reports itself as healthy, but not ready, while it connects to the
+// database. After it, the server reports itself as unhealthy until connected.
+func (c *Config) ServerStartupGrace() time.Duration {
+	c.RLock()
+	defer c.RUnlock()
+
+	if c.server == nil {
+		return DefaultServerStartupGrace
+	}
+
+	return c.server.StartupGrace
+}
+
+// ServerPromptStreamTimeout returns the maximum duration of the AI
+// response stream of a prompt. It is shorter than the prompt timeout, so that
+// the result of a prompt which times out can still be saved.
+func (c *Config) ServerPromptStreamTimeout() time.Duration {
+	c.RLock()
+	defer c.RUnlock()
+
+	if c.server == nil {
+		return DefaultServerPromptStreamTimeout
+	}
+
+	return c.server.PromptStreamTimeout
+}
+
+// ServerStatusWebhook returns the URL to which game status transitions are
+// posted. If it is empty, no webhook requests are sent.
+func (c *Config) ServerStatusWebhook() string {
+	c.RLock()
+	defer c.RUnlock()
+
+	if c.server == nil {
+		return DefaultServerStatusWebhook
+	}
+
+	return c.server.StatusWebhook
+}
+
+// ServerHooks returns the names of the server extension hooks which are
+// enabled, in the order in which they are run. If it is empty, no hooks run.
+func (c *Config) ServerHooks() []string {
+	c.RLock()
+	defer c.RUnlock()
+
+	if c.server == nil {
+		return nil
+	}
+
+	return c.server.Hooks
+}
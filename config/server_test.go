@@ -15,15 +15,36 @@ func TestServerConfig(t *testing.T) {
 	cfg.Load(nil)
 
 	cfg.SetServer(&config.ServerConfig{
-		Address:        ":8090",
-		Cert:           "test",
-		Key:            "test",
-		Timeout:        time.Second * 10,
-		IdleTimeout:    time.Second * 10,
-		PromptTimeout:  time.Second * 10,
-		Host:           "test.com",
-		PathPrefix:     "/api/v2",
-		MaxRequestSize: 10,
+		Address:             ":8090",
+		Cert:                "test",
+		Key:                 "test",
+		Timeout:             time.Second * 10,
+		IdleTimeout:         time.Second * 10,
+		PromptTimeout:       time.Second * 10,
+		PromptStreamTimeout: time.Second * 5,
+		Host:                "test.com",
+		PathPrefix:          "/api/v2",
+		MaxRequestSize:      10,
+		MaxAuthSize:         11,
+		MaxGamesSize:        12,
+		MaxTagsSize:         13,
+		CORSOrigins:         []string{"https://*.test.com"},
+		CORSMethods:         []string{"GET"},
+		CORSHeaders:         []string{"Content-Type"},
+		CORSMaxAge:          time.Second * 10,
+		CORSNoCreds:         true,
+		Autocert:            true,
+		AutocertDir:         "test",
+		AutocertEmail:       "test@test.com",
+		RedirectAddr:        ":8081",
+		Protocols:           []string{"h2c"},
+		DrainDelay:          time.Second * 5,
+		DrainTimeout:        time.Second * 10,
+		OutboundTimeout:     time.Second * 15,
+		TaskTimeout:         time.Minute,
+		StartupGrace:        time.Second * 45,
+		StatusWebhook:       "https://test.com/hook",
+		Hooks:               []string{"audit", "quota"},
 	})
 
 	if cfg.ServerAddress() != ":8090" {
@@ -42,6 +63,10 @@ func TestServerConfig(t *testing.T) {
 		t.Errorf("Expected timeout: 10s, got: %v", cfg.ServerTimeout())
 	}
 
+	if v := cfg.ServerPromptStreamTimeout(); v != time.Second*5 {
+		t.Errorf("Expected prompt stream timeout: 5s, got: %v", v)
+	}
+
 	if cfg.ServerIdleTimeout() != time.Second*10 {
 		t.Errorf("Expected idle timeout: 10s, got: %v",
 			cfg.ServerIdleTimeout())
@@ -64,4 +89,98 @@ func TestServerConfig(t *testing.T) {
 		t.Errorf("Expected max request size: 10, got: %v",
 			cfg.ServerMaxRequestSize())
 	}
+
+	if cfg.ServerMaxAuthRequestSize() != 11 {
+		t.Errorf("Expected max auth request size: 11, got: %v",
+			cfg.ServerMaxAuthRequestSize())
+	}
+
+	if cfg.ServerMaxGamesRequestSize() != 12 {
+		t.Errorf("Expected max games request size: 12, got: %v",
+			cfg.ServerMaxGamesRequestSize())
+	}
+
+	if cfg.ServerMaxTagsRequestSize() != 13 {
+		t.Errorf("Expected max tags request size: 13, got: %v",
+			cfg.ServerMaxTagsRequestSize())
+	}
+
+	if v := cfg.ServerCORSOrigins(); len(v) != 1 || v[0] != "https://*.test.com" {
+		t.Errorf("Expected CORS origins: [https://*.test.com], got: %v", v)
+	}
+
+	if v := cfg.ServerCORSMethods(); len(v) != 1 || v[0] != "GET" {
+		t.Errorf("Expected CORS methods: [GET], got: %v", v)
+	}
+
+	if v := cfg.ServerCORSHeaders(); len(v) != 1 || v[0] != "Content-Type" {
+		t.Errorf("Expected CORS headers: [Content-Type], got: %v", v)
+	}
+
+	if cfg.ServerCORSMaxAge() != time.Second*10 {
+		t.Errorf("Expected CORS max age: 10s, got: %v",
+			cfg.ServerCORSMaxAge())
+	}
+
+	if cfg.ServerCORSCredentials() {
+		t.Errorf("Expected CORS credentials: false, got: %v",
+			cfg.ServerCORSCredentials())
+	}
+
+	if !cfg.ServerAutocert() {
+		t.Errorf("Expected autocert: true, got: %v", cfg.ServerAutocert())
+	}
+
+	if cfg.ServerAutocertDir() != "test" {
+		t.Errorf("Expected autocert dir: test, got: %v",
+			cfg.ServerAutocertDir())
+	}
+
+	if cfg.ServerAutocertEmail() != "test@test.com" {
+		t.Errorf("Expected autocert email: test@test.com, got: %v",
+			cfg.ServerAutocertEmail())
+	}
+
+	if cfg.ServerRedirectAddress() != ":8081" {
+		t.Errorf("Expected redirect address: :8081, got: %v",
+			cfg.ServerRedirectAddress())
+	}
+
+	if v := cfg.ServerProtocols(); len(v) != 1 || v[0] != "h2c" {
+		t.Errorf("Expected protocols: [h2c], got: %v", v)
+	}
+
+	if cfg.ServerDrainDelay() != time.Second*5 {
+		t.Errorf("Expected drain delay: 5s, got: %v", cfg.ServerDrainDelay())
+	}
+
+	if cfg.ServerDrainTimeout() != time.Second*10 {
+		t.Errorf("Expected drain timeout: 10s, got: %v",
+			cfg.ServerDrainTimeout())
+	}
+
+	if cfg.ServerOutboundTimeout() != time.Second*15 {
+		t.Errorf("Expected outbound timeout: 15s, got: %v",
+			cfg.ServerOutboundTimeout())
+	}
+
+	if cfg.ServerTaskTimeout() != time.Minute {
+		t.Errorf("Expected task timeout: 1m, got: %v",
+			cfg.ServerTaskTimeout())
+	}
+
+	if cfg.ServerStartupGrace() != time.Second*45 {
+		t.Errorf("Expected startup grace: 45s, got: %v",
+			cfg.ServerStartupGrace())
+	}
+
+	if cfg.ServerStatusWebhook() != "https://test.com/hook" {
+		t.Errorf("Expected status webhook: https://test.com/hook, got: %v",
+			cfg.ServerStatusWebhook())
+	}
+
+	if v := cfg.ServerHooks(); len(v) != 2 || v[0] != "audit" ||
+		v[1] != "quota" {
+		t.Errorf("Expected hooks: [audit quota], got: %v", v)
+	}
 }
@@ -1,8 +1,9 @@
 package config
 
 import (
-	"os"
+	"maps"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -12,27 +13,74 @@ const (
 	KeyAccountName        = "account_name"
 	KeyServiceMaintenance = "service/maintenance"
 	KeyImportInterval     = "service/import_interval"
+	KeyImportConcurrency  = "service/import_concurrency"
+	KeyImportTimeout      = "service/import_timeout"
+	KeyImportFileDir      = "service/import_file_dir"
+	KeyBackupInterval     = "service/backup_interval"
+	KeyBackupURL          = "service/backup_url"
+	KeyBackupRetention    = "service/backup_retention"
+	KeyBackupUsers        = "service/backup_users"
 	KeyGameLimitDefault   = "service/game_limit_default"
+	KeyStorageLimit       = "service/storage_limit_default"
+	KeyAssetGCInterval    = "service/asset_gc_interval"
+	KeyAssetRetention     = "service/asset_retention"
+	KeyPlanDefault        = "service/plan_default"
 	KeyPromptHistorySize  = "service/prompt_history_size"
+	KeyPromptGameLimit    = "service/prompt_game_limit"
+	KeyPromptAccountLimit = "service/prompt_account_limit"
+	KeyPromptCooldown     = "service/prompt_cooldown"
+	KeyFeatureRollout     = "service/feature_rollout"
+	KeySecretRotate       = "service/secret_rotate_interval"
 
 	DefaultServiceName        = "game2d-api"
 	DefaultAccountID          = "game2d"
 	DefaultAccountName        = "game2d-api"
 	DefaultServiceMaintenance = false
 	DefaultImportInterval     = time.Minute * 5
+	DefaultImportConcurrency  = 4
+	DefaultImportTimeout      = time.Minute * 10
+	DefaultImportFileDir      = ""
+	DefaultBackupInterval     = time.Hour * 24
+	DefaultBackupURL          = ""
+	DefaultBackupRetention    = 7
+	DefaultBackupUsers        = false
 	DefaultGameLimitDefault   = 10
+	DefaultStorageLimit       = 1024 * 1024 * 100 // 100 MB
+	DefaultAssetGCInterval    = time.Hour * 24
+	DefaultAssetRetention     = time.Hour * 24 * 7
+	DefaultPlanDefault        = "free"
 	DefaultPromptHistorySize  = 1024 * 1024 // 1 MB
+	DefaultPromptGameLimit    = 30
+	DefaultPromptAccountLimit = 120
+	DefaultPromptCooldown     = time.Second * 10
+	DefaultSecretRotate       = time.Minute * 5
 )
 
 // ServiceConfig values represent telemetry configuration data.
 type ServiceConfig struct {
-	Name              string        `json:"name,omitempty"                yaml:"name,omitempty"`
-	AccountID         string        `json:"account_id,omitempty"          yaml:"account_id,omitempty"`
-	AccountName       string        `json:"account_name,omitempty"        yaml:"account_name,omitempty"`
-	Maintenance       bool          `json:"maintenance,omitempty"         yaml:"maintenance,omitempty"`
-	ImportInterval    time.Duration `json:"import_interval,omitempty"     yaml:"import_interval,omitempty"`
-	GameLimitDefault  int64         `json:"game_limit_default,omitempty"  yaml:"game_limit_default,omitempty"`
-	PromptHistorySize int64         `json:"prompt_history_size,omitempty" yaml:"prompt_history_size,omitempty"`
+	Name               string         `json:"name,omitempty"                   yaml:"name,omitempty"`
+	AccountID          string         `json:"account_id,omitempty"             yaml:"account_id,omitempty"`
+	AccountName        string         `json:"account_name,omitempty"           yaml:"account_name,omitempty"`
+	Maintenance        bool           `json:"maintenance,omitempty"            yaml:"maintenance,omitempty"`
+	ImportInterval     time.Duration  `json:"import_interval,omitempty"        yaml:"import_interval,omitempty"`
+	ImportConcurrency  int            `json:"import_concurrency,omitempty"     yaml:"import_concurrency,omitempty"`
+	ImportTimeout      time.Duration  `json:"import_timeout,omitempty"         yaml:"import_timeout,omitempty"`
+	ImportFileDir      string         `json:"import_file_dir,omitempty"        yaml:"import_file_dir,omitempty"`
+	BackupInterval     time.Duration  `json:"backup_interval,omitempty"        yaml:"backup_interval,omitempty"`
+	BackupURL          string         `json:"backup_url,omitempty"             yaml:"backup_url,omitempty"`
+	BackupRetention    int            `json:"backup_retention,omitempty"       yaml:"backup_retention,omitempty"`
+	BackupUsers        bool           `json:"backup_users,omitempty"           yaml:"backup_users,omitempty"`
+	GameLimitDefault   int64          `json:"game_limit_default,omitempty"     yaml:"game_limit_default,omitempty"`
+	StorageLimit       int64          `json:"storage_limit_default,omitempty"  yaml:"storage_limit_default,omitempty"`
+	AssetGCInterval    time.Duration  `json:"asset_gc_interval,omitempty"      yaml:"asset_gc_interval,omitempty"`
+	AssetRetention     time.Duration  `json:"asset_retention,omitempty"        yaml:"asset_retention,omitempty"`
+	PlanDefault        string         `json:"plan_default,omitempty"           yaml:"plan_default,omitempty"`
+	PromptHistorySize  int64          `json:"prompt_history_size,omitempty"    yaml:"prompt_history_size,omitempty"`
+	PromptGameLimit    int64          `json:"prompt_game_limit,omitempty"      yaml:"prompt_game_limit,omitempty"`
+	PromptAccountLimit int64          `json:"prompt_account_limit,omitempty"   yaml:"prompt_account_limit,omitempty"`
+	PromptCooldown     time.Duration  `json:"prompt_cooldown,omitempty"        yaml:"prompt_cooldown,omitempty"`
+	FeatureRollout     map[string]int `json:"feature_rollout,omitempty"        yaml:"feature_rollout,omitempty"`
+	SecretRotate       time.Duration  `json:"secret_rotate_interval,omitempty" yaml:"secret_rotate_interval,omitempty"`
 }
 
 // Load reads configuration data from environment variables and applies defaults
@@ -42,7 +90,7 @@ func (c *ServiceConfig) Load() {
 		c.Name = DefaultServiceName
 	}
 
-	if v := os.Getenv(ReplaceEnv(KeyAccountID)); v != "" {
+	if v := getEnv(KeyAccountID); v != "" {
 		c.AccountID = v
 	}
 
@@ -50,7 +98,7 @@ func (c *ServiceConfig) Load() {
 		c.AccountID = DefaultAccountID
 	}
 
-	if v := os.Getenv(ReplaceEnv(KeyAccountName)); v != "" {
+	if v := getEnv(KeyAccountName); v != "" {
 		c.AccountName = v
 	}
 
@@ -58,7 +106,7 @@ func (c *ServiceConfig) Load() {
 		c.AccountName = DefaultAccountName
 	}
 
-	if v := os.Getenv(ReplaceEnv(KeyServiceMaintenance)); v != "" {
+	if v := getEnv(KeyServiceMaintenance); v != "" {
 		v, err := strconv.ParseBool(v)
 		if err != nil {
 			v = DefaultServiceMaintenance
@@ -67,7 +115,7 @@ func (c *ServiceConfig) Load() {
 		c.Maintenance = v
 	}
 
-	if v := os.Getenv(ReplaceEnv(KeyImportInterval)); v != "" {
+	if v := getEnv(KeyImportInterval); v != "" {
 		v, err := time.ParseDuration(v)
 		if err != nil {
 			v = DefaultImportInterval
@@ -80,7 +128,76 @@ func (c *ServiceConfig) Load() {
 		c.ImportInterval = DefaultImportInterval
 	}
 
-	if v := os.Getenv(ReplaceEnv(KeyGameLimitDefault)); v != "" {
+	if v := getEnv(KeyImportConcurrency); v != "" {
+		v, err := strconv.Atoi(v)
+		if err != nil {
+			v = DefaultImportConcurrency
+		}
+
+		c.ImportConcurrency = v
+	}
+
+	if c.ImportConcurrency <= 0 {
+		c.ImportConcurrency = DefaultImportConcurrency
+	}
+
+	if v := getEnv(KeyImportTimeout); v != "" {
+		v, err := time.ParseDuration(v)
+		if err != nil {
+			v = DefaultImportTimeout
+		}
+
+		c.ImportTimeout = v
+	}
+
+	if c.ImportTimeout == 0 {
+		c.ImportTimeout = DefaultImportTimeout
+	}
+
+	if v := getEnv(KeyImportFileDir); v != "" {
+		c.ImportFileDir = v
+	}
+
+	if v := getEnv(KeyBackupInterval); v != "" {
+		v, err := time.ParseDuration(v)
+		if err != nil {
+			v = DefaultBackupInterval
+		}
+
+		c.BackupInterval = v
+	}
+
+	if c.BackupInterval == 0 {
+		c.BackupInterval = DefaultBackupInterval
+	}
+
+	if v := getEnv(KeyBackupURL); v != "" {
+		c.BackupURL = v
+	}
+
+	if v := getEnv(KeyBackupRetention); v != "" {
+		v, err := strconv.Atoi(v)
+		if err != nil {
+			v = DefaultBackupRetention
+		}
+
+		c.BackupRetention = v
+	}
+
+	if c.BackupRetention <= 0 {
+		c.BackupRetention = DefaultBackupRetention
+	}
+
+	if v := getEnv(KeyBackupUsers); v != "" {
+		v, err := strconv.ParseBool(v)
+		if err != nil {
+			v = DefaultBackupUsers
+		}
+
+		c.BackupUsers = v
+	}
+
+	if v := getEnv(KeyGameLimitDefault); v != "" {
 		v, err := strconv.ParseInt(v, 10, 64)
 		if err != nil {
 			v = DefaultGameLimitDefault
@@ -93,7 +210,54 @@ func (c *ServiceConfig) Load() {
 		c.GameLimitDefault = DefaultGameLimitDefault
 	}
 
-	if v := os.Getenv(ReplaceEnv(KeyPromptHistorySize)); v != "" {
+	if v := getEnv(KeyStorageLimit); v != "" {
+		v, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			v = DefaultStorageLimit
+		}
+
+		c.StorageLimit = v
+	}
+
+	if c.StorageLimit == 0 {
+		c.StorageLimit = DefaultStorageLimit
+	}
+
+	if v := getEnv(KeyAssetGCInterval); v != "" {
+		v, err := time.ParseDuration(v)
+		if err != nil {
+			v = DefaultAssetGCInterval
+		}
+
+		c.AssetGCInterval = v
+	}
+
+	if c.AssetGCInterval == 0 {
+		c.AssetGCInterval = DefaultAssetGCInterval
+	}
+
+	if v := getEnv(KeyAssetRetention); v != "" {
+		v, err := time.ParseDuration(v)
+		if err != nil {
+			v = DefaultAssetRetention
+		}
+
+		c.AssetRetention = v
+	}
+
+	if c.AssetRetention == 0 {
+		c.AssetRetention = DefaultAssetRetention
+	}
+
+	if v := getEnv(KeyPlanDefault); v != "" {
+		c.PlanDefault = v
+	}
+
+	if c.PlanDefault == "" {
+		c.PlanDefault = DefaultPlanDefault
+	}
+
+	if v := getEnv(KeyPromptHistorySize); v != "" {
 		v, err := strconv.ParseInt(v, 10, 64)
 		if err != nil {
 			v = DefaultPromptHistorySize
@@ -105,6 +269,73 @@ func (c *ServiceConfig) Load() {
 	if c.PromptHistorySize == 0 {
 		c.PromptHistorySize = DefaultPromptHistorySize
 	}
+
+	if v := getEnv(KeyPromptGameLimit); v != "" {
+		v, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			v = DefaultPromptGameLimit
+		}
+
+		c.PromptGameLimit = v
+	}
+
+	if c.PromptGameLimit == 0 {
+		c.PromptGameLimit = DefaultPromptGameLimit
+	}
+
+	if v := getEnv(KeyPromptAccountLimit); v != "" {
+		v, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			v = DefaultPromptAccountLimit
+		}
+
+		c.PromptAccountLimit = v
+	}
+
+	if c.PromptAccountLimit == 0 {
+		c.PromptAccountLimit = DefaultPromptAccountLimit
+	}
+
+	if v := getEnv(KeyPromptCooldown); v != "" {
+		v, err := time.ParseDuration(v)
+		if err != nil {
+			v = DefaultPromptCooldown
+		}
+
+		c.PromptCooldown = v
+	}
+
+	if c.PromptCooldown == 0 {
+		c.PromptCooldown = DefaultPromptCooldown
+	}
+
+	if v := getEnv(KeyFeatureRollout); v != "" {
+		c.FeatureRollout = map[string]int{}
+
+		for _, f := range strings.Fields(v) {
+			name, pct, ok := strings.Cut(f, "=")
+			if !ok {
+				continue
+			}
+
+			if v, err := strconv.Atoi(pct); err == nil {
+				c.FeatureRollout[name] = min(max(v, 0), 100)
+			}
+		}
+	}
+
+	if v := getEnv(KeySecretRotate); v != "" {
+		v, err := time.ParseDuration(v)
+		if err != nil {
+			v = DefaultSecretRotate
+		}
+
+		c.SecretRotate = v
+	}
+
+	if c.SecretRotate == 0 {
+		c.SecretRotate = DefaultSecretRotate
+	}
 }
 
 // ServiceName returns the name of the service.
@@ -169,6 +400,84 @@ func (c *Config) ImportInterval() time.Duration {
 	return c.service.ImportInterval
 }
 
+// ImportConcurrency returns the default number of repository files imported
+// concurrently for each account.
+func (c *Config) ImportConcurrency() int {
+	c.RLock()
+	defer c.RUnlock()
+
+	if c.service == nil {
+		return DefaultImportConcurrency
+	}
+
+	return c.service.ImportConcurrency
+}
+
+// ImportFileDir returns the local directory containing the repositories which
+// accounts can import games from using file URLs. File URLs are not allowed if
+// it is empty.
+func (c *Config) ImportFileDir() string {
+	c.RLock()
+	defer c.RUnlock()
+
+	if c.service == nil {
+		return DefaultImportFileDir
+	}
+
+	return c.service.ImportFileDir
+}
+
+// BackupInterval returns the frequency at which account games are backed up to
+// writable import repositories.
+func (c *Config) BackupInterval() time.Duration {
+	c.RLock()
+	defer c.RUnlock()
+
+	if c.service == nil {
+		return DefaultBackupInterval
+	}
+
+	return c.service.BackupInterval
+}
+
+// BackupURL returns the URL of the repository to which all account backups are
+// written. If it is empty, each account is backed up to its import repository.
+func (c *Config) BackupURL() string {
+	c.RLock()
+	defer c.RUnlock()
+
+	if c.service == nil {
+		return DefaultBackupURL
+	}
+
+	return c.service.BackupURL
+}
+
+// BackupRetention returns the number of backups retained for each account.
+func (c *Config) BackupRetention() int {
+	c.RLock()
+	defer c.RUnlock()
+
+	if c.service == nil {
+		return DefaultBackupRetention
+	}
+
+	return c.service.BackupRetention
+}
+
+// BackupUsers returns whether account backups include users and settings, in
+// addition to games.
+func (c *Config) BackupUsers() bool {
+	c.RLock()
+	defer c.RUnlock()
+
+	if c.service == nil {
+		return DefaultBackupUsers
+	}
+
+	return c.service.BackupUsers
+}
+
 // GameLimitDefault returns the default game limit for accounts.
 func (c *Config) GameLimitDefault() int64 {
 	c.RLock()
@@ -181,6 +490,96 @@ func (c *Config) GameLimitDefault() int64 {
 	return c.service.GameLimitDefault
 }
 
+// StorageLimit returns the default limit, in bytes, of the asset storage used
+// by accounts. Storage is not limited if it is negative.
+func (c *Config) StorageLimit() int64 {
+	c.RLock()
+	defer c.RUnlock()
+
+	if c.service == nil {
+		return DefaultStorageLimit
+	}
+
+	return c.service.StorageLimit
+}
+
+// AssetGCInterval returns the frequency at which assets which are not used by
+// any game are removed.
+func (c *Config) AssetGCInterval() time.Duration {
+	c.RLock()
+	defer c.RUnlock()
+
+	if c.service == nil {
+		return DefaultAssetGCInterval
+	}
+
+	return c.service.AssetGCInterval
+}
+
+// AssetRetention returns how long assets are kept after they were last used
+// by a saved game, before they may be removed if no game uses them.
+func (c *Config) AssetRetention() time.Duration {
+	c.RLock()
+	defer c.RUnlock()
+
+	if c.service == nil {
+		return DefaultAssetRetention
+	}
+
+	return c.service.AssetRetention
+}
+
+// PlanDefault returns the plan of accounts which have not been assigned one.
+func (c *Config) PlanDefault() string {
+	c.RLock()
+	defer c.RUnlock()
+
+	if c.service == nil {
+		return DefaultPlanDefault
+	}
+
+	return c.service.PlanDefault
+}
+
+// PromptGameLimit returns the maximum number of prompts which may be sent for
+// a game in an hour. Prompts are not limited if it is not positive.
+func (c *Config) PromptGameLimit() int64 {
+	c.RLock()
+	defer c.RUnlock()
+
+	if c.service == nil {
+		return DefaultPromptGameLimit
+	}
+
+	return c.service.PromptGameLimit
+}
+
+// PromptAccountLimit returns the maximum number of prompts which may be sent
+// by an account in an hour. Prompts are not limited if it is not positive.
+func (c *Config) PromptAccountLimit() int64 {
+	c.RLock()
+	defer c.RUnlock()
+
+	if c.service == nil {
+		return DefaultPromptAccountLimit
+	}
+
+	return c.service.PromptAccountLimit
+}
+
+// PromptCooldown returns the minimum time between prompts for a game. There is
+// no cooldown if it is not positive.
+func (c *Config) PromptCooldown() time.Duration {
+	c.RLock()
+	defer c.RUnlock()
+
+	if c.service == nil {
+		return DefaultPromptCooldown
+	}
+
+	return c.service.PromptCooldown
+}
+
 // PromptHistorySize returns the size limit for prompt history in bytes.
 func (c *Config) PromptHistorySize() int64 {
 	c.RLock()
@@ -192,3 +591,42 @@ func (c *Config) PromptHistorySize() int64 {
 
 	return c.service.PromptHistorySize
 }
+
+// FeatureRollout returns the percentage of accounts for which each feature
+// flag is enabled by default.
+func (c *Config) FeatureRollout() map[string]int {
+	c.RLock()
+	defer c.RUnlock()
+
+	if c.service == nil {
+		return nil
+	}
+
+	return maps.Clone(c.service.FeatureRollout)
+}
+
+// SecretRotateInterval returns the frequency at which configuration secrets
+// are read and resolved again, so that rotated secrets are used.
+func (c *Config) SecretRotateInterval() time.Duration {
+	c.RLock()
+	defer c.RUnlock()
+
+	if c.service == nil {
+		return DefaultSecretRotate
+	}
+
+	return c.service.SecretRotate
+}
+
+// ImportTimeout returns the maximum duration of a repository import or
+// backup.
+func (c *Config) ImportTimeout() time.Duration {
+	c.RLock()
+	defer c.RUnlock()
+
+	if c.service == nil {
+		return DefaultImportTimeout
+	}
+
+	return c.service.ImportTimeout
+}
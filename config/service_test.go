@@ -15,13 +15,28 @@ func TestServiceConfig(t *testing.T) {
 	cfg.Load(nil)
 
 	cfg.SetService(&config.ServiceConfig{
-		Name:              "test name",
-		AccountID:         "test id",
-		AccountName:       "test name",
-		Maintenance:       true,
-		ImportInterval:    time.Second,
-		GameLimitDefault:  5,
-		PromptHistorySize: 10,
+		Name:               "test name",
+		AccountID:          "test id",
+		AccountName:        "test name",
+		Maintenance:        true,
+		ImportInterval:     time.Second,
+		ImportConcurrency:  2,
+		ImportTimeout:      time.Minute,
+		ImportFileDir:      "/test",
+		BackupInterval:     time.Hour,
+		BackupURL:          "file:///test",
+		BackupRetention:    3,
+		BackupUsers:        true,
+		GameLimitDefault:   5,
+		StorageLimit:       1024,
+		AssetGCInterval:    time.Hour,
+		AssetRetention:     time.Minute,
+		PlanDefault:        "pro",
+		PromptHistorySize:  10,
+		PromptGameLimit:    3,
+		PromptAccountLimit: -1,
+		PromptCooldown:     time.Second,
+		FeatureRollout:     map[string]int{"test": 50},
 	})
 
 	if cfg.ServiceName() != "test name" {
@@ -47,13 +62,81 @@ func TestServiceConfig(t *testing.T) {
 		t.Errorf("Expected import interval: 1s, got: %v", cfg.ImportInterval())
 	}
 
+	if cfg.ImportConcurrency() != 2 {
+		t.Errorf("Expected import concurrency: 2, got: %v",
+			cfg.ImportConcurrency())
+	}
+
+	if cfg.ImportTimeout() != time.Minute {
+		t.Errorf("Expected import timeout: 1m, got: %v", cfg.ImportTimeout())
+	}
+
+	if cfg.ImportFileDir() != "/test" {
+		t.Errorf("Expected import file dir: /test, got: %v",
+			cfg.ImportFileDir())
+	}
+
+	if cfg.BackupInterval() != time.Hour {
+		t.Errorf("Expected backup interval: 1h, got: %v", cfg.BackupInterval())
+	}
+
+	if cfg.BackupURL() != "file:///test" {
+		t.Errorf("Expected backup url: file:///test, got: %v", cfg.BackupURL())
+	}
+
+	if cfg.BackupRetention() != 3 {
+		t.Errorf("Expected backup retention: 3, got: %v",
+			cfg.BackupRetention())
+	}
+
+	if cfg.BackupUsers() != true {
+		t.Errorf("Expected backup users: true, got: %v", cfg.BackupUsers())
+	}
+
 	if cfg.GameLimitDefault() != 5 {
 		t.Errorf("Expected game limit default: 5, got: %v",
 			cfg.GameLimitDefault())
 	}
 
+	if cfg.StorageLimit() != 1024 {
+		t.Errorf("Expected storage limit: 1024, got: %v", cfg.StorageLimit())
+	}
+
+	if cfg.AssetGCInterval() != time.Hour {
+		t.Errorf("Expected asset gc interval: 1h, got: %v",
+			cfg.AssetGCInterval())
+	}
+
+	if cfg.AssetRetention() != time.Minute {
+		t.Errorf("Expected asset retention: 1m, got: %v",
+			cfg.AssetRetention())
+	}
+
+	if cfg.PlanDefault() != "pro" {
+		t.Errorf("Expected plan default: pro, got: %v", cfg.PlanDefault())
+	}
+
 	if cfg.PromptHistorySize() != 10 {
 		t.Errorf("Expected prompt history size: 10, got: %v",
 			cfg.PromptHistorySize())
 	}
+
+	if cfg.PromptGameLimit() != 3 {
+		t.Errorf("Expected prompt game limit: 3, got: %v",
+			cfg.PromptGameLimit())
+	}
+
+	if cfg.PromptAccountLimit() != -1 {
+		t.Errorf("Expected prompt account limit: -1, got: %v",
+			cfg.PromptAccountLimit())
+	}
+
+	if cfg.PromptCooldown() != time.Second {
+		t.Errorf("Expected prompt cooldown: 1s, got: %v", cfg.PromptCooldown())
+	}
+
+	if cfg.FeatureRollout()["test"] != 50 {
+		t.Errorf("Expected feature rollout: 50, got: %v",
+			cfg.FeatureRollout()["test"])
+	}
 }
@@ -0,0 +1,132 @@
+package config
+
+import "strconv"
+
+const (
+	KeySpeechProvider = "speech/provider"
+	KeySpeechURL      = "speech/url"
+	KeySpeechAPIKey   = "speech/api_key"
+	KeySpeechModel    = "speech/model"
+	KeySpeechMaxSize  = "speech/max_size"
+
+	DefaultSpeechProvider = ""
+	DefaultSpeechURL      = "https://api.openai.com/v1/audio/transcriptions"
+	DefaultSpeechAPIKey   = ""
+	DefaultSpeechModel    = "whisper-1"
+	DefaultSpeechMaxSize  = 4 * 1024 * 1024 // 4 MB
+)
+
+// SpeechConfig values represent speech to text configuration data.
+type SpeechConfig struct {
+	Provider string `json:"provider,omitempty" yaml:"provider,omitempty"`
+	URL      string `json:"url,omitempty"      yaml:"url,omitempty"`
+	APIKey   string `json:"api_key,omitempty"  yaml:"api_key,omitempty"`
+	Model    string `json:"model,omitempty"    yaml:"model,omitempty"`
+	MaxSize  int64  `json:"max_size,omitempty" yaml:"max_size,omitempty"`
+}
+
+// Load reads configuration data from environment variables and applies defaults
+// for any missing or invalid configuration data.
+func (c *SpeechConfig) Load() {
+	if v := getEnv(KeySpeechProvider); v != "" {
+		c.Provider = v
+	}
+
+	if v := getEnv(KeySpeechURL); v != "" {
+		c.URL = v
+	}
+
+	if c.URL == "" {
+		c.URL = DefaultSpeechURL
+	}
+
+	if v := getEnv(KeySpeechAPIKey); v != "" {
+		c.APIKey = v
+	}
+
+	if v := getEnv(KeySpeechModel); v != "" {
+		c.Model = v
+	}
+
+	if c.Model == "" {
+		c.Model = DefaultSpeechModel
+	}
+
+	if v := getEnv(KeySpeechMaxSize); v != "" {
+		v, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			v = DefaultSpeechMaxSize
+		}
+
+		c.MaxSize = v
+	}
+
+	if c.MaxSize == 0 {
+		c.MaxSize = DefaultSpeechMaxSize
+	}
+}
+
+// SpeechProvider returns the speech to text provider used to transcribe voice
+// prompts. Voice prompts are disabled if it is empty.
+func (c *Config) SpeechProvider() string {
+	c.RLock()
+	defer c.RUnlock()
+
+	if c.speech == nil {
+		return DefaultSpeechProvider
+	}
+
+	return c.speech.Provider
+}
+
+// SpeechURL returns the URL of the transcription API of the speech to text
+// provider.
+func (c *Config) SpeechURL() string {
+	c.RLock()
+	defer c.RUnlock()
+
+	if c.speech == nil {
+		return DefaultSpeechURL
+	}
+
+	return c.speech.URL
+}
+
+// SpeechAPIKey returns the secret API key used to make requests to the speech
+// to text provider.
+func (c *Config) SpeechAPIKey() string {
+	c.RLock()
+	defer c.RUnlock()
+
+	if c.speech == nil {
+		return DefaultSpeechAPIKey
+	}
+
+	return c.speech.APIKey
+}
+
+// SpeechModel returns the speech to text model used to transcribe voice
+// prompts.
+func (c *Config) SpeechModel() string {
+	c.RLock()
+	defer c.RUnlock()
+
+	if c.speech == nil {
+		return DefaultSpeechModel
+	}
+
+	return c.speech.Model
+}
+
+// SpeechMaxSize returns the maximum size of the audio clips of voice prompts,
+// in bytes.
+func (c *Config) SpeechMaxSize() int64 {
+	c.RLock()
+	defer c.RUnlock()
+
+	if c.speech == nil {
+		return DefaultSpeechMaxSize
+	}
+
+	return c.speech.MaxSize
+}
@@ -0,0 +1,57 @@
+package config_test
+
+import (
+	"testing"
+
+	"github.com/dhaifley/game2d/config"
+)
+
+func TestSpeechConfig(t *testing.T) {
+	t.Setenv("SPEECH_MAX_SIZE", "invalid")
+
+	cfg := config.New("")
+
+	cfg.Load(nil)
+
+	if cfg.SpeechProvider() != config.DefaultSpeechProvider {
+		t.Errorf("Expected speech provider: %v, got: %v",
+			config.DefaultSpeechProvider, cfg.SpeechProvider())
+	}
+
+	if cfg.SpeechMaxSize() != config.DefaultSpeechMaxSize {
+		t.Errorf("Expected speech max size: %v, got: %v",
+			config.DefaultSpeechMaxSize, cfg.SpeechMaxSize())
+	}
+
+	cfg.SetSpeech(&config.SpeechConfig{
+		Provider: "openai",
+		URL:      "https://example.com/transcriptions",
+		APIKey:   "sk_test",
+		Model:    "test",
+		MaxSize:  1024,
+	})
+
+	if cfg.SpeechProvider() != "openai" {
+		t.Errorf("Expected speech provider: openai, got: %v",
+			cfg.SpeechProvider())
+	}
+
+	if cfg.SpeechURL() != "https://example.com/transcriptions" {
+		t.Errorf("Expected speech url: "+
+			"https://example.com/transcriptions, got: %v", cfg.SpeechURL())
+	}
+
+	if cfg.SpeechAPIKey() != "sk_test" {
+		t.Errorf("Expected speech api key: sk_test, got: %v",
+			cfg.SpeechAPIKey())
+	}
+
+	if cfg.SpeechModel() != "test" {
+		t.Errorf("Expected speech model: test, got: %v", cfg.SpeechModel())
+	}
+
+	if cfg.SpeechMaxSize() != 1024 {
+		t.Errorf("Expected speech max size: 1024, got: %v",
+			cfg.SpeechMaxSize())
+	}
+}
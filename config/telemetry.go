@@ -1,9 +1,6 @@
 package config
 
-import (
-	"os"
-	"time"
-)
+import "time"
 
 const (
 	KeyMetricAddress  = "metric/address"
@@ -28,7 +25,7 @@ type TelemetryConfig struct {
 // Load reads configuration data from environment variables and applies defaults
 // for any missing or invalid configuration data.
 func (c *TelemetryConfig) Load() {
-	if v := os.Getenv(ReplaceEnv(KeyMetricAddress)); v != "" {
+	if v := getEnv(KeyMetricAddress); v != "" {
 		c.MetricAddress = v
 	}
 
@@ -36,7 +33,7 @@ func (c *TelemetryConfig) Load() {
 		c.MetricAddress = DefaultMetricAddress
 	}
 
-	if v := os.Getenv(ReplaceEnv(KeyMetricInterval)); v != "" {
+	if v := getEnv(KeyMetricInterval); v != "" {
 		v, err := time.ParseDuration(v)
 		if err != nil {
 			v = DefaultMetricInterval
@@ -49,7 +46,7 @@ func (c *TelemetryConfig) Load() {
 		c.MetricInterval = DefaultMetricInterval
 	}
 
-	if v := os.Getenv(ReplaceEnv(KeyMetricVersion)); v != "" {
+	if v := getEnv(KeyMetricVersion); v != "" {
 		c.MetricVersion = v
 	}
 
@@ -57,7 +54,7 @@ func (c *TelemetryConfig) Load() {
 		c.MetricVersion = DefaultMetricVersion
 	}
 
-	if v := os.Getenv(ReplaceEnv(KeyTraceAddress)); v != "" {
+	if v := getEnv(KeyTraceAddress); v != "" {
 		c.TraceAddress = v
 	}
 
@@ -0,0 +1,271 @@
+package config
+
+import (
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/dhaifley/game2d/errors"
+)
+
+// Configuration validation error reasons.
+const (
+	ReasonConfigMissing  = "CONFIG_MISSING"
+	ReasonConfigInvalid  = "CONFIG_INVALID"
+	ReasonConfigConflict = "CONFIG_CONFLICT"
+)
+
+// missing returns an error for a setting which is required by another.
+func missing(key, message string, args ...any) *errors.Error {
+	return errors.New(errors.ErrConfiguration, message,
+		append([]any{"key", key, "env", ReplaceEnv(key)}, args...)...).
+		WithReason(ReasonConfigMissing)
+}
+
+// invalid returns an error for a setting with an invalid value.
+func invalid(key, message string, args ...any) *errors.Error {
+	return errors.New(errors.ErrConfiguration, message,
+		append([]any{"key", key, "env", ReplaceEnv(key)}, args...)...).
+		WithReason(ReasonConfigInvalid)
+}
+
+// conflict returns an error for settings which contradict each other.
+func conflict(key, other, message string) *errors.Error {
+	return errors.New(errors.ErrConfiguration, message,
+		"key", key,
+		"env", ReplaceEnv(key),
+		"conflicts_with", other).
+		WithReason(ReasonConfigConflict)
+}
+
+// Validate checks the configuration for missing, invalid, or contradictory
+// settings. All problems found are returned together, each with a reason
+// identifying the kind of problem and the key of the setting.
+func (c *Config) Validate() error {
+	var errs []error
+
+	add := func(err error) {
+		errs = append(errs, err)
+	}
+
+	if u, err := url.Parse(c.DBConn()); err != nil ||
+		(u.Scheme != "mongodb" && u.Scheme != "mongodb+srv") {
+		add(invalid(KeyDBConn, "database connection must be a MongoDB URI"))
+	}
+
+	if c.DBDatabase() == "" {
+		add(missing(KeyDBDatabase, "database name required"))
+	}
+
+	if c.DBMinPoolSize() > c.DBMaxPoolSize() {
+		add(conflict(KeyDBDMinPoolSize, KeyDBMaxPoolSize,
+			"minimum pool size exceeds maximum pool size"))
+	}
+
+	if c.DBDefaultSize() > c.DBMaxSize() {
+		add(conflict(KeyDBDefaultSize, KeyDBMaxSize,
+			"default query size exceeds maximum query size"))
+	}
+
+	switch c.DBTenancy() {
+	case "shared", "database", "prefix":
+	default:
+		add(invalid(KeyDBTenancy, "unknown database tenancy",
+			"value", c.DBTenancy()))
+	}
+
+	if c.DBRetryMin() > c.DBRetryMax() {
+		add(conflict(KeyDBRetryMin, KeyDBRetryMax,
+			"minimum connection retry wait exceeds maximum"))
+	}
+
+	if c.CacheType() != "redis" && c.CacheType() != "memcache" {
+		add(invalid(KeyCacheType, "unknown cache type",
+			"value", c.CacheType()))
+	}
+
+	if c.AuthTokenWellKnown() != "" && c.AuthIdentityDomain() == "" {
+		add(missing(KeyAuthIdentityDomain,
+			"identity domain required to retrieve well known info"))
+	}
+
+	if c.AuthTokenExpiresIn() > c.AuthTokenRefreshExpiresIn() {
+		add(conflict(KeyAuthTokenExpiresIn, KeyAuthTokenRefreshExpiresIn,
+			"access tokens expire after refresh tokens"))
+	}
+
+	if (c.ServerCert() == "") != (c.ServerKey() == "") {
+		if c.ServerCert() == "" {
+			add(missing(KeyServerCert,
+				"certificate required with certificate key"))
+		} else {
+			add(missing(KeyServerKey,
+				"certificate key required with certificate"))
+		}
+	}
+
+	if c.ServerAutocert() && c.ServerCert() != "" {
+		add(conflict(KeyServerAutocert, KeyServerCert,
+			"automatic certificates used with a certificate file"))
+	}
+
+	if c.ServerRedirectAddress() != "" && !c.ServerAutocert() &&
+		c.ServerCert() == "" {
+		add(conflict(KeyServerRedirectAddr, KeyServerCert,
+			"HTTPS redirect used without TLS"))
+	}
+
+	for _, p := range c.ServerProtocols() {
+		if !slices.Contains([]string{"http1", "http2", "h2c"}, p) {
+			add(invalid(KeyServerProtocols, "unknown server protocol",
+				"value", p))
+		}
+	}
+
+	if c.ServerTimeout() <= 0 {
+		add(invalid(KeyServerTimeout, "server timeout must be positive"))
+	}
+
+	if c.ServerOutboundTimeout() <= 0 {
+		add(invalid(KeyServerOutboundTimeout,
+			"outbound timeout must be positive"))
+	}
+
+	if c.ServerTaskTimeout() <= 0 {
+		add(invalid(KeyServerTaskTimeout, "task timeout must be positive"))
+	}
+
+	if c.ServerStartupGrace() < 0 {
+		add(invalid(KeyServerStartupGrace,
+			"startup grace period must not be negative"))
+	}
+
+	if c.ServerPromptTimeout() < c.ServerTimeout() {
+		add(conflict(KeyServerPromptTimeout, KeyServerTimeout,
+			"prompt timeout is shorter than the server timeout"))
+	}
+
+	if c.ServerPromptStreamTimeout() >= c.ServerPromptTimeout() {
+		add(conflict(KeyServerPromptStreamTimeout, KeyServerPromptTimeout,
+			"prompt stream timeout is not shorter than the prompt timeout"))
+	}
+
+	if p := c.ServerPathPrefix(); p != "" && !strings.HasPrefix(p, "/") {
+		add(invalid(KeyServerPathPrefix, "path prefix must begin with /",
+			"value", p))
+	}
+
+	if slices.Contains(c.ServerCORSOrigins(), "*") &&
+		c.ServerCORSCredentials() {
+		add(conflict(KeyServerCORSOrigins, KeyServerCORSNoCreds,
+			"credentials allowed for all cross-origin requests"))
+	}
+
+	if c.NotifySMTPAddress() != "" && c.NotifySMTPFrom() == "" {
+		add(missing(KeyNotifySMTPFrom,
+			"from address required to send email notifications"))
+	}
+
+	if c.NotifySMTPUsername() != "" && c.NotifySMTPPassword() == "" {
+		add(missing(KeyNotifySMTPPassword,
+			"password required with SMTP username"))
+	}
+
+	if sub := c.NotifyPushSubject(); c.NotifyPushPrivateKey() != "" {
+		if sub == "" {
+			add(missing(KeyNotifyPushSubject,
+				"subject required to send push notifications"))
+		} else if !strings.HasPrefix(sub, "mailto:") &&
+			!strings.HasPrefix(sub, "https:") {
+			add(invalid(KeyNotifyPushSubject,
+				"push subject must be a mailto: or https: URL",
+				"value", sub))
+		}
+	}
+
+	if c.BillingStripeKey() != "" && c.BillingWebhookSecret() == "" {
+		add(missing(KeyBillingWebhookSecret,
+			"webhook secret required to receive billing events"))
+	}
+
+	if len(c.BillingPrices()) > 0 && c.BillingStripeKey() == "" {
+		add(missing(KeyBillingStripeKey,
+			"Stripe key required with billing prices"))
+	}
+
+	switch c.SpeechProvider() {
+	case "":
+	case "openai":
+		if c.SpeechAPIKey() == "" {
+			add(missing(KeySpeechAPIKey,
+				"API key required to transcribe voice prompts"))
+		}
+
+		if u, err := url.Parse(c.SpeechURL()); err != nil ||
+			(u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			add(invalid(KeySpeechURL,
+				"speech URL must be an HTTP or HTTPS URL"))
+		}
+	default:
+		add(invalid(KeySpeechProvider, "unknown speech provider",
+			"value", c.SpeechProvider()))
+	}
+
+	if c.SpeechMaxSize() <= 0 {
+		add(invalid(KeySpeechMaxSize, "speech maximum size must be positive"))
+	}
+
+	switch c.EmbeddingProvider() {
+	case "none", "hash":
+	case "openai":
+		if c.EmbeddingAPIKey() == "" {
+			add(missing(KeyEmbeddingAPIKey,
+				"API key required to create embeddings"))
+		}
+
+		if u, err := url.Parse(c.EmbeddingURL()); err != nil ||
+			(u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			add(invalid(KeyEmbeddingURL,
+				"embedding URL must be an HTTP or HTTPS URL"))
+		}
+	default:
+		add(invalid(KeyEmbeddingProvider, "unknown embedding provider",
+			"value", c.EmbeddingProvider()))
+	}
+
+	if v := c.ServerStatusWebhook(); v != "" {
+		if u, err := url.Parse(v); err != nil ||
+			(u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			add(invalid(KeyServerStatusWebhook,
+				"status webhook must be an HTTP or HTTPS URL"))
+		}
+	}
+
+	seen := map[string]bool{}
+
+	for _, h := range c.ServerHooks() {
+		if seen[h] {
+			add(invalid(KeyServerHooks, "server hooks must not be repeated",
+				"hook", h))
+
+			break
+		}
+
+		seen[h] = true
+	}
+
+	if c.ImportConcurrency() <= 0 {
+		add(invalid(KeyImportConcurrency,
+			"import concurrency must be positive"))
+	}
+
+	if c.BackupURL() != "" && c.BackupRetention() <= 0 {
+		add(invalid(KeyBackupRetention, "backup retention must be positive"))
+	}
+
+	if c.AccountID() == "" {
+		add(missing(KeyAccountID, "account ID required"))
+	}
+
+	return errors.Join(errs...)
+}
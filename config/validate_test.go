@@ -0,0 +1,133 @@
+package config_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dhaifley/game2d/config"
+	"github.com/dhaifley/game2d/errors"
+)
+
+func TestValidate(t *testing.T) {
+	t.Parallel()
+
+	cfg := config.New("")
+
+	cfg.Load(nil)
+
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Expected default config to be valid, got: %v", err)
+	}
+
+	cfg.SetDB(&config.DBConfig{
+		Conn:        "postgres://localhost",
+		Database:    "test",
+		MinPoolSize: 20,
+		MaxPoolSize: 10,
+		DefaultSize: 10,
+		MaxSize:     100,
+	})
+
+	cfg.SetServer(&config.ServerConfig{
+		Cert:          "test.crt",
+		Autocert:      true,
+		Timeout:       time.Minute,
+		PromptTimeout: time.Hour,
+		Protocols:     []string{"http1", "http3"},
+		StatusWebhook: "ftp://test.com",
+		Hooks:         []string{"audit", "audit"},
+	})
+
+	cfg.SetSpeech(&config.SpeechConfig{
+		Provider: "openai",
+		URL:      "ftp://test.com",
+		MaxSize:  1024,
+	})
+
+	cfg.SetEmbedding(&config.EmbeddingConfig{Provider: "invalid"})
+
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("Expected validation errors")
+	}
+
+	var e *errors.Error
+
+	if !errors.As(err, &e) || !errors.Has(err, errors.ErrConfiguration) {
+		t.Fatalf("Expected configuration error, got: %v", err)
+	}
+
+	reasons := map[string]string{}
+
+	for _, ev := range e.Errors {
+		if k, ok := ev.Data["key"].(string); ok {
+			reasons[k] = ev.Reason
+		}
+	}
+
+	exp := map[string]string{
+		config.KeyDBConn:              config.ReasonConfigInvalid,
+		config.KeyDBDMinPoolSize:      config.ReasonConfigConflict,
+		config.KeyServerKey:           config.ReasonConfigMissing,
+		config.KeyServerAutocert:      config.ReasonConfigConflict,
+		config.KeyServerProtocols:     config.ReasonConfigInvalid,
+		config.KeyServerStatusWebhook: config.ReasonConfigInvalid,
+		config.KeyServerHooks:         config.ReasonConfigInvalid,
+		config.KeySpeechAPIKey:        config.ReasonConfigMissing,
+		config.KeySpeechURL:           config.ReasonConfigInvalid,
+		config.KeyEmbeddingProvider:   config.ReasonConfigInvalid,
+	}
+
+	for k, r := range exp {
+		if reasons[k] != r {
+			t.Errorf("Expected reason for %v: %v, got: %v", k, r, reasons[k])
+		}
+	}
+}
+
+func TestSettings(t *testing.T) {
+	t.Parallel()
+
+	cfg := config.New("")
+
+	cfg.Load(nil)
+
+	cfg.SetNotify(&config.NotifyConfig{
+		SMTPAddress:  "localhost:25",
+		SMTPPassword: "secret",
+	})
+
+	found := 0
+
+	for _, s := range cfg.Settings() {
+		switch s.Key {
+		case config.KeyNotifySMTPPassword:
+			found++
+
+			if s.Value != config.Redacted || !s.Secret {
+				t.Errorf("Expected redacted secret, got: %+v", s)
+			}
+		case config.KeyNotifySMTPAddress:
+			found++
+
+			if s.Value != "localhost:25" || s.Source != config.SourceConfig {
+				t.Errorf("Expected config setting, got: %+v", s)
+			}
+
+			if s.Env != "NOTIFY_SMTP_ADDRESS" {
+				t.Errorf("Expected env: NOTIFY_SMTP_ADDRESS, got: %v", s.Env)
+			}
+		case config.KeyDBDatabase:
+			found++
+
+			if s.Value != config.DefaultDBDatabase ||
+				s.Source != config.SourceDefault {
+				t.Errorf("Expected default setting, got: %+v", s)
+			}
+		}
+	}
+
+	if found != 3 {
+		t.Errorf("Expected settings found: 3, got: %v", found)
+	}
+}
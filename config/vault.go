@@ -0,0 +1,126 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dhaifley/game2d/errors"
+)
+
+// SchemeVault is the scheme of references to secrets held in HashiCorp Vault,
+// such as vault://secret/data/game2d#db_connection.
+const SchemeVault = "vault"
+
+// vaultResolver values resolve secrets held in HashiCorp Vault using its HTTP
+// API. Both version 1 and version 2 key/value secrets engines are supported.
+type vaultResolver struct {
+	addr  string
+	token string
+	cli   *http.Client
+}
+
+// NewVaultResolver creates a secret resolver for a Vault server address,
+// authenticating with a token.
+func NewVaultResolver(addr, token string) SecretResolver {
+	return &vaultResolver{
+		addr:  strings.TrimSuffix(addr, "/"),
+		token: token,
+		cli:   &http.Client{Timeout: time.Second * 10},
+	}
+}
+
+// ResolveSecret resolves a reference to a secret held in Vault.
+func (r *vaultResolver) ResolveSecret(ctx context.Context,
+	ref string,
+) (string, error) {
+	p, field := splitSecretRef(ref)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		r.addr+"/v1/"+strings.TrimPrefix(p, "/"), nil)
+	if err != nil {
+		return "", errors.Wrap(err, errors.ErrClient,
+			"unable to create vault request",
+			"path", p)
+	}
+
+	req.Header.Set("X-Vault-Token", r.token)
+
+	res, err := r.cli.Do(req)
+	if err != nil {
+		return "", errors.Wrap(err, errors.ErrClient,
+			"unable to send vault request",
+			"path", p)
+	}
+
+	defer res.Body.Close()
+
+	b, err := io.ReadAll(res.Body)
+	if err != nil {
+		return "", errors.Wrap(err, errors.ErrClient,
+			"unable to read vault response",
+			"path", p)
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return "", errors.New(errors.ErrClient,
+			"unexpected vault response status",
+			"path", p,
+			"status", res.StatusCode)
+	}
+
+	var v struct {
+		Data map[string]any `json:"data"`
+	}
+
+	if err := json.Unmarshal(b, &v); err != nil {
+		return "", errors.Wrap(err, errors.ErrClient,
+			"unable to decode vault response",
+			"path", p)
+	}
+
+	data := v.Data
+
+	// Version 2 key/value secrets are nested with their metadata.
+	if d, ok := data["data"].(map[string]any); ok {
+		if _, ok := data["metadata"]; ok {
+			data = d
+		}
+	}
+
+	return secretField(data, p, field)
+}
+
+// secretField returns a field of secret data. If no field is specified, the
+// data must contain exactly one field.
+func secretField(data map[string]any, p, field string) (string, error) {
+	if field == "" {
+		if len(data) != 1 {
+			return "", errors.New(errors.ErrInvalidRequest,
+				"secret field required",
+				"path", p)
+		}
+
+		for k := range data {
+			field = k
+		}
+	}
+
+	v, ok := data[field]
+	if !ok {
+		return "", errors.New(errors.ErrNotFound,
+			"secret field not found",
+			"path", p,
+			"field", field)
+	}
+
+	if s, ok := v.(string); ok {
+		return s, nil
+	}
+
+	return fmt.Sprint(v), nil
+}
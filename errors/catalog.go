@@ -0,0 +1,225 @@
+package errors
+
+// Stable machine readable error reasons. Unlike error messages, reasons never
+// change once published, so that clients can rely on them.
+const (
+	ReasonInvalidRequest       = "INVALID_REQUEST"
+	ReasonUnauthenticated      = "UNAUTHENTICATED"
+	ReasonScopeRequired        = "SCOPE_REQUIRED"
+	ReasonNotFound             = "NOT_FOUND"
+	ReasonGameNotFound         = "GAME_NOT_FOUND"
+	ReasonRouteNotFound        = "ROUTE_NOT_FOUND"
+	ReasonInvalidGameID        = "INVALID_GAME_ID"
+	ReasonGameTooLarge         = "GAME_TOO_LARGE"
+	ReasonRequestTooLarge      = "REQUEST_TOO_LARGE"
+	ReasonGameLimitReached     = "GAME_LIMIT_REACHED"
+	ReasonStorageLimitReached  = "STORAGE_LIMIT_REACHED"
+	ReasonPromptBudgetExceeded = "PROMPT_BUDGET_EXCEEDED"
+	ReasonPromptRateLimit      = "PROMPT_RATE_LIMIT"
+	ReasonPromptCooldown       = "PROMPT_COOLDOWN"
+	ReasonFeatureDisabled      = "FEATURE_DISABLED"
+	ReasonPlanUpgradeRequired  = "PLAN_UPGRADE_REQUIRED"
+	ReasonTagNotManaged        = "TAG_NOT_MANAGED"
+	ReasonStatusTransition     = "INVALID_STATUS_TRANSITION"
+	ReasonConflict             = "CONFLICT"
+	ReasonRateLimit            = "RATE_LIMIT"
+	ReasonCanceled             = "CANCELED"
+	ReasonMaintenance          = "MAINTENANCE"
+	ReasonUnavailable          = "UNAVAILABLE"
+	ReasonDatabaseUnavailable  = "DATABASE_UNAVAILABLE"
+	ReasonInternal             = "INTERNAL"
+)
+
+// CatalogEntry values describe an error reason which may be returned by the
+// API.
+type CatalogEntry struct {
+	Reason      string `json:"reason"      yaml:"reason"`
+	Code        string `json:"code"        yaml:"code"`
+	Status      int    `json:"status"      yaml:"status"`
+	Description string `json:"description" yaml:"description"`
+}
+
+// Catalog contains all error reasons which may be returned by the API.
+var Catalog = []CatalogEntry{{
+	Reason:      ReasonInvalidRequest,
+	Code:        ErrInvalidRequest.Name,
+	Status:      ErrInvalidRequest.Status,
+	Description: "The request is invalid.",
+}, {
+	Reason:      ReasonUnauthenticated,
+	Code:        ErrUnauthorized.Name,
+	Status:      ErrUnauthorized.Status,
+	Description: "The request is missing a valid access token.",
+}, {
+	Reason:      ReasonScopeRequired,
+	Code:        ErrForbidden.Name,
+	Status:      ErrForbidden.Status,
+	Description: "The access token does not have the scope required.",
+}, {
+	Reason:      ReasonNotFound,
+	Code:        ErrNotFound.Name,
+	Status:      ErrNotFound.Status,
+	Description: "The requested resource was not found.",
+}, {
+	Reason:      ReasonGameNotFound,
+	Code:        ErrNotFound.Name,
+	Status:      ErrNotFound.Status,
+	Description: "The game was not found, or is not visible to the account.",
+}, {
+	Reason:      ReasonRouteNotFound,
+	Code:        ErrNotFound.Name,
+	Status:      ErrNotFound.Status,
+	Description: "No API route matches the request path.",
+}, {
+	Reason:      ReasonInvalidGameID,
+	Code:        ErrInvalidRequest.Name,
+	Status:      ErrInvalidRequest.Status,
+	Description: "The game ID is not a valid UUID.",
+}, {
+	Reason:      ReasonGameTooLarge,
+	Code:        ErrTooLarge.Name,
+	Status:      ErrTooLarge.Status,
+	Description: "The game exceeds the maximum game request size.",
+}, {
+	Reason:      ReasonRequestTooLarge,
+	Code:        ErrTooLarge.Name,
+	Status:      ErrTooLarge.Status,
+	Description: "The request body exceeds the maximum request size.",
+}, {
+	Reason:      ReasonGameLimitReached,
+	Code:        ErrorRateLimit.Name,
+	Status:      ErrorRateLimit.Status,
+	Description: "The account has reached its maximum number of games.",
+}, {
+	Reason:      ReasonStorageLimitReached,
+	Code:        ErrorRateLimit.Name,
+	Status:      ErrorRateLimit.Status,
+	Description: "Saving the game would exceed the account storage limit.",
+}, {
+	Reason:      ReasonPromptBudgetExceeded,
+	Code:        ErrPrompt.Name,
+	Status:      ErrPrompt.Status,
+	Description: "The prompt response exceeded the account AI token budget.",
+}, {
+	Reason:      ReasonPromptRateLimit,
+	Code:        ErrorRateLimit.Name,
+	Status:      ErrorRateLimit.Status,
+	Description: "Too many prompts have been sent for the game or account.",
+}, {
+	Reason:      ReasonPromptCooldown,
+	Code:        ErrorRateLimit.Name,
+	Status:      ErrorRateLimit.Status,
+	Description: "A prompt was sent for the game too recently.",
+}, {
+	Reason:      ReasonFeatureDisabled,
+	Code:        ErrNotFound.Name,
+	Status:      ErrNotFound.Status,
+	Description: "The feature is not enabled for the user.",
+}, {
+	Reason:      ReasonPlanUpgradeRequired,
+	Code:        ErrForbidden.Name,
+	Status:      ErrForbidden.Status,
+	Description: "The account plan does not include the feature requested.",
+}, {
+	Reason:      ReasonTagNotManaged,
+	Code:        ErrInvalidRequest.Name,
+	Status:      ErrInvalidRequest.Status,
+	Description: "The tag is not in the managed tag vocabulary of the account.",
+}, {
+	Reason:      ReasonStatusTransition,
+	Code:        ErrConflict.Name,
+	Status:      ErrConflict.Status,
+	Description: "The game cannot change from its status to the one requested.",
+}, {
+	Reason:      ReasonConflict,
+	Code:        ErrConflict.Name,
+	Status:      ErrConflict.Status,
+	Description: "The request conflicts with the current resource state.",
+}, {
+	Reason:      ReasonRateLimit,
+	Code:        ErrorRateLimit.Name,
+	Status:      ErrorRateLimit.Status,
+	Description: "Too many requests have been sent.",
+}, {
+	Reason:      ReasonCanceled,
+	Code:        ErrContextCanceled.Name,
+	Status:      ErrContextCanceled.Status,
+	Description: "The request was canceled by the client.",
+}, {
+	Reason:      ReasonMaintenance,
+	Code:        ErrMaintenance.Name,
+	Status:      ErrMaintenance.Status,
+	Description: "The service is undergoing maintenance.",
+}, {
+	Reason:      ReasonUnavailable,
+	Code:        ErrUnavailable.Name,
+	Status:      ErrUnavailable.Status,
+	Description: "The service, or a service it depends on, is unavailable.",
+}, {
+	Reason:      ReasonDatabaseUnavailable,
+	Code:        ErrUnavailable.Name,
+	Status:      ErrUnavailable.Status,
+	Description: "The service database is unavailable.",
+}, {
+	Reason:      ReasonInternal,
+	Code:        ErrServer.Name,
+	Status:      ErrServer.Status,
+	Description: "An unexpected server error occurred.",
+}}
+
+// codeReasons maps error codes to the reason used for errors with that code,
+// when no more specific reason has been set.
+var codeReasons = map[string]string{
+	ErrInvalidRequest.Name:   ReasonInvalidRequest,
+	ErrInvalidHeader.Name:    ReasonInvalidRequest,
+	ErrInvalidParameter.Name: ReasonInvalidRequest,
+	ErrUnauthorized.Name:     ReasonUnauthenticated,
+	ErrForbidden.Name:        ReasonScopeRequired,
+	ErrNotFound.Name:         ReasonNotFound,
+	ErrConflict.Name:         ReasonConflict,
+	ErrTooLarge.Name:         ReasonRequestTooLarge,
+	ErrorRateLimit.Name:      ReasonRateLimit,
+	ErrContextCanceled.Name:  ReasonCanceled,
+	ErrMaintenance.Name:      ReasonMaintenance,
+	ErrUnavailable.Name:      ReasonUnavailable,
+}
+
+// CodeReason returns the reason used for errors with the specified code, when
+// no more specific reason has been set.
+func CodeReason(code Code) string {
+	if r, ok := codeReasons[code.Name]; ok {
+		return r
+	}
+
+	if code.Status >= 500 {
+		return ReasonInternal
+	}
+
+	return ReasonInvalidRequest
+}
+
+// WithReason sets the stable machine readable reason of the error.
+func (e *Error) WithReason(reason string) *Error {
+	e.Reason = reason
+
+	return e
+}
+
+// ReasonOf returns the reason of an error, or the reason used for its code if
+// no reason has been set. An empty string is returned if the error is not an
+// Error value.
+func ReasonOf(err error) string {
+	var e *Error
+
+	if !As(err, &e) || e == nil {
+		return ""
+	}
+
+	for v := e; v != nil; v = v.Err {
+		if v.Reason != "" {
+			return v.Reason
+		}
+	}
+
+	return CodeReason(e.Code)
+}
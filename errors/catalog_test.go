@@ -0,0 +1,83 @@
+package errors_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/dhaifley/game2d/errors"
+)
+
+func TestCatalog(t *testing.T) {
+	t.Parallel()
+
+	seen := map[string]bool{}
+
+	for _, c := range errors.Catalog {
+		if seen[c.Reason] {
+			t.Errorf("Expected unique reason, got duplicate: %v", c.Reason)
+		}
+
+		seen[c.Reason] = true
+
+		if c.Code == "" || c.Status == 0 || c.Description == "" {
+			t.Errorf("Expected complete catalog entry, got: %+v", c)
+		}
+	}
+
+	for _, code := range []errors.Code{
+		errors.ErrInvalidRequest, errors.ErrUnauthorized, errors.ErrForbidden,
+		errors.ErrNotFound, errors.ErrConflict, errors.ErrTooLarge,
+		errors.ErrorRateLimit, errors.ErrMaintenance, errors.ErrUnavailable,
+		errors.ErrServer, errors.ErrDatabase,
+	} {
+		if r := errors.CodeReason(code); !seen[r] {
+			t.Errorf("Expected code reason %v of %v in catalog", r, code.Name)
+		}
+	}
+}
+
+func TestReasonOf(t *testing.T) {
+	t.Parallel()
+
+	a := errors.New(errors.ErrInvalidRequest, "test").
+		WithReason(errors.ReasonGameTooLarge)
+
+	b := errors.Wrap(a, errors.ErrServer, "test2")
+
+	if b.Reason != errors.ReasonGameTooLarge {
+		t.Errorf("Expected reason: %v, got: %v",
+			errors.ReasonGameTooLarge, b.Reason)
+	}
+
+	if r := errors.ReasonOf(fmt.Errorf("wrapped: %w", b)); r !=
+		errors.ReasonGameTooLarge {
+		t.Errorf("Expected reason: %v, got: %v",
+			errors.ReasonGameTooLarge, r)
+	}
+
+	if r := errors.ReasonOf(errors.New(errors.ErrNotFound,
+		"test")); r != errors.ReasonNotFound {
+		t.Errorf("Expected reason: %v, got: %v", errors.ReasonNotFound, r)
+	}
+
+	if r := errors.ReasonOf(errors.New(errors.ErrDatabase,
+		"test")); r != errors.ReasonInternal {
+		t.Errorf("Expected reason: %v, got: %v", errors.ReasonInternal, r)
+	}
+
+	if r := errors.ReasonOf(fmt.Errorf("test")); r != "" {
+		t.Errorf("Expected empty reason, got: %v", r)
+	}
+
+	m := map[string]any{}
+
+	if err := json.Unmarshal([]byte(b.Error()), &m); err != nil {
+		t.Fatal(err)
+	}
+
+	if m["reason"] != errors.ReasonGameTooLarge {
+		t.Errorf("Expected rendered reason: %v, got: %v",
+			errors.ReasonGameTooLarge, m["reason"])
+	}
+}
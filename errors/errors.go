@@ -16,11 +16,13 @@ import (
 // Error values contain information about error conditions.
 type Error struct {
 	Code
+	Reason string         `json:"reason,omitempty"`
 	Msg    string         `json:"message,omitempty"`
 	Proc   string         `json:"procedure,omitempty"`
 	Svr    string         `json:"server,omitempty"`
 	Time   int64          `json:"time,omitempty"`
 	Data   map[string]any `json:"data,omitempty"`
+	Rate   *RateLimit     `json:"rate_limit,omitempty"`
 	Err    *Error         `json:"error,omitempty"`
 	Errors []*Error       `json:"errors,omitempty"`
 	err    error          `json:"-"`
@@ -151,12 +153,14 @@ func Wrap(err error, code Code, message string, args ...any) *Error {
 		e.Err = ev
 		e.Time = ev.Time
 		e.Code = ev.Code
+		e.Reason = ev.Reason
+		e.Rate = ev.Rate
 
 		if message == "" {
 			e.Msg = ev.Msg
 		}
 	} else if err != nil {
-		e.Err = &Error{Msg: err.Error(), err: err}
+		e.Err = from(err)
 	}
 
 	caller := 1
@@ -198,9 +202,12 @@ func Is(err, target error) bool {
 	return errors.Is(err, target)
 }
 
-// Has returns whether an error has a specified error code.
+// Has returns whether an error has a specified error code. The code of the
+// first Error value found in the error tree is used.
 func Has(err error, code Code) bool {
-	if e, ok := err.(*Error); ok {
+	var e *Error
+
+	if As(err, &e) && e != nil {
 		if e.Name == code.Name && e.Status == code.Status {
 			return true
 		}
@@ -209,6 +216,64 @@ func Has(err error, code Code) bool {
 	return false
 }
 
+// from converts any error into an Error value. Errors which wrap multiple
+// errors, such as those created by the standard library errors.Join, are
+// converted into a tree, so that each wrapped error is rendered.
+func from(err error) *Error {
+	if err == nil {
+		return nil
+	}
+
+	if e, ok := err.(*Error); ok {
+		return e
+	}
+
+	e := &Error{Msg: err.Error(), err: err}
+
+	if me, ok := err.(interface{ Unwrap() []error }); ok {
+		for _, err := range me.Unwrap() {
+			if err != nil {
+				e.Errors = append(e.Errors, from(err))
+			}
+		}
+	}
+
+	return e
+}
+
+// Join returns an error wrapping the specified errors, as the standard library
+// errors.Join does, but as an Error value rendered as a tree. Nil errors are
+// discarded, and nil is returned if all of the errors are nil. If the first
+// error joined is an Error value, its code is used for the returned error.
+func Join(errs ...error) error {
+	var e *Error
+
+	for _, err := range errs {
+		ev := from(err)
+		if ev == nil {
+			continue
+		}
+
+		if e == nil {
+			code := ErrServer
+
+			if ev.Code.Name != "" {
+				code = ev.Code
+			}
+
+			e = New(code, "multiple errors")
+		}
+
+		e.Errors = append(e.Errors, ev)
+	}
+
+	if e == nil {
+		return nil
+	}
+
+	return e
+}
+
 // String returns the Error object as a string.
 func (e *Error) String() string {
 	str, err := json.Marshal(e)
@@ -224,22 +289,53 @@ func (e *Error) Error() string {
 	return e.String()
 }
 
-// Unwrap returns the error wrapped by this error, nil if no error is wrapped.
-func (e *Error) Unwrap() error {
+// Unwrap returns the errors wrapped by this error, including any attached
+// errors, so that the standard library errors.Is and errors.As functions
+// traverse the whole error tree.
+func (e *Error) Unwrap() []error {
 	if e == nil {
 		return nil
 	}
 
 	if e.err != nil {
-		return e.err
+		return []error{e.err}
+	}
+
+	res := make([]error, 0, len(e.Errors)+1)
+
+	if e.Err != nil {
+		res = append(res, e.Err)
 	}
 
-	return e.Err
+	for _, ev := range e.Errors {
+		if ev != nil {
+			res = append(res, ev)
+		}
+	}
+
+	return res
+}
+
+// Append attaches errors to this error. Nil errors are discarded.
+func (e *Error) Append(errs ...error) *Error {
+	for _, err := range errs {
+		if ev := from(err); ev != nil {
+			e.Errors = append(e.Errors, ev)
+		}
+	}
+
+	return e
 }
 
 // Wrap returns an error value wrapping an existing error value.
 func (e *Error) Wrap(err error) *Error {
-	return Wrap(err, e.Code, e.Msg, e.Data)
+	res := Wrap(err, e.Code, e.Msg, e.Data)
+
+	if e.Reason != "" {
+		res.Reason = e.Reason
+	}
+
+	return res
 }
 
 // ErrorHas returns true if the provided error as a string contains s.
@@ -298,6 +394,11 @@ var (
 		Status: http.StatusConflict,
 	}
 
+	ErrTooLarge = Code{
+		Name:   "TooLarge",
+		Status: http.StatusRequestEntityTooLarge,
+	}
+
 	ErrServer = Code{
 		Name:   "Server",
 		Status: http.StatusInternalServerError,
@@ -2,6 +2,8 @@ package errors_test
 
 import (
 	"context"
+	"encoding/json"
+	stderrors "errors"
 	"os"
 	"strings"
 	"testing"
@@ -108,3 +110,77 @@ func TestString(t *testing.T) {
 			exp, e.String())
 	}
 }
+
+func TestUnwrap(t *testing.T) {
+	t.Parallel()
+
+	nf := errors.New(errors.ErrNotFound, "not found")
+
+	e := errors.New(errors.ErrImport, "unable to import").
+		Append(errors.New(errors.ErrDatabase, "database"), nf, nil)
+
+	if len(e.Errors) != 2 {
+		t.Errorf("Expected attached errors: 2, got: %v", len(e.Errors))
+	}
+
+	if !errors.Is(e, nf) {
+		t.Error("Expected attached error to be found")
+	}
+
+	var target *errors.Error
+
+	if !errors.As(errors.Wrap(e, errors.ErrServer, "test"), &target) ||
+		target.Code != errors.ErrImport {
+		t.Errorf("Expected import error, got: %v", target)
+	}
+
+	if !errors.Has(e, errors.ErrImport) || errors.Has(e, errors.ErrNotFound) {
+		t.Error("Expected only the code of the error itself")
+	}
+
+	ctxErr := errors.Wrap(context.Canceled, errors.ErrImport, "canceled")
+
+	if !errors.Is(errors.Join(e, ctxErr), context.Canceled) {
+		t.Error("Expected wrapped error to be found in joined errors")
+	}
+}
+
+func TestJoin(t *testing.T) {
+	t.Parallel()
+
+	if err := errors.Join(nil, nil); err != nil {
+		t.Errorf("Expected nil error, got: %v", err)
+	}
+
+	err := errors.Join(errors.New(errors.ErrNotFound, "a"), nil,
+		stderrors.New("b"))
+
+	var e *errors.Error
+
+	if !errors.As(err, &e) {
+		t.Fatalf("Expected Error value, got: %T", err)
+	}
+
+	if e.Code != errors.ErrNotFound {
+		t.Errorf("Expected code: NotFound, got: %v", e.Code.Name)
+	}
+
+	if len(e.Errors) != 2 {
+		t.Errorf("Expected joined errors: 2, got: %v", len(e.Errors))
+	}
+
+	w := errors.Wrap(stderrors.Join(stderrors.New("c"),
+		stderrors.New("d")), errors.ErrImport, "test")
+
+	m := map[string]any{}
+
+	if err := json.Unmarshal([]byte(w.Error()), &m); err != nil {
+		t.Fatal(err)
+	}
+
+	inner, _ := m["error"].(map[string]any)
+
+	if es, _ := inner["errors"].([]any); len(es) != 2 {
+		t.Errorf("Expected rendered error tree, got: %v", w.Error())
+	}
+}
@@ -0,0 +1,64 @@
+package errors
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RateLimit values describe the request quota which was exceeded by a rate
+// limit error. A zero limit means the quota is unknown, and a zero reset means
+// the quota is not reset over time.
+type RateLimit struct {
+	Limit     int64 `json:"limit,omitempty"`
+	Remaining int64 `json:"remaining"`
+	Reset     int64 `json:"reset,omitempty"`
+}
+
+// RetryAfter returns how long to wait before retrying, rounded up to the next
+// second, or zero if the quota is not reset over time.
+func (rl *RateLimit) RetryAfter(now time.Time) time.Duration {
+	if rl == nil || rl.Reset <= 0 {
+		return 0
+	}
+
+	d := time.Unix(rl.Reset, 0).Sub(now)
+
+	return time.Duration(math.Ceil(max(d.Seconds(), 1))) * time.Second
+}
+
+// WithRateLimit sets the quota which was exceeded by a rate limit error. The
+// reset time may be zero if the quota is not reset over time.
+func (e *Error) WithRateLimit(limit, remaining int64,
+	reset time.Time,
+) *Error {
+	rl := &RateLimit{Limit: limit, Remaining: remaining}
+
+	if !reset.IsZero() {
+		rl.Reset = reset.Unix()
+	}
+
+	e.Rate = rl
+
+	return e
+}
+
+// ParseRetryAfter parses the value of a Retry-After header, which may be
+// either a number of seconds or an HTTP date, into the time at which to retry.
+// A zero time is returned if the value is invalid.
+func ParseRetryAfter(v string, now time.Time) time.Time {
+	if v == "" {
+		return time.Time{}
+	}
+
+	if s, err := strconv.ParseInt(v, 10, 64); err == nil && s >= 0 {
+		return now.Add(time.Duration(s) * time.Second)
+	}
+
+	if t, err := http.ParseTime(v); err == nil {
+		return t
+	}
+
+	return time.Time{}
+}
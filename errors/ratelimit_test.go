@@ -0,0 +1,86 @@
+package errors_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/dhaifley/game2d/errors"
+)
+
+func TestRateLimit(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+
+	e := errors.New(errors.ErrorRateLimit, "test").
+		WithRateLimit(10, 0, now.Add(30*time.Second))
+
+	w := errors.Wrap(e, errors.ErrServer, "test2")
+
+	if w.Rate == nil || w.Rate.Limit != 10 || w.Rate.Remaining != 0 {
+		t.Fatalf("Expected wrapped rate limit, got: %+v", w.Rate)
+	}
+
+	if d := w.Rate.RetryAfter(now); d != 30*time.Second {
+		t.Errorf("Expected retry after: 30s, got: %v", d)
+	}
+
+	if d := w.Rate.RetryAfter(now.Add(time.Minute)); d != time.Second {
+		t.Errorf("Expected minimum retry after: 1s, got: %v", d)
+	}
+
+	m := map[string]any{}
+
+	if err := json.Unmarshal([]byte(w.Error()), &m); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := m["rate_limit"].(map[string]any); !ok {
+		t.Errorf("Expected rendered rate limit, got: %v", w.Error())
+	}
+
+	nr := errors.New(errors.ErrorRateLimit, "test").
+		WithRateLimit(5, 0, time.Time{})
+
+	if nr.Rate.Reset != 0 || nr.Rate.RetryAfter(now) != 0 {
+		t.Errorf("Expected no reset, got: %+v", nr.Rate)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	t.Parallel()
+
+	now := time.Unix(1700000000, 0)
+
+	tests := []struct {
+		name string
+		v    string
+		exp  time.Time
+	}{{
+		name: "seconds",
+		v:    "120",
+		exp:  now.Add(2 * time.Minute),
+	}, {
+		name: "date",
+		v:    now.Add(time.Hour).UTC().Format(http.TimeFormat),
+		exp:  now.Add(time.Hour),
+	}, {
+		name: "empty",
+		v:    "",
+		exp:  time.Time{},
+	}, {
+		name: "invalid",
+		v:    "soon",
+		exp:  time.Time{},
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := errors.ParseRetryAfter(tt.v, now); !got.Equal(tt.exp) {
+				t.Errorf("Expected time: %v, got: %v", tt.exp, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,639 @@
+// Package expr implements a small expression language used to evaluate
+// conditions against event data. Expressions may only read values, compare
+// them, and combine the results, so evaluating them is always safe and fast.
+//
+// Values are referenced by dot separated paths, such as game.name, and may be
+// compared with string, number, boolean and null literals using ==, !=, <, <=,
+// >, >= and contains. Conditions are combined using &&, || and !, and grouped
+// using parentheses.
+package expr
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/dhaifley/game2d/errors"
+)
+
+// Expression limits.
+const (
+	// MaxLength is the maximum length of an expression.
+	MaxLength = 1024
+
+	// maxDepth is the maximum nesting depth of an expression.
+	maxDepth = 32
+)
+
+// token kinds.
+const (
+	tokEOF = iota
+	tokString
+	tokNumber
+	tokIdent
+	tokOp
+)
+
+// token values are the lexical elements of an expression.
+type token struct {
+	kind int
+	val  string
+	pos  int
+}
+
+// node values are the parsed elements of an expression.
+type node interface {
+	eval(vars map[string]any) (any, error)
+}
+
+// Expr values are parsed expressions, which may be evaluated many times.
+type Expr struct {
+	src  string
+	root node
+}
+
+// Parse parses an expression.
+func Parse(src string) (*Expr, error) {
+	if len(src) > MaxLength {
+		return nil, errors.New(errors.ErrInvalidRequest,
+			"expression too long",
+			"max", MaxLength)
+	}
+
+	toks, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{toks: toks}
+
+	root, err := p.parseOr(0)
+	if err != nil {
+		return nil, err
+	}
+
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, errors.New(errors.ErrInvalidRequest,
+			"unexpected token in expression",
+			"token", t.val,
+			"position", t.pos)
+	}
+
+	return &Expr{src: src, root: root}, nil
+}
+
+// String returns the source of the expression.
+func (e *Expr) String() string {
+	return e.src
+}
+
+// Eval evaluates the expression using a set of variables, and returns whether
+// the result is true. Strings, numbers, lists and maps are true if they are not
+// empty or zero, and missing values are null, which is false.
+func (e *Expr) Eval(vars map[string]any) (bool, error) {
+	v, err := e.root.eval(vars)
+	if err != nil {
+		return false, err
+	}
+
+	return truthy(v), nil
+}
+
+// lex splits an expression into tokens.
+func lex(src string) ([]token, error) {
+	toks := []token{}
+
+	for i := 0; i < len(src); {
+		c := src[i]
+
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '"':
+			j := i + 1
+
+			for ; j < len(src) && src[j] != '"'; j++ {
+				if src[j] == '\\' {
+					j++
+				}
+			}
+
+			if j >= len(src) {
+				return nil, errors.New(errors.ErrInvalidRequest,
+					"unterminated string in expression",
+					"position", i)
+			}
+
+			s, err := strconv.Unquote(src[i : j+1])
+			if err != nil {
+				return nil, errors.Wrap(err, errors.ErrInvalidRequest,
+					"invalid string in expression",
+					"position", i)
+			}
+
+			toks = append(toks, token{kind: tokString, val: s, pos: i})
+			i = j + 1
+		case c >= '0' && c <= '9' || c == '-' && i+1 < len(src) &&
+			src[i+1] >= '0' && src[i+1] <= '9':
+			j := i + 1
+
+			for j < len(src) && (src[j] >= '0' && src[j] <= '9' ||
+				src[j] == '.') {
+				j++
+			}
+
+			toks = append(toks, token{kind: tokNumber, val: src[i:j], pos: i})
+			i = j
+		case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+			j := i + 1
+
+			for j < len(src) && (src[j] == '_' || src[j] == '.' ||
+				src[j] >= 'a' && src[j] <= 'z' ||
+				src[j] >= 'A' && src[j] <= 'Z' ||
+				src[j] >= '0' && src[j] <= '9') {
+				j++
+			}
+
+			kind := tokIdent
+			if src[i:j] == "contains" {
+				kind = tokOp
+			}
+
+			toks = append(toks, token{kind: kind, val: src[i:j], pos: i})
+			i = j
+		default:
+			op := ""
+
+			for _, o := range []string{
+				"==", "!=", "<=", ">=", "&&", "||", "<", ">", "!", "(", ")",
+			} {
+				if strings.HasPrefix(src[i:], o) {
+					op = o
+
+					break
+				}
+			}
+
+			if op == "" {
+				return nil, errors.New(errors.ErrInvalidRequest,
+					"invalid character in expression",
+					"character", string(c),
+					"position", i)
+			}
+
+			toks = append(toks, token{kind: tokOp, val: op, pos: i})
+			i += len(op)
+		}
+	}
+
+	return append(toks, token{kind: tokEOF, pos: len(src)}), nil
+}
+
+// parser values parse a list of tokens into an expression.
+type parser struct {
+	toks []token
+	pos  int
+}
+
+// peek returns the next token without consuming it.
+func (p *parser) peek() token {
+	return p.toks[p.pos]
+}
+
+// next consumes and returns the next token.
+func (p *parser) next() token {
+	t := p.toks[p.pos]
+
+	if t.kind != tokEOF {
+		p.pos++
+	}
+
+	return t
+}
+
+// isOp checks whether the next token is one of a list of operators.
+func (p *parser) isOp(ops ...string) bool {
+	t := p.peek()
+
+	if t.kind != tokOp {
+		return false
+	}
+
+	for _, op := range ops {
+		if t.val == op {
+			return true
+		}
+	}
+
+	return false
+}
+
+// checkDepth returns an error if an expression is nested too deeply.
+func (p *parser) checkDepth(depth int) error {
+	if depth > maxDepth {
+		return errors.New(errors.ErrInvalidRequest,
+			"expression nested too deeply",
+			"max", maxDepth)
+	}
+
+	return nil
+}
+
+// parseOr parses a list of conditions combined using ||.
+func (p *parser) parseOr(depth int) (node, error) {
+	if err := p.checkDepth(depth); err != nil {
+		return nil, err
+	}
+
+	l, err := p.parseAnd(depth)
+	if err != nil {
+		return nil, err
+	}
+
+	for p.isOp("||") {
+		p.next()
+
+		r, err := p.parseAnd(depth)
+		if err != nil {
+			return nil, err
+		}
+
+		l = &logical{op: "||", l: l, r: r}
+	}
+
+	return l, nil
+}
+
+// parseAnd parses a list of conditions combined using &&.
+func (p *parser) parseAnd(depth int) (node, error) {
+	l, err := p.parseNot(depth)
+	if err != nil {
+		return nil, err
+	}
+
+	for p.isOp("&&") {
+		p.next()
+
+		r, err := p.parseNot(depth)
+		if err != nil {
+			return nil, err
+		}
+
+		l = &logical{op: "&&", l: l, r: r}
+	}
+
+	return l, nil
+}
+
+// parseNot parses a condition, which may be negated using !.
+func (p *parser) parseNot(depth int) (node, error) {
+	if p.isOp("!") {
+		p.next()
+
+		if err := p.checkDepth(depth + 1); err != nil {
+			return nil, err
+		}
+
+		x, err := p.parseNot(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+
+		return &not{x: x}, nil
+	}
+
+	return p.parseCompare(depth)
+}
+
+// parseCompare parses a value, which may be compared with another value.
+func (p *parser) parseCompare(depth int) (node, error) {
+	l, err := p.parseValue(depth)
+	if err != nil {
+		return nil, err
+	}
+
+	if !p.isOp("==", "!=", "<", "<=", ">", ">=", "contains") {
+		return l, nil
+	}
+
+	op := p.next().val
+
+	r, err := p.parseValue(depth)
+	if err != nil {
+		return nil, err
+	}
+
+	return &compare{op: op, l: l, r: r}, nil
+}
+
+// parseValue parses a literal, a path, or a parenthesized expression.
+func (p *parser) parseValue(depth int) (node, error) {
+	t := p.next()
+
+	switch t.kind {
+	case tokString:
+		return &literal{v: t.val}, nil
+	case tokNumber:
+		f, err := strconv.ParseFloat(t.val, 64)
+		if err != nil {
+			return nil, errors.Wrap(err, errors.ErrInvalidRequest,
+				"invalid number in expression",
+				"number", t.val,
+				"position", t.pos)
+		}
+
+		return &literal{v: f}, nil
+	case tokIdent:
+		switch t.val {
+		case "true":
+			return &literal{v: true}, nil
+		case "false":
+			return &literal{v: false}, nil
+		case "null":
+			return &literal{v: nil}, nil
+		}
+
+		parts := strings.Split(t.val, ".")
+
+		for _, part := range parts {
+			if part == "" {
+				return nil, errors.New(errors.ErrInvalidRequest,
+					"invalid path in expression",
+					"path", t.val,
+					"position", t.pos)
+			}
+		}
+
+		return &path{parts: parts}, nil
+	case tokOp:
+		if t.val == "(" {
+			x, err := p.parseOr(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+
+			if !p.isOp(")") {
+				return nil, errors.New(errors.ErrInvalidRequest,
+					"missing closing parenthesis in expression",
+					"position", p.peek().pos)
+			}
+
+			p.next()
+
+			return x, nil
+		}
+	}
+
+	if t.kind == tokEOF {
+		return nil, errors.New(errors.ErrInvalidRequest,
+			"unexpected end of expression")
+	}
+
+	return nil, errors.New(errors.ErrInvalidRequest,
+		"unexpected token in expression",
+		"token", t.val,
+		"position", t.pos)
+}
+
+// literal values are constant values.
+type literal struct {
+	v any
+}
+
+func (n *literal) eval(map[string]any) (any, error) {
+	return n.v, nil
+}
+
+// path values reference a value in the variables.
+type path struct {
+	parts []string
+}
+
+func (n *path) eval(vars map[string]any) (any, error) {
+	var v any = vars
+
+	for _, part := range n.parts {
+		switch m := v.(type) {
+		case map[string]any:
+			v = m[part]
+		case map[string]string:
+			v = m[part]
+		default:
+			return nil, nil
+		}
+	}
+
+	return v, nil
+}
+
+// not values negate a condition.
+type not struct {
+	x node
+}
+
+func (n *not) eval(vars map[string]any) (any, error) {
+	v, err := n.x.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+
+	return !truthy(v), nil
+}
+
+// logical values combine two conditions. The second condition is only
+// evaluated if it is needed to determine the result.
+type logical struct {
+	op   string
+	l, r node
+}
+
+func (n *logical) eval(vars map[string]any) (any, error) {
+	l, err := n.l.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+
+	if truthy(l) == (n.op == "||") {
+		return truthy(l), nil
+	}
+
+	r, err := n.r.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+
+	return truthy(r), nil
+}
+
+// compare values compare two values.
+type compare struct {
+	op   string
+	l, r node
+}
+
+func (n *compare) eval(vars map[string]any) (any, error) {
+	l, err := n.l.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := n.r.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case "==":
+		return equal(l, r), nil
+	case "!=":
+		return !equal(l, r), nil
+	case "contains":
+		return contains(l, r), nil
+	}
+
+	c, ok := order(l, r)
+	if !ok {
+		return false, nil
+	}
+
+	switch n.op {
+	case "<":
+		return c < 0, nil
+	case "<=":
+		return c <= 0, nil
+	case ">":
+		return c > 0, nil
+	default:
+		return c >= 0, nil
+	}
+}
+
+// number converts a numeric value to a float.
+func number(v any) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint:
+		return float64(n), true
+	case uint32:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	}
+
+	return 0, false
+}
+
+// equal checks whether two values are equal. Numbers of different types are
+// equal if they have the same value.
+func equal(l, r any) bool {
+	if ln, ok := number(l); ok {
+		rn, ok := number(r)
+
+		return ok && ln == rn
+	}
+
+	switch lv := l.(type) {
+	case nil:
+		return r == nil
+	case string:
+		rv, ok := r.(string)
+
+		return ok && lv == rv
+	case bool:
+		rv, ok := r.(bool)
+
+		return ok && lv == rv
+	}
+
+	return false
+}
+
+// order compares two numbers or two strings, returning whether they could be
+// compared.
+func order(l, r any) (int, bool) {
+	if ln, ok := number(l); ok {
+		rn, ok := number(r)
+		if !ok || math.IsNaN(ln) || math.IsNaN(rn) {
+			return 0, false
+		}
+
+		switch {
+		case ln < rn:
+			return -1, true
+		case ln > rn:
+			return 1, true
+		default:
+			return 0, true
+		}
+	}
+
+	ls, ok := l.(string)
+	if !ok {
+		return 0, false
+	}
+
+	rs, ok := r.(string)
+	if !ok {
+		return 0, false
+	}
+
+	return strings.Compare(ls, rs), true
+}
+
+// contains checks whether a string contains a sub-string, or a list contains
+// a value.
+func contains(l, r any) bool {
+	switch lv := l.(type) {
+	case string:
+		rv, ok := r.(string)
+
+		return ok && strings.Contains(lv, rv)
+	case []string:
+		for _, v := range lv {
+			if equal(v, r) {
+				return true
+			}
+		}
+	case []any:
+		for _, v := range lv {
+			if equal(v, r) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// truthy checks whether a value is true.
+func truthy(v any) bool {
+	if n, ok := number(v); ok {
+		return n != 0
+	}
+
+	switch t := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return t
+	case string:
+		return t != ""
+	case []string:
+		return len(t) > 0
+	case []any:
+		return len(t) > 0
+	case map[string]any:
+		return len(t) > 0
+	}
+
+	return true
+}
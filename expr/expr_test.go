@@ -0,0 +1,105 @@
+package expr_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dhaifley/game2d/expr"
+)
+
+func TestEval(t *testing.T) {
+	t.Parallel()
+
+	vars := map[string]any{
+		"trigger": "game_created",
+		"game": map[string]any{
+			"name":  "Space Demo",
+			"tags":  []string{"demo", "space"},
+			"w":     int64(640),
+			"debug": false,
+		},
+		"data": map[string]any{
+			"error":   "",
+			"updated": 3,
+			"items":   []any{"a", 1.0},
+		},
+	}
+
+	tests := []struct {
+		name string
+		src  string
+		exp  bool
+	}{
+		{"equal string", `trigger == "game_created"`, true},
+		{"not equal string", `trigger != "game_created"`, false},
+		{"equal number types", `game.w == 640`, true},
+		{"less than", `data.updated < 5`, true},
+		{"greater or equal", `game.w >= 641`, false},
+		{"string order", `game.name > "Apple"`, true},
+		{"contains string", `game.name contains "Demo"`, true},
+		{"contains list", `game.tags contains "space"`, true},
+		{"contains mixed list", `data.items contains 1`, true},
+		{"missing path", `game.missing`, false},
+		{"missing equals null", `game.missing.value == null`, true},
+		{"empty string", `data.error`, false},
+		{"negated", `!game.debug`, true},
+		{"and", `game.w > 100 && trigger == "game_created"`, true},
+		{"or", `data.error || game.tags contains "demo"`, true},
+		{"precedence", `true || false && false`, true},
+		{"grouping", `(true || false) && false`, false},
+		{"negative number", `-1 < 0`, true},
+		{"escaped string", `"a\"b" contains "\""`, true},
+		{"mismatched order", `game.name < 5`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			e, err := expr.Parse(tt.src)
+			if err != nil {
+				t.Fatalf("Unexpected parse error: %v", err)
+			}
+
+			res, err := e.Eval(vars)
+			if err != nil {
+				t.Fatalf("Unexpected eval error: %v", err)
+			}
+
+			if res != tt.exp {
+				t.Errorf("Expected %v for %v, got: %v", tt.exp, tt.src, res)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		src  string
+	}{
+		{"empty", ``},
+		{"unterminated string", `name == "test`},
+		{"invalid character", `name = "test"`},
+		{"missing operand", `name ==`},
+		{"missing parenthesis", `(true && false`},
+		{"trailing token", `true false`},
+		{"invalid path", `game..name`},
+		{"invalid number", `1.2.3 > 1`},
+		{"operator as value", `contains == 1`},
+		{"too deep", strings.Repeat("(", 40) + "true" + strings.Repeat(")", 40)},
+		{"too long", strings.Repeat("a", expr.MaxLength+1)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if _, err := expr.Parse(tt.src); err == nil {
+				t.Errorf("Expected parse error for %v", tt.src)
+			}
+		})
+	}
+}
@@ -0,0 +1,398 @@
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"math"
+	"reflect"
+	"slices"
+
+	"github.com/dhaifley/game2d/errors"
+)
+
+// MaxResolves is the maximum number of fields resolved for a single query.
+const MaxResolves = 10000
+
+// Resolver functions resolve the value of a field from the value of the
+// object containing it.
+type Resolver func(ctx context.Context,
+	src any,
+	args map[string]any,
+) (any, error)
+
+// Field values define the fields of an object type. Fields with a type naming
+// an object type of the schema resolve objects, or lists of objects, from which
+// other fields must be selected. Fields of any other type resolve values which
+// are returned as they are.
+type Field struct {
+	Type    string
+	Resolve Resolver
+}
+
+// Object values define the fields of an object type by name.
+type Object map[string]*Field
+
+// Schema values define the object types which may be queried, starting from
+// the query type.
+type Schema struct {
+	Query string
+	Types map[string]Object
+}
+
+// Error values are the errors contained in a response.
+type Error struct {
+	Message    string         `json:"message"`
+	Path       []any          `json:"path,omitempty"`
+	Extensions map[string]any `json:"extensions,omitempty"`
+}
+
+// Response values are the results of executing a query.
+type Response struct {
+	Data   any      `json:"data,omitempty"`
+	Errors []*Error `json:"errors,omitempty"`
+}
+
+// newError converts an error into a response error for a path.
+func newError(err error, path []any) *Error {
+	res := &Error{Message: err.Error(), Path: path}
+
+	var e *errors.Error
+
+	if errors.As(err, &e) {
+		res.Message = e.Msg
+		res.Extensions = map[string]any{
+			"code":   e.Name,
+			"status": e.Status,
+		}
+
+		if reason := errors.ReasonOf(err); reason != "" {
+			res.Extensions["reason"] = reason
+		}
+	}
+
+	return res
+}
+
+// result values are the results of selecting fields from objects, which
+// preserve the order of the selections when encoded as JSON.
+type result []resultField
+
+// resultField values are the selected fields of results.
+type resultField struct {
+	key string
+	val any
+}
+
+// MarshalJSON encodes the result as a JSON object.
+func (r result) MarshalJSON() ([]byte, error) {
+	buf := bytes.NewBufferString("{")
+
+	for i, f := range r {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+
+		k, err := json.Marshal(f.key)
+		if err != nil {
+			return nil, err
+		}
+
+		v, err := json.Marshal(f.val)
+		if err != nil {
+			return nil, err
+		}
+
+		buf.Write(k)
+		buf.WriteByte(':')
+		buf.Write(v)
+	}
+
+	buf.WriteByte('}')
+
+	return buf.Bytes(), nil
+}
+
+// has checks whether the result contains a field.
+func (r result) has(key string) bool {
+	return slices.ContainsFunc(r, func(f resultField) bool {
+		return f.key == key
+	})
+}
+
+// Validate checks that every field selected by a query operation exists in
+// the schema, and that fields are selected from objects, and only objects.
+func (s *Schema) Validate(op *Operation) []*Error {
+	return s.validate(s.Query, op.Selections, nil)
+}
+
+// validate checks the fields selected from an object type.
+func (s *Schema) validate(typ string,
+	sels []*Selection,
+	path []any,
+) []*Error {
+	res := []*Error{}
+
+	names := map[string]string{}
+
+	for _, sel := range sels {
+		p := append(slices.Clone(path), sel.Key())
+
+		if name, ok := names[sel.Key()]; ok && name != sel.Name {
+			res = append(res, newError(errors.New(errors.ErrInvalidRequest,
+				"fields "+name+" and "+sel.Name+" conflict",
+				"key", sel.Key()), p))
+
+			continue
+		}
+
+		names[sel.Key()] = sel.Name
+
+		if sel.Name == "__typename" {
+			if len(sel.Selections) > 0 {
+				res = append(res, newError(errors.New(errors.ErrInvalidRequest,
+					"field __typename has no subfields"), p))
+			}
+
+			continue
+		}
+
+		f, ok := s.Types[typ][sel.Name]
+		if !ok {
+			res = append(res, newError(errors.New(errors.ErrInvalidRequest,
+				"cannot query field "+sel.Name+" on type "+typ), p))
+
+			continue
+		}
+
+		if _, ok := s.Types[f.Type]; !ok {
+			if len(sel.Selections) > 0 {
+				res = append(res, newError(errors.New(errors.ErrInvalidRequest,
+					"field "+sel.Name+" of type "+f.Type+
+						" has no subfields"), p))
+			}
+
+			continue
+		}
+
+		if len(sel.Selections) == 0 {
+			res = append(res, newError(errors.New(errors.ErrInvalidRequest,
+				"field "+sel.Name+" of type "+f.Type+
+					" must have a selection of subfields"), p))
+
+			continue
+		}
+
+		res = append(res, s.validate(f.Type, sel.Selections, p)...)
+	}
+
+	return res
+}
+
+// executor values contain the state of a single query execution.
+type executor struct {
+	schema   *Schema
+	vars     map[string]any
+	errs     []*Error
+	resolves int
+}
+
+// Execute runs a query operation of a document against the schema. The
+// operation is selected by name, which may be empty if the document contains
+// a single operation. Errors resolving fields are returned in the response,
+// with the data which could be resolved.
+func (s *Schema) Execute(ctx context.Context,
+	doc *Document,
+	operation string,
+	vars map[string]any,
+) *Response {
+	op, err := doc.Operation(operation)
+	if err != nil {
+		return &Response{Errors: []*Error{newError(err, nil)}}
+	}
+
+	if errs := s.Validate(op); len(errs) > 0 {
+		return &Response{Errors: errs}
+	}
+
+	e := &executor{schema: s, vars: map[string]any{}}
+
+	for k, v := range vars {
+		e.vars[k] = v
+	}
+
+	for k, v := range op.Variables {
+		if _, ok := e.vars[k]; !ok && v != nil {
+			e.vars[k] = v.resolve(nil)
+		}
+	}
+
+	data := e.object(ctx, s.Query, nil, op.Selections, nil)
+
+	return &Response{Data: data, Errors: e.errs}
+}
+
+// object resolves the fields selected from an object.
+func (e *executor) object(ctx context.Context,
+	typ string,
+	src any,
+	sels []*Selection,
+	path []any,
+) result {
+	res := make(result, 0, len(sels))
+
+	for _, sel := range sels {
+		if res.has(sel.Key()) {
+			continue
+		}
+
+		p := append(slices.Clone(path), sel.Key())
+
+		if sel.Name == "__typename" {
+			res = append(res, resultField{key: sel.Key(), val: typ})
+
+			continue
+		}
+
+		f := e.schema.Types[typ][sel.Name]
+
+		var val any
+
+		e.resolves++
+
+		if e.resolves > MaxResolves {
+			if e.resolves == MaxResolves+1 {
+				e.errs = append(e.errs, newError(errors.New(
+					errors.ErrInvalidRequest,
+					"query resolves too many fields",
+					"max", MaxResolves), p))
+			}
+		} else if err := ctx.Err(); err != nil {
+			e.errs = append(e.errs, newError(errors.Context(ctx), p))
+		} else {
+			args := make(map[string]any, len(sel.Args))
+
+			for k, v := range sel.Args {
+				args[k] = v.resolve(e.vars)
+			}
+
+			v, err := f.Resolve(ctx, src, args)
+			if err != nil {
+				e.errs = append(e.errs, newError(err, p))
+			} else {
+				val = e.complete(ctx, f.Type, v, sel.Selections, p)
+			}
+		}
+
+		res = append(res, resultField{key: sel.Key(), val: val})
+	}
+
+	return res
+}
+
+// complete resolves the fields selected from the value of a field, if it is
+// an object, or a list of objects.
+func (e *executor) complete(ctx context.Context,
+	typ string,
+	val any,
+	sels []*Selection,
+	path []any,
+) any {
+	rv := reflect.ValueOf(val)
+
+	if !rv.IsValid() {
+		return nil
+	}
+
+	switch rv.Kind() {
+	case reflect.Pointer, reflect.Map, reflect.Slice, reflect.Interface:
+		if rv.IsNil() {
+			return nil
+		}
+	}
+
+	if _, ok := e.schema.Types[typ]; !ok {
+		return val
+	}
+
+	if rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array {
+		res := make([]any, 0, rv.Len())
+
+		for i := range rv.Len() {
+			res = append(res, e.complete(ctx, typ, rv.Index(i).Interface(),
+				sels, append(slices.Clone(path), i)))
+		}
+
+		return res
+	}
+
+	return e.object(ctx, typ, val, sels, path)
+}
+
+// ArgString returns a string argument, or an empty string if it is missing.
+func ArgString(args map[string]any, name string) (string, error) {
+	switch v := args[name].(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	}
+
+	return "", errors.New(errors.ErrInvalidParameter,
+		"argument must be a string",
+		"argument", name)
+}
+
+// ArgStrings returns a list of strings argument. A single string is accepted
+// as a list containing only that string.
+func ArgStrings(args map[string]any, name string) ([]string, error) {
+	switch v := args[name].(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return []string{v}, nil
+	case []any:
+		res := make([]string, 0, len(v))
+
+		for _, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return nil, errors.New(errors.ErrInvalidParameter,
+					"argument must be a list of strings",
+					"argument", name)
+			}
+
+			res = append(res, s)
+		}
+
+		return res, nil
+	}
+
+	return nil, errors.New(errors.ErrInvalidParameter,
+		"argument must be a list of strings",
+		"argument", name)
+}
+
+// ArgInt returns an integer argument, or a default value if it is missing.
+func ArgInt(args map[string]any, name string, def int64) (int64, error) {
+	switch v := args[name].(type) {
+	case nil:
+		return def, nil
+	case int64:
+		return v, nil
+	case int:
+		return int64(v), nil
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) < 1<<53 {
+			return int64(v), nil
+		}
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n, nil
+		}
+	}
+
+	return 0, errors.New(errors.ErrInvalidParameter,
+		"argument must be an integer",
+		"argument", name)
+}
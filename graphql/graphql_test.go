@@ -0,0 +1,219 @@
+package graphql_test
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/dhaifley/game2d/errors"
+	"github.com/dhaifley/game2d/graphql"
+)
+
+type testItem struct {
+	ID   string
+	Tags []string
+}
+
+func testSchema() *graphql.Schema {
+	items := []*testItem{
+		{ID: "1", Tags: []string{"a"}},
+		{ID: "2", Tags: []string{"b", "c"}},
+	}
+
+	return &graphql.Schema{
+		Query: "Query",
+		Types: map[string]graphql.Object{
+			"Query": {
+				"item": {Type: "Item", Resolve: func(ctx context.Context,
+					src any,
+					args map[string]any,
+				) (any, error) {
+					id, err := graphql.ArgString(args, "id")
+					if err != nil {
+						return nil, err
+					}
+
+					for _, item := range items {
+						if item.ID == id {
+							return item, nil
+						}
+					}
+
+					return nil, errors.New(errors.ErrNotFound,
+						"item not found")
+				}},
+				"items": {Type: "Item", Resolve: func(ctx context.Context,
+					src any,
+					args map[string]any,
+				) (any, error) {
+					size, err := graphql.ArgInt(args, "size", 10)
+					if err != nil {
+						return nil, err
+					}
+
+					return items[:min(int(size), len(items))], nil
+				}},
+			},
+			"Item": {
+				"id": {Type: "ID", Resolve: func(ctx context.Context,
+					src any,
+					args map[string]any,
+				) (any, error) {
+					return src.(*testItem).ID, nil
+				}},
+				"tags": {Type: "String", Resolve: func(ctx context.Context,
+					src any,
+					args map[string]any,
+				) (any, error) {
+					return src.(*testItem).Tags, nil
+				}},
+				"parent": {Type: "Item", Resolve: func(ctx context.Context,
+					src any,
+					args map[string]any,
+				) (any, error) {
+					return (*testItem)(nil), nil
+				}},
+			},
+		},
+	}
+}
+
+func TestExecute(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		query string
+		op    string
+		vars  map[string]any
+		exp   string
+	}{{
+		name:  "shorthand",
+		query: `{ item(id: "1") { id tags } }`,
+		exp:   `{"data":{"item":{"id":"1","tags":["a"]}}}`,
+	}, {
+		name: "aliases and order",
+		query: `query { second: item(id: "2") { tags, id } ` +
+			`first: item(id: "1") { id } }`,
+		exp: `{"data":{"second":{"tags":["b","c"],"id":"2"},` +
+			`"first":{"id":"1"}}}`,
+	}, {
+		name: "variables",
+		query: `query Get($id: ID!, $size: Int = 1) { item(id: $id) { id } ` +
+			`items(size: $size) { id } }`,
+		vars: map[string]any{"id": "2"},
+		exp:  `{"data":{"item":{"id":"2"},"items":[{"id":"1"}]}}`,
+	}, {
+		name:  "json variables",
+		query: `query($size: Int) { items(size: $size) { __typename id } }`,
+		vars:  map[string]any{"size": 2.0},
+		exp: `{"data":{"items":[{"__typename":"Item","id":"1"},` +
+			`{"__typename":"Item","id":"2"}]}}`,
+	}, {
+		name:  "named operation",
+		query: `query A { items { id } } query B { item(id: "1") { id } }`,
+		op:    "B",
+		exp:   `{"data":{"item":{"id":"1"}}}`,
+	}, {
+		name:  "null object",
+		query: `{ item(id: "1") { parent { id } } }`,
+		exp:   `{"data":{"item":{"parent":null}}}`,
+	}, {
+		name:  "field error",
+		query: `{ a: item(id: "1") { id } b: item(id: "3") { id } }`,
+		exp: `{"data":{"a":{"id":"1"},"b":null},"errors":[{"message":` +
+			`"item not found","path":["b"],"extensions":{"code":` +
+			`"NotFound","reason":"NOT_FOUND","status":404}}]}`,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			doc, err := graphql.Parse(tt.query)
+			if err != nil {
+				t.Fatalf("Unexpected parse error: %v", err)
+			}
+
+			res := testSchema().Execute(context.Background(), doc, tt.op,
+				tt.vars)
+
+			b, err := json.Marshal(res)
+			if err != nil {
+				t.Fatalf("Unexpected marshal error: %v", err)
+			}
+
+			if string(b) != tt.exp {
+				t.Errorf("Expected response: %v, got: %v", tt.exp, string(b))
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		query string
+	}{
+		{"unknown field", `{ item(id: "1") { name } }`},
+		{"missing subfields", `{ items }`},
+		{"scalar subfields", `{ items { id { value } } }`},
+		{"conflicting aliases", `{ items { x: id x: tags } }`},
+		{"missing operation name", `query A { items { id } } query B ` +
+			`{ items { id } }`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			doc, err := graphql.Parse(tt.query)
+			if err != nil {
+				t.Fatalf("Unexpected parse error: %v", err)
+			}
+
+			res := testSchema().Execute(context.Background(), doc, "", nil)
+
+			if res.Data != nil || len(res.Errors) == 0 {
+				t.Errorf("Expected validation error for %v, got: %+v",
+					tt.query, res)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		query string
+	}{
+		{"empty", ``},
+		{"empty selection", `{ }`},
+		{"unterminated selection", `{ items { id }`},
+		{"unterminated string", `{ item(id: "1) { id } }`},
+		{"invalid character", `{ items { id; } }`},
+		{"mutation", `mutation { items { id } }`},
+		{"fragment spread", `{ items { ...ItemFields } }`},
+		{"fragment", `fragment F on Item { id }`},
+		{"directive", `{ items @skip(if: true) { id } }`},
+		{"constant variable", `query($a: Int = $b) { items { id } }`},
+		{"too deep", strings.Repeat("{ a ", 20) + "{ b }" +
+			strings.Repeat(" }", 20)},
+		{"too long", "{ " + strings.Repeat("a ", graphql.MaxLength) + "}"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if _, err := graphql.Parse(tt.query); err == nil {
+				t.Errorf("Expected parse error for %v", tt.query)
+			}
+		})
+	}
+}
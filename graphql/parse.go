@@ -0,0 +1,612 @@
+// Package graphql implements a minimal GraphQL query engine. Documents are
+// parsed into query operations, which select fields from the object types of
+// a schema, with arguments, aliases and nested selections. Mutations,
+// subscriptions, fragments and directives are not supported.
+package graphql
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/dhaifley/game2d/errors"
+)
+
+// Query limits.
+const (
+	// MaxLength is the maximum length of a query document.
+	MaxLength = 64 * 1024
+
+	// maxDepth is the maximum nesting depth of selections and values.
+	maxDepth = 16
+)
+
+// Document values are parsed GraphQL query documents.
+type Document struct {
+	Operations []*Operation
+}
+
+// Operation values are the query operations of a document.
+type Operation struct {
+	Name       string
+	Variables  map[string]Value
+	Selections []*Selection
+}
+
+// Selection values are the fields selected in an operation, with their
+// arguments, and the fields selected from their results.
+type Selection struct {
+	Alias      string
+	Name       string
+	Args       map[string]Value
+	Selections []*Selection
+}
+
+// Key returns the key of the selection in the result, which is its alias, if
+// it has one.
+func (s *Selection) Key() string {
+	if s.Alias != "" {
+		return s.Alias
+	}
+
+	return s.Name
+}
+
+// Value values are the argument values and variable defaults of a document.
+type Value interface {
+	resolve(vars map[string]any) any
+}
+
+// literal values are constant values.
+type literal struct {
+	v any
+}
+
+func (v *literal) resolve(map[string]any) any {
+	return v.v
+}
+
+// variable values reference a variable.
+type variable struct {
+	name string
+}
+
+func (v *variable) resolve(vars map[string]any) any {
+	return vars[v.name]
+}
+
+// list values are lists of values.
+type list []Value
+
+func (v list) resolve(vars map[string]any) any {
+	res := make([]any, 0, len(v))
+
+	for _, item := range v {
+		res = append(res, item.resolve(vars))
+	}
+
+	return res
+}
+
+// object values are objects of values.
+type object map[string]Value
+
+func (v object) resolve(vars map[string]any) any {
+	res := make(map[string]any, len(v))
+
+	for k, item := range v {
+		res[k] = item.resolve(vars)
+	}
+
+	return res
+}
+
+// token kinds.
+const (
+	tokEOF = iota
+	tokPunct
+	tokName
+	tokInt
+	tokFloat
+	tokString
+)
+
+// token values are the lexical elements of a document.
+type token struct {
+	kind int
+	val  string
+	pos  int
+}
+
+// errSyntax returns an error for invalid syntax in a document.
+func errSyntax(msg string, pos int) error {
+	return errors.New(errors.ErrInvalidRequest,
+		"syntax error: "+msg,
+		"position", pos)
+}
+
+// lex splits a document into tokens. Commas, white space and comments are
+// ignored.
+func lex(src string) ([]token, error) {
+	toks := []token{}
+
+	for i := 0; i < len(src); {
+		c := src[i]
+
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',':
+			i++
+		case c == '#':
+			for i < len(src) && src[i] != '\n' && src[i] != '\r' {
+				i++
+			}
+		case strings.HasPrefix(src[i:], "..."):
+			toks = append(toks, token{kind: tokPunct, val: "...", pos: i})
+			i += 3
+		case strings.IndexByte("!$():=@[]{}|", c) >= 0:
+			toks = append(toks, token{kind: tokPunct, val: string(c), pos: i})
+			i++
+		case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+			j := i + 1
+
+			for j < len(src) && (src[j] == '_' ||
+				src[j] >= 'a' && src[j] <= 'z' ||
+				src[j] >= 'A' && src[j] <= 'Z' ||
+				src[j] >= '0' && src[j] <= '9') {
+				j++
+			}
+
+			toks = append(toks, token{kind: tokName, val: src[i:j], pos: i})
+			i = j
+		case c == '-' || c >= '0' && c <= '9':
+			j, kind := i+1, tokInt
+
+			for j < len(src) && (src[j] >= '0' && src[j] <= '9' ||
+				src[j] == '.' || src[j] == 'e' || src[j] == 'E' ||
+				(src[j] == '+' || src[j] == '-') &&
+					(src[j-1] == 'e' || src[j-1] == 'E')) {
+				if src[j] < '0' || src[j] > '9' {
+					kind = tokFloat
+				}
+
+				j++
+			}
+
+			toks = append(toks, token{kind: kind, val: src[i:j], pos: i})
+			i = j
+		case c == '"':
+			if strings.HasPrefix(src[i:], `"""`) {
+				return nil, errSyntax("block strings are not supported", i)
+			}
+
+			j := i + 1
+
+			for ; j < len(src) && src[j] != '"'; j++ {
+				if src[j] == '\\' {
+					j++
+				} else if src[j] == '\n' || src[j] == '\r' {
+					break
+				}
+			}
+
+			if j >= len(src) || src[j] != '"' {
+				return nil, errSyntax("unterminated string", i)
+			}
+
+			s, err := strconv.Unquote(src[i : j+1])
+			if err != nil {
+				return nil, errSyntax("invalid string", i)
+			}
+
+			toks = append(toks, token{kind: tokString, val: s, pos: i})
+			i = j + 1
+		default:
+			return nil, errSyntax("unexpected character "+
+				strconv.QuoteRune(rune(c)), i)
+		}
+	}
+
+	return append(toks, token{kind: tokEOF, pos: len(src)}), nil
+}
+
+// parser values parse a list of tokens into a document.
+type parser struct {
+	toks []token
+	pos  int
+}
+
+// peek returns the next token without consuming it.
+func (p *parser) peek() token {
+	return p.toks[p.pos]
+}
+
+// next consumes and returns the next token.
+func (p *parser) next() token {
+	t := p.toks[p.pos]
+
+	if t.kind != tokEOF {
+		p.pos++
+	}
+
+	return t
+}
+
+// is checks whether the next token is a punctuator.
+func (p *parser) is(punct string) bool {
+	t := p.peek()
+
+	return t.kind == tokPunct && t.val == punct
+}
+
+// expect consumes the next token, which must be a punctuator.
+func (p *parser) expect(punct string) error {
+	if t := p.next(); t.kind != tokPunct || t.val != punct {
+		return errSyntax("expected "+strconv.Quote(punct), t.pos)
+	}
+
+	return nil
+}
+
+// name consumes the next token, which must be a name.
+func (p *parser) name() (string, error) {
+	t := p.next()
+	if t.kind != tokName {
+		return "", errSyntax("expected name", t.pos)
+	}
+
+	return t.val, nil
+}
+
+// Parse parses a GraphQL query document. Only query operations are supported,
+// without fragments or directives.
+func Parse(src string) (*Document, error) {
+	if len(src) > MaxLength {
+		return nil, errors.New(errors.ErrInvalidRequest,
+			"query too long",
+			"max", MaxLength)
+	}
+
+	toks, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{toks: toks}
+
+	doc := &Document{}
+
+	for p.peek().kind != tokEOF {
+		op, err := p.parseOperation()
+		if err != nil {
+			return nil, err
+		}
+
+		doc.Operations = append(doc.Operations, op)
+	}
+
+	if len(doc.Operations) == 0 {
+		return nil, errSyntax("missing operation", 0)
+	}
+
+	return doc, nil
+}
+
+// Operation returns an operation of the document by name. If the name is
+// empty, the document must contain a single operation.
+func (d *Document) Operation(name string) (*Operation, error) {
+	if name == "" {
+		if len(d.Operations) != 1 {
+			return nil, errors.New(errors.ErrInvalidRequest,
+				"operation name required for documents with many "+
+					"operations")
+		}
+
+		return d.Operations[0], nil
+	}
+
+	for _, op := range d.Operations {
+		if op.Name == name {
+			return op, nil
+		}
+	}
+
+	return nil, errors.New(errors.ErrInvalidRequest,
+		"operation not found",
+		"operation", name)
+}
+
+// parseOperation parses a query operation.
+func (p *parser) parseOperation() (*Operation, error) {
+	op := &Operation{Variables: map[string]Value{}}
+
+	if t := p.peek(); t.kind == tokName {
+		switch t.val {
+		case "query":
+			p.next()
+		case "mutation", "subscription":
+			return nil, errors.New(errors.ErrInvalidRequest,
+				t.val+" operations are not supported")
+		case "fragment":
+			return nil, errors.New(errors.ErrInvalidRequest,
+				"fragments are not supported")
+		default:
+			return nil, errSyntax("unexpected name "+t.val, t.pos)
+		}
+
+		if p.peek().kind == tokName {
+			op.Name = p.next().val
+		}
+
+		if p.is("(") {
+			if err := p.parseVariables(op); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	sels, err := p.parseSelections(0)
+	if err != nil {
+		return nil, err
+	}
+
+	op.Selections = sels
+
+	return op, nil
+}
+
+// parseVariables parses the variable definitions of an operation. Variable
+// types are parsed, but not checked, since arguments are checked when they
+// are used.
+func (p *parser) parseVariables(op *Operation) error {
+	if err := p.expect("("); err != nil {
+		return err
+	}
+
+	for !p.is(")") {
+		if err := p.expect("$"); err != nil {
+			return err
+		}
+
+		name, err := p.name()
+		if err != nil {
+			return err
+		}
+
+		if err := p.expect(":"); err != nil {
+			return err
+		}
+
+		if err := p.parseType(0); err != nil {
+			return err
+		}
+
+		op.Variables[name] = nil
+
+		if p.is("=") {
+			p.next()
+
+			v, err := p.parseValue(true, 0)
+			if err != nil {
+				return err
+			}
+
+			op.Variables[name] = v
+		}
+	}
+
+	p.next()
+
+	return nil
+}
+
+// parseType parses a variable type.
+func (p *parser) parseType(depth int) error {
+	if depth > maxDepth {
+		return errSyntax("type nested too deeply", p.peek().pos)
+	}
+
+	if p.is("[") {
+		p.next()
+
+		if err := p.parseType(depth + 1); err != nil {
+			return err
+		}
+
+		if err := p.expect("]"); err != nil {
+			return err
+		}
+	} else if _, err := p.name(); err != nil {
+		return err
+	}
+
+	if p.is("!") {
+		p.next()
+	}
+
+	return nil
+}
+
+// parseSelections parses a selection set.
+func (p *parser) parseSelections(depth int) ([]*Selection, error) {
+	if depth > maxDepth {
+		return nil, errors.New(errors.ErrInvalidRequest,
+			"query nested too deeply",
+			"max", maxDepth)
+	}
+
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+
+	sels := []*Selection{}
+
+	for !p.is("}") {
+		if p.is("...") {
+			return nil, errors.New(errors.ErrInvalidRequest,
+				"fragments are not supported")
+		}
+
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+
+		sel := &Selection{Name: name}
+
+		if p.is(":") {
+			p.next()
+
+			if sel.Name, err = p.name(); err != nil {
+				return nil, err
+			}
+
+			sel.Alias = name
+		}
+
+		if p.is("(") {
+			if sel.Args, err = p.parseArgs(depth); err != nil {
+				return nil, err
+			}
+		}
+
+		if p.is("@") {
+			return nil, errors.New(errors.ErrInvalidRequest,
+				"directives are not supported")
+		}
+
+		if p.is("{") {
+			if sel.Selections, err = p.parseSelections(depth + 1); err != nil {
+				return nil, err
+			}
+		}
+
+		sels = append(sels, sel)
+	}
+
+	p.next()
+
+	if len(sels) == 0 {
+		return nil, errSyntax("empty selection set", p.peek().pos)
+	}
+
+	return sels, nil
+}
+
+// parseArgs parses the arguments of a field.
+func (p *parser) parseArgs(depth int) (map[string]Value, error) {
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+
+	args := map[string]Value{}
+
+	for !p.is(")") {
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+
+		if args[name], err = p.parseValue(false, depth); err != nil {
+			return nil, err
+		}
+	}
+
+	p.next()
+
+	return args, nil
+}
+
+// parseValue parses a value. Constant values may not contain variables.
+func (p *parser) parseValue(constant bool, depth int) (Value, error) {
+	if depth > maxDepth {
+		return nil, errSyntax("value nested too deeply", p.peek().pos)
+	}
+
+	t := p.next()
+
+	switch t.kind {
+	case tokInt:
+		n, err := strconv.ParseInt(t.val, 10, 64)
+		if err != nil {
+			return nil, errSyntax("invalid integer", t.pos)
+		}
+
+		return &literal{v: n}, nil
+	case tokFloat:
+		f, err := strconv.ParseFloat(t.val, 64)
+		if err != nil {
+			return nil, errSyntax("invalid float", t.pos)
+		}
+
+		return &literal{v: f}, nil
+	case tokString:
+		return &literal{v: t.val}, nil
+	case tokName:
+		switch t.val {
+		case "true":
+			return &literal{v: true}, nil
+		case "false":
+			return &literal{v: false}, nil
+		case "null":
+			return &literal{v: nil}, nil
+		}
+
+		return &literal{v: t.val}, nil
+	case tokPunct:
+		switch t.val {
+		case "$":
+			if constant {
+				return nil, errSyntax("unexpected variable", t.pos)
+			}
+
+			name, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+
+			return &variable{name: name}, nil
+		case "[":
+			res := list{}
+
+			for !p.is("]") {
+				v, err := p.parseValue(constant, depth+1)
+				if err != nil {
+					return nil, err
+				}
+
+				res = append(res, v)
+			}
+
+			p.next()
+
+			return res, nil
+		case "{":
+			res := object{}
+
+			for !p.is("}") {
+				name, err := p.name()
+				if err != nil {
+					return nil, err
+				}
+
+				if err := p.expect(":"); err != nil {
+					return nil, err
+				}
+
+				if res[name], err = p.parseValue(constant,
+					depth+1); err != nil {
+					return nil, err
+				}
+			}
+
+			p.next()
+
+			return res, nil
+		}
+	}
+
+	return nil, errSyntax("unexpected token", t.pos)
+}
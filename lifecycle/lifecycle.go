@@ -0,0 +1,322 @@
+// Package lifecycle manages the starting and stopping of the components of a
+// service, such as its database, cache, background jobs and HTTP servers.
+//
+// Components are started in the order of their dependencies, so that every
+// component is started after the components it depends on, and stopped in the
+// reverse order, so that no component is stopped while another component
+// which depends on it is still running.
+package lifecycle
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/dhaifley/game2d/errors"
+	"github.com/dhaifley/game2d/logger"
+)
+
+// Component values are parts of a service which are started and stopped.
+type Component interface {
+	Start(ctx context.Context) error
+	Stop(ctx context.Context) error
+}
+
+// Funcs values are components which start and stop by calling functions.
+// Either function may be nil, if there is nothing to do.
+type Funcs struct {
+	StartFunc func(ctx context.Context) error
+	StopFunc  func(ctx context.Context) error
+}
+
+// Start starts the component.
+func (f *Funcs) Start(ctx context.Context) error {
+	if f.StartFunc == nil {
+		return nil
+	}
+
+	return f.StartFunc(ctx)
+}
+
+// Stop stops the component.
+func (f *Funcs) Stop(ctx context.Context) error {
+	if f.StopFunc == nil {
+		return nil
+	}
+
+	return f.StopFunc(ctx)
+}
+
+// Option values configure the registration of components.
+type Option func(*entry)
+
+// DependsOn specifies the names of the components which a component depends
+// on, which are started before it, and stopped after it.
+func DependsOn(names ...string) Option {
+	return func(e *entry) {
+		e.deps = append(e.deps, names...)
+	}
+}
+
+// StopTimeout specifies the maximum time allowed for a component to stop.
+func StopTimeout(d time.Duration) Option {
+	return func(e *entry) {
+		e.timeout = d
+	}
+}
+
+// entry values are registered components.
+type entry struct {
+	name    string
+	c       Component
+	deps    []string
+	timeout time.Duration
+}
+
+// Manager values start and stop registered components in the order of their
+// dependencies.
+type Manager struct {
+	sync.Mutex
+	log     logger.Logger
+	entries []*entry
+	started []*entry
+}
+
+// New creates a new lifecycle manager.
+func New(log logger.Logger) *Manager {
+	if log == nil {
+		log = logger.NullLog
+	}
+
+	return &Manager{log: log}
+}
+
+// Register adds a named component to the manager. Components must be
+// registered before the manager is started.
+func (m *Manager) Register(name string, c Component, opts ...Option) error {
+	m.Lock()
+	defer m.Unlock()
+
+	if name == "" || c == nil {
+		return errors.New(errors.ErrInvalidParameter,
+			"component name and value are required",
+			"name", name)
+	}
+
+	if slices.ContainsFunc(m.entries, func(e *entry) bool {
+		return e.name == name
+	}) {
+		return errors.New(errors.ErrInvalidParameter,
+			"component already registered",
+			"name", name)
+	}
+
+	e := &entry{name: name, c: c}
+
+	for _, opt := range opts {
+		opt(e)
+	}
+
+	m.entries = append(m.entries, e)
+
+	return nil
+}
+
+// Order returns the names of the registered components in the order in which
+// they are started. Components without dependencies between them are started
+// in the order in which they were registered.
+func (m *Manager) Order() ([]string, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	order, err := m.order()
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]string, 0, len(order))
+
+	for _, e := range order {
+		res = append(res, e.name)
+	}
+
+	return res, nil
+}
+
+// order sorts the registered components so that each component follows the
+// components it depends on.
+func (m *Manager) order() ([]*entry, error) {
+	byName := make(map[string]*entry, len(m.entries))
+
+	for _, e := range m.entries {
+		byName[e.name] = e
+	}
+
+	const (
+		visiting = 1
+		visited  = 2
+	)
+
+	state := make(map[string]int, len(m.entries))
+
+	res := make([]*entry, 0, len(m.entries))
+
+	var visit func(e *entry) error
+
+	visit = func(e *entry) error {
+		switch state[e.name] {
+		case visited:
+			return nil
+		case visiting:
+			return errors.New(errors.ErrInvalidParameter,
+				"component dependency cycle",
+				"name", e.name)
+		}
+
+		state[e.name] = visiting
+
+		for _, d := range e.deps {
+			de, ok := byName[d]
+			if !ok {
+				return errors.New(errors.ErrInvalidParameter,
+					"unknown component dependency",
+					"name", e.name,
+					"dependency", d)
+			}
+
+			if err := visit(de); err != nil {
+				return err
+			}
+		}
+
+		state[e.name] = visited
+
+		res = append(res, e)
+
+		return nil
+	}
+
+	for _, e := range m.entries {
+		if err := visit(e); err != nil {
+			return nil, err
+		}
+	}
+
+	return res, nil
+}
+
+// Start starts the registered components in the order of their dependencies.
+// If a component fails to start, the components already started are stopped,
+// and the error is returned.
+func (m *Manager) Start(ctx context.Context) error {
+	m.Lock()
+
+	order, err := m.order()
+	if err != nil {
+		m.Unlock()
+
+		return err
+	}
+
+	for _, e := range order {
+		if slices.Contains(m.started, e) {
+			continue
+		}
+
+		if err := e.c.Start(ctx); err != nil {
+			m.Unlock()
+
+			err = errors.Wrap(err, errors.ErrServer,
+				"unable to start component",
+				"name", e.name)
+
+			return errors.Join(err, m.Stop(ctx))
+		}
+
+		m.log.Log(ctx, logger.LvlDebug, "component started",
+			"name", e.name)
+
+		m.started = append(m.started, e)
+	}
+
+	m.Unlock()
+
+	return nil
+}
+
+// Stop stops the started components in the reverse order in which they were
+// started, so that components are stopped before the components they depend
+// on. Each component is allowed up to its stop timeout to stop. Components
+// continue to be stopped if any fail to stop, and the errors are returned.
+func (m *Manager) Stop(ctx context.Context) error {
+	m.Lock()
+
+	started := m.started
+
+	m.started = nil
+
+	m.Unlock()
+
+	return m.stopAll(ctx, started)
+}
+
+// StopAll stops all of the registered components, whether or not they were
+// started by the manager, in the reverse order of their dependencies. It is
+// used when components may also be started without the manager, such as by
+// tests, and so must be safe to stop when they were not started.
+func (m *Manager) StopAll(ctx context.Context) error {
+	m.Lock()
+
+	order, err := m.order()
+
+	m.started = nil
+
+	m.Unlock()
+
+	if err != nil {
+		return err
+	}
+
+	return m.stopAll(ctx, order)
+}
+
+// stopAll stops components in the reverse order of a list of components.
+func (m *Manager) stopAll(ctx context.Context, entries []*entry) error {
+	var errs []error
+
+	for _, e := range slices.Backward(entries) {
+		if err := m.stop(ctx, e); err != nil {
+			m.log.Log(ctx, logger.LvlError, "unable to stop component",
+				"error", err,
+				"name", e.name)
+
+			errs = append(errs, err)
+
+			continue
+		}
+
+		m.log.Log(ctx, logger.LvlDebug, "component stopped",
+			"name", e.name)
+	}
+
+	return errors.Join(errs...)
+}
+
+// stop stops a single component, within its stop timeout.
+func (m *Manager) stop(ctx context.Context, e *entry) error {
+	if e.timeout > 0 {
+		var cancel context.CancelFunc
+
+		ctx, cancel = context.WithTimeout(ctx, e.timeout)
+		defer cancel()
+	}
+
+	if err := e.c.Stop(ctx); err != nil {
+		return errors.Wrap(err, errors.ErrServer,
+			"unable to stop component",
+			"name", e.name)
+	}
+
+	return nil
+}
@@ -0,0 +1,262 @@
+package lifecycle_test
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dhaifley/game2d/errors"
+	"github.com/dhaifley/game2d/lifecycle"
+)
+
+type recorder struct {
+	sync.Mutex
+	events []string
+}
+
+func (r *recorder) add(ev string) {
+	r.Lock()
+	defer r.Unlock()
+
+	r.events = append(r.events, ev)
+}
+
+func (r *recorder) component(name string, startErr error) lifecycle.Component {
+	return &lifecycle.Funcs{
+		StartFunc: func(ctx context.Context) error {
+			if startErr != nil {
+				return startErr
+			}
+
+			r.add("start " + name)
+
+			return nil
+		},
+		StopFunc: func(ctx context.Context) error {
+			r.add("stop " + name)
+
+			return nil
+		},
+	}
+}
+
+func TestManager(t *testing.T) {
+	t.Parallel()
+
+	r := &recorder{}
+
+	m := lifecycle.New(nil)
+
+	if err := m.Register("http", r.component("http", nil),
+		lifecycle.DependsOn("jobs", "db")); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if err := m.Register("jobs", r.component("jobs", nil),
+		lifecycle.DependsOn("db", "cache")); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if err := m.Register("db", r.component("db", nil)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if err := m.Register("cache", r.component("cache", nil)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if err := m.Register("db", r.component("db", nil)); err == nil {
+		t.Errorf("Expected error for duplicate component")
+	}
+
+	order, err := m.Order()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	exp := []string{"db", "cache", "jobs", "http"}
+
+	if !slices.Equal(order, exp) {
+		t.Errorf("Expected order: %v, got: %v", exp, order)
+	}
+
+	if err := m.Start(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if err := m.Stop(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// A second stop has nothing left to stop.
+	if err := m.Stop(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	exp = []string{
+		"start db", "start cache", "start jobs", "start http",
+		"stop http", "stop jobs", "stop cache", "stop db",
+	}
+
+	if !slices.Equal(r.events, exp) {
+		t.Errorf("Expected events: %v, got: %v", exp, r.events)
+	}
+}
+
+func TestManagerDependencyErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		deps map[string][]string
+	}{{
+		name: "unknown",
+		deps: map[string][]string{"a": {"b"}},
+	}, {
+		name: "cycle",
+		deps: map[string][]string{"a": {"b"}, "b": {"c"}, "c": {"a"}},
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			m := lifecycle.New(nil)
+
+			for _, name := range slices.Sorted(maps.Keys(tt.deps)) {
+				if err := m.Register(name, &lifecycle.Funcs{},
+					lifecycle.DependsOn(tt.deps[name]...)); err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+			}
+
+			err := m.Start(context.Background())
+			if !errors.Has(err, errors.ErrInvalidParameter) {
+				t.Errorf("Expected error code: %v, got: %v",
+					errors.ErrInvalidParameter, err)
+			}
+		})
+	}
+}
+
+func TestManagerStartError(t *testing.T) {
+	t.Parallel()
+
+	r := &recorder{}
+
+	m := lifecycle.New(nil)
+
+	if err := m.Register("db", r.component("db", nil)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if err := m.Register("jobs", r.component("jobs",
+		errors.New(errors.ErrServer, "test")),
+		lifecycle.DependsOn("db")); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if err := m.Start(context.Background()); err == nil {
+		t.Fatalf("Expected error")
+	}
+
+	exp := []string{"start db", "stop db"}
+
+	if !slices.Equal(r.events, exp) {
+		t.Errorf("Expected events: %v, got: %v", exp, r.events)
+	}
+}
+
+func TestManagerStopTimeout(t *testing.T) {
+	t.Parallel()
+
+	r := &recorder{}
+
+	m := lifecycle.New(nil)
+
+	if err := m.Register("db", r.component("db", nil)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if err := m.Register("jobs", &lifecycle.Funcs{
+		StopFunc: func(ctx context.Context) error {
+			<-ctx.Done()
+
+			r.add("stop jobs")
+
+			return ctx.Err()
+		},
+	}, lifecycle.DependsOn("db"),
+		lifecycle.StopTimeout(10*time.Millisecond)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if err := m.Start(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	start := time.Now()
+
+	err := m.Stop(context.Background())
+	if !errors.Has(err, errors.ErrServer) {
+		t.Errorf("Expected error code: %v, got: %v", errors.ErrServer, err)
+	}
+
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("Expected stop within timeout, took: %v", d)
+	}
+
+	// The database is stopped even though the jobs failed to stop in time.
+	exp := []string{"start db", "stop jobs", "stop db"}
+
+	if !slices.Equal(r.events, exp) {
+		t.Errorf("Expected events: %v, got: %v", exp, r.events)
+	}
+}
+
+func TestManagerStopAll(t *testing.T) {
+	t.Parallel()
+
+	r := &recorder{}
+
+	m := lifecycle.New(nil)
+
+	if err := m.Register("jobs", r.component("jobs", nil),
+		lifecycle.DependsOn("db")); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if err := m.Register("db", r.component("db", nil)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Components are stopped even though they were not started.
+	if err := m.StopAll(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if err := m.Start(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if err := m.StopAll(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Nothing started remains to be stopped.
+	if err := m.Stop(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	exp := []string{
+		"stop jobs", "stop db",
+		"start db", "start jobs", "stop jobs", "stop db",
+	}
+
+	if !slices.Equal(r.events, exp) {
+		t.Errorf("Expected events: %v, got: %v", exp, r.events)
+	}
+}
@@ -0,0 +1,422 @@
+// Package loadtest drives mixes of game2d API requests against a server, from
+// many concurrent workers, measuring the latency of each operation, and
+// checking the latencies and error rates against performance budgets.
+//
+// Each worker creates its own game before the test starts, which it retrieves,
+// saves and prompts during the test, and which is deleted when it completes.
+package loadtest
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"math"
+	mrand "math/rand/v2"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dhaifley/game2d/client/api"
+	"github.com/dhaifley/game2d/errors"
+	"github.com/dhaifley/game2d/request"
+	"github.com/google/uuid"
+)
+
+// Load test operations.
+const (
+	OpListGames = "list_games"
+	OpGetGame   = "get_game"
+	OpSaveGame  = "save_game"
+	OpPrompt    = "prompt"
+)
+
+// Load test defaults.
+const (
+	DefaultWorkers       = 4
+	DefaultDuration      = 30 * time.Second
+	DefaultGameSize      = 1024 * 1024
+	DefaultPromptTimeout = 30 * time.Second
+)
+
+// pollInterval is the time waited between checks for the completion of
+// prompts.
+const pollInterval = 50 * time.Millisecond
+
+// Operations returns the names of the load test operations.
+func Operations() []string {
+	return []string{OpListGames, OpGetGame, OpSaveGame, OpPrompt}
+}
+
+// DefaultMix returns the default mix of operations, which are mostly reads, as
+// when players browse and play games, with some saves and fewer prompts.
+func DefaultMix() map[string]int {
+	return map[string]int{
+		OpListGames: 40,
+		OpGetGame:   40,
+		OpSaveGame:  15,
+		OpPrompt:    5,
+	}
+}
+
+// ParseMix parses a mix of operations, such as list_games=40,get_game=60,
+// into the relative weight of each operation.
+func ParseMix(s string) (map[string]int, error) {
+	res := map[string]int{}
+
+	for _, p := range strings.Split(s, ",") {
+		if p = strings.TrimSpace(p); p == "" {
+			continue
+		}
+
+		op, w, ok := strings.Cut(p, "=")
+		if !ok || !slices.Contains(Operations(), op) {
+			return nil, errors.New(errors.ErrInvalidRequest,
+				"invalid load test mix operation",
+				"operation", p)
+		}
+
+		n, err := strconv.Atoi(w)
+		if err != nil || n < 0 {
+			return nil, errors.New(errors.ErrInvalidRequest,
+				"invalid load test mix weight",
+				"operation", p)
+		}
+
+		res[op] = n
+	}
+
+	return res, nil
+}
+
+// Options values configure load tests. The test runs until either the
+// duration has elapsed, or the number of requests has been made, if it is
+// greater than zero. The game size is the approximate size, in bytes, of the
+// image data of the games saved by the test.
+type Options struct {
+	Workers       int
+	Duration      time.Duration
+	Requests      int
+	Mix           map[string]int
+	GameSize      int
+	PromptTimeout time.Duration
+	Budgets       map[string]*Budget
+	Seed          int64
+}
+
+// sample values record the result of a single operation.
+type sample struct {
+	op      string
+	latency time.Duration
+	err     error
+}
+
+// worker values run operations on their own game.
+type worker struct {
+	c       *api.Client
+	opts    *Options
+	rng     *mrand.Rand
+	gameID  string
+	created []string
+	images  request.FieldJSON
+}
+
+// Run runs a load test against the API used by a client, and returns the
+// report of the test. The returned error is only for failures to set up and
+// clean up the test, the errors of the operations are counted by the report.
+func Run(ctx context.Context, c *api.Client, opts *Options) (*Report, error) {
+	opts = withDefaults(opts)
+
+	ops, weights := []string{}, []int{}
+
+	for _, op := range Operations() {
+		if w := opts.Mix[op]; w > 0 {
+			ops = append(ops, op)
+			weights = append(weights, w)
+		}
+	}
+
+	if len(ops) == 0 {
+		return nil, errors.New(errors.ErrInvalidRequest,
+			"load test mix has no operations")
+	}
+
+	images, err := gameImages(opts.GameSize)
+	if err != nil {
+		return nil, err
+	}
+
+	workers := make([]*worker, opts.Workers)
+
+	defer func() {
+		for _, w := range workers {
+			if w != nil {
+				w.cleanup()
+			}
+		}
+	}()
+
+	for i := range workers {
+		w := &worker{
+			c:      c,
+			opts:   opts,
+			rng:    mrand.New(mrand.NewPCG(uint64(opts.Seed), uint64(i))),
+			images: images,
+		}
+
+		if err := w.setup(ctx); err != nil {
+			return nil, err
+		}
+
+		workers[i] = w
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, opts.Duration)
+	defer cancel()
+
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		samples []sample
+		started int
+	)
+
+	// next reserves the next request, returning false when the test is done.
+	next := func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if ctx.Err() != nil ||
+			(opts.Requests > 0 && started >= opts.Requests) {
+			return false
+		}
+
+		started++
+
+		return true
+	}
+
+	start := time.Now()
+
+	for _, w := range workers {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for next() {
+				op := pick(w.rng, ops, weights)
+
+				s := w.run(ctx, op)
+
+				// Operations interrupted by the end of the test are not
+				// counted, since they did not fail.
+				if s.err != nil && ctx.Err() != nil {
+					return
+				}
+
+				mu.Lock()
+				samples = append(samples, s)
+				mu.Unlock()
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	return newReport(samples, time.Since(start), opts.Budgets), nil
+}
+
+// withDefaults returns a copy of the options, with default values set.
+func withDefaults(opts *Options) *Options {
+	res := Options{}
+
+	if opts != nil {
+		res = *opts
+	}
+
+	if res.Workers <= 0 {
+		res.Workers = DefaultWorkers
+	}
+
+	if res.Duration <= 0 {
+		res.Duration = DefaultDuration
+	}
+
+	if res.Mix == nil {
+		res.Mix = DefaultMix()
+	}
+
+	if res.GameSize <= 0 {
+		res.GameSize = DefaultGameSize
+	}
+
+	if res.PromptTimeout <= 0 {
+		res.PromptTimeout = DefaultPromptTimeout
+	}
+
+	return &res
+}
+
+// pick picks an operation at random, in proportion to their weights.
+func pick(rng *mrand.Rand, ops []string, weights []int) string {
+	total := 0
+
+	for _, w := range weights {
+		total += w
+	}
+
+	n := rng.IntN(total)
+
+	for i, w := range weights {
+		if n < w {
+			return ops[i]
+		}
+
+		n -= w
+	}
+
+	return ops[len(ops)-1]
+}
+
+// gameImages returns the images of the games saved by the test, containing
+// random data of about a size, so that it can not be compressed.
+func gameImages(size int) (request.FieldJSON, error) {
+	b := make([]byte, max(size*3/4, 1))
+
+	if _, err := rand.Read(b); err != nil {
+		return request.FieldJSON{}, errors.Wrap(err, errors.ErrClient,
+			"unable to generate load test image data")
+	}
+
+	return request.FieldJSON{Set: true, Valid: true, Value: map[string]any{
+		"loadtest": map[string]any{
+			"id":   "loadtest",
+			"name": "loadtest.png",
+			"w":    64,
+			"h":    64,
+			"data": base64.StdEncoding.EncodeToString(b),
+		},
+	}}, nil
+}
+
+// setup creates the game of the worker.
+func (w *worker) setup(ctx context.Context) error {
+	id := uuid.NewString()
+
+	g, err := w.c.CreateGame(ctx, &api.Game{
+		ID:     request.FieldString{Set: true, Valid: true, Value: id},
+		Name:   request.FieldString{Set: true, Valid: true, Value: "Load Test"},
+		W:      request.FieldInt64{Set: true, Valid: true, Value: 640},
+		H:      request.FieldInt64{Set: true, Valid: true, Value: 480},
+		Images: w.images,
+		Script: request.FieldString{
+			Set: true, Valid: true,
+			Value: base64.StdEncoding.EncodeToString(
+				[]byte("function Update(game)\nreturn game\nend")),
+		},
+	})
+	if err != nil {
+		return errors.Wrap(err, errors.ErrClient,
+			"unable to create load test game")
+	}
+
+	if g != nil && g.ID.Value != "" {
+		id = g.ID.Value
+	}
+
+	w.gameID = id
+	w.created = append(w.created, id)
+
+	return nil
+}
+
+// cleanup deletes the games created by the worker.
+func (w *worker) cleanup() {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	for _, id := range w.created {
+		_ = w.c.DeleteGame(ctx, id)
+	}
+}
+
+// run runs an operation, and returns its result.
+func (w *worker) run(ctx context.Context, op string) sample {
+	start := time.Now()
+
+	var err error
+
+	switch op {
+	case OpListGames:
+		_, err = w.c.ListGames(ctx, &request.Query{Size: 20})
+	case OpGetGame:
+		_, err = w.c.GetGame(ctx, w.gameID)
+	case OpSaveGame:
+		err = w.save(ctx)
+	case OpPrompt:
+		err = w.prompt(ctx)
+	}
+
+	return sample{op: op, latency: time.Since(start), err: err}
+}
+
+// save saves the game of the worker, with its images and a new score.
+func (w *worker) save(ctx context.Context) error {
+	sd := map[string]any{"score": w.rng.IntN(math.MaxInt32)}
+
+	_, err := w.c.UpdateGame(ctx, &api.Game{
+		ID:         request.FieldString{Set: true, Valid: true, Value: w.gameID},
+		Images:     w.images,
+		StatusData: request.FieldJSON{Set: true, Valid: true, Value: sd},
+	})
+
+	return err
+}
+
+// prompt sends a prompt for the game of the worker, and waits for the new
+// revision of the game, created by the prompt, to finish updating. The worker
+// uses the new revision from then on.
+func (w *worker) prompt(ctx context.Context) error {
+	res, err := w.c.Prompt(ctx, &api.Prompts{
+		GameID: request.FieldString{Set: true, Valid: true, Value: w.gameID},
+		Current: api.Prompt{Prompt: request.FieldString{
+			Set: true, Valid: true, Value: "Make the game more fun.",
+		}},
+	})
+	if err != nil {
+		return err
+	}
+
+	if res == nil || res.GameID.Value == "" {
+		return errors.New(errors.ErrClient,
+			"prompt response missing game id",
+			"game_id", w.gameID)
+	}
+
+	w.gameID = res.GameID.Value
+	w.created = append(w.created, w.gameID)
+
+	ctx, cancel := context.WithTimeout(ctx, w.opts.PromptTimeout)
+	defer cancel()
+
+	for {
+		g, err := w.c.GetGame(ctx, w.gameID)
+		if err != nil {
+			return err
+		}
+
+		if g.Status.Value != request.StatusUpdating {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return errors.Wrap(ctx.Err(), errors.ErrClient,
+				"prompt did not complete",
+				"game_id", w.gameID)
+		case <-time.After(pollInterval):
+		}
+	}
+}
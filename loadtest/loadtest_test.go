@@ -0,0 +1,163 @@
+package loadtest_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dhaifley/game2d/client/api"
+	"github.com/dhaifley/game2d/loadtest"
+	"github.com/dhaifley/game2d/server/servertest"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestAPI creates a server emulating the game API used by load tests, which
+// counts the games created and deleted.
+func newTestAPI(t *testing.T, created, deleted *atomic.Int64) *httptest.Server {
+	t.Helper()
+
+	ts := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			switch {
+			case r.Method == http.MethodPost && r.URL.Path == "/games":
+				created.Add(1)
+
+				w.WriteHeader(http.StatusCreated)
+				w.Write([]byte(`{}`))
+			case r.Method == http.MethodPost &&
+				r.URL.Path == "/games/prompt":
+				created.Add(1)
+
+				w.WriteHeader(http.StatusCreated)
+				json.NewEncoder(w).Encode(map[string]any{
+					"game_id": uuid.NewString(),
+				})
+			case r.Method == http.MethodGet && r.URL.Path == "/games":
+				w.Write([]byte(`[]`))
+			case r.Method == http.MethodGet || r.Method == http.MethodPatch:
+				w.Write([]byte(`{"status":"active"}`))
+			case r.Method == http.MethodDelete:
+				deleted.Add(1)
+
+				w.WriteHeader(http.StatusNoContent)
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+
+	t.Cleanup(ts.Close)
+
+	return ts
+}
+
+func TestRun(t *testing.T) {
+	t.Parallel()
+
+	var created, deleted atomic.Int64
+
+	ts := newTestAPI(t, &created, &deleted)
+
+	rep, err := loadtest.Run(context.Background(), api.New(ts.URL),
+		&loadtest.Options{
+			Workers:  2,
+			Duration: time.Minute,
+			Requests: 100,
+			GameSize: 1024,
+			Budgets: map[string]*loadtest.Budget{
+				loadtest.OpGetGame: {P95: time.Hour},
+				loadtest.AllOps:    {Max: time.Nanosecond},
+			},
+		})
+	require.NoError(t, err)
+
+	all := rep.Stats[len(rep.Stats)-1]
+	assert.Equal(t, loadtest.AllOps, all.Op)
+	assert.Equal(t, 100, all.Requests, "The number of requests should be made")
+	assert.Zero(t, all.Errors)
+	assert.LessOrEqual(t, all.P50, all.P99)
+	assert.Equal(t, created.Load(), deleted.Load(),
+		"Created games should be deleted")
+
+	assert.False(t, rep.Passed(), "Exceeded budgets should fail")
+
+	buf := &bytes.Buffer{}
+
+	require.NoError(t, rep.Write(buf))
+	assert.Contains(t, buf.String(), "PASS get_game p95")
+	assert.Contains(t, buf.String(), "FAIL all max")
+	assert.True(t, strings.HasSuffix(buf.String(), "FAIL\n"))
+
+	buf.Reset()
+
+	require.NoError(t, rep.WriteJSON(buf))
+	assert.Contains(t, buf.String(), `"passed": false`)
+
+	_, err = loadtest.Run(context.Background(), api.New(ts.URL),
+		&loadtest.Options{Mix: map[string]int{loadtest.OpPrompt: 0}})
+	assert.Error(t, err, "Mixes without operations should be invalid")
+}
+
+func TestRunServer(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration tests")
+	}
+
+	s := servertest.New(t, nil)
+
+	rep, err := loadtest.Run(context.Background(),
+		api.New(s.Path(""), api.WithToken(s.Token)),
+		&loadtest.Options{
+			Workers:  2,
+			Duration: time.Minute,
+			Requests: 40,
+			GameSize: 64 * 1024,
+			Budgets:  map[string]*loadtest.Budget{loadtest.AllOps: {}},
+		})
+	require.NoError(t, err)
+
+	buf := &bytes.Buffer{}
+
+	require.NoError(t, rep.Write(buf))
+	assert.True(t, rep.Passed(), "Requests should not fail:\n%s", buf)
+}
+
+func TestParseMix(t *testing.T) {
+	t.Parallel()
+
+	mix, err := loadtest.ParseMix("list_games=1, prompt=2")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{
+		loadtest.OpListGames: 1,
+		loadtest.OpPrompt:    2,
+	}, mix)
+
+	_, err = loadtest.ParseMix("unknown=1")
+	assert.Error(t, err)
+
+	_, err = loadtest.ParseMix("get_game=-1")
+	assert.Error(t, err)
+}
+
+func TestParseBudget(t *testing.T) {
+	t.Parallel()
+
+	op, b, err := loadtest.ParseBudget("get_game:p95=100ms,errors=0.01")
+	require.NoError(t, err)
+	assert.Equal(t, loadtest.OpGetGame, op)
+	assert.Equal(t, 100*time.Millisecond, b.P95)
+	assert.InEpsilon(t, 0.01, b.ErrorRate, 0.0001)
+
+	for _, s := range []string{"get_game", "unknown:p95=1s",
+		"get_game:p95=fast", "get_game:p42=1s"} {
+		_, _, err := loadtest.ParseBudget(s)
+		assert.Error(t, err, s)
+	}
+}
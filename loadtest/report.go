@@ -0,0 +1,337 @@
+package loadtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/dhaifley/game2d/errors"
+)
+
+// AllOps is the name used for the statistics and budget of all operations.
+const AllOps = "all"
+
+// Budget values represent the performance budget of an operation. Latencies
+// which are zero are not checked. The error rate is the fraction of requests
+// allowed to fail, which is none by default.
+type Budget struct {
+	P50       time.Duration
+	P90       time.Duration
+	P95       time.Duration
+	P99       time.Duration
+	Max       time.Duration
+	ErrorRate float64
+}
+
+// DefaultBudgets returns the default performance budgets, which are intended
+// for a server running locally, with a mock AI prompter.
+func DefaultBudgets() map[string]*Budget {
+	return map[string]*Budget{
+		OpListGames: {P95: 500 * time.Millisecond},
+		OpGetGame:   {P95: 250 * time.Millisecond},
+		OpSaveGame:  {P95: time.Second},
+		OpPrompt:    {P95: 5 * time.Second},
+	}
+}
+
+// ParseBudget parses the budget of an operation, such as
+// get_game:p95=100ms,errors=0.01, returning the operation and the budget. The
+// operation may be all, for the budget of all operations.
+func ParseBudget(s string) (string, *Budget, error) {
+	op, spec, ok := strings.Cut(strings.TrimSpace(s), ":")
+	if !ok || (op != AllOps && !slices.Contains(Operations(), op)) {
+		return "", nil, errors.New(errors.ErrInvalidRequest,
+			"invalid load test budget operation",
+			"budget", s)
+	}
+
+	b := &Budget{}
+
+	for _, p := range strings.Split(spec, ",") {
+		k, v, _ := strings.Cut(strings.TrimSpace(p), "=")
+
+		var err error
+
+		switch k {
+		case "p50":
+			b.P50, err = time.ParseDuration(v)
+		case "p90":
+			b.P90, err = time.ParseDuration(v)
+		case "p95":
+			b.P95, err = time.ParseDuration(v)
+		case "p99":
+			b.P99, err = time.ParseDuration(v)
+		case "max":
+			b.Max, err = time.ParseDuration(v)
+		case "errors":
+			b.ErrorRate, err = strconv.ParseFloat(v, 64)
+		default:
+			err = errors.New(errors.ErrInvalidRequest,
+				"unknown budget limit")
+		}
+
+		if err != nil {
+			return "", nil, errors.Wrap(err, errors.ErrInvalidRequest,
+				"invalid load test budget",
+				"budget", s)
+		}
+	}
+
+	return op, b, nil
+}
+
+// Stats values contain the latency statistics of an operation.
+type Stats struct {
+	Op       string        `json:"op"`
+	Requests int           `json:"requests"`
+	Errors   int           `json:"errors"`
+	Rate     float64       `json:"rate"`
+	Min      time.Duration `json:"min"`
+	Mean     time.Duration `json:"mean"`
+	P50      time.Duration `json:"p50"`
+	P90      time.Duration `json:"p90"`
+	P95      time.Duration `json:"p95"`
+	P99      time.Duration `json:"p99"`
+	Max      time.Duration `json:"max"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// ErrorRate returns the fraction of requests which failed.
+func (s *Stats) ErrorRate() float64 {
+	if s.Requests == 0 {
+		return 0
+	}
+
+	return float64(s.Errors) / float64(s.Requests)
+}
+
+// Check values contain the result of checking a statistic against a budget.
+type Check struct {
+	Op     string `json:"op"`
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Limit  string `json:"limit"`
+	Passed bool   `json:"passed"`
+}
+
+// String returns the result of the check as a line of text.
+func (c *Check) String() string {
+	res, cmp := "PASS", "<="
+	if !c.Passed {
+		res, cmp = "FAIL", ">"
+	}
+
+	return fmt.Sprintf("%s %s %s %s %s %s", res, c.Op, c.Name, c.Value,
+		cmp, c.Limit)
+}
+
+// Report values contain the results of load tests. Durations are encoded in
+// JSON as nanoseconds.
+type Report struct {
+	Duration time.Duration `json:"duration"`
+	Stats    []*Stats      `json:"stats"`
+	Checks   []*Check      `json:"checks"`
+}
+
+// newReport creates the report of the samples of a load test, checked against
+// budgets.
+func newReport(samples []sample,
+	d time.Duration,
+	budgets map[string]*Budget,
+) *Report {
+	res := &Report{Duration: d}
+
+	byOp := map[string][]sample{}
+
+	for _, s := range samples {
+		byOp[s.op] = append(byOp[s.op], s)
+	}
+
+	for _, op := range Operations() {
+		if len(byOp[op]) > 0 {
+			res.Stats = append(res.Stats, newStats(op, byOp[op], d))
+		}
+	}
+
+	res.Stats = append(res.Stats, newStats(AllOps, samples, d))
+
+	for _, st := range res.Stats {
+		if b := budgets[st.Op]; b != nil {
+			res.Checks = append(res.Checks, b.check(st)...)
+		}
+	}
+
+	return res
+}
+
+// newStats calculates the statistics of the samples of an operation.
+func newStats(op string, samples []sample, d time.Duration) *Stats {
+	res := &Stats{Op: op, Requests: len(samples)}
+
+	if len(samples) == 0 {
+		return res
+	}
+
+	ls := make([]time.Duration, 0, len(samples))
+
+	var total time.Duration
+
+	for _, s := range samples {
+		if s.err != nil {
+			res.Errors++
+
+			if res.Error == "" {
+				res.Error = s.err.Error()
+			}
+		}
+
+		ls = append(ls, s.latency)
+		total += s.latency
+	}
+
+	slices.Sort(ls)
+
+	if d > 0 {
+		res.Rate = float64(len(samples)) / d.Seconds()
+	}
+
+	res.Min = ls[0]
+	res.Max = ls[len(ls)-1]
+	res.Mean = total / time.Duration(len(ls))
+	res.P50 = percentile(ls, 50)
+	res.P90 = percentile(ls, 90)
+	res.P95 = percentile(ls, 95)
+	res.P99 = percentile(ls, 99)
+
+	return res
+}
+
+// percentile returns a percentile of sorted latencies, using the nearest rank
+// method.
+func percentile(ls []time.Duration, p int) time.Duration {
+	if len(ls) == 0 {
+		return 0
+	}
+
+	i := (p*len(ls)+99)/100 - 1
+
+	return ls[min(max(i, 0), len(ls)-1)]
+}
+
+// check checks the statistics of an operation against the budget.
+func (b *Budget) check(st *Stats) []*Check {
+	res := []*Check{}
+
+	for _, l := range []struct {
+		name         string
+		value, limit time.Duration
+	}{
+		{"p50", st.P50, b.P50},
+		{"p90", st.P90, b.P90},
+		{"p95", st.P95, b.P95},
+		{"p99", st.P99, b.P99},
+		{"max", st.Max, b.Max},
+	} {
+		if l.limit <= 0 {
+			continue
+		}
+
+		res = append(res, &Check{
+			Op:     st.Op,
+			Name:   l.name,
+			Value:  l.value.Round(time.Microsecond).String(),
+			Limit:  l.limit.String(),
+			Passed: l.value <= l.limit,
+		})
+	}
+
+	return append(res, &Check{
+		Op:     st.Op,
+		Name:   "errors",
+		Value:  strconv.FormatFloat(st.ErrorRate(), 'f', 4, 64),
+		Limit:  strconv.FormatFloat(b.ErrorRate, 'f', 4, 64),
+		Passed: st.ErrorRate() <= b.ErrorRate,
+	})
+}
+
+// Passed returns whether all of the budget checks passed.
+func (r *Report) Passed() bool {
+	for _, c := range r.Checks {
+		if !c.Passed {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Write writes the report as text, with a table of the statistics of each
+// operation, followed by a line for each budget check, and a final PASS or
+// FAIL line, so that the output can be checked by CI tools.
+func (r *Report) Write(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+
+	fmt.Fprintln(tw, "OPERATION\tREQUESTS\tERRORS\tRATE\tMIN\tMEAN\tP50\t"+
+		"P90\tP95\tP99\tMAX")
+
+	for _, st := range r.Stats {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%.1f/s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			st.Op, st.Requests, st.Errors, st.Rate,
+			round(st.Min), round(st.Mean), round(st.P50), round(st.P90),
+			round(st.P95), round(st.P99), round(st.Max))
+	}
+
+	if err := tw.Flush(); err != nil {
+		return errors.Wrap(err, errors.ErrClient,
+			"unable to write load test report")
+	}
+
+	for _, st := range r.Stats {
+		if st.Error != "" && st.Op != AllOps {
+			fmt.Fprintf(w, "ERROR %s: %s\n", st.Op, st.Error)
+		}
+	}
+
+	for _, c := range r.Checks {
+		fmt.Fprintln(w, c.String())
+	}
+
+	res := "PASS"
+	if !r.Passed() {
+		res = "FAIL"
+	}
+
+	if _, err := fmt.Fprintln(w, res); err != nil {
+		return errors.Wrap(err, errors.ErrClient,
+			"unable to write load test report")
+	}
+
+	return nil
+}
+
+// WriteJSON writes the report as JSON.
+func (r *Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+
+	enc.SetIndent("", "  ")
+
+	if err := enc.Encode(struct {
+		*Report
+		Passed bool `json:"passed"`
+	}{r, r.Passed()}); err != nil {
+		return errors.Wrap(err, errors.ErrClient,
+			"unable to write load test report")
+	}
+
+	return nil
+}
+
+// round rounds a latency for display.
+func round(d time.Duration) string {
+	return d.Round(10 * time.Microsecond).String()
+}
@@ -0,0 +1,46 @@
+// Package notify is used for delivering notifications to users through
+// external channels, such as email or web push.
+package notify
+
+import (
+	"context"
+)
+
+// Notification delivery channels.
+const (
+	ChannelEmail = "email"
+	ChannelPush  = "push"
+)
+
+// Message values represent the contents of a notification.
+type Message struct {
+	Title string `json:"title"         yaml:"title"`
+	Body  string `json:"body"          yaml:"body"`
+	URL   string `json:"url,omitempty" yaml:"url,omitempty"`
+}
+
+// SubscriptionKeys values contain the keys of a web push subscription used to
+// encrypt messages sent to it.
+type SubscriptionKeys struct {
+	P256DH string `bson:"p256dh" json:"p256dh" yaml:"p256dh"`
+	Auth   string `bson:"auth"   json:"auth"   yaml:"auth"`
+}
+
+// Subscription values represent a web push subscription, as created by a
+// browser push manager.
+type Subscription struct {
+	Endpoint string           `bson:"endpoint" json:"endpoint" yaml:"endpoint"`
+	Keys     SubscriptionKeys `bson:"keys"     json:"keys"     yaml:"keys"`
+}
+
+// Recipient values identify where notifications are delivered for a user.
+type Recipient struct {
+	Email         string
+	Subscriptions []Subscription
+}
+
+// Sender values are used to deliver notifications through a single channel.
+// Recipients with no address for the channel are skipped.
+type Sender interface {
+	Send(ctx context.Context, to *Recipient, msg *Message) error
+}
@@ -0,0 +1,301 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dhaifley/game2d/errors"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	// pushTTL is how long a push service retains an undelivered message.
+	pushTTL = time.Hour * 24
+
+	// pushRecordSize is the record size of encrypted push messages.
+	pushRecordSize = 4096
+)
+
+// pushSender values are used to deliver notifications by web push, using
+// VAPID authentication (RFC 8292) and message encryption (RFC 8291).
+type pushSender struct {
+	key     *ecdsa.PrivateKey
+	pub     string
+	subject string
+	client  *http.Client
+}
+
+// decodeKey decodes a base64 URL encoded key, with or without padding.
+func decodeKey(v string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(v, "="))
+}
+
+// NewPushSender creates a new web push notification sender. The private key
+// is the base64 URL encoded VAPID private key, and the subject is a mailto: or
+// https: URL, which push services use to contact the sender.
+func NewPushSender(privateKey, subject string,
+	client *http.Client,
+) (Sender, error) {
+	d, err := decodeKey(privateKey)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrConfiguration,
+			"invalid push private key")
+	}
+
+	k, err := ecdh.P256().NewPrivateKey(d)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrConfiguration,
+			"invalid push private key")
+	}
+
+	pub := k.PublicKey().Bytes()
+
+	if !strings.HasPrefix(subject, "mailto:") &&
+		!strings.HasPrefix(subject, "https:") {
+		return nil, errors.New(errors.ErrConfiguration,
+			"invalid push subject",
+			"subject", subject)
+	}
+
+	if client == nil {
+		client = &http.Client{Timeout: time.Second * 30}
+	}
+
+	return &pushSender{
+		key: &ecdsa.PrivateKey{
+			PublicKey: ecdsa.PublicKey{
+				Curve: elliptic.P256(),
+				X:     new(big.Int).SetBytes(pub[1:33]),
+				Y:     new(big.Int).SetBytes(pub[33:]),
+			},
+			D: new(big.Int).SetBytes(d),
+		},
+		pub:     base64.RawURLEncoding.EncodeToString(pub),
+		subject: subject,
+		client:  client,
+	}, nil
+}
+
+// authorization creates the VAPID authorization header for a push endpoint.
+func (s *pushSender) authorization(endpoint string) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Scheme != "https" && u.Scheme != "http" {
+		return "", errors.New(errors.ErrInvalidParameter,
+			"invalid push subscription endpoint",
+			"endpoint", endpoint)
+	}
+
+	tok, err := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{
+		"aud": u.Scheme + "://" + u.Host,
+		"exp": time.Now().Add(time.Hour * 12).Unix(),
+		"sub": s.subject,
+	}).SignedString(s.key)
+	if err != nil {
+		return "", errors.Wrap(err, errors.ErrServer,
+			"unable to sign push authorization token")
+	}
+
+	return "vapid t=" + tok + ", k=" + s.pub, nil
+}
+
+// encrypt encrypts a message for a push subscription, using a single record
+// of the aes128gcm content encoding.
+func encrypt(sub *Subscription, data []byte) ([]byte, error) {
+	uaPub, err := decodeKey(sub.Keys.P256DH)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrInvalidParameter,
+			"invalid push subscription key")
+	}
+
+	ua, err := ecdh.P256().NewPublicKey(uaPub)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrInvalidParameter,
+			"invalid push subscription key")
+	}
+
+	auth, err := decodeKey(sub.Keys.Auth)
+	if err != nil || len(auth) == 0 {
+		return nil, errors.New(errors.ErrInvalidParameter,
+			"invalid push subscription auth secret")
+	}
+
+	as, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrServer,
+			"unable to generate push encryption key")
+	}
+
+	secret, err := as.ECDH(ua)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrInvalidParameter,
+			"unable to derive push shared secret")
+	}
+
+	asPub := as.PublicKey().Bytes()
+
+	ikm, err := hkdf.Key(sha256.New, secret, auth,
+		"WebPush: info\x00"+string(uaPub)+string(asPub), 32)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrServer,
+			"unable to derive push key material")
+	}
+
+	salt := make([]byte, 16)
+
+	if _, err := rand.Read(salt); err != nil {
+		return nil, errors.Wrap(err, errors.ErrServer,
+			"unable to generate push encryption salt")
+	}
+
+	cek, err := hkdf.Key(sha256.New, ikm, salt,
+		"Content-Encoding: aes128gcm\x00", 16)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrServer,
+			"unable to derive push content encryption key")
+	}
+
+	nonce, err := hkdf.Key(sha256.New, ikm, salt,
+		"Content-Encoding: nonce\x00", 12)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrServer,
+			"unable to derive push nonce")
+	}
+
+	// Push services limit the encrypted body, including the 86 byte header,
+	// the delimiter, and the authentication tag, to a single record.
+	if len(data)+17 > pushRecordSize-86 {
+		return nil, errors.New(errors.ErrInvalidParameter,
+			"push message too large",
+			"size", len(data))
+	}
+
+	b, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrServer,
+			"unable to create push cipher")
+	}
+
+	gcm, err := cipher.NewGCM(b)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrServer,
+			"unable to create push cipher")
+	}
+
+	// The header contains the salt, record size, and sender public key.
+	buf := bytes.NewBuffer(salt)
+
+	_ = binary.Write(buf, binary.BigEndian, uint32(pushRecordSize))
+
+	buf.WriteByte(byte(len(asPub)))
+	buf.Write(asPub)
+
+	// A delimiter of 2 marks the final record.
+	buf.Write(gcm.Seal(nil, nonce, append(data, 2), nil))
+
+	return buf.Bytes(), nil
+}
+
+// send delivers a notification to a single push subscription.
+func (s *pushSender) send(ctx context.Context,
+	sub *Subscription,
+	data []byte,
+) error {
+	auth, err := s.authorization(sub.Endpoint)
+	if err != nil {
+		return err
+	}
+
+	body, err := encrypt(sub, data)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.Endpoint,
+		bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, errors.ErrClient,
+			"unable to create push request",
+			"endpoint", sub.Endpoint)
+	}
+
+	req.Header.Set("Authorization", auth)
+	req.Header.Set("Content-Encoding", "aes128gcm")
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("TTL", strconv.Itoa(int(pushTTL.Seconds())))
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, errors.ErrClient,
+			"unable to send push request",
+			"endpoint", sub.Endpoint)
+	}
+
+	defer res.Body.Close()
+
+	switch {
+	case res.StatusCode == http.StatusNotFound ||
+		res.StatusCode == http.StatusGone:
+		return errors.New(errors.ErrNotFound,
+			"push subscription expired",
+			"endpoint", sub.Endpoint)
+	case res.StatusCode == http.StatusTooManyRequests:
+		return errors.New(errors.ErrorRateLimit,
+			"push service rate limit exceeded",
+			"endpoint", sub.Endpoint).
+			WithRateLimit(0, 0, errors.ParseRetryAfter(
+				res.Header.Get("Retry-After"), time.Now()))
+	case res.StatusCode >= 300:
+		return errors.New(errors.ErrClient,
+			"push request failed",
+			"endpoint", sub.Endpoint,
+			"status", res.StatusCode)
+	}
+
+	return nil
+}
+
+// Send delivers a notification by web push to each of the subscriptions of
+// the recipient. Delivery is attempted for all subscriptions, and the first
+// error encountered is returned.
+func (s *pushSender) Send(ctx context.Context,
+	to *Recipient,
+	msg *Message,
+) error {
+	if to == nil || len(to.Subscriptions) == 0 || msg == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return errors.Wrap(err, errors.ErrInvalidParameter,
+			"unable to encode push message")
+	}
+
+	var res error
+
+	for i := range to.Subscriptions {
+		if err := s.send(ctx, &to.Subscriptions[i], data); err != nil &&
+			res == nil {
+			res = err
+		}
+	}
+
+	return res
+}
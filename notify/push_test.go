@@ -0,0 +1,206 @@
+package notify_test
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dhaifley/game2d/errors"
+	"github.com/dhaifley/game2d/notify"
+)
+
+// decrypt decrypts a web push message body, as a user agent would.
+func decrypt(t *testing.T, ua *ecdh.PrivateKey, auth, body []byte) []byte {
+	t.Helper()
+
+	if len(body) < 86 {
+		t.Fatalf("Expected push body header, got: %d bytes", len(body))
+	}
+
+	salt := body[:16]
+
+	if rs := binary.BigEndian.Uint32(body[16:20]); rs != 4096 {
+		t.Errorf("Expected record size: 4096, got: %v", rs)
+	}
+
+	n := int(body[20])
+
+	as, err := ecdh.P256().NewPublicKey(body[21 : 21+n])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	secret, err := ua.ECDH(as)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ikm, err := hkdf.Key(sha256.New, secret, auth, "WebPush: info\x00"+
+		string(ua.PublicKey().Bytes())+string(as.Bytes()), 32)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cek, err := hkdf.Key(sha256.New, ikm, salt,
+		"Content-Encoding: aes128gcm\x00", 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	nonce, err := hkdf.Key(sha256.New, ikm, salt,
+		"Content-Encoding: nonce\x00", 12)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := aes.NewCipher(cek)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gcm, err := cipher.NewGCM(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := gcm.Open(nil, nonce, body[21+n:], nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(res) == 0 || res[len(res)-1] != 2 {
+		t.Fatalf("Expected final record delimiter, got: %v", res)
+	}
+
+	return res[:len(res)-1]
+}
+
+func TestPushSender(t *testing.T) {
+	t.Parallel()
+
+	vapid, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ua, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	auth := make([]byte, 16)
+
+	if _, err := rand.Read(auth); err != nil {
+		t.Fatal(err)
+	}
+
+	var got notify.Message
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter,
+		r *http.Request,
+	) {
+		if r.URL.Path == "/gone" {
+			w.WriteHeader(http.StatusGone)
+
+			return
+		}
+
+		if r.URL.Path == "/limited" {
+			w.Header().Set("Retry-After", "60")
+			w.WriteHeader(http.StatusTooManyRequests)
+
+			return
+		}
+
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "vapid t=") {
+			t.Errorf("Expected vapid authorization, got: %v",
+				r.Header.Get("Authorization"))
+		}
+
+		if r.Header.Get("Content-Encoding") != "aes128gcm" {
+			t.Errorf("Expected content encoding: aes128gcm, got: %v",
+				r.Header.Get("Content-Encoding"))
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := json.Unmarshal(decrypt(t, ua, auth, body),
+			&got); err != nil {
+			t.Error(err)
+		}
+
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	defer ts.Close()
+
+	sd, err := notify.NewPushSender(base64.RawURLEncoding.EncodeToString(
+		vapid.Bytes()), "mailto:test@example.com", ts.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keys := notify.SubscriptionKeys{
+		P256DH: base64.RawURLEncoding.EncodeToString(ua.PublicKey().Bytes()),
+		Auth:   base64.RawURLEncoding.EncodeToString(auth),
+	}
+
+	msg := &notify.Message{Title: "test", Body: "test body"}
+
+	if err := sd.Send(context.Background(), &notify.Recipient{
+		Subscriptions: []notify.Subscription{{
+			Endpoint: ts.URL + "/push",
+			Keys:     keys,
+		}},
+	}, msg); err != nil {
+		t.Fatal(err)
+	}
+
+	if got != *msg {
+		t.Errorf("Expected message: %v, got: %v", msg, got)
+	}
+
+	err = sd.Send(context.Background(), &notify.Recipient{
+		Subscriptions: []notify.Subscription{{
+			Endpoint: ts.URL + "/gone",
+			Keys:     keys,
+		}},
+	}, msg)
+	if !errors.Has(err, errors.ErrNotFound) {
+		t.Errorf("Expected not found error, got: %v", err)
+	}
+
+	err = sd.Send(context.Background(), &notify.Recipient{
+		Subscriptions: []notify.Subscription{{
+			Endpoint: ts.URL + "/limited",
+			Keys:     keys,
+		}},
+	}, msg)
+
+	var e *errors.Error
+
+	if !errors.As(err, &e) || !errors.Has(e, errors.ErrorRateLimit) ||
+		e.Rate == nil || e.Rate.Reset == 0 {
+		t.Errorf("Expected rate limit error with reset, got: %v", err)
+	}
+
+	if _, err := notify.NewPushSender("invalid", "mailto:test@example.com",
+		nil); err == nil {
+		t.Error("Expected invalid private key error")
+	}
+}
@@ -0,0 +1,184 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"github.com/dhaifley/game2d/errors"
+)
+
+// smtpSender values are used to deliver notifications by email.
+type smtpSender struct {
+	addr string
+	from string
+	auth smtp.Auth
+}
+
+// NewSMTPSender creates a new email notification sender, which delivers
+// messages through an SMTP server at the specified address. If a username is
+// provided, it is used to authenticate with the server.
+func NewSMTPSender(addr, username, password, from string) (Sender, error) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrConfiguration,
+			"invalid smtp address",
+			"addr", addr)
+	}
+
+	if _, err := mail.ParseAddress(from); err != nil {
+		return nil, errors.Wrap(err, errors.ErrConfiguration,
+			"invalid smtp from address",
+			"from", from)
+	}
+
+	s := &smtpSender{addr: addr, from: from}
+
+	if username != "" {
+		s.auth = smtp.PlainAuth("", username, password, host)
+	}
+
+	return s, nil
+}
+
+// message formats an email message.
+func (s *smtpSender) message(to string, msg *Message) []byte {
+	buf := &bytes.Buffer{}
+
+	buf.WriteString("From: " + s.from + "\r\n")
+	buf.WriteString("To: " + to + "\r\n")
+	buf.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", msg.Title) +
+		"\r\n")
+	buf.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	buf.WriteString("\r\n")
+
+	body := msg.Body
+
+	if msg.URL != "" {
+		body += "\n\n" + msg.URL
+	}
+
+	for _, l := range strings.Split(body, "\n") {
+		// Lines beginning with a period are escaped by the SMTP client.
+		buf.WriteString(strings.TrimRight(l, "\r") + "\r\n")
+	}
+
+	return buf.Bytes()
+}
+
+// Send delivers a notification by email.
+func (s *smtpSender) Send(ctx context.Context,
+	to *Recipient,
+	msg *Message,
+) error {
+	if to == nil || to.Email == "" || msg == nil {
+		return nil
+	}
+
+	addr, err := mail.ParseAddress(to.Email)
+	if err != nil {
+		return errors.Wrap(err, errors.ErrInvalidParameter,
+			"invalid notification email address",
+			"email", to.Email)
+	}
+
+	from, err := mail.ParseAddress(s.from)
+	if err != nil {
+		return errors.Wrap(err, errors.ErrConfiguration,
+			"invalid smtp from address",
+			"from", s.from)
+	}
+
+	d := &net.Dialer{}
+
+	conn, err := d.DialContext(ctx, "tcp", s.addr)
+	if err != nil {
+		return errors.Wrap(err, errors.ErrClient,
+			"unable to connect to smtp server",
+			"addr", s.addr)
+	}
+
+	if dl, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(dl); err != nil {
+			conn.Close()
+
+			return errors.Wrap(err, errors.ErrClient,
+				"unable to set smtp connection deadline",
+				"addr", s.addr)
+		}
+	}
+
+	host, _, _ := net.SplitHostPort(s.addr)
+
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+
+		return errors.Wrap(err, errors.ErrClient,
+			"unable to create smtp client",
+			"addr", s.addr)
+	}
+
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return errors.Wrap(err, errors.ErrClient,
+				"unable to start smtp tls",
+				"addr", s.addr)
+		}
+	}
+
+	if s.auth != nil {
+		if err := c.Auth(s.auth); err != nil {
+			return errors.Wrap(err, errors.ErrClient,
+				"unable to authenticate with smtp server",
+				"addr", s.addr)
+		}
+	}
+
+	if err := c.Mail(from.Address); err != nil {
+		return errors.Wrap(err, errors.ErrClient,
+			"unable to set smtp sender",
+			"from", s.from)
+	}
+
+	if err := c.Rcpt(addr.Address); err != nil {
+		return errors.Wrap(err, errors.ErrClient,
+			"unable to set smtp recipient",
+			"email", to.Email)
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return errors.Wrap(err, errors.ErrClient,
+			"unable to start smtp message")
+	}
+
+	if _, err := w.Write(s.message(addr.String(), msg)); err != nil {
+		return errors.Wrap(err, errors.ErrClient,
+			"unable to write smtp message")
+	}
+
+	if err := w.Close(); err != nil {
+		return errors.Wrap(err, errors.ErrClient,
+			"unable to send smtp message",
+			"email", to.Email)
+	}
+
+	if err := c.Quit(); err != nil {
+		return errors.Wrap(err, errors.ErrClient,
+			"unable to close smtp connection",
+			"addr", s.addr)
+	}
+
+	return nil
+}
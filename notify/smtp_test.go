@@ -0,0 +1,131 @@
+package notify_test
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/dhaifley/game2d/notify"
+)
+
+// mockSMTP starts a minimal SMTP server, which sends each received message to
+// the returned channel.
+func mockSMTP(t *testing.T) (string, <-chan string) {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { l.Close() })
+
+	ch := make(chan string, 1)
+
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+
+		defer conn.Close()
+
+		r := bufio.NewReader(conn)
+
+		reply := func(s string) {
+			_, _ = conn.Write([]byte(s + "\r\n"))
+		}
+
+		reply("220 localhost ESMTP")
+
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+
+			cmd := strings.ToUpper(strings.TrimSpace(line))
+
+			switch {
+			case strings.HasPrefix(cmd, "EHLO"):
+				reply("250 localhost")
+			case strings.HasPrefix(cmd, "DATA"):
+				reply("354 start mail input")
+
+				var sb strings.Builder
+
+				for {
+					l, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+
+					if l == ".\r\n" {
+						break
+					}
+
+					sb.WriteString(l)
+				}
+
+				ch <- sb.String()
+
+				reply("250 ok")
+			case strings.HasPrefix(cmd, "QUIT"):
+				reply("221 bye")
+
+				return
+			default:
+				reply("250 ok")
+			}
+		}
+	}()
+
+	return l.Addr().String(), ch
+}
+
+func TestSMTPSender(t *testing.T) {
+	t.Parallel()
+
+	addr, ch := mockSMTP(t)
+
+	sd, err := notify.NewSMTPSender(addr, "", "", "game2d@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := sd.Send(context.Background(), &notify.Recipient{
+		Email: "test@example.com",
+	}, &notify.Message{
+		Title: "test",
+		Body:  "test body",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	msg := <-ch
+
+	if !strings.Contains(msg, "To: <test@example.com>\r\n") {
+		t.Errorf("Expected message recipient, got: %v", msg)
+	}
+
+	if !strings.Contains(msg, "Subject: test\r\n") {
+		t.Errorf("Expected message subject, got: %v", msg)
+	}
+
+	if !strings.Contains(msg, "\r\n\r\ntest body\r\n") {
+		t.Errorf("Expected message body, got: %v", msg)
+	}
+
+	if err := sd.Send(context.Background(), &notify.Recipient{},
+		&notify.Message{Title: "test"}); err != nil {
+		t.Errorf("Expected recipient without email to be skipped, got: %v",
+			err)
+	}
+
+	if _, err := notify.NewSMTPSender("invalid", "", "",
+		"game2d@example.com"); err == nil {
+		t.Error("Expected invalid address error")
+	}
+}
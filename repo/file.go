@@ -0,0 +1,351 @@
+package repo
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/dhaifley/game2d/errors"
+	"github.com/dhaifley/game2d/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// fileClient values are used for interacting with repositories stored in a
+// local directory, such as a mounted volume. Since a directory has no commits,
+// the hashes of file contents are used in their place.
+type fileClient struct {
+	cfg    *Config
+	fs     fs.FS
+	metric metric.Recorder
+	tracer trace.Tracer
+}
+
+// newFileClient creates a new local directory repository client.
+func newFileClient(cfg *Config,
+	metric metric.Recorder,
+	tracer trace.Tracer,
+) (*fileClient, error) {
+	return &fileClient{
+		cfg:    cfg,
+		fs:     os.DirFS(cfg.Path),
+		metric: metric,
+		tracer: tracer,
+	}, nil
+}
+
+// fileMimetype returns the mime type of a repository file.
+func fileMimetype(name string) string {
+	switch filepath.Ext(name) {
+	case ".zip":
+		return "application/zip"
+	case ".yaml", ".yml":
+		return "application/yaml"
+	case ".json":
+		return "application/json"
+	case ".toml":
+		return "application/toml"
+	case ".xml":
+		return "application/xml"
+	case ".sh":
+		return "application/x-sh"
+	case ".exe":
+		return "application/ms-dos"
+	default:
+		return "text/plain"
+	}
+}
+
+// fsPath returns the path within the directory of a repository path.
+func fsPath(p string) string {
+	p = strings.Trim(path.Clean("/"+p), "/")
+	if p == "" {
+		return "."
+	}
+
+	return p
+}
+
+// item returns the repository item for a directory entry. Symbolic links are
+// followed, so that files in mounted volumes can be read.
+func (c *fileClient) item(p string) (*Item, error) {
+	fi, err := fs.Stat(c.fs, p)
+	if err != nil {
+		return nil, err
+	}
+
+	if fi.IsDir() {
+		return &Item{
+			Mimetype: "text/plain",
+			Path:     p,
+			Type:     "dir",
+		}, nil
+	}
+
+	buf, err := fs.ReadFile(c.fs, p)
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(buf)
+
+	hash := hex.EncodeToString(sum[:])
+
+	return &Item{
+		Mimetype: fileMimetype(p),
+		Path:     p,
+		Size:     len(buf),
+		Type:     "file",
+		Commit:   hash,
+		Hash:     hash,
+	}, nil
+}
+
+// dirError returns an error for a directory which can not be read.
+func dirError(err error, dirPath string) error {
+	if errors.Is(err, fs.ErrNotExist) {
+		return errors.Wrap(err, errors.ErrNotFound,
+			"repository directory not found",
+			"path", dirPath)
+	}
+
+	return errors.Wrap(err, errors.ErrClient,
+		"unable to list directory contents",
+		"path", dirPath)
+}
+
+// List retrieves a directory listing from the repository.
+func (c *fileClient) List(ctx context.Context,
+	dirPath string,
+) ([]Item, error) {
+	_, finish := startRepoSpan(ctx, c.metric, c.tracer, "file",
+		c.cfg, dirPath, "list")
+
+	des, err := fs.ReadDir(c.fs, fsPath(dirPath))
+	if err != nil {
+		err = dirError(err, dirPath)
+
+		finish(err)
+
+		return nil, err
+	}
+
+	res := make([]Item, 0, len(des))
+
+	for _, de := range des {
+		if strings.HasPrefix(de.Name(), ".") {
+			continue
+		}
+
+		i, err := c.item(path.Join(fsPath(dirPath), de.Name()))
+		if err != nil {
+			err = dirError(err, dirPath)
+
+			finish(err)
+
+			return nil, err
+		}
+
+		res = append(res, *i)
+	}
+
+	finish(nil)
+
+	return res, nil
+}
+
+// listAll retrieves a recursive listing of a directory.
+func (c *fileClient) listAll(ctx context.Context,
+	dirPath string,
+) ([]Item, error) {
+	res := []Item{}
+
+	err := fs.WalkDir(c.fs, fsPath(dirPath),
+		func(p string, de fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			if strings.HasPrefix(de.Name(), ".") && p != fsPath(dirPath) {
+				if de.IsDir() {
+					return fs.SkipDir
+				}
+
+				return nil
+			}
+
+			if de.IsDir() || de.Name() == "version" {
+				return nil
+			}
+
+			i, err := c.item(p)
+			if err != nil {
+				return err
+			}
+
+			if i.Type == "file" {
+				res = append(res, *i)
+			}
+
+			return nil
+		})
+	if err != nil {
+		return nil, dirError(err, dirPath)
+	}
+
+	return res, nil
+}
+
+// ListAll retrieves a tree listing, recursively, from the repository.
+func (c *fileClient) ListAll(ctx context.Context,
+	dirPath string,
+) ([]Item, error) {
+	_, finish := startRepoSpan(ctx, c.metric, c.tracer, "file",
+		c.cfg, dirPath, "listAll")
+
+	res, err := c.listAll(ctx, dirPath)
+	if err != nil {
+		finish(err)
+
+		return nil, err
+	}
+
+	finish(nil)
+
+	return res, nil
+}
+
+// Get retrieves file contents from the repository.
+func (c *fileClient) Get(ctx context.Context,
+	filePath string,
+) ([]byte, error) {
+	_, finish := startRepoSpan(ctx, c.metric, c.tracer, "file",
+		c.cfg, filePath, "get")
+
+	buf, err := fs.ReadFile(c.fs, fsPath(filePath))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			err = errors.Wrap(err, errors.ErrNotFound,
+				"repository file not found",
+				"path", filePath)
+		} else {
+			err = errors.Wrap(err, errors.ErrClient,
+				"unable to get repository file contents",
+				"path", filePath)
+		}
+
+		finish(err)
+
+		return nil, err
+	}
+
+	finish(nil)
+
+	return buf, nil
+}
+
+// Put writes file contents to the repository, creating any missing parent
+// directories.
+func (c *fileClient) Put(ctx context.Context,
+	filePath string,
+	data []byte,
+) error {
+	_, finish := startRepoSpan(ctx, c.metric, c.tracer, "file",
+		c.cfg, filePath, "put")
+
+	fp := filepath.Join(c.cfg.Path, filepath.FromSlash(fsPath(filePath)))
+
+	if err := os.MkdirAll(filepath.Dir(fp), 0o755); err != nil {
+		err = errors.Wrap(err, errors.ErrClient,
+			"unable to create repository directory",
+			"path", filePath)
+
+		finish(err)
+
+		return err
+	}
+
+	if err := os.WriteFile(fp, data, 0o644); err != nil {
+		err = errors.Wrap(err, errors.ErrClient,
+			"unable to put repository file contents",
+			"path", filePath)
+
+		finish(err)
+
+		return err
+	}
+
+	finish(nil)
+
+	return nil
+}
+
+// Delete removes a file from the repository.
+func (c *fileClient) Delete(ctx context.Context,
+	filePath string,
+) error {
+	_, finish := startRepoSpan(ctx, c.metric, c.tracer, "file",
+		c.cfg, filePath, "delete")
+
+	fp := filepath.Join(c.cfg.Path, filepath.FromSlash(fsPath(filePath)))
+
+	if err := os.Remove(fp); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		err = errors.Wrap(err, errors.ErrClient,
+			"unable to delete repository file",
+			"path", filePath)
+
+		finish(err)
+
+		return err
+	}
+
+	finish(nil)
+
+	return nil
+}
+
+// Commit retrieves a hash of the contents of all files in the repository,
+// other than backups, which changes whenever any file is added, removed, or
+// modified.
+func (c *fileClient) Commit(ctx context.Context) (string, error) {
+	_, finish := startRepoSpan(ctx, c.metric, c.tracer, "file",
+		c.cfg, "/", "commit")
+
+	items, err := c.listAll(ctx, "/")
+	if err != nil {
+		err = errors.Wrap(err, errors.ErrClient,
+			"unable to get repository commit hash",
+			"path", c.cfg.Path)
+
+		finish(err)
+
+		return "", err
+	}
+
+	slices.SortFunc(items, func(a, b Item) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+
+	h := sha256.New()
+
+	for _, i := range items {
+		if strings.HasPrefix(i.Path, BackupDir+"/") {
+			continue
+		}
+
+		h.Write([]byte(i.Path + "\x00" + i.Hash + "\n"))
+	}
+
+	finish(nil)
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,113 @@
+package repo_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dhaifley/game2d/repo"
+)
+
+func TestFileClient(t *testing.T) {
+	ctx := mockContext()
+
+	dir := t.TempDir()
+
+	if err := os.MkdirAll(filepath.Join(dir, "games"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, data := range map[string]string{
+		"games/test.yaml":   "name: test",
+		"games/.hidden":     "hidden",
+		"games/sub/a.json":  "{}",
+		"games/sub/version": "1",
+	} {
+		fp := filepath.Join(dir, name)
+
+		if err := os.MkdirAll(filepath.Dir(fp), 0o755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(fp, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := repo.NewClient("file://remote"+dir, nil, nil); err == nil {
+		t.Error("NewClient() with remote host expected error")
+	}
+
+	cli, err := repo.NewClient("file://"+dir, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := cli.ListAll(ctx, "games/")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(res) != 2 {
+		t.Fatalf("len(ListAll()) = %v, want %v", len(res), 2)
+	}
+
+	for _, i := range res {
+		if i.Type != "file" || i.Hash == "" || i.Commit != i.Hash {
+			t.Errorf("ListAll() item = %+v, want file with hash", i)
+		}
+	}
+
+	buf, err := cli.Get(ctx, "games/test.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(buf) != "name: test" {
+		t.Errorf("Get() = %v, want %v", string(buf), "name: test")
+	}
+
+	if _, err := cli.Get(ctx, "games/missing.yaml"); err == nil {
+		t.Error("Get() with missing file expected error")
+	}
+
+	c1, err := cli.Commit(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "games/test.yaml"),
+		[]byte("name: changed"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	c2, err := cli.Commit(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if c1 == c2 {
+		t.Errorf("Commit() unchanged after file modified: %v", c1)
+	}
+
+	w, ok := cli.(repo.Writer)
+	if !ok {
+		t.Fatal("file client is not a writer")
+	}
+
+	if err := w.Put(ctx, repo.BackupDir+"/a/1.json", []byte("{}")); err != nil {
+		t.Fatal(err)
+	}
+
+	if c3, err := cli.Commit(ctx); err != nil || c3 != c2 {
+		t.Errorf("Commit() changed after backup: %v, %v", c2, c3)
+	}
+
+	if err := w.Delete(ctx, repo.BackupDir+"/a/1.json"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := cli.Get(ctx, repo.BackupDir+"/a/1.json"); err == nil {
+		t.Error("Get() after Delete() expected error")
+	}
+}
@@ -1,11 +1,16 @@
 package repo
 
 import (
+	"bytes"
 	"context"
+	"fmt"
 	"io"
+	"net/http"
+	"net/url"
 	"path"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/dhaifley/game2d/errors"
 	"github.com/dhaifley/game2d/metric"
@@ -18,6 +23,7 @@ import (
 type gitHubClient struct {
 	cfg    *Config
 	cli    *github.Client
+	rl     *rateLimiter
 	metric metric.Recorder
 	tracer trace.Tracer
 }
@@ -37,11 +43,66 @@ func newGitHubClient(password string,
 	return &gitHubClient{
 		cfg:    cfg,
 		cli:    cli,
+		rl:     &rateLimiter{system: "github", metric: metric},
 		metric: metric,
 		tracer: tracer,
 	}, nil
 }
 
+// RateLimit returns the most recently reported GitHub API rate limit quota.
+func (c *gitHubClient) RateLimit() *RateLimit {
+	return c.rl.RateLimit()
+}
+
+// do makes a GitHub API request, pausing before the rate limit quota is
+// exhausted, and retrying requests which exceed the rate limit.
+func (c *gitHubClient) do(ctx context.Context,
+	req func() (*github.Response, error),
+) error {
+	for attempt := 0; ; attempt++ {
+		if err := c.rl.wait(ctx); err != nil {
+			return err
+		}
+
+		resp, err := req()
+		if resp != nil {
+			c.rl.update(ctx, resp.Rate.Limit, resp.Rate.Remaining,
+				resp.Rate.Reset.Time)
+		}
+
+		var (
+			d   time.Duration
+			rle *github.RateLimitError
+			are *github.AbuseRateLimitError
+		)
+
+		switch {
+		case errors.As(err, &rle):
+			c.rl.update(ctx, rle.Rate.Limit, rle.Rate.Remaining,
+				rle.Rate.Reset.Time)
+
+			d = time.Until(rle.Rate.Reset.Time)
+		case errors.As(err, &are):
+			d = are.GetRetryAfter()
+			if d <= 0 {
+				d = time.Duration(1<<attempt) * time.Second
+			}
+		default:
+			return err
+		}
+
+		if attempt >= maxRateLimitRetries {
+			return errors.Wrap(err, errors.ErrorRateLimit,
+				"repository rate limit exceeded",
+				"system", "github")
+		}
+
+		if err := c.rl.backoff(ctx, d, err); err != nil {
+			return err
+		}
+	}
+}
+
 // List retrieves a directory listing from the repository.
 func (c *gitHubClient) List(ctx context.Context,
 	dirPath string,
@@ -53,8 +114,17 @@ func (c *gitHubClient) List(ctx context.Context,
 		Ref: c.cfg.Ref,
 	}
 
-	fc, dc, _, err := c.cli.Repositories.GetContents(ctx, c.cfg.Owner,
-		c.cfg.Repo, path.Join(c.cfg.Path, dirPath), opt)
+	var (
+		fc *github.RepositoryContent
+		dc []*github.RepositoryContent
+	)
+
+	err := c.do(ctx, func() (resp *github.Response, err error) {
+		fc, dc, resp, err = c.cli.Repositories.GetContents(ctx, c.cfg.Owner,
+			c.cfg.Repo, path.Join(c.cfg.Path, dirPath), opt)
+
+		return resp, err
+	})
 	if err != nil {
 		if errors.ErrorHas(err, "404 Not Found") {
 			err = errors.Wrap(err, errors.ErrNotFound,
@@ -107,12 +177,21 @@ func (c *gitHubClient) List(ctx context.Context,
 			mt = "application/ms-dos"
 		}
 
+		// The SHA of file contents is the SHA of the file blob, which only
+		// changes when the file changes.
+		hash := ""
+
+		if rc.GetType() == "file" {
+			hash = rc.GetSHA()
+		}
+
 		res = append(res, Item{
 			Mimetype: mt,
 			Path:     rc.GetPath(),
 			Size:     rc.GetSize(),
 			Type:     rc.GetType(),
 			Commit:   rc.GetSHA(),
+			Hash:     hash,
 		})
 	}
 
@@ -128,8 +207,14 @@ func (c *gitHubClient) ListAll(ctx context.Context,
 	_, finish := startRepoSpan(ctx, c.metric, c.tracer, "github",
 		c.cfg, "/", "listAll")
 
-	t, _, err := c.cli.Git.GetTree(ctx, c.cfg.Owner,
-		c.cfg.Repo, "main", true)
+	var t *github.Tree
+
+	err := c.do(ctx, func() (resp *github.Response, err error) {
+		t, resp, err = c.cli.Git.GetTree(ctx, c.cfg.Owner,
+			c.cfg.Repo, "main", true)
+
+		return resp, err
+	})
 	if err != nil {
 		err = errors.Wrap(err, errors.ErrClient,
 			"unable to get repository tree")
@@ -182,12 +267,21 @@ TreeLoop:
 			ft = "dir"
 		}
 
+		// The SHA of blob entries is the SHA of the file blob, which only
+		// changes when the file changes.
+		hash := ""
+
+		if te.GetType() == "blob" {
+			hash = te.GetSHA()
+		}
+
 		res = append(res, Item{
 			Mimetype: mt,
 			Path:     te.GetPath(),
 			Size:     te.GetSize(),
 			Type:     ft,
 			Commit:   te.GetSHA(),
+			Hash:     hash,
 		})
 	}
 
@@ -207,8 +301,14 @@ func (c *gitHubClient) Get(ctx context.Context,
 		Ref: c.cfg.Ref,
 	}
 
-	r, _, err := c.cli.Repositories.DownloadContents(ctx,
-		c.cfg.Owner, c.cfg.Repo, filePath, opt)
+	var r io.ReadCloser
+
+	err := c.do(ctx, func() (resp *github.Response, err error) {
+		r, resp, err = c.cli.Repositories.DownloadContents(ctx,
+			c.cfg.Owner, c.cfg.Repo, filePath, opt)
+
+		return resp, err
+	})
 	if err != nil {
 		if errors.ErrorHas(err, "404 Not Found") {
 			err = errors.Wrap(err, errors.ErrNotFound,
@@ -249,13 +349,109 @@ func (c *gitHubClient) Get(ctx context.Context,
 	return nil, nil
 }
 
+// GetIfModified retrieves file contents from the repository, if they have
+// changed since they were retrieved with the entity tag. Requests for contents
+// which have not changed do not count against the rate limit quota.
+func (c *gitHubClient) GetIfModified(ctx context.Context,
+	filePath, tag string,
+) ([]byte, string, bool, error) {
+	_, finish := startRepoSpan(ctx, c.metric, c.tracer, "github",
+		c.cfg, filePath, "getIfModified")
+
+	u := fmt.Sprintf("repos/%s/%s/contents/%s", url.PathEscape(c.cfg.Owner),
+		url.PathEscape(c.cfg.Repo), escapePath(filePath))
+
+	if c.cfg.Ref != "" {
+		u += "?ref=" + url.QueryEscape(c.cfg.Ref)
+	}
+
+	req, err := c.cli.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		err = errors.Wrap(err, errors.ErrClient,
+			"unable to create repository file request",
+			"path", filePath)
+
+		finish(err)
+
+		return nil, "", false, err
+	}
+
+	req.Header.Set("Accept", "application/vnd.github.raw")
+
+	if tag != "" {
+		req.Header.Set("If-None-Match", tag)
+	}
+
+	var (
+		buf    bytes.Buffer
+		status int
+		etag   string
+	)
+
+	err = c.do(ctx, func() (*github.Response, error) {
+		buf.Reset()
+
+		resp, err := c.cli.Do(ctx, req, &buf)
+		if resp != nil {
+			status = resp.StatusCode
+			etag = resp.Header.Get("ETag")
+		}
+
+		if status == http.StatusNotModified {
+			return resp, nil
+		}
+
+		return resp, err
+	})
+	if err != nil {
+		if errors.ErrorHas(err, "404 Not Found") {
+			err = errors.Wrap(err, errors.ErrNotFound,
+				"repository file not found",
+				"path", filePath)
+		} else {
+			err = errors.Wrap(err, errors.ErrClient,
+				"unable to get repository file contents",
+				"path", filePath)
+		}
+
+		finish(err)
+
+		return nil, "", false, err
+	}
+
+	finish(nil)
+
+	if status == http.StatusNotModified {
+		return nil, tag, false, nil
+	}
+
+	return buf.Bytes(), etag, true, nil
+}
+
+// escapePath escapes each segment of a repository file path.
+func escapePath(filePath string) string {
+	seg := strings.Split(strings.TrimPrefix(filePath, "/"), "/")
+
+	for i, sp := range seg {
+		seg[i] = url.PathEscape(sp)
+	}
+
+	return strings.Join(seg, "/")
+}
+
 // Commit retrieves the main branch commit hash from the repository.
 func (c *gitHubClient) Commit(ctx context.Context) (string, error) {
 	_, finish := startRepoSpan(ctx, c.metric, c.tracer, "github",
 		c.cfg, "main", "commit")
 
-	r, _, err := c.cli.Repositories.GetBranch(ctx,
-		c.cfg.Owner, c.cfg.Repo, "main", true)
+	var r *github.Branch
+
+	err := c.do(ctx, func() (resp *github.Response, err error) {
+		r, resp, err = c.cli.Repositories.GetBranch(ctx,
+			c.cfg.Owner, c.cfg.Repo, "main", true)
+
+		return resp, err
+	})
 	if err != nil {
 		if errors.ErrorHas(err, "404 Not Found") {
 			err = errors.Wrap(err, errors.ErrNotFound,
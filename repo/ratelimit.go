@@ -0,0 +1,128 @@
+package repo
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/dhaifley/game2d/errors"
+	"github.com/dhaifley/game2d/metric"
+)
+
+const (
+	// rateLimitReserve is the number of remaining requests at which a client
+	// pauses until its rate limit quota is reset.
+	rateLimitReserve = 5
+
+	// maxRateLimitWait is the longest a client waits for its rate limit quota
+	// to be reset. Longer waits fail with a rate limit error instead.
+	maxRateLimitWait = 5 * time.Minute
+
+	// maxRateLimitRetries is the number of times a rate limited request is
+	// retried before failing.
+	maxRateLimitRetries = 3
+)
+
+// RateLimit values represent the request quota of a repository API, as most
+// recently reported by the repository.
+type RateLimit struct {
+	Limit     int       `json:"limit"`
+	Remaining int       `json:"remaining"`
+	Reset     time.Time `json:"reset"`
+}
+
+// RateLimiter values are clients of repositories which limit the rate at which
+// requests can be made.
+type RateLimiter interface {
+	RateLimit() *RateLimit
+}
+
+// rateLimiter values track the rate limit quota of a repository client.
+type rateLimiter struct {
+	sync.Mutex
+	rate   *RateLimit
+	system string
+	metric metric.Recorder
+}
+
+// RateLimit returns the most recently reported rate limit quota, or nil if no
+// quota has been reported.
+func (r *rateLimiter) RateLimit() *RateLimit {
+	r.Lock()
+	defer r.Unlock()
+
+	if r.rate == nil {
+		return nil
+	}
+
+	rl := *r.rate
+
+	return &rl
+}
+
+// update records a rate limit quota reported by the repository.
+func (r *rateLimiter) update(ctx context.Context,
+	limit, remaining int,
+	reset time.Time,
+) {
+	if limit <= 0 {
+		return
+	}
+
+	r.Lock()
+
+	r.rate = &RateLimit{Limit: limit, Remaining: remaining, Reset: reset}
+
+	r.Unlock()
+
+	if r.metric != nil {
+		r.metric.Set(ctx, "repo_rate_limit_remaining", int64(remaining),
+			"system:"+r.system)
+	}
+}
+
+// wait pauses until the rate limit quota is reset, if it is nearly exhausted.
+func (r *rateLimiter) wait(ctx context.Context) error {
+	rl := r.RateLimit()
+	if rl == nil || rl.Remaining > rateLimitReserve {
+		return nil
+	}
+
+	return r.backoff(ctx, time.Until(rl.Reset), nil)
+}
+
+// backoff pauses for a duration before a rate limited request is made. An
+// error is returned if the duration is too long, or the context is done.
+func (r *rateLimiter) backoff(ctx context.Context,
+	d time.Duration,
+	err error,
+) error {
+	if d <= 0 {
+		return nil
+	}
+
+	if d > maxRateLimitWait {
+		var limit, remaining int64
+
+		if rl := r.RateLimit(); rl != nil {
+			limit, remaining = int64(rl.Limit), int64(rl.Remaining)
+		}
+
+		return errors.Wrap(err, errors.ErrorRateLimit,
+			"repository rate limit exceeded",
+			"system", r.system,
+			"retry_after", d.Round(time.Second).String()).
+			WithRateLimit(limit, remaining, time.Now().Add(d))
+	}
+
+	t := time.NewTimer(d)
+
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return errors.Context(ctx)
+	case <-t.C:
+		return nil
+	}
+}
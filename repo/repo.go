@@ -24,7 +24,10 @@ type Client interface {
 	Commit(ctx context.Context) (string, error)
 }
 
-// Item values represent a single item in a repository.
+// Item values represent a single item in a repository. The hash identifies the
+// contents of the item, such as a blob SHA, and changes whenever the contents
+// change, but not when other files are committed. It is empty if the
+// repository does not provide one.
 type Item struct {
 	Path       string   `json:"path"`
 	Attributes []string `json:"attributes"`
@@ -32,6 +35,18 @@ type Item struct {
 	Size       int      `json:"size"`
 	Type       string   `json:"type"`
 	Commit     string   `json:"commit"`
+	Hash       string   `json:"hash,omitempty"`
+}
+
+// ConditionalGetter values are clients of repositories which can retrieve file
+// contents only if they have changed since they were last retrieved. The tag
+// identifies the contents last retrieved, such as an HTTP entity tag, and is
+// empty if the contents must be retrieved. If the contents have not changed,
+// no contents are returned, and modified is false.
+type ConditionalGetter interface {
+	GetIfModified(ctx context.Context,
+		filePath, tag string,
+	) (contents []byte, newTag string, modified bool, err error)
 }
 
 // Config values represent configuration indicating a specific git repository.
@@ -160,6 +175,46 @@ func NewClient(repoURL string,
 		cfg.Ref = u.Fragment
 
 		return newTestClient(username, password, cfg, metric, tracer)
+	case "s3":
+		if u.User == nil {
+			return nil, errors.New(errors.ErrClient,
+				"invalid repository URL: no user information")
+		}
+
+		secretKey, ok := u.User.Password()
+		if !ok {
+			return nil, errors.New(errors.ErrClient,
+				"invalid repository URL: no secret key")
+		}
+
+		if u.Host == "" {
+			return nil, errors.New(errors.ErrClient,
+				"invalid repository URL: missing bucket")
+		}
+
+		cfg := &Config{
+			URL:   u.Query().Get("endpoint"),
+			Owner: u.Host,
+			Path:  strings.Trim(u.Path, "/"),
+			Ref:   u.Fragment,
+		}
+
+		return newS3Client(u.User.Username(), secretKey,
+			u.Query().Get("region"), cfg.URL, cfg, metric, tracer)
+	case "file":
+		if u.Host != "" && u.Host != "localhost" {
+			return nil, errors.New(errors.ErrClient,
+				"invalid repository URL: remote file host",
+				"host", u.Host)
+		}
+
+		if u.Path == "" {
+			return nil, errors.New(errors.ErrClient,
+				"invalid repository URL: missing directory")
+		}
+
+		return newFileClient(&Config{URL: u.String(), Path: u.Path},
+			metric, tracer)
 	case "git", "ssh", "http", "https", "git+ssh", "git+http", "git+https":
 		gitLock.RLock()
 
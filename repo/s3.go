@@ -0,0 +1,523 @@
+package repo
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/dhaifley/game2d/errors"
+	"github.com/dhaifley/game2d/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// BackupDir is the repository directory to which backups are written. Its
+// contents are not included in the commit hashes of object storage buckets, so
+// that writing backups does not cause games to be imported again.
+const BackupDir = "backups"
+
+// Default S3 connection values.
+const (
+	defaultS3Region = "us-east-1"
+	s3Service       = "s3"
+	s3Algorithm     = "AWS4-HMAC-SHA256"
+	s3EmptyHash     = "e3b0c44298fc1c149afbf4c8996fb924" +
+		"27ae41e4649b934ca495991b7852b855"
+)
+
+// Writer values are clients of repositories which files can be written to,
+// and deleted from.
+type Writer interface {
+	Put(ctx context.Context, filePath string, data []byte) error
+	Delete(ctx context.Context, filePath string) error
+}
+
+// s3Client values are used for interacting with S3 compatible object storage
+// buckets. Object ETags are used as the hashes of files, and the commit hash
+// of a bucket is derived from the ETags of all of its objects.
+type s3Client struct {
+	cfg                  *Config
+	accessKey, secretKey string
+	region               string
+	endpoint             *url.URL
+	cli                  *http.Client
+	metric               metric.Recorder
+	tracer               trace.Tracer
+}
+
+// newS3Client creates a new S3 compatible object storage client.
+func newS3Client(accessKey, secretKey, region, endpoint string,
+	cfg *Config,
+	metric metric.Recorder,
+	tracer trace.Tracer,
+) (*s3Client, error) {
+	if region == "" {
+		region = defaultS3Region
+	}
+
+	if endpoint == "" {
+		endpoint = "https://s3." + region + ".amazonaws.com"
+	}
+
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Host == "" ||
+		(u.Scheme != "http" && u.Scheme != "https") {
+		return nil, errors.New(errors.ErrClient,
+			"invalid repository URL: invalid endpoint",
+			"endpoint", endpoint)
+	}
+
+	return &s3Client{
+		cfg:       cfg,
+		accessKey: accessKey,
+		secretKey: secretKey,
+		region:    region,
+		endpoint:  u,
+		cli:       &http.Client{Timeout: time.Minute},
+		metric:    metric,
+		tracer:    tracer,
+	}, nil
+}
+
+// s3Escape encodes a value as required by AWS signature version 4, escaping
+// every byte except unreserved characters, and slashes if keepSlash is true.
+func s3Escape(s string, keepSlash bool) string {
+	var sb strings.Builder
+
+	for _, b := range []byte(s) {
+		switch {
+		case 'A' <= b && b <= 'Z', 'a' <= b && b <= 'z',
+			'0' <= b && b <= '9', b == '-', b == '.', b == '_', b == '~',
+			b == '/' && keepSlash:
+			sb.WriteByte(b)
+		default:
+			fmt.Fprintf(&sb, "%%%02X", b)
+		}
+	}
+
+	return sb.String()
+}
+
+// hmacSHA256 returns the HMAC-SHA256 of data using a key.
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+
+	h.Write([]byte(data))
+
+	return h.Sum(nil)
+}
+
+// key returns the object key of a repository path.
+func (c *s3Client) key(p string) string {
+	return strings.TrimPrefix(path.Join(c.cfg.Path, p), "/")
+}
+
+// do makes a signed request to the bucket, returning the response body.
+func (c *s3Client) do(ctx context.Context,
+	method, key string,
+	query url.Values,
+	body []byte,
+) ([]byte, error) {
+	uriPath := "/" + c.cfg.Owner
+
+	if key != "" {
+		uriPath += "/" + key
+	}
+
+	canonicalURI := strings.TrimSuffix(c.endpoint.Path, "/") +
+		s3Escape(uriPath, true)
+
+	keys := make([]string, 0, len(query))
+
+	for k := range query {
+		keys = append(keys, k)
+	}
+
+	slices.Sort(keys)
+
+	qp := make([]string, 0, len(keys))
+
+	for _, k := range keys {
+		qp = append(qp,
+			s3Escape(k, false)+"="+s3Escape(query.Get(k), false))
+	}
+
+	canonicalQuery := strings.Join(qp, "&")
+
+	payloadHash := s3EmptyHash
+
+	if len(body) > 0 {
+		sum := sha256.Sum256(body)
+
+		payloadHash = hex.EncodeToString(sum[:])
+	}
+
+	now := time.Now().UTC()
+
+	amzDate := now.Format("20060102T150405Z")
+
+	scope := now.Format("20060102") + "/" + c.region + "/" + s3Service +
+		"/aws4_request"
+
+	signedHeaders := "host;x-amz-content-sha256;x-amz-date"
+
+	canonicalRequest := strings.Join([]string{
+		method,
+		canonicalURI,
+		canonicalQuery,
+		"host:" + c.endpoint.Host,
+		"x-amz-content-sha256:" + payloadHash,
+		"x-amz-date:" + amzDate,
+		"",
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	crh := sha256.Sum256([]byte(canonicalRequest))
+
+	stringToSign := s3Algorithm + "\n" + amzDate + "\n" + scope + "\n" +
+		hex.EncodeToString(crh[:])
+
+	sk := hmacSHA256([]byte("AWS4"+c.secretKey), now.Format("20060102"))
+	sk = hmacSHA256(sk, c.region)
+	sk = hmacSHA256(sk, s3Service)
+	sk = hmacSHA256(sk, "aws4_request")
+
+	signature := hex.EncodeToString(hmacSHA256(sk, stringToSign))
+
+	u := c.endpoint.Scheme + "://" + c.endpoint.Host + canonicalURI
+
+	if canonicalQuery != "" {
+		u += "?" + canonicalQuery
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u,
+		bytes.NewReader(body))
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrClient,
+			"unable to create object storage request",
+			"key", key)
+	}
+
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("Authorization", s3Algorithm+
+		" Credential="+c.accessKey+"/"+scope+
+		", SignedHeaders="+signedHeaders+
+		", Signature="+signature)
+
+	resp, err := c.cli.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrClient,
+			"unable to send object storage request",
+			"key", key)
+	}
+
+	defer resp.Body.Close()
+
+	buf, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrClient,
+			"unable to read object storage response",
+			"key", key)
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, errors.New(errors.ErrNotFound,
+			"object storage key not found",
+			"key", key)
+	case resp.StatusCode == http.StatusServiceUnavailable ||
+		resp.StatusCode == http.StatusTooManyRequests:
+		return nil, errors.New(errors.ErrorRateLimit,
+			"object storage rate limit exceeded",
+			"key", key)
+	case resp.StatusCode >= http.StatusBadRequest:
+		var e struct {
+			Code    string `xml:"Code"`
+			Message string `xml:"Message"`
+		}
+
+		_ = xml.Unmarshal(buf, &e)
+
+		return nil, errors.New(errors.ErrClient,
+			"object storage request failed",
+			"key", key,
+			"status", resp.StatusCode,
+			"code", e.Code,
+			"message", e.Message)
+	}
+
+	return buf, nil
+}
+
+// s3ListResult values represent a page of objects in a bucket.
+type s3ListResult struct {
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+	Contents              []struct {
+		Key  string `xml:"Key"`
+		ETag string `xml:"ETag"`
+		Size int    `xml:"Size"`
+	} `xml:"Contents"`
+	CommonPrefixes []struct {
+		Prefix string `xml:"Prefix"`
+	} `xml:"CommonPrefixes"`
+}
+
+// list retrieves the items in a directory of the bucket, recursively unless
+// a delimiter is used.
+func (c *s3Client) list(ctx context.Context,
+	dirPath string,
+	recursive bool,
+) ([]Item, error) {
+	prefix := c.key(dirPath)
+	if prefix != "" {
+		prefix += "/"
+	}
+
+	root := c.key("")
+	if root != "" {
+		root += "/"
+	}
+
+	res := []Item{}
+
+	q := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+
+	if !recursive {
+		q.Set("delimiter", "/")
+	}
+
+	for {
+		buf, err := c.do(ctx, http.MethodGet, "", q, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		lr := &s3ListResult{}
+
+		if err := xml.Unmarshal(buf, lr); err != nil {
+			return nil, errors.Wrap(err, errors.ErrClient,
+				"unable to decode object storage listing",
+				"path", dirPath)
+		}
+
+	ObjectLoop:
+		for _, o := range lr.Contents {
+			p := strings.TrimPrefix(o.Key, root)
+
+			if strings.HasSuffix(p, "/") || path.Base(p) == "version" {
+				continue
+			}
+
+			for sp := range strings.SplitSeq(p, "/") {
+				if strings.HasPrefix(sp, ".") {
+					continue ObjectLoop
+				}
+			}
+
+			etag := strings.Trim(o.ETag, `"`)
+
+			res = append(res, Item{
+				Mimetype: fileMimetype(p),
+				Path:     p,
+				Size:     o.Size,
+				Type:     "file",
+				Commit:   etag,
+				Hash:     etag,
+			})
+		}
+
+		for _, cp := range lr.CommonPrefixes {
+			p := strings.TrimSuffix(strings.TrimPrefix(cp.Prefix, root), "/")
+
+			if strings.HasPrefix(path.Base(p), ".") {
+				continue
+			}
+
+			res = append(res, Item{
+				Mimetype: "text/plain",
+				Path:     p,
+				Type:     "dir",
+			})
+		}
+
+		if !lr.IsTruncated || lr.NextContinuationToken == "" {
+			break
+		}
+
+		q.Set("continuation-token", lr.NextContinuationToken)
+	}
+
+	return res, nil
+}
+
+// List retrieves a directory listing from the repository.
+func (c *s3Client) List(ctx context.Context,
+	dirPath string,
+) ([]Item, error) {
+	_, finish := startRepoSpan(ctx, c.metric, c.tracer, "s3",
+		c.cfg, dirPath, "list")
+
+	res, err := c.list(ctx, dirPath, false)
+	if err != nil {
+		err = errors.Wrap(err, errors.ErrClient,
+			"unable to list directory contents",
+			"path", dirPath)
+
+		finish(err)
+
+		return nil, err
+	}
+
+	finish(nil)
+
+	return res, nil
+}
+
+// ListAll retrieves a tree listing, recursively, from the repository.
+func (c *s3Client) ListAll(ctx context.Context,
+	dirPath string,
+) ([]Item, error) {
+	_, finish := startRepoSpan(ctx, c.metric, c.tracer, "s3",
+		c.cfg, dirPath, "listAll")
+
+	res, err := c.list(ctx, dirPath, true)
+	if err != nil {
+		err = errors.Wrap(err, errors.ErrClient,
+			"unable to list directory contents",
+			"path", dirPath)
+
+		finish(err)
+
+		return nil, err
+	}
+
+	finish(nil)
+
+	return res, nil
+}
+
+// Get retrieves file contents from the repository.
+func (c *s3Client) Get(ctx context.Context,
+	filePath string,
+) ([]byte, error) {
+	_, finish := startRepoSpan(ctx, c.metric, c.tracer, "s3",
+		c.cfg, filePath, "get")
+
+	buf, err := c.do(ctx, http.MethodGet, c.key(filePath), nil, nil)
+	if err != nil {
+		if errors.Has(err, errors.ErrNotFound) {
+			err = errors.Wrap(err, errors.ErrNotFound,
+				"repository file not found",
+				"path", filePath)
+		} else {
+			err = errors.Wrap(err, errors.ErrClient,
+				"unable to get repository file contents",
+				"path", filePath)
+		}
+
+		finish(err)
+
+		return nil, err
+	}
+
+	finish(nil)
+
+	return buf, nil
+}
+
+// Put writes file contents to the repository.
+func (c *s3Client) Put(ctx context.Context,
+	filePath string,
+	data []byte,
+) error {
+	_, finish := startRepoSpan(ctx, c.metric, c.tracer, "s3",
+		c.cfg, filePath, "put")
+
+	if _, err := c.do(ctx, http.MethodPut, c.key(filePath), nil,
+		data); err != nil {
+		err = errors.Wrap(err, errors.ErrClient,
+			"unable to put repository file contents",
+			"path", filePath,
+			"size", len(data))
+
+		finish(err)
+
+		return err
+	}
+
+	finish(nil)
+
+	return nil
+}
+
+// Delete removes a file from the repository.
+func (c *s3Client) Delete(ctx context.Context,
+	filePath string,
+) error {
+	_, finish := startRepoSpan(ctx, c.metric, c.tracer, "s3",
+		c.cfg, filePath, "delete")
+
+	if _, err := c.do(ctx, http.MethodDelete, c.key(filePath), nil,
+		nil); err != nil && !errors.Has(err, errors.ErrNotFound) {
+		err = errors.Wrap(err, errors.ErrClient,
+			"unable to delete repository file",
+			"path", filePath)
+
+		finish(err)
+
+		return err
+	}
+
+	finish(nil)
+
+	return nil
+}
+
+// Commit retrieves a hash of the ETags of all objects in the repository, which
+// changes whenever any object, other than a backup, is added, removed, or
+// modified.
+func (c *s3Client) Commit(ctx context.Context) (string, error) {
+	_, finish := startRepoSpan(ctx, c.metric, c.tracer, "s3",
+		c.cfg, "/", "commit")
+
+	items, err := c.list(ctx, "", true)
+	if err != nil {
+		err = errors.Wrap(err, errors.ErrClient,
+			"unable to get repository commit hash")
+
+		finish(err)
+
+		return "", err
+	}
+
+	slices.SortFunc(items, func(a, b Item) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+
+	h := sha256.New()
+
+	for _, i := range items {
+		if strings.HasPrefix(i.Path, BackupDir+"/") {
+			continue
+		}
+
+		h.Write([]byte(i.Path + "\x00" + i.Hash + "\n"))
+	}
+
+	finish(nil)
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
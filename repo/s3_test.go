@@ -0,0 +1,180 @@
+package repo_test
+
+import (
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/dhaifley/game2d/repo"
+)
+
+// mockS3 returns a test server emulating an S3 compatible bucket.
+func mockS3(t *testing.T, objects map[string]string) *httptest.Server {
+	t.Helper()
+
+	var mu sync.Mutex
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter,
+		r *http.Request,
+	) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"),
+			"AWS4-HMAC-SHA256 Credential=key/") {
+			w.WriteHeader(http.StatusForbidden)
+
+			return
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		key := strings.TrimPrefix(r.URL.Path, "/bucket/")
+
+		switch {
+		case r.Method == http.MethodPut:
+			b, _ := io.ReadAll(r.Body)
+
+			objects[key] = string(b)
+		case r.Method == http.MethodDelete:
+			delete(objects, key)
+
+			w.WriteHeader(http.StatusNoContent)
+		case r.URL.Query().Get("list-type") == "2":
+			type content struct {
+				Key  string `xml:"Key"`
+				ETag string `xml:"ETag"`
+				Size int    `xml:"Size"`
+			}
+
+			res := struct {
+				XMLName  xml.Name  `xml:"ListBucketResult"`
+				Contents []content `xml:"Contents"`
+			}{}
+
+			keys := make([]string, 0, len(objects))
+
+			for k := range objects {
+				if strings.HasPrefix(k, r.URL.Query().Get("prefix")) {
+					keys = append(keys, k)
+				}
+			}
+
+			sort.Strings(keys)
+
+			for _, k := range keys {
+				res.Contents = append(res.Contents, content{
+					Key:  k,
+					ETag: `"` + objects[k] + `"`,
+					Size: len(objects[k]),
+				})
+			}
+
+			_ = xml.NewEncoder(w).Encode(res)
+		default:
+			v, ok := objects[key]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+
+				return
+			}
+
+			_, _ = w.Write([]byte(v))
+		}
+	}))
+}
+
+func TestS3Client(t *testing.T) {
+	ctx := mockContext()
+
+	svr := mockS3(t, map[string]string{
+		"prefix/games/test.yaml":    "name: test",
+		"prefix/games/.hidden.yaml": "hidden",
+		"other/games/other.yaml":    "other",
+	})
+
+	defer svr.Close()
+
+	if _, err := repo.NewClient("s3://bucket/prefix", nil, nil); err == nil {
+		t.Error("NewClient() without credentials expected error")
+	}
+
+	cli, err := repo.NewClient("s3://key:secret@bucket/prefix?endpoint="+
+		svr.URL, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := cli.ListAll(ctx, "games/")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(res) != 1 {
+		t.Fatalf("len(ListAll()) = %v, want %v", len(res), 1)
+	}
+
+	if res[0].Path != "games/test.yaml" || res[0].Hash != "name: test" {
+		t.Errorf("ListAll() item = %+v, want games/test.yaml", res[0])
+	}
+
+	buf, err := cli.Get(ctx, res[0].Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(buf) != "name: test" {
+		t.Errorf("Get() = %v, want %v", string(buf), "name: test")
+	}
+
+	if _, err := cli.Get(ctx, "games/missing.yaml"); err == nil {
+		t.Error("Get() with missing file expected error")
+	}
+
+	c1, err := cli.Commit(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w, ok := cli.(repo.Writer)
+	if !ok {
+		t.Fatal("S3 client is not a writer")
+	}
+
+	if err := w.Put(ctx, repo.BackupDir+"/test.json", []byte("{}")); err != nil {
+		t.Fatal(err)
+	}
+
+	c2, err := cli.Commit(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if c1 != c2 {
+		t.Errorf("Commit() changed after backup: %v, %v", c1, c2)
+	}
+
+	if err := w.Put(ctx, "games/new.yaml", []byte("name: new")); err != nil {
+		t.Fatal(err)
+	}
+
+	c3, err := cli.Commit(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if c1 == c3 {
+		t.Errorf("Commit() unchanged after file added: %v", c1)
+	}
+
+	if err := w.Delete(ctx, "games/new.yaml"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := cli.Get(ctx, "games/new.yaml"); err == nil {
+		t.Error("Get() after Delete() expected error")
+	}
+}
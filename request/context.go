@@ -170,8 +170,18 @@ func ContextUserID(ctx context.Context) (string, error) {
 	return id, nil
 }
 
+// ContextWithTimeout creates a context, derived from an existing context, which
+// is canceled after a timeout, or when the existing context is canceled. It is
+// used to bound the database and outbound calls made for an operation.
+func ContextWithTimeout(ctx context.Context,
+	d time.Duration,
+) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, d)
+}
+
 // ContextReplaceTimeout creates a copy of an existing context but with a new
-// timeout.
+// timeout. The copy is not canceled when the existing context is, so it is
+// used for operations which continue after the request which started them.
 func ContextReplaceTimeout(ctx context.Context,
 	d time.Duration,
 ) (context.Context, context.CancelFunc) {
@@ -4,6 +4,7 @@ import (
 	"context"
 	"net/url"
 	"testing"
+	"time"
 
 	"github.com/dhaifley/game2d/request"
 )
@@ -162,3 +163,39 @@ func TestContextUserID(t *testing.T) {
 		t.Errorf("Expected value: %v, got: %v", exp, val)
 	}
 }
+
+func TestContextTimeout(t *testing.T) {
+	t.Parallel()
+
+	parent, cancelParent := context.WithCancel(context.WithValue(
+		context.Background(), request.CtxKeyAccountID, "1"))
+
+	ctx, cancel := request.ContextWithTimeout(parent, time.Minute)
+
+	rCtx, rCancel := request.ContextReplaceTimeout(parent, time.Minute)
+
+	if _, ok := ctx.Deadline(); !ok {
+		t.Errorf("Expected context deadline")
+	}
+
+	cancelParent()
+
+	if ctx.Err() == nil {
+		t.Errorf("Expected context canceled with its parent")
+	}
+
+	if rCtx.Err() != nil {
+		t.Errorf("Unexpected replaced context error: %v", rCtx.Err())
+	}
+
+	if id, err := request.ContextAccountID(rCtx); err != nil || id != "1" {
+		t.Errorf("Expected account id: 1, got: %v, %v", id, err)
+	}
+
+	cancel()
+	rCancel()
+
+	if rCtx.Err() == nil {
+		t.Errorf("Expected replaced context canceled")
+	}
+}
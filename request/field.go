@@ -1,18 +1,32 @@
 package request
 
 import (
+	"bytes"
+	"encoding/binary"
 	"encoding/json"
 	"fmt"
-	"maps"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/dhaifley/game2d/cbor"
 	"github.com/dhaifley/game2d/errors"
 	"go.mongodb.org/mongo-driver/v2/bson"
 	"gopkg.in/yaml.v3"
 )
 
+// Field decoding limits, which protect against pathological inputs, such as
+// untrusted user requests and AI responses.
+const (
+	// MaxFieldSize is the maximum size, in bytes, of an encoded field value.
+	MaxFieldSize = 16 * 1024 * 1024
+
+	// MaxFieldDepth is the maximum nesting depth of the objects and arrays
+	// within a field value.
+	MaxFieldDepth = 64
+)
+
 // FieldString values represent strings tolerant of JSON inputs.
 type FieldString struct {
 	Set   bool
@@ -22,6 +36,10 @@ type FieldString struct {
 
 // UnmarshalJSON decodes a JSON format byte slice into this value.
 func (f *FieldString) UnmarshalJSON(b []byte) error {
+	if err := checkJSON(b); err != nil {
+		return err
+	}
+
 	f.Set = true
 	f.Valid = true
 	f.Value = ""
@@ -75,6 +93,10 @@ func (f *FieldString) UnmarshalBSON(b []byte) error {
 
 	var v *string
 
+	if err := checkBSON(bson.TypeString, b); err != nil {
+		return err
+	}
+
 	if err := bson.UnmarshalValue(bson.TypeString, b, &v); err != nil {
 		return err
 	}
@@ -127,6 +149,42 @@ func (f FieldString) MarshalYAML() (any, error) {
 	return f.Value, nil
 }
 
+// UnmarshalCBOR decodes a CBOR format byte slice into this value.
+func (f *FieldString) UnmarshalCBOR(b []byte) error {
+	if err := checkFieldSize(b); err != nil {
+		return err
+	}
+
+	f.Set = true
+	f.Valid = true
+	f.Value = ""
+
+	var v *string
+
+	if err := cbor.Unmarshal(b, &v); err != nil {
+		return err
+	}
+
+	if v == nil {
+		f.Valid = false
+
+		return nil
+	}
+
+	f.Value = *v
+
+	return nil
+}
+
+// MarshalCBOR encodes this value into a CBOR format byte slice.
+func (f FieldString) MarshalCBOR() ([]byte, error) {
+	if !f.Set || !f.Valid {
+		return cbor.Marshal(nil)
+	}
+
+	return cbor.Marshal(f.Value)
+}
+
 // Scan allows this value to be used in database/sql scan functions.
 func (f *FieldString) Scan(src any) error {
 	f.Set = true
@@ -162,6 +220,10 @@ type FieldInt64 struct {
 
 // UnmarshalJSON decodes a JSON format byte slice into this value.
 func (f *FieldInt64) UnmarshalJSON(b []byte) error {
+	if err := checkJSON(b); err != nil {
+		return err
+	}
+
 	f.Set = true
 	f.Valid = true
 	f.Value = 0
@@ -232,6 +294,10 @@ func (f *FieldInt64) UnmarshalBSON(b []byte) error {
 
 	var v *int64
 
+	if err := checkBSON(bson.TypeInt64, b); err != nil {
+		return err
+	}
+
 	if err := bson.UnmarshalValue(bson.TypeInt64, b, &v); err != nil {
 		return err
 	}
@@ -290,6 +356,42 @@ func (f FieldInt64) MarshalYAML() (any, error) {
 	return f.Value, nil
 }
 
+// UnmarshalCBOR decodes a CBOR format byte slice into this value.
+func (f *FieldInt64) UnmarshalCBOR(b []byte) error {
+	if err := checkFieldSize(b); err != nil {
+		return err
+	}
+
+	f.Set = true
+	f.Valid = true
+	f.Value = 0
+
+	var v *int64
+
+	if err := cbor.Unmarshal(b, &v); err != nil {
+		return err
+	}
+
+	if v == nil {
+		f.Valid = false
+
+		return nil
+	}
+
+	f.Value = *v
+
+	return nil
+}
+
+// MarshalCBOR encodes this value into a CBOR format byte slice.
+func (f FieldInt64) MarshalCBOR() ([]byte, error) {
+	if !f.Set || !f.Valid {
+		return cbor.Marshal(nil)
+	}
+
+	return cbor.Marshal(f.Value)
+}
+
 // Scan allows this value to be used in database/sql scan functions.
 func (f *FieldInt64) Scan(src any) error {
 	f.Set = true
@@ -323,6 +425,10 @@ type FieldFloat64 struct {
 
 // UnmarshalJSON decodes a JSON format byte slice into this value.
 func (f *FieldFloat64) UnmarshalJSON(b []byte) error {
+	if err := checkJSON(b); err != nil {
+		return err
+	}
+
 	f.Set = true
 	f.Valid = true
 	f.Value = 0.0
@@ -388,6 +494,10 @@ func (f *FieldFloat64) UnmarshalBSON(b []byte) error {
 
 	var v *float64
 
+	if err := checkBSON(bson.TypeDouble, b); err != nil {
+		return err
+	}
+
 	if err := bson.UnmarshalValue(bson.TypeDouble, b, &v); err != nil {
 		return err
 	}
@@ -440,6 +550,42 @@ func (f FieldFloat64) MarshalYAML() (any, error) {
 	return f.Value, nil
 }
 
+// UnmarshalCBOR decodes a CBOR format byte slice into this value.
+func (f *FieldFloat64) UnmarshalCBOR(b []byte) error {
+	if err := checkFieldSize(b); err != nil {
+		return err
+	}
+
+	f.Set = true
+	f.Valid = true
+	f.Value = 0
+
+	var v *float64
+
+	if err := cbor.Unmarshal(b, &v); err != nil {
+		return err
+	}
+
+	if v == nil {
+		f.Valid = false
+
+		return nil
+	}
+
+	f.Value = *v
+
+	return nil
+}
+
+// MarshalCBOR encodes this value into a CBOR format byte slice.
+func (f FieldFloat64) MarshalCBOR() ([]byte, error) {
+	if !f.Set || !f.Valid {
+		return cbor.Marshal(nil)
+	}
+
+	return cbor.Marshal(f.Value)
+}
+
 // Scan allows this value to be used in database/sql scan functions.
 func (f *FieldFloat64) Scan(src any) error {
 	f.Set = true
@@ -473,6 +619,10 @@ type FieldBool struct {
 
 // UnmarshalJSON decodes a JSON format byte slice into this value.
 func (f *FieldBool) UnmarshalJSON(b []byte) error {
+	if err := checkJSON(b); err != nil {
+		return err
+	}
+
 	f.Set = true
 	f.Valid = true
 	f.Value = false
@@ -542,6 +692,10 @@ func (f *FieldBool) UnmarshalBSON(b []byte) error {
 
 	var v *bool
 
+	if err := checkBSON(bson.TypeBoolean, b); err != nil {
+		return err
+	}
+
 	if err := bson.UnmarshalValue(bson.TypeBoolean, b, &v); err != nil {
 		return err
 	}
@@ -594,6 +748,42 @@ func (f FieldBool) MarshalYAML() (any, error) {
 	return f.Value, nil
 }
 
+// UnmarshalCBOR decodes a CBOR format byte slice into this value.
+func (f *FieldBool) UnmarshalCBOR(b []byte) error {
+	if err := checkFieldSize(b); err != nil {
+		return err
+	}
+
+	f.Set = true
+	f.Valid = true
+	f.Value = false
+
+	var v *bool
+
+	if err := cbor.Unmarshal(b, &v); err != nil {
+		return err
+	}
+
+	if v == nil {
+		f.Valid = false
+
+		return nil
+	}
+
+	f.Value = *v
+
+	return nil
+}
+
+// MarshalCBOR encodes this value into a CBOR format byte slice.
+func (f FieldBool) MarshalCBOR() ([]byte, error) {
+	if !f.Set || !f.Valid {
+		return cbor.Marshal(nil)
+	}
+
+	return cbor.Marshal(f.Value)
+}
+
 // Scan allows this value to be used in database/sql scan functions.
 func (f *FieldBool) Scan(src any) error {
 	f.Set = true
@@ -627,6 +817,10 @@ type FieldTime struct {
 
 // UnmarshalJSON decodes a JSON format byte slice into this value.
 func (f *FieldTime) UnmarshalJSON(b []byte) error {
+	if err := checkJSON(b); err != nil {
+		return err
+	}
+
 	f.Set = true
 	f.Valid = true
 	f.Value = 0
@@ -691,6 +885,10 @@ func (f *FieldTime) UnmarshalBSON(b []byte) error {
 
 	var v *int64
 
+	if err := checkBSON(bson.TypeInt64, b); err != nil {
+		return err
+	}
+
 	if err := bson.UnmarshalValue(bson.TypeInt64, b, &v); err != nil {
 		return err
 	}
@@ -743,6 +941,42 @@ func (f FieldTime) MarshalYAML() (any, error) {
 	return f.Value, nil
 }
 
+// UnmarshalCBOR decodes a CBOR format byte slice into this value.
+func (f *FieldTime) UnmarshalCBOR(b []byte) error {
+	if err := checkFieldSize(b); err != nil {
+		return err
+	}
+
+	f.Set = true
+	f.Valid = true
+	f.Value = 0
+
+	var v *int64
+
+	if err := cbor.Unmarshal(b, &v); err != nil {
+		return err
+	}
+
+	if v == nil {
+		f.Valid = false
+
+		return nil
+	}
+
+	f.Value = *v
+
+	return nil
+}
+
+// MarshalCBOR encodes this value into a CBOR format byte slice.
+func (f FieldTime) MarshalCBOR() ([]byte, error) {
+	if !f.Set || !f.Valid {
+		return cbor.Marshal(nil)
+	}
+
+	return cbor.Marshal(f.Value)
+}
+
 // Scan allows this value to be used in database/sql scan functions.
 func (f *FieldTime) Scan(src any) error {
 	f.Set = true
@@ -778,6 +1012,10 @@ type FieldStringArray struct {
 
 // UnmarshalJSON decodes a JSON format byte slice into this value.
 func (f *FieldStringArray) UnmarshalJSON(b []byte) error {
+	if err := checkJSON(b); err != nil {
+		return err
+	}
+
 	f.Set = true
 	f.Valid = true
 	f.Value = nil
@@ -833,6 +1071,10 @@ func (f *FieldStringArray) UnmarshalBSON(b []byte) error {
 
 	var v []any
 
+	if err := checkBSON(bson.TypeArray, b); err != nil {
+		return err
+	}
+
 	if err := bson.UnmarshalValue(bson.TypeArray, b, &v); err != nil {
 		return err
 	}
@@ -874,6 +1116,10 @@ func (f *FieldStringArray) MarshalBSON() ([]byte, error) {
 
 // UnmarshalYAML decodes a YAML format byte slice into this value.
 func (f *FieldStringArray) UnmarshalYAML(value *yaml.Node) error {
+	if err := checkYAMLDepth(value, 0); err != nil {
+		return err
+	}
+
 	f.Set = true
 	f.Valid = true
 
@@ -893,6 +1139,42 @@ func (f FieldStringArray) MarshalYAML() (any, error) {
 	return f.Value, nil
 }
 
+// UnmarshalCBOR decodes a CBOR format byte slice into this value.
+func (f *FieldStringArray) UnmarshalCBOR(b []byte) error {
+	if err := checkFieldSize(b); err != nil {
+		return err
+	}
+
+	f.Set = true
+	f.Valid = true
+	f.Value = nil
+
+	var v *[]string
+
+	if err := cbor.Unmarshal(b, &v); err != nil {
+		return err
+	}
+
+	if v == nil {
+		f.Valid = false
+
+		return nil
+	}
+
+	f.Value = *v
+
+	return nil
+}
+
+// MarshalCBOR encodes this value into a CBOR format byte slice.
+func (f FieldStringArray) MarshalCBOR() ([]byte, error) {
+	if !f.Set || !f.Valid {
+		return cbor.Marshal(nil)
+	}
+
+	return cbor.Marshal(f.Value)
+}
+
 // String returns the value as a string.
 func (f *FieldStringArray) String() string {
 	return strings.Join(f.Value, " ")
@@ -907,6 +1189,10 @@ type FieldInt64Array struct {
 
 // UnmarshalJSON decodes a JSON format byte slice into this value.
 func (f *FieldInt64Array) UnmarshalJSON(b []byte) error {
+	if err := checkJSON(b); err != nil {
+		return err
+	}
+
 	f.Set = true
 	f.Valid = true
 	f.Value = nil
@@ -965,6 +1251,10 @@ func (f *FieldInt64Array) UnmarshalBSON(b []byte) error {
 
 	var v []any
 
+	if err := checkBSON(bson.TypeArray, b); err != nil {
+		return err
+	}
+
 	if err := bson.UnmarshalValue(bson.TypeArray, b, &v); err != nil {
 		return err
 	}
@@ -1010,6 +1300,10 @@ func (f *FieldInt64Array) MarshalBSON() ([]byte, error) {
 
 // UnmarshalYAML decodes a YAML format byte slice into this value.
 func (f *FieldInt64Array) UnmarshalYAML(value *yaml.Node) error {
+	if err := checkYAMLDepth(value, 0); err != nil {
+		return err
+	}
+
 	f.Set = true
 	f.Valid = true
 
@@ -1029,6 +1323,42 @@ func (f FieldInt64Array) MarshalYAML() (any, error) {
 	return f.Value, nil
 }
 
+// UnmarshalCBOR decodes a CBOR format byte slice into this value.
+func (f *FieldInt64Array) UnmarshalCBOR(b []byte) error {
+	if err := checkFieldSize(b); err != nil {
+		return err
+	}
+
+	f.Set = true
+	f.Valid = true
+	f.Value = nil
+
+	var v *[]int64
+
+	if err := cbor.Unmarshal(b, &v); err != nil {
+		return err
+	}
+
+	if v == nil {
+		f.Valid = false
+
+		return nil
+	}
+
+	f.Value = *v
+
+	return nil
+}
+
+// MarshalCBOR encodes this value into a CBOR format byte slice.
+func (f FieldInt64Array) MarshalCBOR() ([]byte, error) {
+	if !f.Set || !f.Valid {
+		return cbor.Marshal(nil)
+	}
+
+	return cbor.Marshal(f.Value)
+}
+
 // String returns the value as a string.
 func (f *FieldInt64Array) String() string {
 	return fmt.Sprintf("%v", f.Value)
@@ -1043,6 +1373,10 @@ type FieldJSON struct {
 
 // UnmarshalJSON decodes a JSON format byte slice into this value.
 func (f *FieldJSON) UnmarshalJSON(b []byte) error {
+	if err := checkJSON(b); err != nil {
+		return err
+	}
+
 	f.Set = true
 
 	if err := json.Unmarshal(b, &f.Value); err != nil {
@@ -1074,14 +1408,18 @@ func (f *FieldJSON) UnmarshalBSON(b []byte) error {
 
 	var v map[string]any
 
-	if err := bson.Unmarshal(b, &v); err != nil {
-		if errors.ErrorHas(err, "invalid document length") {
-			if err := json.Unmarshal(b[5:], &v); err != nil {
-				return err
-			}
-		} else {
+	if err := checkBSON(bson.TypeEmbeddedDocument, b); err != nil {
+		if len(b) <= 5 || checkJSON(b[5:]) != nil {
 			return err
 		}
+
+		if err := json.Unmarshal(b[5:], &v); err != nil {
+			return errors.Wrap(err, errors.ErrInvalidRequest,
+				"unable to parse BSON into JSON")
+		}
+	} else if err := bson.Unmarshal(b, &v); err != nil {
+		return errors.Wrap(err, errors.ErrInvalidRequest,
+			"unable to parse BSON into JSON")
 	}
 
 	if v == nil {
@@ -1113,6 +1451,10 @@ func (f *FieldJSON) MarshalBSON() ([]byte, error) {
 
 // UnmarshalYAML decodes a YAML format byte slice into this value.
 func (f *FieldJSON) UnmarshalYAML(value *yaml.Node) error {
+	if err := checkYAMLDepth(value, 0); err != nil {
+		return err
+	}
+
 	f.Set = true
 	f.Valid = true
 
@@ -1132,6 +1474,39 @@ func (f FieldJSON) MarshalYAML() (any, error) {
 	return f.Value, nil
 }
 
+// UnmarshalCBOR decodes a CBOR format byte slice into this value.
+func (f *FieldJSON) UnmarshalCBOR(b []byte) error {
+	if err := checkFieldSize(b); err != nil {
+		return err
+	}
+
+	f.Set = true
+	f.Value = nil
+
+	if err := cbor.Unmarshal(b, &f.Value); err != nil {
+		return err
+	}
+
+	if err := checkValueDepth(f.Value, 0); err != nil {
+		f.Value = nil
+
+		return err
+	}
+
+	f.Valid = (f.Value != nil)
+
+	return nil
+}
+
+// MarshalCBOR encodes this value into a CBOR format byte slice.
+func (f FieldJSON) MarshalCBOR() ([]byte, error) {
+	if !f.Set || !f.Valid {
+		return cbor.Marshal(nil)
+	}
+
+	return cbor.Marshal(f.Value)
+}
+
 // Scan allows this value to be used in database/sql scan functions.
 func (f *FieldJSON) Scan(src any) error {
 	f.Set = true
@@ -1176,11 +1551,14 @@ func (f *FieldJSON) String() string {
 	return "{}"
 }
 
-// Copy creates a copy of this value.
+// Copy creates a deep copy of this value, so that no maps or slices nested
+// within the copy are shared with this value.
 func (f FieldJSON) Copy() FieldJSON {
-	m := make(map[string]any, len(f.Value))
+	var m map[string]any
 
-	maps.Copy(m, f.Value)
+	if f.Value != nil {
+		m = copyJSONMap(f.Value)
+	}
 
 	return FieldJSON{
 		Set:   f.Set,
@@ -1189,6 +1567,249 @@ func (f FieldJSON) Copy() FieldJSON {
 	}
 }
 
+// copyJSONMap creates a deep copy of a map decoded from JSON or BSON.
+func copyJSONMap(m map[string]any) map[string]any {
+	res := make(map[string]any, len(m))
+
+	for k, v := range m {
+		res[k] = copyJSONValue(v)
+	}
+
+	return res
+}
+
+// copyJSONValue creates a deep copy of a value decoded from JSON or BSON.
+func copyJSONValue(v any) any {
+	switch vv := v.(type) {
+	case map[string]any:
+		return copyJSONMap(vv)
+	case bson.M:
+		return bson.M(copyJSONMap(vv))
+	case bson.D:
+		res := make(bson.D, len(vv))
+
+		for i, e := range vv {
+			res[i] = bson.E{Key: e.Key, Value: copyJSONValue(e.Value)}
+		}
+
+		return res
+	case []any:
+		res := make([]any, len(vv))
+
+		for i, e := range vv {
+			res[i] = copyJSONValue(e)
+		}
+
+		return res
+	case bson.A:
+		res := make(bson.A, len(vv))
+
+		for i, e := range vv {
+			res[i] = copyJSONValue(e)
+		}
+
+		return res
+	case []byte:
+		return slices.Clone(vv)
+	case bson.Binary:
+		return bson.Binary{Subtype: vv.Subtype, Data: slices.Clone(vv.Data)}
+	default:
+		return v
+	}
+}
+
+// checkFieldSize returns an error if an encoded field value is larger than the
+// maximum field size.
+func checkFieldSize(b []byte) error {
+	if len(b) > MaxFieldSize {
+		return errors.New(errors.ErrInvalidRequest,
+			"field value exceeds maximum size",
+			"size", len(b),
+			"max_size", MaxFieldSize)
+	}
+
+	return nil
+}
+
+// checkBSON returns an error if a BSON format value is larger than the maximum
+// field size, or is not a valid value of a BSON type. It is checked before
+// decoding, since the BSON decoder trusts the lengths within values, and does
+// not limit the nesting of documents. The BSON validator can panic on some
+// invalid lengths, so any panic is also returned as an error.
+func checkBSON(t bson.Type, b []byte) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.New(errors.ErrInvalidRequest,
+				"invalid BSON field value",
+				"type", t.String(),
+				"panic", r)
+		}
+	}()
+
+	if err := checkFieldSize(b); err != nil {
+		return err
+	}
+
+	if t == bson.TypeEmbeddedDocument || t == bson.TypeArray {
+		return checkBSONDocument(b, 0)
+	}
+
+	if err := (bson.RawValue{Type: t, Value: b}).Validate(); err != nil {
+		return errors.Wrap(err, errors.ErrInvalidRequest,
+			"invalid BSON field value",
+			"type", t.String())
+	}
+
+	return nil
+}
+
+// checkBSONDocument returns an error if a BSON format document or array, or
+// any document or array nested within it, is invalid, or if they are nested
+// deeper than the maximum field depth.
+func checkBSONDocument(d bson.Raw, depth int) error {
+	if depth++; depth > MaxFieldDepth {
+		return errors.New(errors.ErrInvalidRequest,
+			"BSON field value exceeds maximum depth",
+			"max_depth", MaxFieldDepth)
+	}
+
+	if len(d) < 5 || int(binary.LittleEndian.Uint32(d)) != len(d) ||
+		d[len(d)-1] != 0 {
+		return errors.New(errors.ErrInvalidRequest,
+			"invalid BSON field value length",
+			"length", len(d))
+	}
+
+	elems, err := d.Elements()
+	if err != nil {
+		return errors.Wrap(err, errors.ErrInvalidRequest,
+			"invalid BSON field value")
+	}
+
+	for _, e := range elems {
+		v, err := e.ValueErr()
+		if err != nil {
+			return errors.Wrap(err, errors.ErrInvalidRequest,
+				"invalid BSON field value")
+		}
+
+		if v.Type == bson.TypeEmbeddedDocument || v.Type == bson.TypeArray {
+			if err := checkBSONDocument(v.Value, depth); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// checkJSON returns an error if a JSON format byte slice is larger than the
+// maximum field size, or nests objects and arrays deeper than the maximum
+// field depth. It is checked before decoding, so that pathological inputs are
+// rejected without being parsed.
+func checkJSON(b []byte) error {
+	if err := checkFieldSize(b); err != nil {
+		return err
+	}
+
+	depth := 0
+
+	for i := 0; i < len(b); i++ {
+		switch b[i] {
+		case '"':
+			// Strings are skipped to their closing quote, which is not
+			// preceded by an odd number of backslashes.
+			for {
+				n := bytes.IndexByte(b[i+1:], '"')
+				if n < 0 {
+					return nil
+				}
+
+				i += n + 1
+
+				bs := 0
+
+				for j := i - 1; b[j] == '\\'; j-- {
+					bs++
+				}
+
+				if bs%2 == 0 {
+					break
+				}
+			}
+		case '{', '[':
+			depth++
+
+			if depth > MaxFieldDepth {
+				return errors.New(errors.ErrInvalidRequest,
+					"JSON field value exceeds maximum depth",
+					"max_depth", MaxFieldDepth)
+			}
+		case '}', ']':
+			depth--
+		}
+	}
+
+	return nil
+}
+
+// checkValueDepth returns an error if a value decoded from CBOR nests objects
+// and arrays deeper than the maximum field depth.
+func checkValueDepth(v any, depth int) error {
+	var vals []any
+
+	switch vv := v.(type) {
+	case map[string]any:
+		for _, e := range vv {
+			vals = append(vals, e)
+		}
+	case []any:
+		vals = vv
+	default:
+		return nil
+	}
+
+	if depth++; depth > MaxFieldDepth {
+		return errors.New(errors.ErrInvalidRequest,
+			"field value exceeds maximum depth",
+			"max_depth", MaxFieldDepth)
+	}
+
+	for _, e := range vals {
+		if err := checkValueDepth(e, depth); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkYAMLDepth returns an error if a YAML node nests mappings and sequences
+// deeper than the maximum field depth. Aliases are not followed, since the
+// YAML decoder limits their expansion.
+func checkYAMLDepth(n *yaml.Node, depth int) error {
+	if n == nil {
+		return nil
+	}
+
+	if n.Kind == yaml.MappingNode || n.Kind == yaml.SequenceNode {
+		if depth++; depth > MaxFieldDepth {
+			return errors.New(errors.ErrInvalidRequest,
+				"YAML field value exceeds maximum depth",
+				"max_depth", MaxFieldDepth,
+				"line", n.Line)
+		}
+	}
+
+	for _, c := range n.Content {
+		if err := checkYAMLDepth(c, depth); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 // FieldDuration values represent integers tolerant of JSON inputs.
 type FieldDuration struct {
 	Set   bool
@@ -1198,6 +1819,10 @@ type FieldDuration struct {
 
 // UnmarshalJSON decodes a JSON format byte slice into this value.
 func (f *FieldDuration) UnmarshalJSON(b []byte) error {
+	if err := checkJSON(b); err != nil {
+		return err
+	}
+
 	f.Set = true
 	f.Valid = true
 	f.Value = 0
@@ -1264,6 +1889,10 @@ func (f *FieldDuration) UnmarshalBSON(b []byte) error {
 
 	var v *string
 
+	if err := checkBSON(bson.TypeString, b); err != nil {
+		return err
+	}
+
 	if err := bson.UnmarshalValue(bson.TypeString, b, &v); err != nil {
 		return err
 	}
@@ -1321,6 +1950,49 @@ func (f FieldDuration) MarshalYAML() (any, error) {
 	return f.Value, nil
 }
 
+// UnmarshalCBOR decodes a CBOR format byte slice into this value.
+func (f *FieldDuration) UnmarshalCBOR(b []byte) error {
+	if err := checkFieldSize(b); err != nil {
+		return err
+	}
+
+	f.Set = true
+	f.Valid = true
+	f.Value = 0
+
+	var v *string
+
+	if err := cbor.Unmarshal(b, &v); err != nil {
+		return err
+	}
+
+	if v == nil {
+		f.Valid = false
+
+		return nil
+	}
+
+	d, err := time.ParseDuration(*v)
+	if err != nil {
+		return errors.Wrap(err, errors.ErrInvalidRequest,
+			"unable to parse CBOR string into duration",
+			"string", *v)
+	}
+
+	f.Value = d
+
+	return nil
+}
+
+// MarshalCBOR encodes this value into a CBOR format byte slice.
+func (f FieldDuration) MarshalCBOR() ([]byte, error) {
+	if !f.Set || !f.Valid {
+		return cbor.Marshal(nil)
+	}
+
+	return cbor.Marshal(f.Value.String())
+}
+
 // Scan allows this value to be used in database/sql scan functions.
 func (f *FieldDuration) Scan(src any) error {
 	f.Set = true
@@ -2,9 +2,14 @@ package request_test
 
 import (
 	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/dhaifley/game2d/cbor"
+	"github.com/dhaifley/game2d/errors"
 	"github.com/dhaifley/game2d/request"
 	"go.mongodb.org/mongo-driver/v2/bson"
 	"gopkg.in/yaml.v3"
@@ -636,6 +641,66 @@ duration: 1s
 	}
 }
 
+func TestFieldCBOR(t *testing.T) {
+	t.Parallel()
+
+	type tests struct {
+		Value       request.FieldString      `json:"value"`
+		ZeroValue   request.FieldString      `json:"zero_value"`
+		Null        request.FieldString      `json:"null"`
+		NotSet      request.FieldString      `json:"not_set"`
+		Int64       request.FieldInt64       `json:"int64"`
+		Float64     request.FieldFloat64     `json:"float64"`
+		Bool        request.FieldBool        `json:"bool"`
+		Time        request.FieldTime        `json:"time"`
+		StringArray request.FieldStringArray `json:"string_array"`
+		Int64Array  request.FieldInt64Array  `json:"int64_array"`
+		JSON        request.FieldJSON        `json:"json"`
+		Duration    request.FieldDuration    `json:"duration"`
+	}
+
+	exp := tests{
+		Value:     request.FieldString{Set: true, Valid: true, Value: "test"},
+		ZeroValue: request.FieldString{Set: true, Valid: true},
+		Null:      request.FieldString{Set: true},
+		Int64:     request.FieldInt64{Set: true, Valid: true, Value: -1},
+		Float64:   request.FieldFloat64{Set: true, Valid: true, Value: 1.1},
+		Bool:      request.FieldBool{Set: true, Valid: true, Value: true},
+		Time:      request.FieldTime{Set: true, Valid: true, Value: 1},
+		StringArray: request.FieldStringArray{
+			Set: true, Valid: true, Value: []string{"test", "test2"},
+		},
+		Int64Array: request.FieldInt64Array{
+			Set: true, Valid: true, Value: []int64{1, 2, 3},
+		},
+		JSON: request.FieldJSON{
+			Set: true, Valid: true, Value: map[string]any{"test": "test"},
+		},
+		Duration: request.FieldDuration{
+			Set: true, Valid: true, Value: time.Second,
+		},
+	}
+
+	b, err := cbor.Marshal(exp)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var v tests
+
+	if err := cbor.Unmarshal(b, &v); err != nil {
+		t.Fatal(err)
+	}
+
+	// Fields which are not set are encoded as null, so they are decoded as
+	// set, but not valid.
+	exp.NotSet.Set = true
+
+	if !reflect.DeepEqual(v, exp) {
+		t.Errorf("Expected value: %+v, got: %+v", exp, v)
+	}
+}
+
 func TestSetField(t *testing.T) {
 	t.Parallel()
 
@@ -702,3 +767,272 @@ func TestSetField(t *testing.T) {
 		t.Errorf("Expected sets length: %v, got: %v", exp, len(*doc))
 	}
 }
+
+func BenchmarkSetField(b *testing.B) {
+	images := map[string]any{}
+
+	for i := range 16 {
+		id := "image" + strconv.Itoa(i)
+
+		images[id] = map[string]any{
+			"id":   id,
+			"data": strings.Repeat("A", 64*1024),
+		}
+	}
+
+	str := request.FieldString{Set: true, Valid: true, Value: "test"}
+	num := request.FieldInt64{Set: true, Valid: true, Value: 640}
+	tm := request.FieldTime{Set: true, Valid: true, Value: 1}
+	js := request.FieldJSON{Set: true, Valid: true, Value: images}
+
+	b.ReportAllocs()
+
+	for b.Loop() {
+		doc := &bson.D{}
+
+		request.SetField(doc, "id", str)
+		request.SetField(doc, "name", str)
+		request.SetField(doc, "description", str)
+		request.SetField(doc, "w", num)
+		request.SetField(doc, "h", num)
+		request.SetField(doc, "images", js)
+		request.SetField(doc, "updated_at", tm)
+		request.SetField(doc, "updated_by", str)
+
+		if _, err := bson.Marshal(doc); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestFieldJSONCopy(t *testing.T) {
+	t.Parallel()
+
+	f := request.FieldJSON{Set: true, Valid: true, Value: map[string]any{
+		"image": map[string]any{"id": "test", "data": "test"},
+		"list":  []any{map[string]any{"id": "test"}},
+		"doc":   bson.D{{Key: "id", Value: bson.D{{Key: "id", Value: "test"}}}},
+	}}
+
+	c := f.Copy()
+
+	if !reflect.DeepEqual(f, c) {
+		t.Fatalf("Expected copy: %v, got: %v", f, c)
+	}
+
+	c.Value["image"].(map[string]any)["data"] = "changed"
+	c.Value["list"].([]any)[0].(map[string]any)["id"] = "changed"
+	c.Value["doc"].(bson.D)[0].Value.(bson.D)[0].Value = "changed"
+
+	if v := f.Value["image"].(map[string]any)["data"]; v != "test" {
+		t.Errorf("Expected image data: test, got: %v", v)
+	}
+
+	if v := f.Value["list"].([]any)[0].(map[string]any)["id"]; v != "test" {
+		t.Errorf("Expected list id: test, got: %v", v)
+	}
+
+	if v := f.Value["doc"].(bson.D)[0].Value.(bson.D)[0].Value; v != "test" {
+		t.Errorf("Expected doc id: test, got: %v", v)
+	}
+}
+
+func TestFieldLimits(t *testing.T) {
+	t.Parallel()
+
+	deep := strings.Repeat(`{"a":`, request.MaxFieldDepth+1) + `1` +
+		strings.Repeat(`}`, request.MaxFieldDepth+1)
+
+	var v map[string]any
+
+	if err := json.Unmarshal([]byte(deep), &v); err != nil {
+		t.Fatal(err)
+	}
+
+	cb, err := cbor.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		fn   func() error
+	}{{
+		name: "JSON depth",
+		fn: func() error {
+			return (&request.FieldJSON{}).UnmarshalJSON([]byte(deep))
+		},
+	}, {
+		name: "JSON array depth",
+		fn: func() error {
+			return (&request.FieldStringArray{}).UnmarshalJSON(
+				[]byte(strings.Repeat("[", 100000)))
+		},
+	}, {
+		name: "JSON size",
+		fn: func() error {
+			return (&request.FieldString{}).UnmarshalJSON(
+				[]byte(`"` + strings.Repeat("a", request.MaxFieldSize) + `"`))
+		},
+	}, {
+		name: "YAML depth",
+		fn: func() error {
+			var f struct {
+				JSON request.FieldJSON `yaml:"json"`
+			}
+
+			return yaml.Unmarshal([]byte("json: "+deep), &f)
+		},
+	}, {
+		name: "CBOR depth",
+		fn: func() error {
+			return (&request.FieldJSON{}).UnmarshalCBOR(cb)
+		},
+	}, {
+		name: "BSON array",
+		fn: func() error {
+			return (&request.FieldInt64Array{}).UnmarshalBSON(
+				[]byte{0xff, 0xff, 0xff, 0x7f, 0x10, '0', 0})
+		},
+	}, {
+		name: "BSON string length",
+		fn: func() error {
+			return (&request.FieldString{}).UnmarshalBSON(
+				[]byte{0xff, 0xff, 0xff, 0x7f})
+		},
+	}, {
+		name: "BSON short",
+		fn: func() error {
+			return (&request.FieldJSON{}).UnmarshalBSON([]byte{0xff, 0})
+		},
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if err := tt.fn(); !errors.Has(err, errors.ErrInvalidRequest) {
+				t.Errorf("Expected invalid request error, got: %v", err)
+			}
+		})
+	}
+}
+
+// fuzzFields values contain each type of field, for fuzz tests.
+type fuzzFields struct {
+	String      request.FieldString      `bson:"string"       json:"string"       yaml:"string"`
+	Int64       request.FieldInt64       `bson:"int64"        json:"int64"        yaml:"int64"`
+	Float64     request.FieldFloat64     `bson:"float64"      json:"float64"      yaml:"float64"`
+	Bool        request.FieldBool        `bson:"bool"         json:"bool"         yaml:"bool"`
+	Time        request.FieldTime        `bson:"time"         json:"time"         yaml:"time"`
+	StringArray request.FieldStringArray `bson:"string_array" json:"string_array" yaml:"string_array"`
+	Int64Array  request.FieldInt64Array  `bson:"int64_array"  json:"int64_array"  yaml:"int64_array"`
+	JSON        request.FieldJSON        `bson:"json"         json:"json"         yaml:"json"`
+	Duration    request.FieldDuration    `bson:"duration"     json:"duration"     yaml:"duration"`
+}
+
+// fuzzSeeds are the seed inputs of the fuzz tests.
+var fuzzSeeds = []string{
+	`{"string":"test","int64":1,"float64":1.1,"bool":true,"time":1,` +
+		`"string_array":["a"],"int64_array":[1],"json":{"a":{"b":[1]}},` +
+		`"duration":"1s"}`,
+	`{"json":null,"string":null}`,
+	`{"json":` + strings.Repeat(`{"a":`, 100) + `1` +
+		strings.Repeat(`}`, 100) + `}`,
+	"string: test\njson:\n  a: &a [1]\n  b: *a\n",
+	"",
+}
+
+func TestFieldJSONStringDepth(t *testing.T) {
+	t.Parallel()
+
+	s := `\"` + strings.Repeat("[", request.MaxFieldDepth+1) + `\`
+
+	b, err := json.Marshal(map[string]any{"a": s})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var f request.FieldJSON
+
+	if err := f.UnmarshalJSON(b); err != nil {
+		t.Errorf("Unexpected error for brackets within strings: %v", err)
+	}
+
+	if f.Value["a"] != s {
+		t.Errorf("Unexpected value: %v", f.Value["a"])
+	}
+}
+
+func FuzzFieldJSON(f *testing.F) {
+	for _, s := range fuzzSeeds {
+		f.Add([]byte(s))
+	}
+
+	f.Fuzz(func(t *testing.T, b []byte) {
+		var v fuzzFields
+
+		if err := json.Unmarshal(b, &v); err != nil {
+			return
+		}
+
+		if _, err := json.Marshal(&v); err != nil {
+			t.Errorf("Unexpected marshal error: %v", err)
+		}
+	})
+}
+
+func FuzzFieldBSON(f *testing.F) {
+	for _, s := range fuzzSeeds {
+		f.Add([]byte(s))
+
+		m := map[string]any{}
+
+		if json.Unmarshal([]byte(s), &m) == nil {
+			if b, err := bson.Marshal(m); err == nil {
+				f.Add(b)
+			}
+		}
+	}
+
+	f.Fuzz(func(t *testing.T, b []byte) {
+		var v fuzzFields
+
+		for _, u := range []interface{ UnmarshalBSON([]byte) error }{
+			&v.String, &v.Int64, &v.Float64, &v.Bool, &v.Time,
+			&v.StringArray, &v.Int64Array, &v.JSON, &v.Duration,
+		} {
+			_ = u.UnmarshalBSON(b)
+		}
+	})
+}
+
+func FuzzFieldYAML(f *testing.F) {
+	for _, s := range fuzzSeeds {
+		f.Add([]byte(s))
+	}
+
+	f.Fuzz(func(t *testing.T, b []byte) {
+		var v fuzzFields
+
+		_ = yaml.Unmarshal(b, &v)
+	})
+}
+
+func FuzzFieldCBOR(f *testing.F) {
+	for _, s := range fuzzSeeds {
+		var v fuzzFields
+
+		if json.Unmarshal([]byte(s), &v) == nil {
+			if b, err := cbor.Marshal(v); err == nil {
+				f.Add(b)
+			}
+		}
+	}
+
+	f.Fuzz(func(t *testing.T, b []byte) {
+		var v fuzzFields
+
+		_ = cbor.Unmarshal(b, &v)
+	})
+}
@@ -1,7 +1,9 @@
 package request
 
 import (
+	"encoding/json"
 	"net/url"
+	"slices"
 	"strconv"
 	"strings"
 
@@ -69,3 +71,119 @@ func ParseQuery(values url.Values) (*Query, error) {
 
 	return req, nil
 }
+
+// maxSortFields is the maximum number of fields used to sort search results.
+const maxSortFields = 4
+
+// SortField values are the fields used to sort search results, in order.
+type SortField struct {
+	Name string
+	Desc bool
+}
+
+// SortFields parses the sort of the query, which is a comma separated list of
+// field names, each sorted in descending order if it has a minus (-) prefix.
+// A JSON object of field names with values of 1 or -1 is also accepted. Only
+// the allowed fields may be used, so that results are only sorted by indexed
+// fields.
+func (q *Query) SortFields(allowed ...string) ([]SortField, error) {
+	var (
+		res []SortField
+		err error
+	)
+
+	sort := strings.TrimSpace(q.Sort)
+
+	if strings.HasPrefix(sort, "{") {
+		res, err = parseJSONSort(sort)
+		if err != nil {
+			return nil, err
+		}
+	} else if sort != "" {
+		for f := range strings.SplitSeq(sort, ",") {
+			f = strings.TrimSpace(f)
+
+			sf := SortField{Name: strings.TrimLeft(f, "+-")}
+
+			if strings.HasPrefix(f, "-") {
+				sf.Desc = true
+			}
+
+			res = append(res, sf)
+		}
+	}
+
+	if len(res) > maxSortFields {
+		return nil, errors.New(errors.ErrInvalidRequest,
+			"too many sort fields",
+			"sort", q.Sort,
+			"max", maxSortFields)
+	}
+
+	seen := map[string]bool{}
+
+	for _, sf := range res {
+		if !slices.Contains(allowed, sf.Name) {
+			return nil, errors.New(errors.ErrInvalidRequest,
+				"invalid sort field",
+				"field", sf.Name,
+				"allowed", allowed)
+		}
+
+		if seen[sf.Name] {
+			return nil, errors.New(errors.ErrInvalidRequest,
+				"repeated sort field",
+				"field", sf.Name)
+		}
+
+		seen[sf.Name] = true
+	}
+
+	return res, nil
+}
+
+// parseJSONSort parses a sort in JSON object format, keeping the order of the
+// fields.
+func parseJSONSort(sort string) ([]SortField, error) {
+	errInvalid := errors.New(errors.ErrInvalidRequest,
+		"invalid sort",
+		"sort", sort)
+
+	dec := json.NewDecoder(strings.NewReader(sort))
+
+	if t, err := dec.Token(); err != nil || t != json.Delim('{') {
+		return nil, errInvalid
+	}
+
+	var res []SortField
+
+	for dec.More() {
+		t, err := dec.Token()
+		if err != nil {
+			return nil, errInvalid
+		}
+
+		name, ok := t.(string)
+		if !ok {
+			return nil, errInvalid
+		}
+
+		var dir int
+
+		if err := dec.Decode(&dir); err != nil || (dir != 1 && dir != -1) {
+			return nil, errInvalid
+		}
+
+		res = append(res, SortField{Name: name, Desc: dir < 0})
+	}
+
+	if t, err := dec.Token(); err != nil || t != json.Delim('}') {
+		return nil, errInvalid
+	}
+
+	if dec.More() {
+		return nil, errInvalid
+	}
+
+	return res, nil
+}
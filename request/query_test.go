@@ -2,6 +2,7 @@ package request_test
 
 import (
 	"net/url"
+	"reflect"
 	"testing"
 
 	"github.com/dhaifley/game2d/request"
@@ -47,3 +48,57 @@ func TestParseQuery(t *testing.T) {
 		t.Errorf("Expected sort: %v, got: %v", expS, req.Sort)
 	}
 }
+
+func TestSortFields(t *testing.T) {
+	t.Parallel()
+
+	allowed := []string{"name", "created_at", "updated_at"}
+
+	tests := []struct {
+		name string
+		sort string
+		exp  []request.SortField
+		err  bool
+	}{
+		{"empty", "", nil, false},
+		{"ascending", "name", []request.SortField{{Name: "name"}}, false},
+		{"compound", "-created_at, name", []request.SortField{
+			{Name: "created_at", Desc: true}, {Name: "name"},
+		}, false},
+		{"json", `{"updated_at": -1, "name": 1}`, []request.SortField{
+			{Name: "updated_at", Desc: true}, {Name: "name"},
+		}, false},
+		{"not allowed", "script", nil, true},
+		{"json not allowed", `{"script": 1}`, nil, true},
+		{"json invalid direction", `{"name": 2}`, nil, true},
+		{"json trailing data", `{"name": 1} x`, nil, true},
+		{"repeated", "name,-name", nil, true},
+		{"empty field", "name,", nil, true},
+		{"too many", "name,created_at,updated_at,name,name", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			q := &request.Query{Sort: tt.sort}
+
+			res, err := q.SortFields(allowed...)
+			if tt.err {
+				if err == nil {
+					t.Errorf("Expected error for sort: %v", tt.sort)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(res, tt.exp) {
+				t.Errorf("Expected sort fields: %v, got: %v", tt.exp, res)
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package request
 
 import (
+	"net/url"
 	"strings"
 
 	"github.com/google/uuid"
@@ -118,6 +119,22 @@ func ValidGameID(id string) bool {
 	return false
 }
 
+// ValidOrigin checks whether a string is a valid web origin, consisting of an
+// http or https scheme, a host, and an optional port.
+func ValidOrigin(origin string) bool {
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return false
+	}
+
+	return u.Host != "" && u.User == nil && u.Path == "" &&
+		u.RawQuery == "" && u.Fragment == "" && !u.ForceQuery
+}
+
 // ValidScope checks whether a string is a valid scope.
 func ValidScope(scope string) bool {
 	for _, s := range Scopes {
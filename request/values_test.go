@@ -47,6 +47,46 @@ func TestValidAccountID(t *testing.T) {
 	}
 }
 
+func TestValidOrigin(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		origin string
+		want   bool
+	}{{
+		name:   "valid",
+		origin: "https://example.com",
+		want:   true,
+	}, {
+		name:   "valid port",
+		origin: "http://localhost:8080",
+		want:   true,
+	}, {
+		name:   "path",
+		origin: "https://example.com/games",
+		want:   false,
+	}, {
+		name:   "scheme",
+		origin: "ftp://example.com",
+		want:   false,
+	}, {
+		name:   "host",
+		origin: "example.com",
+		want:   false,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := request.ValidOrigin(tt.origin); got != tt.want {
+				t.Errorf("ValidOrigin() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestValidAccountName(t *testing.T) {
 	t.Parallel()
 
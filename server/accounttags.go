@@ -0,0 +1,495 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/dhaifley/game2d/cache"
+	"github.com/dhaifley/game2d/errors"
+	"github.com/dhaifley/game2d/request"
+	"github.com/go-chi/chi/v5"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// Managed tag limits.
+const (
+	maxManagedTags   = 1000
+	maxManagedTagLen = 100
+)
+
+// tagNamespaceWildcard is the suffix of a managed tag which allows any tag in
+// its namespace. For example, genre:* allows genre:puzzle and genre:racing.
+const tagNamespaceWildcard = ":*"
+
+// ManagedTagRename values contain the new name of a managed tag.
+type ManagedTagRename struct {
+	Name request.FieldString `json:"name" yaml:"name"`
+}
+
+// validManagedTag checks whether a string is a valid managed tag.
+func validManagedTag(tag string) bool {
+	if tag == "" || len(tag) > maxManagedTagLen ||
+		strings.TrimSpace(tag) != tag || strings.Contains(tag, ",") {
+		return false
+	}
+
+	if ns, ok := strings.CutSuffix(tag, tagNamespaceWildcard); ok {
+		return ns != "" && !strings.Contains(ns, "*")
+	}
+
+	return !strings.Contains(tag, "*")
+}
+
+// tagManaged returns whether a tag is allowed by a managed tag vocabulary,
+// either directly or by a namespace wildcard.
+func tagManaged(vocab []string, tag string) bool {
+	for _, v := range vocab {
+		if v == tag {
+			return true
+		}
+
+		if ns, ok := strings.CutSuffix(v, tagNamespaceWildcard); ok &&
+			strings.HasPrefix(tag, ns+":") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// getManagedTags retrieves the managed tag vocabulary of the current account.
+func (s *Server) getManagedTags(ctx context.Context) ([]string, error) {
+	a, err := s.getAccount(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	if a == nil {
+		return nil, errors.New(errors.ErrNotFound,
+			"account not found")
+	}
+
+	res := slices.Clone(a.ManagedTags.Value)
+
+	if res == nil {
+		res = []string{}
+	}
+
+	slices.Sort(res)
+
+	return res, nil
+}
+
+// checkManagedTags returns an error if the current account enforces its
+// managed tag vocabulary, and any of the tags are not in it.
+func (s *Server) checkManagedTags(ctx context.Context, tags []string) error {
+	if len(tags) == 0 {
+		return nil
+	}
+
+	if aID, _ := request.ContextAccountID(ctx); aID == request.SystemAccount {
+		return nil
+	}
+
+	a, err := s.getAccount(ctx, "")
+	if err != nil {
+		return err
+	}
+
+	if a == nil || !a.EnforceTags.Value {
+		return nil
+	}
+
+	for _, t := range tags {
+		if !tagManaged(a.ManagedTags.Value, t) {
+			return errors.New(errors.ErrInvalidRequest,
+				"tag is not managed by the account",
+				"account_id", a.ID.Value,
+				"tag", t).
+				WithReason(errors.ReasonTagNotManaged)
+		}
+	}
+
+	return nil
+}
+
+// checkRequestTags checks the tags of a game request against the managed tag
+// vocabulary, if the request is allowed to set tags.
+func (s *Server) checkRequestTags(ctx context.Context, g *Game) error {
+	if ctx.Value(CtxKeyGameAllowTags) == nil || g == nil || !g.Tags.Set {
+		return nil
+	}
+
+	return s.checkManagedTags(ctx, g.Tags.Value)
+}
+
+// updateManagedTags applies an update to the managed tag vocabulary of the
+// current account and returns the resulting vocabulary.
+func (s *Server) updateManagedTags(ctx context.Context,
+	update bson.M,
+) ([]string, error) {
+	aID, err := request.ContextAccountID(ctx)
+	if err != nil {
+		return nil, errors.New(errors.ErrUnauthorized,
+			"unable to get account id from context")
+	}
+
+	update["$set"] = bson.M{"updated_at": time.Now().Unix()}
+
+	var res *Account
+
+	if err := s.DB().Collection("accounts").FindOneAndUpdate(ctx,
+		bson.M{"id": aID}, update,
+		options.FindOneAndUpdate().SetProjection(bson.M{"_id": 0}).
+			SetReturnDocument(options.After)).
+		Decode(&res); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, errors.New(errors.ErrNotFound,
+				"account not found",
+				"account_id", aID)
+		}
+
+		return nil, errors.Wrap(err, errors.ErrDatabase,
+			"unable to update managed tags",
+			"account_id", aID)
+	}
+
+	s.deleteCache(ctx, cache.KeyAccount(aID))
+
+	tags := slices.Clone(res.ManagedTags.Value)
+
+	if tags == nil {
+		tags = []string{}
+	}
+
+	slices.Sort(tags)
+
+	return tags, nil
+}
+
+// addManagedTags adds tags to the managed tag vocabulary of the current
+// account.
+func (s *Server) addManagedTags(ctx context.Context,
+	tags []string,
+) ([]string, error) {
+	for _, t := range tags {
+		if !validManagedTag(t) {
+			return nil, errors.New(errors.ErrInvalidRequest,
+				"invalid tag",
+				"tag", t)
+		}
+	}
+
+	cur, err := s.getManagedTags(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	n := len(cur)
+
+	for _, t := range tags {
+		if !slices.Contains(cur, t) {
+			n++
+		}
+	}
+
+	if n > maxManagedTags {
+		return nil, errors.New(errors.ErrInvalidRequest,
+			"too many managed tags",
+			"limit", maxManagedTags)
+	}
+
+	return s.updateManagedTags(ctx, bson.M{
+		"$addToSet": bson.M{"managed_tags": bson.M{"$each": tags}},
+	})
+}
+
+// renameGameTag renames a tag on all games of the current account which have
+// it, including previous revisions of games.
+func (s *Server) renameGameTag(ctx context.Context, from, to string) error {
+	aID, err := request.ContextAccountID(ctx)
+	if err != nil {
+		return errors.New(errors.ErrUnauthorized,
+			"unable to get account id from context")
+	}
+
+	c, err := s.collection(ctx, "games")
+	if err != nil {
+		return err
+	}
+
+	f := bson.M{"account_id": aID, "tags": from}
+
+	var ids []string
+
+	if err := c.Distinct(ctx, "id", f).
+		Decode(&ids); err != nil {
+		return errors.Wrap(err, errors.ErrDatabase,
+			"unable to find games with tag",
+			"tag", from)
+	}
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	if _, err := c.UpdateMany(ctx, f,
+		bson.M{"$addToSet": bson.M{"tags": to}}); err != nil {
+		return errors.Wrap(err, errors.ErrDatabase,
+			"unable to add renamed tag to games",
+			"from", from,
+			"to", to)
+	}
+
+	if _, err := c.UpdateMany(ctx, f,
+		bson.M{"$pull": bson.M{"tags": from}}); err != nil {
+		return errors.Wrap(err, errors.ErrDatabase,
+			"unable to remove renamed tag from games",
+			"from", from,
+			"to", to)
+	}
+
+	for _, id := range ids {
+		s.deleteCache(ctx, cache.KeyGame(id))
+	}
+
+	return nil
+}
+
+// renameManagedTag renames a tag in the managed tag vocabulary of the current
+// account, and on all games of the account. Renaming a namespace wildcard
+// renames the namespace of all tags in it.
+func (s *Server) renameManagedTag(ctx context.Context,
+	from, to string,
+) ([]string, error) {
+	if !validManagedTag(to) ||
+		strings.HasSuffix(from, tagNamespaceWildcard) !=
+			strings.HasSuffix(to, tagNamespaceWildcard) {
+		return nil, errors.New(errors.ErrInvalidRequest,
+			"invalid tag",
+			"tag", to)
+	}
+
+	cur, err := s.getManagedTags(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if !slices.Contains(cur, from) {
+		return nil, errors.New(errors.ErrNotFound,
+			"managed tag not found",
+			"tag", from)
+	}
+
+	if from == to {
+		return cur, nil
+	}
+
+	renames := map[string]string{from: to}
+
+	if ns, ok := strings.CutSuffix(from, tagNamespaceWildcard); ok {
+		nns := strings.TrimSuffix(to, tagNamespaceWildcard)
+
+		aID, err := request.ContextAccountID(ctx)
+		if err != nil {
+			return nil, errors.New(errors.ErrUnauthorized,
+				"unable to get account id from context")
+		}
+
+		c, err := s.collection(ctx, "games")
+		if err != nil {
+			return nil, err
+		}
+
+		var all []string
+
+		if err := c.Distinct(ctx, "tags", bson.M{
+			"account_id": aID,
+			"tags":       bson.M{"$regex": "^" + regexp.QuoteMeta(ns+":")},
+		}).Decode(&all); err != nil {
+			return nil, errors.Wrap(err, errors.ErrDatabase,
+				"unable to list game tags in namespace",
+				"namespace", ns)
+		}
+
+		renames = map[string]string{}
+
+		for _, t := range all {
+			if rest, ok := strings.CutPrefix(t, ns+":"); ok {
+				renames[t] = nns + ":" + rest
+			}
+		}
+	}
+
+	for f, t := range renames {
+		if err := s.renameGameTag(ctx, f, t); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := s.updateManagedTags(ctx, bson.M{
+		"$addToSet": bson.M{"managed_tags": to},
+	}); err != nil {
+		return nil, err
+	}
+
+	return s.updateManagedTags(ctx, bson.M{
+		"$pull": bson.M{"managed_tags": from},
+	})
+}
+
+// deleteManagedTag removes a tag from the managed tag vocabulary of the
+// current account. Games which have the tag are not changed.
+func (s *Server) deleteManagedTag(ctx context.Context, tag string) error {
+	cur, err := s.getManagedTags(ctx)
+	if err != nil {
+		return err
+	}
+
+	if !slices.Contains(cur, tag) {
+		return errors.New(errors.ErrNotFound,
+			"managed tag not found",
+			"tag", tag)
+	}
+
+	_, err = s.updateManagedTags(ctx, bson.M{
+		"$pull": bson.M{"managed_tags": tag},
+	})
+
+	return err
+}
+
+// managedTagParam returns the unescaped managed tag URL parameter.
+func managedTagParam(r *http.Request) string {
+	tag := chi.URLParam(r, "tag")
+
+	if v, err := url.PathUnescape(tag); err == nil {
+		tag = v
+	}
+
+	return tag
+}
+
+// getManagedTagsHandler is the get handler used to retrieve the managed tag
+// vocabulary of the current account.
+func (s *Server) getManagedTagsHandler(w http.ResponseWriter,
+	r *http.Request,
+) {
+	ctx := r.Context()
+
+	if err := s.checkScope(ctx, request.ScopeAccountRead); err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	res, err := s.getManagedTags(ctx)
+	if err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	if err := s.encode(w, r, res); err != nil {
+		s.error(err, w, r)
+	}
+}
+
+// postManagedTagsHandler is the post handler used to add tags to the managed
+// tag vocabulary of the current account.
+func (s *Server) postManagedTagsHandler(w http.ResponseWriter,
+	r *http.Request,
+) {
+	ctx := r.Context()
+
+	if err := s.checkScope(ctx, request.ScopeAccountAdmin); err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	tags := []string{}
+
+	if err := s.decode(r, &tags); err != nil {
+		s.error(errors.Wrap(err, errors.ErrInvalidRequest,
+			"unable to decode request"), w, r)
+
+		return
+	}
+
+	res, err := s.addManagedTags(ctx, tags)
+	if err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+
+	if err := s.encode(w, r, res); err != nil {
+		s.error(err, w, r)
+	}
+}
+
+// putManagedTagHandler is the put handler used to rename a managed tag, and
+// the tag on all games of the current account.
+func (s *Server) putManagedTagHandler(w http.ResponseWriter,
+	r *http.Request,
+) {
+	ctx := r.Context()
+
+	if err := s.checkScope(ctx, request.ScopeAccountAdmin); err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	req := &ManagedTagRename{}
+
+	if err := s.decode(r, &req); err != nil {
+		s.error(errors.Wrap(err, errors.ErrInvalidRequest,
+			"unable to decode request"), w, r)
+
+		return
+	}
+
+	res, err := s.renameManagedTag(ctx, managedTagParam(r), req.Name.Value)
+	if err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	if err := s.encode(w, r, res); err != nil {
+		s.error(err, w, r)
+	}
+}
+
+// deleteManagedTagHandler is the delete handler used to remove a tag from the
+// managed tag vocabulary of the current account.
+func (s *Server) deleteManagedTagHandler(w http.ResponseWriter,
+	r *http.Request,
+) {
+	ctx := r.Context()
+
+	if err := s.checkScope(ctx, request.ScopeAccountAdmin); err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	if err := s.deleteManagedTag(ctx, managedTagParam(r)); err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
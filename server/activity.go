@@ -0,0 +1,214 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dhaifley/game2d/errors"
+	"github.com/dhaifley/game2d/logger"
+	"github.com/dhaifley/game2d/request"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// Activity event types.
+const (
+	ActivityGameCreated = "game_created"
+	ActivityGameUpdated = "game_updated"
+	ActivityGameDeleted = "game_deleted"
+	ActivityGameStatus  = "game_status"
+	ActivityPrompt      = "prompt"
+	ActivityImport      = "import"
+	ActivityLogin       = "login"
+)
+
+const (
+	// activityRetention is how long activity events are kept in the audit log.
+	activityRetention = time.Hour * 24 * 90
+
+	// defaultActivitySize is the default number of activity events returned
+	// in a page.
+	defaultActivitySize = 50
+
+	// maxActivitySize is the maximum number of activity events returned in a
+	// page.
+	maxActivitySize = 500
+)
+
+// Activity values represent a single event in the account audit log.
+type Activity struct {
+	Seq       bson.ObjectID  `bson:"_id,omitempty"     json:"-"                 yaml:"-"`
+	ID        string         `bson:"id"                json:"id"                yaml:"id"`
+	AccountID string         `bson:"account_id"        json:"account_id"        yaml:"account_id"`
+	UserID    string         `bson:"user_id"           json:"user_id"           yaml:"user_id"`
+	Type      string         `bson:"type"              json:"type"              yaml:"type"`
+	GameID    string         `bson:"game_id,omitempty" json:"game_id,omitempty" yaml:"game_id,omitempty"`
+	Data      map[string]any `bson:"data,omitempty"    json:"data,omitempty"    yaml:"data,omitempty"`
+	CreatedAt int64          `bson:"created_at"        json:"created_at"        yaml:"created_at"`
+	ExpiresAt time.Time      `bson:"expires_at"        json:"-"                 yaml:"-"`
+}
+
+// ActivityPage values represent a page of account activity, from the newest
+// to the oldest event. The cursor is used to retrieve the next page, and is
+// empty if there are no more events.
+type ActivityPage struct {
+	Activity []*Activity `json:"activity"         yaml:"activity"`
+	Cursor   string      `json:"cursor,omitempty" yaml:"cursor,omitempty"`
+}
+
+// recordActivity adds an event to the audit log of the current account.
+// Failures are logged, but otherwise ignored, so that recording activity never
+// causes the operation being recorded to fail.
+func (s *Server) recordActivity(ctx context.Context,
+	typ, gameID string,
+	data map[string]any,
+) {
+	aID, err := request.ContextAccountID(ctx)
+	if err != nil || s.DB() == nil {
+		return
+	}
+
+	uID, _ := request.ContextUserID(ctx)
+
+	now := time.Now()
+
+	a := &Activity{
+		ID:        uuid.NewString(),
+		AccountID: aID,
+		UserID:    uID,
+		Type:      typ,
+		GameID:    gameID,
+		Data:      data,
+		CreatedAt: now.Unix(),
+		ExpiresAt: now.Add(activityRetention),
+	}
+
+	// Activity is recorded even if the request is canceled.
+	ctx, cancel := s.detachedContext(ctx, opDB)
+	defer cancel()
+
+	c, err := s.collection(ctx, "activity")
+	if err == nil {
+		_, err = c.InsertOne(ctx, a)
+	}
+
+	if err != nil {
+		s.log.Log(ctx, logger.LvlWarn,
+			"unable to record activity",
+			"error", err,
+			"type", typ,
+			"game_id", gameID)
+	}
+}
+
+// getActivity retrieves a page of the activity of the current account, which
+// starts after the cursor, if one is specified.
+func (s *Server) getActivity(ctx context.Context,
+	cursor string,
+	size int64,
+	types []string,
+) (*ActivityPage, error) {
+	aID, err := request.ContextAccountID(ctx)
+	if err != nil {
+		return nil, errors.New(errors.ErrUnauthorized,
+			"unable to get account id from context")
+	}
+
+	if size <= 0 {
+		size = defaultActivitySize
+	}
+
+	size = min(size, maxActivitySize)
+
+	f := bson.M{"account_id": aID}
+
+	if cursor != "" {
+		oID, err := bson.ObjectIDFromHex(cursor)
+		if err != nil {
+			return nil, errors.Wrap(err, errors.ErrInvalidParameter,
+				"invalid activity cursor",
+				"cursor", cursor)
+		}
+
+		f["_id"] = bson.M{"$lt": oID}
+	}
+
+	if len(types) > 0 {
+		f["type"] = bson.M{"$in": types}
+	}
+
+	c, err := s.collection(ctx, "activity")
+	if err != nil {
+		return nil, err
+	}
+
+	cur, err := c.Find(ctx, f,
+		options.Find().SetSort(bson.D{{Key: "_id", Value: -1}}).
+			SetLimit(size))
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrDatabase,
+			"unable to find activity")
+	}
+
+	res := &ActivityPage{Activity: []*Activity{}}
+
+	if err := cur.All(ctx, &res.Activity); err != nil {
+		return nil, errors.Wrap(err, errors.ErrDatabase,
+			"unable to decode activity")
+	}
+
+	if n := len(res.Activity); int64(n) == size {
+		res.Cursor = res.Activity[n-1].Seq.Hex()
+	}
+
+	return res, nil
+}
+
+// getActivityHandler is the get handler used to retrieve account activity.
+func (s *Server) getActivityHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if err := s.checkScope(ctx, request.ScopeAccountRead); err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	q := r.URL.Query()
+
+	var size int64
+
+	if v := q.Get("size"); v != "" {
+		i, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || i < 0 {
+			s.error(errors.New(errors.ErrInvalidParameter,
+				"invalid activity size",
+				"size", v), w, r)
+
+			return
+		}
+
+		size = i
+	}
+
+	var types []string
+
+	if v := q.Get("type"); v != "" {
+		types = strings.Split(v, ",")
+	}
+
+	res, err := s.getActivity(ctx, q.Get("cursor"), size, types)
+	if err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	if err := s.encode(w, r, res); err != nil {
+		s.error(err, w, r)
+	}
+}
@@ -0,0 +1,62 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/dhaifley/game2d/config"
+	"github.com/dhaifley/game2d/errors"
+	"github.com/dhaifley/game2d/request"
+	"github.com/go-chi/chi/v5"
+)
+
+// ConfigReport values describe the effective configuration of the server.
+// Secret settings are redacted.
+type ConfigReport struct {
+	Valid    bool             `json:"valid"            yaml:"valid"`
+	Errors   []*errors.Error  `json:"errors,omitempty" yaml:"errors,omitempty"`
+	Settings []config.Setting `json:"settings"         yaml:"settings"`
+}
+
+// adminHandler performs routing for administration requests.
+func (s *Server) adminHandler() http.Handler {
+	r := chi.NewRouter()
+
+	r.With(s.stat, s.trace, s.auth).Get("/config", s.getConfigHandler)
+	r.With(s.dbAvail, s.stat, s.trace, s.auth).Post("/chains/repair",
+		s.postChainsRepairHandler)
+	r.With(s.dbAvail, s.stat, s.trace).Post("/bootstrap",
+		s.postBootstrapHandler)
+
+	return r
+}
+
+// getConfigHandler is the get handler used to retrieve the effective, redacted
+// configuration of the server.
+func (s *Server) getConfigHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if err := s.checkScope(ctx, request.ScopeSuperuser); err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	res := &ConfigReport{
+		Valid:    true,
+		Settings: s.cfg.Settings(),
+	}
+
+	if err := s.cfg.Validate(); err != nil {
+		res.Valid = false
+
+		var e *errors.Error
+
+		if errors.As(err, &e) {
+			res.Errors = e.Errors
+		}
+	}
+
+	if err := s.encode(w, r, res); err != nil {
+		s.error(err, w, r)
+	}
+}
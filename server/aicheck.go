@@ -0,0 +1,167 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/anthropics/anthropic-sdk-go/option"
+	"github.com/dhaifley/game2d/errors"
+	"github.com/dhaifley/game2d/request"
+)
+
+// aiValidateTimeout is the maximum time allowed for validating an AI API key.
+const aiValidateTimeout = time.Second * 30
+
+// AIModel values describe the AI models available to an AI API key.
+type AIModel struct {
+	ID        string `json:"id"                   yaml:"id"`
+	Name      string `json:"name,omitempty"       yaml:"name,omitempty"`
+	CreatedAt int64  `json:"created_at,omitempty" yaml:"created_at,omitempty"`
+}
+
+// AILimits values describe the limits applied to prompts sent using an AI API
+// key. Rate limits are those reported by the AI provider, and are zero if it
+// did not report them.
+type AILimits struct {
+	MaxTokens         int64 `json:"max_tokens"                   yaml:"max_tokens"`
+	ThinkingBudget    int64 `json:"thinking_budget"              yaml:"thinking_budget"`
+	RequestsLimit     int64 `json:"requests_limit,omitempty"     yaml:"requests_limit,omitempty"`
+	RequestsRemaining int64 `json:"requests_remaining,omitempty" yaml:"requests_remaining,omitempty"`
+	TokensLimit       int64 `json:"tokens_limit,omitempty"       yaml:"tokens_limit,omitempty"`
+	TokensRemaining   int64 `json:"tokens_remaining,omitempty"   yaml:"tokens_remaining,omitempty"`
+}
+
+// AIValidation values are the results of validating the AI API key of an
+// account. The model is the model used for prompts.
+type AIValidation struct {
+	Valid    bool       `json:"valid"              yaml:"valid"`
+	Provider string     `json:"provider,omitempty" yaml:"provider,omitempty"`
+	Model    string     `json:"model,omitempty"    yaml:"model,omitempty"`
+	Models   []*AIModel `json:"models"             yaml:"models"`
+	Limits   *AILimits  `json:"limits,omitempty"   yaml:"limits,omitempty"`
+	Checks   []*Check   `json:"checks"             yaml:"checks"`
+}
+
+// AIValidator values are prompters which are able to check that their AI API
+// key works, by listing the models available to it.
+type AIValidator interface {
+	Validate(ctx context.Context, res *AIValidation) error
+}
+
+// Validate lists the models available to the Anthropic API key, and the rate
+// limits reported with the list.
+func (p *anthropicPrompter) Validate(ctx context.Context,
+	res *AIValidation,
+) error {
+	res.Provider = "anthropic"
+	res.Model = string(anthropic.ModelClaude3_7SonnetLatest)
+	res.Limits = &AILimits{MaxTokens: p.max, ThinkingBudget: p.budget}
+
+	var raw *http.Response
+
+	page, err := p.cli.Models.List(ctx, anthropic.ModelListParams{},
+		option.WithResponseInto(&raw))
+	if err != nil {
+		return errors.Wrap(err, errors.ErrClient,
+			"unable to list AI models")
+	}
+
+	for _, m := range page.Data {
+		res.Models = append(res.Models, &AIModel{
+			ID:        m.ID,
+			Name:      m.DisplayName,
+			CreatedAt: m.CreatedAt.Unix(),
+		})
+	}
+
+	if raw != nil {
+		header := func(name string) int64 {
+			n, _ := strconv.ParseInt(raw.Header.Get(name), 10, 64)
+
+			return n
+		}
+
+		res.Limits.RequestsLimit = header("anthropic-ratelimit-requests-limit")
+		res.Limits.RequestsRemaining =
+			header("anthropic-ratelimit-requests-remaining")
+		res.Limits.TokensLimit = header("anthropic-ratelimit-tokens-limit")
+		res.Limits.TokensRemaining =
+			header("anthropic-ratelimit-tokens-remaining")
+	}
+
+	return nil
+}
+
+// Validate reports the mock model as the only available model.
+func (m *mockPrompter) Validate(ctx context.Context,
+	res *AIValidation,
+) error {
+	res.Provider = "mock"
+	res.Model = "mock"
+	res.Models = append(res.Models, &AIModel{ID: "mock", Name: "Mock"})
+	res.Limits = &AILimits{}
+
+	return nil
+}
+
+// validateAI validates the AI API key of the current account by making a
+// minimal request to the AI provider. Failed checks are reported in the
+// result, rather than returned as errors.
+func (s *Server) validateAI(ctx context.Context) *AIValidation {
+	res := &AIValidation{Models: []*AIModel{}, Checks: []*Check{}}
+
+	ctx, cancel := request.ContextWithTimeout(ctx, aiValidateTimeout)
+	defer cancel()
+
+	var v AIValidator
+
+	runCheck(&res.Checks, "key", func() error {
+		p := s.getPrompter(ctx)
+		if p == nil {
+			return errors.New(errors.ErrInvalidRequest,
+				"account AI API key not set")
+		}
+
+		pv, ok := p.(AIValidator)
+		if !ok {
+			return errors.New(errors.ErrServer,
+				"AI provider does not support validation")
+		}
+
+		v = pv
+
+		return nil
+	})
+
+	runCheck(&res.Checks, "models", func() error {
+		return v.Validate(ctx, res)
+	})
+
+	res.Valid = res.Checks[len(res.Checks)-1].Status == CheckPassed
+
+	return res
+}
+
+// postAIValidateHandler is the post handler used to validate the AI API key of
+// the current account, so that problems with the key are found before the
+// first prompt is sent.
+func (s *Server) postAIValidateHandler(w http.ResponseWriter,
+	r *http.Request,
+) {
+	ctx := r.Context()
+
+	if err := s.checkScope(ctx, request.ScopeAccountAdmin); err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	res := s.validateAI(ctx)
+
+	if err := s.encode(w, r, res); err != nil {
+		s.error(err, w, r)
+	}
+}
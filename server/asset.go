@@ -0,0 +1,649 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/dhaifley/game2d/errors"
+	"github.com/dhaifley/game2d/logger"
+	"github.com/dhaifley/game2d/request"
+	"github.com/go-chi/chi/v5"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// Image data is stored once per account in the asset store, keyed by the
+// SHA-256 hash of the data. Stored games refer to their image data by this
+// hash, so identical images, such as those of copied games and revisions, do
+// not consume additional storage.
+const (
+	assetKey     = "asset"
+	assetDataKey = "data"
+)
+
+// assetCacheControl is the cache control of served assets. Assets are
+// addressed by the hash of their content, so they never change, and may be
+// cached by anyone who knows their hash.
+const assetCacheControl = "public, max-age=31536000, immutable"
+
+// assetHashPattern matches valid asset hashes.
+var assetHashPattern = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// Asset values represent content stored once per account in the asset store.
+// The time an asset was last used is updated whenever a game using it is
+// saved, so that assets no longer used by any game can be removed.
+type Asset struct {
+	AccountID string `bson:"account_id"        json:"account_id"        yaml:"account_id"`
+	Hash      string `bson:"hash"              json:"hash"              yaml:"hash"`
+	Data      string `bson:"data"              json:"data"              yaml:"data"`
+	Size      int64  `bson:"size"              json:"size"              yaml:"size"`
+	CreatedAt int64  `bson:"created_at"        json:"created_at"        yaml:"created_at"`
+	UsedAt    int64  `bson:"used_at,omitempty" json:"used_at,omitempty" yaml:"used_at,omitempty"`
+}
+
+// assetHash returns the hash used to identify asset data.
+func assetHash(data string) string {
+	h := sha256.Sum256([]byte(data))
+
+	return hex.EncodeToString(h[:])
+}
+
+// assetImage returns a game image as a map, whether it was decoded from a
+// request or from the database.
+func assetImage(v any) (map[string]any, bool) {
+	switch img := v.(type) {
+	case map[string]any:
+		return img, true
+	case bson.M:
+		return img, true
+	case bson.D:
+		m := make(map[string]any, len(img))
+
+		for _, e := range img {
+			m[e.Key] = e.Value
+		}
+
+		return m, true
+	default:
+		return nil, false
+	}
+}
+
+// assetUsage returns the number of bytes of asset storage used by an account.
+func (s *Server) assetUsage(ctx context.Context,
+	accountID string,
+) (int64, error) {
+	c, err := s.collection(ctx, "assets")
+	if err != nil {
+		return 0, err
+	}
+
+	cur, err := c.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"account_id": accountID}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":  nil,
+			"size": bson.M{"$sum": "$size"},
+		}}},
+	})
+	if err != nil {
+		return 0, errors.Wrap(err, errors.ErrDatabase,
+			"unable to get asset usage",
+			"account_id", accountID)
+	}
+
+	var res []struct {
+		Size int64 `bson:"size"`
+	}
+
+	if err := cur.All(ctx, &res); err != nil {
+		return 0, errors.Wrap(err, errors.ErrDatabase,
+			"unable to decode asset usage",
+			"account_id", accountID)
+	}
+
+	if len(res) == 0 {
+		return 0, nil
+	}
+
+	return res[0].Size, nil
+}
+
+// checkStorage returns an error if storing new assets would exceed the storage
+// limit of an account. Only the assets which are not already stored count
+// toward the limit. The system account is not limited.
+func (s *Server) checkStorage(ctx context.Context,
+	accountID string,
+	assets map[string]*Asset,
+) error {
+	if accountID == request.SystemAccount || len(assets) == 0 {
+		return nil
+	}
+
+	a, err := s.getAccount(ctx, accountID)
+	if err != nil {
+		return err
+	}
+
+	if a == nil {
+		return errors.New(errors.ErrNotFound,
+			"account not found",
+			"account_id", accountID)
+	}
+
+	limit := s.accountEntitlements(a).StorageLimit
+	if limit < 0 {
+		return nil
+	}
+
+	hashes := make([]string, 0, len(assets))
+
+	for h := range assets {
+		hashes = append(hashes, h)
+	}
+
+	c, err := s.collection(ctx, "assets")
+	if err != nil {
+		return err
+	}
+
+	cur, err := c.Find(ctx, bson.M{
+		"account_id": accountID,
+		"hash":       bson.M{"$in": hashes},
+	}, options.Find().SetProjection(bson.M{"_id": 0, "hash": 1}))
+	if err != nil {
+		return errors.Wrap(err, errors.ErrDatabase,
+			"unable to find assets",
+			"account_id", accountID)
+	}
+
+	var stored []*Asset
+
+	if err := cur.All(ctx, &stored); err != nil {
+		return errors.Wrap(err, errors.ErrDatabase,
+			"unable to decode assets",
+			"account_id", accountID)
+	}
+
+	exists := make(map[string]bool, len(stored))
+
+	for _, sa := range stored {
+		exists[sa.Hash] = true
+	}
+
+	var size int64
+
+	for h, sa := range assets {
+		if !exists[h] {
+			size += sa.Size
+		}
+	}
+
+	if size == 0 {
+		return nil
+	}
+
+	used, err := s.assetUsage(ctx, accountID)
+	if err != nil {
+		return err
+	}
+
+	if used+size > limit {
+		return errors.New(errors.ErrorRateLimit,
+			"account storage limit reached",
+			"account_id", accountID,
+			"storage_limit", limit,
+			"storage_used", used,
+			"size", size).
+			WithReason(errors.ReasonStorageLimitReached).
+			WithRateLimit(limit, max(limit-used, 0), time.Time{})
+	}
+
+	return nil
+}
+
+// storeAssets saves the data of a set of game images in the asset store, and
+// returns a copy of the images which refer to the stored data by hash. Storing
+// new assets is limited by the storage limit of the account.
+func (s *Server) storeAssets(ctx context.Context,
+	accountID string,
+	images request.FieldJSON,
+) (request.FieldJSON, error) {
+	if !images.Set || !images.Valid || len(images.Value) == 0 {
+		return images, nil
+	}
+
+	res := images.Copy()
+
+	now := time.Now().Unix()
+
+	assets := map[string]*Asset{}
+
+	for id, v := range res.Value {
+		img, ok := assetImage(v)
+		if !ok {
+			continue
+		}
+
+		data, ok := img[assetDataKey].(string)
+		if !ok || data == "" {
+			continue
+		}
+
+		h := assetHash(data)
+
+		delete(img, assetDataKey)
+
+		img[assetKey] = h
+
+		res.Value[id] = img
+
+		assets[h] = &Asset{
+			AccountID: accountID,
+			Hash:      h,
+			Data:      data,
+			Size:      int64(len(data)),
+			CreatedAt: now,
+		}
+	}
+
+	if len(assets) == 0 {
+		return res, nil
+	}
+
+	if err := s.checkStorage(ctx, accountID, assets); err != nil {
+		return request.FieldJSON{}, err
+	}
+
+	wm := make([]mongo.WriteModel, 0, len(assets))
+
+	for h, a := range assets {
+		wm = append(wm, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"account_id": accountID, "hash": h}).
+			SetUpdate(bson.M{
+				"$setOnInsert": a,
+				"$set":         bson.M{"used_at": now},
+			}).SetUpsert(true))
+	}
+
+	c, err := s.collection(ctx, "assets")
+	if err != nil {
+		return request.FieldJSON{}, err
+	}
+
+	if _, err := c.BulkWrite(ctx, wm,
+		options.BulkWrite().SetOrdered(false)); err != nil &&
+		!mongo.IsDuplicateKeyError(err) {
+		return request.FieldJSON{}, errors.Wrap(err, errors.ErrDatabase,
+			"unable to store assets",
+			"account_id", accountID)
+	}
+
+	return res, nil
+}
+
+// loadAssets replaces the asset references in the images of a set of games
+// with the data from the asset store. Images which already contain their data
+// are unchanged.
+func (s *Server) loadAssets(ctx context.Context,
+	accountID string,
+	games ...*Game,
+) error {
+	var hashes []string
+
+	seen := map[string]bool{}
+
+	for _, g := range games {
+		if g == nil || !g.Images.Valid {
+			continue
+		}
+
+		for _, v := range g.Images.Value {
+			img, ok := assetImage(v)
+			if !ok {
+				continue
+			}
+
+			if h, ok := img[assetKey].(string); ok && h != "" && !seen[h] {
+				seen[h] = true
+				hashes = append(hashes, h)
+			}
+		}
+	}
+
+	if len(hashes) == 0 {
+		return nil
+	}
+
+	c, err := s.collection(ctx, "assets")
+	if err != nil {
+		return err
+	}
+
+	cur, err := c.Find(ctx, bson.M{
+		"account_id": accountID,
+		"hash":       bson.M{"$in": hashes},
+	}, options.Find().SetProjection(bson.M{
+		"_id": 0, "hash": 1, "data": 1,
+	}))
+	if err != nil {
+		return errors.Wrap(err, errors.ErrDatabase,
+			"unable to find assets",
+			"account_id", accountID)
+	}
+
+	var assets []*Asset
+
+	if err := cur.All(ctx, &assets); err != nil {
+		return errors.Wrap(err, errors.ErrDatabase,
+			"unable to decode assets",
+			"account_id", accountID)
+	}
+
+	data := make(map[string]string, len(assets))
+
+	for _, a := range assets {
+		data[a.Hash] = a.Data
+	}
+
+	for _, g := range games {
+		if g == nil || !g.Images.Valid {
+			continue
+		}
+
+		for id, v := range g.Images.Value {
+			img, ok := assetImage(v)
+			if !ok {
+				continue
+			}
+
+			h, ok := img[assetKey].(string)
+			if !ok || h == "" {
+				continue
+			}
+
+			d, ok := data[h]
+			if !ok {
+				s.log.Log(ctx, logger.LvlWarn,
+					"game image asset not found",
+					"account_id", accountID,
+					"game_id", g.ID.Value,
+					"image_id", id,
+					"hash", h)
+
+				continue
+			}
+
+			delete(img, assetKey)
+
+			img[assetDataKey] = d
+
+			g.Images.Value[id] = img
+		}
+	}
+
+	return nil
+}
+
+// collectAssets removes the assets of an account which are not used by any
+// version of its games, and have not been used for the asset retention period.
+// It returns the number of assets removed.
+func (s *Server) collectAssets(ctx context.Context,
+	accountID string,
+) (int64, error) {
+	cutoff := time.Now().Add(-s.cfg.AssetRetention()).Unix()
+
+	games, err := s.collection(ctx, "games")
+	if err != nil {
+		return 0, err
+	}
+
+	assets, err := s.collection(ctx, "assets")
+	if err != nil {
+		return 0, err
+	}
+
+	cur, err := games.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"account_id": accountID,
+			"images":     bson.M{"$type": "object"},
+		}}},
+		{{Key: "$project", Value: bson.M{
+			"_id":    0,
+			"images": bson.M{"$objectToArray": "$images"},
+		}}},
+		{{Key: "$unwind", Value: "$images"}},
+		{{Key: "$group", Value: bson.M{"_id": "$images.v." + assetKey}}},
+	})
+	if err != nil {
+		return 0, errors.Wrap(err, errors.ErrDatabase,
+			"unable to find used assets",
+			"account_id", accountID)
+	}
+
+	var used []struct {
+		Hash any `bson:"_id"`
+	}
+
+	if err := cur.All(ctx, &used); err != nil {
+		return 0, errors.Wrap(err, errors.ErrDatabase,
+			"unable to decode used assets",
+			"account_id", accountID)
+	}
+
+	hashes := make([]string, 0, len(used))
+
+	for _, u := range used {
+		if h, ok := u.Hash.(string); ok && h != "" {
+			hashes = append(hashes, h)
+		}
+	}
+
+	// Assets are used again before the games using them are saved, so those
+	// saved while assets are collected are not removed.
+	res, err := assets.DeleteMany(ctx, bson.M{
+		"account_id": accountID,
+		"hash":       bson.M{"$nin": hashes},
+		"$or": bson.A{
+			bson.M{"used_at": bson.M{"$lt": cutoff}},
+			bson.M{
+				"used_at":    bson.M{"$exists": false},
+				"created_at": bson.M{"$lt": cutoff},
+			},
+		},
+	})
+	if err != nil {
+		return 0, errors.Wrap(err, errors.ErrDatabase,
+			"unable to remove unused assets",
+			"account_id", accountID)
+	}
+
+	return res.DeletedCount, nil
+}
+
+// UpdateAssets periodically removes the assets of all accounts which are no
+// longer used by any game.
+func (s *Server) UpdateAssets() {
+	s.assetOnce.Do(func() {
+		if s.cfg.AssetGCInterval() <= 0 {
+			return
+		}
+
+		go func() {
+			for s.db == nil {
+				time.Sleep(100 * time.Millisecond)
+			}
+
+			s.addCancelFunc(s.updateAssets(context.Background()))
+		}()
+	})
+}
+
+// updateAssets starts removing unused assets, returning a function which
+// stops it.
+func (s *Server) updateAssets(ctx context.Context) context.CancelFunc {
+	ctx, cancel := context.WithCancel(ctx)
+
+	s.jobs.Add(1)
+
+	go func(ctx context.Context) {
+		defer s.jobs.Done()
+
+		tick := time.NewTicker(s.cfg.AssetGCInterval())
+
+		defer tick.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-tick.C:
+				accounts, err := s.getAllAccounts(ctx)
+				if err != nil {
+					s.log.Log(ctx, logger.LvlError,
+						"unable to get accounts to collect assets",
+						"error", err)
+
+					break
+				}
+
+				for _, aID := range accounts {
+					if ctx.Err() != nil {
+						return
+					}
+
+					tctx, cancel := s.opContext(ctx, opTask)
+
+					tctx = context.WithValue(tctx, request.CtxKeyAccountID,
+						aID)
+					tctx = context.WithValue(tctx, request.CtxKeyUserID,
+						request.SystemUser)
+					tctx = context.WithValue(tctx, request.CtxKeyScopes,
+						request.ScopeSuperuser)
+
+					n, err := s.collectAssets(tctx, aID)
+
+					cancel()
+
+					if err != nil {
+						s.log.Log(ctx, logger.LvlError,
+							"unable to collect assets",
+							"error", err,
+							"account_id", aID)
+
+						continue
+					}
+
+					if n > 0 {
+						s.log.Log(ctx, logger.LvlInfo,
+							"unused assets removed",
+							"account_id", aID,
+							"assets", n)
+					}
+				}
+			}
+		}
+	}(ctx)
+
+	return cancel
+}
+
+// getAsset retrieves the data of an asset by hash. Since assets are addressed
+// by their content, the asset of any account with the hash is returned. Only
+// the asset store of the configured database is searched.
+func (s *Server) getAsset(ctx context.Context, hash string) (*Asset, error) {
+	if !assetHashPattern.MatchString(hash) {
+		return nil, errors.New(errors.ErrInvalidParameter,
+			"invalid asset hash",
+			"hash", hash)
+	}
+
+	res := &Asset{}
+
+	if err := s.tenantCollection(sharedTenant, "assets").FindOne(ctx,
+		bson.M{"hash": hash}, options.FindOne().SetProjection(bson.M{
+			"_id": 0, "hash": 1, "data": 1, "size": 1,
+		})).Decode(res); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, errors.New(errors.ErrNotFound,
+				"asset not found",
+				"hash", hash)
+		}
+
+		return nil, errors.Wrap(err, errors.ErrDatabase,
+			"unable to get asset",
+			"hash", hash)
+	}
+
+	return res, nil
+}
+
+// assetContentType returns the content type of decoded asset data. SVG images
+// are not detected by the standard content sniffing.
+func assetContentType(b []byte) string {
+	if bytes.Contains(b[:min(len(b), 512)], []byte("<svg")) {
+		return "image/svg+xml"
+	}
+
+	return http.DetectContentType(b)
+}
+
+// assetsHandler performs routing for content addressed assets.
+func (s *Server) assetsHandler() http.Handler {
+	r := chi.NewRouter()
+
+	r.Use(s.dbAvail)
+
+	r.With(s.stat, s.trace).Get("/{hash}", s.getAssetHandler)
+
+	return r
+}
+
+// getAssetHandler serves the data of an asset, such as the image data of a
+// game, at an immutable URL derived from its hash, so that CDNs can cache the
+// heavy parts of games, while game definitions only refer to their hashes.
+func (s *Server) getAssetHandler(w http.ResponseWriter, r *http.Request) {
+	hash := chi.URLParam(r, "hash")
+
+	etag := `"` + hash + `"`
+
+	// Assets never change, so any cached copy is current.
+	if r.Header.Get("If-None-Match") == etag &&
+		assetHashPattern.MatchString(hash) {
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Cache-Control", assetCacheControl)
+		w.WriteHeader(http.StatusNotModified)
+
+		return
+	}
+
+	a, err := s.getAsset(r.Context(), hash)
+	if err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	b, err := base64.StdEncoding.DecodeString(a.Data)
+	if err != nil {
+		b = []byte(a.Data)
+	}
+
+	w.Header().Set("Content-Type", assetContentType(b))
+	w.Header().Set("Cache-Control", assetCacheControl)
+	w.Header().Set("ETag", etag)
+
+	// Assets are generated, so they must not run scripts if opened directly.
+	w.Header().Set("Content-Security-Policy",
+		"default-src 'none'; style-src 'unsafe-inline'; sandbox")
+
+	if _, err := w.Write(b); err != nil {
+		s.error(err, w, r)
+	}
+}
@@ -28,22 +28,31 @@ import (
 
 // Account values represent account data.
 type Account struct {
-	ID               request.FieldString `bson:"id"                 json:"id"                 yaml:"id"`
-	Name             request.FieldString `bson:"name"               json:"name"               yaml:"name"`
-	Status           request.FieldString `bson:"status"             json:"status"             yaml:"status"`
-	StatusData       request.FieldJSON   `bson:"status_data"        json:"status_data"        yaml:"status_data"`
-	Repo             request.FieldString `bson:"repo"               json:"repo"               yaml:"repo"`
-	RepoStatus       request.FieldString `bson:"repo_status"        json:"repo_status"        yaml:"repo_status"`
-	RepoStatusData   request.FieldJSON   `bson:"repo_status_data"   json:"repo_status_data"   yaml:"repo_status_data"`
-	GameCommitHash   request.FieldString `bson:"game_commit_hash"   json:"game_commit_hash"   yaml:"game_commit_hash"`
-	GameLimit        request.FieldInt64  `bson:"game_limit"         json:"game_limit"         yaml:"game_limit"`
-	Secret           request.FieldString `bson:"secret"             json:"secret"             yaml:"secret"`
-	AIAPIKey         request.FieldString `bson:"ai_api_key"         json:"ai_api_key"         yaml:"ai_api_key"`
-	AIMaxTokens      request.FieldInt64  `bson:"ai_max_tokens"      json:"ai_max_tokens"      yaml:"ai_max_tokens"`
-	AIThinkingBudget request.FieldInt64  `bson:"ai_thinking_budget" json:"ai_thinking_budget" yaml:"ai_thinking_budget"`
-	Data             request.FieldJSON   `bson:"data"               json:"data"               yaml:"data"`
-	CreatedAt        request.FieldTime   `bson:"created_at"         json:"created_at"         yaml:"created_at"`
-	UpdatedAt        request.FieldTime   `bson:"updated_at"         json:"updated_at"         yaml:"updated_at"`
+	ID                request.FieldString      `bson:"id"                 json:"id"                 yaml:"id"`
+	Name              request.FieldString      `bson:"name"               json:"name"               yaml:"name"`
+	Status            request.FieldString      `bson:"status"             json:"status"             yaml:"status"`
+	StatusData        request.FieldJSON        `bson:"status_data"        json:"status_data"        yaml:"status_data"`
+	Repo              request.FieldString      `bson:"repo"               json:"repo"               yaml:"repo"`
+	RepoStatus        request.FieldString      `bson:"repo_status"        json:"repo_status"        yaml:"repo_status"`
+	RepoStatusData    request.FieldJSON        `bson:"repo_status_data"   json:"repo_status_data"   yaml:"repo_status_data"`
+	GameCommitHash    request.FieldString      `bson:"game_commit_hash"   json:"game_commit_hash"   yaml:"game_commit_hash"`
+	GameLimit         request.FieldInt64       `bson:"game_limit"         json:"game_limit"         yaml:"game_limit"`
+	Plan              request.FieldString      `bson:"plan"               json:"plan"               yaml:"plan"`
+	BillingCustomer   request.FieldString      `bson:"billing_customer"   json:"billing_customer"   yaml:"billing_customer"`
+	BillingStatus     request.FieldString      `bson:"billing_status"     json:"billing_status"     yaml:"billing_status"`
+	Secret            request.FieldString      `bson:"secret"             json:"secret"             yaml:"secret"`
+	AIAPIKey          request.FieldString      `bson:"ai_api_key"         json:"ai_api_key"         yaml:"ai_api_key"`
+	AIMaxTokens       request.FieldInt64       `bson:"ai_max_tokens"      json:"ai_max_tokens"      yaml:"ai_max_tokens"`
+	AIThinkingBudget  request.FieldInt64       `bson:"ai_thinking_budget" json:"ai_thinking_budget" yaml:"ai_thinking_budget"`
+	AllowedOrigins    request.FieldStringArray `bson:"allowed_origins"    json:"allowed_origins"    yaml:"allowed_origins"`
+	ManagedTags       request.FieldStringArray `bson:"managed_tags"       json:"managed_tags"       yaml:"managed_tags"`
+	EnforceTags       request.FieldBool        `bson:"enforce_tags"       json:"enforce_tags"       yaml:"enforce_tags"`
+	ImportConcurrency request.FieldInt64       `bson:"import_concurrency" json:"import_concurrency" yaml:"import_concurrency"`
+	Tenancy           request.FieldString      `bson:"tenancy"            json:"tenancy"            yaml:"tenancy"`
+	TenantStore       request.FieldString      `bson:"tenant_store"       json:"tenant_store"       yaml:"tenant_store"`
+	Data              request.FieldJSON        `bson:"data"               json:"data"               yaml:"data"`
+	CreatedAt         request.FieldTime        `bson:"created_at"         json:"created_at"         yaml:"created_at"`
+	UpdatedAt         request.FieldTime        `bson:"updated_at"         json:"updated_at"         yaml:"updated_at"`
 }
 
 // Validate checks that the value contains valid data.
@@ -123,6 +132,20 @@ func (a *Account) Validate() error {
 		}
 	}
 
+	if a.Plan.Set {
+		if !a.Plan.Valid {
+			return errors.New(errors.ErrInvalidRequest,
+				"plan must not be null",
+				"account", a)
+		}
+
+		if _, ok := Plans[a.Plan.Value]; !ok {
+			return errors.New(errors.ErrInvalidRequest,
+				"invalid plan",
+				"account", a)
+		}
+	}
+
 	if a.AIMaxTokens.Set {
 		if !a.AIMaxTokens.Valid {
 			return errors.New(errors.ErrInvalidRequest,
@@ -151,12 +174,48 @@ func (a *Account) Validate() error {
 		}
 	}
 
+	if a.ImportConcurrency.Set {
+		if !a.ImportConcurrency.Valid {
+			return errors.New(errors.ErrInvalidRequest,
+				"import_concurrency must not be null",
+				"account", a)
+		}
+
+		if a.ImportConcurrency.Value < 0 ||
+			a.ImportConcurrency.Value > MaxImportConcurrency {
+			return errors.New(errors.ErrInvalidRequest,
+				"invalid import_concurrency",
+				"account", a)
+		}
+	}
+
 	if a.Secret.Set && !a.Secret.Valid {
 		return errors.New(errors.ErrInvalidRequest,
 			"secret must not be null",
 			"account", a)
 	}
 
+	if a.EnforceTags.Set && !a.EnforceTags.Valid {
+		return errors.New(errors.ErrInvalidRequest,
+			"enforce_tags must not be null",
+			"account", a)
+	}
+
+	if a.AllowedOrigins.Set && a.AllowedOrigins.Valid {
+		for i, o := range a.AllowedOrigins.Value {
+			o = strings.ToLower(o)
+
+			if !request.ValidOrigin(o) {
+				return errors.New(errors.ErrInvalidRequest,
+					"invalid allowed_origins",
+					"origin", o,
+					"account", a)
+			}
+
+			a.AllowedOrigins.Value[i] = o
+		}
+	}
+
 	return nil
 }
 
@@ -168,6 +227,12 @@ func (a *Account) ValidateCreate() error {
 			"account", a)
 	}
 
+	if a.Tenancy.Set && (!a.Tenancy.Valid || !validTenancy(a.Tenancy.Value)) {
+		return errors.New(errors.ErrInvalidRequest,
+			"invalid tenancy",
+			"account", a)
+	}
+
 	return a.Validate()
 }
 
@@ -181,6 +246,9 @@ type Claims struct {
 
 // getAllAccounts retrieves a list of all active account ID's.
 func (s *Server) getAllAccounts(ctx context.Context) ([]string, error) {
+	ctx, cancel := s.opContext(ctx, opDB)
+	defer cancel()
+
 	ctx = context.WithValue(ctx, request.CtxKeyAccountID, request.SystemAccount)
 
 	res := []string{}
@@ -341,6 +409,12 @@ func (s *Server) createAccount(ctx context.Context,
 			"unauthorized request")
 	}
 
+	if req.Plan.Set && aID != request.SystemAccount &&
+		!request.ContextHasScope(ctx, request.ScopeSuperuser) {
+		return nil, errors.New(errors.ErrUnauthorized,
+			"unauthorized request to change account plan")
+	}
+
 	if err := req.ValidateCreate(); err != nil {
 		return nil, err
 	}
@@ -388,6 +462,9 @@ func (s *Server) createAccount(ctx context.Context,
 	request.SetField(doc, "ai_api_key", req.AIAPIKey)
 	request.SetField(doc, "ai_max_tokens", req.AIMaxTokens)
 	request.SetField(doc, "ai_thinking_budget", req.AIThinkingBudget)
+	request.SetField(doc, "allowed_origins", req.AllowedOrigins)
+	request.SetField(doc, "enforce_tags", req.EnforceTags)
+	request.SetField(doc, "import_concurrency", req.ImportConcurrency)
 	request.SetField(doc, "data", req.Data)
 	request.SetField(doc, "updated_at", req.UpdatedAt)
 
@@ -398,6 +475,27 @@ func (s *Server) createAccount(ctx context.Context,
 	request.SetField(cDoc, "game_limit", req.GameLimit)
 	request.SetField(cDoc, "secret", req.Secret)
 
+	// The tenancy of an account can only be set when it is created.
+	if !req.Tenancy.Set {
+		req.Tenancy = request.FieldString{
+			Set: true, Valid: true, Value: s.cfg.DBTenancy(),
+		}
+	}
+
+	request.SetField(cDoc, "tenancy", req.Tenancy)
+	request.SetField(cDoc, "tenant_store", request.FieldString{
+		Set: true, Valid: true,
+		Value: s.tenantStore(req.Tenancy.Value, req.ID.Value),
+	})
+
+	if req.Plan.Set {
+		request.SetField(doc, "plan", req.Plan)
+	} else {
+		request.SetField(cDoc, "plan", request.FieldString{
+			Set: true, Valid: true, Value: s.cfg.PlanDefault(),
+		})
+	}
+
 	doc = &bson.D{{Key: "$set", Value: doc}, {Key: "$setOnInsert", Value: cDoc}}
 
 	if err := s.DB().Collection("accounts").FindOneAndUpdate(ctx, f, doc,
@@ -415,8 +513,14 @@ func (s *Server) createAccount(ctx context.Context,
 			"req", req)
 	}
 
+	s.initTenant(ctx, res)
+
 	s.setCache(ctx, cache.KeyAccount(res.ID.Value), res)
 
+	if req.AllowedOrigins.Set {
+		s.refreshOrigins(ctx)
+	}
+
 	return res, nil
 }
 
@@ -428,6 +532,29 @@ func (s *Server) accountHandler() http.Handler {
 
 	r.With(s.stat, s.trace, s.auth).Get("/", s.getAccountHandler)
 	r.With(s.stat, s.trace, s.auth).Post("/", s.postAccountHandler)
+	r.With(s.stat, s.trace, s.auth).Get("/plan", s.getAccountPlanHandler)
+	r.With(s.stat, s.trace, s.auth).Put("/plan", s.putAccountPlanHandler)
+	r.With(s.stat, s.trace, s.auth).Get("/tags", s.getManagedTagsHandler)
+	r.With(s.stat, s.trace, s.auth).Post("/tags", s.postManagedTagsHandler)
+	r.With(s.stat, s.trace, s.auth).Put("/tags/{tag}",
+		s.putManagedTagHandler)
+	r.With(s.stat, s.trace, s.auth).Delete("/tags/{tag}",
+		s.deleteManagedTagHandler)
+	r.With(s.stat, s.trace, s.auth).Post("/ai/validate",
+		s.postAIValidateHandler)
+	r.With(s.stat, s.trace, s.auth).Post("/repo/validate",
+		s.postRepoValidateHandler)
+	r.With(s.stat, s.trace, s.auth).Get("/domain", s.getDomainHandler)
+	r.With(s.stat, s.trace, s.auth).Put("/domain", s.putDomainHandler)
+	r.With(s.stat, s.trace, s.auth).Delete("/domain", s.deleteDomainHandler)
+	r.With(s.stat, s.trace, s.auth).Post("/domain/verify",
+		s.postDomainVerifyHandler)
+	r.With(s.stat, s.trace, s.auth).Post("/site/export",
+		s.postAccountSiteHandler)
+	r.With(s.stat, s.trace, s.auth).Get("/activity", s.getActivityHandler)
+	r.With(s.stat, s.trace, s.auth).Get("/backups", s.getBackupsHandler)
+	r.With(s.stat, s.trace, s.auth).Post("/backups/restore",
+		s.postBackupsRestoreHandler)
 
 	return r
 }
@@ -449,7 +576,7 @@ func (s *Server) getAccountHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := json.NewEncoder(w).Encode(res); err != nil {
+	if err := s.encode(w, r, res); err != nil {
 		s.error(err, w, r)
 	}
 }
@@ -466,7 +593,7 @@ func (s *Server) postAccountHandler(w http.ResponseWriter, r *http.Request) {
 
 	req := &Account{}
 
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	if err := s.decode(r, &req); err != nil {
 		switch e := err.(type) {
 		case *errors.Error:
 			s.error(e, w, r)
@@ -500,7 +627,7 @@ func (s *Server) postAccountHandler(w http.ResponseWriter, r *http.Request) {
 
 	w.WriteHeader(http.StatusCreated)
 
-	if err := json.NewEncoder(w).Encode(res); err != nil {
+	if err := s.encode(w, r, res); err != nil {
 		s.error(err, w, r)
 	}
 }
@@ -949,6 +1076,14 @@ func (s *Server) userHandler() http.Handler {
 	r.With(s.stat, s.trace, s.auth).Get("/", s.getUserHandler)
 	r.With(s.stat, s.trace, s.auth).Patch("/", s.putUserHandler)
 	r.With(s.stat, s.trace, s.auth).Put("/", s.putUserHandler)
+	r.With(s.stat, s.trace, s.auth).Get("/notifications",
+		s.getNotificationsHandler)
+	r.With(s.stat, s.trace, s.auth).Post("/notifications/read",
+		s.postNotificationsReadHandler)
+	r.With(s.stat, s.trace, s.auth).Get("/notifications/preferences",
+		s.getNotificationPreferencesHandler)
+	r.With(s.stat, s.trace, s.auth).Put("/notifications/preferences",
+		s.putNotificationPreferencesHandler)
 	r.With(s.stat, s.trace, s.auth).Delete("/{id}", s.deleteUserHandler)
 
 	return r
@@ -971,7 +1106,7 @@ func (s *Server) getUserHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := json.NewEncoder(w).Encode(res); err != nil {
+	if err := s.encode(w, r, res); err != nil {
 		s.error(err, w, r)
 	}
 }
@@ -988,7 +1123,7 @@ func (s *Server) putUserHandler(w http.ResponseWriter, r *http.Request) {
 
 	req := &User{}
 
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	if err := s.decode(r, &req); err != nil {
 		switch e := err.(type) {
 		case *errors.Error:
 			s.error(e, w, r)
@@ -1011,7 +1146,7 @@ func (s *Server) putUserHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := json.NewEncoder(w).Encode(res); err != nil {
+	if err := s.encode(w, r, res); err != nil {
 		s.error(err, w, r)
 	}
 }
@@ -1290,7 +1425,11 @@ func (s *Server) updateAuthConfig(ctx context.Context) context.CancelFunc {
 		ctx = context.WithValue(ctx, request.CtxKeyTraceID, tu.String())
 	}
 
+	s.jobs.Add(1)
+
 	go func(ctx context.Context) {
+		defer s.jobs.Done()
+
 		tick := time.NewTimer(0)
 
 		for {
@@ -1302,8 +1441,7 @@ func (s *Server) updateAuthConfig(ctx context.Context) context.CancelFunc {
 					break
 				}
 
-				ctx, cancel := request.ContextReplaceTimeout(ctx,
-					s.cfg.AuthUpdateInterval())
+				ctx, cancel := s.opContext(ctx, opOutbound)
 
 				if tu, err := uuid.NewRandom(); err == nil {
 					ctx = context.WithValue(ctx, request.CtxKeyTraceID,
@@ -1338,7 +1476,7 @@ func (s *Server) updateAuthConfig(ctx context.Context) context.CancelFunc {
 					break
 				}
 
-				cli := &http.Client{Timeout: time.Second * 10}
+				cli := &http.Client{Timeout: s.cfg.ServerOutboundTimeout()}
 
 				resp, err := cli.Do(r)
 				if err != nil {
@@ -1553,7 +1691,7 @@ func (s *Server) createToken(ctx context.Context,
 			"expiration", expiration)
 	}
 
-	claims := jwt.MapClaims{
+	return s.signToken(ctx, jwt.MapClaims{
 		"exp":    expiration,
 		"iat":    now.Unix(),
 		"nbf":    now.Unix(),
@@ -1561,8 +1699,15 @@ func (s *Server) createToken(ctx context.Context,
 		"sub":    userID,
 		"aud":    []string{s.cfg.ServiceName()},
 		"scopes": scopes,
-	}
+	}, aID)
+}
 
+// signToken creates a signed authentication token, containing the claims,
+// using the secret of an account.
+func (s *Server) signToken(ctx context.Context,
+	claims jwt.MapClaims,
+	aID string,
+) (string, error) {
 	tok := jwt.NewWithClaims(jwt.SigningMethodHS512, claims)
 
 	tok.Header = map[string]any{
@@ -1640,6 +1785,13 @@ func (s *Server) auth(next http.Handler) http.Handler {
 				"request_remote", r.RemoteAddr)
 		}
 
+		if err := s.checkOrigin(ctx, r.Header.Get("Origin"),
+			claims.AccountID); err != nil {
+			s.error(err, w, r)
+
+			return
+		}
+
 		ctx = context.WithValue(ctx, request.CtxKeyJWT, token)
 		ctx = context.WithValue(ctx, request.CtxKeyAccountID, claims.AccountID)
 		ctx = context.WithValue(ctx, request.CtxKeyScopes, claims.Scopes)
@@ -1648,6 +1800,12 @@ func (s *Server) auth(next http.Handler) http.Handler {
 			ctx = context.WithValue(ctx, request.CtxKeyUserID, claims.UserID)
 		}
 
+		if _, err := s.accountTenant(ctx); err != nil {
+			s.error(err, w, r)
+
+			return
+		}
+
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
@@ -1699,6 +1857,13 @@ func (s *Server) postLoginTokenHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	actx := context.WithValue(ctx, request.CtxKeyAccountID, claims.AccountID)
+	actx = context.WithValue(actx, request.CtxKeyUserID, claims.UserID)
+
+	s.recordActivity(actx, ActivityLogin, "", nil)
+
+	s.runHooks(actx, HookUserLoggedIn, "", nil)
+
 	res := map[string]any{
 		"access_token": tok,
 		"token_type":   "bearer",
@@ -1708,7 +1873,7 @@ func (s *Server) postLoginTokenHandler(w http.ResponseWriter, r *http.Request) {
 		"scopes":       claims.Scopes,
 	}
 
-	if err := json.NewEncoder(w).Encode(res); err != nil {
+	if err := s.encode(w, r, res); err != nil {
 		s.error(err, w, r)
 	}
 }
@@ -0,0 +1,1075 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/dhaifley/game2d/errors"
+	"github.com/dhaifley/game2d/expr"
+	"github.com/dhaifley/game2d/logger"
+	"github.com/dhaifley/game2d/request"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// Automation triggers.
+const (
+	AutomationGameCreated     = "game_created"
+	AutomationPromptCompleted = "prompt_completed"
+	AutomationImportError     = "import_error"
+)
+
+// Automation actions.
+const (
+	AutomationWebhook = "webhook"
+	AutomationAddTags = "add_tags"
+	AutomationPublish = "publish"
+)
+
+// Automation job statuses.
+const (
+	AutomationJobPending   = "pending"
+	AutomationJobRunning   = "running"
+	AutomationJobCompleted = "completed"
+	AutomationJobSkipped   = "skipped"
+	AutomationJobFailed    = "failed"
+)
+
+// Automation limits.
+const (
+	maxAutomations       = 100
+	maxAutomationName    = 256
+	maxAutomationActions = 10
+	maxAutomationJobs    = 100
+
+	// automationInterval is how often pending automation jobs are run.
+	automationInterval = time.Second * 5
+
+	// automationBatchSize is the maximum number of automation jobs run at
+	// each interval.
+	automationBatchSize = 50
+
+	// automationJobTimeout is the maximum time allowed for running an
+	// automation job. Jobs running for longer are failed.
+	automationJobTimeout = time.Minute
+
+	// automationJobRetention is how long automation jobs are kept.
+	automationJobRetention = time.Hour * 24 * 7
+)
+
+// AutomationAction values describe an action run by an automation. Webhook
+// actions post the job to a URL, add tags actions add tags to the game of the
+// job, and publish actions make the game of the job public.
+type AutomationAction struct {
+	Type string   `bson:"type"           json:"type"           yaml:"type"`
+	URL  string   `bson:"url,omitempty"  json:"url,omitempty"  yaml:"url,omitempty"`
+	Tags []string `bson:"tags,omitempty" json:"tags,omitempty" yaml:"tags,omitempty"`
+}
+
+// Validate checks that the value contains valid data.
+func (a *AutomationAction) Validate(trigger string) error {
+	switch a.Type {
+	case AutomationWebhook:
+		if u, err := url.Parse(a.URL); err != nil ||
+			(u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return errors.New(errors.ErrInvalidRequest,
+				"webhook url must be an HTTP or HTTPS URL",
+				"url", a.URL)
+		}
+
+		a.Tags = nil
+	case AutomationAddTags, AutomationPublish:
+		if trigger == AutomationImportError {
+			return errors.New(errors.ErrInvalidRequest,
+				"action requires a game trigger",
+				"type", a.Type,
+				"trigger", trigger)
+		}
+
+		if a.Type == AutomationPublish {
+			a.Tags = nil
+		} else if len(a.Tags) == 0 {
+			return errors.New(errors.ErrInvalidRequest,
+				"missing tags",
+				"type", a.Type)
+		}
+
+		for _, t := range a.Tags {
+			if !validManagedTag(t) {
+				return errors.New(errors.ErrInvalidRequest,
+					"invalid tag",
+					"tag", t)
+			}
+		}
+
+		a.URL = ""
+	default:
+		return errors.New(errors.ErrInvalidRequest,
+			"invalid action type",
+			"type", a.Type)
+	}
+
+	return nil
+}
+
+// Automation values represent rules defined by an account, which run actions
+// when events occur. When the trigger of an automation occurs, a job is queued
+// for it, and the job runs the actions if the condition of the automation is
+// true. Conditions are expressions evaluated against the trigger, the data of
+// the event, and the game of the event, if any. An empty condition is always
+// true.
+type Automation struct {
+	AccountID string              `bson:"account_id"  json:"account_id"           yaml:"account_id"`
+	ID        string              `bson:"id"          json:"id"                   yaml:"id"`
+	Name      string              `bson:"name"        json:"name"                 yaml:"name"`
+	Trigger   string              `bson:"trigger"     json:"trigger"              yaml:"trigger"`
+	Condition string              `bson:"condition"   json:"condition,omitempty"  yaml:"condition,omitempty"`
+	Actions   []*AutomationAction `bson:"actions"     json:"actions"              yaml:"actions"`
+	Disabled  bool                `bson:"disabled"    json:"disabled"             yaml:"disabled"`
+	LastRunAt int64               `bson:"last_run_at" json:"last_run_at"          yaml:"last_run_at"`
+	LastError string              `bson:"last_error"  json:"last_error,omitempty" yaml:"last_error,omitempty"`
+	CreatedAt int64               `bson:"created_at"  json:"created_at"           yaml:"created_at"`
+	CreatedBy string              `bson:"created_by"  json:"created_by"           yaml:"created_by"`
+	UpdatedAt int64               `bson:"updated_at"  json:"updated_at"           yaml:"updated_at"`
+	UpdatedBy string              `bson:"updated_by"  json:"updated_by"           yaml:"updated_by"`
+}
+
+// Validate checks that the value contains valid data.
+func (a *Automation) Validate() error {
+	if a.Name = strings.TrimSpace(a.Name); a.Name == "" ||
+		utf8.RuneCountInString(a.Name) > maxAutomationName {
+		return errors.New(errors.ErrInvalidRequest,
+			"invalid name",
+			"name", a.Name)
+	}
+
+	switch a.Trigger {
+	case AutomationGameCreated, AutomationPromptCompleted,
+		AutomationImportError:
+	default:
+		return errors.New(errors.ErrInvalidRequest,
+			"invalid trigger",
+			"trigger", a.Trigger)
+	}
+
+	if a.Condition = strings.TrimSpace(a.Condition); a.Condition != "" {
+		if _, err := expr.Parse(a.Condition); err != nil {
+			return errors.Wrap(err, errors.ErrInvalidRequest,
+				"invalid condition",
+				"condition", a.Condition)
+		}
+	}
+
+	if len(a.Actions) == 0 || len(a.Actions) > maxAutomationActions {
+		return errors.New(errors.ErrInvalidRequest,
+			"invalid number of actions",
+			"max", maxAutomationActions)
+	}
+
+	for _, act := range a.Actions {
+		if act == nil {
+			return errors.New(errors.ErrInvalidRequest,
+				"missing action")
+		}
+
+		if err := act.Validate(a.Trigger); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// AutomationJob values represent the runs of an automation, which are queued
+// when its trigger occurs, and run in the background.
+type AutomationJob struct {
+	AccountID    string         `bson:"account_id"    json:"-"                 yaml:"-"`
+	ID           string         `bson:"id"            json:"id"                yaml:"id"`
+	AutomationID string         `bson:"automation_id" json:"automation_id"     yaml:"automation_id"`
+	Trigger      string         `bson:"trigger"       json:"trigger"           yaml:"trigger"`
+	GameID       string         `bson:"game_id"       json:"game_id,omitempty" yaml:"game_id,omitempty"`
+	UserID       string         `bson:"user_id"       json:"user_id,omitempty" yaml:"user_id,omitempty"`
+	Data         map[string]any `bson:"data"          json:"data,omitempty"    yaml:"data,omitempty"`
+	Status       string         `bson:"status"        json:"status"            yaml:"status"`
+	Error        string         `bson:"error"         json:"error,omitempty"   yaml:"error,omitempty"`
+	CreatedAt    int64          `bson:"created_at"    json:"created_at"        yaml:"created_at"`
+	UpdatedAt    int64          `bson:"updated_at"    json:"updated_at"        yaml:"updated_at"`
+	ExpiresAt    time.Time      `bson:"expires_at"    json:"-"                 yaml:"-"`
+}
+
+// validAutomationID checks that an automation ID is valid.
+func validAutomationID(id string) error {
+	if _, err := uuid.Parse(id); err != nil {
+		return errors.New(errors.ErrInvalidRequest,
+			"invalid automation id",
+			"id", id)
+	}
+
+	return nil
+}
+
+// getAutomations retrieves the automations of the current account.
+func (s *Server) getAutomations(ctx context.Context) ([]*Automation, error) {
+	aID, err := request.ContextAccountID(ctx)
+	if err != nil {
+		return nil, errors.New(errors.ErrUnauthorized,
+			"unable to get account id from context")
+	}
+
+	c, err := s.collection(ctx, "automations")
+	if err != nil {
+		return nil, err
+	}
+
+	cur, err := c.Find(ctx,
+		bson.M{"account_id": aID},
+		options.Find().SetProjection(bson.M{"_id": 0}).
+			SetSort(bson.D{{Key: "created_at", Value: 1}}).
+			SetLimit(maxAutomations))
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrDatabase,
+			"unable to find automations")
+	}
+
+	res := []*Automation{}
+
+	if err := cur.All(ctx, &res); err != nil {
+		return nil, errors.Wrap(err, errors.ErrDatabase,
+			"unable to decode automations")
+	}
+
+	return res, nil
+}
+
+// getAutomation retrieves an automation of the current account by ID.
+func (s *Server) getAutomation(ctx context.Context,
+	id string,
+) (*Automation, error) {
+	aID, err := request.ContextAccountID(ctx)
+	if err != nil {
+		return nil, errors.New(errors.ErrUnauthorized,
+			"unable to get account id from context")
+	}
+
+	if err := validAutomationID(id); err != nil {
+		return nil, err
+	}
+
+	var res *Automation
+
+	c, err := s.collection(ctx, "automations")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.FindOne(ctx,
+		bson.M{"id": id, "account_id": aID},
+		options.FindOne().SetProjection(bson.M{"_id": 0})).
+		Decode(&res); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, errors.New(errors.ErrNotFound,
+				"automation not found",
+				"id", id)
+		}
+
+		return nil, errors.Wrap(err, errors.ErrDatabase,
+			"unable to get automation",
+			"id", id)
+	}
+
+	return res, nil
+}
+
+// createAutomation creates an automation for the current account.
+func (s *Server) createAutomation(ctx context.Context,
+	v *Automation,
+) (*Automation, error) {
+	aID, err := request.ContextAccountID(ctx)
+	if err != nil {
+		return nil, errors.New(errors.ErrUnauthorized,
+			"unable to get account id from context")
+	}
+
+	uID, err := request.ContextUserID(ctx)
+	if err != nil {
+		return nil, errors.New(errors.ErrUnauthorized,
+			"unable to get user id from context")
+	}
+
+	if v == nil {
+		return nil, errors.New(errors.ErrInvalidRequest,
+			"missing automation")
+	}
+
+	if err := v.Validate(); err != nil {
+		return nil, err
+	}
+
+	c, err := s.collection(ctx, "automations")
+	if err != nil {
+		return nil, err
+	}
+
+	n, err := c.CountDocuments(ctx,
+		bson.M{"account_id": aID})
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrDatabase,
+			"unable to count automations")
+	}
+
+	if n >= maxAutomations {
+		return nil, errors.New(errors.ErrConflict,
+			"too many automations",
+			"max", maxAutomations)
+	}
+
+	now := time.Now().Unix()
+
+	v.AccountID = aID
+	v.ID = uuid.NewString()
+	v.LastRunAt = 0
+	v.LastError = ""
+	v.CreatedAt = now
+	v.CreatedBy = uID
+	v.UpdatedAt = now
+	v.UpdatedBy = uID
+
+	if _, err := c.InsertOne(ctx,
+		v); err != nil {
+		return nil, errors.Wrap(err, errors.ErrDatabase,
+			"unable to create automation",
+			"name", v.Name)
+	}
+
+	return v, nil
+}
+
+// updateAutomation updates an automation of the current account.
+func (s *Server) updateAutomation(ctx context.Context,
+	id string,
+	v *Automation,
+) (*Automation, error) {
+	uID, err := request.ContextUserID(ctx)
+	if err != nil {
+		return nil, errors.New(errors.ErrUnauthorized,
+			"unable to get user id from context")
+	}
+
+	a, err := s.getAutomation(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if v == nil {
+		return nil, errors.New(errors.ErrInvalidRequest,
+			"missing automation")
+	}
+
+	if err := v.Validate(); err != nil {
+		return nil, err
+	}
+
+	c, err := s.collection(ctx, "automations")
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := c.UpdateOne(ctx,
+		bson.M{"id": id, "account_id": a.AccountID},
+		bson.M{"$set": bson.M{
+			"name":       v.Name,
+			"trigger":    v.Trigger,
+			"condition":  v.Condition,
+			"actions":    v.Actions,
+			"disabled":   v.Disabled,
+			"updated_at": time.Now().Unix(),
+			"updated_by": uID,
+		}}); err != nil {
+		return nil, errors.Wrap(err, errors.ErrDatabase,
+			"unable to update automation",
+			"id", id)
+	}
+
+	return s.getAutomation(ctx, id)
+}
+
+// deleteAutomation deletes an automation of the current account, and its jobs.
+func (s *Server) deleteAutomation(ctx context.Context, id string) error {
+	aID, err := request.ContextAccountID(ctx)
+	if err != nil {
+		return errors.New(errors.ErrUnauthorized,
+			"unable to get account id from context")
+	}
+
+	if err := validAutomationID(id); err != nil {
+		return err
+	}
+
+	c, err := s.collection(ctx, "automations")
+	if err != nil {
+		return err
+	}
+
+	res, err := c.DeleteOne(ctx,
+		bson.M{"id": id, "account_id": aID})
+	if err != nil {
+		return errors.Wrap(err, errors.ErrDatabase,
+			"unable to delete automation",
+			"id", id)
+	}
+
+	if res.DeletedCount == 0 {
+		return errors.New(errors.ErrNotFound,
+			"automation not found",
+			"id", id)
+	}
+
+	if _, err := s.DB().Collection("automation_jobs").DeleteMany(ctx,
+		bson.M{"automation_id": id, "account_id": aID}); err != nil {
+		return errors.Wrap(err, errors.ErrDatabase,
+			"unable to delete automation jobs",
+			"id", id)
+	}
+
+	return nil
+}
+
+// getAutomationJobs retrieves the most recent jobs of an automation of the
+// current account.
+func (s *Server) getAutomationJobs(ctx context.Context,
+	id string,
+) ([]*AutomationJob, error) {
+	a, err := s.getAutomation(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	cur, err := s.DB().Collection("automation_jobs").Find(ctx,
+		bson.M{"automation_id": a.ID, "account_id": a.AccountID},
+		options.Find().SetProjection(bson.M{"_id": 0}).
+			SetSort(bson.D{{Key: "created_at", Value: -1}}).
+			SetLimit(maxAutomationJobs))
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrDatabase,
+			"unable to find automation jobs",
+			"id", id)
+	}
+
+	res := []*AutomationJob{}
+
+	if err := cur.All(ctx, &res); err != nil {
+		return nil, errors.Wrap(err, errors.ErrDatabase,
+			"unable to decode automation jobs",
+			"id", id)
+	}
+
+	return res, nil
+}
+
+// triggerAutomations queues a job for each enabled automation of the current
+// account with a trigger. Failures are logged, but otherwise ignored, so that
+// triggering automations never causes the operation which triggered them to
+// fail.
+func (s *Server) triggerAutomations(ctx context.Context,
+	trigger, gameID string,
+	data map[string]any,
+) {
+	aID, err := request.ContextAccountID(ctx)
+	if err != nil || s.DB() == nil {
+		return
+	}
+
+	uID, _ := request.ContextUserID(ctx)
+
+	ctx, cancel := s.opContext(context.WithoutCancel(ctx), opDB)
+	defer cancel()
+
+	c, err := s.collection(ctx, "automations")
+	if err != nil {
+		s.log.Log(ctx, logger.LvlWarn,
+			"unable to find automations to trigger",
+			"error", err,
+			"trigger", trigger)
+
+		return
+	}
+
+	cur, err := c.Find(ctx,
+		bson.M{"account_id": aID, "trigger": trigger, "disabled": false},
+		options.Find().SetProjection(bson.M{"_id": 0, "id": 1}))
+	if err != nil {
+		s.log.Log(ctx, logger.LvlWarn,
+			"unable to find automations to trigger",
+			"error", err,
+			"trigger", trigger)
+
+		return
+	}
+
+	var automations []*Automation
+
+	if err := cur.All(ctx, &automations); err != nil {
+		s.log.Log(ctx, logger.LvlWarn,
+			"unable to decode automations to trigger",
+			"error", err,
+			"trigger", trigger)
+
+		return
+	}
+
+	if len(automations) == 0 {
+		return
+	}
+
+	now := time.Now()
+
+	jobs := make([]any, 0, len(automations))
+
+	for _, a := range automations {
+		jobs = append(jobs, &AutomationJob{
+			AccountID:    aID,
+			ID:           uuid.NewString(),
+			AutomationID: a.ID,
+			Trigger:      trigger,
+			GameID:       gameID,
+			UserID:       uID,
+			Data:         data,
+			Status:       AutomationJobPending,
+			CreatedAt:    now.Unix(),
+			UpdatedAt:    now.Unix(),
+			ExpiresAt:    now.Add(automationJobRetention),
+		})
+	}
+
+	if _, err := s.DB().Collection("automation_jobs").InsertMany(ctx,
+		jobs); err != nil {
+		s.log.Log(ctx, logger.LvlWarn,
+			"unable to queue automation jobs",
+			"error", err,
+			"trigger", trigger,
+			"game_id", gameID)
+	}
+}
+
+// UpdateAutomations periodically runs pending automation jobs.
+func (s *Server) UpdateAutomations() {
+	s.automationOnce.Do(func() {
+		go func() {
+			for s.db == nil {
+				time.Sleep(100 * time.Millisecond)
+			}
+
+			s.addCancelFunc(s.updateAutomations(context.Background()))
+		}()
+	})
+}
+
+// updateAutomations starts running pending automation jobs, returning a
+// function which stops it.
+func (s *Server) updateAutomations(ctx context.Context) context.CancelFunc {
+	ctx, cancel := context.WithCancel(ctx)
+
+	s.jobs.Add(1)
+
+	go func(ctx context.Context) {
+		defer s.jobs.Done()
+
+		tick := time.NewTicker(automationInterval)
+
+		defer tick.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-tick.C:
+				tctx, cancel := s.opContext(ctx, opTask)
+
+				n, err := s.runAutomationJobs(tctx)
+
+				cancel()
+
+				if err != nil {
+					s.log.Log(ctx, logger.LvlError,
+						"unable to run automation jobs",
+						"error", err)
+				}
+
+				if n > 0 {
+					s.log.Log(ctx, logger.LvlDebug,
+						"automation jobs run",
+						"jobs", n)
+				}
+			}
+		}
+	}(ctx)
+
+	return cancel
+}
+
+// runAutomationJobs claims and runs pending automation jobs, oldest first,
+// until none remain or the batch size is reached. Claiming a job atomically
+// marks it running, so each job is only run once, even by many servers. Jobs
+// left running for longer than the job timeout, because the server running
+// them stopped, are failed.
+func (s *Server) runAutomationJobs(ctx context.Context) (int, error) {
+	now := time.Now()
+
+	if _, err := s.DB().Collection("automation_jobs").UpdateMany(ctx,
+		bson.M{
+			"status": AutomationJobRunning,
+			"updated_at": bson.M{
+				"$lt": now.Add(-automationJobTimeout * 2).Unix(),
+			},
+		},
+		bson.M{"$set": bson.M{
+			"status":     AutomationJobFailed,
+			"error":      "automation job timed out",
+			"updated_at": now.Unix(),
+		}}); err != nil {
+		return 0, errors.Wrap(err, errors.ErrDatabase,
+			"unable to fail timed out automation jobs")
+	}
+
+	n := 0
+
+	for ; n < automationBatchSize; n++ {
+		var job *AutomationJob
+
+		if err := s.DB().Collection("automation_jobs").FindOneAndUpdate(ctx,
+			bson.M{"status": AutomationJobPending},
+			bson.M{"$set": bson.M{
+				"status":     AutomationJobRunning,
+				"updated_at": time.Now().Unix(),
+			}},
+			options.FindOneAndUpdate().
+				SetSort(bson.D{{Key: "created_at", Value: 1}}).
+				SetProjection(bson.M{"_id": 0}).
+				SetReturnDocument(options.After)).
+			Decode(&job); err != nil {
+			if errors.Is(err, mongo.ErrNoDocuments) {
+				return n, nil
+			}
+
+			return n, errors.Wrap(err, errors.ErrDatabase,
+				"unable to claim automation job")
+		}
+
+		s.runAutomationJob(ctx, job)
+	}
+
+	return n, nil
+}
+
+// runAutomationJob runs a claimed automation job for its account, and records
+// the result in the job and its automation.
+func (s *Server) runAutomationJob(ctx context.Context, job *AutomationJob) {
+	ctx = context.WithValue(ctx, request.CtxKeyAccountID, job.AccountID)
+	ctx = context.WithValue(ctx, request.CtxKeyUserID, request.SystemUser)
+	ctx = context.WithValue(ctx, request.CtxKeyScopes, request.ScopeSuperuser)
+
+	jctx, cancel := request.ContextWithTimeout(ctx, automationJobTimeout)
+
+	status, err := s.evalAutomationJob(jctx, job)
+
+	cancel()
+
+	msg := ""
+
+	if err != nil {
+		status, msg = AutomationJobFailed, err.Error()
+
+		s.log.Log(ctx, logger.LvlWarn,
+			"automation job failed",
+			"error", err,
+			"automation_id", job.AutomationID,
+			"job_id", job.ID)
+	}
+
+	now := time.Now().Unix()
+
+	if _, err := s.DB().Collection("automation_jobs").UpdateOne(ctx,
+		bson.M{"id": job.ID},
+		bson.M{"$set": bson.M{
+			"status":     status,
+			"error":      msg,
+			"updated_at": now,
+		}}); err != nil {
+		s.log.Log(ctx, logger.LvlError,
+			"unable to record automation job result",
+			"error", err,
+			"job_id", job.ID)
+	}
+
+	if status == AutomationJobSkipped {
+		return
+	}
+
+	c, err := s.collection(ctx, "automations")
+	if err == nil {
+		_, err = c.UpdateOne(ctx,
+			bson.M{"id": job.AutomationID, "account_id": job.AccountID},
+			bson.M{"$set": bson.M{
+				"last_run_at": now,
+				"last_error":  msg,
+			}})
+	}
+
+	if err != nil {
+		s.log.Log(ctx, logger.LvlError,
+			"unable to record automation run",
+			"error", err,
+			"automation_id", job.AutomationID)
+	}
+}
+
+// evalAutomationJob evaluates the condition of the automation of a job, and
+// runs its actions in order if the condition is true, stopping at the first
+// action which fails. It returns the resulting status of the job.
+func (s *Server) evalAutomationJob(ctx context.Context,
+	job *AutomationJob,
+) (string, error) {
+	a, err := s.getAutomation(ctx, job.AutomationID)
+	if err != nil {
+		return AutomationJobFailed, err
+	}
+
+	if a.Disabled {
+		return AutomationJobSkipped, nil
+	}
+
+	vars := map[string]any{
+		"trigger": job.Trigger,
+		"user_id": job.UserID,
+		"data":    job.Data,
+	}
+
+	var g *Game
+
+	if job.GameID != "" {
+		g, err = s.getGame(context.WithValue(ctx, CtxKeyGameMinData, true),
+			job.GameID)
+		if err != nil {
+			return AutomationJobFailed, err
+		}
+
+		vars["game"] = map[string]any{
+			"id":      g.ID.Value,
+			"name":    g.Name.Value,
+			"version": g.Version.Value,
+			"status":  g.Status.Value,
+			"source":  g.Source.Value,
+			"public":  g.Public.Value,
+			"tags":    g.Tags.Value,
+		}
+	}
+
+	if a.Condition != "" {
+		e, err := expr.Parse(a.Condition)
+		if err != nil {
+			return AutomationJobFailed, err
+		}
+
+		ok, err := e.Eval(vars)
+		if err != nil {
+			return AutomationJobFailed, err
+		}
+
+		if !ok {
+			return AutomationJobSkipped, nil
+		}
+	}
+
+	for _, act := range a.Actions {
+		if err := s.runAutomationAction(ctx, a, job, g, act); err != nil {
+			return AutomationJobFailed, errors.Wrap(err, errors.ErrServer,
+				"unable to run automation action",
+				"type", act.Type)
+		}
+	}
+
+	return AutomationJobCompleted, nil
+}
+
+// runAutomationAction runs a single action of an automation for a job.
+func (s *Server) runAutomationAction(ctx context.Context,
+	a *Automation,
+	job *AutomationJob,
+	g *Game,
+	act *AutomationAction,
+) error {
+	if act.Type == AutomationWebhook {
+		return s.postAutomationWebhook(ctx, a, job, act.URL)
+	}
+
+	if g == nil {
+		return errors.New(errors.ErrInvalidRequest,
+			"action requires a game",
+			"type", act.Type)
+	}
+
+	if g.ReadOnly.Value {
+		return errReadOnlyGame(g.ID.Value)
+	}
+
+	switch act.Type {
+	case AutomationAddTags:
+		_, err := s.addGameTags(ctx, g.ID.Value, act.Tags)
+
+		return err
+	case AutomationPublish:
+		_, err := s.updateGame(ctx, &Game{
+			ID: request.FieldString{
+				Set: true, Valid: true, Value: g.ID.Value,
+			},
+			Public: request.FieldBool{
+				Set: true, Valid: true, Value: true,
+			},
+		})
+
+		return err
+	}
+
+	return errors.New(errors.ErrInvalidRequest,
+		"invalid action type",
+		"type", act.Type)
+}
+
+// postAutomationWebhook posts an automation job to a webhook URL.
+func (s *Server) postAutomationWebhook(ctx context.Context,
+	a *Automation,
+	job *AutomationJob,
+	u string,
+) error {
+	b, err := json.Marshal(map[string]any{
+		"automation_id":   a.ID,
+		"automation_name": a.Name,
+		"job":             job,
+	})
+	if err != nil {
+		return errors.Wrap(err, errors.ErrServer,
+			"unable to encode automation webhook")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u,
+		bytes.NewReader(b))
+	if err != nil {
+		return errors.Wrap(err, errors.ErrServer,
+			"unable to create automation webhook request")
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	cli := &http.Client{Timeout: s.cfg.ServerOutboundTimeout()}
+
+	res, err := cli.Do(req)
+	if err != nil {
+		return errors.Wrap(err, errors.ErrServer,
+			"unable to send automation webhook")
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return errors.New(errors.ErrServer,
+			"unexpected automation webhook response status",
+			"status", res.StatusCode)
+	}
+
+	return nil
+}
+
+// automationsHandler performs routing for automation requests.
+func (s *Server) automationsHandler() http.Handler {
+	r := chi.NewRouter()
+
+	r.Use(s.dbAvail)
+
+	r.With(s.stat, s.trace, s.auth).Get("/", s.getAutomationsHandler)
+	r.With(s.stat, s.trace, s.auth).Post("/", s.postAutomationHandler)
+	r.With(s.stat, s.trace, s.auth).Get("/{id}", s.getAutomationHandler)
+	r.With(s.stat, s.trace, s.auth).Patch("/{id}", s.putAutomationHandler)
+	r.With(s.stat, s.trace, s.auth).Put("/{id}", s.putAutomationHandler)
+	r.With(s.stat, s.trace, s.auth).Delete("/{id}",
+		s.deleteAutomationHandler)
+	r.With(s.stat, s.trace, s.auth).Get("/{id}/jobs",
+		s.getAutomationJobsHandler)
+
+	return r
+}
+
+// getAutomationsHandler is the get handler used to list the automations of
+// the current account.
+func (s *Server) getAutomationsHandler(w http.ResponseWriter,
+	r *http.Request,
+) {
+	ctx := r.Context()
+
+	if err := s.checkScope(ctx, request.ScopeAccountRead); err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	res, err := s.getAutomations(ctx)
+	if err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	if err := s.encode(w, r, res); err != nil {
+		s.error(err, w, r)
+	}
+}
+
+// getAutomationHandler is the get handler used to retrieve an automation.
+func (s *Server) getAutomationHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if err := s.checkScope(ctx, request.ScopeAccountRead); err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	res, err := s.getAutomation(ctx, chi.URLParam(r, "id"))
+	if err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	if err := s.encode(w, r, res); err != nil {
+		s.error(err, w, r)
+	}
+}
+
+// postAutomationHandler is the post handler used to create an automation.
+func (s *Server) postAutomationHandler(w http.ResponseWriter,
+	r *http.Request,
+) {
+	ctx := r.Context()
+
+	if err := s.checkScope(ctx, request.ScopeAccountAdmin); err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	req := &Automation{}
+
+	if err := s.decode(r, &req); err != nil {
+		s.error(errors.Wrap(err, errors.ErrInvalidRequest,
+			"unable to decode request"), w, r)
+
+		return
+	}
+
+	res, err := s.createAutomation(ctx, req)
+	if err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+
+	if err := s.encode(w, r, res); err != nil {
+		s.error(err, w, r)
+	}
+}
+
+// putAutomationHandler is the put and patch handler used to update an
+// automation. Fields not included in the request are left unchanged.
+func (s *Server) putAutomationHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if err := s.checkScope(ctx, request.ScopeAccountAdmin); err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+
+	req, err := s.getAutomation(ctx, id)
+	if err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	if err := s.decode(r, &req); err != nil {
+		s.error(errors.Wrap(err, errors.ErrInvalidRequest,
+			"unable to decode request"), w, r)
+
+		return
+	}
+
+	res, err := s.updateAutomation(ctx, id, req)
+	if err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	if err := s.encode(w, r, res); err != nil {
+		s.error(err, w, r)
+	}
+}
+
+// deleteAutomationHandler is the delete handler used to delete an automation.
+func (s *Server) deleteAutomationHandler(w http.ResponseWriter,
+	r *http.Request,
+) {
+	ctx := r.Context()
+
+	if err := s.checkScope(ctx, request.ScopeAccountAdmin); err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	if err := s.deleteAutomation(ctx, chi.URLParam(r, "id")); err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getAutomationJobsHandler is the get handler used to list the most recent
+// jobs of an automation.
+func (s *Server) getAutomationJobsHandler(w http.ResponseWriter,
+	r *http.Request,
+) {
+	ctx := r.Context()
+
+	if err := s.checkScope(ctx, request.ScopeAccountRead); err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	res, err := s.getAutomationJobs(ctx, chi.URLParam(r, "id"))
+	if err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	if err := s.encode(w, r, res); err != nil {
+		s.error(err, w, r)
+	}
+}
@@ -0,0 +1,554 @@
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"path"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/dhaifley/game2d/errors"
+	"github.com/dhaifley/game2d/logger"
+	"github.com/dhaifley/game2d/repo"
+	"github.com/dhaifley/game2d/request"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// backupManifestFile is the name of the manifest file in the backup directory
+// of each account.
+const backupManifestFile = "manifest.json"
+
+// Backup values represent a backup of the games of an account, and optionally
+// of its settings and users.
+type Backup struct {
+	AccountID string   `json:"account_id"`
+	CreatedAt int64    `json:"created_at"`
+	Account   *Account `json:"account,omitempty"`
+	Users     []*User  `json:"users,omitempty"`
+	Games     []*Game  `json:"games"`
+}
+
+// BackupEntry values describe a single backup in a backup manifest. The hash
+// is used to verify the integrity of the backup before it is restored.
+type BackupEntry struct {
+	Path      string `json:"path"       yaml:"path"`
+	CreatedAt int64  `json:"created_at" yaml:"created_at"`
+	Size      int64  `json:"size"       yaml:"size"`
+	SHA256    string `json:"sha256"     yaml:"sha256"`
+	Games     int64  `json:"games"      yaml:"games"`
+	Users     int64  `json:"users"      yaml:"users"`
+}
+
+// BackupManifest values list the retained backups of an account, from oldest
+// to newest.
+type BackupManifest struct {
+	AccountID string         `json:"account_id" yaml:"account_id"`
+	Backups   []*BackupEntry `json:"backups"    yaml:"backups"`
+}
+
+// BackupRestore values represent a request to restore a backup. The most
+// recent backup is restored if no creation time is specified.
+type BackupRestore struct {
+	CreatedAt int64 `json:"created_at,omitempty" yaml:"created_at,omitempty"`
+}
+
+// BackupRestoreResult values represent the result of restoring a backup.
+type BackupRestoreResult struct {
+	Backup   *BackupEntry `json:"backup"           yaml:"backup"`
+	Restored int64        `json:"restored"         yaml:"restored"`
+	Errors   []string     `json:"errors,omitempty" yaml:"errors,omitempty"`
+}
+
+// backupTarget is the repository client used to store the backups of the
+// current account. A nil client is returned if the account has no backup
+// target.
+func (s *Server) backupTarget(ctx context.Context,
+) (repo.Client, repo.Writer, error) {
+	var (
+		cli repo.Client
+		err error
+	)
+
+	if u := s.cfg.BackupURL(); u != "" {
+		cli, err = repo.NewClient(u, s.metric, s.tracer)
+	} else {
+		a, aErr := s.getAccount(ctx, "")
+		if aErr != nil {
+			return nil, nil, errors.Wrap(aErr, errors.ErrDatabase,
+				"unable to get account repository")
+		}
+
+		if a.Repo.Value == "" {
+			return nil, nil, nil
+		}
+
+		cli, err = s.getRepoClient(a.Repo.Value)
+	}
+
+	if err != nil {
+		return nil, nil, errors.Wrap(err, errors.ErrClient,
+			"unable to create backup repository client")
+	}
+
+	w, ok := cli.(repo.Writer)
+	if !ok {
+		return nil, nil, nil
+	}
+
+	return cli, w, nil
+}
+
+// getBackupManifest retrieves the backup manifest of the current account from
+// a backup repository. An empty manifest is returned if none exists.
+func (s *Server) getBackupManifest(ctx context.Context,
+	cli repo.Client,
+) (*BackupManifest, error) {
+	aID, err := request.ContextAccountID(ctx)
+	if err != nil {
+		return nil, errors.New(errors.ErrUnauthorized,
+			"unable to get account id from context")
+	}
+
+	res := &BackupManifest{AccountID: aID, Backups: []*BackupEntry{}}
+
+	buf, err := cli.Get(ctx, path.Join(repo.BackupDir, aID,
+		backupManifestFile))
+	if err != nil {
+		if errors.Has(err, errors.ErrNotFound) {
+			return res, nil
+		}
+
+		return nil, errors.Wrap(err, errors.ErrClient,
+			"unable to get backup manifest")
+	}
+
+	if err := json.Unmarshal(buf, res); err != nil {
+		return nil, errors.Wrap(err, errors.ErrServer,
+			"unable to decode backup manifest")
+	}
+
+	return res, nil
+}
+
+// backupGames writes a backup of the current account to its backup target,
+// prunes backups beyond the retention limit, and returns the manifest entry of
+// the backup. Nothing is written if the account has no backup target.
+func (s *Server) backupGames(ctx context.Context) (*BackupEntry, error) {
+	ctx, cancel := s.detachedContext(ctx, opImport)
+
+	defer cancel()
+
+	aID, err := request.ContextAccountID(ctx)
+	if err != nil {
+		return nil, errors.New(errors.ErrUnauthorized,
+			"unable to get account id from context")
+	}
+
+	cli, w, err := s.backupTarget(ctx)
+	if err != nil || w == nil {
+		return nil, err
+	}
+
+	c, err := s.collection(ctx, "games")
+	if err != nil {
+		return nil, err
+	}
+
+	cur, err := c.Find(ctx, bson.M{
+		"account_id": aID,
+		"status":     bson.M{"$ne": request.StatusInactive},
+	}, options.Find().SetSort(bson.M{"id": 1}).
+		SetProjection(bson.M{"_id": 0}))
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrDatabase,
+			"unable to find games to back up")
+	}
+
+	b := &Backup{
+		AccountID: aID,
+		CreatedAt: time.Now().Unix(),
+		Games:     []*Game{},
+	}
+
+	if err := cur.All(ctx, &b.Games); err != nil {
+		return nil, errors.Wrap(err, errors.ErrDatabase,
+			"unable to decode games to back up")
+	}
+
+	if err := s.loadAssets(ctx, aID, b.Games...); err != nil {
+		return nil, err
+	}
+
+	// Secrets, such as credentials and password hashes, are never included
+	// in backups.
+	if s.cfg.BackupUsers() {
+		if b.Account, err = s.getAccount(ctx, ""); err != nil {
+			return nil, errors.Wrap(err, errors.ErrDatabase,
+				"unable to get account to back up")
+		}
+
+		b.Account.Secret = request.FieldString{}
+		b.Account.AIAPIKey = request.FieldString{}
+		b.Account.Repo = request.FieldString{}
+
+		cur, err := s.DB().Collection("users").Find(ctx, bson.M{
+			"account_id": aID,
+		}, options.Find().SetSort(bson.M{"id": 1}).
+			SetProjection(bson.M{"_id": 0, "password": 0}))
+		if err != nil {
+			return nil, errors.Wrap(err, errors.ErrDatabase,
+				"unable to find users to back up")
+		}
+
+		if err := cur.All(ctx, &b.Users); err != nil {
+			return nil, errors.Wrap(err, errors.ErrDatabase,
+				"unable to decode users to back up")
+		}
+	}
+
+	m, err := s.getBackupManifest(ctx, cli)
+	if err != nil {
+		return nil, err
+	}
+
+	buf, err := json.Marshal(b)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrServer,
+			"unable to encode backup")
+	}
+
+	sum := sha256.Sum256(buf)
+
+	e := &BackupEntry{
+		Path: path.Join(repo.BackupDir, aID,
+			strconv.FormatInt(b.CreatedAt, 10)+".json"),
+		CreatedAt: b.CreatedAt,
+		Size:      int64(len(buf)),
+		SHA256:    hex.EncodeToString(sum[:]),
+		Games:     int64(len(b.Games)),
+		Users:     int64(len(b.Users)),
+	}
+
+	if err := w.Put(ctx, e.Path, buf); err != nil {
+		return nil, errors.Wrap(err, errors.ErrClient,
+			"unable to write backup",
+			"path", e.Path)
+	}
+
+	m.Backups = slices.DeleteFunc(m.Backups, func(be *BackupEntry) bool {
+		return be.Path == e.Path
+	})
+
+	m.Backups = append(m.Backups, e)
+
+	slices.SortFunc(m.Backups, func(a, b *BackupEntry) int {
+		return int(a.CreatedAt - b.CreatedAt)
+	})
+
+	// The oldest backups beyond the retention limit are removed from the
+	// manifest before they are deleted, so that the manifest never refers to
+	// deleted backups.
+	var pruned []*BackupEntry
+
+	if n := len(m.Backups) - s.cfg.BackupRetention(); n > 0 {
+		pruned, m.Backups = m.Backups[:n], m.Backups[n:]
+	}
+
+	if buf, err = json.Marshal(m); err != nil {
+		return nil, errors.Wrap(err, errors.ErrServer,
+			"unable to encode backup manifest")
+	}
+
+	if err := w.Put(ctx, path.Join(repo.BackupDir, aID,
+		backupManifestFile), buf); err != nil {
+		return nil, errors.Wrap(err, errors.ErrClient,
+			"unable to write backup manifest")
+	}
+
+	for _, pe := range pruned {
+		if err := w.Delete(ctx, pe.Path); err != nil {
+			s.log.Log(ctx, logger.LvlWarn,
+				"unable to delete pruned backup",
+				"error", err,
+				"path", pe.Path)
+		}
+	}
+
+	if err := s.setImportProgress(ctx, map[string]any{
+		"games_last_backup":      e.CreatedAt,
+		"games_last_backup_path": e.Path,
+	}); err != nil {
+		s.log.Log(ctx, logger.LvlWarn,
+			"unable to record backup",
+			"error", err,
+			"path", e.Path)
+	}
+
+	return e, nil
+}
+
+// getBackups retrieves the backup manifest of the current account.
+func (s *Server) getBackups(ctx context.Context) (*BackupManifest, error) {
+	cli, w, err := s.backupTarget(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if w == nil {
+		return nil, errors.New(errors.ErrNotFound,
+			"account has no backup repository")
+	}
+
+	return s.getBackupManifest(ctx, cli)
+}
+
+// getBackup retrieves a backup of the current account, verifying its integrity.
+// The most recent backup is retrieved if createdAt is zero, otherwise the backup
+// created at that time is retrieved.
+func (s *Server) getBackup(ctx context.Context,
+	createdAt int64,
+) (*Backup, *BackupEntry, error) {
+	cli, w, err := s.backupTarget(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if w == nil {
+		return nil, nil, errors.New(errors.ErrNotFound,
+			"account has no backup repository")
+	}
+
+	m, err := s.getBackupManifest(ctx, cli)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var e *BackupEntry
+
+	for _, be := range m.Backups {
+		if createdAt == 0 || be.CreatedAt == createdAt {
+			e = be
+		}
+	}
+
+	if e == nil {
+		return nil, nil, errors.New(errors.ErrNotFound,
+			"backup not found",
+			"created_at", createdAt)
+	}
+
+	buf, err := cli.Get(ctx, e.Path)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, errors.ErrClient,
+			"unable to get backup",
+			"path", e.Path)
+	}
+
+	sum := sha256.Sum256(buf)
+
+	if int64(len(buf)) != e.Size || hex.EncodeToString(sum[:]) != e.SHA256 {
+		return nil, nil, errors.New(errors.ErrConflict,
+			"backup integrity check failed",
+			"path", e.Path)
+	}
+
+	b := &Backup{}
+
+	if err := json.Unmarshal(buf, b); err != nil {
+		return nil, nil, errors.Wrap(err, errors.ErrServer,
+			"unable to decode backup",
+			"path", e.Path)
+	}
+
+	return b, e, nil
+}
+
+// restoreBackup restores the games of a backup of the current account. Games
+// which exist are replaced, and games created since the backup are kept.
+func (s *Server) restoreBackup(ctx context.Context,
+	req *BackupRestore,
+) (*BackupRestoreResult, error) {
+	aID, err := request.ContextAccountID(ctx)
+	if err != nil {
+		return nil, errors.New(errors.ErrUnauthorized,
+			"unable to get account id from context")
+	}
+
+	if req == nil {
+		req = &BackupRestore{}
+	}
+
+	b, e, err := s.getBackup(ctx, req.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &BackupRestoreResult{Backup: e}
+
+	ctx = context.WithValue(ctx, CtxKeyGameAllowTags, true)
+
+	for _, g := range b.Games {
+		if g == nil {
+			continue
+		}
+
+		g.AccountID = request.FieldString{Set: true, Valid: true, Value: aID}
+
+		if _, err := s.createGame(ctx, g); err != nil {
+			res.Errors = append(res.Errors, g.ID.Value+": "+err.Error())
+
+			continue
+		}
+
+		res.Restored++
+	}
+
+	return res, nil
+}
+
+// getBackupsHandler is the get handler used to list account backups.
+func (s *Server) getBackupsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if err := s.checkScope(ctx, request.ScopeAccountRead); err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	res, err := s.getBackups(ctx)
+	if err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	if err := s.encode(w, r, res); err != nil {
+		s.error(err, w, r)
+	}
+}
+
+// postBackupsRestoreHandler is the post handler used to restore an account
+// backup.
+func (s *Server) postBackupsRestoreHandler(w http.ResponseWriter,
+	r *http.Request,
+) {
+	ctx := r.Context()
+
+	if err := s.checkScope(ctx, request.ScopeAccountAdmin); err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	req := &BackupRestore{}
+
+	if r.ContentLength != 0 {
+		if err := s.decode(r, &req); err != nil {
+			switch e := err.(type) {
+			case *errors.Error:
+				s.error(e, w, r)
+			default:
+				s.error(errors.Wrap(err, errors.ErrInvalidRequest,
+					"unable to decode request"), w, r)
+			}
+
+			return
+		}
+	}
+
+	res, err := s.restoreBackup(ctx, req)
+	if err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	if err := s.encode(w, r, res); err != nil {
+		s.error(err, w, r)
+	}
+}
+
+// updateGameBackups periodically backs up all accounts.
+func (s *Server) updateGameBackups(ctx context.Context,
+) context.CancelFunc {
+	ctx, cancel := context.WithCancel(ctx)
+
+	s.jobs.Add(1)
+
+	go func(ctx context.Context) {
+		defer s.jobs.Done()
+
+		tick := time.NewTimer(s.cfg.BackupInterval())
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-tick.C:
+				accounts, err := s.getAllAccounts(ctx)
+				if err != nil {
+					s.log.Log(ctx, logger.LvlError,
+						"unable to get accounts to back up",
+						"error", err)
+
+					break
+				}
+
+				var wg sync.WaitGroup
+
+				for _, aID := range accounts {
+					wg.Add(1)
+
+					go func(ctx context.Context, accountID string) {
+						defer wg.Done()
+
+						ctx, cancel := s.opContext(ctx, opImport)
+						defer cancel()
+
+						ctx = context.WithValue(ctx, request.CtxKeyAccountID,
+							accountID)
+						ctx = context.WithValue(ctx, request.CtxKeyUserID,
+							request.SystemUser)
+						ctx = context.WithValue(ctx, request.CtxKeyScopes,
+							request.ScopeSuperuser)
+
+						if tu, err := uuid.NewRandom(); err == nil {
+							ctx = context.WithValue(ctx, request.CtxKeyTraceID,
+								tu.String())
+						}
+
+						e, err := s.backupGames(ctx)
+						if err != nil {
+							s.log.Log(ctx, logger.LvlError,
+								"unable to back up account",
+								"error", err)
+
+							return
+						}
+
+						if e != nil {
+							s.log.Log(ctx, logger.LvlInfo,
+								"account backup completed",
+								"path", e.Path,
+								"games", e.Games)
+						}
+					}(ctx, aID)
+				}
+
+				wg.Wait()
+			}
+
+			tick = time.NewTimer(s.cfg.BackupInterval())
+		}
+	}(ctx)
+
+	return cancel
+}
@@ -0,0 +1,619 @@
+package server
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dhaifley/game2d/cache"
+	"github.com/dhaifley/game2d/errors"
+	"github.com/dhaifley/game2d/logger"
+	"github.com/dhaifley/game2d/request"
+	"github.com/go-chi/chi/v5"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// StripeAPI is the base URL of the Stripe API.
+var StripeAPI = "https://api.stripe.com"
+
+// maxBillingEventSize is the maximum size of a billing webhook event.
+const maxBillingEventSize = 1 << 16
+
+// Stripe webhook event types handled by the server.
+const (
+	stripeCheckoutCompleted   = "checkout.session.completed"
+	stripeSubscriptionCreated = "customer.subscription.created"
+	stripeSubscriptionUpdated = "customer.subscription.updated"
+	stripeSubscriptionDeleted = "customer.subscription.deleted"
+)
+
+// Stripe subscription statuses used to determine account plans.
+const (
+	stripeStatusActive   = "active"
+	stripeStatusTrialing = "trialing"
+	stripeStatusPastDue  = "past_due"
+	stripeStatusCanceled = "canceled"
+)
+
+const (
+	// stripeSignatureHeader is the header containing webhook signatures.
+	stripeSignatureHeader = "Stripe-Signature"
+
+	// stripeSignatureScheme is the scheme of webhook signatures verified.
+	stripeSignatureScheme = "v1"
+
+	// stripeAccountIDKey is the subscription metadata key which may contain
+	// the ID of the account subscribed.
+	stripeAccountIDKey = "account_id"
+)
+
+// stripeEvent values represent Stripe webhook events.
+type stripeEvent struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+	Data struct {
+		Object json.RawMessage `json:"object"`
+	} `json:"data"`
+}
+
+// stripeCheckout values represent Stripe checkout sessions. The client
+// reference ID of checkout sessions is the ID of the account subscribing, which
+// is used if the customer is not yet associated with an account.
+type stripeCheckout struct {
+	ClientReferenceID string `json:"client_reference_id"`
+	Customer          string `json:"customer"`
+}
+
+// stripeSubscription values represent Stripe subscriptions.
+type stripeSubscription struct {
+	ID       string            `json:"id"`
+	Customer string            `json:"customer"`
+	Status   string            `json:"status"`
+	Metadata map[string]string `json:"metadata"`
+	Items    struct {
+		Data []struct {
+			Price struct {
+				ID string `json:"id"`
+			} `json:"price"`
+		} `json:"data"`
+	} `json:"items"`
+}
+
+// BillingPortal values contain a link to the billing portal of an account.
+type BillingPortal struct {
+	URL string `json:"url" yaml:"url"`
+}
+
+// verifyStripeSignature verifies the signature of a Stripe webhook event.
+func (s *Server) verifyStripeSignature(header string, body []byte) error {
+	var ts string
+
+	sigs := []string{}
+
+	for _, p := range strings.Split(header, ",") {
+		k, v, _ := strings.Cut(strings.TrimSpace(p), "=")
+
+		switch k {
+		case "t":
+			ts = v
+		case stripeSignatureScheme:
+			sigs = append(sigs, v)
+		}
+	}
+
+	t, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil || len(sigs) == 0 {
+		return errors.New(errors.ErrUnauthorized,
+			"invalid billing event signature header",
+			"header", header)
+	}
+
+	// Events signed slightly in the future, due to clock skew, are accepted.
+	if age := time.Since(time.Unix(t, 0)).Abs(); age >
+		s.cfg.BillingWebhookTolerance() {
+		return errors.New(errors.ErrUnauthorized,
+			"billing event signature expired",
+			"timestamp", t)
+	}
+
+	h := hmac.New(sha256.New, []byte(s.cfg.BillingWebhookSecret()))
+
+	h.Write([]byte(ts + "."))
+	h.Write(body)
+
+	exp := h.Sum(nil)
+
+	for _, sig := range sigs {
+		if b, err := hex.DecodeString(sig); err == nil && hmac.Equal(b, exp) {
+			return nil
+		}
+	}
+
+	return errors.New(errors.ErrUnauthorized,
+		"invalid billing event signature")
+}
+
+// billingContext returns a context used to update an account in response to
+// billing events.
+func billingContext(ctx context.Context, accountID string) context.Context {
+	ctx = context.WithValue(ctx, request.CtxKeyAccountID, accountID)
+	ctx = context.WithValue(ctx, request.CtxKeyUserID, request.SystemUser)
+	ctx = context.WithValue(ctx, request.CtxKeyScopes, request.ScopeSuperuser)
+
+	return ctx
+}
+
+// billingAccountID returns the ID of the account of a Stripe customer. The
+// account is found using the Stripe customer ID, if it is already associated
+// with an account, or else using the account ID given by the billing event. An
+// error is returned if no account matches, so that the event is retried.
+func (s *Server) billingAccountID(ctx context.Context,
+	customer, accountID string,
+) (string, error) {
+	c, err := s.collection(ctx, "accounts")
+	if err != nil {
+		return "", err
+	}
+
+	filters := []bson.M{}
+
+	if customer != "" {
+		filters = append(filters, bson.M{"billing_customer": customer})
+	}
+
+	if accountID != "" {
+		filters = append(filters, bson.M{"id": accountID})
+	}
+
+	var a Account
+
+	for _, f := range filters {
+		err := c.FindOne(ctx, f,
+			options.FindOne().SetProjection(bson.M{"_id": 0, "id": 1})).
+			Decode(&a)
+		if err == nil {
+			return a.ID.Value, nil
+		}
+
+		if !errors.Is(err, mongo.ErrNoDocuments) {
+			return "", errors.Wrap(err, errors.ErrDatabase,
+				"unable to get account for billing customer",
+				"customer", customer,
+				"account_id", accountID)
+		}
+	}
+
+	return "", errors.New(errors.ErrNotFound,
+		"account not found for billing customer",
+		"customer", customer,
+		"account_id", accountID)
+}
+
+// subscriptionPlan returns the plan of an account with a Stripe subscription.
+// Accounts with lapsed subscriptions are downgraded to the free plan, while
+// accounts with past due subscriptions keep their plan until it lapses.
+func (s *Server) subscriptionPlan(sub *stripeSubscription,
+	current string,
+) string {
+	switch sub.Status {
+	case stripeStatusActive, stripeStatusTrialing:
+		for _, it := range sub.Items.Data {
+			if p, ok := s.cfg.BillingPrices()[it.Price.ID]; ok {
+				if _, ok := Plans[p]; ok {
+					return p
+				}
+			}
+		}
+
+		return current
+	case stripeStatusPastDue:
+		return current
+	default:
+		return PlanFree
+	}
+}
+
+// updateBillingCustomer associates a Stripe customer with an account.
+func (s *Server) updateBillingCustomer(ctx context.Context,
+	accountID, customer string,
+) error {
+	c, err := s.collection(ctx, "accounts")
+	if err != nil {
+		return err
+	}
+
+	res, err := c.UpdateOne(ctx,
+		bson.M{"id": accountID},
+		bson.M{"$set": bson.M{
+			"billing_customer": customer,
+			"updated_at":       time.Now().Unix(),
+		}})
+	if err != nil {
+		return errors.Wrap(err, errors.ErrDatabase,
+			"unable to update account billing customer",
+			"account_id", accountID,
+			"customer", customer)
+	}
+
+	if res.MatchedCount == 0 {
+		return errors.New(errors.ErrNotFound,
+			"account not found for billing customer",
+			"account_id", accountID,
+			"customer", customer)
+	}
+
+	s.deleteCache(ctx, cache.KeyAccount(accountID))
+
+	return nil
+}
+
+// updateSubscription updates the plan and billing status of an account from
+// a Stripe subscription, and enforces the limits of the resulting plan.
+func (s *Server) updateSubscription(ctx context.Context,
+	sub *stripeSubscription,
+	deleted bool,
+) error {
+	aID, err := s.billingAccountID(ctx, sub.Customer,
+		sub.Metadata[stripeAccountIDKey])
+	if err != nil {
+		return err
+	}
+
+	ctx = billingContext(ctx, aID)
+
+	a, err := s.getAccount(ctx, aID)
+	if err != nil {
+		return err
+	}
+
+	if deleted {
+		sub.Status = stripeStatusCanceled
+	}
+
+	plan := s.subscriptionPlan(sub, s.accountPlan(a))
+
+	if _, err := s.DB().Collection("accounts").UpdateOne(ctx,
+		bson.M{"id": aID},
+		bson.M{"$set": bson.M{
+			"plan":             plan,
+			"billing_customer": sub.Customer,
+			"billing_status":   sub.Status,
+			"updated_at":       time.Now().Unix(),
+		}}); err != nil {
+		return errors.Wrap(err, errors.ErrDatabase,
+			"unable to update account subscription",
+			"account_id", aID,
+			"subscription_id", sub.ID)
+	}
+
+	s.deleteCache(ctx, cache.KeyAccount(aID))
+
+	if plan != s.accountPlan(a) {
+		s.log.Log(ctx, logger.LvlInfo,
+			"account plan changed by billing event",
+			"account_id", aID,
+			"plan", plan,
+			"previous_plan", s.accountPlan(a),
+			"billing_status", sub.Status)
+	}
+
+	a.Plan = request.FieldString{Set: true, Valid: true, Value: plan}
+
+	return s.enforcePlanLimits(ctx, a)
+}
+
+// errReadOnlyGame returns the error used for changes to games which are read
+// only, because they exceed the game limit of the account plan.
+func errReadOnlyGame(id string) *errors.Error {
+	return errors.New(errors.ErrForbidden,
+		"game is read only because it exceeds the account plan game limit",
+		"id", id).
+		WithReason(errors.ReasonPlanUpgradeRequired)
+}
+
+// enforcePlanLimits makes the games of an account which exceed the game limit
+// of its plan read only, keeping the most recently updated games writable. Any
+// games within the limit which were read only are made writable again.
+func (s *Server) enforcePlanLimits(ctx context.Context, a *Account) error {
+	e := s.accountEntitlements(a)
+
+	c, err := s.collection(ctx, "games")
+	if err != nil {
+		return err
+	}
+
+	cur, err := c.Find(ctx, bson.M{
+		"account_id": a.ID.Value,
+		"status":     request.StatusActive,
+	}, options.Find().SetProjection(bson.M{"_id": 0, "id": 1}).
+		SetSort(bson.D{{Key: "updated_at", Value: -1}}))
+	if err != nil {
+		return errors.Wrap(err, errors.ErrDatabase,
+			"unable to find games to enforce plan limits",
+			"account_id", a.ID.Value)
+	}
+
+	var games []*Game
+
+	if err := cur.All(ctx, &games); err != nil {
+		return errors.Wrap(err, errors.ErrDatabase,
+			"unable to decode games to enforce plan limits",
+			"account_id", a.ID.Value)
+	}
+
+	ids := make([]string, 0, len(games))
+
+	for _, g := range games {
+		ids = append(ids, g.ID.Value)
+	}
+
+	keep, lock := ids, []string{}
+
+	if e.GameLimit > 0 && int64(len(ids)) > e.GameLimit {
+		keep, lock = ids[:e.GameLimit], ids[e.GameLimit:]
+	}
+
+	for _, u := range []struct {
+		ids      []string
+		readOnly bool
+	}{{lock, true}, {keep, false}} {
+		if len(u.ids) == 0 {
+			continue
+		}
+
+		if _, err := c.UpdateMany(ctx, bson.M{
+			"account_id": a.ID.Value,
+			"id":         bson.M{"$in": u.ids},
+			"read_only":  bson.M{"$ne": u.readOnly},
+		}, bson.M{"$set": bson.M{"read_only": u.readOnly}}); err != nil {
+			return errors.Wrap(err, errors.ErrDatabase,
+				"unable to update games to enforce plan limits",
+				"account_id", a.ID.Value,
+				"read_only", u.readOnly)
+		}
+	}
+
+	for _, id := range ids {
+		s.deleteCache(ctx, cache.KeyGame(id))
+	}
+
+	if len(lock) > 0 {
+		s.log.Log(ctx, logger.LvlInfo,
+			"games exceeding plan limit made read only",
+			"account_id", a.ID.Value,
+			"game_limit", e.GameLimit,
+			"read_only", len(lock))
+	}
+
+	return nil
+}
+
+// handleBillingEvent updates accounts in response to a Stripe webhook event.
+// Events of other types are ignored.
+func (s *Server) handleBillingEvent(ctx context.Context,
+	ev *stripeEvent,
+) error {
+	switch ev.Type {
+	case stripeCheckoutCompleted:
+		var co stripeCheckout
+
+		if err := json.Unmarshal(ev.Data.Object, &co); err != nil {
+			return errors.Wrap(err, errors.ErrInvalidRequest,
+				"unable to decode checkout session",
+				"event_id", ev.ID)
+		}
+
+		if co.Customer == "" {
+			return nil
+		}
+
+		aID, err := s.billingAccountID(ctx, co.Customer,
+			co.ClientReferenceID)
+		if err != nil {
+			s.log.Log(ctx, logger.LvlWarn,
+				"unable to find account for checkout session",
+				"error", err,
+				"event_id", ev.ID,
+				"customer", co.Customer,
+				"account_id", co.ClientReferenceID)
+
+			return err
+		}
+
+		return s.updateBillingCustomer(ctx, aID, co.Customer)
+	case stripeSubscriptionCreated, stripeSubscriptionUpdated,
+		stripeSubscriptionDeleted:
+		var sub stripeSubscription
+
+		if err := json.Unmarshal(ev.Data.Object, &sub); err != nil {
+			return errors.Wrap(err, errors.ErrInvalidRequest,
+				"unable to decode subscription",
+				"event_id", ev.ID)
+		}
+
+		return s.updateSubscription(ctx, &sub,
+			ev.Type == stripeSubscriptionDeleted)
+	}
+
+	return nil
+}
+
+// createBillingPortal creates a Stripe billing portal session for the current
+// account, which is used to manage its subscription.
+func (s *Server) createBillingPortal(ctx context.Context,
+) (*BillingPortal, error) {
+	a, err := s.getAccount(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	if a.BillingCustomer.Value == "" {
+		return nil, errors.New(errors.ErrNotFound,
+			"account has no billing customer",
+			"account_id", a.ID.Value)
+	}
+
+	form := url.Values{"customer": {a.BillingCustomer.Value}}
+
+	if u := s.cfg.BillingPortalReturnURL(); u != "" {
+		form.Set("return_url", u)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		StripeAPI+"/v1/billing_portal/sessions",
+		strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrClient,
+			"unable to create billing portal request",
+			"account_id", a.ID.Value)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+s.cfg.BillingStripeKey())
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	cli := &http.Client{Timeout: s.cfg.ServerOutboundTimeout()}
+
+	res, err := cli.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrClient,
+			"unable to send billing portal request",
+			"account_id", a.ID.Value)
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, errors.New(errors.ErrClient,
+			"unexpected billing portal response status",
+			"account_id", a.ID.Value,
+			"status", res.StatusCode)
+	}
+
+	bp := &BillingPortal{}
+
+	if err := json.NewDecoder(res.Body).Decode(bp); err != nil {
+		return nil, errors.Wrap(err, errors.ErrClient,
+			"unable to decode billing portal response",
+			"account_id", a.ID.Value)
+	}
+
+	return bp, nil
+}
+
+// billingHandler performs routing for billing requests.
+func (s *Server) billingHandler() http.Handler {
+	r := chi.NewRouter()
+
+	r.Use(s.dbAvail)
+
+	r.With(s.stat, s.trace).Post("/webhook", s.postBillingWebhookHandler)
+	r.With(s.stat, s.trace, s.auth).Post("/portal",
+		s.postBillingPortalHandler)
+
+	return r
+}
+
+// postBillingWebhookHandler is the post handler used to receive Stripe
+// webhook events.
+func (s *Server) postBillingWebhookHandler(w http.ResponseWriter,
+	r *http.Request,
+) {
+	ctx := r.Context()
+
+	if s.cfg.BillingWebhookSecret() == "" {
+		s.error(errors.New(errors.ErrUnavailable,
+			"billing is not configured"), w, r)
+
+		return
+	}
+
+	// One byte more than the limit is read, so that events which are too
+	// large are rejected, rather than truncated.
+	b, err := io.ReadAll(io.LimitReader(r.Body, maxBillingEventSize+1))
+
+	var mbe *http.MaxBytesError
+
+	if errors.As(err, &mbe) || len(b) > maxBillingEventSize {
+		s.error(errors.New(errors.ErrTooLarge,
+			"billing event too large",
+			"limit", maxBillingEventSize).
+			WithReason(errors.ReasonRequestTooLarge), w, r)
+
+		return
+	}
+
+	if err != nil {
+		s.error(errors.Wrap(err, errors.ErrInvalidRequest,
+			"unable to read billing event"), w, r)
+
+		return
+	}
+
+	if err := s.verifyStripeSignature(r.Header.Get(stripeSignatureHeader),
+		b); err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	ev := &stripeEvent{}
+
+	if err := json.Unmarshal(b, ev); err != nil {
+		s.error(errors.Wrap(err, errors.ErrInvalidRequest,
+			"unable to decode billing event"), w, r)
+
+		return
+	}
+
+	if err := s.handleBillingEvent(ctx, ev); err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// postBillingPortalHandler is the post handler used to create a link to the
+// billing portal of the current account.
+func (s *Server) postBillingPortalHandler(w http.ResponseWriter,
+	r *http.Request,
+) {
+	ctx := r.Context()
+
+	if err := s.checkScope(ctx, request.ScopeAccountAdmin); err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	if s.cfg.BillingStripeKey() == "" {
+		s.error(errors.New(errors.ErrUnavailable,
+			"billing is not configured"), w, r)
+
+		return
+	}
+
+	res, err := s.createBillingPortal(ctx)
+	if err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+
+	if err := s.encode(w, r, res); err != nil {
+		s.error(err, w, r)
+	}
+}
@@ -0,0 +1,114 @@
+package server
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/dhaifley/game2d/config"
+)
+
+func testStripeSignature(secret string, t time.Time, body []byte) string {
+	ts := strconv.FormatInt(t.Unix(), 10)
+
+	h := hmac.New(sha256.New, []byte(secret))
+
+	h.Write([]byte(ts + "."))
+	h.Write(body)
+
+	return "t=" + ts + "," + stripeSignatureScheme + "=" +
+		hex.EncodeToString(h.Sum(nil))
+}
+
+func TestPostBillingWebhook(t *testing.T) {
+	t.Parallel()
+
+	cfg := config.NewDefault()
+
+	cfg.SetBilling(&config.BillingConfig{
+		WebhookSecret:    "test",
+		WebhookTolerance: 5 * time.Minute,
+	})
+
+	svr, err := NewServer(cfg, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ev := []byte(`{"id":"evt_test","type":"test.event"}`)
+
+	// The server has no database, so no account can be found for the checkout
+	// session, and an error is returned, so that the event is retried.
+	co := []byte(`{"id":"evt_test","type":"checkout.session.completed",` +
+		`"data":{"object":{"client_reference_id":"test",` +
+		`"customer":"cus_test"}}}`)
+
+	tests := []struct {
+		name   string
+		body   []byte
+		sig    string
+		status int
+	}{{
+		name:   "valid",
+		body:   ev,
+		sig:    testStripeSignature("test", time.Now(), ev),
+		status: http.StatusNoContent,
+	}, {
+		name:   "clock skew",
+		body:   ev,
+		sig:    testStripeSignature("test", time.Now().Add(time.Minute), ev),
+		status: http.StatusNoContent,
+	}, {
+		name:   "checkout account not found",
+		body:   co,
+		sig:    testStripeSignature("test", time.Now(), co),
+		status: http.StatusServiceUnavailable,
+	}, {
+		name:   "expired",
+		body:   ev,
+		sig:    testStripeSignature("test", time.Now().Add(-time.Hour), ev),
+		status: http.StatusUnauthorized,
+	}, {
+		name:   "too far in the future",
+		body:   ev,
+		sig:    testStripeSignature("test", time.Now().Add(time.Hour), ev),
+		status: http.StatusUnauthorized,
+	}, {
+		name:   "invalid signature",
+		body:   ev,
+		sig:    testStripeSignature("invalid", time.Now(), ev),
+		status: http.StatusUnauthorized,
+	}, {
+		name: "too large",
+		body: bytes.Repeat([]byte(" "), maxBillingEventSize+1),
+		sig: testStripeSignature("test", time.Now(),
+			bytes.Repeat([]byte(" "), maxBillingEventSize)),
+		status: http.StatusRequestEntityTooLarge,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			r := httptest.NewRequest(http.MethodPost,
+				"/api/v1/billing/webhook", bytes.NewReader(tt.body))
+
+			r.Header.Set(stripeSignatureHeader, tt.sig)
+
+			w := httptest.NewRecorder()
+
+			svr.postBillingWebhookHandler(w, r)
+
+			if w.Code != tt.status {
+				t.Errorf("Expected status: %v, got: %v, body: %s",
+					tt.status, w.Code, w.Body.String())
+			}
+		})
+	}
+}
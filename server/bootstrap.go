@@ -0,0 +1,215 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dhaifley/game2d/errors"
+	"github.com/dhaifley/game2d/logger"
+	"github.com/dhaifley/game2d/request"
+	"github.com/google/uuid"
+)
+
+// BootstrapRequest values describe the initial account and superuser to be
+// created by bootstrapping. Missing values use the configured account, and a
+// superuser named admin.
+type BootstrapRequest struct {
+	AccountID   string `json:"account_id"   yaml:"account_id"`
+	AccountName string `json:"account_name" yaml:"account_name"`
+	UserID      string `json:"user_id"      yaml:"user_id"`
+	Password    string `json:"password"     yaml:"password"`
+}
+
+// Bootstrap values contain the credentials created by bootstrapping. The
+// password is only returned if it was generated for a new superuser.
+type Bootstrap struct {
+	AccountID   string `json:"account_id"         yaml:"account_id"`
+	AccountName string `json:"account_name"       yaml:"account_name"`
+	UserID      string `json:"user_id"            yaml:"user_id"`
+	Password    string `json:"password,omitempty" yaml:"password,omitempty"`
+	Created     bool   `json:"created"            yaml:"created"`
+	APIKey      string `json:"api_key"            yaml:"api_key"`
+	TokenType   string `json:"token_type"         yaml:"token_type"`
+	ExpiresAt   int64  `json:"expires_at"         yaml:"expires_at"`
+}
+
+// defaultBootstrapUser is the ID of the superuser created by bootstrapping,
+// if none is requested.
+const defaultBootstrapUser = "admin"
+
+// checkBootstrapToken returns an error if a request does not contain the
+// configured bootstrap token.
+func (s *Server) checkBootstrapToken(r *http.Request) error {
+	bt := s.cfg.AuthBootstrapToken()
+	if bt == "" {
+		return errors.New(errors.ErrUnavailable,
+			"bootstrap is not configured")
+	}
+
+	tok, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(tok), []byte(bt)) != 1 {
+		return errors.New(errors.ErrUnauthorized,
+			"invalid bootstrap token")
+	}
+
+	return nil
+}
+
+// bootstrap idempotently creates an account, a superuser within it, and an
+// API key for the superuser. An existing account and superuser are retained,
+// and their password is only changed if one is requested.
+func (s *Server) bootstrap(ctx context.Context,
+	req *BootstrapRequest,
+) (*Bootstrap, error) {
+	if req.AccountID == "" {
+		req.AccountID = s.cfg.AccountID()
+	}
+
+	if req.AccountName == "" {
+		req.AccountName = s.cfg.AccountName()
+	}
+
+	if req.UserID == "" {
+		req.UserID = defaultBootstrapUser
+	}
+
+	if !request.ValidAccountID(req.AccountID) {
+		return nil, errors.New(errors.ErrInvalidRequest,
+			"invalid account_id",
+			"account_id", req.AccountID)
+	}
+
+	if !request.ValidUserID(req.UserID) {
+		return nil, errors.New(errors.ErrInvalidRequest,
+			"invalid user_id",
+			"user_id", req.UserID)
+	}
+
+	ctx = context.WithValue(ctx, request.CtxKeyScopes,
+		request.ScopeSuperuser)
+	ctx = context.WithValue(ctx, request.CtxKeyAccountID, req.AccountID)
+	ctx = context.WithValue(ctx, request.CtxKeyUserID, request.SystemUser)
+
+	a, err := s.createAccount(ctx, &Account{
+		ID: request.FieldString{
+			Set: true, Valid: true, Value: req.AccountID,
+		},
+		Name: request.FieldString{
+			Set: true, Valid: true, Value: req.AccountName,
+		},
+		Secret: request.FieldString{
+			Set: true, Valid: true, Value: uuid.NewString(),
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	res := &Bootstrap{
+		AccountID:   a.ID.Value,
+		AccountName: a.Name.Value,
+		UserID:      req.UserID,
+		TokenType:   "bearer",
+	}
+
+	u, err := s.getUser(ctx, req.UserID)
+	if err != nil && !errors.Has(err, errors.ErrNotFound) {
+		return nil, err
+	}
+
+	res.Created = u == nil
+
+	var pw *string
+
+	switch {
+	case req.Password != "":
+		pw = &req.Password
+	case res.Created:
+		b := make([]byte, 16)
+
+		if _, err := rand.Read(b); err != nil {
+			return nil, errors.Wrap(err, errors.ErrServer,
+				"unable to generate password")
+		}
+
+		res.Password = hex.EncodeToString(b)
+		pw = &res.Password
+	}
+
+	if _, err := s.createUser(ctx, &User{
+		AccountID: request.FieldString{
+			Set: true, Valid: true, Value: a.ID.Value,
+		},
+		ID: request.FieldString{
+			Set: true, Valid: true, Value: req.UserID,
+		},
+		Scopes: request.FieldString{
+			Set: true, Valid: true, Value: request.ScopeSuperuser,
+		},
+		Password: pw,
+	}); err != nil {
+		return nil, err
+	}
+
+	res.ExpiresAt = time.Now().Add(s.cfg.AuthAPIKeyExpiresIn()).Unix()
+
+	res.APIKey, err = s.createToken(ctx, req.UserID, res.ExpiresAt,
+		request.ScopeSuperuser, a.ID.Value)
+	if err != nil {
+		return nil, err
+	}
+
+	s.log.Log(ctx, logger.LvlInfo,
+		"bootstrapped account and superuser",
+		"account_id", a.ID.Value,
+		"user_id", req.UserID,
+		"created", res.Created)
+
+	return res, nil
+}
+
+// postBootstrapHandler is the post handler used to bootstrap the initial
+// account, superuser and API key. It is authorized using the bootstrap token,
+// rather than a user token, so that it can be called by provisioning tools.
+func (s *Server) postBootstrapHandler(w http.ResponseWriter,
+	r *http.Request,
+) {
+	ctx := r.Context()
+
+	if err := s.checkBootstrapToken(r); err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	req := &BootstrapRequest{}
+
+	if r.ContentLength != 0 {
+		if err := s.decode(r, &req); err != nil {
+			s.error(errors.Wrap(err, errors.ErrInvalidRequest,
+				"unable to decode request"), w, r)
+
+			return
+		}
+	}
+
+	res, err := s.bootstrap(ctx, req)
+	if err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	if res.Created {
+		w.WriteHeader(http.StatusCreated)
+	}
+
+	if err := s.encode(w, r, res); err != nil {
+		s.error(err, w, r)
+	}
+}
@@ -0,0 +1,206 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/dhaifley/game2d/errors"
+	"github.com/dhaifley/game2d/logger"
+	"github.com/dhaifley/game2d/request"
+)
+
+// ContentTypeNDJSON is the content type of newline-delimited JSON.
+const ContentTypeNDJSON = "application/x-ndjson"
+
+// Bulk operation types.
+const (
+	BulkOpCreate = "create"
+	BulkOpUpdate = "update"
+	BulkOpDelete = "delete"
+)
+
+// Bulk operation result statuses.
+const (
+	BulkStatusApplied = "applied"
+	BulkStatusFailed  = "failed"
+)
+
+// BulkOperation values represent a single operation in a bulk request.
+type BulkOperation struct {
+	Op   string `json:"op"`
+	ID   string `json:"id,omitempty"`
+	Game *Game  `json:"game,omitempty"`
+}
+
+// BulkResult values represent the result of a single bulk operation.
+type BulkResult struct {
+	Line   int           `json:"line"`
+	Op     string        `json:"op,omitempty"`
+	ID     string        `json:"id,omitempty"`
+	Status string        `json:"status"`
+	Error  *errors.Error `json:"error,omitempty"`
+}
+
+// bulkGame applies a single bulk operation, returning the ID of the game
+// affected by the operation.
+func (s *Server) bulkGame(ctx context.Context,
+	op *BulkOperation,
+) (string, error) {
+	aID, err := request.ContextAccountID(ctx)
+	if err != nil {
+		return "", errors.New(errors.ErrUnauthorized,
+			"unable to get account id from context")
+	}
+
+	switch op.Op {
+	case BulkOpCreate:
+		if op.Game == nil {
+			return "", errors.New(errors.ErrInvalidRequest,
+				"missing game")
+		}
+
+		if op.ID != "" {
+			op.Game.ID = request.FieldString{
+				Set: true, Valid: true, Value: op.ID,
+			}
+		}
+
+		op.Game.AccountID = request.FieldString{
+			Set: true, Valid: true, Value: aID,
+		}
+
+		if err := s.checkRequestTags(ctx, op.Game); err != nil {
+			return op.ID, err
+		}
+
+		res, err := s.createGame(ctx, op.Game)
+		if err != nil {
+			return op.ID, err
+		}
+
+		return res.ID.Value, nil
+	case BulkOpUpdate:
+		if op.Game == nil {
+			return op.ID, errors.New(errors.ErrInvalidRequest,
+				"missing game")
+		}
+
+		if op.ID == "" {
+			op.ID = op.Game.ID.Value
+		}
+
+		op.Game.ID = request.FieldString{
+			Set: true, Valid: true, Value: op.ID,
+		}
+
+		if err := s.checkRequestTags(ctx, op.Game); err != nil {
+			return op.ID, err
+		}
+
+		res, err := s.updateGame(ctx, op.Game)
+		if err != nil {
+			return op.ID, err
+		}
+
+		return res.ID.Value, nil
+	case BulkOpDelete:
+		if op.ID == "" && op.Game != nil {
+			op.ID = op.Game.ID.Value
+		}
+
+		return op.ID, s.deleteGame(ctx, op.ID)
+	default:
+		return op.ID, errors.New(errors.ErrInvalidRequest,
+			"invalid bulk operation",
+			"op", op.Op)
+	}
+}
+
+// postGamesBulkHandler is the post handler used to create, update, and delete
+// games in bulk. The request body contains newline-delimited JSON operations,
+// which are applied in order. A result for each operation is written to the
+// newline-delimited JSON response as it is applied, and a failed operation
+// does not prevent subsequent operations from being applied.
+func (s *Server) postGamesBulkHandler(w http.ResponseWriter,
+	r *http.Request,
+) {
+	ctx := r.Context()
+
+	if err := s.checkScope(ctx, request.ScopeGamesWrite); err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	if qp := r.URL.Query().Get("allow_tags"); qp != "" && qp != "0" &&
+		!strings.EqualFold(qp, "false") && !strings.EqualFold(qp, "f") {
+		ctx = context.WithValue(ctx, CtxKeyGameAllowTags, true)
+	}
+
+	w.Header().Set("Content-Type", ContentTypeNDJSON+"; charset=utf-8")
+
+	enc := json.NewEncoder(w)
+
+	flusher, _ := w.(http.Flusher)
+
+	br := bufio.NewReader(r.Body)
+
+	for line := 1; ; line++ {
+		b, err := br.ReadBytes('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			s.log.Log(ctx, logger.LvlError,
+				"unable to read bulk request",
+				"error", err,
+				"line", line)
+
+			return
+		}
+
+		if b = bytes.TrimSpace(b); len(b) > 0 {
+			res := &BulkResult{Line: line, Status: BulkStatusApplied}
+
+			op := &BulkOperation{}
+
+			var opErr error
+
+			if dErr := json.Unmarshal(b, op); dErr != nil {
+				opErr = errors.Wrap(dErr, errors.ErrInvalidRequest,
+					"unable to decode bulk operation")
+			} else {
+				res.Op = op.Op
+				res.ID, opErr = s.bulkGame(ctx, op)
+			}
+
+			if opErr != nil {
+				e, ok := opErr.(*errors.Error)
+				if !ok {
+					e = errors.Wrap(opErr, errors.ErrServer, opErr.Error())
+				}
+
+				res.Status, res.Error = BulkStatusFailed, e
+			}
+
+			if err := enc.Encode(res); err != nil {
+				s.log.Log(ctx, logger.LvlError,
+					"unable to write bulk response",
+					"error", err,
+					"line", line)
+
+				return
+			}
+
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+
+		if err != nil || ctx.Err() != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,27 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/dhaifley/game2d/errors"
+	"github.com/go-chi/chi/v5"
+)
+
+// errorsHandler performs routing for error information requests.
+func (s *Server) errorsHandler() http.Handler {
+	r := chi.NewRouter()
+
+	r.With(s.stat, s.trace).Get("/catalog", s.getErrorCatalogHandler)
+
+	return r
+}
+
+// getErrorCatalogHandler is the get handler used to retrieve the catalog of
+// stable error reasons which may be returned by the API.
+func (s *Server) getErrorCatalogHandler(w http.ResponseWriter,
+	r *http.Request,
+) {
+	if err := s.encode(w, r, errors.Catalog); err != nil {
+		s.error(err, w, r)
+	}
+}
@@ -0,0 +1,800 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dhaifley/game2d/cache"
+	"github.com/dhaifley/game2d/errors"
+	"github.com/dhaifley/game2d/logger"
+	"github.com/dhaifley/game2d/request"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// Game revision chains link each game to the revision it was created from,
+// using previous_id. Chains maintain these invariants:
+//
+//   - Each chain has a single head, which is the only game in the chain that
+//     is not inactive.
+//   - Chains are at most two revisions deep. The previous revision of a head
+//     either has no previous_id, or refers back to the head, after an undo.
+//   - Every inactive revision is referred to by a head, or it is an orphan.
+//
+// Chains are only changed using the functions in this file, which run within
+// a database transaction, if supported, and hold a lock on the account chains.
+
+// Game revision chain repair problems.
+const (
+	ChainDanglingPrevious = "dangling_previous"
+	ChainMultipleHeads    = "multiple_heads"
+	ChainMultipleActive   = "multiple_active"
+	ChainTooDeep          = "too_deep"
+	ChainOrphan           = "orphan"
+)
+
+// Game revision chain repair actions.
+const (
+	ChainActionClearPrevious = "clear_previous"
+	ChainActionDeactivate    = "deactivate"
+	ChainActionDelete        = "delete"
+)
+
+// ChainIssue values describe a problem found in a game revision chain, and
+// the action taken to repair it.
+type ChainIssue struct {
+	AccountID string `json:"account_id" yaml:"account_id"`
+	GameID    string `json:"game_id"    yaml:"game_id"`
+	Problem   string `json:"problem"    yaml:"problem"`
+	Action    string `json:"action"     yaml:"action"`
+}
+
+// ChainRepair values contain the result of checking and repairing the game
+// revision chains of all accounts.
+type ChainRepair struct {
+	DryRun   bool          `json:"dry_run"  yaml:"dry_run"`
+	Accounts int           `json:"accounts" yaml:"accounts"`
+	Games    int           `json:"games"    yaml:"games"`
+	Issues   []*ChainIssue `json:"issues"   yaml:"issues"`
+}
+
+// chainProjection contains the game fields used to maintain chains.
+var chainProjection = bson.M{
+	"_id":         0,
+	"account_id":  1,
+	"id":          1,
+	"status":      1,
+	"previous_id": 1,
+	"prompts":     1,
+	"updated_at":  1,
+}
+
+// lockChains locks the game revision chains of an account, and returns a
+// function used to unlock them. The lock only serializes changes made by this
+// server, so changes also rely on transactions and conditional updates.
+func (s *Server) lockChains(accountID string) func() {
+	v, _ := s.chainLocks.LoadOrStore(accountID, &sync.Mutex{})
+
+	mu := v.(*sync.Mutex)
+
+	mu.Lock()
+
+	return mu.Unlock
+}
+
+// transactionsUnsupported checks whether an error was caused by the database
+// not supporting transactions, such as a standalone server.
+func transactionsUnsupported(err error) bool {
+	var se mongo.ServerError
+
+	return errors.As(err, &se) && (se.HasErrorCode(20) ||
+		strings.Contains(err.Error(), "Transaction numbers are only allowed"))
+}
+
+// withTransaction runs a function within a database transaction. If the
+// database does not support transactions, the function is run without one.
+func (s *Server) withTransaction(ctx context.Context,
+	fn func(ctx context.Context) error,
+) error {
+	sess, err := s.DB().Client().StartSession()
+	if err != nil {
+		return errors.Wrap(err, errors.ErrDatabase,
+			"unable to start database session")
+	}
+
+	defer sess.EndSession(context.WithoutCancel(ctx))
+
+	_, err = sess.WithTransaction(ctx, func(ctx context.Context) (any, error) {
+		return nil, fn(ctx)
+	})
+	if err != nil && transactionsUnsupported(err) {
+		return fn(ctx)
+	}
+
+	return err
+}
+
+// getChainGame retrieves the fields of a game used to maintain chains
+// directly from the database. It returns nil if the game is not found.
+func (s *Server) getChainGame(ctx context.Context,
+	accountID, id string,
+) (*Game, error) {
+	var res *Game
+
+	f := bson.M{"account_id": accountID, "id": id}
+
+	c, err := s.collection(ctx, "games")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.FindOne(ctx, f,
+		options.FindOne().SetProjection(chainProjection)).
+		Decode(&res); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+
+		return nil, errors.Wrap(err, errors.ErrDatabase,
+			"unable to get chain game",
+			"id", id)
+	}
+
+	return res, nil
+}
+
+// setChainGame updates the chain fields of a game, if its status is still the
+// status expected. It returns whether the game was updated.
+func (s *Server) setChainGame(ctx context.Context,
+	accountID, id, status string,
+	set bson.M,
+) (bool, error) {
+	uID, _ := request.ContextUserID(ctx)
+
+	set["updated_at"] = time.Now().Unix()
+	set["updated_by"] = uID
+
+	f := bson.M{"account_id": accountID, "id": id, "status": status}
+
+	c, err := s.collection(ctx, "games")
+	if err != nil {
+		return false, err
+	}
+
+	res, err := c.UpdateOne(ctx, f, bson.D{
+		{Key: "$set", Value: set},
+		{Key: "$inc", Value: bson.M{"revision": 1}},
+	})
+	if err != nil {
+		return false, errors.Wrap(err, errors.ErrDatabase,
+			"unable to update chain game",
+			"id", id)
+	}
+
+	return res.MatchedCount > 0, nil
+}
+
+// deleteChainGame deletes a game which is no longer part of a chain.
+func (s *Server) deleteChainGame(ctx context.Context,
+	accountID, id string,
+) error {
+	f := bson.M{"account_id": accountID, "id": id}
+
+	c, err := s.collection(ctx, "games")
+	if err != nil {
+		return err
+	}
+
+	if _, err := c.DeleteOne(ctx, f); err != nil {
+		return errors.Wrap(err, errors.ErrDatabase,
+			"unable to delete chain game",
+			"id", id)
+	}
+
+	return nil
+}
+
+// chainPrompts returns the prompts of a game, with the game ID of the prompts
+// replaced, so that they refer to another revision of the game.
+func chainPrompts(g *Game, gameID string) (request.FieldJSON, error) {
+	prompts, err := promptsFromFieldJSON(g.Prompts)
+	if err != nil {
+		return request.FieldJSON{}, errors.Wrap(err, errors.ErrDatabase,
+			"unable to decode chain game prompts",
+			"id", g.ID.Value)
+	}
+
+	if prompts == nil {
+		prompts = &Prompts{}
+	}
+
+	prompts.GameID = request.FieldString{
+		Set: true, Valid: true, Value: gameID,
+	}
+
+	return promptsToFieldJSON(prompts)
+}
+
+// gameRevision creates a new revision of a game, with a status and prompts,
+// which refers to the game as its previous revision. The revision must be
+// created, which links it into the chain of the game.
+func gameRevision(g *Game, status string, prompts request.FieldJSON) *Game {
+	return &Game{
+		AccountID: g.AccountID,
+		Debug:     g.Debug,
+		Public:    g.Public,
+		Pause:     g.Pause,
+		W:         g.W,
+		H:         g.H,
+		PreviousID: request.FieldString{
+			Set: true, Valid: true, Value: g.ID.Value,
+		},
+		Name:        g.Name,
+		Version:     g.Version,
+		Description: g.Description,
+		Icon:        g.Icon,
+		Controls:    g.Controls,
+		Status: request.FieldString{
+			Set: true, Valid: true, Value: status,
+		},
+		StatusData: g.StatusData.Copy(),
+		Subject:    g.Subject.Copy(),
+		Objects:    g.Objects.Copy(),
+		Images:     g.Images.Copy(),
+		Script:     g.Script,
+		Bindings:   g.Bindings.Copy(),
+		Locales:    g.Locales.Copy(),
+		Source: request.FieldString{
+			Set: true, Valid: true, Value: "app",
+		},
+		Tags: request.FieldStringArray{
+			Set: g.Tags.Set, Valid: g.Tags.Valid,
+			Value: slices.Clone(g.Tags.Value),
+		},
+		Prompts: prompts,
+	}
+}
+
+// chainChange values describe a change to the status of a game in a chain.
+type chainChange struct {
+	id, from, to string
+}
+
+// chainChanged removes changed games from the cache, and calls the status
+// hooks of games with a changed status, after a chain change is saved.
+func (s *Server) chainChanged(ctx context.Context,
+	accountID string,
+	deleted []string,
+	changes []chainChange,
+) {
+	for _, id := range deleted {
+		s.deleteCache(ctx, cache.KeyGame(id))
+	}
+
+	for _, c := range changes {
+		s.deleteCache(ctx, cache.KeyGame(c.id))
+
+		if c.from == c.to {
+			continue
+		}
+
+		s.gameStatusChanged(ctx, &Game{
+			AccountID: request.FieldString{
+				Set: true, Valid: true, Value: accountID,
+			},
+			ID:     request.FieldString{Set: true, Valid: true, Value: c.id},
+			Status: request.FieldString{Set: true, Valid: true, Value: c.to},
+		}, c.from)
+	}
+}
+
+// linkRevision links a newly created head revision to its previous revision.
+// The previous revision is made inactive, and its own previous revision is
+// deleted, keeping the chain bounded. Missing previous revisions are ignored,
+// since games may be restored in any order. If another head was linked to the
+// same previous revision concurrently, a conflict error is returned, and the
+// head is deleted, if it was created, so that it is not left unlinked.
+func (s *Server) linkRevision(ctx context.Context,
+	head *Game,
+	created bool,
+) error {
+	aID, pID := head.AccountID.Value, head.PreviousID.Value
+
+	if pID == "" {
+		return nil
+	}
+
+	if pID == head.ID.Value {
+		return errors.New(errors.ErrInvalidRequest,
+			"game may not be its own previous game",
+			"id", head.ID.Value)
+	}
+
+	unlock := s.lockChains(aID)
+	defer unlock()
+
+	var (
+		deleted []string
+		changes []chainChange
+	)
+
+	if err := s.withTransaction(ctx, func(ctx context.Context) error {
+		deleted, changes = nil, nil
+
+		pg, err := s.getChainGame(ctx, aID, pID)
+		if err != nil {
+			return err
+		}
+
+		if pg == nil {
+			return nil
+		}
+
+		c, err := s.collection(ctx, "games")
+		if err != nil {
+			return err
+		}
+
+		n, err := c.CountDocuments(ctx, bson.M{
+			"account_id":  aID,
+			"previous_id": pID,
+			"status":      bson.M{"$ne": request.StatusInactive},
+			"id":          bson.M{"$ne": head.ID.Value},
+		})
+		if err != nil {
+			return errors.Wrap(err, errors.ErrDatabase,
+				"unable to count chain heads",
+				"previous_id", pID)
+		}
+
+		if n > 0 {
+			return errors.New(errors.ErrConflict,
+				"previous game already has a newer revision",
+				"id", head.ID.Value,
+				"previous_id", pID)
+		}
+
+		if pg.Status.Value == request.StatusInactive {
+			return nil
+		}
+
+		prompts, err := chainPrompts(pg, head.ID.Value)
+		if err != nil {
+			return err
+		}
+
+		set := bson.M{
+			"status":  request.StatusInactive,
+			"prompts": prompts,
+		}
+
+		if gp := pg.PreviousID.Value; gp != "" && gp != head.ID.Value {
+			if err := s.deleteChainGame(ctx, aID, gp); err != nil {
+				return err
+			}
+
+			deleted = append(deleted, gp)
+
+			set["previous_id"] = nil
+		}
+
+		if ok, err := s.setChainGame(ctx, aID, pID, pg.Status.Value,
+			set); err != nil {
+			return err
+		} else if !ok {
+			return errors.New(errors.ErrConflict,
+				"previous game changed during revision",
+				"id", head.ID.Value,
+				"previous_id", pID)
+		}
+
+		changes = append(changes, chainChange{
+			pID, pg.Status.Value, request.StatusInactive,
+		})
+
+		return nil
+	}); err != nil {
+		if created {
+			if derr := s.deleteChainGame(ctx, aID,
+				head.ID.Value); derr != nil {
+				s.log.Log(ctx, logger.LvlError,
+					"unable to delete unlinked game revision",
+					"error", derr,
+					"id", head.ID.Value)
+			}
+
+			s.deleteCache(ctx, cache.KeyGame(head.ID.Value))
+		}
+
+		return err
+	}
+
+	s.chainChanged(ctx, aID, deleted, changes)
+
+	return nil
+}
+
+// undoRevision makes the previous revision of a game the head of its chain,
+// and the game an inactive revision which refers to it. It returns the ID of
+// the new head.
+func (s *Server) undoRevision(ctx context.Context, id string) (string, error) {
+	aID, err := request.ContextAccountID(ctx)
+	if err != nil {
+		return "", errors.New(errors.ErrUnauthorized,
+			"unable to get account id from context")
+	}
+
+	unlock := s.lockChains(aID)
+	defer unlock()
+
+	var (
+		pID     string
+		deleted []string
+		changes []chainChange
+	)
+
+	if err := s.withTransaction(ctx, func(ctx context.Context) error {
+		deleted, changes = nil, nil
+
+		g, err := s.getChainGame(ctx, aID, id)
+		if err != nil {
+			return err
+		}
+
+		if g == nil {
+			return errors.New(errors.ErrNotFound,
+				"game not found to undo",
+				"id", id)
+		}
+
+		pID = g.PreviousID.Value
+
+		if pID == "" {
+			return errors.New(errors.ErrInvalidRequest,
+				"unable to undo game, no previous game",
+				"id", id)
+		}
+
+		pg, err := s.getChainGame(ctx, aID, pID)
+		if err != nil {
+			return err
+		}
+
+		if pg == nil {
+			return errors.New(errors.ErrNotFound,
+				"previous game not found",
+				"id", id,
+				"previous_id", pID)
+		}
+
+		if err := checkGameStatusTransition(id, g.Status.Value,
+			request.StatusInactive); err != nil {
+			return err
+		}
+
+		if err := checkGameStatusTransition(pID, pg.Status.Value,
+			request.StatusActive); err != nil {
+			return err
+		}
+
+		if ok, err := s.setChainGame(ctx, aID, id, g.Status.Value, bson.M{
+			"status":      request.StatusInactive,
+			"previous_id": nil,
+		}); err != nil {
+			return err
+		} else if !ok {
+			return errors.New(errors.ErrConflict,
+				"game changed during undo",
+				"id", id)
+		}
+
+		if gp := pg.PreviousID.Value; gp != "" && gp != id {
+			if err := s.deleteChainGame(ctx, aID, gp); err != nil {
+				return err
+			}
+
+			deleted = append(deleted, gp)
+		}
+
+		if ok, err := s.setChainGame(ctx, aID, pID, pg.Status.Value, bson.M{
+			"status":      request.StatusActive,
+			"previous_id": id,
+		}); err != nil {
+			return err
+		} else if !ok {
+			return errors.New(errors.ErrConflict,
+				"previous game changed during undo",
+				"id", id,
+				"previous_id", pID)
+		}
+
+		changes = append(changes,
+			chainChange{id, g.Status.Value, request.StatusInactive},
+			chainChange{pID, pg.Status.Value, request.StatusActive})
+
+		return nil
+	}); err != nil {
+		return "", err
+	}
+
+	s.chainChanged(ctx, aID, deleted, changes)
+
+	return pID, nil
+}
+
+// checkChains finds the problems in the game revision chains of the current
+// account, and the actions which repair them.
+func (s *Server) checkChains(ctx context.Context,
+) ([]*ChainIssue, int, error) {
+	aID, err := request.ContextAccountID(ctx)
+	if err != nil {
+		return nil, 0, errors.New(errors.ErrUnauthorized,
+			"unable to get account id from context")
+	}
+
+	c, err := s.collection(ctx, "games")
+	if err != nil {
+		return nil, 0, err
+	}
+
+	cur, err := c.Find(ctx,
+		bson.M{"account_id": aID},
+		options.Find().SetProjection(chainProjection))
+	if err != nil {
+		return nil, 0, errors.Wrap(err, errors.ErrDatabase,
+			"unable to find chain games",
+			"account_id", aID)
+	}
+
+	var games []*Game
+
+	if err := cur.All(ctx, &games); err != nil {
+		return nil, 0, errors.Wrap(err, errors.ErrDatabase,
+			"unable to decode chain games",
+			"account_id", aID)
+	}
+
+	byID := make(map[string]*Game, len(games))
+
+	for _, g := range games {
+		byID[g.ID.Value] = g
+	}
+
+	var issues []*ChainIssue
+
+	issue := func(id, problem, action string) {
+		issues = append(issues, &ChainIssue{
+			AccountID: aID,
+			GameID:    id,
+			Problem:   problem,
+			Action:    action,
+		})
+	}
+
+	active := func(g *Game) bool {
+		return g.Status.Value != request.StatusInactive
+	}
+
+	heads := map[string]*Game{}
+
+	for _, g := range games {
+		pID := g.PreviousID.Value
+
+		if pID == "" {
+			continue
+		}
+
+		if _, ok := byID[pID]; !ok || pID == g.ID.Value {
+			issue(g.ID.Value, ChainDanglingPrevious, ChainActionClearPrevious)
+
+			g.PreviousID = request.FieldString{}
+
+			continue
+		}
+
+		if !active(g) {
+			continue
+		}
+
+		if h, ok := heads[pID]; ok {
+			if h.UpdatedAt.Value < g.UpdatedAt.Value {
+				h, g = g, h
+			}
+
+			issue(g.ID.Value, ChainMultipleHeads, ChainActionClearPrevious)
+
+			g.PreviousID = request.FieldString{}
+
+			heads[pID] = h
+
+			continue
+		}
+
+		heads[pID] = g
+	}
+
+	deleted := map[string]bool{}
+
+	for _, pg := range games {
+		pID := pg.ID.Value
+
+		h, ok := heads[pID]
+		if !ok {
+			continue
+		}
+
+		if active(pg) {
+			issue(pID, ChainMultipleActive, ChainActionDeactivate)
+
+			pg.Status = request.FieldString{
+				Set: true, Valid: true, Value: request.StatusInactive,
+			}
+		}
+
+		if gp := pg.PreviousID.Value; gp != "" && gp != h.ID.Value {
+			if gg, ok := byID[gp]; ok && !active(gg) && !deleted[gp] &&
+				heads[gp] == nil {
+				issue(gp, ChainTooDeep, ChainActionDelete)
+
+				deleted[gp] = true
+			}
+
+			issue(pID, ChainTooDeep, ChainActionClearPrevious)
+
+			pg.PreviousID = request.FieldString{}
+		}
+	}
+
+	referenced := map[string]bool{}
+
+	for _, g := range games {
+		if g.PreviousID.Value != "" && !deleted[g.ID.Value] {
+			referenced[g.PreviousID.Value] = true
+		}
+	}
+
+	for _, g := range games {
+		if active(g) || referenced[g.ID.Value] || deleted[g.ID.Value] {
+			continue
+		}
+
+		if next, _ := g.Prompts.Value["game_id"].(string); next != "" &&
+			next != g.ID.Value {
+			issue(g.ID.Value, ChainOrphan, ChainActionDelete)
+		}
+	}
+
+	return issues, len(games), nil
+}
+
+// repairChain applies the action which repairs a chain issue.
+func (s *Server) repairChain(ctx context.Context, ci *ChainIssue) error {
+	c, err := s.collection(ctx, "games")
+	if err != nil {
+		return err
+	}
+
+	f := bson.M{"account_id": ci.AccountID, "id": ci.GameID}
+
+	switch ci.Action {
+	case ChainActionDelete:
+		err = s.deleteChainGame(ctx, ci.AccountID, ci.GameID)
+	case ChainActionClearPrevious:
+		_, err = c.UpdateOne(ctx, f,
+			bson.M{"$set": bson.M{"previous_id": nil}})
+	case ChainActionDeactivate:
+		_, err = c.UpdateOne(ctx, f,
+			bson.M{"$set": bson.M{"status": request.StatusInactive}})
+	}
+
+	if err != nil {
+		return errors.Wrap(err, errors.ErrDatabase,
+			"unable to repair game chain",
+			"issue", ci)
+	}
+
+	return nil
+}
+
+// repairChains checks the game revision chains of all accounts, and repairs
+// the problems found, unless it is a dry run.
+func (s *Server) repairChains(ctx context.Context,
+	dryRun bool,
+) (*ChainRepair, error) {
+	accounts, err := s.getAllAccounts(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &ChainRepair{DryRun: dryRun, Issues: []*ChainIssue{}}
+
+	for _, aID := range accounts {
+		actx := context.WithValue(ctx, request.CtxKeyAccountID, aID)
+
+		var (
+			issues []*ChainIssue
+			n      int
+		)
+
+		unlock := s.lockChains(aID)
+
+		err := s.withTransaction(actx, func(ctx context.Context) error {
+			var err error
+
+			issues, n, err = s.checkChains(ctx)
+			if err != nil || dryRun {
+				return err
+			}
+
+			for _, ci := range issues {
+				if err := s.repairChain(ctx, ci); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		})
+
+		unlock()
+
+		if err != nil {
+			return nil, err
+		}
+
+		if !dryRun {
+			for _, ci := range issues {
+				s.deleteCache(actx, cache.KeyGame(ci.GameID))
+			}
+		}
+
+		res.Accounts++
+		res.Games += n
+		res.Issues = append(res.Issues, issues...)
+	}
+
+	if len(res.Issues) > 0 {
+		s.log.Log(ctx, logger.LvlWarn,
+			"game revision chain problems found",
+			"issues", len(res.Issues),
+			"dry_run", dryRun)
+	}
+
+	return res, nil
+}
+
+// postChainsRepairHandler is the post handler used to check, and repair, the
+// game revision chains of all accounts.
+func (s *Server) postChainsRepairHandler(w http.ResponseWriter,
+	r *http.Request,
+) {
+	ctx := r.Context()
+
+	if err := s.checkScope(ctx, request.ScopeSuperuser); err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	dryRun := false
+
+	if qp := r.URL.Query().Get("dry_run"); qp != "" && qp != "0" &&
+		!strings.EqualFold(qp, "false") && !strings.EqualFold(qp, "f") {
+		dryRun = true
+	}
+
+	res, err := s.repairChains(ctx, dryRun)
+	if err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	if err := s.encode(w, r, res); err != nil {
+		s.error(err, w, r)
+	}
+}
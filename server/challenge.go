@@ -0,0 +1,950 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/dhaifley/game2d/errors"
+	"github.com/dhaifley/game2d/request"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// Challenge scoring rules.
+const (
+	ScoringHighest = "highest"
+	ScoringLowest  = "lowest"
+	ScoringTotal   = "total"
+)
+
+// Challenge statuses.
+const (
+	ChallengeUpcoming = "upcoming"
+	ChallengeOpen     = "open"
+	ChallengeEnded    = "ended"
+)
+
+// Challenge limits.
+const (
+	maxChallenges       = 100
+	maxChallengeRanking = 100
+	maxChallengeName    = 256
+	maxChallengeDesc    = 4096
+	maxPlayerName       = 64
+	defaultPlayerName   = "Player"
+)
+
+// Challenge values represent a challenge, in which the players of a game
+// compete for the best score during a time window. Scores are ranked using
+// the scoring rule of the challenge: each player's highest or lowest score,
+// or the total of their scores. When the challenge ends, the players ranked
+// first are recorded as its winners.
+type Challenge struct {
+	AccountID   string            `bson:"account_id"  json:"account_id"        yaml:"account_id"`
+	ID          string            `bson:"id"          json:"id"                yaml:"id"`
+	GameID      string            `bson:"game_id"     json:"game_id"           yaml:"game_id"`
+	Name        string            `bson:"name"        json:"name"              yaml:"name"`
+	Description string            `bson:"description" json:"description"       yaml:"description"`
+	StartAt     int64             `bson:"start_at"    json:"start_at"          yaml:"start_at"`
+	EndAt       int64             `bson:"end_at"      json:"end_at"            yaml:"end_at"`
+	Scoring     string            `bson:"scoring"     json:"scoring"           yaml:"scoring"`
+	Public      bool              `bson:"public"      json:"public"            yaml:"public"`
+	Status      string            `bson:"-"           json:"status"            yaml:"status"`
+	Finalized   bool              `bson:"finalized"   json:"finalized"         yaml:"finalized"`
+	Winners     []*ChallengeEntry `bson:"winners"     json:"winners,omitempty" yaml:"winners,omitempty"`
+	CreatedAt   int64             `bson:"created_at"  json:"created_at"        yaml:"created_at"`
+	CreatedBy   string            `bson:"created_by"  json:"created_by"        yaml:"created_by"`
+	UpdatedAt   int64             `bson:"updated_at"  json:"updated_at"        yaml:"updated_at"`
+	UpdatedBy   string            `bson:"updated_by"  json:"updated_by"        yaml:"updated_by"`
+}
+
+// Validate checks that the value contains valid data.
+func (c *Challenge) Validate() error {
+	if !request.ValidGameID(c.GameID) {
+		return errors.New(errors.ErrInvalidRequest,
+			"invalid game_id",
+			"game_id", c.GameID)
+	}
+
+	if c.Name = strings.TrimSpace(c.Name); c.Name == "" ||
+		utf8.RuneCountInString(c.Name) > maxChallengeName {
+		return errors.New(errors.ErrInvalidRequest,
+			"invalid name",
+			"name", c.Name)
+	}
+
+	if utf8.RuneCountInString(c.Description) > maxChallengeDesc {
+		return errors.New(errors.ErrInvalidRequest,
+			"description too long",
+			"max", maxChallengeDesc)
+	}
+
+	if c.StartAt <= 0 || c.EndAt <= c.StartAt {
+		return errors.New(errors.ErrInvalidRequest,
+			"invalid challenge time window",
+			"start_at", c.StartAt,
+			"end_at", c.EndAt)
+	}
+
+	if c.Scoring == "" {
+		c.Scoring = ScoringHighest
+	}
+
+	switch c.Scoring {
+	case ScoringHighest, ScoringLowest, ScoringTotal:
+	default:
+		return errors.New(errors.ErrInvalidRequest,
+			"invalid scoring",
+			"scoring", c.Scoring)
+	}
+
+	return nil
+}
+
+// status returns the status of the challenge at a time.
+func (c *Challenge) status(now int64) string {
+	switch {
+	case now < c.StartAt:
+		return ChallengeUpcoming
+	case now < c.EndAt:
+		return ChallengeOpen
+	default:
+		return ChallengeEnded
+	}
+}
+
+// ChallengeScore values represent scores submitted by players of a challenge.
+type ChallengeScore struct {
+	AccountID   string `bson:"account_id"   json:"-"      yaml:"-"`
+	ChallengeID string `bson:"challenge_id" json:"-"      yaml:"-"`
+	UserID      string `bson:"user_id"      json:"-"      yaml:"-"`
+	Player      string `bson:"player"       json:"player" yaml:"player"`
+	Score       int64  `bson:"score"        json:"score"  yaml:"score"`
+	CreatedAt   int64  `bson:"created_at"   json:"-"      yaml:"-"`
+}
+
+// ChallengeEntry values represent the ranking of a player in a challenge.
+type ChallengeEntry struct {
+	Rank        int    `bson:"rank"         json:"rank"              yaml:"rank"`
+	UserID      string `bson:"_id"          json:"user_id,omitempty" yaml:"user_id,omitempty"`
+	Player      string `bson:"player"       json:"player"            yaml:"player"`
+	Score       int64  `bson:"score"        json:"score"             yaml:"score"`
+	Submissions int64  `bson:"submissions"  json:"submissions"       yaml:"submissions"`
+	SubmittedAt int64  `bson:"submitted_at" json:"submitted_at"      yaml:"submitted_at"`
+}
+
+// ChallengePage values contain the public details of a challenge, and its
+// current ranking. Player user IDs are not included.
+type ChallengePage struct {
+	ID          string            `json:"id"                yaml:"id"`
+	GameID      string            `json:"game_id"           yaml:"game_id"`
+	GameName    string            `json:"game_name"         yaml:"game_name"`
+	Name        string            `json:"name"              yaml:"name"`
+	Description string            `json:"description"       yaml:"description"`
+	StartAt     int64             `json:"start_at"          yaml:"start_at"`
+	EndAt       int64             `json:"end_at"            yaml:"end_at"`
+	Scoring     string            `json:"scoring"           yaml:"scoring"`
+	Status      string            `json:"status"            yaml:"status"`
+	Ranking     []*ChallengeEntry `json:"ranking"           yaml:"ranking"`
+	Winners     []*ChallengeEntry `json:"winners,omitempty" yaml:"winners,omitempty"`
+}
+
+// validChallengeID checks that a challenge ID is valid.
+func validChallengeID(id string) error {
+	if _, err := uuid.Parse(id); err != nil {
+		return errors.New(errors.ErrInvalidRequest,
+			"invalid challenge id",
+			"id", id)
+	}
+
+	return nil
+}
+
+// findChallenge retrieves a challenge using a filter, and records its winners
+// if it has ended.
+func (s *Server) findChallenge(ctx context.Context,
+	id string,
+	f bson.M,
+) (*Challenge, error) {
+	var res *Challenge
+
+	c, err := s.collection(ctx, "challenges")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.FindOne(ctx, f,
+		options.FindOne().SetProjection(bson.M{"_id": 0})).
+		Decode(&res); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, errors.New(errors.ErrNotFound,
+				"challenge not found",
+				"id", id)
+		}
+
+		return nil, errors.Wrap(err, errors.ErrDatabase,
+			"unable to get challenge",
+			"id", id)
+	}
+
+	now := time.Now().Unix()
+
+	res.Status = res.status(now)
+
+	if res.Status == ChallengeEnded && !res.Finalized {
+		if err := s.finalizeChallenge(ctx, res); err != nil {
+			return nil, err
+		}
+	}
+
+	return res, nil
+}
+
+// finalizeChallenge records the winners of a challenge which has ended. The
+// winners are the players ranked first, including any players tied for first.
+func (s *Server) finalizeChallenge(ctx context.Context, c *Challenge) error {
+	ranking, err := s.rankChallenge(ctx, c)
+	if err != nil {
+		return err
+	}
+
+	winners := []*ChallengeEntry{}
+
+	for _, e := range ranking {
+		if e.Rank != 1 {
+			break
+		}
+
+		winners = append(winners, e)
+	}
+
+	col, err := s.collection(ctx, "challenges")
+	if err != nil {
+		return err
+	}
+
+	if _, err := col.UpdateOne(ctx,
+		bson.M{"id": c.ID, "finalized": bson.M{"$ne": true}},
+		bson.M{"$set": bson.M{
+			"finalized": true,
+			"winners":   winners,
+		}}); err != nil {
+		return errors.Wrap(err, errors.ErrDatabase,
+			"unable to record challenge winners",
+			"id", c.ID)
+	}
+
+	c.Finalized = true
+	c.Winners = winners
+
+	return nil
+}
+
+// rankChallenge ranks the players of a challenge using its scoring rule.
+// Players with equal scores share a rank, and are listed in the order their
+// scores were first submitted.
+func (s *Server) rankChallenge(ctx context.Context,
+	c *Challenge,
+) ([]*ChallengeEntry, error) {
+	acc, dir := "$max", -1
+
+	switch c.Scoring {
+	case ScoringLowest:
+		acc, dir = "$min", 1
+	case ScoringTotal:
+		acc = "$sum"
+	}
+
+	col, err := s.collection(ctx, "challenge_scores")
+	if err != nil {
+		return nil, err
+	}
+
+	cur, err := col.Aggregate(ctx,
+		mongo.Pipeline{
+			bson.D{{Key: "$match", Value: bson.M{
+				"challenge_id": c.ID,
+				"created_at": bson.M{
+					"$gte": c.StartAt,
+					"$lt":  c.EndAt,
+				},
+			}}},
+			bson.D{{Key: "$sort", Value: bson.M{"created_at": 1}}},
+			bson.D{{Key: "$group", Value: bson.M{
+				"_id":          "$user_id",
+				"player":       bson.M{"$last": "$player"},
+				"score":        bson.M{acc: "$score"},
+				"submissions":  bson.M{"$sum": 1},
+				"submitted_at": bson.M{"$first": "$created_at"},
+			}}},
+			bson.D{{Key: "$sort", Value: bson.D{
+				{Key: "score", Value: dir},
+				{Key: "submitted_at", Value: 1},
+				{Key: "_id", Value: 1},
+			}}},
+			bson.D{{Key: "$limit", Value: maxChallengeRanking}},
+		})
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrDatabase,
+			"unable to rank challenge scores",
+			"id", c.ID)
+	}
+
+	res := []*ChallengeEntry{}
+
+	if err := cur.All(ctx, &res); err != nil {
+		return nil, errors.Wrap(err, errors.ErrDatabase,
+			"unable to decode challenge ranking",
+			"id", c.ID)
+	}
+
+	for i, e := range res {
+		e.Rank = i + 1
+
+		if i > 0 && e.Score == res[i-1].Score {
+			e.Rank = res[i-1].Rank
+		}
+	}
+
+	return res, nil
+}
+
+// getChallenges retrieves the challenges of the current account, optionally
+// only those for a game, from the most recently started.
+func (s *Server) getChallenges(ctx context.Context,
+	gameID string,
+) ([]*Challenge, error) {
+	aID, err := request.ContextAccountID(ctx)
+	if err != nil {
+		return nil, errors.New(errors.ErrUnauthorized,
+			"unable to get account id from context")
+	}
+
+	f := bson.M{"account_id": aID}
+
+	if gameID != "" {
+		if !request.ValidGameID(gameID) {
+			return nil, errors.New(errors.ErrInvalidParameter,
+				"invalid game_id",
+				"game_id", gameID)
+		}
+
+		f["game_id"] = gameID
+	}
+
+	col, err := s.collection(ctx, "challenges")
+	if err != nil {
+		return nil, err
+	}
+
+	cur, err := col.Find(ctx, f,
+		options.Find().SetProjection(bson.M{"_id": 0}).
+			SetSort(bson.D{{Key: "start_at", Value: -1}}).
+			SetLimit(maxChallenges))
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrDatabase,
+			"unable to find challenges")
+	}
+
+	res := []*Challenge{}
+
+	if err := cur.All(ctx, &res); err != nil {
+		return nil, errors.Wrap(err, errors.ErrDatabase,
+			"unable to decode challenges")
+	}
+
+	now := time.Now().Unix()
+
+	for _, c := range res {
+		c.Status = c.status(now)
+	}
+
+	return res, nil
+}
+
+// getChallenge retrieves a challenge by ID. Challenges are visible to the
+// account which created them, and public challenges are visible to all
+// accounts.
+func (s *Server) getChallenge(ctx context.Context,
+	id string,
+) (*Challenge, error) {
+	aID, err := request.ContextAccountID(ctx)
+	if err != nil {
+		return nil, errors.New(errors.ErrUnauthorized,
+			"unable to get account id from context")
+	}
+
+	if err := validChallengeID(id); err != nil {
+		return nil, err
+	}
+
+	return s.findChallenge(ctx, id, bson.M{"id": id, "$or": bson.A{
+		bson.D{{Key: "public", Value: true}},
+		bson.D{{Key: "account_id", Value: aID}},
+	}})
+}
+
+// ownChallenge retrieves a challenge by ID, if it was created by the current
+// account.
+func (s *Server) ownChallenge(ctx context.Context,
+	id string,
+) (*Challenge, error) {
+	aID, err := request.ContextAccountID(ctx)
+	if err != nil {
+		return nil, errors.New(errors.ErrUnauthorized,
+			"unable to get account id from context")
+	}
+
+	if err := validChallengeID(id); err != nil {
+		return nil, err
+	}
+
+	return s.findChallenge(ctx, id, bson.M{"id": id, "account_id": aID})
+}
+
+// createChallenge creates a challenge for a game owned by the current account.
+func (s *Server) createChallenge(ctx context.Context,
+	v *Challenge,
+) (*Challenge, error) {
+	aID, err := request.ContextAccountID(ctx)
+	if err != nil {
+		return nil, errors.New(errors.ErrUnauthorized,
+			"unable to get account id from context")
+	}
+
+	uID, err := request.ContextUserID(ctx)
+	if err != nil {
+		return nil, errors.New(errors.ErrUnauthorized,
+			"unable to get user id from context")
+	}
+
+	if v == nil {
+		return nil, errors.New(errors.ErrInvalidRequest,
+			"missing challenge")
+	}
+
+	if err := v.Validate(); err != nil {
+		return nil, err
+	}
+
+	g, err := s.getGame(context.WithValue(ctx, CtxKeyGameMinData, true),
+		v.GameID)
+	if err != nil {
+		return nil, err
+	}
+
+	if g.AccountID.Value != aID {
+		return nil, errors.New(errors.ErrForbidden,
+			"only games owned by the account can have challenges",
+			"game_id", v.GameID)
+	}
+
+	now := time.Now().Unix()
+
+	v.AccountID = aID
+	v.ID = uuid.NewString()
+	v.Finalized = false
+	v.Winners = nil
+	v.CreatedAt = now
+	v.CreatedBy = uID
+	v.UpdatedAt = now
+	v.UpdatedBy = uID
+
+	c, err := s.collection(ctx, "challenges")
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := c.InsertOne(ctx,
+		v); err != nil {
+		return nil, errors.Wrap(err, errors.ErrDatabase,
+			"unable to create challenge",
+			"game_id", v.GameID)
+	}
+
+	v.Status = v.status(now)
+
+	return v, nil
+}
+
+// updateChallenge updates the details of a challenge of the current account,
+// which has not ended. The game of a challenge can not be changed.
+func (s *Server) updateChallenge(ctx context.Context,
+	id string,
+	v *Challenge,
+) (*Challenge, error) {
+	uID, err := request.ContextUserID(ctx)
+	if err != nil {
+		return nil, errors.New(errors.ErrUnauthorized,
+			"unable to get user id from context")
+	}
+
+	c, err := s.ownChallenge(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.Status == ChallengeEnded {
+		return nil, errors.New(errors.ErrConflict,
+			"challenge has ended",
+			"id", id)
+	}
+
+	if v == nil {
+		return nil, errors.New(errors.ErrInvalidRequest,
+			"missing challenge")
+	}
+
+	v.GameID = c.GameID
+
+	if err := v.Validate(); err != nil {
+		return nil, err
+	}
+
+	col, err := s.collection(ctx, "challenges")
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := col.UpdateOne(ctx,
+		bson.M{"id": id, "account_id": c.AccountID},
+		bson.M{"$set": bson.M{
+			"name":        v.Name,
+			"description": v.Description,
+			"start_at":    v.StartAt,
+			"end_at":      v.EndAt,
+			"scoring":     v.Scoring,
+			"public":      v.Public,
+			"updated_at":  time.Now().Unix(),
+			"updated_by":  uID,
+		}}); err != nil {
+		return nil, errors.Wrap(err, errors.ErrDatabase,
+			"unable to update challenge",
+			"id", id)
+	}
+
+	return s.ownChallenge(ctx, id)
+}
+
+// deleteChallenge deletes a challenge of the current account, and its scores.
+func (s *Server) deleteChallenge(ctx context.Context, id string) error {
+	aID, err := request.ContextAccountID(ctx)
+	if err != nil {
+		return errors.New(errors.ErrUnauthorized,
+			"unable to get account id from context")
+	}
+
+	if err := validChallengeID(id); err != nil {
+		return err
+	}
+
+	challenges, err := s.collection(ctx, "challenges")
+	if err != nil {
+		return err
+	}
+
+	scores, err := s.collection(ctx, "challenge_scores")
+	if err != nil {
+		return err
+	}
+
+	res, err := challenges.DeleteOne(ctx,
+		bson.M{"id": id, "account_id": aID})
+	if err != nil {
+		return errors.Wrap(err, errors.ErrDatabase,
+			"unable to delete challenge",
+			"id", id)
+	}
+
+	if res.DeletedCount == 0 {
+		return errors.New(errors.ErrNotFound,
+			"challenge not found",
+			"id", id)
+	}
+
+	if _, err := scores.DeleteMany(ctx,
+		bson.M{"challenge_id": id}); err != nil {
+		return errors.Wrap(err, errors.ErrDatabase,
+			"unable to delete challenge scores",
+			"id", id)
+	}
+
+	return nil
+}
+
+// submitChallengeScore records a score submitted by the current user for a
+// challenge which is open, and returns the current ranking of the challenge.
+func (s *Server) submitChallengeScore(ctx context.Context,
+	id string,
+	v *ChallengeScore,
+) ([]*ChallengeEntry, error) {
+	aID, err := request.ContextAccountID(ctx)
+	if err != nil {
+		return nil, errors.New(errors.ErrUnauthorized,
+			"unable to get account id from context")
+	}
+
+	uID, err := request.ContextUserID(ctx)
+	if err != nil {
+		return nil, errors.New(errors.ErrUnauthorized,
+			"unable to get user id from context")
+	}
+
+	if v == nil {
+		return nil, errors.New(errors.ErrInvalidRequest,
+			"missing challenge score")
+	}
+
+	c, err := s.getChallenge(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.Status != ChallengeOpen {
+		return nil, errors.New(errors.ErrConflict,
+			"challenge is not open",
+			"id", id,
+			"status", c.Status)
+	}
+
+	if v.Player = strings.TrimSpace(v.Player); v.Player == "" {
+		v.Player = defaultPlayerName
+	}
+
+	if utf8.RuneCountInString(v.Player) > maxPlayerName {
+		return nil, errors.New(errors.ErrInvalidRequest,
+			"player name too long",
+			"max", maxPlayerName)
+	}
+
+	v.AccountID = aID
+	v.ChallengeID = id
+	v.UserID = uID
+	v.CreatedAt = time.Now().Unix()
+
+	col, err := s.collection(ctx, "challenge_scores")
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := col.InsertOne(ctx, v); err != nil {
+		return nil, errors.Wrap(err, errors.ErrDatabase,
+			"unable to submit challenge score",
+			"id", id)
+	}
+
+	return s.rankChallenge(ctx, c)
+}
+
+// getChallengeRanking retrieves the current ranking of a challenge.
+func (s *Server) getChallengeRanking(ctx context.Context,
+	id string,
+) ([]*ChallengeEntry, error) {
+	c, err := s.getChallenge(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.rankChallenge(ctx, c)
+}
+
+// getChallengePage retrieves the public details and ranking of a public
+// challenge. No authentication is required.
+func (s *Server) getChallengePage(ctx context.Context,
+	id string,
+) (*ChallengePage, error) {
+	if err := validChallengeID(id); err != nil {
+		return nil, err
+	}
+
+	c, err := s.findChallenge(ctx, id, bson.M{"id": id, "public": true})
+	if err != nil {
+		return nil, err
+	}
+
+	ranking, err := s.rankChallenge(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &ChallengePage{
+		ID:          c.ID,
+		GameID:      c.GameID,
+		Name:        c.Name,
+		Description: c.Description,
+		StartAt:     c.StartAt,
+		EndAt:       c.EndAt,
+		Scoring:     c.Scoring,
+		Status:      c.Status,
+		Ranking:     ranking,
+		Winners:     c.Winners,
+	}
+
+	// The game name is only shown if the game is public.
+	gctx := context.WithValue(ctx, request.CtxKeyAccountID, "")
+
+	if g, err := s.getGame(context.WithValue(gctx, CtxKeyGameMinData, true),
+		c.GameID); err == nil {
+		res.GameName = g.Name.Value
+	}
+
+	for _, e := range slices.Concat(res.Ranking, res.Winners) {
+		e.UserID = ""
+	}
+
+	return res, nil
+}
+
+// challengesHandler performs routing for challenge requests.
+func (s *Server) challengesHandler() http.Handler {
+	r := chi.NewRouter()
+
+	r.Use(s.dbAvail)
+
+	r.With(s.stat, s.trace).Get("/{id}/public", s.getChallengePageHandler)
+
+	r.With(s.stat, s.trace, s.auth).Get("/", s.getChallengesHandler)
+	r.With(s.stat, s.trace, s.auth).Post("/", s.postChallengeHandler)
+	r.With(s.stat, s.trace, s.auth).Get("/{id}", s.getChallengeHandler)
+	r.With(s.stat, s.trace, s.auth).Patch("/{id}", s.putChallengeHandler)
+	r.With(s.stat, s.trace, s.auth).Put("/{id}", s.putChallengeHandler)
+	r.With(s.stat, s.trace, s.auth).Delete("/{id}",
+		s.deleteChallengeHandler)
+	r.With(s.stat, s.trace, s.auth).Get("/{id}/ranking",
+		s.getChallengeRankingHandler)
+	r.With(s.stat, s.trace, s.auth).Post("/{id}/scores",
+		s.postChallengeScoreHandler)
+
+	return r
+}
+
+// getChallengesHandler is the get handler used to list the challenges of the
+// current account.
+func (s *Server) getChallengesHandler(w http.ResponseWriter,
+	r *http.Request,
+) {
+	ctx := r.Context()
+
+	if err := s.checkScope(ctx, request.ScopeGamesRead); err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	res, err := s.getChallenges(ctx, r.URL.Query().Get("game_id"))
+	if err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	if err := s.encode(w, r, res); err != nil {
+		s.error(err, w, r)
+	}
+}
+
+// getChallengeHandler is the get handler used to retrieve a challenge.
+func (s *Server) getChallengeHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if err := s.checkScope(ctx, request.ScopeGamesRead); err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	res, err := s.getChallenge(ctx, chi.URLParam(r, "id"))
+	if err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	if err := s.encode(w, r, res); err != nil {
+		s.error(err, w, r)
+	}
+}
+
+// postChallengeHandler is the post handler used to create a challenge.
+func (s *Server) postChallengeHandler(w http.ResponseWriter,
+	r *http.Request,
+) {
+	ctx := r.Context()
+
+	if err := s.checkScope(ctx, request.ScopeGamesWrite); err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	req := &Challenge{}
+
+	if err := s.decode(r, &req); err != nil {
+		s.error(errors.Wrap(err, errors.ErrInvalidRequest,
+			"unable to decode request"), w, r)
+
+		return
+	}
+
+	res, err := s.createChallenge(ctx, req)
+	if err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+
+	if err := s.encode(w, r, res); err != nil {
+		s.error(err, w, r)
+	}
+}
+
+// putChallengeHandler is the put and patch handler used to update a
+// challenge. Fields not included in the request are left unchanged.
+func (s *Server) putChallengeHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if err := s.checkScope(ctx, request.ScopeGamesWrite); err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+
+	req, err := s.ownChallenge(ctx, id)
+	if err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	if err := s.decode(r, &req); err != nil {
+		s.error(errors.Wrap(err, errors.ErrInvalidRequest,
+			"unable to decode request"), w, r)
+
+		return
+	}
+
+	res, err := s.updateChallenge(ctx, id, req)
+	if err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	if err := s.encode(w, r, res); err != nil {
+		s.error(err, w, r)
+	}
+}
+
+// deleteChallengeHandler is the delete handler used to delete a challenge.
+func (s *Server) deleteChallengeHandler(w http.ResponseWriter,
+	r *http.Request,
+) {
+	ctx := r.Context()
+
+	if err := s.checkScope(ctx, request.ScopeGamesWrite); err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	if err := s.deleteChallenge(ctx, chi.URLParam(r, "id")); err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getChallengeRankingHandler is the get handler used to retrieve the ranking
+// of a challenge.
+func (s *Server) getChallengeRankingHandler(w http.ResponseWriter,
+	r *http.Request,
+) {
+	ctx := r.Context()
+
+	if err := s.checkScope(ctx, request.ScopeGamesRead); err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	res, err := s.getChallengeRanking(ctx, chi.URLParam(r, "id"))
+	if err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	if err := s.encode(w, r, res); err != nil {
+		s.error(err, w, r)
+	}
+}
+
+// postChallengeScoreHandler is the post handler used to submit a score for a
+// challenge.
+func (s *Server) postChallengeScoreHandler(w http.ResponseWriter,
+	r *http.Request,
+) {
+	ctx := r.Context()
+
+	if err := s.checkScope(ctx, request.ScopeGamesRead); err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	req := &ChallengeScore{}
+
+	if err := s.decode(r, &req); err != nil {
+		s.error(errors.Wrap(err, errors.ErrInvalidRequest,
+			"unable to decode request"), w, r)
+
+		return
+	}
+
+	res, err := s.submitChallengeScore(ctx, chi.URLParam(r, "id"), req)
+	if err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+
+	if err := s.encode(w, r, res); err != nil {
+		s.error(err, w, r)
+	}
+}
+
+// getChallengePageHandler is the get handler used to retrieve the public page
+// of a public challenge.
+func (s *Server) getChallengePageHandler(w http.ResponseWriter,
+	r *http.Request,
+) {
+	ctx := r.Context()
+
+	res, err := s.getChallengePage(ctx, chi.URLParam(r, "id"))
+	if err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+
+	if err := s.encode(w, r, res); err != nil {
+		s.error(err, w, r)
+	}
+}
@@ -0,0 +1,285 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dhaifley/game2d/errors"
+	"github.com/dhaifley/game2d/logger"
+	"github.com/dhaifley/game2d/request"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// matchOrigin returns whether an origin matches an allowed origin pattern, in
+// which each * matches any sequence of characters.
+func matchOrigin(pattern, origin string) bool {
+	pattern, origin = strings.ToLower(pattern), strings.ToLower(origin)
+
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return pattern == origin
+	}
+
+	if !strings.HasPrefix(origin, parts[0]) {
+		return false
+	}
+
+	origin = origin[len(parts[0]):]
+
+	for _, p := range parts[1 : len(parts)-1] {
+		i := strings.Index(origin, p)
+		if i < 0 {
+			return false
+		}
+
+		origin = origin[i+len(p):]
+	}
+
+	return strings.HasSuffix(origin, parts[len(parts)-1])
+}
+
+// allowOrigin returns whether an origin is allowed to make cross-origin
+// requests to the server.
+func (s *Server) allowOrigin(origin string) bool {
+	for _, p := range s.cfg.ServerCORSOrigins() {
+		if matchOrigin(p, origin) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// originsUpdateInterval is how often the origins allowed by accounts are
+// retrieved again, so that changes made by other servers are used.
+const originsUpdateInterval = time.Minute
+
+// originCache values contain the origins allowed by any account, so that the
+// origin of each cross-origin request is not looked up in the database.
+type originCache struct {
+	sync.RWMutex
+	origins map[string]bool
+	loaded  bool
+}
+
+// loadOrigins retrieves the origins allowed by all accounts.
+func (s *Server) loadOrigins(ctx context.Context) error {
+	db := s.DB()
+	if db == nil {
+		return errors.New(errors.ErrUnavailable,
+			"database unavailable").
+			WithReason(errors.ReasonDatabaseUnavailable)
+	}
+
+	ctx, cancel := s.opContext(ctx, opDB)
+	defer cancel()
+
+	var res []string
+
+	if err := db.Collection("accounts").Distinct(ctx, "allowed_origins",
+		bson.M{}).Decode(&res); err != nil {
+		return errors.Wrap(err, errors.ErrDatabase,
+			"unable to find accounts allowed origins")
+	}
+
+	origins := make(map[string]bool, len(res))
+
+	for _, o := range res {
+		origins[strings.ToLower(o)] = true
+	}
+
+	s.origins.Lock()
+
+	s.origins.origins = origins
+	s.origins.loaded = true
+
+	s.origins.Unlock()
+
+	return nil
+}
+
+// refreshOrigins retrieves the origins allowed by all accounts again, after an
+// account has been updated.
+func (s *Server) refreshOrigins(ctx context.Context) {
+	if err := s.loadOrigins(ctx); err != nil {
+		s.log.Log(ctx, logger.LvlError,
+			"unable to refresh accounts allowed origins",
+			"error", err)
+	}
+}
+
+// UpdateOrigins periodically retrieves the origins allowed by all accounts.
+func (s *Server) UpdateOrigins() {
+	s.originOnce.Do(func() {
+		go func() {
+			for s.db == nil {
+				time.Sleep(100 * time.Millisecond)
+			}
+
+			s.addCancelFunc(s.updateOrigins(context.Background()))
+		}()
+	})
+}
+
+// updateOrigins starts retrieving the origins allowed by all accounts,
+// returning a function which stops it.
+func (s *Server) updateOrigins(ctx context.Context) context.CancelFunc {
+	ctx, cancel := context.WithCancel(ctx)
+
+	s.jobs.Add(1)
+
+	go func(ctx context.Context) {
+		defer s.jobs.Done()
+
+		tick := time.NewTimer(0)
+
+		defer tick.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-tick.C:
+				s.refreshOrigins(ctx)
+			}
+
+			tick.Reset(originsUpdateInterval)
+		}
+	}(ctx)
+
+	return cancel
+}
+
+// accountsAllowOrigin returns whether any account allows an origin to make
+// cross-origin requests. Requests from such origins are authorized for only the
+// accounts which allow them. The allowed origins are retrieved when they are
+// first needed, and then refreshed periodically and when accounts change.
+func (s *Server) accountsAllowOrigin(ctx context.Context, origin string) bool {
+	s.origins.RLock()
+
+	loaded, ok := s.origins.loaded, s.origins.origins[strings.ToLower(origin)]
+
+	s.origins.RUnlock()
+
+	if loaded {
+		return ok
+	}
+
+	if err := s.loadOrigins(ctx); err != nil {
+		s.log.Log(ctx, logger.LvlError,
+			"unable to find accounts allowing origin",
+			"error", err,
+			"origin", origin)
+
+		return false
+	}
+
+	s.origins.RLock()
+	defer s.origins.RUnlock()
+
+	return s.origins.origins[strings.ToLower(origin)]
+}
+
+// accountOrigins returns the origins allowed by an account, or nil if the
+// account does not restrict the allowed origins.
+func (s *Server) accountOrigins(ctx context.Context,
+	aID string,
+) ([]string, error) {
+	ctx = context.WithValue(ctx, request.CtxKeyAccountID, aID)
+
+	a, err := s.getAccount(ctx, aID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !a.AllowedOrigins.Valid || len(a.AllowedOrigins.Value) == 0 {
+		return nil, nil
+	}
+
+	return a.AllowedOrigins.Value, nil
+}
+
+// checkOrigin verifies that a request origin is allowed to make requests on
+// behalf of an account. Origins allowed by the server configuration may make
+// requests on behalf of any account.
+func (s *Server) checkOrigin(ctx context.Context, origin, aID string) error {
+	if origin == "" || s.allowOrigin(origin) {
+		return nil
+	}
+
+	origins, err := s.accountOrigins(ctx, aID)
+	if err != nil {
+		return err
+	}
+
+	if !slices.Contains(origins, strings.ToLower(origin)) {
+		return errors.New(errors.ErrForbidden,
+			"origin not allowed",
+			"origin", origin,
+			"account_id", aID)
+	}
+
+	return nil
+}
+
+// cors wraps the request handlers of a route group with cross-origin resource
+// sharing functionality. Cross-origin requests are allowed from the configured
+// origins, and the origins allowed by accounts, using the configured methods
+// which the route group supports.
+func (s *Server) cors(methods ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+
+			if origin != "" && (s.allowOrigin(origin) ||
+				s.accountsAllowOrigin(r.Context(), origin)) {
+				w.Header().Set("Access-Control-Allow-Origin", origin)
+
+				if s.cfg.ServerCORSCredentials() {
+					w.Header().Set("Access-Control-Allow-Credentials",
+						"true")
+				}
+			}
+
+			if r.Method != http.MethodOptions {
+				next.ServeHTTP(w, r)
+
+				return
+			}
+
+			if w.Header().Get("Access-Control-Allow-Origin") == "" ||
+				r.Header.Get("Access-Control-Request-Method") == "" {
+				s.noContent(w, r)
+
+				return
+			}
+
+			allowed := []string{}
+
+			for _, m := range s.cfg.ServerCORSMethods() {
+				m = strings.ToUpper(m)
+
+				if slices.Contains(methods, m) {
+					allowed = append(allowed, m)
+				}
+			}
+
+			w.Header().Set("Access-Control-Allow-Methods",
+				strings.Join(allowed, ", "))
+			w.Header().Set("Access-Control-Allow-Headers",
+				strings.Join(s.cfg.ServerCORSHeaders(), ", "))
+
+			if ma := s.cfg.ServerCORSMaxAge(); ma > 0 {
+				w.Header().Set("Access-Control-Max-Age",
+					strconv.FormatInt(int64(ma.Seconds()), 10))
+			}
+
+			s.noContent(w, r)
+		})
+	}
+}
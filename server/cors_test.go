@@ -0,0 +1,35 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dhaifley/game2d/config"
+)
+
+func TestAccountsAllowOrigin(t *testing.T) {
+	t.Parallel()
+
+	svr, err := NewServer(config.NewDefault(), nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+
+	// The allowed origins can not be retrieved without a database.
+	if svr.accountsAllowOrigin(ctx, "https://example.com") {
+		t.Error("Expected origin not allowed without allowed origins")
+	}
+
+	svr.origins.origins = map[string]bool{"https://example.com": true}
+	svr.origins.loaded = true
+
+	if !svr.accountsAllowOrigin(ctx, "https://Example.com") {
+		t.Error("Expected origin allowed")
+	}
+
+	if svr.accountsAllowOrigin(ctx, "https://other.example.com") {
+		t.Error("Expected origin not allowed")
+	}
+}
@@ -0,0 +1,197 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/dhaifley/game2d/errors"
+	"github.com/dhaifley/game2d/request"
+	"github.com/go-chi/chi/v5"
+)
+
+// deltaHashSize is the size of the content hashes of game sections, in bytes.
+const deltaHashSize = 8
+
+// DeltaSectionGame is the game section containing all the fields of a game
+// which are not in a section of their own.
+const DeltaSectionGame = "game"
+
+// deltaSections are the sections into which game definitions are divided for
+// delta updates, in the order their hashes appear in game revisions. Each
+// section, other than the game section, is a field of the game.
+var deltaSections = []string{
+	DeltaSectionGame,
+	"subject",
+	"objects",
+	"images",
+	"script",
+	"bindings",
+	"locales",
+}
+
+// GameDeltaSection values contain the content hash of a section of a game
+// definition, and its data, if it changed.
+type GameDeltaSection struct {
+	Hash string          `json:"hash"           yaml:"hash"`
+	Data json.RawMessage `json:"data,omitempty" yaml:"data,omitempty"`
+}
+
+// GameDelta values contain the sections of a game definition which changed
+// since a revision of it. The revision identifies the content of every
+// section, and is used to request the next delta.
+type GameDelta struct {
+	ID       string                       `json:"id"       yaml:"id"`
+	Rev      string                       `json:"rev"      yaml:"rev"`
+	Sections map[string]*GameDeltaSection `json:"sections" yaml:"sections"`
+}
+
+// gameSections divides the JSON encoded definition of a game into its delta
+// sections.
+func gameSections(g *Game) (map[string]json.RawMessage, error) {
+	b, err := json.Marshal(g)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrServer,
+			"unable to encode game",
+			"id", g.ID.Value)
+	}
+
+	fields := map[string]json.RawMessage{}
+
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return nil, errors.Wrap(err, errors.ErrServer,
+			"unable to decode game fields",
+			"id", g.ID.Value)
+	}
+
+	res := make(map[string]json.RawMessage, len(deltaSections))
+
+	for _, name := range deltaSections[1:] {
+		if v, ok := fields[name]; ok {
+			res[name] = v
+
+			delete(fields, name)
+		}
+	}
+
+	// Maps are encoded with sorted keys, so the game section is stable.
+	if res[DeltaSectionGame], err = json.Marshal(fields); err != nil {
+		return nil, errors.Wrap(err, errors.ErrServer,
+			"unable to encode game section",
+			"id", g.ID.Value)
+	}
+
+	return res, nil
+}
+
+// sectionHash returns the content hash of a game section.
+func sectionHash(b []byte) string {
+	h := sha256.Sum256(b)
+
+	return hex.EncodeToString(h[:deltaHashSize])
+}
+
+// parseRev returns the section hashes identified by a game revision, keyed by
+// section name. Empty revisions identify no sections.
+func parseRev(rev string) (map[string]string, error) {
+	res := make(map[string]string, len(deltaSections))
+
+	if rev == "" {
+		return res, nil
+	}
+
+	n := deltaHashSize * 2
+
+	if len(rev) != n*len(deltaSections) {
+		return nil, errors.New(errors.ErrInvalidParameter,
+			"invalid game revision",
+			"since", rev)
+	}
+
+	if _, err := hex.DecodeString(rev); err != nil {
+		return nil, errors.New(errors.ErrInvalidParameter,
+			"invalid game revision",
+			"since", rev)
+	}
+
+	for i, name := range deltaSections {
+		res[name] = rev[i*n : (i+1)*n]
+	}
+
+	return res, nil
+}
+
+// gameDelta returns the sections of a game which changed since a revision of
+// it. Sections which have not changed contain only their hash.
+func gameDelta(g *Game, since string) (*GameDelta, error) {
+	prev, err := parseRev(since)
+	if err != nil {
+		return nil, err
+	}
+
+	sections, err := gameSections(g)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &GameDelta{
+		ID:       g.ID.Value,
+		Sections: make(map[string]*GameDeltaSection, len(deltaSections)),
+	}
+
+	var rev strings.Builder
+
+	for _, name := range deltaSections {
+		b := sections[name]
+		if b == nil {
+			b = json.RawMessage("null")
+		}
+
+		ds := &GameDeltaSection{Hash: sectionHash(b)}
+
+		if prev[name] != ds.Hash {
+			ds.Data = b
+		}
+
+		res.Sections[name] = ds
+
+		rev.WriteString(ds.Hash)
+	}
+
+	res.Rev = rev.String()
+
+	return res, nil
+}
+
+// getGameDeltaHandler is the get handler used to retrieve the sections of a
+// game definition which changed since a revision of it, so that clients need
+// not download unchanged sections, such as images, again.
+func (s *Server) getGameDeltaHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if err := s.checkScope(ctx, request.ScopeGamesRead); err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	g, err := s.getGame(ctx, chi.URLParam(r, "id"))
+	if err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	res, err := gameDelta(g, r.URL.Query().Get("since"))
+	if err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	if err := s.encode(w, r, res); err != nil {
+		s.error(err, w, r)
+	}
+}
@@ -0,0 +1,296 @@
+package server
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/dhaifley/game2d/errors"
+	"github.com/dhaifley/game2d/request"
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	// describeMaxTokens is the maximum number of tokens of the response to a
+	// request to describe a game.
+	describeMaxTokens = 4096
+
+	// maxDescribeTags is the maximum number of tags added to a described
+	// game.
+	maxDescribeTags = 5
+)
+
+// GameDescription values contain the metadata generated by the AI service for
+// a game. The icon is an SVG document, which is not encoded.
+type GameDescription struct {
+	Description string   `json:"description"`
+	Controls    string   `json:"controls"`
+	Tags        []string `json:"tags"`
+	Icon        string   `json:"icon"`
+}
+
+// GameDescriber values are prompters which are able to describe games.
+type GameDescriber interface {
+	Describe(ctx context.Context, game *Game) (*GameDescription, error)
+}
+
+// describeMessage creates the message sent to describe a game, which contains
+// only the metadata and the script of the game, so that it remains small.
+func describeMessage(game *Game) string {
+	script := game.Script.Value
+
+	if b, err := base64.StdEncoding.DecodeString(script); err == nil {
+		script = string(b)
+	}
+
+	return "Here is the metadata of the game:\n\n" +
+		"Name: " + game.Name.Value + "\n" +
+		"Version: " + game.Version.Value + "\n" +
+		"Size: " + strconv.FormatInt(game.W.Value, 10) + "x" +
+		strconv.FormatInt(game.H.Value, 10) + "\n" +
+		"Tags: " + strings.Join(game.Tags.Value, ", ") + "\n" +
+		"Description: " + game.Description.Value + "\n" +
+		"Controls: " + game.Controls.Value + "\n" +
+		"\nHere is the Lua script of the game:\n" +
+		"\n<document source=\"game.lua\">\n" + script + "\n</document>\n"
+}
+
+// describeSystem is the system prompt sent to describe a game.
+const describeSystem = `You are an expert 2D game developer, who writes the
+listings of games in a game gallery. You will be given the metadata and the Lua
+script of a game. Respond with only a JSON object, with these fields:
+
+"description": a concise description of the game and its goal, in one or two
+sentences.
+
+"controls": short instructions for the controls of the game, as found in its
+script.
+
+"tags": a list of up to five short, lower case tags for the game, such as its
+genre.
+
+"icon": an SVG document of a simple, square icon for the game, which must be
+valid SVG with a viewBox, and must not be encoded.`
+
+// Describe sends the metadata and script of a game to the Anthropic API to
+// generate its description.
+func (p *anthropicPrompter) Describe(ctx context.Context,
+	game *Game,
+) (*GameDescription, error) {
+	message, err := p.cli.Messages.New(ctx, anthropic.MessageNewParams{
+		Model:     anthropic.F(anthropic.ModelClaude3_7SonnetLatest),
+		MaxTokens: anthropic.F(min(p.max, describeMaxTokens)),
+		System: anthropic.F([]anthropic.TextBlockParam{
+			anthropic.NewTextBlock(describeSystem),
+		}),
+		Messages: anthropic.F([]anthropic.MessageParam{
+			anthropic.NewUserMessage(
+				anthropic.NewTextBlock(describeMessage(game))),
+		}),
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrPrompt,
+			"unable to get description response",
+			"game_id", game.ID.Value)
+	}
+
+	var text string
+
+	for _, c := range message.Content {
+		text += c.Text
+	}
+
+	start, end := strings.Index(text, "{"), strings.LastIndex(text, "}")
+	if start == -1 || end < start {
+		return nil, errors.New(errors.ErrPrompt,
+			"description response is missing JSON object",
+			"game_id", game.ID.Value,
+			"response", text)
+	}
+
+	var res *GameDescription
+
+	if err := json.Unmarshal([]byte(text[start:end+1]), &res); err != nil {
+		return nil, errors.Wrap(err, errors.ErrPrompt,
+			"unable to decode description response",
+			"game_id", game.ID.Value,
+			"response", text)
+	}
+
+	return res, nil
+}
+
+// Describe returns a fixed description for any game.
+func (m *mockPrompter) Describe(ctx context.Context,
+	game *Game,
+) (*GameDescription, error) {
+	return &GameDescription{
+		Description: "A game described by the AI.",
+		Controls:    "Use the arrow keys to move.",
+		Tags:        []string{"arcade"},
+		Icon: `<svg xmlns="http://www.w3.org/2000/svg" ` +
+			`viewBox="0 0 16 16"><rect width="16" height="16"/></svg>`,
+	}, nil
+}
+
+// describedTags returns the tags generated for a game, without duplicates, up
+// to the maximum number of tags. Tags not managed by the account are removed,
+// if the account enforces managed tags.
+func (s *Server) describedTags(ctx context.Context,
+	tags []string,
+) ([]string, error) {
+	a, err := s.getAccount(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	res := []string{}
+
+	for _, t := range tags {
+		t = strings.ToLower(strings.TrimSpace(t))
+
+		if t == "" || len(res) >= maxDescribeTags || slices.Contains(res, t) {
+			continue
+		}
+
+		if a != nil && a.EnforceTags.Value &&
+			!tagManaged(a.ManagedTags.Value, t) {
+			continue
+		}
+
+		res = append(res, t)
+	}
+
+	return res, nil
+}
+
+// describeGame generates the description, control instructions, tags, and
+// icon of a game using the AI service, and updates the game with those which
+// it is missing. Games which are missing none of them are not sent to the AI
+// service.
+func (s *Server) describeGame(ctx context.Context, id string) (*Game, error) {
+	aID, err := request.ContextAccountID(ctx)
+	if err != nil {
+		return nil, errors.New(errors.ErrUnauthorized,
+			"unable to get account id from context")
+	}
+
+	if s.getPrompter == nil {
+		if err := s.initPrompter(); err != nil {
+			return nil, errors.Wrap(err, errors.ErrUnavailable,
+				"unable to initialize prompter")
+		}
+	}
+
+	g, err := s.getGame(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if g == nil || (g.AccountID.Value != aID &&
+		aID != request.SystemAccount) {
+		return nil, errors.New(errors.ErrNotFound,
+			"game not found for description",
+			"id", id).WithReason(errors.ReasonGameNotFound)
+	}
+
+	if g.ReadOnly.Value {
+		return nil, errReadOnlyGame(id)
+	}
+
+	if g.Description.Value != "" && g.Controls.Value != "" &&
+		len(g.Tags.Value) > 0 && g.Icon.Value != "" {
+		return g, nil
+	}
+
+	p := s.getPrompter(ctx)
+	if p == nil {
+		return nil, errors.New(errors.ErrInvalidRequest,
+			"account AI API key not set")
+	}
+
+	gd, ok := p.(GameDescriber)
+	if !ok {
+		return nil, errors.New(errors.ErrServer,
+			"AI provider does not support game descriptions")
+	}
+
+	d, err := gd.Describe(ctx, g)
+	if err != nil {
+		return nil, err
+	}
+
+	req := &Game{AccountID: g.AccountID, ID: g.ID}
+
+	if v := strings.TrimSpace(d.Description); g.Description.Value == "" &&
+		v != "" {
+		req.Description = request.FieldString{Set: true, Valid: true, Value: v}
+	}
+
+	if v := strings.TrimSpace(d.Controls); g.Controls.Value == "" &&
+		v != "" {
+		req.Controls = request.FieldString{Set: true, Valid: true, Value: v}
+	}
+
+	if v := strings.TrimSpace(d.Icon); g.Icon.Value == "" &&
+		strings.Contains(v, "<svg") {
+		req.Icon = request.FieldString{
+			Set: true, Valid: true,
+			Value: base64.StdEncoding.EncodeToString([]byte(compressSVG(v))),
+		}
+	}
+
+	if len(g.Tags.Value) == 0 {
+		tags, err := s.describedTags(ctx, d.Tags)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(tags) > 0 {
+			req.Tags = request.FieldStringArray{
+				Set: true, Valid: true, Value: tags,
+			}
+
+			ctx = context.WithValue(ctx, CtxKeyGameAllowTags, true)
+		}
+	}
+
+	return s.updateGame(ctx, req)
+}
+
+// postGameDescribeHandler is the post handler used to generate the missing
+// description, control instructions, tags, and icon of a game using the AI
+// service.
+func (s *Server) postGameDescribeHandler(w http.ResponseWriter,
+	r *http.Request,
+) {
+	ctx := r.Context()
+
+	if err := s.checkScope(ctx, request.ScopeGamesWrite); err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	res, err := s.describeGame(ctx, chi.URLParam(r, "id"))
+	if err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	s.recordActivity(ctx, ActivityGameUpdated, res.ID.Value,
+		map[string]any{"name": res.Name.Value})
+
+	s.runHooks(ctx, HookGameUpdated, res.ID.Value,
+		map[string]any{"name": res.Name.Value})
+
+	if err := s.encode(w, r, res); err != nil {
+		s.error(err, w, r)
+	}
+}
@@ -0,0 +1,797 @@
+package server
+
+import (
+	"container/list"
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dhaifley/game2d/errors"
+	"github.com/dhaifley/game2d/logger"
+	"github.com/dhaifley/game2d/request"
+	"github.com/dhaifley/game2d/static"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// CtxKeyDomainAccountID is the context key of the ID of the account whose
+// custom domain a request was received on.
+const CtxKeyDomainAccountID = "domain_account_id"
+
+const (
+	// domainRecordPrefix is the prefix of the name of the DNS TXT record used
+	// to verify that an account controls a custom domain.
+	domainRecordPrefix = "_game2d."
+
+	// domainRecordValue is the prefix of the value of the DNS TXT record used
+	// to verify a custom domain, which is followed by the domain token.
+	domainRecordValue = "game2d-verify="
+
+	// domainCacheExpiration is how long the accounts served on custom domains
+	// are kept, including hosts which are not custom domains.
+	domainCacheExpiration = time.Minute
+
+	// maxDomainMisses is the maximum number of hosts which are not custom
+	// domains that are kept, so that requests for unknown hosts can not grow
+	// the memory used without limit.
+	maxDomainMisses = 1000
+
+	// domainPendingExpiration is how long custom domains may be pending, before
+	// they are removed if they are not verified.
+	domainPendingExpiration = 7 * 24 * time.Hour
+
+	// maxDomainGames is the maximum number of games listed in the gallery of
+	// a custom domain.
+	maxDomainGames = 100
+)
+
+// domainPattern matches valid custom domain names, which must have at least two
+// labels.
+var domainPattern = regexp.MustCompile(
+	`^([a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?\.)+[a-z]([a-z0-9-]{0,61}[a-z0-9])?$`)
+
+// Domain values represent the custom domain of an account, on which its public
+// gallery and game embeds are served, once the domain is verified. The record
+// is the name of the DNS TXT record used to verify the domain, and the value
+// is the value it must contain. Only one account may have an active domain,
+// but any number may have it pending, until they verify it, or it expires.
+type Domain struct {
+	AccountID  string    `bson:"account_id"           json:"account_id"            yaml:"account_id"`
+	Domain     string    `bson:"domain"               json:"domain"                yaml:"domain"`
+	Status     string    `bson:"status"               json:"status"                yaml:"status"`
+	Token      string    `bson:"token"                json:"-"                     yaml:"-"`
+	Record     string    `bson:"-"                    json:"record"                yaml:"record"`
+	Value      string    `bson:"-"                    json:"value"                 yaml:"value"`
+	VerifiedAt int64     `bson:"verified_at"          json:"verified_at,omitempty" yaml:"verified_at,omitempty"`
+	CreatedAt  int64     `bson:"created_at"           json:"created_at"            yaml:"created_at"`
+	UpdatedAt  int64     `bson:"updated_at"           json:"updated_at"            yaml:"updated_at"`
+	ExpiresAt  time.Time `bson:"expires_at,omitempty" json:"-"                     yaml:"-"`
+}
+
+// Validate checks that the value contains valid data.
+func (d *Domain) Validate() error {
+	if !domainPattern.MatchString(d.Domain) || len(d.Domain) > 253 {
+		return errors.New(errors.ErrInvalidRequest,
+			"invalid domain",
+			"domain", d.Domain)
+	}
+
+	return nil
+}
+
+// setRecord sets the DNS TXT record used to verify the domain.
+func (d *Domain) setRecord() *Domain {
+	d.Record = domainRecordPrefix + d.Domain
+	d.Value = domainRecordValue + d.Token
+
+	return d
+}
+
+// domainEntry values are the accounts served on hosts, which are kept for a
+// short time, so that the database is not queried for every request.
+type domainEntry struct {
+	host      string
+	accountID string
+	expires   time.Time
+}
+
+// domainCache values keep the accounts served on custom domains until they
+// expire. Hosts which are not custom domains are kept in a bounded list, from
+// which the least recently used are removed.
+type domainCache struct {
+	sync.Mutex
+	hits   map[string]*domainEntry
+	misses map[string]*list.Element
+	order  *list.List
+}
+
+// get returns the account served on a host, and whether the host was found,
+// removing it if it has expired.
+func (c *domainCache) get(host string, now time.Time) (string, bool) {
+	c.Lock()
+	defer c.Unlock()
+
+	if e, ok := c.hits[host]; ok {
+		if now.Before(e.expires) {
+			return e.accountID, true
+		}
+
+		delete(c.hits, host)
+	}
+
+	if el, ok := c.misses[host]; ok {
+		if e := el.Value.(*domainEntry); now.Before(e.expires) {
+			c.order.MoveToFront(el)
+
+			return "", true
+		}
+
+		c.order.Remove(el)
+		delete(c.misses, host)
+	}
+
+	return "", false
+}
+
+// add keeps the account served on a host, which is empty if the host is not a
+// custom domain. Any expired custom domains are removed.
+func (c *domainCache) add(host, accountID string, now time.Time) {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.hits == nil {
+		c.hits = map[string]*domainEntry{}
+		c.misses = map[string]*list.Element{}
+		c.order = list.New()
+	}
+
+	e := &domainEntry{
+		host:      host,
+		accountID: accountID,
+		expires:   now.Add(domainCacheExpiration),
+	}
+
+	if accountID != "" {
+		for h, he := range c.hits {
+			if !now.Before(he.expires) {
+				delete(c.hits, h)
+			}
+		}
+
+		c.hits[host] = e
+
+		return
+	}
+
+	if el, ok := c.misses[host]; ok {
+		el.Value = e
+
+		c.order.MoveToFront(el)
+
+		return
+	}
+
+	c.misses[host] = c.order.PushFront(e)
+
+	for c.order.Len() > maxDomainMisses {
+		el := c.order.Back()
+
+		c.order.Remove(el)
+		delete(c.misses, el.Value.(*domainEntry).host)
+	}
+}
+
+// remove removes a host, so that it is found again.
+func (c *domainCache) remove(host string) {
+	c.Lock()
+	defer c.Unlock()
+
+	delete(c.hits, host)
+
+	if el, ok := c.misses[host]; ok {
+		c.order.Remove(el)
+		delete(c.misses, host)
+	}
+}
+
+// domainHost returns the host name of a request, without any port, in lower
+// case.
+func domainHost(r *http.Request) string {
+	host := r.Host
+
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	return strings.ToLower(strings.TrimSuffix(host, "."))
+}
+
+// domainAccount returns the ID of the account whose verified custom domain is
+// a host, or an empty string if the host is not a verified custom domain. The
+// server host, and hosts which are not valid custom domains, are never found.
+func (s *Server) domainAccount(ctx context.Context,
+	host string,
+) (string, error) {
+	if strings.EqualFold(host, s.cfg.ServerHost()) ||
+		!domainPattern.MatchString(host) || len(host) > 253 {
+		return "", nil
+	}
+
+	now := time.Now()
+
+	if aID, ok := s.domains.get(host, now); ok {
+		return aID, nil
+	}
+
+	db := s.DB()
+	if db == nil {
+		return "", nil
+	}
+
+	var d *Domain
+
+	if err := s.tenantCollection(sharedTenant, "domains").FindOne(ctx,
+		bson.M{"domain": host, "status": request.StatusActive},
+		options.FindOne().SetProjection(bson.M{
+			"_id":        0,
+			"account_id": 1,
+		})).Decode(&d); err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return "", errors.Wrap(err, errors.ErrDatabase,
+			"unable to find domain",
+			"domain", host)
+	}
+
+	aID := ""
+
+	if d != nil {
+		aID = d.AccountID
+	}
+
+	s.domains.add(host, aID, now)
+
+	return aID, nil
+}
+
+// domainHostPolicy permits automatic certificates to be obtained for the
+// server host, and for verified custom domains.
+func (s *Server) domainHostPolicy(ctx context.Context, host string) error {
+	if strings.EqualFold(host, s.cfg.ServerHost()) {
+		return nil
+	}
+
+	aID, err := s.domainAccount(ctx, strings.ToLower(host))
+	if err != nil {
+		return err
+	}
+
+	if aID == "" {
+		return errors.New(errors.ErrForbidden,
+			"host not allowed",
+			"host", host)
+	}
+
+	return nil
+}
+
+// getDomain retrieves the custom domain of the current account.
+func (s *Server) getDomain(ctx context.Context) (*Domain, error) {
+	aID, err := request.ContextAccountID(ctx)
+	if err != nil {
+		return nil, errors.New(errors.ErrUnauthorized,
+			"unable to get account id from context")
+	}
+
+	var res *Domain
+
+	c, err := s.collection(ctx, "domains")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.FindOne(ctx,
+		bson.M{"account_id": aID},
+		options.FindOne().SetProjection(bson.M{"_id": 0})).
+		Decode(&res); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, errors.New(errors.ErrNotFound,
+				"domain not found",
+				"account_id", aID)
+		}
+
+		return nil, errors.Wrap(err, errors.ErrDatabase,
+			"unable to find domain",
+			"account_id", aID)
+	}
+
+	return res.setRecord(), nil
+}
+
+// putDomain registers the custom domain of the current account, replacing any
+// previous domain. The domain is pending until it is verified, and is removed
+// if it is not verified before it expires. Domains active for other accounts
+// can not be registered.
+func (s *Server) putDomain(ctx context.Context, req *Domain) (*Domain, error) {
+	aID, err := request.ContextAccountID(ctx)
+	if err != nil {
+		return nil, errors.New(errors.ErrUnauthorized,
+			"unable to get account id from context")
+	}
+
+	if req == nil {
+		return nil, errors.New(errors.ErrInvalidRequest,
+			"missing domain")
+	}
+
+	if err := s.checkEntitlement(ctx, EntitlementPublicGames); err != nil {
+		return nil, err
+	}
+
+	req.Domain = strings.ToLower(strings.TrimSuffix(
+		strings.TrimSpace(req.Domain), "."))
+
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	if strings.EqualFold(req.Domain, s.cfg.ServerHost()) {
+		return nil, errors.New(errors.ErrInvalidRequest,
+			"domain must not be the server host",
+			"domain", req.Domain)
+	}
+
+	prev, err := s.getDomain(ctx)
+	if err != nil && !errors.Has(err, errors.ErrNotFound) {
+		return nil, err
+	}
+
+	if prev != nil && prev.Domain == req.Domain {
+		return prev, nil
+	}
+
+	if err := s.checkDomainAvailable(ctx, aID, req.Domain); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	res := &Domain{
+		AccountID: aID,
+		Domain:    req.Domain,
+		Status:    request.StatusPending,
+		Token:     uuid.NewString(),
+		CreatedAt: now.Unix(),
+		UpdatedAt: now.Unix(),
+		ExpiresAt: now.Add(domainPendingExpiration),
+	}
+
+	c, err := s.collection(ctx, "domains")
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := c.ReplaceOne(ctx,
+		bson.M{"account_id": aID}, res,
+		options.Replace().SetUpsert(true)); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, errors.New(errors.ErrConflict,
+				"domain is registered by another account",
+				"domain", req.Domain)
+		}
+
+		return nil, errors.Wrap(err, errors.ErrDatabase,
+			"unable to store domain",
+			"domain", req.Domain)
+	}
+
+	if prev != nil {
+		s.domains.remove(prev.Domain)
+	}
+
+	return res.setRecord(), nil
+}
+
+// checkDomainAvailable returns an error if a domain is active for an account
+// other than the specified one.
+func (s *Server) checkDomainAvailable(ctx context.Context,
+	aID, domain string,
+) error {
+	c, err := s.collection(ctx, "domains")
+	if err != nil {
+		return err
+	}
+
+	n, err := c.CountDocuments(ctx, bson.M{
+		"domain":     domain,
+		"status":     request.StatusActive,
+		"account_id": bson.M{"$ne": aID},
+	})
+	if err != nil {
+		return errors.Wrap(err, errors.ErrDatabase,
+			"unable to find domain",
+			"domain", domain)
+	}
+
+	if n > 0 {
+		return errors.New(errors.ErrConflict,
+			"domain is registered by another account",
+			"domain", domain)
+	}
+
+	return nil
+}
+
+// verifyDomain verifies the custom domain of the current account, by checking
+// that its DNS TXT record contains the domain token. Once verified, the domain
+// is served, and a certificate is obtained for it, if automatic certificates
+// are enabled. Since verification proves control of the domain, any pending
+// registrations of it by other accounts are removed.
+func (s *Server) verifyDomain(ctx context.Context) (*Domain, error) {
+	d, err := s.getDomain(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if d.Status == request.StatusActive {
+		return d, nil
+	}
+
+	if err := s.checkDomainAvailable(ctx, d.AccountID, d.Domain); err != nil {
+		return nil, err
+	}
+
+	txt, err := net.DefaultResolver.LookupTXT(ctx, d.Record)
+	if err != nil || !slices.Contains(txt, d.Value) {
+		return nil, errors.New(errors.ErrInvalidRequest,
+			"domain verification record not found",
+			"domain", d.Domain,
+			"record", d.Record,
+			"value", d.Value)
+	}
+
+	now := time.Now().Unix()
+
+	c, err := s.collection(ctx, "domains")
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := c.UpdateOne(ctx,
+		bson.M{"account_id": d.AccountID, "domain": d.Domain},
+		bson.M{"$set": bson.M{
+			"status":      request.StatusActive,
+			"verified_at": now,
+			"updated_at":  now,
+		}, "$unset": bson.M{
+			"expires_at": "",
+		}}); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, errors.New(errors.ErrConflict,
+				"domain is registered by another account",
+				"domain", d.Domain)
+		}
+
+		return nil, errors.Wrap(err, errors.ErrDatabase,
+			"unable to update domain",
+			"domain", d.Domain)
+	}
+
+	d.Status = request.StatusActive
+	d.VerifiedAt = now
+	d.UpdatedAt = now
+	d.ExpiresAt = time.Time{}
+
+	if _, err := c.DeleteMany(ctx, bson.M{
+		"domain":     d.Domain,
+		"status":     request.StatusPending,
+		"account_id": bson.M{"$ne": d.AccountID},
+	}); err != nil {
+		s.log.Log(ctx, logger.LvlError,
+			"unable to remove pending domain registrations",
+			"error", err,
+			"domain", d.Domain)
+	}
+
+	s.domains.remove(d.Domain)
+
+	s.provisionCert(ctx, d.Domain)
+
+	return d, nil
+}
+
+// provisionCert obtains a certificate for a verified custom domain in the
+// background, so that the first request to the domain does not wait for it.
+func (s *Server) provisionCert(ctx context.Context, domain string) {
+	if s.certs == nil {
+		return
+	}
+
+	ctx = context.WithoutCancel(ctx)
+
+	s.jobs.Add(1)
+
+	go func() {
+		defer s.jobs.Done()
+
+		if _, err := s.certs.GetCertificate(&tls.ClientHelloInfo{
+			ServerName: domain,
+		}); err != nil {
+			s.log.Log(ctx, logger.LvlError,
+				"unable to obtain domain certificate",
+				"error", err,
+				"domain", domain)
+		}
+	}()
+}
+
+// deleteDomain removes the custom domain of the current account.
+func (s *Server) deleteDomain(ctx context.Context) error {
+	d, err := s.getDomain(ctx)
+	if err != nil {
+		return err
+	}
+
+	c, err := s.collection(ctx, "domains")
+	if err != nil {
+		return err
+	}
+
+	if _, err := c.DeleteOne(ctx,
+		bson.M{"account_id": d.AccountID}); err != nil {
+		return errors.Wrap(err, errors.ErrDatabase,
+			"unable to delete domain",
+			"domain", d.Domain)
+	}
+
+	s.domains.remove(d.Domain)
+
+	return nil
+}
+
+// customDomain wraps the server routes to serve the public gallery and game
+// embeds of accounts on their verified custom domains. Requests to any other
+// host are served by the server routes.
+func (s *Server) customDomain(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		aID, err := s.domainAccount(ctx, domainHost(r))
+		if err != nil {
+			s.log.Log(ctx, logger.LvlError,
+				"unable to find custom domain",
+				"error", err,
+				"host", r.Host)
+		}
+
+		if aID == "" {
+			next.ServeHTTP(w, r)
+
+			return
+		}
+
+		ctx = context.WithValue(ctx, CtxKeyDomainAccountID, aID)
+
+		s.domainRouter().ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// domainRouter returns the routes served on custom domains.
+func (s *Server) domainRouter() chi.Router {
+	s.RLock()
+
+	r := s.dr
+
+	s.RUnlock()
+
+	return r
+}
+
+// initDomainRouter initializes the routes served on custom domains.
+func (s *Server) initDomainRouter() {
+	r := chi.NewRouter()
+
+	r.Use(
+		s.context,
+		s.header,
+		s.logger,
+		s.recoverer,
+	)
+
+	r.NotFound(s.notFound)
+	r.MethodNotAllowed(s.methodNotAllowed)
+
+	r.With(s.dbAvail, s.stat, s.trace).Get("/", s.getDomainGalleryHandler)
+
+	r.With(s.dbAvail, s.stat, s.trace).Get("/sitemap.xml", s.getSitemapHandler)
+
+	r.Mount("/embed", s.embedHandler())
+
+	r.Get("/scripts/wasm_exec.js",
+		func(w http.ResponseWriter, r *http.Request) {
+			s.serveFile(w, r, static.FS, "scripts/wasm_exec.js",
+				"text/javascript; charset=UTF-8")
+		})
+
+	r.Get("/game2d.wasm",
+		func(w http.ResponseWriter, r *http.Request) {
+			s.serveFile(w, r, static.FS, "game2d.wasm", "application/wasm")
+		})
+
+	r.Get("/sw.js", s.getServiceWorkerHandler)
+
+	s.Lock()
+
+	s.dr = r
+
+	s.Unlock()
+}
+
+// getDomainGalleryHandler serves the public gallery of the account served on
+// a custom domain, which lists its public games, linked to their embeds.
+func (s *Server) getDomainGalleryHandler(w http.ResponseWriter,
+	r *http.Request,
+) {
+	ctx := r.Context()
+
+	aID, _ := ctx.Value(CtxKeyDomainAccountID).(string)
+
+	ctx = context.WithValue(ctx, request.CtxKeyAccountID, aID)
+
+	a, err := s.getAccount(ctx, aID)
+	if err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	games, err := s.publicGames(ctx, aID, maxDomainGames)
+	if err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	gg := make([]*galleryGame, 0, len(games))
+
+	for _, g := range games {
+		gg = append(gg, newGalleryGame(g, "/embed/"+g.ID.Value))
+	}
+
+	data := map[string]any{
+		"Name":  a.Name.Value,
+		"URL":   pageURL(r, "/"),
+		"Games": gg,
+	}
+
+	// The icon of the first game is the thumbnail of shared gallery links.
+	if len(gg) > 0 {
+		data["Image"] = pageURL(r, "/embed/"+gg[0].ID+"/icon.svg")
+	}
+
+	b, err := renderPage("gallery", data)
+	if err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=UTF-8")
+	w.Header().Set("Cache-Control", "no-cache")
+
+	if _, err := w.Write(b); err != nil {
+		s.error(err, w, r)
+	}
+}
+
+// getDomainHandler is the get handler used to retrieve the custom domain of
+// the current account.
+func (s *Server) getDomainHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if err := s.checkScope(ctx, request.ScopeAccountRead); err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	res, err := s.getDomain(ctx)
+	if err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	if err := s.encode(w, r, res); err != nil {
+		s.error(err, w, r)
+	}
+}
+
+// putDomainHandler is the put handler used to register the custom domain of
+// the current account.
+func (s *Server) putDomainHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if err := s.checkScope(ctx, request.ScopeAccountAdmin); err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	req := &Domain{}
+
+	if err := s.decode(r, &req); err != nil {
+		switch e := err.(type) {
+		case *errors.Error:
+			s.error(e, w, r)
+		default:
+			s.error(errors.Wrap(err, errors.ErrInvalidRequest,
+				"unable to decode request"), w, r)
+		}
+
+		return
+	}
+
+	res, err := s.putDomain(ctx, req)
+	if err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	if err := s.encode(w, r, res); err != nil {
+		s.error(err, w, r)
+	}
+}
+
+// postDomainVerifyHandler is the post handler used to verify the custom domain
+// of the current account.
+func (s *Server) postDomainVerifyHandler(w http.ResponseWriter,
+	r *http.Request,
+) {
+	ctx := r.Context()
+
+	if err := s.checkScope(ctx, request.ScopeAccountAdmin); err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	res, err := s.verifyDomain(ctx)
+	if err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	if err := s.encode(w, r, res); err != nil {
+		s.error(err, w, r)
+	}
+}
+
+// deleteDomainHandler is the delete handler used to remove the custom domain
+// of the current account.
+func (s *Server) deleteDomainHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if err := s.checkScope(ctx, request.ScopeAccountAdmin); err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	if err := s.deleteDomain(ctx); err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
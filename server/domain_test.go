@@ -0,0 +1,105 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/dhaifley/game2d/config"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+func TestDomainCache(t *testing.T) {
+	t.Parallel()
+
+	c := &domainCache{}
+
+	now := time.Now()
+
+	c.add("old.example.com", "old", now.Add(-2*domainCacheExpiration))
+	c.add("games.example.com", "test", now)
+
+	if aID, ok := c.get("games.example.com", now); !ok || aID != "test" {
+		t.Errorf("Expected account: test, got: %v, %v", aID, ok)
+	}
+
+	if _, ok := c.hits["old.example.com"]; ok {
+		t.Error("Expected expired domain to be removed")
+	}
+
+	for i := range maxDomainMisses + 10 {
+		c.add(fmt.Sprintf("host%d.example.com", i), "", now)
+	}
+
+	if n := len(c.misses); n != maxDomainMisses || c.order.Len() != n {
+		t.Errorf("Expected misses: %v, got: %v", maxDomainMisses, n)
+	}
+
+	if _, ok := c.get("host0.example.com", now); ok {
+		t.Error("Expected least recently used miss to be removed")
+	}
+
+	last := fmt.Sprintf("host%d.example.com", maxDomainMisses+9)
+
+	if aID, ok := c.get(last, now); !ok || aID != "" {
+		t.Errorf("Expected miss, got: %v, %v", aID, ok)
+	}
+
+	if _, ok := c.get(last, now.Add(domainCacheExpiration)); ok {
+		t.Error("Expected expired miss not to be found")
+	}
+
+	if _, ok := c.misses[last]; ok {
+		t.Error("Expected expired miss to be removed")
+	}
+
+	c.remove("games.example.com")
+
+	if _, ok := c.get("games.example.com", now); ok {
+		t.Error("Expected removed domain not to be found")
+	}
+}
+
+func TestDomainAccountHosts(t *testing.T) {
+	t.Parallel()
+
+	svr, err := NewServer(config.NewDefault(), nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The database can not be reached, so only hosts which are looked up
+	// return an error.
+	db, err := mongo.Connect(options.Client().
+		SetHosts([]string{"127.0.0.1:1"}).
+		SetServerSelectionTimeout(100 * time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { _ = db.Disconnect(context.Background()) })
+
+	svr.SetDB(db)
+
+	ctx := context.Background()
+
+	for _, host := range []string{
+		svr.cfg.ServerHost(), "localhost", "127.0.0.1", "::1", "", "invalid",
+		"-invalid.example.com", "invalid_host.example.com",
+	} {
+		if aID, err := svr.domainAccount(ctx, host); err != nil || aID != "" {
+			t.Errorf("Expected host %q not to be looked up, got: %v, %v",
+				host, aID, err)
+		}
+	}
+
+	if _, err := svr.domainAccount(ctx, "games.example.com"); err == nil {
+		t.Error("Expected error looking up custom domain")
+	}
+
+	if _, ok := svr.domains.get("games.example.com", time.Now()); ok {
+		t.Error("Expected failed lookup not to be kept")
+	}
+}
@@ -0,0 +1,265 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dhaifley/game2d/errors"
+	"github.com/dhaifley/game2d/request"
+	"github.com/go-chi/chi/v5"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// DefaultShareExpiration is the default duration for which game share tokens
+// are valid.
+const DefaultShareExpiration = 30 * 24 * time.Hour
+
+// ShareToken values represent tokens used to play a private game in the
+// embedded player.
+type ShareToken struct {
+	GameID     string `json:"game_id"    yaml:"game_id"`
+	Token      string `json:"token"      yaml:"token"`
+	Expiration int64  `json:"expiration" yaml:"expiration"`
+}
+
+// createShareToken creates a token which permits a single game to be played
+// in the embedded player. The token has no scopes, so it can not be used to
+// access the API.
+func (s *Server) createShareToken(ctx context.Context,
+	id string,
+	expiration int64,
+) (*ShareToken, error) {
+	aID, err := request.ContextAccountID(ctx)
+	if err != nil {
+		return nil, errors.New(errors.ErrUnauthorized,
+			"unable to get account id from context")
+	}
+
+	uID, err := request.ContextUserID(ctx)
+	if err != nil {
+		return nil, errors.New(errors.ErrUnauthorized,
+			"unable to get user id from context")
+	}
+
+	g, err := s.getGame(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if g.AccountID.Value != aID {
+		return nil, errors.New(errors.ErrForbidden,
+			"only games owned by the account can be shared",
+			"id", id)
+	}
+
+	if err := s.checkEntitlement(ctx, EntitlementPublicGames); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	if expiration == 0 {
+		expiration = now.Add(DefaultShareExpiration).Unix()
+	}
+
+	if now.Unix() >= expiration {
+		return nil, errors.New(errors.ErrInvalidParameter,
+			"invalid expiration",
+			"expiration", expiration)
+	}
+
+	tok, err := s.signToken(ctx, jwt.MapClaims{
+		"exp":     expiration,
+		"iat":     now.Unix(),
+		"nbf":     now.Unix(),
+		"iss":     s.cfg.AuthTokenIssuer(),
+		"sub":     uID,
+		"aud":     []string{s.cfg.ServiceName()},
+		"scopes":  "",
+		"game_id": id,
+	}, aID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ShareToken{
+		GameID:     id,
+		Token:      tok,
+		Expiration: expiration,
+	}, nil
+}
+
+// authShareToken verifies a game share token, and returns a context for the
+// account which shared the game.
+func (s *Server) authShareToken(ctx context.Context,
+	token, id string,
+) (context.Context, error) {
+	if _, err := s.authJWT(ctx, token, ""); err != nil {
+		return nil, err
+	}
+
+	claims := jwt.MapClaims{}
+
+	tok, _, err := jwt.NewParser().ParseUnverified(token, claims)
+	if err != nil {
+		return nil, errors.New(errors.ErrUnauthorized,
+			"invalid share token")
+	}
+
+	if gID, _ := claims["game_id"].(string); gID == "" || gID != id {
+		return nil, errors.New(errors.ErrUnauthorized,
+			"invalid share token",
+			"id", id)
+	}
+
+	aID, _ := tok.Header["kid"].(string)
+
+	return context.WithValue(ctx, request.CtxKeyAccountID, aID), nil
+}
+
+// embedHandler performs routing for the embedded game player.
+func (s *Server) embedHandler() http.Handler {
+	r := chi.NewRouter()
+
+	r.Use(s.dbAvail)
+
+	r.With(s.stat, s.trace).Get("/{id}", s.getEmbedHandler)
+	r.With(s.stat, s.trace).Get("/{id}/icon.svg", s.getEmbedIconHandler)
+
+	return r
+}
+
+// embedGame retrieves the game played by an embed request. Public games are
+// found by anyone, while private games require a share token. Custom domains
+// only serve the games of the account owning the domain.
+func (s *Server) embedGame(r *http.Request) (context.Context, *Game, error) {
+	ctx := r.Context()
+
+	id := chi.URLParam(r, "id")
+
+	// Only public games are found without a share token.
+	ctx = context.WithValue(ctx, request.CtxKeyAccountID, "")
+
+	if token := r.URL.Query().Get("token"); token != "" {
+		var err error
+
+		ctx, err = s.authShareToken(ctx, token, id)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	g, err := s.getGame(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if aID, ok := r.Context().Value(CtxKeyDomainAccountID).(string); ok &&
+		g.AccountID.Value != aID {
+		return nil, nil, errors.New(errors.ErrNotFound,
+			"game not found",
+			"id", id).WithReason(errors.ReasonGameNotFound)
+	}
+
+	return ctx, g, nil
+}
+
+// getEmbedHandler serves a minimal HTML page which plays a single game, so that
+// it can be embedded in other web pages using an iframe. Public games can be
+// played by anyone, while private games require a share token. If the account
+// owning the game restricts its allowed origins, the game can only be embedded
+// on those origins.
+func (s *Server) getEmbedHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, g, err := s.embedGame(r)
+	if err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	origins, err := s.accountOrigins(ctx, g.AccountID.Value)
+	if err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	image := pageURL(r, "/embed/"+g.ID.Value+"/icon.svg")
+
+	// Private games need their share token for their icons as well.
+	if token := r.URL.Query().Get("token"); token != "" {
+		image += "?" + url.Values{"token": {token}}.Encode()
+	}
+
+	b, err := embedPage(g, "/", &pageMeta{
+		URL:    pageURL(r, r.URL.Path),
+		Image:  image,
+		Worker: "/sw.js",
+	})
+	if err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=UTF-8")
+	w.Header().Set("Cache-Control", "no-store")
+
+	// Accounts which restrict their allowed origins may only have their games
+	// embedded on those sites.
+	if origins != nil {
+		w.Header().Set("Content-Security-Policy", "frame-ancestors 'self' "+
+			strings.Join(origins, " "))
+	}
+
+	if _, err := w.Write(b); err != nil {
+		s.error(err, w, r)
+	}
+}
+
+// postGameShareHandler is the handler function for creating game share tokens.
+func (s *Server) postGameShareHandler(w http.ResponseWriter,
+	r *http.Request,
+) {
+	ctx := r.Context()
+
+	if err := s.checkScope(ctx, request.ScopeGamesWrite); err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+
+	var expiration int64
+
+	if qp := r.URL.Query().Get("expiration"); qp != "" {
+		v, err := strconv.ParseInt(qp, 10, 64)
+		if err != nil {
+			s.error(errors.New(errors.ErrInvalidParameter,
+				"invalid expiration",
+				"expiration", qp), w, r)
+
+			return
+		}
+
+		expiration = v
+	}
+
+	res, err := s.createShareToken(ctx, id, expiration)
+	if err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+
+	if err := s.encode(w, r, res); err != nil {
+		s.error(err, w, r)
+	}
+}
@@ -0,0 +1,390 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"hash/fnv"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/dhaifley/game2d/errors"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// Embedding providers, which create the embeddings used to find similar games.
+// The hash provider creates embeddings from the words of the games, without
+// an external service.
+const (
+	EmbeddingProviderNone   = "none"
+	EmbeddingProviderHash   = "hash"
+	EmbeddingProviderOpenAI = "openai"
+)
+
+const (
+	// hashEmbeddingSize is the number of dimensions of hash embeddings.
+	hashEmbeddingSize = 256
+
+	// maxEmbeddingText is the maximum size of the text of a game which is
+	// embedded, in bytes.
+	maxEmbeddingText = 8192
+
+	// maxEmbeddingBatch is the maximum number of texts embedded in a single
+	// request to the embedding provider.
+	maxEmbeddingBatch = 100
+)
+
+// Embedder values are able to create embedding vectors of texts.
+type Embedder interface {
+	Embed(ctx context.Context, texts []string) ([][]float64, error)
+}
+
+// GameEmbedding values contain the embedding vector of a game. The hash
+// identifies the text of the game which was embedded, and the model the
+// embedding provider and model which embedded it, so that embeddings are
+// created again if either changes.
+type GameEmbedding struct {
+	GameID    string    `bson:"game_id"`
+	Model     string    `bson:"model"`
+	Hash      string    `bson:"hash"`
+	Vector    []float64 `bson:"vector"`
+	UpdatedAt int64     `bson:"updated_at"`
+}
+
+// getEmbedder returns the embedder used to find similar games, or nil if
+// similar games are disabled.
+func (s *Server) getEmbedder() Embedder {
+	s.RLock()
+
+	e := s.embedder
+
+	s.RUnlock()
+
+	if e != nil {
+		return e
+	}
+
+	switch s.cfg.EmbeddingProvider() {
+	case EmbeddingProviderHash:
+		return &hashEmbedder{}
+	case EmbeddingProviderOpenAI:
+		return &openAIEmbedder{s: s}
+	}
+
+	return nil
+}
+
+// embeddingModel returns the name identifying the embeddings created by the
+// configured embedding provider.
+func (s *Server) embeddingModel() string {
+	switch p := s.cfg.EmbeddingProvider(); p {
+	case EmbeddingProviderOpenAI:
+		return p + "/" + s.cfg.EmbeddingModel()
+	default:
+		return p
+	}
+}
+
+// hashEmbedder values create embeddings by hashing the words of texts into a
+// fixed number of dimensions.
+type hashEmbedder struct{}
+
+// Embed creates the embedding vectors of texts.
+func (h *hashEmbedder) Embed(ctx context.Context,
+	texts []string,
+) ([][]float64, error) {
+	res := make([][]float64, 0, len(texts))
+
+	for _, text := range texts {
+		v := make([]float64, hashEmbeddingSize)
+
+		for _, w := range strings.FieldsFunc(strings.ToLower(text),
+			func(r rune) bool {
+				return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+			}) {
+			if len(w) < 2 {
+				continue
+			}
+
+			f := fnv.New32a()
+
+			f.Write([]byte(w))
+
+			n := f.Sum32()
+
+			// The sign reduces the bias of words hashed to the same index.
+			if n&(1<<31) != 0 {
+				v[n%hashEmbeddingSize]--
+			} else {
+				v[n%hashEmbeddingSize]++
+			}
+		}
+
+		res = append(res, normalize(v))
+	}
+
+	return res, nil
+}
+
+// openAIEmbedder values create embeddings using the OpenAI embeddings API, or
+// any API compatible with it. The configuration is read for every request, so
+// that rotated API keys are used.
+type openAIEmbedder struct {
+	s *Server
+}
+
+// Embed creates the embedding vectors of texts.
+func (e *openAIEmbedder) Embed(ctx context.Context,
+	texts []string,
+) ([][]float64, error) {
+	b, err := json.Marshal(map[string]any{
+		"model": e.s.cfg.EmbeddingModel(),
+		"input": texts,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrServer,
+			"unable to encode embedding request")
+	}
+
+	ctx, cancel := e.s.opContext(ctx, opOutbound)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		e.s.cfg.EmbeddingURL(), bytes.NewReader(b))
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrClient,
+			"unable to create embedding request")
+	}
+
+	req.Header.Set("Authorization", "Bearer "+e.s.cfg.EmbeddingAPIKey())
+	req.Header.Set("Content-Type", "application/json")
+
+	cli := &http.Client{Timeout: e.s.cfg.ServerOutboundTimeout()}
+
+	res, err := cli.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrClient,
+			"unable to send embedding request")
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, errors.New(errors.ErrClient,
+			"unexpected embedding response status",
+			"status", res.StatusCode)
+	}
+
+	var er struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float64 `json:"embedding"`
+		} `json:"data"`
+	}
+
+	if err := json.NewDecoder(res.Body).Decode(&er); err != nil {
+		return nil, errors.Wrap(err, errors.ErrClient,
+			"unable to decode embedding response")
+	}
+
+	vs := make([][]float64, len(texts))
+
+	for _, d := range er.Data {
+		if d.Index < 0 || d.Index >= len(vs) {
+			return nil, errors.New(errors.ErrClient,
+				"invalid embedding response index",
+				"index", d.Index)
+		}
+
+		vs[d.Index] = normalize(d.Embedding)
+	}
+
+	return vs, nil
+}
+
+// normalize scales a vector to unit length, so that the similarity of vectors
+// is their dot product.
+func normalize(v []float64) []float64 {
+	var n float64
+
+	for _, x := range v {
+		n += x * x
+	}
+
+	if n = math.Sqrt(n); n == 0 {
+		return v
+	}
+
+	for i := range v {
+		v[i] /= n
+	}
+
+	return v
+}
+
+// similarity returns the cosine similarity of two normalized vectors, or zero
+// if they were not created by the same model.
+func similarity(a, b []float64) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+
+	var res float64
+
+	for i := range a {
+		res += a[i] * b[i]
+	}
+
+	return res
+}
+
+// embeddingText returns the text of a game which is embedded, consisting of
+// its metadata and its script.
+func embeddingText(g *Game) string {
+	script := g.Script.Value
+
+	if b, err := base64.StdEncoding.DecodeString(script); err == nil {
+		script = string(b)
+	}
+
+	res := strings.Join([]string{
+		g.Name.Value,
+		g.Description.Value,
+		g.Controls.Value,
+		strings.Join(g.Tags.Value, " "),
+		script,
+	}, "\n")
+
+	if len(res) > maxEmbeddingText {
+		res = strings.ToValidUTF8(res[:maxEmbeddingText], "")
+	}
+
+	return res
+}
+
+// gameEmbeddings returns the embedding vectors of games, keyed by game ID.
+// Stored embeddings are used, unless the games have changed since they were
+// created, in which case they are created again and stored.
+func (s *Server) gameEmbeddings(ctx context.Context,
+	games []*Game,
+) (map[string][]float64, error) {
+	e := s.getEmbedder()
+	if e == nil {
+		return nil, errors.New(errors.ErrUnavailable,
+			"similar games are not available")
+	}
+
+	res := make(map[string][]float64, len(games))
+
+	if len(games) == 0 {
+		return res, nil
+	}
+
+	ids := make([]string, 0, len(games))
+
+	for _, g := range games {
+		ids = append(ids, g.ID.Value)
+	}
+
+	c, err := s.collection(ctx, "game_embeddings")
+	if err != nil {
+		return nil, err
+	}
+
+	cur, err := c.Find(ctx,
+		bson.M{"game_id": bson.M{"$in": ids}},
+		options.Find().SetProjection(bson.M{"_id": 0}))
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrDatabase,
+			"unable to find game embeddings")
+	}
+
+	var stored []*GameEmbedding
+
+	if err := cur.All(ctx, &stored); err != nil {
+		return nil, errors.Wrap(err, errors.ErrDatabase,
+			"unable to decode game embeddings")
+	}
+
+	model := s.embeddingModel()
+
+	hashes := make(map[string]string, len(stored))
+
+	for _, ge := range stored {
+		if ge.Model == model {
+			hashes[ge.GameID] = ge.Hash
+			res[ge.GameID] = ge.Vector
+		}
+	}
+
+	var stale []*GameEmbedding
+
+	texts := []string{}
+
+	for _, g := range games {
+		text := embeddingText(g)
+
+		h := sha256.Sum256([]byte(text))
+
+		hash := hex.EncodeToString(h[:])
+
+		if hashes[g.ID.Value] == hash {
+			continue
+		}
+
+		stale = append(stale, &GameEmbedding{
+			GameID: g.ID.Value,
+			Model:  model,
+			Hash:   hash,
+		})
+
+		texts = append(texts, text)
+	}
+
+	if len(stale) == 0 {
+		return res, nil
+	}
+
+	now := time.Now().Unix()
+
+	wm := make([]mongo.WriteModel, 0, len(stale))
+
+	for i := 0; i < len(stale); i += maxEmbeddingBatch {
+		end := min(i+maxEmbeddingBatch, len(stale))
+
+		vs, err := e.Embed(ctx, texts[i:end])
+		if err != nil {
+			return nil, errors.Wrap(err, errors.ErrClient,
+				"unable to create game embeddings")
+		}
+
+		for j, v := range vs {
+			ge := stale[i+j]
+
+			ge.Vector = v
+			ge.UpdatedAt = now
+
+			res[ge.GameID] = v
+
+			wm = append(wm, mongo.NewReplaceOneModel().
+				SetFilter(bson.M{"game_id": ge.GameID}).
+				SetReplacement(ge).SetUpsert(true))
+		}
+	}
+
+	if _, err := c.BulkWrite(ctx, wm,
+		options.BulkWrite().SetOrdered(false)); err != nil {
+		return nil, errors.Wrap(err, errors.ErrDatabase,
+			"unable to store game embeddings")
+	}
+
+	return res, nil
+}
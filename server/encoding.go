@@ -0,0 +1,321 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/dhaifley/game2d/cbor"
+	"github.com/dhaifley/game2d/errors"
+	"github.com/dhaifley/game2d/logger"
+	"gopkg.in/yaml.v3"
+)
+
+// Content types supported for request and response bodies.
+const (
+	ContentTypeJSON = "application/json"
+	ContentTypeYAML = "application/yaml"
+	ContentTypeCBOR = cbor.ContentType
+)
+
+// mediaType returns the supported content type matching a media type, or an
+// empty string if the media type is not supported.
+func mediaType(mt string) string {
+	switch strings.ToLower(strings.TrimSpace(mt)) {
+	case ContentTypeJSON, "text/json", "*/*", "application/*":
+		return ContentTypeJSON
+	case ContentTypeYAML, "application/x-yaml", "text/yaml", "text/x-yaml":
+		return ContentTypeYAML
+	case ContentTypeCBOR:
+		return ContentTypeCBOR
+	default:
+		return ""
+	}
+}
+
+// requestType returns the content type of a request body. JSON is assumed if
+// the request does not specify a supported content type.
+func requestType(r *http.Request) string {
+	mt, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return ContentTypeJSON
+	}
+
+	if ct := mediaType(mt); ct != "" {
+		return ct
+	}
+
+	return ContentTypeJSON
+}
+
+// responseType returns the content type of a response body, negotiated using
+// the Accept header of the request. JSON is used if the request does not accept
+// any supported content type.
+func responseType(r *http.Request) string {
+	res, best := ContentTypeJSON, 0.0
+
+	for _, v := range r.Header.Values("Accept") {
+		for _, a := range strings.Split(v, ",") {
+			mt, params, err := mime.ParseMediaType(a)
+			if err != nil {
+				continue
+			}
+
+			ct := mediaType(mt)
+			if ct == "" {
+				continue
+			}
+
+			q := 1.0
+
+			if v, ok := params["q"]; ok {
+				if q, err = strconv.ParseFloat(v, 64); err != nil {
+					continue
+				}
+			}
+
+			// Exact matches are preferred over wildcards of equal quality.
+			if q > best || (q == best && q > 0 && !strings.Contains(mt, "*")) {
+				res, best = ct, q
+			}
+		}
+	}
+
+	return res
+}
+
+// decode reads the body of a request into a value, using the content type of
+// the request.
+func (s *Server) decode(r *http.Request, v any) error {
+	var err error
+
+	switch requestType(r) {
+	case ContentTypeYAML:
+		err = yaml.NewDecoder(r.Body).Decode(v)
+	case ContentTypeCBOR:
+		var b []byte
+
+		if b, err = io.ReadAll(r.Body); err == nil {
+			err = cbor.Unmarshal(b, v)
+		}
+	default:
+		err = json.NewDecoder(r.Body).Decode(v)
+	}
+
+	var mbe *http.MaxBytesError
+
+	if errors.As(err, &mbe) {
+		reason := errors.ReasonRequestTooLarge
+
+		p := strings.TrimPrefix(r.URL.Path, s.cfg.ServerPathPrefix())
+		if (p == "/games" || strings.HasPrefix(p, "/games/")) &&
+			!strings.HasSuffix(p, "/tags") {
+			reason = errors.ReasonGameTooLarge
+		}
+
+		return errors.New(errors.ErrTooLarge,
+			"request body too large",
+			"limit", mbe.Limit).WithReason(reason)
+	}
+
+	return err
+}
+
+// responseBufferSize is the size, in bytes, up to which response bodies are
+// buffered before they are written. Larger responses are streamed, so that the
+// memory used by each response is bounded.
+const responseBufferSize = 1024 * 1024
+
+// bufferPool contains the buffers used to encode responses.
+var bufferPool = sync.Pool{New: func() any { return &bytes.Buffer{} }}
+
+// getBuffer returns an empty buffer from the pool.
+func getBuffer() *bytes.Buffer {
+	buf := bufferPool.Get().(*bytes.Buffer)
+
+	buf.Reset()
+
+	return buf
+}
+
+// putBuffer returns a buffer to the pool, unless it has grown so large that
+// the pool should not retain it.
+func putBuffer(buf *bytes.Buffer) {
+	if buf.Cap() <= 2*responseBufferSize {
+		bufferPool.Put(buf)
+	}
+}
+
+// bufferedWriter values buffer the body of a response, up to the response
+// buffer size, after which the buffered body, and everything written after it,
+// is written directly to the response.
+type bufferedWriter struct {
+	w         http.ResponseWriter
+	buf       *bytes.Buffer
+	streaming bool
+}
+
+// Write writes to the buffer, or to the response, once the buffer is full.
+func (bw *bufferedWriter) Write(p []byte) (int, error) {
+	if !bw.streaming {
+		if bw.buf.Len()+len(p) <= responseBufferSize {
+			return bw.buf.Write(p)
+		}
+
+		bw.streaming = true
+
+		if _, err := bw.w.Write(bw.buf.Bytes()); err != nil {
+			return 0, err
+		}
+
+		bw.buf.Reset()
+	}
+
+	return bw.w.Write(p)
+}
+
+// flush writes any buffered body to the response, with its content length.
+func (bw *bufferedWriter) flush() error {
+	if bw.streaming {
+		return nil
+	}
+
+	bw.w.Header().Set("Content-Length", strconv.Itoa(bw.buf.Len()))
+
+	_, err := bw.w.Write(bw.buf.Bytes())
+
+	return err
+}
+
+// encode writes a value to the body of a response, using the content type
+// negotiated with the request. Small responses are written from a pooled
+// buffer, so that nothing is written if encoding fails, while large responses
+// are streamed, and their connection is aborted if encoding fails.
+func (s *Server) encode(w http.ResponseWriter, r *http.Request, v any) error {
+	buf := getBuffer()
+	defer putBuffer(buf)
+
+	bw := &bufferedWriter{w: w, buf: buf}
+
+	var err error
+
+	switch responseType(r) {
+	case ContentTypeYAML:
+		enc := yaml.NewEncoder(bw)
+
+		if err = enc.Encode(v); err == nil {
+			err = enc.Close()
+		}
+	case ContentTypeCBOR:
+		err = cbor.NewEncoder(bw).Encode(v)
+	default:
+		err = encodeJSON(bw, v)
+	}
+
+	if err != nil {
+		if bw.streaming {
+			// Part of the response was written, so the error can not be
+			// returned. The connection is aborted, so that the client does
+			// not receive the truncated response as though it were complete.
+			s.log.Log(r.Context(), logger.LvlError,
+				"unable to encode response",
+				"error", err,
+				"kind", r.Method,
+				"uri", r.RequestURI)
+
+			panic(http.ErrAbortHandler)
+		}
+
+		return err
+	}
+
+	return bw.flush()
+}
+
+// jsonMarshalerType is the type of values which encode themselves as JSON.
+var jsonMarshalerType = reflect.TypeFor[json.Marshaler]()
+
+// encodeJSON writes the JSON encoding of a value. The elements of slices are
+// encoded and written one at a time, so that only the largest element, rather
+// than the whole slice, is held in memory.
+func encodeJSON(w io.Writer, v any) error {
+	rv := reflect.ValueOf(v)
+
+	if rv.Kind() != reflect.Slice || rv.Len() == 0 ||
+		rv.Type().Elem().Kind() == reflect.Uint8 ||
+		rv.Type().Implements(jsonMarshalerType) {
+		return json.NewEncoder(w).Encode(v)
+	}
+
+	buf := getBuffer()
+	defer putBuffer(buf)
+
+	enc := json.NewEncoder(buf)
+
+	sep := byte('[')
+
+	for i := range rv.Len() {
+		// Elements are addressable, as they are when encoding the slice, so
+		// that methods with pointer receivers are used.
+		ev := rv.Index(i)
+		if k := ev.Kind(); k != reflect.Pointer && k != reflect.Interface {
+			ev = ev.Addr()
+		}
+
+		buf.Reset()
+		buf.WriteByte(sep)
+
+		if err := enc.Encode(ev.Interface()); err != nil {
+			return err
+		}
+
+		// The newline following each encoded value is removed.
+		buf.Truncate(buf.Len() - 1)
+
+		if _, err := w.Write(buf.Bytes()); err != nil {
+			return err
+		}
+
+		sep = ','
+	}
+
+	_, err := io.WriteString(w, "]\n")
+
+	return err
+}
+
+// writeJSON writes a response, with a status code, and a body containing the
+// JSON encoding of a value, which is encoded using a pooled buffer.
+func writeJSON(w http.ResponseWriter, status int, v any) error {
+	buf := getBuffer()
+	defer putBuffer(buf)
+
+	if err := json.NewEncoder(buf).Encode(v); err != nil {
+		w.WriteHeader(status)
+
+		return err
+	}
+
+	w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+	w.WriteHeader(status)
+
+	_, err := w.Write(buf.Bytes())
+
+	return err
+}
+
+// contentType returns the Content-Type header value for a response content
+// type. Text formats include their character set.
+func contentType(ct string) string {
+	if ct == ContentTypeCBOR {
+		return ct
+	}
+
+	return ct + "; charset=utf-8"
+}
@@ -0,0 +1,121 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dhaifley/game2d/config"
+)
+
+// failJSON values fail to encode as JSON, if fail is set.
+type failJSON struct {
+	data string
+	fail bool
+}
+
+func (f *failJSON) MarshalJSON() ([]byte, error) {
+	if f.fail {
+		return nil, fmt.Errorf("unable to encode")
+	}
+
+	return json.Marshal(f.data)
+}
+
+// failJSONSlice returns a slice of values, encoded as roughly the specified
+// number of bytes, the last of which fails to encode.
+func failJSONSlice(size int) []*failJSON {
+	res := []*failJSON{}
+
+	for n := 0; n < size; n += 1024 {
+		res = append(res, &failJSON{data: strings.Repeat("a", 1024)})
+	}
+
+	return append(res, &failJSON{fail: true})
+}
+
+func TestEncodeError(t *testing.T) {
+	t.Parallel()
+
+	log := &bytes.Buffer{}
+
+	svr, err := NewServer(config.NewDefault(),
+		slog.New(slog.NewTextHandler(log, nil)), nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Nothing is written if a small response fails to encode.
+	w := httptest.NewRecorder()
+
+	r := httptest.NewRequest(http.MethodGet, "/api/v1/games", nil)
+
+	if err := svr.encode(w, r, failJSONSlice(1024)); err == nil {
+		t.Error("Expected encoding error")
+	}
+
+	if w.Body.Len() != 0 {
+		t.Errorf("Expected empty body, got: %v bytes", w.Body.Len())
+	}
+
+	// The connection is aborted if a large response fails to encode, after
+	// part of it was written.
+	w = httptest.NewRecorder()
+
+	func() {
+		defer func() {
+			if v := recover(); v != http.ErrAbortHandler {
+				t.Errorf("Expected panic: %v, got: %v",
+					http.ErrAbortHandler, v)
+			}
+		}()
+
+		_ = svr.encode(w, r, failJSONSlice(2*responseBufferSize))
+
+		t.Error("Expected encoding to abort the handler")
+	}()
+
+	if w.Body.Len() < responseBufferSize {
+		t.Errorf("Expected streamed body, got: %v bytes", w.Body.Len())
+	}
+
+	if !strings.Contains(log.String(), "unable to encode response") {
+		t.Errorf("Expected encoding error logged, got: %v", log.String())
+	}
+}
+
+func TestEncodeErrorAbort(t *testing.T) {
+	t.Parallel()
+
+	svr, err := NewServer(config.NewDefault(), nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts := httptest.NewServer(svr.recoverer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			if err := svr.encode(w, r,
+				failJSONSlice(2*responseBufferSize)); err != nil {
+				svr.error(err, w, r)
+			}
+		})))
+
+	t.Cleanup(ts.Close)
+
+	res, err := http.Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer res.Body.Close()
+
+	if _, err := io.ReadAll(res.Body); err == nil {
+		t.Error("Expected truncated response to fail to read")
+	}
+}
@@ -0,0 +1,9 @@
+package server
+
+import "sync/atomic"
+
+// SetOpenContexts sets the counter of the operation contexts of the server
+// which have not been canceled, so that tests can detect leaked contexts.
+func (s *Server) SetOpenContexts(n *atomic.Int64) {
+	s.openContexts.Store(n)
+}
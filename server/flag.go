@@ -0,0 +1,360 @@
+package server
+
+import (
+	"context"
+	"hash/fnv"
+	"net/http"
+	"time"
+
+	"github.com/dhaifley/game2d/errors"
+	"github.com/dhaifley/game2d/request"
+	"github.com/go-chi/chi/v5"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// Feature flag names.
+const (
+	FlagMultiplayer = "multiplayer"
+	FlagAIProviders = "ai_providers"
+)
+
+// Feature flag evaluation sources, in order of increasing precedence.
+const (
+	FlagSourceDefault = "default"
+	FlagSourceRollout = "rollout"
+	FlagSourceAccount = "account"
+	FlagSourceUser    = "user"
+)
+
+// FlagDefinition values describe a feature flag known to the server.
+type FlagDefinition struct {
+	Name        string
+	Description string
+}
+
+// FlagDefinitions contains all feature flags known to the server.
+var FlagDefinitions = []FlagDefinition{{
+	Name:        FlagMultiplayer,
+	Description: "Multiplayer game sessions.",
+}, {
+	Name:        FlagAIProviders,
+	Description: "Additional AI providers for game prompts.",
+}}
+
+// Flag values represent the evaluated state of a feature flag for a user.
+type Flag struct {
+	Name        string `json:"name"        yaml:"name"`
+	Description string `json:"description" yaml:"description"`
+	Enabled     bool   `json:"enabled"     yaml:"enabled"`
+	Source      string `json:"source"      yaml:"source"`
+}
+
+// FlagOverride values represent a feature flag setting stored for an account,
+// or for a single user of an account, if a user ID is specified.
+type FlagOverride struct {
+	AccountID string `bson:"account_id" json:"-"                 yaml:"-"`
+	UserID    string `bson:"user_id"    json:"user_id,omitempty" yaml:"user_id,omitempty"`
+	Name      string `bson:"name"       json:"name"              yaml:"name"`
+	Enabled   bool   `bson:"enabled"    json:"enabled"           yaml:"enabled"`
+	UpdatedAt int64  `bson:"updated_at" json:"updated_at"        yaml:"updated_at"`
+	UpdatedBy string `bson:"updated_by" json:"updated_by"        yaml:"updated_by"`
+}
+
+// validFlag returns whether a feature flag name is known to the server.
+func validFlag(name string) bool {
+	for _, fd := range FlagDefinitions {
+		if fd.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// flagRollout returns whether a feature flag is enabled for an account by a
+// percentage rollout. Accounts are assigned to a stable bucket for each flag,
+// so that increasing the percentage only ever enables additional accounts.
+func flagRollout(name, accountID string, pct int) bool {
+	if pct <= 0 {
+		return false
+	}
+
+	h := fnv.New32a()
+
+	h.Write([]byte(name + "/" + accountID))
+
+	return int(h.Sum32()%100) < pct
+}
+
+// getFlags evaluates all feature flags for the current user. User overrides
+// take precedence over account overrides, which take precedence over the
+// configured rollout percentage.
+func (s *Server) getFlags(ctx context.Context) ([]*Flag, error) {
+	aID, err := request.ContextAccountID(ctx)
+	if err != nil {
+		return nil, errors.New(errors.ErrUnauthorized,
+			"unable to get account id from context")
+	}
+
+	uID, err := request.ContextUserID(ctx)
+	if err != nil {
+		return nil, errors.New(errors.ErrUnauthorized,
+			"unable to get user id from context")
+	}
+
+	f := bson.M{
+		"account_id": aID,
+		"user_id":    bson.M{"$in": []string{"", uID}},
+	}
+
+	cur, err := s.DB().Collection("flags").Find(ctx, f)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrDatabase,
+			"unable to find feature flags")
+	}
+
+	var overrides []*FlagOverride
+
+	if err := cur.All(ctx, &overrides); err != nil {
+		return nil, errors.Wrap(err, errors.ErrDatabase,
+			"unable to decode feature flags")
+	}
+
+	rollout := s.cfg.FeatureRollout()
+
+	res := make([]*Flag, 0, len(FlagDefinitions))
+
+	for _, fd := range FlagDefinitions {
+		fl := &Flag{
+			Name:        fd.Name,
+			Description: fd.Description,
+			Source:      FlagSourceDefault,
+		}
+
+		if pct, ok := rollout[fd.Name]; ok {
+			fl.Enabled = flagRollout(fd.Name, aID, pct)
+			fl.Source = FlagSourceRollout
+		}
+
+		for _, o := range overrides {
+			if o == nil || o.Name != fd.Name {
+				continue
+			}
+
+			if o.UserID != "" {
+				fl.Enabled, fl.Source = o.Enabled, FlagSourceUser
+			} else if fl.Source != FlagSourceUser {
+				fl.Enabled, fl.Source = o.Enabled, FlagSourceAccount
+			}
+		}
+
+		res = append(res, fl)
+	}
+
+	return res, nil
+}
+
+// flagEnabled returns whether a feature flag is enabled for the current user.
+// Flags are disabled if they can not be evaluated.
+func (s *Server) flagEnabled(ctx context.Context, name string) bool {
+	flags, err := s.getFlags(ctx)
+	if err != nil {
+		return false
+	}
+
+	for _, fl := range flags {
+		if fl.Name == name {
+			return fl.Enabled
+		}
+	}
+
+	return false
+}
+
+// requireFlag is middleware which responds with not found to requests from
+// users for whom a feature flag is not enabled.
+func (s *Server) requireFlag(name string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !s.flagEnabled(r.Context(), name) {
+				s.error(errors.New(errors.ErrNotFound,
+					"feature not enabled",
+					"flag", name).
+					WithReason(errors.ReasonFeatureDisabled), w, r)
+
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// setFlag stores a feature flag override for the current account, or for a
+// user of the account.
+func (s *Server) setFlag(ctx context.Context,
+	v *FlagOverride,
+) (*FlagOverride, error) {
+	aID, err := request.ContextAccountID(ctx)
+	if err != nil {
+		return nil, errors.New(errors.ErrUnauthorized,
+			"unable to get account id from context")
+	}
+
+	uID, err := request.ContextUserID(ctx)
+	if err != nil {
+		return nil, errors.New(errors.ErrUnauthorized,
+			"unable to get user id from context")
+	}
+
+	if v == nil {
+		return nil, errors.New(errors.ErrInvalidRequest,
+			"missing feature flag")
+	}
+
+	if !validFlag(v.Name) {
+		return nil, errors.New(errors.ErrNotFound,
+			"feature flag not found",
+			"flag", v.Name)
+	}
+
+	if v.UserID != "" && !request.ValidUserID(v.UserID) {
+		return nil, errors.New(errors.ErrInvalidRequest,
+			"invalid user id",
+			"user_id", v.UserID)
+	}
+
+	v.AccountID = aID
+	v.UpdatedAt = time.Now().Unix()
+	v.UpdatedBy = uID
+
+	f := bson.M{"account_id": aID, "user_id": v.UserID, "name": v.Name}
+
+	if _, err := s.DB().Collection("flags").ReplaceOne(ctx, f, v,
+		options.Replace().SetUpsert(true)); err != nil {
+		return nil, errors.Wrap(err, errors.ErrDatabase,
+			"unable to set feature flag",
+			"flag", v.Name)
+	}
+
+	return v, nil
+}
+
+// deleteFlag removes a feature flag override for the current account, or for
+// a user of the account.
+func (s *Server) deleteFlag(ctx context.Context,
+	name, userID string,
+) error {
+	aID, err := request.ContextAccountID(ctx)
+	if err != nil {
+		return errors.New(errors.ErrUnauthorized,
+			"unable to get account id from context")
+	}
+
+	if !validFlag(name) {
+		return errors.New(errors.ErrNotFound,
+			"feature flag not found",
+			"flag", name)
+	}
+
+	f := bson.M{"account_id": aID, "user_id": userID, "name": name}
+
+	if _, err := s.DB().Collection("flags").DeleteOne(ctx, f); err != nil {
+		return errors.Wrap(err, errors.ErrDatabase,
+			"unable to delete feature flag",
+			"flag", name)
+	}
+
+	return nil
+}
+
+// flagsHandler performs routing for feature flag requests.
+func (s *Server) flagsHandler() http.Handler {
+	r := chi.NewRouter()
+
+	r.Use(s.dbAvail)
+
+	r.With(s.stat, s.trace, s.auth).Get("/", s.getFlagsHandler)
+	r.With(s.stat, s.trace, s.auth).Put("/{name}", s.putFlagHandler)
+	r.With(s.stat, s.trace, s.auth).Delete("/{name}", s.deleteFlagHandler)
+
+	return r
+}
+
+// getFlagsHandler is the get handler used to evaluate the feature flags for
+// the current user.
+func (s *Server) getFlagsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if err := s.checkScope(ctx, request.ScopeUserRead); err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	res, err := s.getFlags(ctx)
+	if err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	if err := s.encode(w, r, res); err != nil {
+		s.error(err, w, r)
+	}
+}
+
+// putFlagHandler is the put handler used to set a feature flag override.
+func (s *Server) putFlagHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if err := s.checkScope(ctx, request.ScopeAccountAdmin); err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	req := &FlagOverride{}
+
+	if err := s.decode(r, &req); err != nil {
+		s.error(errors.Wrap(err, errors.ErrInvalidRequest,
+			"unable to decode request"), w, r)
+
+		return
+	}
+
+	req.Name = chi.URLParam(r, "name")
+
+	res, err := s.setFlag(ctx, req)
+	if err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	if err := s.encode(w, r, res); err != nil {
+		s.error(err, w, r)
+	}
+}
+
+// deleteFlagHandler is the delete handler used to remove a feature flag
+// override.
+func (s *Server) deleteFlagHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if err := s.checkScope(ctx, request.ScopeAccountAdmin); err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	if err := s.deleteFlag(ctx, chi.URLParam(r, "name"),
+		r.URL.Query().Get("user_id")); err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
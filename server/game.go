@@ -7,14 +7,19 @@ import (
 	"net/http"
 	"net/url"
 	"path/filepath"
+	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/dhaifley/game2d/cache"
+	"github.com/dhaifley/game2d/client/luaapi"
 	"github.com/dhaifley/game2d/errors"
 	"github.com/dhaifley/game2d/logger"
+	"github.com/dhaifley/game2d/notify"
+	"github.com/dhaifley/game2d/repo"
 	"github.com/dhaifley/game2d/request"
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
@@ -24,12 +29,63 @@ import (
 	"gopkg.in/yaml.v3"
 )
 
+// localePattern matches the language tags of game locales, such as en, fr or
+// pt-BR.
+var localePattern = regexp.MustCompile(`^[a-z]{2,3}(-[A-Za-z0-9]{2,8})*$`)
+
 // Context keys.
 const (
 	CtxKeyGameNoCount         = "game_no_count"
+	CtxKeyGameEstimatedCount  = "game_estimated_count"
 	CtxKeyGameMinData         = "game_min_data"
 	CtxKeyGameAllowPreviousID = "game_allow_previous_id"
 	CtxKeyGameAllowTags       = "game_allow_tags"
+	CtxKeyGameTags            = "game_tags"
+	CtxKeyGameCompress        = "game_compress"
+	CtxKeyGameAssetRefs       = "game_asset_refs"
+)
+
+// Game listing limits.
+const (
+	// maxBatchGames is the maximum number of games which may be retrieved by
+	// ID in a single request.
+	maxBatchGames = 100
+
+	// maxEstimatedGames is the maximum number of games counted for estimated
+	// counts. The estimated document count of the collection is not used,
+	// since listings are always limited to an account, or to public games.
+	maxEstimatedGames = 10000
+)
+
+// gameSortFields are the fields which may be used to sort game listings. Each
+// is indexed for the games of an account.
+var gameSortFields = []string{
+	"id", "name", "status", "created_at", "updated_at",
+}
+
+// sortDocument converts the fields used to sort search results into a sort
+// document, which keeps the order of the fields.
+func sortDocument(fields []request.SortField) bson.D {
+	res := bson.D{}
+
+	for _, f := range fields {
+		dir := 1
+
+		if f.Desc {
+			dir = -1
+		}
+
+		res = append(res, bson.E{Key: f.Name, Value: dir})
+	}
+
+	return res
+}
+
+// Game count modes, requested using the count query parameter.
+const (
+	GameCountExact     = "exact"
+	GameCountEstimated = "estimated"
+	GameCountNone      = "none"
 )
 
 // Game values represent game state data.
@@ -38,6 +94,7 @@ type Game struct {
 	Debug       request.FieldBool        `bson:"debug"       json:"debug"       yaml:"debug"`
 	Pause       request.FieldBool        `bson:"pause"       json:"pause"       yaml:"pause"`
 	Public      request.FieldBool        `bson:"public"      json:"public"      yaml:"public"`
+	ReadOnly    request.FieldBool        `bson:"read_only"   json:"read_only"   yaml:"read_only"`
 	W           request.FieldInt64       `bson:"w"           json:"w"           yaml:"w"`
 	H           request.FieldInt64       `bson:"h"           json:"h"           yaml:"h"`
 	ID          request.FieldString      `bson:"id"          json:"id"          yaml:"id"`
@@ -46,14 +103,18 @@ type Game struct {
 	Version     request.FieldString      `bson:"version"     json:"version"     yaml:"version"`
 	Description request.FieldString      `bson:"description" json:"description" yaml:"description"`
 	Icon        request.FieldString      `bson:"icon"        json:"icon"        yaml:"icon"`
+	Controls    request.FieldString      `bson:"controls"    json:"controls"    yaml:"controls"`
 	Status      request.FieldString      `bson:"status"      json:"status"      yaml:"status"`
 	StatusData  request.FieldJSON        `bson:"status_data" json:"status_data" yaml:"status_data"`
 	Subject     request.FieldJSON        `bson:"subject"     json:"subject"     yaml:"subject"`
 	Objects     request.FieldJSON        `bson:"objects"     json:"objects"     yaml:"objects"`
 	Images      request.FieldJSON        `bson:"images"      json:"images"      yaml:"images"`
 	Script      request.FieldString      `bson:"script"      json:"script"      yaml:"script"`
+	Bindings    request.FieldJSON        `bson:"bindings"    json:"bindings"    yaml:"bindings"`
+	Locales     request.FieldJSON        `bson:"locales"     json:"locales"     yaml:"locales"`
 	Source      request.FieldString      `bson:"source"      json:"source"      yaml:"source"`
 	CommitHash  request.FieldString      `bson:"commit_hash" json:"commit_hash" yaml:"commit_hash"`
+	Revision    request.FieldInt64       `bson:"revision"    json:"revision"    yaml:"revision"`
 	Tags        request.FieldStringArray `bson:"tags"        json:"tags"        yaml:"tags"`
 	Prompts     request.FieldJSON        `bson:"prompts"     json:"prompts"     yaml:"prompts"`
 	CreatedAt   request.FieldTime        `bson:"created_at"  json:"created_at"  yaml:"created_at"`
@@ -113,20 +174,99 @@ func (g *Game) Validate() error {
 				"game", g)
 		}
 
-		switch g.Status.Value {
-		case request.StatusActive, request.StatusInactive,
-			request.StatusUpdating, request.StatusImporting,
-			request.StatusError:
-		default:
+		if !validGameStatus(g.Status.Value) {
 			return errors.New(errors.ErrInvalidRequest,
 				"invalid status",
 				"game", g)
 		}
 	}
 
+	if g.Bindings.Set && g.Bindings.Valid {
+		for action, v := range g.Bindings.Value {
+			if !validGameBinding(v) {
+				return errors.New(errors.ErrInvalidRequest,
+					"invalid bindings",
+					"action", action,
+					"game", g)
+			}
+		}
+	}
+
+	if g.Locales.Set && g.Locales.Valid {
+		for locale, v := range g.Locales.Value {
+			if !localePattern.MatchString(locale) || !validGameLocale(v) {
+				return errors.New(errors.ErrInvalidRequest,
+					"invalid locales",
+					"locale", locale,
+					"game", g)
+			}
+		}
+	}
+
 	return nil
 }
 
+// validGameLocale checks that the strings of a game locale are a map of text,
+// keyed by the text keys used by the game script.
+func validGameLocale(v any) bool {
+	var texts map[string]any
+
+	switch t := v.(type) {
+	case map[string]any:
+		texts = t
+	case bson.M:
+		texts = t
+	case bson.D:
+		texts = make(map[string]any, len(t))
+
+		for _, e := range t {
+			texts[e.Key] = e.Value
+		}
+	default:
+		return false
+	}
+
+	for k, text := range texts {
+		if k == "" {
+			return false
+		}
+
+		if _, ok := text.(string); !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+// validGameBinding checks that the keys bound to a game action are a list of
+// key names.
+func validGameBinding(v any) bool {
+	var keys []any
+
+	switch k := v.(type) {
+	case []any:
+		keys = k
+	case bson.A:
+		keys = k
+	default:
+		return false
+	}
+
+	for _, k := range keys {
+		name, ok := k.(string)
+		if !ok {
+			return false
+		}
+
+		if _, ok := luaapi.KeyCode(name); !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
 // ValidateCreate checks that the value contains valid data for creation.
 func (g *Game) ValidateCreate() error {
 	if !g.AccountID.Set {
@@ -150,7 +290,9 @@ func (g *Game) ValidateCreate() error {
 	return g.Validate()
 }
 
-// getGames retrieves games based on a search query.
+// getGames retrieves games based on a search query, and the number of games
+// matching the query. The number is -1 if counting is disabled by the context,
+// and an estimated count is never more than maxEstimatedGames.
 func (s *Server) getGames(ctx context.Context,
 	query *request.Query,
 ) ([]*Game, int64, error) {
@@ -166,7 +308,7 @@ func (s *Server) getGames(ctx context.Context,
 
 	res := []*Game{}
 
-	var f, srt bson.M
+	var f bson.M
 
 	if query.Search != "" {
 		if err := bson.UnmarshalExtJSON([]byte(query.Search),
@@ -189,17 +331,29 @@ func (s *Server) getGames(ctx context.Context,
 		f["account_id"] = aID
 	}
 
-	if query.Sort != "" {
-		if err := bson.UnmarshalExtJSON([]byte(query.Sort),
-			false, &srt); err != nil {
-			return nil, 0, errors.Wrap(err, errors.ErrInvalidRequest,
-				"unable to decode sort query",
-				"query", query)
+	if tags, ok := ctx.Value(CtxKeyGameTags).([]string); ok && len(tags) > 0 {
+		tf := bson.M{"tags": bson.M{"$all": tags}}
+
+		if v, ok := f["tags"]; ok {
+			and, _ := f["$and"].(bson.A)
+
+			f["$and"] = append(and, bson.M{"tags": v}, tf)
+
+			delete(f, "tags")
+		} else {
+			f["tags"] = tf["tags"]
 		}
 	}
 
-	if srt == nil {
-		srt = bson.M{"created_at": -1}
+	sf, err := query.SortFields(gameSortFields...)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	srt := sortDocument(sf)
+
+	if len(srt) == 0 {
+		srt = bson.D{{Key: "created_at", Value: -1}}
 	}
 
 	pro := bson.M{
@@ -210,7 +364,12 @@ func (s *Server) getGames(ctx context.Context,
 		"scripts": 0,
 	}
 
-	cur, err := s.DB().Collection("games").Find(ctx, f, options.Find().
+	c, err := s.collection(ctx, "games")
+	if err != nil {
+		return nil, 0, err
+	}
+
+	cur, err := c.Find(ctx, f, options.Find().
 		SetLimit(query.Size).SetSkip(query.Skip).
 		SetSort(srt).SetProjection(pro))
 	if err != nil {
@@ -252,8 +411,23 @@ func (s *Server) getGames(ctx context.Context,
 			"query", query)
 	}
 
-	n, err := s.DB().Collection("games").CountDocuments(ctx, f,
-		options.Count())
+	if ctx.Value(CtxKeyGameNoCount) != nil {
+		return res, -1, nil
+	}
+
+	opts := options.Count()
+
+	if ctx.Value(CtxKeyGameEstimatedCount) != nil {
+		// A short page contains the last game, so the total is known.
+		if query.Size > 0 && int64(len(res)) < query.Size &&
+			(len(res) > 0 || query.Skip == 0) {
+			return res, query.Skip + int64(len(res)), nil
+		}
+
+		opts.SetLimit(maxEstimatedGames)
+	}
+
+	n, err := c.CountDocuments(ctx, f, opts)
 	if err != nil {
 		return nil, 0, errors.Wrap(err, errors.ErrDatabase,
 			"unable to count games",
@@ -282,12 +456,14 @@ func (s *Server) getGame(ctx context.Context,
 	if !request.ValidGameID(id) {
 		return nil, errors.New(errors.ErrInvalidRequest,
 			"invalid game id",
-			"id", id)
+			"id", id).WithReason(errors.ReasonInvalidGameID)
 	}
 
 	var res *Game
 
-	s.getCache(ctx, cache.KeyGame(id), res)
+	if v := ctx.Value(CtxKeyGameAssetRefs); v == nil {
+		s.getCache(ctx, cache.KeyGame(id), res)
+	}
 
 	if res != nil {
 		return res, nil
@@ -310,12 +486,17 @@ func (s *Server) getGame(ctx context.Context,
 		}
 	}
 
-	if err := s.DB().Collection("games").FindOne(ctx, f,
+	c, err := s.collection(ctx, "games")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.FindOne(ctx, f,
 		options.FindOne().SetProjection(pro)).Decode(&res); err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
 			return nil, errors.New(errors.ErrNotFound,
 				"game not found",
-				"id", id)
+				"id", id).WithReason(errors.ReasonGameNotFound)
 		}
 
 		return nil, errors.Wrap(err, errors.ErrDatabase,
@@ -323,6 +504,16 @@ func (s *Server) getGame(ctx context.Context,
 			"id", id)
 	}
 
+	// Games retrieved with asset references refer to their image data by
+	// hash, so that it can be retrieved from the asset URLs.
+	if v := ctx.Value(CtxKeyGameAssetRefs); v != nil {
+		return res, nil
+	}
+
+	if err := s.loadAssets(ctx, res.AccountID.Value, res); err != nil {
+		return nil, err
+	}
+
 	if v := ctx.Value(CtxKeyGameMinData); v == nil {
 		s.setCache(ctx, cache.KeyGame(res.ID.Value), res)
 	}
@@ -330,6 +521,171 @@ func (s *Server) getGame(ctx context.Context,
 	return res, nil
 }
 
+// getGamesByID retrieves many games by ID in a single request. Games are
+// returned in the order requested, and games which are not found are omitted.
+// Cached games are used where possible, and only the rest are retrieved from
+// the database.
+func (s *Server) getGamesByID(ctx context.Context,
+	ids []string,
+) ([]*Game, error) {
+	aID, err := request.ContextAccountID(ctx)
+	if err != nil {
+		return nil, errors.New(errors.ErrUnauthorized,
+			"unable to get account id from context")
+	}
+
+	if len(ids) > maxBatchGames {
+		return nil, errors.New(errors.ErrInvalidRequest,
+			"too many game ids",
+			"ids", len(ids),
+			"max", maxBatchGames)
+	}
+
+	keys := []string{}
+
+	for _, id := range ids {
+		if !request.ValidGameID(id) {
+			return nil, errors.New(errors.ErrInvalidRequest,
+				"invalid game id",
+				"id", id).WithReason(errors.ReasonInvalidGameID)
+		}
+
+		keys = append(keys, cache.KeyGame(id))
+	}
+
+	minData := ctx.Value(CtxKeyGameMinData) != nil
+
+	found := make(map[string]*Game, len(ids))
+
+	if c := s.Cache(ctx); c != nil && len(keys) > 0 {
+		items, err := c.GetMulti(ctx, keys...)
+		if err != nil && !errors.Has(err, errors.ErrNotFound) {
+			s.log.Log(ctx, logger.LvlError,
+				"unable to get game cache keys",
+				"error", err,
+				"ids", ids)
+		}
+
+		for _, ci := range items {
+			if ci == nil {
+				continue
+			}
+
+			var g *Game
+
+			if err := json.Unmarshal(ci.Value, &g); err != nil || g == nil {
+				continue
+			}
+
+			if g.AccountID.Value != aID && !g.Public.Value {
+				continue
+			}
+
+			// Games cached from listings do not contain their asset data,
+			// so they are only used if the asset data is not needed.
+			if minData {
+				g.Subject, g.Objects, g.Images = request.FieldJSON{},
+					request.FieldJSON{}, request.FieldJSON{}
+			} else if !g.Subject.Valid {
+				continue
+			}
+
+			found[g.ID.Value] = g
+		}
+	}
+
+	missing := []string{}
+
+	for _, id := range ids {
+		if _, ok := found[id]; !ok && !slices.Contains(missing, id) {
+			missing = append(missing, id)
+		}
+	}
+
+	if len(missing) > 0 {
+		f := bson.M{"id": bson.M{"$in": missing}, "$or": bson.A{
+			bson.D{{Key: "public", Value: true}},
+			bson.D{{Key: "account_id", Value: aID}},
+		}}
+
+		pro := bson.M{"_id": 0}
+
+		if minData {
+			pro = bson.M{
+				"_id":     0,
+				"subject": 0,
+				"objects": 0,
+				"images":  0,
+				"scripts": 0,
+			}
+		}
+
+		col, err := s.collection(ctx, "games")
+		if err != nil {
+			return nil, err
+		}
+
+		cur, err := col.Find(ctx, f,
+			options.Find().SetProjection(pro))
+		if err != nil {
+			return nil, errors.Wrap(err, errors.ErrDatabase,
+				"unable to find games",
+				"ids", missing)
+		}
+
+		defer func() {
+			if err := cur.Close(ctx); err != nil {
+				s.log.Log(ctx, logger.LvlError,
+					"unable to close cursor",
+					"err", err,
+					"ids", missing)
+			}
+		}()
+
+		for cur.Next(ctx) {
+			var g *Game
+
+			if err := cur.Decode(&g); err != nil {
+				return nil, errors.Wrap(err, errors.ErrDatabase,
+					"unable to decode game",
+					"ids", missing)
+			}
+
+			if g == nil {
+				continue
+			}
+
+			if !minData {
+				if err := s.loadAssets(ctx, g.AccountID.Value, g); err != nil {
+					return nil, err
+				}
+
+				s.setCache(ctx, cache.KeyGame(g.ID.Value), g)
+			}
+
+			found[g.ID.Value] = g
+		}
+
+		if err := cur.Err(); err != nil {
+			return nil, errors.Wrap(err, errors.ErrDatabase,
+				"unable to get games",
+				"ids", missing)
+		}
+	}
+
+	res := make([]*Game, 0, len(found))
+
+	for _, id := range ids {
+		if g, ok := found[id]; ok {
+			res = append(res, g)
+
+			delete(found, id)
+		}
+	}
+
+	return res, nil
+}
+
 // createGame creates a new game.
 func (s *Server) createGame(ctx context.Context,
 	req *Game,
@@ -404,43 +760,14 @@ func (s *Server) createGame(ctx context.Context,
 		return nil, err
 	}
 
-	a, err := s.getAccount(ctx, aID)
-	if err != nil {
-		return nil, errors.Wrap(err, errors.ErrDatabase,
-			"unable to get account game limits",
-			"account_id", aID,
-			"req", req)
-	}
-
-	if a == nil {
-		return nil, errors.New(errors.ErrNotFound,
-			"account game limits not found",
-			"account_id", aID,
-			"req", req)
-	}
-
-	if a.GameLimit.Value > 0 {
-		f := bson.M{
-			"account_id": aID,
-			"status":     request.StatusActive,
-		}
-
-		n, err := s.DB().Collection("games").CountDocuments(ctx, f,
-			options.Count())
-		if err != nil {
-			return nil, errors.Wrap(err, errors.ErrDatabase,
-				"unable to count games",
-				"account_id", aID,
-				"req", req)
+	if req.Public.Value {
+		if err := s.checkEntitlement(ctx, EntitlementPublicGames); err != nil {
+			return nil, err
 		}
+	}
 
-		if n >= a.GameLimit.Value {
-			return nil, errors.New(errors.ErrorRateLimit,
-				"account game limit reached",
-				"account_id", aID,
-				"game_limit", a.GameLimit.Value,
-				"game_count", n)
-		}
+	if err := s.checkGameLimit(ctx); err != nil {
+		return nil, err
 	}
 
 	req.CreatedAt = request.FieldTime{
@@ -459,6 +786,15 @@ func (s *Server) createGame(ctx context.Context,
 		Set: true, Valid: true, Value: uID,
 	}
 
+	if err := prepareGameSize(ctx, req); err != nil {
+		return nil, err
+	}
+
+	images, err := s.storeAssets(ctx, req.AccountID.Value, req.Images)
+	if err != nil {
+		return nil, err
+	}
+
 	var res *Game
 
 	f := bson.M{"account_id": req.AccountID.Value, "id": req.ID.Value}
@@ -473,12 +809,15 @@ func (s *Server) createGame(ctx context.Context,
 	request.SetField(doc, "version", req.Version)
 	request.SetField(doc, "description", req.Description)
 	request.SetField(doc, "icon", req.Icon)
+	request.SetField(doc, "controls", req.Controls)
 	request.SetField(doc, "status", req.Status)
 	request.SetField(doc, "status_data", req.StatusData)
 	request.SetField(doc, "subject", req.Subject)
 	request.SetField(doc, "objects", req.Objects)
-	request.SetField(doc, "images", req.Images)
+	request.SetField(doc, "images", images)
 	request.SetField(doc, "script", req.Script)
+	request.SetField(doc, "bindings", req.Bindings)
+	request.SetField(doc, "locales", req.Locales)
 	request.SetField(doc, "commit_hash", req.CommitHash)
 	request.SetField(doc, "prompts", req.Prompts)
 	request.SetField(doc, "updated_at", req.UpdatedAt)
@@ -496,7 +835,16 @@ func (s *Server) createGame(ctx context.Context,
 		request.SetField(doc, "tags", req.Tags)
 	}
 
-	doc = &bson.D{{Key: "$set", Value: doc}, {Key: "$setOnInsert", Value: cDoc}}
+	doc = &bson.D{
+		{Key: "$set", Value: doc},
+		{Key: "$setOnInsert", Value: cDoc},
+		{Key: "$inc", Value: bson.M{"revision": 1}},
+	}
+
+	c, err := s.collection(ctx, "games")
+	if err != nil {
+		return nil, err
+	}
 
 	pro := bson.M{"_id": 0}
 
@@ -510,7 +858,7 @@ func (s *Server) createGame(ctx context.Context,
 		}
 	}
 
-	if err := s.DB().Collection("games").FindOneAndUpdate(ctx, f, doc,
+	if err := c.FindOneAndUpdate(ctx, f, doc,
 		options.FindOneAndUpdate().SetProjection(pro).
 			SetReturnDocument(options.After).SetUpsert(true)).
 		Decode(&res); err != nil {
@@ -549,64 +897,15 @@ func (s *Server) createGame(ctx context.Context,
 		return s.createGame(ctx, res)
 	}
 
-	s.setCache(ctx, cache.KeyGame(res.ID.Value), res)
-
-	if req.PreviousID.Value != "" {
-		pg, err := s.getGame(ctx, res.PreviousID.Value)
-		if err != nil && !errors.Has(err, errors.ErrNotFound) {
-			return nil, errors.Wrap(err, errors.ErrDatabase,
-				"unable to get previous game",
-				"req", req,
-				"previous_id", res.PreviousID.Value)
-		}
-
-		if pg.Status.Value != request.StatusInactive {
-			pg.Status = request.FieldString{
-				Set: true, Valid: true, Value: request.StatusInactive,
-			}
-
-			prompts, err := promptsFromFieldJSON(pg.Prompts)
-			if err != nil {
-				return nil, errors.Wrap(err, errors.ErrDatabase,
-					"unable to decode previous game prompts",
-					"previous_id", res.PreviousID.Value)
-			}
-
-			if prompts == nil {
-				prompts = &Prompts{}
-			}
-
-			prompts.GameID = request.FieldString{
-				Set: true, Valid: true, Value: res.ID.Value,
-			}
-
-			pg.Prompts, err = promptsToFieldJSON(prompts)
-			if err != nil {
-				return nil, errors.Wrap(err, errors.ErrDatabase,
-					"unable to encode previous game prompts",
-					"previous_id", res.PreviousID.Value)
-			}
-
-			if pg.PreviousID.Value != res.ID.Value &&
-				pg.PreviousID.Value != "" {
-				if err := s.deleteGame(ctx, pg.PreviousID.Value); err != nil {
-					return nil, errors.Wrap(err, errors.ErrDatabase,
-						"unable to delete previous game",
-						"previous_id", res.PreviousID.Value)
-				}
+	if err := s.loadAssets(ctx, res.AccountID.Value, res); err != nil {
+		return nil, err
+	}
 
-				pg.PreviousID = request.FieldString{
-					Set: true, Valid: false, Value: "",
-				}
-			}
+	s.setCache(ctx, cache.KeyGame(res.ID.Value), res)
 
-			if _, err := s.updateGame(ctx, pg); err != nil {
-				return nil, errors.Wrap(err, errors.ErrDatabase,
-					"unable to update previous game status",
-					"req", req,
-					"previous_id", res.PreviousID.Value)
-			}
-		}
+	if err := s.linkRevision(ctx, res,
+		res.CreatedAt.Value == req.CreatedAt.Value); err != nil {
+		return nil, err
 	}
 
 	return res, nil
@@ -656,6 +955,33 @@ func (s *Server) updateGame(ctx context.Context,
 		return nil, err
 	}
 
+	cur, err := s.getGameState(ctx, req.AccountID.Value, req.ID.Value)
+	if err != nil {
+		return nil, err
+	}
+
+	if cur.ReadOnly.Value {
+		return nil, errReadOnlyGame(req.ID.Value)
+	}
+
+	if req.Public.Value && !cur.Public.Value {
+		if err := s.checkEntitlement(ctx,
+			EntitlementPublicGames); err != nil {
+			return nil, err
+		}
+	}
+
+	var from string
+
+	if req.Status.Set {
+		from = cur.Status.Value
+
+		if err := checkGameStatusTransition(req.ID.Value, from,
+			req.Status.Value); err != nil {
+			return nil, err
+		}
+	}
+
 	req.UpdatedAt = request.FieldTime{
 		Set: true, Valid: true, Value: time.Now().Unix(),
 	}
@@ -664,10 +990,23 @@ func (s *Server) updateGame(ctx context.Context,
 		Set: true, Valid: true, Value: uID,
 	}
 
+	if err := prepareGameSize(ctx, req); err != nil {
+		return nil, err
+	}
+
+	images, err := s.storeAssets(ctx, req.AccountID.Value, req.Images)
+	if err != nil {
+		return nil, err
+	}
+
 	var res *Game
 
 	f := bson.M{"account_id": req.AccountID.Value, "id": req.ID.Value}
 
+	if req.Status.Set && from != "" {
+		f["status"] = from
+	}
+
 	doc := &bson.D{}
 
 	request.SetField(doc, "public", req.Public)
@@ -678,12 +1017,15 @@ func (s *Server) updateGame(ctx context.Context,
 	request.SetField(doc, "version", req.Version)
 	request.SetField(doc, "description", req.Description)
 	request.SetField(doc, "icon", req.Icon)
+	request.SetField(doc, "controls", req.Controls)
 	request.SetField(doc, "status", req.Status)
 	request.SetField(doc, "status_data", req.StatusData)
 	request.SetField(doc, "subject", req.Subject)
 	request.SetField(doc, "objects", req.Objects)
-	request.SetField(doc, "images", req.Images)
+	request.SetField(doc, "images", images)
 	request.SetField(doc, "script", req.Script)
+	request.SetField(doc, "bindings", req.Bindings)
+	request.SetField(doc, "locales", req.Locales)
 	request.SetField(doc, "commit_hash", req.CommitHash)
 	request.SetField(doc, "prompts", req.Prompts)
 	request.SetField(doc, "updated_at", req.UpdatedAt)
@@ -705,12 +1047,26 @@ func (s *Server) updateGame(ctx context.Context,
 		}
 	}
 
-	if err := s.DB().Collection("games").FindOneAndUpdate(ctx, f,
-		&bson.D{{Key: "$set", Value: doc}},
+	c, err := s.collection(ctx, "games")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.FindOneAndUpdate(ctx, f,
+		&bson.D{
+			{Key: "$set", Value: doc},
+			{Key: "$inc", Value: bson.M{"revision": 1}},
+		},
 		options.FindOneAndUpdate().SetProjection(pro).
 			SetReturnDocument(options.After).SetUpsert(false)).
 		Decode(&res); err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
+			if req.Status.Set {
+				return nil, errors.New(errors.ErrConflict,
+					"game status changed during update",
+					"req", req)
+			}
+
 			return nil, errors.New(errors.ErrNotFound,
 				"game not found",
 				"req", req)
@@ -737,8 +1093,16 @@ func (s *Server) updateGame(ctx context.Context,
 			"req", req)
 	}
 
+	if err := s.loadAssets(ctx, res.AccountID.Value, res); err != nil {
+		return nil, err
+	}
+
 	s.setCache(ctx, cache.KeyGame(res.ID.Value), res)
 
+	if req.Status.Set && from != res.Status.Value {
+		s.gameStatusChanged(ctx, res, from)
+	}
+
 	return res, nil
 }
 
@@ -766,7 +1130,12 @@ func (s *Server) deleteGame(ctx context.Context,
 
 	f := bson.M{"account_id": aID, "id": id}
 
-	if res, err := s.DB().Collection("games").
+	c, err := s.collection(ctx, "games")
+	if err != nil {
+		return err
+	}
+
+	if res, err := c.
 		DeleteOne(ctx, f, options.DeleteOne()); err != nil {
 		return errors.Wrap(err, errors.ErrDatabase,
 			"unable to delete game",
@@ -815,6 +1184,10 @@ func (s *Server) importGames(ctx context.Context,
 	}
 
 	dm["games_last_imported"] = time.Now().Unix()
+	dm["games_files_processed"] = 0
+	dm["games_files_total"] = 0
+	dm["games_current_file"] = ""
+	dm["games_import_errors"] = []string{}
 
 	ar.RepoStatusData = request.FieldJSON{
 		Set: true, Valid: true, Value: dm,
@@ -826,7 +1199,8 @@ func (s *Server) importGames(ctx context.Context,
 			"unable to set account repository status")
 	}
 
-	updated, deleted, iErr := s.importRepoGames(ctx, ar.Repo.Value, force)
+	updated, deleted, iErr := s.importRepoGames(ctx, ar.Repo.Value,
+		s.importConcurrency(ar), force)
 
 	ar, err = s.getAccount(ctx, "")
 	if err != nil {
@@ -848,6 +1222,9 @@ func (s *Server) importGames(ctx context.Context,
 
 	dm["games_deleted"] = deleted
 
+	delete(dm, "games_current_file")
+	delete(dm, "games_file_hashes")
+
 	if iErr != nil {
 		ar.RepoStatus.Value = request.StatusError
 
@@ -865,6 +1242,24 @@ func (s *Server) importGames(ctx context.Context,
 			"unable to set account repository status")
 	}
 
+	ad := map[string]any{"updated": updated, "deleted": deleted}
+
+	if iErr != nil {
+		ad["error"] = iErr.Error()
+	}
+
+	s.recordActivity(ctx, ActivityImport, "", ad)
+
+	if iErr != nil {
+		s.triggerAutomations(ctx, AutomationImportError, "", ad)
+
+		s.notifyAccount(ctx, NotificationImportFailed, "", &notify.Message{
+			Title: "Game import failed",
+			Body: "The import of games from the account repository has " +
+				"failed: " + iErr.Error(),
+		})
+	}
+
 	if iErr != nil {
 		return iErr
 	}
@@ -946,7 +1341,12 @@ func (s *Server) deleteRepoGames(ctx context.Context,
 
 	pro := bson.M{"id": 1}
 
-	cur, err := s.DB().Collection("games").Find(ctx, f,
+	c, err := s.collection(ctx, "games")
+	if err != nil {
+		return 0, err
+	}
+
+	cur, err := c.Find(ctx, f,
 		options.Find().SetProjection(pro))
 	if err != nil {
 		return 0, errors.Wrap(err, errors.ErrDatabase,
@@ -982,34 +1382,183 @@ func (s *Server) deleteRepoGames(ctx context.Context,
 			"source":     "git",
 		}
 
-		if _, err := s.DB().Collection("games").
-			DeleteOne(ctx, df, options.DeleteOne()); err != nil {
-			return n, errors.Wrap(err, errors.ErrDatabase,
-				"unable to delete imported game",
-				"filter", df)
-		}
+		if _, err := c.DeleteOne(ctx, df,
+			options.DeleteOne()); err != nil {
+			return n, errors.Wrap(err, errors.ErrDatabase,
+				"unable to delete imported game",
+				"filter", df)
+		}
+
+		s.deleteCache(ctx, cache.KeyGame(g.ID.Value))
+
+		n++
+	}
+
+	if err := cur.Err(); err != nil {
+		return n, errors.Wrap(err, errors.ErrDatabase,
+			"unable to delete imported games",
+			"filter", f)
+	}
+
+	return n, nil
+}
+
+// importRepoGame imports a single game file from the account import
+// repository, returning whether the game was created or updated. Unless force
+// is set, games which are unchanged since they were last imported, according
+// to the record of the previous import of the file, are only updated with the
+// new commit hash. If the file is imported, the record is updated to match it.
+func (s *Server) importRepoGame(ctx context.Context,
+	cli repo.Client,
+	i repo.Item,
+	h *importHash,
+	newHash string,
+	force bool,
+) (bool, *errors.Error) {
+	ctx, cancel := s.opContext(ctx, opImport)
+
+	defer cancel()
+
+	gID := strings.TrimPrefix(strings.TrimPrefix(i.Path, "/"), "games/")
+
+	ext := filepath.Ext(gID)
+
+	gID = strings.TrimSuffix(gID, ext)
+
+	g, err := s.getGame(ctx, gID)
+	if err != nil && !errors.Has(err, errors.ErrNotFound) {
+		return false, errors.Wrap(err, errors.ErrDatabase,
+			"unable to get current game",
+			"game_id", gID)
+	}
+
+	// Files whose contents are unchanged since the previous import are not
+	// downloaded again. The hash of the file identifies its contents, if the
+	// repository provides one, otherwise the commit does.
+	if h == nil {
+		h = &importHash{Path: i.Path}
+	}
+
+	unchanged := g != nil && !force && g.Version.Value == i.Commit
+
+	if i.Hash != "" {
+		unchanged = g != nil && !force && i.Hash == h.Hash
+	}
+
+	if unchanged {
+		return s.touchRepoGame(ctx, g, newHash)
+	}
+
+	var vb []byte
+
+	if cg, ok := cli.(repo.ConditionalGetter); ok {
+		tag := ""
+
+		if g != nil && !force {
+			tag = h.Tag
+		}
+
+		b, newTag, modified, err := cg.GetIfModified(ctx,
+			"games/"+gID+ext, tag)
+		if err != nil {
+			return false, errors.Wrap(err, errors.ErrImport,
+				"unable to get game repository file",
+				"game_id", gID)
+		}
+
+		h.Hash, h.Tag = i.Hash, newTag
+
+		if !modified {
+			return s.touchRepoGame(ctx, g, newHash)
+		}
+
+		vb = b
+	} else {
+		b, err := cli.Get(ctx, "games/"+gID+ext)
+		if err != nil {
+			return false, errors.Wrap(err, errors.ErrImport,
+				"unable to get game repository file",
+				"game_id", gID)
+		}
+
+		h.Hash = i.Hash
+
+		vb = b
+	}
+
+	if err := yaml.Unmarshal(vb, &g); err != nil {
+		return false, errors.Wrap(err, errors.ErrImport,
+			"unable to parse game repository file",
+			"game_id", gID)
+	}
+
+	g.ID = request.FieldString{
+		Set: true, Valid: true, Value: gID,
+	}
+
+	g.Version = request.FieldString{
+		Set: true, Valid: true, Value: newHash,
+	}
+
+	g.Status = request.FieldString{
+		Set: true, Valid: true, Value: request.StatusActive,
+	}
+
+	g.Source = request.FieldString{
+		Set: true, Valid: true, Value: "git",
+	}
+
+	g.CommitHash = request.FieldString{
+		Set: true, Valid: true, Value: newHash,
+	}
+
+	ctx = context.WithValue(ctx, CtxKeyGameAllowTags, true)
+	ctx = context.WithValue(ctx, CtxKeyGameAllowPreviousID, true)
+
+	if _, err := s.createGame(ctx, g); err != nil {
+		return false, errors.Wrap(err, errors.ErrDatabase,
+			"unable to create imported game",
+			"game", g)
+	}
+
+	return true, nil
+}
 
-		s.deleteCache(ctx, cache.KeyGame(g.ID.Value))
+// touchRepoGame updates a game imported from the account import repository,
+// whose file is unchanged, with the new commit hash, returning whether it was
+// updated.
+func (s *Server) touchRepoGame(ctx context.Context,
+	g *Game,
+	newHash string,
+) (bool, *errors.Error) {
+	if g.CommitHash.Value == newHash {
+		return false, nil
+	}
 
-		n++
+	g.CommitHash = request.FieldString{
+		Set: true, Valid: true, Value: newHash,
 	}
 
-	if err := cur.Err(); err != nil {
-		return n, errors.Wrap(err, errors.ErrDatabase,
-			"unable to delete imported games",
-			"filter", f)
+	ctx = context.WithValue(ctx, CtxKeyGameAllowPreviousID, true)
+	ctx = context.WithValue(ctx, CtxKeyGameAllowTags, true)
+
+	if _, err := s.updateGame(ctx, g); err != nil {
+		return false, errors.Wrap(err, errors.ErrDatabase,
+			"unable to update repository game",
+			"game", g)
 	}
 
-	return n, nil
+	return true, nil
 }
 
 // importRepoGames updates the games based on the contents of the account
 // import repository.
 func (s *Server) importRepoGames(ctx context.Context,
 	repoURL string,
+	concurrency int,
 	force bool,
 ) (int, int, error) {
-	ctx, cancel := request.ContextReplaceTimeout(ctx, s.cfg.ServerTimeout())
+	ctx, cancel := s.detachedContext(ctx, opImport)
 
 	defer cancel()
 
@@ -1052,107 +1601,125 @@ func (s *Server) importRepoGames(ctx context.Context,
 	errs := errors.New(errors.ErrImport,
 		"unable to import games")
 
+	// The files recorded by the previous import are used to avoid
+	// downloading unchanged files.
+	prevHashes, err := s.getImportHashes(ctx)
+	if err != nil {
+		s.log.Log(ctx, logger.LvlWarn,
+			"unable to get game import file hashes",
+			"error", err)
+
+		prevHashes = map[string]*importHash{}
+	}
+
+	hashes := make([]*importHash, 0, len(res))
+
+	files := make([]repo.Item, 0, len(res))
+
 	for _, i := range res {
 		if i.Type == "file" || i.Type == "commit_file" {
-			ctx, cancel := request.ContextReplaceTimeout(ctx,
-				s.cfg.ServerTimeout())
+			files = append(files, i)
+		}
+	}
+
+	// Progress is recorded as each file is processed, so that it can be
+	// reported while the import is running.
+	pCtx := ctx
 
-			defer cancel()
+	progress := func(processed int, current string) {
+		pm := map[string]any{
+			"games_files_processed": processed,
+			"games_files_total":     len(files),
+			"games_current_file":    current,
+			"games_import_errors":   importErrors(errs.Errors),
+		}
 
-			gID := strings.TrimPrefix(strings.TrimPrefix(i.Path, "/"), "games/")
+		importRateLimit(cli, pm)
 
-			ext := filepath.Ext(gID)
+		if err := s.setImportProgress(pCtx, pm); err != nil {
+			s.log.Log(pCtx, logger.LvlWarn,
+				"unable to record game import progress",
+				"error", err)
+		}
+	}
 
-			gID = strings.TrimSuffix(gID, ext)
+	progress(0, "")
 
-			g, err := s.getGame(ctx, gID)
-			if err != nil && !errors.Has(err, errors.ErrNotFound) {
-				errs.Errors = append(errs.Errors, errors.Wrap(err,
-					errors.ErrDatabase,
-					"unable to get current game",
-					"game_id", gID))
+	defer func() {
+		progress(len(files), "")
+	}()
 
-				continue
-			}
+	// Files are fetched, parsed, and stored by a bounded pool of workers. The
+	// results, and the progress, are only updated while holding the lock.
+	var (
+		mu        sync.Mutex
+		wg        sync.WaitGroup
+		processed int
+	)
 
-			if g != nil && (!force && g.Version.Value == i.Commit) {
-				if g.CommitHash.Value != newHash {
-					g.CommitHash = request.FieldString{
-						Set: true, Valid: true, Value: newHash,
-					}
+	items := make(chan repo.Item)
 
-					ctx = context.WithValue(ctx, CtxKeyGameAllowPreviousID,
-						true)
-					ctx = context.WithValue(ctx, CtxKeyGameAllowTags, true)
+	for range max(min(concurrency, len(files)), 1) {
+		wg.Add(1)
 
-					if _, err := s.updateGame(ctx, g); err != nil {
-						errs.Errors = append(errs.Errors, errors.Wrap(err,
-							errors.ErrDatabase,
-							"unable to update repository game",
-							"game", g))
+		go func() {
+			defer wg.Done()
 
-						continue
-					}
+			for i := range items {
+				h := &importHash{Path: i.Path}
 
-					updated++
+				if ph, ok := prevHashes[i.Path]; ok {
+					*h = *ph
 				}
 
-				continue
-			}
-
-			vb, err := cli.Get(ctx, "games/"+gID+ext)
-			if err != nil {
-				errs.Errors = append(errs.Errors, errors.Wrap(err,
-					errors.ErrImport,
-					"unable to get game repository file",
-					"game_id", gID))
+				ok, err := s.importRepoGame(ctx, cli, i, h, newHash, force)
 
-				continue
-			}
+				mu.Lock()
 
-			if err := yaml.Unmarshal(vb, &g); err != nil {
-				errs.Errors = append(errs.Errors, errors.Wrap(err,
-					errors.ErrImport,
-					"unable to parse game repository file",
-					"game_id", gID))
+				processed++
 
-				continue
-			}
+				if err != nil {
+					errs.Append(err)
+				} else {
+					if ok {
+						updated++
+					}
 
-			g.ID = request.FieldString{
-				Set: true, Valid: true, Value: gID,
-			}
+					if h.Hash != "" || h.Tag != "" {
+						hashes = append(hashes, h)
+					}
+				}
 
-			g.Version = request.FieldString{
-				Set: true, Valid: true, Value: newHash,
-			}
+				progress(processed, i.Path)
 
-			g.Status = request.FieldString{
-				Set: true, Valid: true, Value: request.StatusActive,
+				mu.Unlock()
 			}
+		}()
+	}
 
-			g.Source = request.FieldString{
-				Set: true, Valid: true, Value: "git",
-			}
+	for _, i := range files {
+		if ctx.Err() != nil {
+			break
+		}
 
-			g.CommitHash = request.FieldString{
-				Set: true, Valid: true, Value: newHash,
-			}
+		items <- i
+	}
 
-			ctx = context.WithValue(ctx, CtxKeyGameAllowTags, true)
-			ctx = context.WithValue(ctx, CtxKeyGameAllowPreviousID, true)
+	close(items)
 
-			if _, err := s.createGame(ctx, g); err != nil {
-				errs.Errors = append(errs.Errors, errors.Wrap(err,
-					errors.ErrDatabase,
-					"unable to create imported game",
-					"game", g))
+	wg.Wait()
 
-				continue
-			}
+	// Only the hashes of files which were imported successfully are recorded,
+	// so that failed files are downloaded again by the next import.
+	if err := s.setImportHashes(pCtx, hashes); err != nil {
+		s.log.Log(pCtx, logger.LvlWarn,
+			"unable to record game import file hashes",
+			"error", err)
+	}
 
-			updated++
-		}
+	if err := ctx.Err(); err != nil {
+		errs.Append(errors.Wrap(err, errors.ErrImport,
+			"game import canceled"))
 	}
 
 	if len(errs.Errors) > 0 {
@@ -1164,7 +1731,7 @@ func (s *Server) importRepoGames(ctx context.Context,
 		return updated, 0, errs
 	}
 
-	ctx, cancel = request.ContextReplaceTimeout(ctx, s.cfg.ServerTimeout())
+	ctx, cancel = s.detachedContext(ctx, opImport)
 
 	defer cancel()
 
@@ -1173,14 +1740,12 @@ func (s *Server) importRepoGames(ctx context.Context,
 	if newHash != "" {
 		err := s.setAccountGameCommitHash(ctx, newHash)
 		if err != nil {
-			errs.Errors = append(errs.Errors, errors.Wrap(err,
-				errors.ErrDatabase,
+			errs.Append(errors.Wrap(err, errors.ErrDatabase,
 				"unable to set account game_commit_hash"))
 		} else {
 			deleted, err = s.deleteRepoGames(ctx, newHash)
 			if err != nil {
-				errs.Errors = append(errs.Errors, errors.Wrap(err,
-					errors.ErrDatabase,
+				errs.Append(errors.Wrap(err, errors.ErrDatabase,
 					"unable to delete removed repository games",
 					"commit_hash", newHash))
 			}
@@ -1210,7 +1775,11 @@ func (s *Server) updateGameImports(ctx context.Context,
 ) context.CancelFunc {
 	ctx, cancel := context.WithCancel(ctx)
 
+	s.jobs.Add(1)
+
 	go func(ctx context.Context) {
+		defer s.jobs.Done()
+
 		tick := time.NewTimer(0)
 
 		adj := time.Duration(0)
@@ -1237,6 +1806,9 @@ func (s *Server) updateGameImports(ctx context.Context,
 					wg.Add(1)
 
 					go func(ctx context.Context, accountID string) {
+						ctx, cancel := s.opContext(ctx, opImport)
+						defer cancel()
+
 						ctx = context.WithValue(ctx, request.CtxKeyAccountID,
 							accountID)
 						ctx = context.WithValue(ctx, request.CtxKeyUserID,
@@ -1290,29 +1862,34 @@ func (s *Server) updateGameImports(ctx context.Context,
 	return cancel
 }
 
-// getAllGameTags retrieves all game tags.
+// getAllGameTags retrieves all tags used by the games of the current account,
+// in sorted order. The tags are listed using the tags index, rather than by
+// retrieving the games.
 func (s *Server) getAllGameTags(ctx context.Context,
 ) ([]string, error) {
-	ctx = context.WithValue(ctx, CtxKeyGameNoCount, true)
-
-	gs, _, err := s.getGames(ctx, nil)
+	aID, err := request.ContextAccountID(ctx)
 	if err != nil {
-		return nil, err
+		return nil, errors.New(errors.ErrUnauthorized,
+			"unable to get account id from context")
 	}
 
-	tm := make(map[string]struct{}, len(gs))
+	tags := []string{}
 
-	for _, g := range gs {
-		for _, t := range g.Tags.Value {
-			tm[t] = struct{}{}
-		}
+	c, err := s.collection(ctx, "games")
+	if err != nil {
+		return nil, err
 	}
 
-	tags := make([]string, 0, len(tm))
-	for t := range tm {
-		tags = append(tags, t)
+	if err := c.Distinct(ctx, "tags", bson.M{
+		"account_id": aID,
+		"status":     bson.M{"$ne": request.StatusInactive},
+	}).Decode(&tags); err != nil {
+		return nil, errors.Wrap(err, errors.ErrDatabase,
+			"unable to list game tags")
 	}
 
+	slices.Sort(tags)
+
 	return tags, nil
 }
 
@@ -1335,6 +1912,10 @@ func (s *Server) addGameTags(ctx context.Context,
 	id string,
 	tags []string,
 ) ([]string, error) {
+	if err := s.checkManagedTags(ctx, tags); err != nil {
+		return nil, err
+	}
+
 	ctx = context.WithValue(ctx, CtxKeyGameMinData, true)
 
 	g, err := s.getGame(ctx, id)
@@ -1423,17 +2004,73 @@ func (s *Server) gamesHandler() http.Handler {
 	r.Use(s.dbAvail)
 
 	r.With(s.stat, s.trace, s.auth).Post("/import", s.postImportGamesHandler)
+	r.With(s.stat, s.trace, s.auth).Get("/import/status",
+		s.getImportStatusHandler)
+	r.With(s.stat, s.trace, s.auth).Post("/import/*", s.postImportGameHandler)
 	r.With(s.stat, s.trace, s.auth).Post("/copy", s.postGamesCopyHandler)
 	r.With(s.stat, s.trace, s.auth).Post("/prompt", s.postGamesPromptHandler)
 	r.With(s.stat, s.trace, s.auth).Post("/undo", s.postGamesUndoHandler)
+	r.With(s.stat, s.trace, s.auth).Post("/upload", s.postGameUploadHandler)
+	r.With(s.stat, s.trace, s.auth).Post("/bulk", s.postGamesBulkHandler)
+	r.With(s.stat, s.trace, s.auth).Post("/size", s.postGameSizeHandler)
+	r.With(s.stat, s.trace, s.auth).Get("/recommended",
+		s.getGamesRecommendedHandler)
 
 	r.With(s.stat, s.trace, s.auth).Get("/tags", s.getAllGamesTagsHandler)
+	r.With(s.stat, s.trace, s.auth).Get("/live", s.getGamesLiveHandler)
 	r.With(s.stat, s.trace, s.auth).Get("/{id}/tags",
 		s.getGameTagsHandler)
 	r.With(s.stat, s.trace, s.auth).Post("/{id}/tags",
 		s.postGameTagsHandler)
 	r.With(s.stat, s.trace, s.auth).Delete("/{id}/tags",
 		s.deleteGameTagsHandler)
+	r.With(s.stat, s.trace, s.auth).Get("/{id}/package",
+		s.getGamePackageHandler)
+	r.With(s.stat, s.trace, s.auth).Get("/{id}/delta",
+		s.getGameDeltaHandler)
+	r.With(s.stat, s.trace, s.auth).Post("/{id}/share",
+		s.postGameShareHandler)
+	r.With(s.stat, s.trace, s.auth).Post("/{id}/restore",
+		s.postGameRestoreHandler)
+	r.With(s.stat, s.trace, s.auth).Get("/{id}/prompts",
+		s.getGamePromptsHandler)
+	r.With(s.stat, s.trace, s.auth).Get("/{id}/similar",
+		s.getGameSimilarHandler)
+	r.With(s.stat, s.trace, s.auth).Post("/{id}/describe",
+		s.postGameDescribeHandler)
+	r.With(s.stat, s.trace, s.auth).Post("/{id}/prompt",
+		s.postGamePromptHandler)
+	r.With(s.stat, s.trace, s.auth).Post("/{id}/prompt/audio",
+		s.postGamePromptAudioHandler)
+	r.With(s.stat, s.trace, s.auth).Post("/{id}/prompt/estimate",
+		s.postGamePromptEstimateHandler)
+	r.With(s.stat, s.trace, s.auth).Post("/{id}/prompt/reset",
+		s.postGamePromptResetHandler)
+	r.With(s.stat, s.trace, s.auth).Post("/{id}/status",
+		s.postGameStatusHandler)
+	r.With(s.stat, s.trace, s.auth).Get("/{id}/stats",
+		s.getGameStatsHandler)
+	r.With(s.stat, s.trace, s.auth).Post("/{id}/heartbeat",
+		s.postGameHeartbeatHandler)
+	r.With(s.stat, s.trace, s.auth).Get("/{id}/live", s.getGameLiveHandler)
+	r.With(s.stat, s.trace, s.auth).Get("/{id}/spectate",
+		s.getSpectateStreamsHandler)
+	r.With(s.stat, s.trace, s.auth).Get("/{id}/spectate/{session}",
+		s.getSpectateHandler)
+	r.With(s.stat, s.trace, s.auth).Get("/{id}/spectate/{session}/publish",
+		s.getSpectatePublishHandler)
+	r.With(s.stat, s.trace, s.auth).Get("/{id}/saves",
+		s.getGameSavesHandler)
+	r.With(s.stat, s.trace, s.auth).Get("/{id}/saves/{slot}",
+		s.getGameSaveHandler)
+	r.With(s.stat, s.trace, s.auth).Put("/{id}/saves/{slot}",
+		s.putGameSaveHandler)
+	r.With(s.stat, s.trace, s.auth).Delete("/{id}/saves/{slot}",
+		s.deleteGameSaveHandler)
+	r.With(s.stat, s.trace, s.auth).Post("/{id}/errors",
+		s.postGameErrorHandler)
+	r.With(s.stat, s.trace, s.auth).Get("/{id}/errors",
+		s.getGameErrorsHandler)
 
 	r.With(s.stat, s.trace, s.auth).Get("/", s.getGamesHandler)
 	r.With(s.stat, s.trace, s.auth).Get("/{id}", s.getGameHandler)
@@ -1445,6 +2082,22 @@ func (s *Server) gamesHandler() http.Handler {
 	return r
 }
 
+// queryTags returns the tags requested using tag query parameters. Tags may
+// be given as repeated parameters or as comma separated lists.
+func queryTags(values url.Values) []string {
+	var tags []string
+
+	for _, v := range values["tag"] {
+		for t := range strings.SplitSeq(v, ",") {
+			if t = strings.TrimSpace(t); t != "" && !slices.Contains(tags, t) {
+				tags = append(tags, t)
+			}
+		}
+	}
+
+	return tags
+}
+
 // getGamesHandler is the search handler function for game types.
 func (s *Server) getGamesHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
@@ -1455,6 +2108,12 @@ func (s *Server) getGamesHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if qp := r.URL.Query().Get("ids"); qp != "" {
+		s.getGamesByIDHandler(w, r, strings.Split(qp, ","))
+
+		return
+	}
+
 	query, err := request.ParseQuery(r.URL.Query())
 	if err != nil {
 		s.error(err, w, r)
@@ -1462,6 +2121,24 @@ func (s *Server) getGamesHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if tags := queryTags(r.URL.Query()); len(tags) > 0 {
+		ctx = context.WithValue(ctx, CtxKeyGameTags, tags)
+	}
+
+	switch qp := r.URL.Query().Get("count"); qp {
+	case "", GameCountExact:
+	case GameCountEstimated:
+		ctx = context.WithValue(ctx, CtxKeyGameEstimatedCount, true)
+	case GameCountNone:
+		ctx = context.WithValue(ctx, CtxKeyGameNoCount, true)
+	default:
+		s.error(errors.New(errors.ErrInvalidParameter,
+			"invalid count mode",
+			"count", qp), w, r)
+
+		return
+	}
+
 	res, n, err := s.getGames(ctx, query)
 	if err != nil {
 		s.error(err, w, r)
@@ -1469,9 +2146,43 @@ func (s *Server) getGamesHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	w.Header().Add("X-Total-Count", strconv.FormatInt(n, 10))
+	if n >= 0 {
+		w.Header().Add("X-Total-Count", strconv.FormatInt(n, 10))
+	}
+
+	// An estimated count which reached the limit is only a lower bound.
+	if ctx.Value(CtxKeyGameEstimatedCount) != nil && n >= maxEstimatedGames {
+		w.Header().Add("X-Total-Count-Estimated", "true")
+	}
+
+	if err := s.encode(w, r, res); err != nil {
+		s.error(err, w, r)
+	}
+}
+
+// getGamesByIDHandler is the handler function used to retrieve many games by
+// ID, which are requested using the ids query parameter of the search handler.
+func (s *Server) getGamesByIDHandler(w http.ResponseWriter,
+	r *http.Request,
+	ids []string,
+) {
+	ctx := r.Context()
+
+	if qp := r.URL.Query().Get("minimal"); qp != "" && qp != "0" &&
+		!strings.EqualFold(qp, "false") && !strings.EqualFold(qp, "f") {
+		ctx = context.WithValue(ctx, CtxKeyGameMinData, true)
+	}
+
+	res, err := s.getGamesByID(ctx, ids)
+	if err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	w.Header().Add("X-Total-Count", strconv.Itoa(len(res)))
 
-	if err := json.NewEncoder(w).Encode(res); err != nil {
+	if err := s.encode(w, r, res); err != nil {
 		s.error(err, w, r)
 	}
 }
@@ -1493,6 +2204,11 @@ func (s *Server) getGameHandler(w http.ResponseWriter, r *http.Request) {
 		ctx = context.WithValue(ctx, CtxKeyGameMinData, true)
 	}
 
+	if qp := r.URL.Query().Get("asset_refs"); qp != "" && qp != "0" &&
+		!strings.EqualFold(qp, "false") && !strings.EqualFold(qp, "f") {
+		ctx = context.WithValue(ctx, CtxKeyGameAssetRefs, true)
+	}
+
 	res, err := s.getGame(ctx, id)
 	if err != nil {
 		s.error(err, w, r)
@@ -1500,7 +2216,7 @@ func (s *Server) getGameHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := json.NewEncoder(w).Encode(res); err != nil {
+	if err := s.encode(w, r, res); err != nil {
 		s.error(err, w, r)
 	}
 }
@@ -1517,7 +2233,7 @@ func (s *Server) postGameHandler(w http.ResponseWriter, r *http.Request) {
 
 	req := &Game{}
 
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	if err := s.decode(r, &req); err != nil {
 		switch e := err.(type) {
 		case *errors.Error:
 			s.error(e, w, r)
@@ -1534,6 +2250,17 @@ func (s *Server) postGameHandler(w http.ResponseWriter, r *http.Request) {
 		ctx = context.WithValue(ctx, CtxKeyGameAllowTags, true)
 	}
 
+	if qp := r.URL.Query().Get("compress"); qp != "" && qp != "0" &&
+		!strings.EqualFold(qp, "false") && !strings.EqualFold(qp, "f") {
+		ctx = context.WithValue(ctx, CtxKeyGameCompress, true)
+	}
+
+	if err := s.checkRequestTags(ctx, req); err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
 	aID, err := request.ContextAccountID(ctx)
 	if err != nil {
 		s.error(errors.New(errors.ErrUnauthorized,
@@ -1553,6 +2280,15 @@ func (s *Server) postGameHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	s.recordActivity(ctx, ActivityGameCreated, res.ID.Value,
+		map[string]any{"name": res.Name.Value})
+
+	s.runHooks(ctx, HookGameCreated, res.ID.Value,
+		map[string]any{"name": res.Name.Value})
+
+	s.triggerAutomations(ctx, AutomationGameCreated, res.ID.Value,
+		map[string]any{"name": res.Name.Value})
+
 	w.WriteHeader(http.StatusCreated)
 
 	scheme := "https"
@@ -1568,7 +2304,7 @@ func (s *Server) postGameHandler(w http.ResponseWriter, r *http.Request) {
 
 	w.Header().Set("Location", loc.String())
 
-	if err := json.NewEncoder(w).Encode(res); err != nil {
+	if err := s.encode(w, r, res); err != nil {
 		s.error(err, w, r)
 	}
 }
@@ -1587,7 +2323,7 @@ func (s *Server) putGameHandler(w http.ResponseWriter, r *http.Request) {
 
 	req := &Game{}
 
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	if err := s.decode(r, &req); err != nil {
 		switch e := err.(type) {
 		case *errors.Error:
 			s.error(e, w, r)
@@ -1604,6 +2340,11 @@ func (s *Server) putGameHandler(w http.ResponseWriter, r *http.Request) {
 		Value: id,
 	}
 
+	if qp := r.URL.Query().Get("compress"); qp != "" && qp != "0" &&
+		!strings.EqualFold(qp, "false") && !strings.EqualFold(qp, "f") {
+		ctx = context.WithValue(ctx, CtxKeyGameCompress, true)
+	}
+
 	res, err := s.updateGame(ctx, req)
 	if err != nil {
 		s.error(err, w, r)
@@ -1611,7 +2352,13 @@ func (s *Server) putGameHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := json.NewEncoder(w).Encode(res); err != nil {
+	s.recordActivity(ctx, ActivityGameUpdated, res.ID.Value,
+		map[string]any{"name": res.Name.Value})
+
+	s.runHooks(ctx, HookGameUpdated, res.ID.Value,
+		map[string]any{"name": res.Name.Value})
+
+	if err := s.encode(w, r, res); err != nil {
 		s.error(err, w, r)
 	}
 }
@@ -1634,6 +2381,19 @@ func (s *Server) deleteGameHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	s.recordActivity(ctx, ActivityGameDeleted, id, nil)
+
+	s.runHooks(ctx, HookGameDeleted, id, nil)
+
+	if a, err := s.getAccount(ctx, ""); err == nil {
+		if err := s.enforcePlanLimits(ctx, a); err != nil {
+			s.log.Log(ctx, logger.LvlError,
+				"unable to enforce plan limits",
+				"error", err,
+				"account_id", a.ID.Value)
+		}
+	}
+
 	w.WriteHeader(http.StatusNoContent)
 }
 
@@ -1677,7 +2437,7 @@ func (s *Server) postGamesCopyHandler(w http.ResponseWriter,
 
 	req := &Game{}
 
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	if err := s.decode(r, &req); err != nil {
 		switch e := err.(type) {
 		case *errors.Error:
 			s.error(e, w, r)
@@ -1734,19 +2494,25 @@ func (s *Server) postGamesCopyHandler(w http.ResponseWriter,
 			Version:     g.Version,
 			Description: g.Description,
 			Icon:        g.Icon,
+			Controls:    g.Controls,
 			Status: request.FieldString{
 				Set: true, Valid: true, Value: request.StatusActive,
 			},
-			StatusData: g.StatusData,
-			Subject:    g.Subject,
-			Objects:    g.Objects,
-			Images:     g.Images,
+			StatusData: g.StatusData.Copy(),
+			Subject:    g.Subject.Copy(),
+			Objects:    g.Objects.Copy(),
+			Images:     g.Images.Copy(),
 			Script:     g.Script,
+			Bindings:   g.Bindings.Copy(),
+			Locales:    g.Locales.Copy(),
 			Source: request.FieldString{
 				Set: true, Valid: true, Value: "app",
 			},
-			Tags:    g.Tags,
-			Prompts: g.Prompts,
+			Tags: request.FieldStringArray{
+				Set: g.Tags.Set, Valid: g.Tags.Valid,
+				Value: slices.Clone(g.Tags.Value),
+			},
+			Prompts: g.Prompts.Copy(),
 		}
 
 		res, err = s.createGame(ctx, res)
@@ -1782,7 +2548,7 @@ func (s *Server) postGamesCopyHandler(w http.ResponseWriter,
 
 	w.Header().Set("Location", loc.String())
 
-	if err := json.NewEncoder(w).Encode(res); err != nil {
+	if err := s.encode(w, r, res); err != nil {
 		s.error(err, w, r)
 	}
 }
@@ -1800,26 +2566,9 @@ func (s *Server) postGamesPromptHandler(w http.ResponseWriter,
 		return
 	}
 
-	aID, err := request.ContextAccountID(ctx)
-	if err != nil {
-		s.error(errors.New(errors.ErrUnauthorized,
-			"unable to get account id from context"), w, r)
-
-		return
-	}
-
-	if s.getPrompter == nil {
-		if err := s.initPrompter(); err != nil {
-			s.error(errors.Wrap(err, errors.ErrUnavailable,
-				"unable to initialize prompter"), w, r)
-
-			return
-		}
-	}
-
 	req := &Prompts{}
 
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	if err := s.decode(r, &req); err != nil {
 		switch e := err.(type) {
 		case *errors.Error:
 			s.error(e, w, r)
@@ -1831,6 +2580,17 @@ func (s *Server) postGamesPromptHandler(w http.ResponseWriter,
 		return
 	}
 
+	s.servePrompt(w, r, req)
+}
+
+// servePrompt sends a prompt about a game to the AI, in a new revision of the
+// game, and responds with the prompts of the new revision.
+func (s *Server) servePrompt(w http.ResponseWriter,
+	r *http.Request,
+	req *Prompts,
+) {
+	ctx := r.Context()
+
 	if req == nil {
 		s.error(errors.New(errors.ErrInvalidRequest,
 			"missing request"), w, r)
@@ -1838,6 +2598,23 @@ func (s *Server) postGamesPromptHandler(w http.ResponseWriter,
 		return
 	}
 
+	aID, err := request.ContextAccountID(ctx)
+	if err != nil {
+		s.error(errors.New(errors.ErrUnauthorized,
+			"unable to get account id from context"), w, r)
+
+		return
+	}
+
+	if s.getPrompter == nil {
+		if err := s.initPrompter(); err != nil {
+			s.error(errors.Wrap(err, errors.ErrUnavailable,
+				"unable to initialize prompter"), w, r)
+
+			return
+		}
+	}
+
 	if req.GameID.Value == "" {
 		s.error(errors.New(errors.ErrInvalidRequest,
 			"missing game id",
@@ -1846,6 +2623,12 @@ func (s *Server) postGamesPromptHandler(w http.ResponseWriter,
 		return
 	}
 
+	if err := validatePromptAttachments(req.Current.Attachments); err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
 	ctx = context.WithValue(ctx, CtxKeyGameAllowTags, true)
 	ctx = context.WithValue(ctx, CtxKeyGameAllowPreviousID, true)
 
@@ -1899,47 +2682,36 @@ func (s *Server) postGamesPromptHandler(w http.ResponseWriter,
 		return
 	}
 
-	g.Status = request.FieldString{
-		Set: true, Valid: true, Value: request.StatusUpdating,
+	if g.ReadOnly.Value {
+		s.error(errReadOnlyGame(g.ID.Value), w, r)
+
+		return
 	}
 
-	prompts, err := promptsFromFieldJSON(g.Prompts)
+	lineage, err := s.checkPromptLimits(ctx, g)
 	if err != nil {
-		s.error(errors.Wrap(err, errors.ErrInvalidRequest,
-			"unable to decode prompts",
-			"req", req), w, r)
-	}
+		s.error(err, w, r)
 
-	if prompts == nil {
-		prompts = &Prompts{}
+		return
 	}
 
-	hp := prompts.Current
-
-	hp.Thinking = request.FieldString{}
-	prompts.History = append(prompts.History, hp)
+	g.Status = request.FieldString{
+		Set: true, Valid: true, Value: request.StatusUpdating,
+	}
 
-	hb, err := json.Marshal(prompts.History)
+	prompts, err := s.nextPrompts(ctx, g, req.Current)
 	if err != nil {
-		s.error(errors.Wrap(err, errors.ErrServer,
-			"unable to encode prompt history",
-			"req", req), w, r)
+		s.error(err, w, r)
+
+		return
 	}
 
-	for len(hb) > int(s.cfg.PromptHistorySize()) && len(prompts.History) > 1 {
-		prompts.History = prompts.History[1:]
+	if err := s.archivePrompts(ctx, g, prompts.HistoryID.Value); err != nil {
+		s.error(err, w, r)
 
-		hb, err = json.Marshal(prompts.History)
-		if err != nil {
-			s.error(errors.Wrap(err, errors.ErrServer,
-				"unable to encode prompt history",
-				"req", req), w, r)
-		}
+		return
 	}
 
-	prompts.Current = req.Current
-	prompts.Error = request.FieldString{}
-
 	ps, err := promptsToFieldJSON(prompts)
 	if err != nil {
 		s.error(errors.Wrap(err, errors.ErrServer,
@@ -1947,34 +2719,7 @@ func (s *Server) postGamesPromptHandler(w http.ResponseWriter,
 			"req", req), w, r)
 	}
 
-	ng := &Game{
-		AccountID: g.AccountID,
-		Debug:     g.Debug,
-		Public:    g.Public,
-		Pause:     g.Pause,
-		W:         g.W,
-		H:         g.H,
-		PreviousID: request.FieldString{
-			Set: true, Valid: true, Value: g.ID.Value,
-		},
-		Name:        g.Name,
-		Version:     g.Version,
-		Description: g.Description,
-		Icon:        g.Icon,
-		Status: request.FieldString{
-			Set: true, Valid: true, Value: request.StatusUpdating,
-		},
-		StatusData: g.StatusData,
-		Subject:    g.Subject,
-		Objects:    g.Objects,
-		Images:     g.Images,
-		Script:     g.Script,
-		Source: request.FieldString{
-			Set: true, Valid: true, Value: "app",
-		},
-		Tags:    g.Tags,
-		Prompts: ps,
-	}
+	ng := gameRevision(g, request.StatusUpdating, ps)
 
 	ng, err = s.createGame(ctx, ng)
 	if err != nil {
@@ -1985,16 +2730,23 @@ func (s *Server) postGamesPromptHandler(w http.ResponseWriter,
 		return
 	}
 
+	s.recordPrompt(ctx, lineage, ng)
+
 	prompts.GameID = request.FieldString{
 		Set: true, Valid: true, Value: ng.ID.Value,
 	}
 
-	ctx, cancel := request.ContextReplaceTimeout(ctx,
-		s.cfg.ServerPromptTimeout())
+	ctx, cancel := s.detachedContext(ctx, opPrompt)
 
 	s.addPrompt(ng.ID.Value, cancel)
 
-	go s.sendPrompt(ctx, ng, prompts.Copy())
+	s.jobs.Add(1)
+
+	go func() {
+		defer s.jobs.Done()
+
+		s.sendPrompt(ctx, ng, prompts.Copy())
+	}()
 
 	w.WriteHeader(http.StatusCreated)
 
@@ -2011,7 +2763,7 @@ func (s *Server) postGamesPromptHandler(w http.ResponseWriter,
 
 	w.Header().Set("Location", loc.String())
 
-	if err := json.NewEncoder(w).Encode(prompts); err != nil {
+	if err := s.encode(w, r, prompts); err != nil {
 		s.error(err, w, r)
 	}
 }
@@ -2031,7 +2783,7 @@ func (s *Server) postGamesUndoHandler(w http.ResponseWriter,
 
 	req := &Prompts{}
 
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	if err := s.decode(r, &req); err != nil {
 		switch e := err.(type) {
 		case *errors.Error:
 			s.error(e, w, r)
@@ -2074,67 +2826,18 @@ func (s *Server) postGamesUndoHandler(w http.ResponseWriter,
 				"req", req)
 		}
 
-		if g.PreviousID.Value == "" {
-			return nil, errors.New(errors.ErrInvalidRequest,
-				"unable to undo game, no previous game",
-				"req", req)
+		pID, err := s.undoRevision(ctx, g.ID.Value)
+		if err != nil {
+			return nil, err
 		}
 
-		pg, err := s.getGame(ctx, g.PreviousID.Value)
+		pg, err := s.getGame(ctx, pID)
 		if err != nil {
 			return nil, errors.Wrap(err, errors.ErrDatabase,
 				"unable to get previous game to undo",
 				"req", req)
 		}
 
-		if pg == nil {
-			return nil, errors.New(errors.ErrNotFound,
-				"previous game not found",
-				"req", req)
-		}
-
-		if pg.PreviousID.Value != g.ID.Value &&
-			pg.PreviousID.Value != "" {
-			if err := s.deleteGame(ctx, pg.PreviousID.Value); err != nil {
-				return nil, errors.Wrap(err, errors.ErrDatabase,
-					"unable to delete previous game",
-					"previous_id", pg.PreviousID.Value,
-					"req", req)
-			}
-		}
-
-		g.PreviousID = request.FieldString{
-			Set: true, Valid: false, Value: "",
-		}
-
-		g.Status = request.FieldString{
-			Set: true, Valid: true, Value: request.StatusInactive,
-		}
-
-		pg.PreviousID = request.FieldString{
-			Set: true, Valid: true, Value: g.ID.Value,
-		}
-
-		pg.Status = request.FieldString{
-			Set: true, Valid: true, Value: request.StatusActive,
-		}
-
-		pg, err = s.updateGame(ctx, pg)
-		if err != nil {
-			return nil, errors.Wrap(err, errors.ErrDatabase,
-				"unable to update previous game to undo",
-				"req", req,
-				"previous_game", pg)
-		}
-
-		g, err = s.updateGame(ctx, g)
-		if err != nil {
-			return nil, errors.Wrap(err, errors.ErrDatabase,
-				"unable to update game to undo",
-				"req", req,
-				"game", g)
-		}
-
 		req.Current = Prompt{
 			Prompt: request.FieldString{
 				Set: true, Valid: true,
@@ -2175,7 +2878,7 @@ func (s *Server) postGamesUndoHandler(w http.ResponseWriter,
 
 	w.Header().Set("Location", loc.String())
 
-	if err := json.NewEncoder(w).Encode(res); err != nil {
+	if err := s.encode(w, r, res); err != nil {
 		s.error(err, w, r)
 	}
 }
@@ -2199,7 +2902,7 @@ func (s *Server) getAllGamesTagsHandler(w http.ResponseWriter,
 		return
 	}
 
-	if err := json.NewEncoder(w).Encode(res); err != nil {
+	if err := s.encode(w, r, res); err != nil {
 		s.error(err, w, r)
 	}
 }
@@ -2223,7 +2926,7 @@ func (s *Server) getGameTagsHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := json.NewEncoder(w).Encode(res); err != nil {
+	if err := s.encode(w, r, res); err != nil {
 		s.error(err, w, r)
 	}
 }
@@ -2242,7 +2945,7 @@ func (s *Server) postGameTagsHandler(w http.ResponseWriter, r *http.Request) {
 
 	tags := []string{}
 
-	if err := json.NewDecoder(r.Body).Decode(&tags); err != nil {
+	if err := s.decode(r, &tags); err != nil {
 		switch e := err.(type) {
 		case *errors.Error:
 			s.error(e, w, r)
@@ -2265,7 +2968,7 @@ func (s *Server) postGameTagsHandler(w http.ResponseWriter, r *http.Request) {
 
 	w.WriteHeader(http.StatusCreated)
 
-	if err := json.NewEncoder(w).Encode(res); err != nil {
+	if err := s.encode(w, r, res); err != nil {
 		s.error(err, w, r)
 	}
 }
@@ -2284,7 +2987,7 @@ func (s *Server) deleteGameTagsHandler(w http.ResponseWriter, r *http.Request) {
 
 	tags := []string{}
 
-	if err := json.NewDecoder(r.Body).Decode(&tags); err != nil {
+	if err := s.decode(r, &tags); err != nil {
 		switch e := err.(type) {
 		case *errors.Error:
 			s.error(e, w, r)
@@ -2,16 +2,27 @@ package server_test
 
 import (
 	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
 	"encoding/json"
+	"fmt"
 	"io"
+	"mime/multipart"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 	"sync"
 	"testing"
+	"time"
 
+	"github.com/dhaifley/game2d/cbor"
+	"github.com/dhaifley/game2d/repo"
 	"github.com/dhaifley/game2d/request"
 	"github.com/dhaifley/game2d/server"
+	"github.com/dhaifley/game2d/server/servertest"
+	"go.mongodb.org/mongo-driver/v2/bson"
 )
 
 var TestGame = server.Game{
@@ -76,6 +87,57 @@ func TestGamesServer(t *testing.T) {
 
 	dataLock := sync.Mutex{}
 
+	upload := &bytes.Buffer{}
+
+	mw := multipart.NewWriter(upload)
+
+	for _, f := range []struct{ field, name, data string }{
+		{"definition", "game.yaml", "name: Uploaded Game\nstatus: active\n"},
+		{"script", "game.lua", "function Update(data)\nend"},
+		{"image", "test.svg", "<svg></svg>"},
+	} {
+		fw, err := mw.CreateFormFile(f.field, f.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := fw.Write([]byte(f.data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := mw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	attach := &bytes.Buffer{}
+
+	aw := multipart.NewWriter(attach)
+
+	if err := aw.WriteField("prompt", "test"); err != nil {
+		t.Fatal(err)
+	}
+
+	if fw, err := aw.CreateFormFile("attachment", "test.bin"); err != nil {
+		t.Fatal(err)
+	} else if _, err := fw.Write([]byte{0, 1, 2, 3}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := aw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	bulkID := "22334455-6677-8899-0011-aabbccddeeff"
+
+	bulk := bytes.NewBufferString(`{"op":"create","id":"` + bulkID +
+		`","game":{"name":"Bulk Game","status":"active"}}
+{"op":"update","id":"` + bulkID + `","game":{"name":"Bulk Game 2"}}
+
+{"op":"delete","id":"` + bulkID + `"}
+{"op":"invalid"}
+`)
+
 	tests := []struct {
 		name   string
 		url    string
@@ -246,15 +308,9 @@ func TestGamesServer(t *testing.T) {
 			}
 		},
 	}, {
-		name:   "patch game",
-		url:    "http://localhost:8080/api/v1/games/{{id}}",
-		method: http.MethodPatch,
-		body: map[string]any{
-			"description": "Updated test game",
-			"data": map[string]any{
-				"updated": true,
-			},
-		},
+		name:   "get game stats",
+		url:    "http://localhost:8080/api/v1/games/{{id}}/stats",
+		method: http.MethodGet,
 		resp: func(t *testing.T, res *http.Response) {
 			expC := http.StatusOK
 
@@ -271,25 +327,25 @@ func TestGamesServer(t *testing.T) {
 			m := map[string]any{}
 
 			if err := json.Unmarshal(b, &m); err != nil {
-				t.Errorf("Unexpected error decoding response: %v", err)
+				t.Errorf("Unexpected error decoding response: %v ", err)
 			}
 
-			desc, ok := m["description"].(string)
-			if !ok || desc != "Updated test game" {
-				t.Errorf("Expected updated description in response: %v", m)
+			if v, ok := m["size"].(float64); !ok || v <= 0 {
+				t.Errorf("Expected size in response: %v", m)
+			}
+
+			if v, ok := m["size_limit"].(float64); !ok || v <= 0 {
+				t.Errorf("Expected size limit in response: %v", m)
 			}
 		},
 	}, {
-		name:   "put game",
-		url:    "http://localhost:8080/api/v1/games/{{id}}",
+		name:   "save game slot",
+		url:    "http://localhost:8080/api/v1/games/{{id}}/saves/slot1",
 		method: http.MethodPut,
 		body: map[string]any{
-			"name":        "Test Game Updated",
-			"version":     "2",
-			"description": "A fully updated test game",
-			"status":      "active",
 			"data": map[string]any{
-				"test": "updated",
+				"name":  "Test Game",
+				"score": 10,
 			},
 		},
 		resp: func(t *testing.T, res *http.Response) {
@@ -308,17 +364,16 @@ func TestGamesServer(t *testing.T) {
 			m := map[string]any{}
 
 			if err := json.Unmarshal(b, &m); err != nil {
-				t.Errorf("Unexpected error decoding response: %v", err)
+				t.Errorf("Unexpected error decoding response: %v ", err)
 			}
 
-			version, ok := m["version"].(string)
-			if !ok || version != "2" {
-				t.Errorf("Expected updated version in response: %v", m)
+			if v, ok := m["slot"].(string); !ok || v != "slot1" {
+				t.Errorf("Expected slot in response: %v", m)
 			}
 		},
 	}, {
-		name:   "get game tags",
-		url:    "http://localhost:8080/api/v1/games/{{id}}/tags",
+		name:   "list game saves",
+		url:    "http://localhost:8080/api/v1/games/{{id}}/saves",
 		method: http.MethodGet,
 		resp: func(t *testing.T, res *http.Response) {
 			expC := http.StatusOK
@@ -333,43 +388,35 @@ func TestGamesServer(t *testing.T) {
 				t.Errorf("Unexpected response error: %v", err)
 			}
 
-			var tags []any
-			if err := json.Unmarshal(b, &tags); err != nil {
-				t.Errorf("Unexpected error decoding response: %v %v", err, tags)
+			var saves []map[string]any
+
+			if err := json.Unmarshal(b, &saves); err != nil {
+				t.Errorf("Unexpected error decoding response: %v ", err)
+			}
+
+			if len(saves) != 1 {
+				t.Errorf("Expected one save slot in response: %v", saves)
 			}
 		},
 	}, {
-		name:   "create game tags",
-		url:    "http://localhost:8080/api/v1/games/{{id}}/tags",
-		method: http.MethodPost,
-		body:   []string{"test:tag1", "test:tag2"},
+		name:   "save game invalid slot",
+		url:    "http://localhost:8080/api/v1/games/{{id}}/saves/bad.slot",
+		method: http.MethodPut,
+		body: map[string]any{
+			"data": map[string]any{"name": "Test Game"},
+		},
 		resp: func(t *testing.T, res *http.Response) {
-			expC := http.StatusCreated
+			expC := http.StatusBadRequest
 
 			if res.StatusCode != expC {
 				t.Errorf("Status code expected: %v, got: %v",
 					expC, res.StatusCode)
 			}
-
-			b, err := io.ReadAll(res.Body)
-			if err != nil {
-				t.Errorf("Unexpected response error: %v", err)
-			}
-
-			var tags []string
-			if err := json.Unmarshal(b, &tags); err != nil {
-				t.Errorf("Unexpected error decoding response: %v", err)
-			}
-
-			if len(tags) != 2 {
-				t.Errorf("Expected 2 tags, got: %v", len(tags))
-			}
 		},
 	}, {
-		name:   "delete game tags",
-		url:    "http://localhost:8080/api/v1/games/{{id}}/tags",
+		name:   "delete game save",
+		url:    "http://localhost:8080/api/v1/games/{{id}}/saves/slot1",
 		method: http.MethodDelete,
-		body:   []string{"test:tag1", "test:tag2"},
 		resp: func(t *testing.T, res *http.Response) {
 			expC := http.StatusNoContent
 
@@ -379,10 +426,17 @@ func TestGamesServer(t *testing.T) {
 			}
 		},
 	}, {
-		name:   "prompt game",
-		url:    "http://localhost:8080/api/v1/games/prompt",
+		name:   "create challenge",
+		url:    "http://localhost:8080/api/v1/challenges",
 		method: http.MethodPost,
-		body:   map[string]any{"game_id": TestUUID, "prompt": "test"},
+		body: map[string]any{
+			"game_id":  TestUUID,
+			"name":     "Test Challenge",
+			"start_at": time.Now().Add(-time.Minute).Unix(),
+			"end_at":   time.Now().Add(time.Hour).Unix(),
+			"scoring":  "highest",
+			"public":   true,
+		},
 		resp: func(t *testing.T, res *http.Response) {
 			expC := http.StatusCreated
 
@@ -402,20 +456,42 @@ func TestGamesServer(t *testing.T) {
 				t.Errorf("Unexpected error decoding response: %v ", err)
 			}
 
-			id, ok := m["game_id"].(string)
+			if v, ok := m["status"].(string); !ok || v != "open" {
+				t.Errorf("Expected open status in response: %v", m)
+			}
+
+			id, ok := m["id"].(string)
 			if !ok {
-				t.Errorf("Expected game_id in response: %v", m)
+				t.Errorf("Expected id in response: %v", m)
 			}
 
 			dataLock.Lock()
-			data["game_id"] = id
+			data["challenge_id"] = id
 			dataLock.Unlock()
 		},
 	}, {
-		name:   "undo prompt",
-		url:    "http://localhost:8080/api/v1/games/undo",
+		name:   "create challenge invalid window",
+		url:    "http://localhost:8080/api/v1/challenges",
 		method: http.MethodPost,
-		body:   map[string]any{"game_id": TestUUID},
+		body: map[string]any{
+			"game_id":  TestUUID,
+			"name":     "Test Challenge",
+			"start_at": time.Now().Unix(),
+			"end_at":   time.Now().Add(-time.Hour).Unix(),
+		},
+		resp: func(t *testing.T, res *http.Response) {
+			expC := http.StatusBadRequest
+
+			if res.StatusCode != expC {
+				t.Errorf("Status code expected: %v, got: %v",
+					expC, res.StatusCode)
+			}
+		},
+	}, {
+		name:   "submit challenge score",
+		url:    "http://localhost:8080/api/v1/challenges/{{challenge_id}}/scores",
+		method: http.MethodPost,
+		body:   map[string]any{"player": "Tester", "score": 100},
 		resp: func(t *testing.T, res *http.Response) {
 			expC := http.StatusCreated
 
@@ -429,21 +505,63 @@ func TestGamesServer(t *testing.T) {
 				t.Errorf("Unexpected response error: %v", err)
 			}
 
-			m := map[string]any{}
+			var ranking []map[string]any
 
-			if err := json.Unmarshal(b, &m); err != nil {
+			if err := json.Unmarshal(b, &ranking); err != nil {
 				t.Errorf("Unexpected error decoding response: %v ", err)
 			}
 
-			if _, ok := m["game_id"].(string); !ok {
-				t.Errorf("Expected id in response: %v", m)
+			if len(ranking) != 1 || ranking[0]["score"] != float64(100) {
+				t.Errorf("Expected submitted score in ranking: %v", ranking)
 			}
 		},
 	}, {
-		name:   "copy game",
-		url:    "http://localhost:8080/api/v1/games/copy",
+		name:   "challenge public page",
+		url:    "http://localhost:8080/api/v1/challenges/{{challenge_id}}/public",
+		method: http.MethodGet,
+		resp: func(t *testing.T, res *http.Response) {
+			expC := http.StatusOK
+
+			if res.StatusCode != expC {
+				t.Errorf("Status code expected: %v, got: %v",
+					expC, res.StatusCode)
+			}
+
+			b, err := io.ReadAll(res.Body)
+			if err != nil {
+				t.Errorf("Unexpected response error: %v", err)
+			}
+
+			if strings.Contains(string(b), "user_id") {
+				t.Errorf("Expected no user ids in public page: %v",
+					string(b))
+			}
+		},
+	}, {
+		name:   "delete challenge",
+		url:    "http://localhost:8080/api/v1/challenges/{{challenge_id}}",
+		method: http.MethodDelete,
+		resp: func(t *testing.T, res *http.Response) {
+			expC := http.StatusNoContent
+
+			if res.StatusCode != expC {
+				t.Errorf("Status code expected: %v, got: %v",
+					expC, res.StatusCode)
+			}
+		},
+	}, {
+		name:   "create automation",
+		url:    "http://localhost:8080/api/v1/automations",
 		method: http.MethodPost,
-		body:   map[string]any{"id": TestUUID, "name": "test copy"},
+		body: map[string]any{
+			"name":      "Tag new games",
+			"trigger":   "game_created",
+			"condition": `data.name contains "test"`,
+			"actions": []map[string]any{{
+				"type": "add_tags",
+				"tags": []string{"automated"},
+			}},
+		},
 		resp: func(t *testing.T, res *http.Response) {
 			expC := http.StatusCreated
 
@@ -469,15 +587,24 @@ func TestGamesServer(t *testing.T) {
 			}
 
 			dataLock.Lock()
-			data["copy_id"] = id
+			data["automation_id"] = id
 			dataLock.Unlock()
 		},
 	}, {
-		name:   "delete game copy",
-		url:    "http://localhost:8080/api/v1/games/{{copy_id}}",
-		method: http.MethodDelete,
+		name:   "create automation invalid condition",
+		url:    "http://localhost:8080/api/v1/automations",
+		method: http.MethodPost,
+		body: map[string]any{
+			"name":      "Invalid",
+			"trigger":   "import_error",
+			"condition": `data.error ==`,
+			"actions": []map[string]any{{
+				"type": "webhook",
+				"url":  "https://example.com/hook",
+			}},
+		},
 		resp: func(t *testing.T, res *http.Response) {
-			expC := http.StatusNoContent
+			expC := http.StatusBadRequest
 
 			if res.StatusCode != expC {
 				t.Errorf("Status code expected: %v, got: %v",
@@ -485,8 +612,20 @@ func TestGamesServer(t *testing.T) {
 			}
 		},
 	}, {
-		name:   "delete game",
-		url:    "http://localhost:8080/api/v1/games/{{id}}",
+		name:   "list automation jobs",
+		url:    "http://localhost:8080/api/v1/automations/{{automation_id}}/jobs",
+		method: http.MethodGet,
+		resp: func(t *testing.T, res *http.Response) {
+			expC := http.StatusOK
+
+			if res.StatusCode != expC {
+				t.Errorf("Status code expected: %v, got: %v",
+					expC, res.StatusCode)
+			}
+		},
+	}, {
+		name:   "delete automation",
+		url:    "http://localhost:8080/api/v1/automations/{{automation_id}}",
 		method: http.MethodDelete,
 		resp: func(t *testing.T, res *http.Response) {
 			expC := http.StatusNoContent
@@ -496,72 +635,1440 @@ func TestGamesServer(t *testing.T) {
 					expC, res.StatusCode)
 			}
 		},
-	}}
+	}, {
+		name:   "get games by id",
+		url:    "http://localhost:8080/api/v1/games?ids={{id}}&minimal=true",
+		method: http.MethodGet,
+		resp: func(t *testing.T, res *http.Response) {
+			expC := http.StatusOK
 
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			if strings.Contains(tt.url, "{{id}}") {
-				dataLock.Lock()
-				gameID, _ := data["id"].(string)
-				dataLock.Unlock()
+			if res.StatusCode != expC {
+				t.Errorf("Status code expected: %v, got: %v",
+					expC, res.StatusCode)
+			}
 
-				tt.url = strings.ReplaceAll(tt.url, "{{id}}",
-					gameID)
+			b, err := io.ReadAll(res.Body)
+			if err != nil {
+				t.Errorf("Unexpected response error: %v", err)
 			}
 
-			if strings.Contains(tt.url, "{{copy_id}}") {
-				dataLock.Lock()
-				gameID, _ := data["copy_id"].(string)
-				dataLock.Unlock()
+			var games []map[string]any
 
-				tt.url = strings.ReplaceAll(tt.url, "{{copy_id}}",
-					gameID)
+			if err := json.Unmarshal(b, &games); err != nil {
+				t.Errorf("Unexpected error decoding response: %v ", err)
 			}
 
-			buf := &bytes.Buffer{}
-
-			if tt.body != nil {
-				if ct, ok := tt.header["Content-Type"]; ok {
-					if !strings.Contains("json", ct) {
-						if bm, ok := tt.body.(map[string]any); ok {
-							form := url.Values{}
+			if len(games) != 1 {
+				t.Errorf("Expected one game in response: %v", games)
+			}
+		},
+	}, {
+		name:   "get games by invalid id",
+		url:    "http://localhost:8080/api/v1/games?ids=invalid",
+		method: http.MethodGet,
+		resp: func(t *testing.T, res *http.Response) {
+			expC := http.StatusBadRequest
 
-							for k, v := range bm {
-								switch vv := v.(type) {
-								case string:
-									form.Add(k, vv)
-								default:
-									b, err := json.Marshal(vv)
-									if err != nil {
-										t.Error(err)
-									}
+			if res.StatusCode != expC {
+				t.Errorf("Status code expected: %v, got: %v",
+					expC, res.StatusCode)
+			}
+		},
+	}, {
+		name:   "graphql query games",
+		url:    "http://localhost:8080/api/v1/graphql",
+		method: http.MethodPost,
+		body: map[string]any{
+			"query": `query($size: Int) { games(size: $size) { id name ` +
+				`versions(limit: 2) { id } prompts { current { prompt } } } ` +
+				`tags account { id name } }`,
+			"variables": map[string]any{"size": 5},
+		},
+		resp: func(t *testing.T, res *http.Response) {
+			expC := http.StatusOK
 
-									form.Add(k, string(b))
-								}
-							}
+			if res.StatusCode != expC {
+				t.Errorf("Status code expected: %v, got: %v",
+					expC, res.StatusCode)
+			}
 
-							buf = bytes.NewBufferString(form.Encode())
-						}
-					}
-				}
+			b, err := io.ReadAll(res.Body)
+			if err != nil {
+				t.Errorf("Unexpected response error: %v", err)
+			}
 
-				if buf.Len() == 0 {
-					var (
-						b   []byte
-						err error
-					)
+			m := map[string]any{}
 
-					if bm, ok := tt.body.(map[string]any); ok {
-						dataLock.Lock()
-						gID, _ := data["game_id"].(string)
-						dataLock.Unlock()
+			if err := json.Unmarshal(b, &m); err != nil {
+				t.Errorf("Unexpected error decoding response: %v ", err)
+			}
 
-						if gID != "" {
-							bm["game_id"] = gID
-						}
+			if _, ok := m["errors"]; ok {
+				t.Errorf("Unexpected errors in response: %v", m)
+			}
 
-						b, err = json.Marshal(bm)
-						if err != nil {
+			d, _ := m["data"].(map[string]any)
+			if _, ok := d["games"].([]any); !ok {
+				t.Errorf("Expected games in response: %v", m)
+			}
+		},
+	}, {
+		name:   "graphql invalid field",
+		url:    "http://localhost:8080/api/v1/graphql",
+		method: http.MethodPost,
+		body: map[string]any{
+			"query": `{ account { secret } }`,
+		},
+		resp: func(t *testing.T, res *http.Response) {
+			expC := http.StatusBadRequest
+
+			if res.StatusCode != expC {
+				t.Errorf("Status code expected: %v, got: %v",
+					expC, res.StatusCode)
+			}
+		},
+	}, {
+		name:   "list game spectate streams",
+		url:    "http://localhost:8080/api/v1/games/{{id}}/spectate",
+		method: http.MethodGet,
+		resp: func(t *testing.T, res *http.Response) {
+			expC := http.StatusOK
+
+			if res.StatusCode != expC {
+				t.Errorf("Status code expected: %v, got: %v",
+					expC, res.StatusCode)
+			}
+
+			b, err := io.ReadAll(res.Body)
+			if err != nil {
+				t.Errorf("Unexpected response error: %v", err)
+			}
+
+			var streams []map[string]any
+
+			if err := json.Unmarshal(b, &streams); err != nil {
+				t.Errorf("Unexpected error decoding response: %v ", err)
+			}
+
+			if len(streams) != 0 {
+				t.Errorf("Expected no spectate streams: %v", streams)
+			}
+		},
+	}, {
+		name: "spectate game stream not found",
+		url: "http://localhost:8080/api/v1/games/{{id}}/spectate/" +
+			"11223344-5566-7788-9900-aabbccddeeff",
+		method: http.MethodGet,
+		resp: func(t *testing.T, res *http.Response) {
+			expC := http.StatusNotFound
+
+			if res.StatusCode != expC {
+				t.Errorf("Status code expected: %v, got: %v",
+					expC, res.StatusCode)
+			}
+		},
+	}, {
+		name:   "spectate game invalid session",
+		url:    "http://localhost:8080/api/v1/games/{{id}}/spectate/invalid",
+		method: http.MethodGet,
+		resp: func(t *testing.T, res *http.Response) {
+			expC := http.StatusBadRequest
+
+			if res.StatusCode != expC {
+				t.Errorf("Status code expected: %v, got: %v",
+					expC, res.StatusCode)
+			}
+		},
+	}, {
+		name:   "package game",
+		url:    "http://localhost:8080/api/v1/games/{{id}}/package",
+		method: http.MethodGet,
+		resp: func(t *testing.T, res *http.Response) {
+			expC := http.StatusOK
+
+			if res.StatusCode != expC {
+				t.Errorf("Status code expected: %v, got: %v",
+					expC, res.StatusCode)
+			}
+
+			b, err := io.ReadAll(res.Body)
+			if err != nil {
+				t.Errorf("Unexpected response error: %v", err)
+			}
+
+			expB := "setGameData("
+
+			if !strings.Contains(string(b), expB) {
+				t.Errorf("Expected body to contain: %v, got: %v",
+					expB, string(b))
+			}
+		},
+	}, {
+		name:   "share game",
+		url:    "http://localhost:8080/api/v1/games/{{id}}/share",
+		method: http.MethodPost,
+		resp: func(t *testing.T, res *http.Response) {
+			expC := http.StatusCreated
+
+			if res.StatusCode != expC {
+				t.Errorf("Status code expected: %v, got: %v",
+					expC, res.StatusCode)
+			}
+
+			b, err := io.ReadAll(res.Body)
+			if err != nil {
+				t.Errorf("Unexpected response error: %v", err)
+			}
+
+			m := map[string]any{}
+
+			if err := json.Unmarshal(b, &m); err != nil {
+				t.Errorf("Unexpected error decoding response: %v ", err)
+			}
+
+			if _, ok := m["token"].(string); !ok {
+				t.Errorf("Expected token in response: %v", m)
+			}
+		},
+	}, {
+		name:   "embed private game",
+		url:    "http://localhost:8080/embed/{{id}}",
+		method: http.MethodGet,
+		resp: func(t *testing.T, res *http.Response) {
+			expC := http.StatusNotFound
+
+			if res.StatusCode != expC {
+				t.Errorf("Status code expected: %v, got: %v",
+					expC, res.StatusCode)
+			}
+		},
+	}, {
+		name:   "embed private game icon",
+		url:    "http://localhost:8080/embed/{{id}}/icon.svg",
+		method: http.MethodGet,
+		resp: func(t *testing.T, res *http.Response) {
+			expC := http.StatusNotFound
+
+			if res.StatusCode != expC {
+				t.Errorf("Status code expected: %v, got: %v",
+					expC, res.StatusCode)
+			}
+		},
+	}, {
+		name:   "get sitemap",
+		url:    "http://localhost:8080/sitemap.xml",
+		method: http.MethodGet,
+		resp: func(t *testing.T, res *http.Response) {
+			expC := http.StatusOK
+
+			if res.StatusCode != expC {
+				t.Errorf("Status code expected: %v, got: %v",
+					expC, res.StatusCode)
+			}
+
+			b, err := io.ReadAll(res.Body)
+			if err != nil {
+				t.Errorf("Unexpected response error: %v", err)
+			}
+
+			expB := "<urlset"
+
+			if !strings.Contains(string(b), expB) {
+				t.Errorf("Expected body to contain: %v, got: %v",
+					expB, string(b))
+			}
+		},
+	}, {
+		name:   "get service worker",
+		url:    "http://localhost:8080/sw.js",
+		method: http.MethodGet,
+		resp: func(t *testing.T, res *http.Response) {
+			expC := http.StatusOK
+
+			if res.StatusCode != expC {
+				t.Errorf("Status code expected: %v, got: %v",
+					expC, res.StatusCode)
+			}
+
+			b, err := io.ReadAll(res.Body)
+			if err != nil {
+				t.Errorf("Unexpected response error: %v", err)
+			}
+
+			expB := `const CACHE = "game2d-runtime-`
+
+			if !strings.Contains(string(b), expB) {
+				t.Errorf("Expected body to contain: %v, got: %v",
+					expB, string(b))
+			}
+		},
+	}, {
+		name:   "patch game",
+		url:    "http://localhost:8080/api/v1/games/{{id}}",
+		method: http.MethodPatch,
+		body: map[string]any{
+			"description": "Updated test game",
+			"data": map[string]any{
+				"updated": true,
+			},
+		},
+		resp: func(t *testing.T, res *http.Response) {
+			expC := http.StatusOK
+
+			if res.StatusCode != expC {
+				t.Errorf("Status code expected: %v, got: %v",
+					expC, res.StatusCode)
+			}
+
+			b, err := io.ReadAll(res.Body)
+			if err != nil {
+				t.Errorf("Unexpected response error: %v", err)
+			}
+
+			m := map[string]any{}
+
+			if err := json.Unmarshal(b, &m); err != nil {
+				t.Errorf("Unexpected error decoding response: %v", err)
+			}
+
+			desc, ok := m["description"].(string)
+			if !ok || desc != "Updated test game" {
+				t.Errorf("Expected updated description in response: %v", m)
+			}
+		},
+	}, {
+		name:   "get similar games",
+		url:    "http://localhost:8080/api/v1/games/{{id}}/similar?size=5",
+		method: http.MethodGet,
+		resp: func(t *testing.T, res *http.Response) {
+			expC := http.StatusOK
+
+			if res.StatusCode != expC {
+				t.Errorf("Status code expected: %v, got: %v",
+					expC, res.StatusCode)
+			}
+
+			var games []map[string]any
+
+			if err := json.NewDecoder(res.Body).Decode(&games); err != nil {
+				t.Errorf("Unexpected error decoding response: %v", err)
+			}
+		},
+	}, {
+		name:   "get game delta",
+		url:    "http://localhost:8080/api/v1/games/{{id}}/delta",
+		method: http.MethodGet,
+		resp: func(t *testing.T, res *http.Response) {
+			expC := http.StatusOK
+
+			if res.StatusCode != expC {
+				t.Errorf("Status code expected: %v, got: %v",
+					expC, res.StatusCode)
+			}
+
+			var delta map[string]any
+
+			if err := json.NewDecoder(res.Body).Decode(&delta); err != nil {
+				t.Errorf("Unexpected error decoding response: %v", err)
+			}
+
+			if rev, _ := delta["rev"].(string); rev == "" {
+				t.Errorf("Expected game revision in response: %v", delta)
+			}
+		},
+	}, {
+		name:   "get game delta invalid revision",
+		url:    "http://localhost:8080/api/v1/games/{{id}}/delta?since=x",
+		method: http.MethodGet,
+		resp: func(t *testing.T, res *http.Response) {
+			expC := http.StatusBadRequest
+
+			if res.StatusCode != expC {
+				t.Errorf("Status code expected: %v, got: %v",
+					expC, res.StatusCode)
+			}
+		},
+	}, {
+		name:   "get game asset refs",
+		url:    "http://localhost:8080/api/v1/games/{{id}}?asset_refs=true",
+		method: http.MethodGet,
+		resp: func(t *testing.T, res *http.Response) {
+			expC := http.StatusOK
+
+			if res.StatusCode != expC {
+				t.Errorf("Status code expected: %v, got: %v",
+					expC, res.StatusCode)
+			}
+		},
+	}, {
+		name:   "get asset invalid hash",
+		url:    "http://localhost:8080/assets/test",
+		method: http.MethodGet,
+		resp: func(t *testing.T, res *http.Response) {
+			expC := http.StatusBadRequest
+
+			if res.StatusCode != expC {
+				t.Errorf("Status code expected: %v, got: %v",
+					expC, res.StatusCode)
+			}
+		},
+	}, {
+		name:   "get asset not found",
+		url:    "http://localhost:8080/assets/" + TestAssetHash,
+		method: http.MethodGet,
+		resp: func(t *testing.T, res *http.Response) {
+			expC := http.StatusNotFound
+
+			if res.StatusCode != expC {
+				t.Errorf("Status code expected: %v, got: %v",
+					expC, res.StatusCode)
+			}
+		},
+	}, {
+		name:   "get games route not found",
+		url:    "http://localhost:8080/api/v1/games/{{id}}/detla",
+		method: http.MethodGet,
+		resp: func(t *testing.T, res *http.Response) {
+			expC := http.StatusNotFound
+
+			if res.StatusCode != expC {
+				t.Errorf("Status code expected: %v, got: %v",
+					expC, res.StatusCode)
+			}
+
+			b, err := io.ReadAll(res.Body)
+			if err != nil {
+				t.Errorf("Unexpected response error: %v", err)
+			}
+
+			m := map[string]any{}
+
+			if err := json.Unmarshal(b, &m); err != nil {
+				t.Errorf("Unexpected error decoding response: %v", err)
+			}
+
+			if m["reason"] != "ROUTE_NOT_FOUND" ||
+				m["message"] != "game route not found" {
+				t.Errorf("Unexpected response: %v", m)
+			}
+
+			data, _ := m["data"].(map[string]any)
+
+			if data["suggestion"] != "/api/v1/games/{id}/delta" {
+				t.Errorf("Unexpected suggestion: %v", data["suggestion"])
+			}
+		},
+	}, {
+		name:   "get recommended games",
+		url:    "http://localhost:8080/api/v1/games/recommended",
+		method: http.MethodGet,
+		resp: func(t *testing.T, res *http.Response) {
+			expC := http.StatusOK
+
+			if res.StatusCode != expC {
+				t.Errorf("Status code expected: %v, got: %v",
+					expC, res.StatusCode)
+			}
+		},
+	}, {
+		name:   "describe game",
+		url:    "http://localhost:8080/api/v1/games/{{id}}/describe",
+		method: http.MethodPost,
+		resp: func(t *testing.T, res *http.Response) {
+			expC := http.StatusOK
+
+			if res.StatusCode != expC {
+				t.Errorf("Status code expected: %v, got: %v",
+					expC, res.StatusCode)
+			}
+
+			b, err := io.ReadAll(res.Body)
+			if err != nil {
+				t.Errorf("Unexpected response error: %v", err)
+			}
+
+			m := map[string]any{}
+
+			if err := json.Unmarshal(b, &m); err != nil {
+				t.Errorf("Unexpected error decoding response: %v", err)
+			}
+
+			desc, ok := m["description"].(string)
+			if !ok || desc != "Updated test game" {
+				t.Errorf("Expected existing description in response: %v", m)
+			}
+
+			if c, ok := m["controls"].(string); !ok || c == "" {
+				t.Errorf("Expected controls in response: %v", m)
+			}
+		},
+	}, {
+		name:   "put game",
+		url:    "http://localhost:8080/api/v1/games/{{id}}",
+		method: http.MethodPut,
+		body: map[string]any{
+			"name":        "Test Game Updated",
+			"version":     "2",
+			"description": "A fully updated test game",
+			"status":      "active",
+			"data": map[string]any{
+				"test": "updated",
+			},
+		},
+		resp: func(t *testing.T, res *http.Response) {
+			expC := http.StatusOK
+
+			if res.StatusCode != expC {
+				t.Errorf("Status code expected: %v, got: %v",
+					expC, res.StatusCode)
+			}
+
+			b, err := io.ReadAll(res.Body)
+			if err != nil {
+				t.Errorf("Unexpected response error: %v", err)
+			}
+
+			m := map[string]any{}
+
+			if err := json.Unmarshal(b, &m); err != nil {
+				t.Errorf("Unexpected error decoding response: %v", err)
+			}
+
+			version, ok := m["version"].(string)
+			if !ok || version != "2" {
+				t.Errorf("Expected updated version in response: %v", m)
+			}
+		},
+	}, {
+		name:   "patch game bindings",
+		url:    "http://localhost:8080/api/v1/games/{{id}}",
+		method: http.MethodPatch,
+		body: map[string]any{
+			"bindings": map[string]any{
+				"jump": []string{"Space", "ArrowUp"},
+			},
+		},
+		resp: func(t *testing.T, res *http.Response) {
+			expC := http.StatusOK
+
+			if res.StatusCode != expC {
+				t.Errorf("Status code expected: %v, got: %v",
+					expC, res.StatusCode)
+			}
+
+			b, err := io.ReadAll(res.Body)
+			if err != nil {
+				t.Errorf("Unexpected response error: %v", err)
+			}
+
+			m := map[string]any{}
+
+			if err := json.Unmarshal(b, &m); err != nil {
+				t.Errorf("Unexpected error decoding response: %v", err)
+			}
+
+			if _, ok := m["bindings"].(map[string]any)["jump"]; !ok {
+				t.Errorf("Expected bindings in response: %v", m)
+			}
+		},
+	}, {
+		name:   "patch game invalid bindings",
+		url:    "http://localhost:8080/api/v1/games/{{id}}",
+		method: http.MethodPatch,
+		body: map[string]any{
+			"bindings": map[string]any{
+				"jump": []string{"NotAKey"},
+			},
+		},
+		resp: func(t *testing.T, res *http.Response) {
+			expC := http.StatusBadRequest
+
+			if res.StatusCode != expC {
+				t.Errorf("Status code expected: %v, got: %v",
+					expC, res.StatusCode)
+			}
+		},
+	}, {
+		name:   "patch game locales",
+		url:    "http://localhost:8080/api/v1/games/{{id}}",
+		method: http.MethodPatch,
+		body: map[string]any{
+			"locales": map[string]any{
+				"en": map[string]any{"title": "Test"},
+				"fr": map[string]any{"title": "Essai"},
+			},
+		},
+		resp: func(t *testing.T, res *http.Response) {
+			expC := http.StatusOK
+
+			if res.StatusCode != expC {
+				t.Errorf("Status code expected: %v, got: %v",
+					expC, res.StatusCode)
+			}
+
+			m := map[string]any{}
+
+			if err := json.NewDecoder(res.Body).Decode(&m); err != nil {
+				t.Errorf("Unexpected error decoding response: %v", err)
+			}
+
+			if _, ok := m["locales"].(map[string]any)["fr"]; !ok {
+				t.Errorf("Expected locales in response: %v", m)
+			}
+		},
+	}, {
+		name:   "patch game invalid locales",
+		url:    "http://localhost:8080/api/v1/games/{{id}}",
+		method: http.MethodPatch,
+		body: map[string]any{
+			"locales": map[string]any{
+				"not a language": map[string]any{"title": 1},
+			},
+		},
+		resp: func(t *testing.T, res *http.Response) {
+			expC := http.StatusBadRequest
+
+			if res.StatusCode != expC {
+				t.Errorf("Status code expected: %v, got: %v",
+					expC, res.StatusCode)
+			}
+		},
+	}, {
+		name:   "get game tags",
+		url:    "http://localhost:8080/api/v1/games/{{id}}/tags",
+		method: http.MethodGet,
+		resp: func(t *testing.T, res *http.Response) {
+			expC := http.StatusOK
+
+			if res.StatusCode != expC {
+				t.Errorf("Status code expected: %v, got: %v",
+					expC, res.StatusCode)
+			}
+
+			b, err := io.ReadAll(res.Body)
+			if err != nil {
+				t.Errorf("Unexpected response error: %v", err)
+			}
+
+			var tags []any
+			if err := json.Unmarshal(b, &tags); err != nil {
+				t.Errorf("Unexpected error decoding response: %v %v", err, tags)
+			}
+		},
+	}, {
+		name:   "create game tags",
+		url:    "http://localhost:8080/api/v1/games/{{id}}/tags",
+		method: http.MethodPost,
+		body:   []string{"test:tag1", "test:tag2"},
+		resp: func(t *testing.T, res *http.Response) {
+			expC := http.StatusCreated
+
+			if res.StatusCode != expC {
+				t.Errorf("Status code expected: %v, got: %v",
+					expC, res.StatusCode)
+			}
+
+			b, err := io.ReadAll(res.Body)
+			if err != nil {
+				t.Errorf("Unexpected response error: %v", err)
+			}
+
+			var tags []string
+			if err := json.Unmarshal(b, &tags); err != nil {
+				t.Errorf("Unexpected error decoding response: %v", err)
+			}
+
+			if len(tags) != 2 {
+				t.Errorf("Expected 2 tags, got: %v", len(tags))
+			}
+		},
+	}, {
+		name:   "search games by tag",
+		url:    "http://localhost:8080/api/v1/games?tag=test:tag1,test:tag2",
+		method: http.MethodGet,
+		resp: func(t *testing.T, res *http.Response) {
+			expC := http.StatusOK
+
+			if res.StatusCode != expC {
+				t.Errorf("Status code expected: %v, got: %v",
+					expC, res.StatusCode)
+			}
+
+			b, err := io.ReadAll(res.Body)
+			if err != nil {
+				t.Errorf("Unexpected response error: %v", err)
+			}
+
+			var games []map[string]any
+			if err := json.Unmarshal(b, &games); err != nil {
+				t.Errorf("Unexpected error decoding response: %v", err)
+			}
+
+			if len(games) != 1 {
+				t.Errorf("Expected 1 game, got: %v", len(games))
+			}
+		},
+	}, {
+		name:   "search games sorted",
+		url:    "http://localhost:8080/api/v1/games?sort=-updated_at,name",
+		method: http.MethodGet,
+		resp: func(t *testing.T, res *http.Response) {
+			expC := http.StatusOK
+
+			if res.StatusCode != expC {
+				t.Errorf("Status code expected: %v, got: %v",
+					expC, res.StatusCode)
+			}
+		},
+	}, {
+		name:   "search games invalid sort",
+		url:    "http://localhost:8080/api/v1/games?sort=script",
+		method: http.MethodGet,
+		resp: func(t *testing.T, res *http.Response) {
+			expC := http.StatusBadRequest
+
+			if res.StatusCode != expC {
+				t.Errorf("Status code expected: %v, got: %v",
+					expC, res.StatusCode)
+			}
+		},
+	}, {
+		name:   "search games without count",
+		url:    "http://localhost:8080/api/v1/games?count=none",
+		method: http.MethodGet,
+		resp: func(t *testing.T, res *http.Response) {
+			expC := http.StatusOK
+
+			if res.StatusCode != expC {
+				t.Errorf("Status code expected: %v, got: %v",
+					expC, res.StatusCode)
+			}
+
+			if v := res.Header.Get("X-Total-Count"); v != "" {
+				t.Errorf("Expected no total count, got: %v", v)
+			}
+		},
+	}, {
+		name:   "search games estimated count",
+		url:    "http://localhost:8080/api/v1/games?count=estimated&size=1000",
+		method: http.MethodGet,
+		resp: func(t *testing.T, res *http.Response) {
+			expC := http.StatusOK
+
+			if res.StatusCode != expC {
+				t.Errorf("Status code expected: %v, got: %v",
+					expC, res.StatusCode)
+			}
+
+			if v := res.Header.Get("X-Total-Count"); v == "" {
+				t.Errorf("Expected total count")
+			}
+
+			if v := res.Header.Get("X-Total-Count-Estimated"); v != "" {
+				t.Errorf("Expected exact estimated count, got: %v", v)
+			}
+		},
+	}, {
+		name:   "search games invalid count",
+		url:    "http://localhost:8080/api/v1/games?count=some",
+		method: http.MethodGet,
+		resp: func(t *testing.T, res *http.Response) {
+			expC := http.StatusBadRequest
+
+			if res.StatusCode != expC {
+				t.Errorf("Status code expected: %v, got: %v",
+					expC, res.StatusCode)
+			}
+		},
+	}, {
+		name:   "search games by missing tag",
+		url:    "http://localhost:8080/api/v1/games?tag=test:missing",
+		method: http.MethodGet,
+		resp: func(t *testing.T, res *http.Response) {
+			expC := http.StatusOK
+
+			if res.StatusCode != expC {
+				t.Errorf("Status code expected: %v, got: %v",
+					expC, res.StatusCode)
+			}
+
+			b, err := io.ReadAll(res.Body)
+			if err != nil {
+				t.Errorf("Unexpected response error: %v", err)
+			}
+
+			var games []map[string]any
+			if err := json.Unmarshal(b, &games); err != nil {
+				t.Errorf("Unexpected error decoding response: %v", err)
+			}
+
+			if len(games) != 0 {
+				t.Errorf("Expected 0 games, got: %v", len(games))
+			}
+		},
+	}, {
+		name:   "delete game tags",
+		url:    "http://localhost:8080/api/v1/games/{{id}}/tags",
+		method: http.MethodDelete,
+		body:   []string{"test:tag1", "test:tag2"},
+		resp: func(t *testing.T, res *http.Response) {
+			expC := http.StatusNoContent
+
+			if res.StatusCode != expC {
+				t.Errorf("Status code expected: %v, got: %v",
+					expC, res.StatusCode)
+			}
+		},
+	}, {
+		name:   "set game status error",
+		url:    "http://localhost:8080/api/v1/games/{{id}}/status",
+		method: http.MethodPost,
+		body:   map[string]any{"status": "error"},
+		resp: func(t *testing.T, res *http.Response) {
+			expC := http.StatusOK
+
+			if res.StatusCode != expC {
+				t.Errorf("Status code expected: %v, got: %v",
+					expC, res.StatusCode)
+			}
+
+			b, err := io.ReadAll(res.Body)
+			if err != nil {
+				t.Errorf("Unexpected response error: %v", err)
+			}
+
+			expB := `"status":"error"`
+
+			if !strings.Contains(string(b), expB) {
+				t.Errorf("Expected body to contain: %v, got: %v",
+					expB, string(b))
+			}
+		},
+	}, {
+		name:   "set game status inactive",
+		url:    "http://localhost:8080/api/v1/games/{{id}}/status",
+		method: http.MethodPost,
+		body:   map[string]any{"status": "inactive"},
+		resp: func(t *testing.T, res *http.Response) {
+			expC := http.StatusOK
+
+			if res.StatusCode != expC {
+				t.Errorf("Status code expected: %v, got: %v",
+					expC, res.StatusCode)
+			}
+		},
+	}, {
+		name:   "set game status invalid transition",
+		url:    "http://localhost:8080/api/v1/games/{{id}}/status",
+		method: http.MethodPost,
+		body:   map[string]any{"status": "updating"},
+		resp: func(t *testing.T, res *http.Response) {
+			expC := http.StatusConflict
+
+			if res.StatusCode != expC {
+				t.Errorf("Status code expected: %v, got: %v",
+					expC, res.StatusCode)
+			}
+
+			b, err := io.ReadAll(res.Body)
+			if err != nil {
+				t.Errorf("Unexpected response error: %v", err)
+			}
+
+			expB := `"INVALID_STATUS_TRANSITION"`
+
+			if !strings.Contains(string(b), expB) {
+				t.Errorf("Expected body to contain: %v, got: %v",
+					expB, string(b))
+			}
+		},
+	}, {
+		name:   "set game status unknown",
+		url:    "http://localhost:8080/api/v1/games/{{id}}/status",
+		method: http.MethodPost,
+		body:   map[string]any{"status": "unknown"},
+		resp: func(t *testing.T, res *http.Response) {
+			expC := http.StatusBadRequest
+
+			if res.StatusCode != expC {
+				t.Errorf("Status code expected: %v, got: %v",
+					expC, res.StatusCode)
+			}
+		},
+	}, {
+		name:   "set game status active",
+		url:    "http://localhost:8080/api/v1/games/{{id}}/status",
+		method: http.MethodPost,
+		body:   map[string]any{"status": "active"},
+		resp: func(t *testing.T, res *http.Response) {
+			expC := http.StatusOK
+
+			if res.StatusCode != expC {
+				t.Errorf("Status code expected: %v, got: %v",
+					expC, res.StatusCode)
+			}
+		},
+	}, {
+		name:   "estimate game prompt",
+		url:    "http://localhost:8080/api/v1/games/{{id}}/prompt/estimate",
+		method: http.MethodPost,
+		body:   map[string]any{"current": map[string]any{"prompt": "test"}},
+		resp: func(t *testing.T, res *http.Response) {
+			expC := http.StatusOK
+
+			if res.StatusCode != expC {
+				t.Errorf("Status code expected: %v, got: %v",
+					expC, res.StatusCode)
+			}
+
+			var e *server.PromptEstimate
+
+			if err := json.NewDecoder(res.Body).Decode(&e); err != nil {
+				t.Errorf("Unexpected error decoding response: %v ", err)
+			}
+
+			if e == nil || e.InputTokens <= 0 || e.Cost <= 0 {
+				t.Errorf("Expected prompt estimate, got: %+v", e)
+			}
+		},
+	}, {
+		name:   "prompt game",
+		url:    "http://localhost:8080/api/v1/games/prompt",
+		method: http.MethodPost,
+		body:   map[string]any{"game_id": TestUUID, "prompt": "test"},
+		resp: func(t *testing.T, res *http.Response) {
+			expC := http.StatusCreated
+
+			if res.StatusCode != expC {
+				t.Errorf("Status code expected: %v, got: %v",
+					expC, res.StatusCode)
+			}
+
+			b, err := io.ReadAll(res.Body)
+			if err != nil {
+				t.Errorf("Unexpected response error: %v", err)
+			}
+
+			m := map[string]any{}
+
+			if err := json.Unmarshal(b, &m); err != nil {
+				t.Errorf("Unexpected error decoding response: %v ", err)
+			}
+
+			id, ok := m["game_id"].(string)
+			if !ok {
+				t.Errorf("Expected game_id in response: %v", m)
+			}
+
+			dataLock.Lock()
+			data["game_id"] = id
+			dataLock.Unlock()
+		},
+	}, {
+		name:   "prompt game invalid attachment",
+		url:    "http://localhost:8080/api/v1/games/{{id}}/prompt",
+		method: http.MethodPost,
+		header: map[string]string{"Content-Type": aw.FormDataContentType()},
+		body:   attach,
+		resp: func(t *testing.T, res *http.Response) {
+			expC := http.StatusBadRequest
+
+			if res.StatusCode != expC {
+				t.Errorf("Status code expected: %v, got: %v",
+					expC, res.StatusCode)
+			}
+		},
+	}, {
+		name:   "prompt game audio missing",
+		url:    "http://localhost:8080/api/v1/games/{{id}}/prompt/audio",
+		method: http.MethodPost,
+		header: map[string]string{"Content-Type": aw.FormDataContentType()},
+		body:   bytes.NewBufferString("--" + aw.Boundary() + "--\r\n"),
+		resp: func(t *testing.T, res *http.Response) {
+			expC := http.StatusBadRequest
+
+			if res.StatusCode != expC {
+				t.Errorf("Status code expected: %v, got: %v",
+					expC, res.StatusCode)
+			}
+		},
+	}, {
+		name:   "get game prompts",
+		url:    "http://localhost:8080/api/v1/games/{{id}}/prompts?size=10",
+		method: http.MethodGet,
+		resp: func(t *testing.T, res *http.Response) {
+			expC := http.StatusOK
+
+			if res.StatusCode != expC {
+				t.Errorf("Status code expected: %v, got: %v",
+					expC, res.StatusCode)
+			}
+
+			if res.Header.Get("X-Total-Count") == "" {
+				t.Errorf("Expected X-Total-Count header")
+			}
+
+			var h []*server.PromptHistory
+
+			if err := json.NewDecoder(res.Body).Decode(&h); err != nil {
+				t.Errorf("Unexpected error decoding response: %v ", err)
+			}
+
+			if len(h) > 10 {
+				t.Errorf("Expected at most 10 prompts, got: %v", len(h))
+			}
+		},
+	}, {
+		name:   "undo prompt",
+		url:    "http://localhost:8080/api/v1/games/undo",
+		method: http.MethodPost,
+		body:   map[string]any{"game_id": TestUUID},
+		resp: func(t *testing.T, res *http.Response) {
+			expC := http.StatusCreated
+
+			if res.StatusCode != expC {
+				t.Errorf("Status code expected: %v, got: %v",
+					expC, res.StatusCode)
+			}
+
+			b, err := io.ReadAll(res.Body)
+			if err != nil {
+				t.Errorf("Unexpected response error: %v", err)
+			}
+
+			m := map[string]any{}
+
+			if err := json.Unmarshal(b, &m); err != nil {
+				t.Errorf("Unexpected error decoding response: %v ", err)
+			}
+
+			if _, ok := m["game_id"].(string); !ok {
+				t.Errorf("Expected id in response: %v", m)
+			}
+		},
+	}, {
+		name:   "reset game prompts",
+		url:    "http://localhost:8080/api/v1/games/{{id}}/prompt/reset",
+		method: http.MethodPost,
+		body:   map[string]any{"summary": true, "text": "A test game."},
+		resp: func(t *testing.T, res *http.Response) {
+			expC := http.StatusCreated
+
+			if res.StatusCode != expC {
+				t.Errorf("Status code expected: %v, got: %v",
+					expC, res.StatusCode)
+			}
+
+			var p *server.Prompts
+
+			if err := json.NewDecoder(res.Body).Decode(&p); err != nil {
+				t.Errorf("Unexpected error decoding response: %v ", err)
+			}
+
+			if p == nil || p.GameID.Value == "" || len(p.History) != 1 {
+				t.Errorf("Expected reset prompts with summary, got: %+v", p)
+			}
+		},
+	}, {
+		name:   "upload game",
+		url:    "http://localhost:8080/api/v1/games/upload",
+		method: http.MethodPost,
+		header: map[string]string{"Content-Type": mw.FormDataContentType()},
+		body:   upload,
+		resp: func(t *testing.T, res *http.Response) {
+			expC := http.StatusCreated
+
+			if res.StatusCode != expC {
+				t.Errorf("Status code expected: %v, got: %v",
+					expC, res.StatusCode)
+			}
+
+			b, err := io.ReadAll(res.Body)
+			if err != nil {
+				t.Errorf("Unexpected response error: %v", err)
+			}
+
+			m := map[string]any{}
+
+			if err := json.Unmarshal(b, &m); err != nil {
+				t.Errorf("Unexpected error decoding response: %v", err)
+			}
+
+			img, ok := m["images"].(map[string]any)["test"].(map[string]any)
+			if !ok {
+				t.Errorf("Expected uploaded image in response: %v", m)
+			}
+
+			if _, ok := img["data"].(string); !ok {
+				t.Errorf("Expected uploaded image data in response: %v", img)
+			}
+
+			id, ok := m["id"].(string)
+			if !ok {
+				t.Errorf("Expected id in response: %v", m)
+			}
+
+			dataLock.Lock()
+			data["upload_id"] = id
+			dataLock.Unlock()
+		},
+	}, {
+		name:   "delete uploaded game",
+		url:    "http://localhost:8080/api/v1/games/{{upload_id}}",
+		method: http.MethodDelete,
+		resp: func(t *testing.T, res *http.Response) {
+			expC := http.StatusNoContent
+
+			if res.StatusCode != expC {
+				t.Errorf("Status code expected: %v, got: %v",
+					expC, res.StatusCode)
+			}
+		},
+	}, {
+		name:   "get import status",
+		url:    "http://localhost:8080/api/v1/games/import/status",
+		method: http.MethodGet,
+		resp: func(t *testing.T, res *http.Response) {
+			expC := http.StatusOK
+
+			if res.StatusCode != expC {
+				t.Errorf("Status code expected: %v, got: %v",
+					expC, res.StatusCode)
+			}
+
+			b, err := io.ReadAll(res.Body)
+			if err != nil {
+				t.Errorf("Unexpected response error: %v", err)
+			}
+
+			m := map[string]any{}
+
+			if err := json.Unmarshal(b, &m); err != nil {
+				t.Errorf("Unexpected error decoding response: %v", err)
+			}
+
+			if _, ok := m["files_total"].(float64); !ok {
+				t.Errorf("Expected files_total in response: %v", m)
+			}
+		},
+	}, {
+		name:   "import invalid game file",
+		url:    "http://localhost:8080/api/v1/games/import/games/invalid.yaml",
+		method: http.MethodPost,
+		resp: func(t *testing.T, res *http.Response) {
+			expC := http.StatusBadRequest
+
+			if res.StatusCode != expC {
+				t.Errorf("Status code expected: %v, got: %v",
+					expC, res.StatusCode)
+			}
+		},
+	}, {
+		name: "restore game missing time",
+		url: "http://localhost:8080/api/v1/games/" +
+			"11223344-5566-7788-9900-aabbccddeeff/restore",
+		method: http.MethodPost,
+		resp: func(t *testing.T, res *http.Response) {
+			expC := http.StatusBadRequest
+
+			if res.StatusCode != expC {
+				t.Errorf("Status code expected: %v, got: %v",
+					expC, res.StatusCode)
+			}
+		},
+	}, {
+		name: "game heartbeat invalid session",
+		url: "http://localhost:8080/api/v1/games/" +
+			"11223344-5566-7788-9900-aabbccddeeff/heartbeat",
+		method: http.MethodPost,
+		body:   map[string]any{"session_id": "invalid"},
+		resp: func(t *testing.T, res *http.Response) {
+			expC := http.StatusBadRequest
+
+			if res.StatusCode != expC {
+				t.Errorf("Status code expected: %v, got: %v",
+					expC, res.StatusCode)
+			}
+		},
+	}, {
+		name: "report game error missing error",
+		url: "http://localhost:8080/api/v1/games/" +
+			"11223344-5566-7788-9900-aabbccddeeff/errors",
+		method: http.MethodPost,
+		body:   map[string]any{"stack": "test"},
+		resp: func(t *testing.T, res *http.Response) {
+			expC := http.StatusBadRequest
+
+			if res.StatusCode != expC {
+				t.Errorf("Status code expected: %v, got: %v",
+					expC, res.StatusCode)
+			}
+		},
+	}, {
+		name: "game errors not found",
+		url: "http://localhost:8080/api/v1/games/" +
+			"11223344-5566-7788-9900-aabbccddeeff/errors",
+		method: http.MethodGet,
+		resp: func(t *testing.T, res *http.Response) {
+			expC := http.StatusNotFound
+
+			if res.StatusCode != expC {
+				t.Errorf("Status code expected: %v, got: %v",
+					expC, res.StatusCode)
+			}
+		},
+	}, {
+		name:   "games live players",
+		url:    "http://localhost:8080/api/v1/games/live",
+		method: http.MethodGet,
+		resp: func(t *testing.T, res *http.Response) {
+			expC := http.StatusOK
+
+			if res.StatusCode != expC {
+				t.Errorf("Status code expected: %v, got: %v",
+					expC, res.StatusCode)
+			}
+		},
+	}, {
+		name:   "bulk games",
+		url:    "http://localhost:8080/api/v1/games/bulk",
+		method: http.MethodPost,
+		header: map[string]string{"Content-Type": "application/x-ndjson"},
+		body:   bulk,
+		resp: func(t *testing.T, res *http.Response) {
+			expC := http.StatusOK
+
+			if res.StatusCode != expC {
+				t.Errorf("Status code expected: %v, got: %v",
+					expC, res.StatusCode)
+			}
+
+			exp := []struct {
+				line   int
+				status string
+			}{
+				{1, server.BulkStatusApplied},
+				{2, server.BulkStatusApplied},
+				{4, server.BulkStatusApplied},
+				{5, server.BulkStatusFailed},
+			}
+
+			dec := json.NewDecoder(res.Body)
+
+			for _, e := range exp {
+				var v server.BulkResult
+
+				if err := dec.Decode(&v); err != nil {
+					t.Fatalf("Unexpected error decoding response: %v", err)
+				}
+
+				if v.Line != e.line || v.Status != e.status {
+					t.Errorf("Expected line %v %v, got: %+v",
+						e.line, e.status, v)
+				}
+
+				if v.Status == server.BulkStatusFailed && v.Error == nil {
+					t.Errorf("Expected error for line %v", v.Line)
+				}
+			}
+		},
+	}, {
+		name:   "measure game size compressed",
+		url:    "http://localhost:8080/api/v1/games/size?compress=true",
+		method: http.MethodPost,
+		body: map[string]any{
+			"name": "Test Game",
+			"images": map[string]any{
+				"test": map[string]any{
+					"id":   "test",
+					"name": "test.svg",
+					"data": base64.StdEncoding.EncodeToString([]byte(
+						"<svg>\n  <!-- test -->\n  " +
+							"<rect x=\"1.23456\" y=\"2.34567\"/>\n</svg>")),
+				},
+			},
+		},
+		resp: func(t *testing.T, res *http.Response) {
+			expC := http.StatusOK
+
+			if res.StatusCode != expC {
+				t.Errorf("Status code expected: %v, got: %v",
+					expC, res.StatusCode)
+			}
+
+			b, err := io.ReadAll(res.Body)
+			if err != nil {
+				t.Errorf("Unexpected response error: %v", err)
+			}
+
+			var gs *server.GameSize
+
+			if err := json.Unmarshal(b, &gs); err != nil {
+				t.Fatalf("Unexpected error decoding response: %v", err)
+			}
+
+			if !gs.Fits || !gs.Compressed || gs.SavedBytes <= 0 {
+				t.Errorf("Expected compressed game size, got: %+v", gs)
+			}
+
+			if len(gs.LargestImages) != 1 ||
+				gs.LargestImages[0].ID != "test" {
+				t.Errorf("Expected largest images: [test], got: %+v",
+					gs.LargestImages)
+			}
+		},
+	}, {
+		name:   "copy game",
+		url:    "http://localhost:8080/api/v1/games/copy",
+		method: http.MethodPost,
+		body:   map[string]any{"id": TestUUID, "name": "test copy"},
+		resp: func(t *testing.T, res *http.Response) {
+			expC := http.StatusCreated
+
+			if res.StatusCode != expC {
+				t.Errorf("Status code expected: %v, got: %v",
+					expC, res.StatusCode)
+			}
+
+			b, err := io.ReadAll(res.Body)
+			if err != nil {
+				t.Errorf("Unexpected response error: %v", err)
+			}
+
+			m := map[string]any{}
+
+			if err := json.Unmarshal(b, &m); err != nil {
+				t.Errorf("Unexpected error decoding response: %v ", err)
+			}
+
+			id, ok := m["id"].(string)
+			if !ok {
+				t.Errorf("Expected id in response: %v", m)
+			}
+
+			dataLock.Lock()
+			data["copy_id"] = id
+			dataLock.Unlock()
+		},
+	}, {
+		name:   "delete game copy",
+		url:    "http://localhost:8080/api/v1/games/{{copy_id}}",
+		method: http.MethodDelete,
+		resp: func(t *testing.T, res *http.Response) {
+			expC := http.StatusNoContent
+
+			if res.StatusCode != expC {
+				t.Errorf("Status code expected: %v, got: %v",
+					expC, res.StatusCode)
+			}
+		},
+	}, {
+		name:   "delete game",
+		url:    "http://localhost:8080/api/v1/games/{{id}}",
+		method: http.MethodDelete,
+		resp: func(t *testing.T, res *http.Response) {
+			expC := http.StatusNoContent
+
+			if res.StatusCode != expC {
+				t.Errorf("Status code expected: %v, got: %v",
+					expC, res.StatusCode)
+			}
+		},
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if strings.Contains(tt.url, "{{id}}") {
+				dataLock.Lock()
+				gameID, _ := data["id"].(string)
+				dataLock.Unlock()
+
+				tt.url = strings.ReplaceAll(tt.url, "{{id}}",
+					gameID)
+			}
+
+			if strings.Contains(tt.url, "{{challenge_id}}") {
+				dataLock.Lock()
+				challengeID, _ := data["challenge_id"].(string)
+				dataLock.Unlock()
+
+				tt.url = strings.ReplaceAll(tt.url, "{{challenge_id}}",
+					challengeID)
+			}
+
+			if strings.Contains(tt.url, "{{automation_id}}") {
+				dataLock.Lock()
+				automationID, _ := data["automation_id"].(string)
+				dataLock.Unlock()
+
+				tt.url = strings.ReplaceAll(tt.url, "{{automation_id}}",
+					automationID)
+			}
+
+			if strings.Contains(tt.url, "{{copy_id}}") {
+				dataLock.Lock()
+				gameID, _ := data["copy_id"].(string)
+				dataLock.Unlock()
+
+				tt.url = strings.ReplaceAll(tt.url, "{{copy_id}}",
+					gameID)
+			}
+
+			if strings.Contains(tt.url, "{{upload_id}}") {
+				dataLock.Lock()
+				gameID, _ := data["upload_id"].(string)
+				dataLock.Unlock()
+
+				tt.url = strings.ReplaceAll(tt.url, "{{upload_id}}",
+					gameID)
+			}
+
+			buf := &bytes.Buffer{}
+
+			if bb, ok := tt.body.(*bytes.Buffer); ok {
+				buf = bb
+			} else if tt.body != nil {
+				if ct, ok := tt.header["Content-Type"]; ok {
+					if !strings.Contains("json", ct) {
+						if bm, ok := tt.body.(map[string]any); ok {
+							form := url.Values{}
+
+							for k, v := range bm {
+								switch vv := v.(type) {
+								case string:
+									form.Add(k, vv)
+								default:
+									b, err := json.Marshal(vv)
+									if err != nil {
+										t.Error(err)
+									}
+
+									form.Add(k, string(b))
+								}
+							}
+
+							buf = bytes.NewBufferString(form.Encode())
+						}
+					}
+				}
+
+				if buf.Len() == 0 {
+					var (
+						b   []byte
+						err error
+					)
+
+					if bm, ok := tt.body.(map[string]any); ok {
+						dataLock.Lock()
+						gID, _ := data["game_id"].(string)
+						dataLock.Unlock()
+
+						if gID != "" {
+							bm["game_id"] = gID
+						}
+
+						b, err = json.Marshal(bm)
+						if err != nil {
 							t.Error(err)
 						}
 					} else {
@@ -581,7 +2088,7 @@ func TestGamesServer(t *testing.T) {
 				br = buf
 			}
 
-			r, err := http.NewRequest(tt.method, tt.url, br)
+			r, err := http.NewRequest(tt.method, testURL(tt.url), br)
 			if err != nil {
 				t.Fatal("Failed to initialize request", err)
 			}
@@ -615,3 +2122,334 @@ func TestGamesServer(t *testing.T) {
 		})
 	}
 }
+
+// benchGame returns a large game, with about a megabyte of image data, for use
+// by benchmarks.
+func benchGame() *server.Game {
+	g := TestGame
+
+	images, objects := map[string]any{}, map[string]any{}
+
+	for i := range 16 {
+		id := "image" + strconv.Itoa(i)
+
+		images[id] = map[string]any{
+			"id":   id,
+			"name": id + ".png",
+			"w":    int64(64),
+			"h":    int64(64),
+			"data": base64.StdEncoding.EncodeToString(
+				bytes.Repeat([]byte{byte(i)}, 48*1024)),
+		}
+	}
+
+	for i := range 256 {
+		id := "object" + strconv.Itoa(i)
+
+		objects[id] = map[string]any{
+			"id":     id,
+			"name":   id,
+			"x":      int64(i),
+			"y":      int64(i * 2),
+			"w":      int64(16),
+			"h":      int64(16),
+			"image":  "image" + strconv.Itoa(i%16),
+			"hidden": false,
+			"data":   map[string]any{"score": int64(i)},
+		}
+	}
+
+	g.W = request.FieldInt64{Set: true, Valid: true, Value: 640}
+	g.H = request.FieldInt64{Set: true, Valid: true, Value: 480}
+	g.Images = request.FieldJSON{Set: true, Valid: true, Value: images}
+	g.Objects = request.FieldJSON{Set: true, Valid: true, Value: objects}
+	g.Script = request.FieldString{
+		Set: true, Valid: true,
+		Value: base64.StdEncoding.EncodeToString(
+			[]byte(strings.Repeat("function Update(game)\nreturn game\nend\n",
+				1024))),
+	}
+	g.Tags = request.FieldStringArray{
+		Set: true, Valid: true,
+		Value: []string{"test", "bench"},
+	}
+
+	return &g
+}
+
+func BenchmarkGameDecodeBSON(b *testing.B) {
+	g := benchGame()
+
+	doc := &bson.D{}
+
+	request.SetField(doc, "id", g.ID)
+	request.SetField(doc, "name", g.Name)
+	request.SetField(doc, "version", g.Version)
+	request.SetField(doc, "description", g.Description)
+	request.SetField(doc, "w", g.W)
+	request.SetField(doc, "h", g.H)
+	request.SetField(doc, "status", g.Status)
+	request.SetField(doc, "status_data", g.StatusData)
+	request.SetField(doc, "objects", g.Objects)
+	request.SetField(doc, "images", g.Images)
+	request.SetField(doc, "script", g.Script)
+	request.SetField(doc, "tags", g.Tags)
+	request.SetField(doc, "created_at", g.CreatedAt)
+	request.SetField(doc, "created_by", g.CreatedBy)
+	request.SetField(doc, "updated_at", g.UpdatedAt)
+	request.SetField(doc, "updated_by", g.UpdatedBy)
+
+	buf, err := bson.Marshal(doc)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.SetBytes(int64(len(buf)))
+	b.ReportAllocs()
+
+	for b.Loop() {
+		var g server.Game
+
+		if err := bson.Unmarshal(buf, &g); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkGameCache(b *testing.B) {
+	g := benchGame()
+
+	b.ReportAllocs()
+
+	for b.Loop() {
+		buf, err := json.Marshal(g)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		var cg server.Game
+
+		if err := json.Unmarshal(buf, &cg); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkGameEncode(b *testing.B) {
+	g := benchGame()
+
+	for _, bb := range []struct {
+		name string
+		enc  func(w io.Writer, v any) error
+	}{{
+		name: "json",
+		enc: func(w io.Writer, v any) error {
+			return json.NewEncoder(w).Encode(v)
+		},
+	}, {
+		name: "cbor",
+		enc: func(w io.Writer, v any) error {
+			return cbor.NewEncoder(w).Encode(v)
+		},
+	}} {
+		b.Run(bb.name, func(b *testing.B) {
+			b.ReportAllocs()
+
+			for b.Loop() {
+				if err := bb.enc(io.Discard, g); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// testRepo values are repositories containing a single game file, which count
+// the requests for the file, and the downloads of its contents.
+type testRepo struct {
+	sync.Mutex
+	commit    string
+	hash      string
+	data      string
+	requests  int
+	downloads int
+}
+
+const testRepoGameID = "aabbccdd-5566-7788-9900-aabbccddeeff"
+
+func (r *testRepo) update(commit, hash, data string) {
+	r.Lock()
+	defer r.Unlock()
+
+	r.commit, r.hash, r.data = commit, hash, data
+}
+
+func (r *testRepo) counts() (int, int) {
+	r.Lock()
+	defer r.Unlock()
+
+	return r.requests, r.downloads
+}
+
+func (r *testRepo) tag() string {
+	return fmt.Sprintf(`"%x"`, sha256.Sum256([]byte(r.data)))
+}
+
+func (r *testRepo) List(ctx context.Context, dirPath string,
+) ([]repo.Item, error) {
+	return r.ListAll(ctx, dirPath)
+}
+
+func (r *testRepo) ListAll(ctx context.Context, dirPath string,
+) ([]repo.Item, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	return []repo.Item{{
+		Path:   "games/" + testRepoGameID + ".yaml",
+		Type:   "file",
+		Commit: r.commit,
+		Hash:   r.hash,
+	}}, nil
+}
+
+func (r *testRepo) Get(ctx context.Context, filePath string) ([]byte, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	r.requests++
+	r.downloads++
+
+	return []byte(r.data), nil
+}
+
+func (r *testRepo) GetIfModified(ctx context.Context,
+	filePath, tag string,
+) ([]byte, string, bool, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	r.requests++
+
+	if tag == r.tag() {
+		return nil, tag, false, nil
+	}
+
+	r.downloads++
+
+	return []byte(r.data), r.tag(), true, nil
+}
+
+func (r *testRepo) Commit(ctx context.Context) (string, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	return r.commit, nil
+}
+
+func TestGameImportUnchanged(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration tests")
+	}
+
+	ts := servertest.New(t, nil)
+
+	tr := &testRepo{}
+
+	ts.API.SetRepoClient(tr)
+
+	tests := []struct {
+		name      string
+		commit    string
+		hash      string
+		data      string
+		requests  int
+		downloads int
+	}{{
+		name:      "new file",
+		commit:    "c1",
+		hash:      "h1",
+		data:      "name: Import Game\n",
+		requests:  1,
+		downloads: 1,
+	}, {
+		name:      "unchanged file hash",
+		commit:    "c2",
+		hash:      "h1",
+		data:      "name: Import Game\n",
+		requests:  1,
+		downloads: 1,
+	}, {
+		name:      "unchanged file contents",
+		commit:    "c3",
+		hash:      "h2",
+		data:      "name: Import Game\n",
+		requests:  2,
+		downloads: 1,
+	}, {
+		name:      "changed file",
+		commit:    "c4",
+		hash:      "h3",
+		data:      "name: Import Game 2\n",
+		requests:  3,
+		downloads: 2,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr.update(tt.commit, tt.hash, tt.data)
+
+			r, err := http.NewRequest(http.MethodPost,
+				ts.Path("/games/import"), nil)
+			if err != nil {
+				t.Fatal("Failed to initialize request", err)
+			}
+
+			r.Header.Set("Authorization", "Bearer "+ts.Token)
+
+			res, err := ts.Client().Do(r)
+			if err != nil {
+				t.Fatalf("Unexpected client error: %v", err)
+			}
+
+			res.Body.Close()
+
+			if res.StatusCode != http.StatusNoContent {
+				t.Errorf("Status code expected: %v, got: %v",
+					http.StatusNoContent, res.StatusCode)
+			}
+
+			requests, downloads := tr.counts()
+
+			if requests != tt.requests {
+				t.Errorf("Requests expected: %v, got: %v",
+					tt.requests, requests)
+			}
+
+			if downloads != tt.downloads {
+				t.Errorf("Downloads expected: %v, got: %v",
+					tt.downloads, downloads)
+			}
+
+			r, err = http.NewRequest(http.MethodGet,
+				ts.Path("/games/"+testRepoGameID), nil)
+			if err != nil {
+				t.Fatal("Failed to initialize request", err)
+			}
+
+			r.Header.Set("Authorization", "Bearer "+ts.Token)
+
+			res, err = ts.Client().Do(r)
+			if err != nil {
+				t.Fatalf("Unexpected client error: %v", err)
+			}
+
+			defer res.Body.Close()
+
+			if res.StatusCode != http.StatusOK {
+				t.Errorf("Status code expected: %v, got: %v",
+					http.StatusOK, res.StatusCode)
+			}
+		})
+	}
+}
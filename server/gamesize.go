@@ -0,0 +1,268 @@
+package server
+
+import (
+	"cmp"
+	"context"
+	"encoding/base64"
+	"math"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/dhaifley/game2d/errors"
+	"github.com/dhaifley/game2d/request"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+const (
+	// maxLargestImages is the maximum number of images suggested for removal
+	// or compression when a game is too large.
+	maxLargestImages = 5
+
+	// svgPrecision is the number of decimal places kept for numbers in
+	// compressed SVG images.
+	svgPrecision = 2
+)
+
+var (
+	svgComment    = regexp.MustCompile(`(?s)<!--.*?-->`)
+	svgLineBreak  = regexp.MustCompile(`>\s*\n\s*<`)
+	svgWhitespace = regexp.MustCompile(`\s+`)
+	svgNumber     = regexp.MustCompile(`-?\d*\.\d+`)
+)
+
+// GameSizeImage values describe the size of an image of a game.
+type GameSizeImage struct {
+	ID    string `json:"id"    yaml:"id"`
+	Name  string `json:"name"  yaml:"name"`
+	Bytes int64  `json:"bytes" yaml:"bytes"`
+}
+
+// GameSize values contain the result of measuring the size of a game before
+// it is saved.
+type GameSize struct {
+	Size          int64             `json:"size"             yaml:"size"`
+	SizeLimit     int64             `json:"size_limit"       yaml:"size_limit"`
+	Fits          bool              `json:"fits"             yaml:"fits"`
+	Compressed    bool              `json:"compressed"       yaml:"compressed"`
+	SavedBytes    int64             `json:"saved_bytes"      yaml:"saved_bytes"`
+	LargestImages []*GameSizeImage  `json:"largest_images"   yaml:"largest_images"`
+	Images        request.FieldJSON `json:"images,omitempty" yaml:"images,omitempty"`
+}
+
+// compressSVG reduces the size of an SVG document by removing comments and
+// redundant whitespace, and by reducing the precision of numbers.
+func compressSVG(svg string) string {
+	svg = svgComment.ReplaceAllString(svg, "")
+	svg = svgLineBreak.ReplaceAllString(svg, "><")
+	svg = svgWhitespace.ReplaceAllString(svg, " ")
+
+	p := math.Pow(10, svgPrecision)
+
+	svg = svgNumber.ReplaceAllStringFunc(svg, func(n string) string {
+		v, err := strconv.ParseFloat(n, 64)
+		if err != nil {
+			return n
+		}
+
+		r := strconv.FormatFloat(math.Round(v*p)/p, 'f', -1, 64)
+		if r == "-0" {
+			r = "0"
+		}
+
+		if len(r) >= len(n) {
+			return n
+		}
+
+		return r
+	})
+
+	return strings.TrimSpace(svg)
+}
+
+// compressImages returns a copy of a set of game images in which the SVG
+// images are compressed, and the number of bytes saved.
+func compressImages(images request.FieldJSON) (request.FieldJSON, int64) {
+	if !images.Set || !images.Valid || len(images.Value) == 0 {
+		return images, 0
+	}
+
+	res := images.Copy()
+
+	var saved int64
+
+	for id, v := range res.Value {
+		img, ok := assetImage(v)
+		if !ok {
+			continue
+		}
+
+		data, ok := img[assetDataKey].(string)
+		if !ok || data == "" {
+			continue
+		}
+
+		b, err := base64.StdEncoding.DecodeString(data)
+		if err != nil || !strings.Contains(string(b), "<svg") {
+			continue
+		}
+
+		c := base64.StdEncoding.EncodeToString([]byte(compressSVG(string(b))))
+		if len(c) >= len(data) {
+			continue
+		}
+
+		saved += int64(len(data) - len(c))
+
+		img[assetDataKey] = c
+
+		res.Value[id] = img
+	}
+
+	return res, saved
+}
+
+// largestImages returns the largest images of a game, largest first.
+func largestImages(g *Game) []*GameSizeImage {
+	res := []*GameSizeImage{}
+
+	for id, v := range g.Images.Value {
+		img, ok := assetImage(v)
+		if !ok {
+			continue
+		}
+
+		data, _ := img[assetDataKey].(string)
+		name, _ := img["name"].(string)
+
+		res = append(res, &GameSizeImage{
+			ID:    id,
+			Name:  name,
+			Bytes: int64(len(data)),
+		})
+	}
+
+	slices.SortFunc(res, func(a, b *GameSizeImage) int {
+		if c := cmp.Compare(b.Bytes, a.Bytes); c != 0 {
+			return c
+		}
+
+		return strings.Compare(a.ID, b.ID)
+	})
+
+	if len(res) > maxLargestImages {
+		res = res[:maxLargestImages]
+	}
+
+	return res
+}
+
+// gameSize measures the encoded size of a game.
+func gameSize(g *Game) (*GameSize, error) {
+	b, err := bson.Marshal(g)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrInvalidRequest,
+			"unable to encode game to measure size",
+			"game_id", g.ID.Value)
+	}
+
+	return &GameSize{
+		Size:          int64(len(b)),
+		SizeLimit:     maxGameSize,
+		Fits:          len(b) <= maxGameSize,
+		LargestImages: largestImages(g),
+	}, nil
+}
+
+// checkGameSize returns an error, which identifies the largest images of the
+// game, if a game is too large to be saved.
+func checkGameSize(g *Game) error {
+	gs, err := gameSize(g)
+	if err != nil {
+		return err
+	}
+
+	if gs.Fits {
+		return nil
+	}
+
+	ids := make([]string, 0, len(gs.LargestImages))
+
+	for _, img := range gs.LargestImages {
+		ids = append(ids, img.ID)
+	}
+
+	return errors.New(errors.ErrTooLarge,
+		"game data exceeds 16MB size limit",
+		"game_id", g.ID.Value,
+		"size", gs.Size,
+		"largest_images", ids).WithReason(errors.ReasonGameTooLarge)
+}
+
+// prepareGameSize compresses the images of a game, if requested, and checks
+// that it is not too large to be saved.
+func prepareGameSize(ctx context.Context, g *Game) error {
+	if v := ctx.Value(CtxKeyGameCompress); v != nil {
+		g.Images, _ = compressImages(g.Images)
+	}
+
+	return checkGameSize(g)
+}
+
+// postGameSizeHandler is the post handler used to measure the size of a game
+// before it is saved.
+func (s *Server) postGameSizeHandler(w http.ResponseWriter,
+	r *http.Request,
+) {
+	ctx := r.Context()
+
+	if err := s.checkScope(ctx, request.ScopeGamesWrite); err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	req := &Game{}
+
+	if err := s.decode(r, &req); err != nil {
+		switch e := err.(type) {
+		case *errors.Error:
+			s.error(e, w, r)
+		default:
+			s.error(errors.Wrap(err, errors.ErrInvalidRequest,
+				"unable to decode request"), w, r)
+		}
+
+		return
+	}
+
+	var saved int64
+
+	compress := false
+
+	if qp := r.URL.Query().Get("compress"); qp != "" && qp != "0" &&
+		!strings.EqualFold(qp, "false") && !strings.EqualFold(qp, "f") {
+		compress = true
+
+		req.Images, saved = compressImages(req.Images)
+	}
+
+	res, err := gameSize(req)
+	if err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	if compress {
+		res.Compressed = true
+		res.SavedBytes = saved
+		res.Images = req.Images
+	}
+
+	if err := s.encode(w, r, res); err != nil {
+		s.error(err, w, r)
+	}
+}
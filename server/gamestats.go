@@ -0,0 +1,150 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/dhaifley/game2d/cache"
+	"github.com/dhaifley/game2d/errors"
+	"github.com/dhaifley/game2d/request"
+	"github.com/dhaifley/game2d/static"
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	// GameSchemaVersion is the version of the game definition schema used by
+	// the server. It is incremented when the schema changes incompatibly.
+	GameSchemaVersion = 1
+
+	// maxGameSize is the maximum size of a stored game in bytes.
+	maxGameSize = 16 * 1024 * 1024
+
+	// promptBytesPerToken is the approximate number of bytes of game JSON in
+	// each token of a prompt, used to estimate prompt token costs.
+	promptBytesPerToken = 4
+)
+
+// GameStats values contain statistics derived from a game definition.
+type GameStats struct {
+	GameID        string `json:"game_id"        yaml:"game_id"`
+	Revision      int64  `json:"revision"       yaml:"revision"`
+	SchemaVersion int64  `json:"schema_version" yaml:"schema_version"`
+	Objects       int64  `json:"objects"        yaml:"objects"`
+	Images        int64  `json:"images"         yaml:"images"`
+	ImageBytes    int64  `json:"image_bytes"    yaml:"image_bytes"`
+	ScriptBytes   int64  `json:"script_bytes"   yaml:"script_bytes"`
+	Size          int64  `json:"size"           yaml:"size"`
+	SizeLimit     int64  `json:"size_limit"     yaml:"size_limit"`
+	PromptTokens  int64  `json:"prompt_tokens"  yaml:"prompt_tokens"`
+}
+
+// gameStats computes the statistics of a game.
+func gameStats(g *Game) (*GameStats, error) {
+	res := &GameStats{
+		GameID:        g.ID.Value,
+		Revision:      g.Revision.Value,
+		SchemaVersion: GameSchemaVersion,
+		Objects:       int64(len(g.Objects.Value)),
+		Images:        int64(len(g.Images.Value)),
+		ScriptBytes:   int64(len(g.Script.Value)),
+		SizeLimit:     maxGameSize,
+	}
+
+	for _, v := range g.Images.Value {
+		if img, ok := assetImage(v); ok {
+			if data, ok := img[assetDataKey].(string); ok {
+				res.ImageBytes += int64(len(data))
+			}
+		}
+	}
+
+	b, err := json.Marshal(g)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrServer,
+			"unable to encode game for stats",
+			"game_id", g.ID.Value)
+	}
+
+	res.Size = int64(len(b))
+
+	// Prompts include the game, without its prompt history, the game
+	// definition schema and the Lua API description.
+	pg := *g
+
+	pg.Prompts = request.FieldJSON{}
+
+	if b, err = json.Marshal(&pg); err != nil {
+		return nil, errors.Wrap(err, errors.ErrServer,
+			"unable to encode game for prompt stats",
+			"game_id", g.ID.Value)
+	}
+
+	sb, err := static.FS.ReadFile("game.json")
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrServer,
+			"unable to read game JSON schema source",
+			"file", "game.json")
+	}
+
+	lb, err := luaAPIDocument()
+	if err != nil {
+		return nil, err
+	}
+
+	res.PromptTokens = int64(len(b)+len(sb)+len(lb)) / promptBytesPerToken
+
+	return res, nil
+}
+
+// getGameStats retrieves the statistics of a game by ID. Statistics are cached
+// until the game is updated.
+func (s *Server) getGameStats(ctx context.Context,
+	id string,
+) (*GameStats, error) {
+	g, err := s.getGame(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	var res *GameStats
+
+	s.getCache(ctx, cache.KeyGameStats(id), &res)
+
+	if res != nil && res.Revision == g.Revision.Value {
+		return res, nil
+	}
+
+	if res, err = gameStats(g); err != nil {
+		return nil, err
+	}
+
+	s.setCache(ctx, cache.KeyGameStats(id), res)
+
+	return res, nil
+}
+
+// getGameStatsHandler is the get handler used to retrieve the statistics of a
+// game.
+func (s *Server) getGameStatsHandler(w http.ResponseWriter,
+	r *http.Request,
+) {
+	ctx := r.Context()
+
+	if err := s.checkScope(ctx, request.ScopeGamesRead); err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	res, err := s.getGameStats(ctx, chi.URLParam(r, "id"))
+	if err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	if err := s.encode(w, r, res); err != nil {
+		s.error(err, w, r)
+	}
+}
@@ -0,0 +1,275 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/dhaifley/game2d/errors"
+	"github.com/dhaifley/game2d/logger"
+	"github.com/dhaifley/game2d/request"
+	"github.com/go-chi/chi/v5"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// gameStatusTransitions contains the statuses to which a game in each status
+// may change. Creating a game sets its initial status, which may be any of
+// these statuses, and a game may always keep its current status.
+var gameStatusTransitions = map[string][]string{
+	request.StatusNew: {
+		request.StatusActive, request.StatusUpdating,
+		request.StatusImporting, request.StatusError, request.StatusInactive,
+	},
+	request.StatusActive: {
+		request.StatusUpdating, request.StatusImporting,
+		request.StatusError, request.StatusInactive,
+	},
+	request.StatusUpdating: {
+		request.StatusActive, request.StatusError, request.StatusInactive,
+	},
+	request.StatusImporting: {
+		request.StatusActive, request.StatusError, request.StatusInactive,
+	},
+	request.StatusError: {
+		request.StatusActive, request.StatusUpdating,
+		request.StatusImporting, request.StatusInactive,
+	},
+	request.StatusInactive: {
+		request.StatusActive, request.StatusImporting,
+	},
+}
+
+// GameStatusChange values request a change to the status of a game.
+type GameStatusChange struct {
+	Status     request.FieldString `json:"status"      yaml:"status"`
+	StatusData request.FieldJSON   `json:"status_data" yaml:"status_data"`
+}
+
+// GameStatusEvent values describe a change to the status of a game.
+type GameStatusEvent struct {
+	AccountID string `json:"account_id" yaml:"account_id"`
+	GameID    string `json:"game_id"    yaml:"game_id"`
+	UserID    string `json:"user_id"    yaml:"user_id"`
+	From      string `json:"from"       yaml:"from"`
+	To        string `json:"to"         yaml:"to"`
+	CreatedAt int64  `json:"created_at" yaml:"created_at"`
+}
+
+// GameStatusHook functions are called after the status of a game changes.
+type GameStatusHook func(ctx context.Context, ev *GameStatusEvent)
+
+// validGameStatus checks whether a string is a valid game status.
+func validGameStatus(status string) bool {
+	_, ok := gameStatusTransitions[status]
+
+	return ok
+}
+
+// checkGameStatusTransition returns an error if a game is not allowed to
+// change from one status to another.
+func checkGameStatusTransition(id, from, to string) error {
+	if from == "" || from == to ||
+		slices.Contains(gameStatusTransitions[from], to) {
+		return nil
+	}
+
+	return errors.New(errors.ErrConflict,
+		"invalid game status transition",
+		"id", id,
+		"from", from,
+		"to", to).
+		WithReason(errors.ReasonStatusTransition)
+}
+
+// AddGameStatusHook adds a hook which is called after the status of any game
+// changes.
+func (s *Server) AddGameStatusHook(h GameStatusHook) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.statusHooks = append(s.statusHooks, h)
+}
+
+// getGameState retrieves the current status, visibility and read only state of
+// a game directly from the database, so that they are never stale.
+func (s *Server) getGameState(ctx context.Context,
+	accountID, id string,
+) (*Game, error) {
+	var res *Game
+
+	f := bson.M{"account_id": accountID, "id": id}
+
+	c, err := s.collection(ctx, "games")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.FindOne(ctx, f,
+		options.FindOne().SetProjection(bson.M{
+			"_id": 0, "status": 1, "public": 1, "read_only": 1,
+		})).Decode(&res); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, errors.New(errors.ErrNotFound,
+				"game not found",
+				"id", id)
+		}
+
+		return nil, errors.Wrap(err, errors.ErrDatabase,
+			"unable to get game state",
+			"id", id)
+	}
+
+	return res, nil
+}
+
+// gameStatusChanged records a change to the status of a game in the account
+// activity, posts it to the status webhook, and calls the status hooks. It is
+// called after the change is saved, so failures never cause it to fail.
+func (s *Server) gameStatusChanged(ctx context.Context, g *Game, from string) {
+	uID, _ := request.ContextUserID(ctx)
+
+	ev := &GameStatusEvent{
+		AccountID: g.AccountID.Value,
+		GameID:    g.ID.Value,
+		UserID:    uID,
+		From:      from,
+		To:        g.Status.Value,
+		CreatedAt: time.Now().Unix(),
+	}
+
+	s.recordActivity(ctx, ActivityGameStatus, ev.GameID,
+		map[string]any{"from": ev.From, "to": ev.To})
+
+	if s.cfg.ServerStatusWebhook() != "" {
+		wctx, cancel := s.detachedContext(ctx, opOutbound)
+
+		s.jobs.Add(1)
+
+		go func() {
+			defer s.jobs.Done()
+			defer cancel()
+
+			s.postStatusWebhook(wctx, ev)
+		}()
+	}
+
+	s.RLock()
+
+	hooks := slices.Clone(s.statusHooks)
+
+	s.RUnlock()
+
+	for _, h := range hooks {
+		h(ctx, ev)
+	}
+}
+
+// postStatusWebhook posts a game status change to the status webhook.
+func (s *Server) postStatusWebhook(ctx context.Context, ev *GameStatusEvent) {
+
+	b, err := json.Marshal(ev)
+	if err != nil {
+		s.log.Log(ctx, logger.LvlWarn,
+			"unable to encode game status webhook",
+			"error", err,
+			"game_id", ev.GameID)
+
+		return
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		s.cfg.ServerStatusWebhook(), bytes.NewReader(b))
+	if err != nil {
+		s.log.Log(ctx, logger.LvlWarn,
+			"unable to create game status webhook request",
+			"error", err,
+			"game_id", ev.GameID)
+
+		return
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	cli := &http.Client{Timeout: s.cfg.ServerOutboundTimeout()}
+
+	res, err := cli.Do(req)
+	if err != nil {
+		s.log.Log(ctx, logger.LvlWarn,
+			"unable to send game status webhook",
+			"error", err,
+			"game_id", ev.GameID)
+
+		return
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		s.log.Log(ctx, logger.LvlWarn,
+			"unexpected game status webhook response status",
+			"status", res.StatusCode,
+			"game_id", ev.GameID)
+	}
+}
+
+// setGameStatus changes the status of a game by ID.
+func (s *Server) setGameStatus(ctx context.Context,
+	id string,
+	req *GameStatusChange,
+) (*Game, error) {
+	if !req.Status.Valid || !validGameStatus(req.Status.Value) {
+		return nil, errors.New(errors.ErrInvalidRequest,
+			"invalid status",
+			"id", id,
+			"status", req.Status.Value)
+	}
+
+	return s.updateGame(ctx, &Game{
+		ID: request.FieldString{
+			Set: true, Valid: true, Value: id,
+		},
+		Status:     req.Status,
+		StatusData: req.StatusData,
+	})
+}
+
+// postGameStatusHandler is the post handler used to change the status of a
+// game.
+func (s *Server) postGameStatusHandler(w http.ResponseWriter,
+	r *http.Request,
+) {
+	ctx := r.Context()
+
+	if err := s.checkScope(ctx, request.ScopeGamesWrite); err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+
+	req := &GameStatusChange{}
+
+	if err := s.decode(r, &req); err != nil {
+		s.error(errors.Wrap(err, errors.ErrInvalidRequest,
+			"unable to decode request"), w, r)
+
+		return
+	}
+
+	res, err := s.setGameStatus(ctx, id, req)
+	if err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	if err := s.encode(w, r, res); err != nil {
+		s.error(err, w, r)
+	}
+}
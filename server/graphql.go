@@ -0,0 +1,393 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/dhaifley/game2d/errors"
+	"github.com/dhaifley/game2d/graphql"
+	"github.com/dhaifley/game2d/request"
+	"github.com/go-chi/chi/v5"
+)
+
+// GraphQL query limits.
+const (
+	// maxGraphQLGames is the maximum number of games listed by a games field.
+	maxGraphQLGames = 100
+
+	// defaultGraphQLVersions is the number of versions listed by a versions
+	// field, unless a limit is requested.
+	defaultGraphQLVersions = 10
+)
+
+// GraphQLRequest values are the requests made to the GraphQL endpoint.
+type GraphQLRequest struct {
+	Query         string         `json:"query"                   yaml:"query"`
+	OperationName string         `json:"operationName,omitempty" yaml:"operationName,omitempty"`
+	Variables     map[string]any `json:"variables,omitempty"     yaml:"variables,omitempty"`
+}
+
+// gqlStructFields creates the scalar fields of a GraphQL object type from the
+// fields of a struct, named by their JSON names. Fields which are excluded, or
+// which are not request fields, are skipped.
+func gqlStructFields(v any, exclude ...string) graphql.Object {
+	res := graphql.Object{}
+
+	t := reflect.TypeOf(v)
+
+	for i := range t.NumField() {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name == "" || name == "-" || slices.Contains(exclude, name) {
+			continue
+		}
+
+		var typ string
+
+		switch reflect.Zero(t.Field(i).Type).Interface().(type) {
+		case request.FieldString:
+			typ = "String"
+		case request.FieldInt64:
+			typ = "Int"
+		case request.FieldBool:
+			typ = "Boolean"
+		case request.FieldTime:
+			typ = "Time"
+		case request.FieldJSON:
+			typ = "JSON"
+		case request.FieldStringArray:
+			typ = "[String]"
+		default:
+			continue
+		}
+
+		idx := i
+
+		res[name] = &graphql.Field{Type: typ, Resolve: func(ctx context.Context,
+			src any,
+			args map[string]any,
+		) (any, error) {
+			// Request fields only encode their values through pointers.
+			return reflect.ValueOf(src).Elem().Field(idx).Addr().Interface(),
+				nil
+		}}
+	}
+
+	return res
+}
+
+// graphqlSchema creates the schema of the GraphQL endpoint. Every field is
+// resolved using the same server methods as the rest of the API, and root
+// fields require the same scopes.
+func (s *Server) graphqlSchema() *graphql.Schema {
+	game := gqlStructFields(Game{}, "subject", "objects", "images",
+		"bindings", "prompts")
+
+	game["prompts"] = &graphql.Field{Type: "Prompts",
+		Resolve: func(ctx context.Context,
+			src any,
+			args map[string]any,
+		) (any, error) {
+			return s.gamePrompts(ctx, src.(*Game))
+		}}
+
+	game["previous"] = &graphql.Field{Type: "Game",
+		Resolve: func(ctx context.Context,
+			src any,
+			args map[string]any,
+		) (any, error) {
+			g := src.(*Game)
+
+			if g.PreviousID.Value == "" {
+				return nil, nil
+			}
+
+			return s.gqlGame(ctx, g.PreviousID.Value)
+		}}
+
+	game["versions"] = &graphql.Field{Type: "Game",
+		Resolve: func(ctx context.Context,
+			src any,
+			args map[string]any,
+		) (any, error) {
+			limit, err := graphql.ArgInt(args, "limit",
+				defaultGraphQLVersions)
+			if err != nil {
+				return nil, err
+			}
+
+			if limit < 0 || limit > maxRestoreVersions {
+				return nil, errors.New(errors.ErrInvalidParameter,
+					"invalid versions limit",
+					"limit", limit,
+					"max", maxRestoreVersions)
+			}
+
+			return s.gqlGameVersions(ctx, src.(*Game), int(limit))
+		}}
+
+	prompts := gqlStructFields(Prompts{}, "current", "history")
+
+	prompts["current"] = &graphql.Field{Type: "Prompt",
+		Resolve: func(ctx context.Context,
+			src any,
+			args map[string]any,
+		) (any, error) {
+			return &src.(*Prompts).Current, nil
+		}}
+
+	prompts["history"] = &graphql.Field{Type: "Prompt",
+		Resolve: func(ctx context.Context,
+			src any,
+			args map[string]any,
+		) (any, error) {
+			res := []*Prompt{}
+
+			for i := range src.(*Prompts).History {
+				res = append(res, &src.(*Prompts).History[i])
+			}
+
+			return res, nil
+		}}
+
+	query := graphql.Object{
+		"game": {Type: "Game", Resolve: func(ctx context.Context,
+			src any,
+			args map[string]any,
+		) (any, error) {
+			if err := s.checkScope(ctx, request.ScopeGamesRead); err != nil {
+				return nil, err
+			}
+
+			id, err := graphql.ArgString(args, "id")
+			if err != nil {
+				return nil, err
+			}
+
+			return s.gqlGame(ctx, id)
+		}},
+		"games": {Type: "Game", Resolve: func(ctx context.Context,
+			src any,
+			args map[string]any,
+		) (any, error) {
+			if err := s.checkScope(ctx, request.ScopeGamesRead); err != nil {
+				return nil, err
+			}
+
+			return s.gqlGames(ctx, args)
+		}},
+		"tags": {Type: "[String]", Resolve: func(ctx context.Context,
+			src any,
+			args map[string]any,
+		) (any, error) {
+			if err := s.checkScope(ctx, request.ScopeGamesRead); err != nil {
+				return nil, err
+			}
+
+			return s.getAllGameTags(ctx)
+		}},
+		"account": {Type: "Account", Resolve: func(ctx context.Context,
+			src any,
+			args map[string]any,
+		) (any, error) {
+			if err := s.checkScope(ctx, request.ScopeAccountRead); err != nil {
+				return nil, err
+			}
+
+			return s.getAccount(ctx, "")
+		}},
+		"user": {Type: "User", Resolve: func(ctx context.Context,
+			src any,
+			args map[string]any,
+		) (any, error) {
+			if err := s.checkScope(ctx, request.ScopeUserRead); err != nil {
+				return nil, err
+			}
+
+			id, err := graphql.ArgString(args, "id")
+			if err != nil {
+				return nil, err
+			}
+
+			return s.getUser(ctx, id)
+		}},
+	}
+
+	return &graphql.Schema{
+		Query: "Query",
+		Types: map[string]graphql.Object{
+			"Query":   query,
+			"Game":    game,
+			"Prompts": prompts,
+			"Prompt":  gqlStructFields(Prompt{}),
+			"Account": gqlStructFields(Account{}, "secret", "ai_api_key",
+				"billing_customer"),
+			"User": gqlStructFields(User{}, "password"),
+		},
+	}
+}
+
+// gqlGame retrieves a game for a GraphQL query, without its asset data.
+func (s *Server) gqlGame(ctx context.Context, id string) (*Game, error) {
+	return s.getGame(context.WithValue(ctx, CtxKeyGameMinData, true), id)
+}
+
+// gqlGames retrieves the games listed by a GraphQL query.
+func (s *Server) gqlGames(ctx context.Context,
+	args map[string]any,
+) ([]*Game, error) {
+	query := request.NewQuery()
+
+	var err error
+
+	if query.Search, err = graphql.ArgString(args, "search"); err != nil {
+		return nil, err
+	}
+
+	if query.Sort, err = graphql.ArgString(args, "sort"); err != nil {
+		return nil, err
+	}
+
+	if query.Size, err = graphql.ArgInt(args, "size",
+		maxGraphQLGames); err != nil {
+		return nil, err
+	}
+
+	if query.Size <= 0 || query.Size > maxGraphQLGames {
+		return nil, errors.New(errors.ErrInvalidParameter,
+			"invalid games size",
+			"size", query.Size,
+			"max", maxGraphQLGames)
+	}
+
+	if query.Skip, err = graphql.ArgInt(args, "skip", 0); err != nil {
+		return nil, err
+	}
+
+	if query.Skip < 0 {
+		return nil, errors.New(errors.ErrInvalidParameter,
+			"invalid games skip",
+			"skip", query.Skip)
+	}
+
+	tags, err := graphql.ArgStrings(args, "tags")
+	if err != nil {
+		return nil, err
+	}
+
+	if len(tags) > 0 {
+		ctx = context.WithValue(ctx, CtxKeyGameTags, tags)
+	}
+
+	res, _, err := s.getGames(context.WithValue(ctx, CtxKeyGameNoCount, true),
+		query)
+
+	return res, err
+}
+
+// gqlGameVersions retrieves the previous versions of a game, from the newest
+// to the oldest, by following the chain of previous game IDs.
+func (s *Server) gqlGameVersions(ctx context.Context,
+	g *Game,
+	limit int,
+) ([]*Game, error) {
+	res := []*Game{}
+
+	seen := map[string]bool{g.ID.Value: true}
+
+	for id := g.PreviousID.Value; id != "" && len(res) < limit; {
+		if seen[id] {
+			break
+		}
+
+		seen[id] = true
+
+		v, err := s.gqlGame(ctx, id)
+		if err != nil {
+			if errors.Has(err, errors.ErrNotFound) {
+				break
+			}
+
+			return nil, err
+		}
+
+		res = append(res, v)
+
+		id = v.PreviousID.Value
+	}
+
+	return res, nil
+}
+
+// graphqlHandler performs routing for GraphQL requests.
+func (s *Server) graphqlHandler() http.Handler {
+	r := chi.NewRouter()
+
+	r.Use(s.dbAvail)
+
+	schema := s.graphqlSchema()
+
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		s.queryGraphQLHandler(schema, w, r)
+	}
+
+	r.With(s.stat, s.trace, s.auth).Get("/", handler)
+	r.With(s.stat, s.trace, s.auth).Post("/", handler)
+
+	return r
+}
+
+// queryGraphQLHandler is the get and post handler used to run GraphQL
+// queries. Errors resolving fields are returned in the response with any data
+// which could be resolved, so only invalid queries cause an error status.
+// Requests may be encoded in any supported content type.
+func (s *Server) queryGraphQLHandler(schema *graphql.Schema,
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	ctx := r.Context()
+
+	req := &GraphQLRequest{}
+
+	if r.Method == http.MethodGet {
+		req.Query = r.URL.Query().Get("query")
+		req.OperationName = r.URL.Query().Get("operationName")
+
+		if v := r.URL.Query().Get("variables"); v != "" {
+			if err := json.Unmarshal([]byte(v), &req.Variables); err != nil {
+				s.error(errors.Wrap(err, errors.ErrInvalidRequest,
+					"unable to decode variables"), w, r)
+
+				return
+			}
+		}
+	} else if err := s.decode(r, &req); err != nil {
+		s.error(errors.Wrap(err, errors.ErrInvalidRequest,
+			"unable to decode request"), w, r)
+
+		return
+	}
+
+	doc, err := graphql.Parse(req.Query)
+	if err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	res := schema.Execute(ctx, doc, req.OperationName, req.Variables)
+
+	// GraphQL responses are always encoded as JSON.
+	w.Header().Set("Content-Type", contentType(ContentTypeJSON))
+
+	if res.Data == nil {
+		w.WriteHeader(http.StatusBadRequest)
+	}
+
+	if err := json.NewEncoder(w).Encode(res); err != nil {
+		s.error(err, w, r)
+	}
+}
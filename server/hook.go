@@ -0,0 +1,187 @@
+package server
+
+import (
+	"context"
+	"maps"
+	"sync"
+	"time"
+
+	"github.com/dhaifley/game2d/errors"
+	"github.com/dhaifley/game2d/logger"
+	"github.com/dhaifley/game2d/request"
+)
+
+// Server hook events.
+const (
+	HookGameCreated     = "game_created"
+	HookGameUpdated     = "game_updated"
+	HookGameDeleted     = "game_deleted"
+	HookPromptCompleted = "prompt_completed"
+	HookUserLoggedIn    = "user_logged_in"
+)
+
+// hookTimeout is the maximum time allowed for running the hook pipeline for
+// a single event.
+const hookTimeout = time.Second * 30
+
+// HookEvent values describe an event which occurred in the server, and are
+// passed to each hook in the pipeline in order. Hooks may add values to the
+// event data for the hooks which follow them.
+type HookEvent struct {
+	Name      string         `json:"name"              yaml:"name"`
+	AccountID string         `json:"account_id"        yaml:"account_id"`
+	UserID    string         `json:"user_id,omitempty" yaml:"user_id,omitempty"`
+	GameID    string         `json:"game_id,omitempty" yaml:"game_id,omitempty"`
+	Data      map[string]any `json:"data,omitempty"    yaml:"data,omitempty"`
+	CreatedAt int64          `json:"created_at"        yaml:"created_at"`
+}
+
+// Hook values are server extensions which are called after events occur in
+// the server. Hooks are registered by name, usually from the init function of
+// the package implementing them, and only run if they are enabled in the
+// server configuration. Hooks are called after the event is saved, so an error
+// returned by a hook is logged, but never causes the request to fail.
+type Hook interface {
+	Name() string
+	Handle(ctx context.Context, ev *HookEvent) error
+}
+
+// hookFunc values adapt a function to the Hook interface.
+type hookFunc struct {
+	name string
+	fn   func(ctx context.Context, ev *HookEvent) error
+}
+
+// Name returns the name of the hook.
+func (h *hookFunc) Name() string {
+	return h.name
+}
+
+// Handle calls the hook function.
+func (h *hookFunc) Handle(ctx context.Context, ev *HookEvent) error {
+	return h.fn(ctx, ev)
+}
+
+// NewHook creates a hook which calls a function for each event.
+func NewHook(name string,
+	fn func(ctx context.Context, ev *HookEvent) error,
+) Hook {
+	return &hookFunc{name: name, fn: fn}
+}
+
+// hookRegistry contains every registered hook by name.
+var hookRegistry = struct {
+	sync.RWMutex
+	hooks map[string]Hook
+}{hooks: map[string]Hook{}}
+
+// RegisterHook registers a hook, so that it may be enabled in the server
+// configuration. It must be called before the server is created.
+func RegisterHook(h Hook) error {
+	if h == nil || h.Name() == "" {
+		return errors.New(errors.ErrConfiguration,
+			"invalid server hook")
+	}
+
+	hookRegistry.Lock()
+	defer hookRegistry.Unlock()
+
+	if _, ok := hookRegistry.hooks[h.Name()]; ok {
+		return errors.New(errors.ErrConfiguration,
+			"server hook already registered",
+			"hook", h.Name())
+	}
+
+	hookRegistry.hooks[h.Name()] = h
+
+	return nil
+}
+
+// initHooks builds the hook pipeline from the hooks enabled in the server
+// configuration, in the configured order.
+func (s *Server) initHooks() error {
+	hookRegistry.RLock()
+	defer hookRegistry.RUnlock()
+
+	s.hooks = nil
+
+	for _, name := range s.cfg.ServerHooks() {
+		h, ok := hookRegistry.hooks[name]
+		if !ok {
+			return errors.New(errors.ErrConfiguration,
+				"server hook not registered",
+				"hook", name)
+		}
+
+		s.hooks = append(s.hooks, h)
+	}
+
+	if len(s.hooks) > 0 {
+		s.log.Log(context.Background(), logger.LvlInfo,
+			"server hooks enabled",
+			"hooks", s.cfg.ServerHooks())
+	}
+
+	return nil
+}
+
+// runHooks runs the hook pipeline for an event which occurred for the current
+// account and user. The hooks are run in the background, one at a time, in
+// the configured order.
+func (s *Server) runHooks(ctx context.Context,
+	name, gameID string,
+	data map[string]any,
+) {
+	if len(s.hooks) == 0 {
+		return
+	}
+
+	aID, _ := request.ContextAccountID(ctx)
+	uID, _ := request.ContextUserID(ctx)
+
+	data = maps.Clone(data)
+	if data == nil {
+		data = map[string]any{}
+	}
+
+	ev := &HookEvent{
+		Name:      name,
+		AccountID: aID,
+		UserID:    uID,
+		GameID:    gameID,
+		Data:      data,
+		CreatedAt: time.Now().Unix(),
+	}
+
+	go func() {
+		ctx, cancel := request.ContextWithTimeout(context.WithoutCancel(ctx),
+			hookTimeout)
+		defer cancel()
+
+		for _, h := range s.hooks {
+			if err := s.runHook(ctx, h, ev); err != nil {
+				s.log.Log(ctx, logger.LvlWarn,
+					"server hook failed",
+					"error", err,
+					"hook", h.Name(),
+					"event", ev.Name)
+			}
+		}
+	}()
+}
+
+// runHook calls a single hook for an event, recovering from any panic.
+func (s *Server) runHook(ctx context.Context,
+	h Hook,
+	ev *HookEvent,
+) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.New(errors.ErrServer,
+				"server hook panicked",
+				"panic", r)
+		}
+	}()
+
+	return h.Handle(ctx, ev)
+}
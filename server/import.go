@@ -0,0 +1,436 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/dhaifley/game2d/cache"
+	"github.com/dhaifley/game2d/errors"
+	"github.com/dhaifley/game2d/repo"
+	"github.com/dhaifley/game2d/request"
+	"github.com/go-chi/chi/v5"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// MaxImportConcurrency is the maximum number of repository files which can be
+// imported concurrently for an account.
+const MaxImportConcurrency = 32
+
+// ImportStatus values represent the progress of the current, or the result of
+// the most recent, game import for an account.
+type ImportStatus struct {
+	Status         string           `json:"status"                  yaml:"status"`
+	FilesProcessed int64            `json:"files_processed"         yaml:"files_processed"`
+	FilesTotal     int64            `json:"files_total"             yaml:"files_total"`
+	CurrentFile    string           `json:"current_file,omitempty"  yaml:"current_file,omitempty"`
+	Errors         []string         `json:"errors,omitempty"        yaml:"errors,omitempty"`
+	Updated        int64            `json:"updated"                 yaml:"updated"`
+	Deleted        int64            `json:"deleted"                 yaml:"deleted"`
+	LastImported   int64            `json:"last_imported,omitempty" yaml:"last_imported,omitempty"`
+	LastError      string           `json:"last_error,omitempty"    yaml:"last_error,omitempty"`
+	RateLimit      *ImportRateLimit `json:"rate_limit,omitempty"    yaml:"rate_limit,omitempty"`
+}
+
+// ImportRateLimit values represent the remaining request quota of the import
+// repository, as reported during the most recent game import.
+type ImportRateLimit struct {
+	Limit     int64 `json:"limit"     yaml:"limit"`
+	Remaining int64 `json:"remaining" yaml:"remaining"`
+	Reset     int64 `json:"reset"     yaml:"reset"`
+}
+
+// importHash values record the contents of a repository file when it was last
+// imported into an account, so that unchanged files are not downloaded again.
+// The hash is the hash of the file in the repository listing, and the tag is
+// the tag of the contents last downloaded, if the repository provides them.
+type importHash struct {
+	AccountID string `bson:"account_id"`
+	Path      string `bson:"path"`
+	Hash      string `bson:"hash,omitempty"`
+	Tag       string `bson:"tag,omitempty"`
+	UpdatedAt int64  `bson:"updated_at"`
+}
+
+// getImportHashes retrieves the records of the repository files last imported
+// into the current account, by path.
+func (s *Server) getImportHashes(ctx context.Context,
+) (map[string]*importHash, error) {
+	aID, err := request.ContextAccountID(ctx)
+	if err != nil {
+		return nil, errors.New(errors.ErrUnauthorized,
+			"unable to get account id from context")
+	}
+
+	c, err := s.collection(ctx, "import_hashes")
+	if err != nil {
+		return nil, err
+	}
+
+	cur, err := c.Find(ctx,
+		bson.M{"account_id": aID},
+		options.Find().SetProjection(bson.M{"_id": 0}))
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrDatabase,
+			"unable to find import hashes",
+			"account_id", aID)
+	}
+
+	var hs []*importHash
+
+	if err := cur.All(ctx, &hs); err != nil {
+		return nil, errors.Wrap(err, errors.ErrDatabase,
+			"unable to decode import hashes",
+			"account_id", aID)
+	}
+
+	res := make(map[string]*importHash, len(hs))
+
+	for _, h := range hs {
+		if h != nil {
+			res[h.Path] = h
+		}
+	}
+
+	return res, nil
+}
+
+// setImportHashes replaces the records of the repository files last imported
+// into the current account. The records of any other files are removed, so
+// that they are downloaded again by the next import.
+func (s *Server) setImportHashes(ctx context.Context,
+	hashes []*importHash,
+) error {
+	aID, err := request.ContextAccountID(ctx)
+	if err != nil {
+		return errors.New(errors.ErrUnauthorized,
+			"unable to get account id from context")
+	}
+
+	c, err := s.collection(ctx, "import_hashes")
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().Unix()
+
+	paths := make([]string, 0, len(hashes))
+
+	wm := make([]mongo.WriteModel, 0, len(hashes))
+
+	for _, h := range hashes {
+		h.AccountID = aID
+		h.UpdatedAt = now
+
+		paths = append(paths, h.Path)
+
+		wm = append(wm, mongo.NewReplaceOneModel().
+			SetFilter(bson.M{"account_id": aID, "path": h.Path}).
+			SetReplacement(h).
+			SetUpsert(true))
+	}
+
+	if len(wm) > 0 {
+		if _, err := c.BulkWrite(ctx, wm,
+			options.BulkWrite().SetOrdered(false)); err != nil {
+			return errors.Wrap(err, errors.ErrDatabase,
+				"unable to store import hashes",
+				"account_id", aID)
+		}
+	}
+
+	if _, err := c.DeleteMany(ctx, bson.M{
+		"account_id": aID,
+		"path":       bson.M{"$nin": paths},
+	}); err != nil {
+		return errors.Wrap(err, errors.ErrDatabase,
+			"unable to delete import hashes",
+			"account_id", aID)
+	}
+
+	return nil
+}
+
+// importRateLimit adds the rate limit quota of a repository client to import
+// progress values, if the repository limits the rate of requests.
+func importRateLimit(cli repo.Client, progress map[string]any) {
+	rl, ok := cli.(repo.RateLimiter)
+	if !ok {
+		return
+	}
+
+	if r := rl.RateLimit(); r != nil {
+		progress["games_rate_limit_limit"] = r.Limit
+		progress["games_rate_limit_remaining"] = r.Remaining
+		progress["games_rate_limit_reset"] = r.Reset.Unix()
+	}
+}
+
+// importErrors returns descriptions of the errors encountered by an import.
+// Errors with attached errors are described by the errors attached, so that
+// each error in the tree is listed.
+func importErrors(errs []*errors.Error) []string {
+	res := make([]string, 0, len(errs))
+
+	for _, e := range errs {
+		if e == nil {
+			continue
+		}
+
+		if len(e.Errors) > 0 {
+			res = append(res, importErrors(e.Errors)...)
+
+			continue
+		}
+
+		if gID, ok := e.Data["game_id"]; ok {
+			res = append(res, fmt.Sprintf("%s: %v", e.Msg, gID))
+
+			continue
+		}
+
+		res = append(res, e.Msg)
+	}
+
+	return res
+}
+
+// importConcurrency returns the number of repository files imported
+// concurrently for an account.
+func (s *Server) importConcurrency(a *Account) int {
+	if a != nil && a.ImportConcurrency.Value > 0 {
+		return int(min(a.ImportConcurrency.Value, MaxImportConcurrency))
+	}
+
+	return s.cfg.ImportConcurrency()
+}
+
+// checkRepoURL returns an error if an import repository URL refers to a local
+// directory outside of the configured import file directory.
+func (s *Server) checkRepoURL(repoURL string) error {
+	u, err := url.Parse(repoURL)
+	if err != nil || u.Scheme != "file" {
+		return nil
+	}
+
+	dir := s.cfg.ImportFileDir()
+	if dir == "" {
+		return errors.New(errors.ErrForbidden,
+			"file repository imports are not enabled")
+	}
+
+	rel, err := filepath.Rel(filepath.Clean(dir), filepath.Clean(u.Path))
+	if err != nil || rel == ".." ||
+		strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return errors.New(errors.ErrForbidden,
+			"file repository is outside of the import file directory",
+			"path", u.Path)
+	}
+
+	return nil
+}
+
+// setImportProgress merges import progress values into the account repository
+// status data, without modifying any other account values.
+func (s *Server) setImportProgress(ctx context.Context,
+	progress map[string]any,
+) error {
+	a, err := s.getAccount(ctx, "")
+	if err != nil {
+		return err
+	}
+
+	dm := a.RepoStatusData.Value
+
+	if dm == nil {
+		dm = map[string]any{}
+	}
+
+	maps.Copy(dm, progress)
+
+	doc := &bson.D{}
+
+	request.SetField(doc, "repo_status_data", request.FieldJSON{
+		Set: true, Valid: true, Value: dm,
+	})
+
+	if _, err := s.DB().Collection("accounts").UpdateOne(ctx,
+		bson.M{"id": a.ID.Value},
+		bson.D{{Key: "$set", Value: doc}}); err != nil {
+		return errors.Wrap(err, errors.ErrDatabase,
+			"unable to update account import progress",
+			"account_id", a.ID.Value)
+	}
+
+	s.deleteCache(ctx, cache.KeyAccount(a.ID.Value))
+
+	return nil
+}
+
+// statusInt64 returns an integer repository status data value, which may have
+// been decoded as any numeric type.
+func statusInt64(v any) int64 {
+	switch tv := v.(type) {
+	case int:
+		return int64(tv)
+	case int32:
+		return int64(tv)
+	case int64:
+		return tv
+	case float64:
+		return int64(tv)
+	default:
+		return 0
+	}
+}
+
+// getImportStatus retrieves the game import status of the current account.
+func (s *Server) getImportStatus(ctx context.Context,
+) (*ImportStatus, error) {
+	a, err := s.getAccount(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	dm := a.RepoStatusData.Value
+
+	res := &ImportStatus{
+		Status:         a.RepoStatus.Value,
+		FilesProcessed: statusInt64(dm["games_files_processed"]),
+		FilesTotal:     statusInt64(dm["games_files_total"]),
+		Updated:        statusInt64(dm["games_updated"]),
+		Deleted:        statusInt64(dm["games_deleted"]),
+		LastImported:   statusInt64(dm["games_last_imported"]),
+	}
+
+	res.CurrentFile, _ = dm["games_current_file"].(string)
+	res.LastError, _ = dm["games_last_error"].(string)
+
+	if _, ok := dm["games_rate_limit_remaining"]; ok {
+		res.RateLimit = &ImportRateLimit{
+			Limit:     statusInt64(dm["games_rate_limit_limit"]),
+			Remaining: statusInt64(dm["games_rate_limit_remaining"]),
+			Reset:     statusInt64(dm["games_rate_limit_reset"]),
+		}
+	}
+
+	if v, ok := dm["games_import_errors"].([]any); ok {
+		for _, e := range v {
+			if es, ok := e.(string); ok {
+				res.Errors = append(res.Errors, es)
+			}
+		}
+	}
+
+	return res, nil
+}
+
+// getImportStatusHandler is the get handler used to retrieve the progress of
+// game imports.
+func (s *Server) getImportStatusHandler(w http.ResponseWriter,
+	r *http.Request,
+) {
+	ctx := r.Context()
+
+	if err := s.checkScope(ctx, request.ScopeGamesRead); err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	res, err := s.getImportStatus(ctx)
+	if err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	if err := s.encode(w, r, res); err != nil {
+		s.error(err, w, r)
+	}
+}
+
+// importGame imports, or refreshes, a single game file from the account import
+// repository, without walking the rest of the repository. The file path is
+// relative to the games directory of the repository.
+func (s *Server) importGame(ctx context.Context,
+	filePath string,
+) (*Game, error) {
+	ctx = context.WithValue(ctx, request.CtxKeyUserID, request.SystemUser)
+	ctx = context.WithValue(ctx, request.CtxKeyScopes, request.ScopeSuperuser)
+
+	name := strings.TrimPrefix(path.Clean("/"+filePath), "/")
+	name = strings.TrimPrefix(name, "games/")
+
+	gID := strings.TrimSuffix(name, filepath.Ext(name))
+
+	if !request.ValidGameID(gID) {
+		return nil, errors.New(errors.ErrInvalidRequest,
+			"invalid game repository file",
+			"path", filePath)
+	}
+
+	ar, err := s.getAccount(ctx, "")
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrDatabase,
+			"unable to get account repository")
+	}
+
+	if ar.Repo.Value == "" {
+		return nil, errors.New(errors.ErrInvalidRequest,
+			"account has no import repository")
+	}
+
+	cli, err := s.getRepoClient(ar.Repo.Value)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrImport,
+			"unable to create repository client")
+	}
+
+	hash, err := cli.Commit(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrImport,
+			"unable to get repository commit hash")
+	}
+
+	if _, err := s.importRepoGame(ctx, cli, repo.Item{
+		Path: "games/" + name,
+		Type: "file",
+	}, nil, hash, true); err != nil {
+		return nil, err
+	}
+
+	return s.getGame(ctx, gID)
+}
+
+// postImportGameHandler is the post handler used to import a single game.
+func (s *Server) postImportGameHandler(w http.ResponseWriter,
+	r *http.Request,
+) {
+	ctx := r.Context()
+
+	if err := s.checkScope(ctx, request.ScopeGamesAdmin); err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	res, err := s.importGame(ctx, chi.URLParam(r, "*"))
+	if err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	if err := s.encode(w, r, res); err != nil {
+		s.error(err, w, r)
+	}
+}
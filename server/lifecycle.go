@@ -0,0 +1,389 @@
+package server
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+
+	"github.com/dhaifley/game2d/errors"
+	"github.com/dhaifley/game2d/lifecycle"
+	"github.com/dhaifley/game2d/logger"
+	"github.com/dhaifley/game2d/request"
+)
+
+// Server lifecycle components, which are started in the order of their
+// dependencies, and stopped in the reverse order, so that the database and
+// cache are only closed after the background jobs, which use them, have
+// stopped, and the jobs only after requests have completed.
+const (
+	componentDB    = "db"
+	componentCache = "cache"
+	componentJobs  = "jobs"
+	componentHTTP  = "http"
+)
+
+// initLifecycle registers the components of the server with its lifecycle
+// manager. They are started by Start, and stopped by Close or Shutdown.
+func (s *Server) initLifecycle() error {
+	s.lc = lifecycle.New(s.log)
+
+	for _, c := range []struct {
+		name  string
+		start func(ctx context.Context) error
+		stop  func(ctx context.Context) error
+		opts  []lifecycle.Option
+	}{{
+		name:  componentDB,
+		start: s.startDB,
+		stop:  s.stopDB,
+		opts: []lifecycle.Option{
+			lifecycle.StopTimeout(s.cfg.DBTimeout()),
+		},
+	}, {
+		name:  componentCache,
+		start: s.startCache,
+		stop:  s.stopCache,
+		opts: []lifecycle.Option{
+			lifecycle.StopTimeout(s.cfg.CacheTimeout()),
+		},
+	}, {
+		name:  componentJobs,
+		start: s.startJobs,
+		stop:  s.stopJobs,
+		opts: []lifecycle.Option{
+			lifecycle.DependsOn(componentDB, componentCache),
+			lifecycle.StopTimeout(s.cfg.ServerDrainTimeout()),
+		},
+	}, {
+		name:  componentHTTP,
+		start: s.startHTTP,
+		stop:  s.stopHTTP,
+		opts: []lifecycle.Option{
+			lifecycle.DependsOn(componentDB, componentCache, componentJobs),
+			lifecycle.StopTimeout(s.cfg.ServerDrainTimeout()),
+		},
+	}} {
+		if err := s.lc.Register(c.name, &lifecycle.Funcs{
+			StartFunc: c.start,
+			StopFunc:  c.stop,
+		}, c.opts...); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Start starts the components of the server, in the order of their
+// dependencies: the database connection, the cache, the background jobs, and
+// then the HTTP servers. If a component fails to start, those already started
+// are stopped. Components which are already started are not started again.
+func (s *Server) Start(ctx context.Context) error {
+	return s.lc.Start(ctx)
+}
+
+// startDB begins connecting the server to the database, in the background, so
+// that requests which do not use it can be served in the meantime.
+func (s *Server) startDB(ctx context.Context) error {
+	s.ConnectDB(ctx)
+
+	return nil
+}
+
+// startCache checks that the cache servers can be reached. The server is
+// started even if they can not be, since it operates without the cache.
+func (s *Server) startCache(ctx context.Context) error {
+	s.RLock()
+
+	c := s.cache
+
+	s.RUnlock()
+
+	p, ok := c.(interface {
+		Ping(ctx context.Context) error
+	})
+	if !ok {
+		return nil
+	}
+
+	ctx, cancel := request.ContextWithTimeout(ctx, s.cfg.CacheTimeout())
+	defer cancel()
+
+	if err := p.Ping(ctx); err != nil {
+		s.log.Log(ctx, logger.LvlWarn, "cache servers unavailable",
+			"error", err,
+			"servers", s.cfg.CacheServers())
+	}
+
+	return nil
+}
+
+// startJobs starts the background jobs of the server. Initial data is
+// provisioned, once the database is connected, before the periodic jobs are
+// started.
+func (s *Server) startJobs(ctx context.Context) error {
+	if s.metric != nil {
+		if err := s.UpdateMetrics(ctx); err != nil {
+			return err
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+
+	s.addCancelFunc(cancel)
+
+	s.jobs.Add(1)
+
+	go func() {
+		defer s.jobs.Done()
+
+		if err := s.Provision(ctx); err != nil {
+			s.log.Log(ctx, logger.LvlError,
+				"unable to provision initial data",
+				"error", err)
+		}
+
+		if ctx.Err() != nil {
+			return
+		}
+
+		s.UpdateAuthConfig()
+		s.UpdateOrigins()
+		s.UpdateGameImports()
+		s.UpdateGameBackups()
+		s.UpdateGamePrompts()
+		s.UpdateSecrets()
+		s.UpdateAutomations()
+		s.UpdateAssets()
+	}()
+
+	return nil
+}
+
+// startHTTP starts listening on the addresses of the server, and serves HTTP
+// requests on them in the background. The errors serving requests are
+// returned by Serve.
+func (s *Server) startHTTP(ctx context.Context) error {
+	s.RLock()
+
+	addr := s.addr
+
+	s.RUnlock()
+
+	s.log.Log(ctx, logger.LvlDebug, "starting server",
+		"address", addr)
+
+	if len(addr) == 0 {
+		return errors.New(errors.ErrConfiguration,
+			"no servers configured")
+	}
+
+	lis := make([]net.Listener, 0, len(addr))
+
+	for _, a := range addr {
+		l, err := net.Listen("tcp", a)
+		if err != nil {
+			for _, l := range lis {
+				_ = l.Close()
+			}
+
+			return errors.Wrap(err, errors.ErrServer,
+				"server unable to start listening on "+a)
+		}
+
+		lis = append(lis, l)
+	}
+
+	ech := make(chan error, len(lis)+1)
+
+	var wg sync.WaitGroup
+
+	if s.redirect != nil {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			s.log.Log(ctx, logger.LvlInfo, "redirect server listening",
+				"address", s.redirect.Addr)
+
+			if err := s.redirect.ListenAndServe(); err != nil &&
+				err != http.ErrServerClosed {
+				ech <- errors.Wrap(err, errors.ErrServer,
+					"redirect server error")
+
+				return
+			}
+
+			ech <- nil
+		}()
+	}
+
+	cert, key := s.cfg.ServerCert(), s.cfg.ServerKey()
+
+	if s.cfg.ServerAutocert() {
+		cert, key = "", ""
+	}
+
+	for _, l := range lis {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			s.log.Log(ctx, logger.LvlInfo, "server listening",
+				"address", l.Addr().String(),
+				"tls", s.useTLS())
+
+			var err error
+
+			if s.useTLS() {
+				err = s.Server.ServeTLS(l, cert, key)
+			} else {
+				err = s.Server.Serve(l)
+			}
+
+			if err != nil && err != http.ErrServerClosed {
+				ech <- errors.Wrap(err, errors.ErrServer,
+					"server error")
+
+				return
+			}
+
+			ech <- nil
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(ech)
+	}()
+
+	s.Lock()
+
+	s.serving = ech
+
+	s.Unlock()
+
+	return nil
+}
+
+// stopHTTP stops the HTTP servers, waiting for active requests to complete,
+// unless the server is closing, in which case they are closed immediately.
+func (s *Server) stopHTTP(ctx context.Context) error {
+	s.RLock()
+
+	closing, redirect := s.closing, s.redirect
+
+	s.RUnlock()
+
+	if closing {
+		var errs []error
+
+		if redirect != nil {
+			if err := redirect.Close(); err != nil {
+				errs = append(errs, errors.Wrap(err, errors.ErrServer,
+					"unable to close redirect server"))
+			}
+		}
+
+		if err := s.Server.Close(); err != nil {
+			errs = append(errs, errors.Wrap(err, errors.ErrServer,
+				"unable to close server"))
+		}
+
+		return errors.Join(errs...)
+	}
+
+	if redirect != nil {
+		if err := redirect.Shutdown(ctx); err != nil {
+			s.log.Log(ctx, logger.LvlError,
+				"error during redirect server shutdown",
+				"error", err)
+		}
+	}
+
+	if err := s.Server.Shutdown(ctx); err != nil {
+		s.log.Log(ctx, logger.LvlError, "error during server shutdown",
+			"error", err)
+
+		if err := s.Server.Close(); err != nil && err != http.ErrServerClosed {
+			return errors.Wrap(err, errors.ErrServer,
+				"unable to close server")
+		}
+	}
+
+	return nil
+}
+
+// stopJobs cancels the background jobs and prompts of the server, and waits
+// for them to return.
+func (s *Server) stopJobs(ctx context.Context) error {
+	s.Lock()
+
+	for _, cancel := range s.prompts {
+		if cancel != nil {
+			cancel()
+		}
+	}
+
+	for _, cancel := range s.cancels {
+		if cancel != nil {
+			cancel()
+		}
+	}
+
+	s.Unlock()
+
+	done := make(chan struct{})
+
+	go func() {
+		s.jobs.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return errors.Wrap(ctx.Err(), errors.ErrServer,
+			"background jobs did not stop")
+	}
+}
+
+// stopCache closes the connections of the server cache.
+func (s *Server) stopCache(ctx context.Context) error {
+	s.RLock()
+
+	c, ok := s.cache.(io.Closer)
+
+	s.RUnlock()
+
+	if !ok {
+		return nil
+	}
+
+	return c.Close()
+}
+
+// stopDB disconnects the server from the database.
+func (s *Server) stopDB(ctx context.Context) error {
+	s.RLock()
+
+	db := s.db
+
+	s.RUnlock()
+
+	if db == nil {
+		return nil
+	}
+
+	if err := db.Disconnect(ctx); err != nil {
+		return errors.Wrap(err, errors.ErrDatabase,
+			"unable to disconnect from database")
+	}
+
+	return nil
+}
@@ -0,0 +1,143 @@
+package server
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dhaifley/game2d/cache"
+	"github.com/dhaifley/game2d/config"
+)
+
+// lifecycleEvents values record the events of the server lifecycle.
+type lifecycleEvents struct {
+	sync.Mutex
+	events []string
+	times  []time.Time
+}
+
+func (e *lifecycleEvents) add(ev string) {
+	e.Lock()
+	defer e.Unlock()
+
+	e.events = append(e.events, ev)
+	e.times = append(e.times, time.Now())
+}
+
+// closeCache values are caches which record when they are closed.
+type closeCache struct {
+	cache.MockCache
+	events *lifecycleEvents
+}
+
+func (c *closeCache) Close() error {
+	c.events.add("stop cache")
+
+	return nil
+}
+
+func TestLifecycleShutdown(t *testing.T) {
+	t.Parallel()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	addr := l.Addr().String()
+
+	_ = l.Close()
+
+	sc := &config.ServerConfig{}
+
+	sc.Load()
+
+	sc.Address = addr
+	sc.DrainTimeout = 100 * time.Millisecond
+	sc.DrainDelay = 0
+
+	cfg := config.NewDefault()
+
+	cfg.SetServer(sc)
+
+	svr, err := NewServer(cfg, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	order, err := svr.lc.Order()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exp := []string{componentDB, componentCache, componentJobs, componentHTTP}
+
+	if !slices.Equal(order, exp) {
+		t.Errorf("Expected order: %v, got: %v", exp, order)
+	}
+
+	ev := &lifecycleEvents{}
+
+	svr.SetCache(&closeCache{events: ev})
+
+	if err := svr.startHTTP(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := http.Get("http://" + addr + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_ = res.Body.Close()
+
+	// The background job does not stop, so its timeout elapses before the
+	// cache is closed.
+	svr.jobs.Add(1)
+
+	t.Cleanup(svr.jobs.Done)
+
+	svr.addCancelFunc(func() {
+		// The HTTP servers are stopped before the jobs.
+		if c, err := net.Dial("tcp", addr); err == nil {
+			_ = c.Close()
+
+			t.Error("Expected HTTP server to be stopped before the jobs")
+		}
+
+		ev.add("stop jobs")
+	})
+
+	start := time.Now()
+
+	svr.Shutdown(context.Background())
+
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("Expected shutdown within the component timeouts, took: %v",
+			d)
+	}
+
+	for err := range svr.serving {
+		if err != nil {
+			t.Errorf("Unexpected error serving requests: %v", err)
+		}
+	}
+
+	ev.Lock()
+	defer ev.Unlock()
+
+	exp = []string{"stop jobs", "stop cache"}
+
+	if !slices.Equal(ev.events, exp) {
+		t.Fatalf("Expected events: %v, got: %v", exp, ev.events)
+	}
+
+	if d := ev.times[1].Sub(start); d < sc.DrainTimeout {
+		t.Errorf("Expected cache stopped after the jobs timeout: %v, got: %v",
+			sc.DrainTimeout, d)
+	}
+}
@@ -0,0 +1,190 @@
+package server
+
+import (
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/dhaifley/game2d/errors"
+	"github.com/go-chi/chi/v5"
+)
+
+// maxRouteDistance is the maximum distance between a request path and a route
+// for the route to be suggested by not found errors. Each missing, extra, or
+// entirely different path segment has a distance of 1, so routes are only
+// suggested for small differences, such as a misspelled segment.
+const maxRouteDistance = 0.5
+
+// routeResources maps the route groups of the API to the names of the
+// resources they serve, which are used by not found errors.
+var routeResources = map[string]string{
+	"account":     "account",
+	"admin":       "admin",
+	"automations": "automation",
+	"billing":     "billing",
+	"challenges":  "challenge",
+	"errors":      "error catalog",
+	"flags":       "flag",
+	"games":       "game",
+	"graphql":     "graphql",
+	"health":      "health",
+	"healthz":     "health",
+	"login":       "login",
+	"ready":       "readiness",
+	"readyz":      "readiness",
+	"schema":      "schema",
+	"user":        "user",
+}
+
+// routePatterns returns the patterns of the API routes of a router, without
+// wildcards or trailing slashes, in lexical order. Debugging routes are not
+// included.
+func routePatterns(r chi.Routes, prefix string) []string {
+	var res []string
+
+	_ = chi.Walk(r, func(_, route string, _ http.Handler,
+		_ ...func(http.Handler) http.Handler,
+	) error {
+		route = strings.TrimSuffix(strings.TrimSuffix(route, "/*"), "/")
+
+		if strings.HasPrefix(route, prefix+"/") &&
+			!strings.HasPrefix(route, prefix+"/debug/") {
+			res = append(res, route)
+		}
+
+		return nil
+	})
+
+	slices.Sort(res)
+
+	return slices.Compact(res)
+}
+
+// levenshtein returns the edit distance between two strings.
+func levenshtein(a, b string) int {
+	ar, br := []rune(a), []rune(b)
+
+	prev := make([]int, len(br)+1)
+	cur := make([]int, len(br)+1)
+
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ar); i++ {
+		cur[0] = i
+
+		for j := 1; j <= len(br); j++ {
+			cost := 1
+			if ar[i-1] == br[j-1] {
+				cost = 0
+			}
+
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+
+		prev, cur = cur, prev
+	}
+
+	return prev[len(br)]
+}
+
+// segmentDistance returns the distance between a path segment and a route
+// segment, from 0, if the route segment is equal, or is a parameter, to 1, if
+// they are entirely different.
+func segmentDistance(seg, route string) float64 {
+	if seg == route ||
+		(strings.HasPrefix(route, "{") && strings.HasSuffix(route, "}")) {
+		return 0
+	}
+
+	n := max(utf8.RuneCountInString(seg), utf8.RuneCountInString(route))
+
+	return float64(levenshtein(seg, route)) / float64(n)
+}
+
+// routeDistance returns the distance between a request path and a route
+// pattern, as the edit distance of their segments.
+func routeDistance(p, route string) float64 {
+	ps := strings.Split(strings.Trim(p, "/"), "/")
+	rs := strings.Split(strings.Trim(route, "/"), "/")
+
+	prev := make([]float64, len(rs)+1)
+	cur := make([]float64, len(rs)+1)
+
+	for j := range prev {
+		prev[j] = float64(j)
+	}
+
+	for i := 1; i <= len(ps); i++ {
+		cur[0] = float64(i)
+
+		for j := 1; j <= len(rs); j++ {
+			cur[j] = min(prev[j]+1, cur[j-1]+1,
+				prev[j-1]+segmentDistance(ps[i-1], rs[j-1]))
+		}
+
+		prev, cur = cur, prev
+	}
+
+	return prev[len(rs)]
+}
+
+// suggestRoute returns the route pattern closest to a request path, or an
+// empty string, if no route is close enough to be suggested.
+func suggestRoute(p string, routes []string) string {
+	res, best := "", maxRouteDistance
+
+	for _, route := range routes {
+		if d := routeDistance(p, route); d <= best && (res == "" || d < best) {
+			res, best = route, d
+		}
+	}
+
+	return res
+}
+
+// notFound is the handler function for 404 errors. The error names the
+// resource of the route group of the request, and suggests the closest route
+// of the group, or of the API, if the group does not exist.
+func (s *Server) notFound(w http.ResponseWriter, r *http.Request) {
+	p := path.Clean("/" + r.URL.Path)
+
+	rel, ok := strings.CutPrefix(p, s.cfg.ServerPathPrefix()+"/")
+	if !ok {
+		s.error(errors.New(errors.ErrNotFound,
+			"route not found",
+			"path", p).WithReason(errors.ReasonRouteNotFound), w, r)
+
+		return
+	}
+
+	group, _, _ := strings.Cut(rel, "/")
+
+	s.RLock()
+	routes := s.routes
+	s.RUnlock()
+
+	msg := "route not found"
+
+	if res, ok := routeResources[group]; ok {
+		msg = res + " route not found"
+
+		gp := path.Join(s.cfg.ServerPathPrefix(), group)
+
+		routes = slices.DeleteFunc(slices.Clone(routes), func(v string) bool {
+			return v != gp && !strings.HasPrefix(v, gp+"/")
+		})
+	}
+
+	data := []any{"path", p}
+
+	if route := suggestRoute(p, routes); route != "" {
+		data = append(data, "suggestion", route)
+	}
+
+	s.error(errors.New(errors.ErrNotFound, msg, data...).
+		WithReason(errors.ReasonRouteNotFound), w, r)
+}
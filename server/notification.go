@@ -0,0 +1,647 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/dhaifley/game2d/errors"
+	"github.com/dhaifley/game2d/logger"
+	"github.com/dhaifley/game2d/notify"
+	"github.com/dhaifley/game2d/request"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// Notification event types.
+const (
+	NotificationPromptCompleted = "prompt_completed"
+	NotificationPromptFailed    = "prompt_failed"
+	NotificationImportFailed    = "import_failed"
+)
+
+// NotificationTypes contains all valid notification event types.
+var NotificationTypes = []string{
+	NotificationPromptCompleted,
+	NotificationPromptFailed,
+	NotificationImportFailed,
+}
+
+const (
+	// maxPushSubscriptions is the maximum number of web push subscriptions
+	// stored for a user.
+	maxPushSubscriptions = 10
+)
+
+// Notification values represent a notification in the inbox of a user.
+type Notification struct {
+	Seq       bson.ObjectID `bson:"_id,omitempty"     json:"-"                 yaml:"-"`
+	ID        string        `bson:"id"                json:"id"                yaml:"id"`
+	AccountID string        `bson:"account_id"        json:"account_id"        yaml:"account_id"`
+	UserID    string        `bson:"user_id"           json:"user_id"           yaml:"user_id"`
+	Type      string        `bson:"type"              json:"type"              yaml:"type"`
+	Title     string        `bson:"title"             json:"title"             yaml:"title"`
+	Body      string        `bson:"body"              json:"body"              yaml:"body"`
+	GameID    string        `bson:"game_id,omitempty" json:"game_id,omitempty" yaml:"game_id,omitempty"`
+	Read      bool          `bson:"read"              json:"read"              yaml:"read"`
+	CreatedAt int64         `bson:"created_at"        json:"created_at"        yaml:"created_at"`
+	ExpiresAt time.Time     `bson:"expires_at"        json:"-"                 yaml:"-"`
+}
+
+// NotificationPage values represent a page of the notifications in the inbox of
+// a user, from the newest to the oldest. The cursor is used to retrieve the
+// next page, and is empty if there are no more notifications.
+type NotificationPage struct {
+	Notifications []*Notification `json:"notifications"    yaml:"notifications"`
+	Unread        int64           `json:"unread"           yaml:"unread"`
+	Cursor        string          `json:"cursor,omitempty" yaml:"cursor,omitempty"`
+}
+
+// NotificationRead values represent a request to mark notifications as read.
+// All notifications are marked as read if no IDs are specified.
+type NotificationRead struct {
+	IDs []string `json:"ids,omitempty" yaml:"ids,omitempty"`
+}
+
+// NotificationPreferences values represent the notification settings of a
+// user. Notifications are always added to the inbox, unless their event type
+// is disabled, and are also delivered through any enabled channels.
+type NotificationPreferences struct {
+	AccountID     string                `bson:"account_id"    json:"-"             yaml:"-"`
+	UserID        string                `bson:"user_id"       json:"-"             yaml:"-"`
+	Email         bool                  `bson:"email"         json:"email"         yaml:"email"`
+	Push          bool                  `bson:"push"          json:"push"          yaml:"push"`
+	Events        map[string]bool       `bson:"events"        json:"events"        yaml:"events"`
+	Subscriptions []notify.Subscription `bson:"subscriptions" json:"subscriptions" yaml:"subscriptions"`
+	UpdatedAt     int64                 `bson:"updated_at"    json:"updated_at"    yaml:"updated_at"`
+}
+
+// Validate checks that the value contains valid data.
+func (p *NotificationPreferences) Validate() error {
+	for k := range p.Events {
+		if !slices.Contains(NotificationTypes, k) {
+			return errors.New(errors.ErrInvalidRequest,
+				"invalid notification event type",
+				"event", k)
+		}
+	}
+
+	if len(p.Subscriptions) > maxPushSubscriptions {
+		return errors.New(errors.ErrInvalidRequest,
+			"too many push subscriptions",
+			"max", maxPushSubscriptions)
+	}
+
+	for _, sub := range p.Subscriptions {
+		u, err := url.Parse(sub.Endpoint)
+		if err != nil || u.Scheme != "https" || u.Host == "" {
+			return errors.New(errors.ErrInvalidRequest,
+				"invalid push subscription endpoint",
+				"endpoint", sub.Endpoint)
+		}
+
+		if sub.Keys.P256DH == "" || sub.Keys.Auth == "" {
+			return errors.New(errors.ErrInvalidRequest,
+				"missing push subscription keys",
+				"endpoint", sub.Endpoint)
+		}
+	}
+
+	return nil
+}
+
+// enabled returns whether notifications of an event type are enabled.
+func (p *NotificationPreferences) enabled(typ string) bool {
+	if v, ok := p.Events[typ]; ok {
+		return v
+	}
+
+	return true
+}
+
+// initNotifiers creates the configured notification senders.
+func (s *Server) initNotifiers() error {
+	s.notifiers = map[string]notify.Sender{}
+
+	if addr := s.cfg.NotifySMTPAddress(); addr != "" {
+		sd, err := notify.NewSMTPSender(addr, s.cfg.NotifySMTPUsername(),
+			s.cfg.NotifySMTPPassword(), s.cfg.NotifySMTPFrom())
+		if err != nil {
+			return err
+		}
+
+		s.notifiers[notify.ChannelEmail] = sd
+	}
+
+	if key := s.cfg.NotifyPushPrivateKey(); key != "" {
+		sd, err := notify.NewPushSender(key, s.cfg.NotifyPushSubject(), nil)
+		if err != nil {
+			return err
+		}
+
+		s.notifiers[notify.ChannelPush] = sd
+	}
+
+	return nil
+}
+
+// notifier returns the notification sender for a channel, if one is
+// configured.
+func (s *Server) notifier(channel string) notify.Sender {
+	s.RLock()
+	defer s.RUnlock()
+
+	return s.notifiers[channel]
+}
+
+// getNotificationPreferences retrieves the notification preferences of the
+// current user, or the defaults, if none have been set.
+func (s *Server) getNotificationPreferences(ctx context.Context,
+) (*NotificationPreferences, error) {
+	aID, err := request.ContextAccountID(ctx)
+	if err != nil {
+		return nil, errors.New(errors.ErrUnauthorized,
+			"unable to get account id from context")
+	}
+
+	uID, err := request.ContextUserID(ctx)
+	if err != nil {
+		return nil, errors.New(errors.ErrUnauthorized,
+			"unable to get user id from context")
+	}
+
+	res := &NotificationPreferences{}
+
+	f := bson.M{"account_id": aID, "user_id": uID}
+
+	c, err := s.collection(ctx, "notification_preferences")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.FindOne(ctx, f).
+		Decode(res); err != nil {
+		if !errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, errors.Wrap(err, errors.ErrDatabase,
+				"unable to get notification preferences",
+				"user_id", uID)
+		}
+
+		res = &NotificationPreferences{AccountID: aID, UserID: uID}
+	}
+
+	if res.Events == nil {
+		res.Events = map[string]bool{}
+	}
+
+	if res.Subscriptions == nil {
+		res.Subscriptions = []notify.Subscription{}
+	}
+
+	return res, nil
+}
+
+// setNotificationPreferences replaces the notification preferences of the
+// current user.
+func (s *Server) setNotificationPreferences(ctx context.Context,
+	v *NotificationPreferences,
+) (*NotificationPreferences, error) {
+	aID, err := request.ContextAccountID(ctx)
+	if err != nil {
+		return nil, errors.New(errors.ErrUnauthorized,
+			"unable to get account id from context")
+	}
+
+	uID, err := request.ContextUserID(ctx)
+	if err != nil {
+		return nil, errors.New(errors.ErrUnauthorized,
+			"unable to get user id from context")
+	}
+
+	if v == nil {
+		return nil, errors.New(errors.ErrInvalidRequest,
+			"missing notification preferences")
+	}
+
+	if err := v.Validate(); err != nil {
+		return nil, err
+	}
+
+	v.AccountID, v.UserID = aID, uID
+	v.UpdatedAt = time.Now().Unix()
+
+	f := bson.M{"account_id": aID, "user_id": uID}
+
+	c, err := s.collection(ctx, "notification_preferences")
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := c.ReplaceOne(ctx,
+		f, v, options.Replace().SetUpsert(true)); err != nil {
+		return nil, errors.Wrap(err, errors.ErrDatabase,
+			"unable to set notification preferences",
+			"user_id", uID)
+	}
+
+	return s.getNotificationPreferences(ctx)
+}
+
+// notifyUser adds a notification to the inbox of a user, and delivers it
+// through the channels enabled by the user. Failures are logged, but otherwise
+// ignored, so that notifying never causes the operation being notified about
+// to fail.
+func (s *Server) notifyUser(ctx context.Context,
+	userID, typ, gameID string,
+	msg *notify.Message,
+) {
+	aID, err := request.ContextAccountID(ctx)
+	if err != nil || s.DB() == nil || userID == "" ||
+		userID == request.SystemUser {
+		return
+	}
+
+	ctx = context.WithoutCancel(ctx)
+	ctx = context.WithValue(ctx, request.CtxKeyUserID, userID)
+	ctx = context.WithValue(ctx, request.CtxKeyScopes, request.ScopeSuperuser)
+
+	// Deliveries, which continue after this returns, use their own contexts.
+	dbCtx, cancel := s.opContext(ctx, opDB)
+	defer cancel()
+
+	p, err := s.getNotificationPreferences(dbCtx)
+	if err != nil {
+		s.log.Log(ctx, logger.LvlWarn,
+			"unable to get notification preferences",
+			"error", err,
+			"user_id", userID)
+
+		return
+	}
+
+	if !p.enabled(typ) {
+		return
+	}
+
+	now := time.Now()
+
+	n := &Notification{
+		ID:        uuid.NewString(),
+		AccountID: aID,
+		UserID:    userID,
+		Type:      typ,
+		Title:     msg.Title,
+		Body:      msg.Body,
+		GameID:    gameID,
+		CreatedAt: now.Unix(),
+		ExpiresAt: now.Add(s.cfg.NotifyRetention()),
+	}
+
+	c, err := s.collection(ctx, "notifications")
+	if err == nil {
+		_, err = c.InsertOne(dbCtx, n)
+	}
+
+	if err != nil {
+		s.log.Log(ctx, logger.LvlWarn,
+			"unable to add notification to inbox",
+			"error", err,
+			"user_id", userID,
+			"type", typ)
+	}
+
+	to := &notify.Recipient{}
+
+	var channels []string
+
+	if p.Email && s.notifier(notify.ChannelEmail) != nil {
+		u, err := s.getUser(dbCtx, userID)
+		if err == nil && u.Email.Value != "" {
+			to.Email = u.Email.Value
+
+			channels = append(channels, notify.ChannelEmail)
+		}
+	}
+
+	if p.Push && len(p.Subscriptions) > 0 &&
+		s.notifier(notify.ChannelPush) != nil {
+		to.Subscriptions = p.Subscriptions
+
+		channels = append(channels, notify.ChannelPush)
+	}
+
+	for _, ch := range channels {
+		go func(ch string) {
+			ctx, cancel := s.opContext(ctx, opOutbound)
+			defer cancel()
+
+			if err := s.notifier(ch).Send(ctx, to, msg); err != nil {
+				s.log.Log(ctx, logger.LvlWarn,
+					"unable to deliver notification",
+					"error", err,
+					"channel", ch,
+					"user_id", userID,
+					"type", typ)
+			}
+		}(ch)
+	}
+}
+
+// notifyAccount notifies all active users of the current account.
+func (s *Server) notifyAccount(ctx context.Context,
+	typ, gameID string,
+	msg *notify.Message,
+) {
+	aID, err := request.ContextAccountID(ctx)
+	if err != nil || s.DB() == nil {
+		return
+	}
+
+	f := bson.M{"account_id": aID, "status": request.StatusActive}
+
+	cur, err := s.DB().Collection("users").Find(ctx, f,
+		options.Find().SetProjection(bson.M{"_id": 0, "id": 1}))
+	if err != nil {
+		s.log.Log(ctx, logger.LvlWarn,
+			"unable to get users to notify",
+			"error", err,
+			"type", typ)
+
+		return
+	}
+
+	var users []*User
+
+	if err := cur.All(ctx, &users); err != nil {
+		s.log.Log(ctx, logger.LvlWarn,
+			"unable to decode users to notify",
+			"error", err,
+			"type", typ)
+
+		return
+	}
+
+	for _, u := range users {
+		if u != nil {
+			s.notifyUser(ctx, u.ID.Value, typ, gameID, msg)
+		}
+	}
+}
+
+// getNotifications retrieves a page of the notifications of the current user,
+// which starts after the cursor, if one is specified.
+func (s *Server) getNotifications(ctx context.Context,
+	cursor string,
+	size int64,
+	unread bool,
+) (*NotificationPage, error) {
+	aID, err := request.ContextAccountID(ctx)
+	if err != nil {
+		return nil, errors.New(errors.ErrUnauthorized,
+			"unable to get account id from context")
+	}
+
+	uID, err := request.ContextUserID(ctx)
+	if err != nil {
+		return nil, errors.New(errors.ErrUnauthorized,
+			"unable to get user id from context")
+	}
+
+	if size <= 0 {
+		size = defaultActivitySize
+	}
+
+	size = min(size, maxActivitySize)
+
+	f := bson.M{"account_id": aID, "user_id": uID}
+
+	c, err := s.collection(ctx, "notifications")
+	if err != nil {
+		return nil, err
+	}
+
+	res := &NotificationPage{Notifications: []*Notification{}}
+
+	res.Unread, err = c.CountDocuments(ctx,
+		bson.M{"account_id": aID, "user_id": uID, "read": false})
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrDatabase,
+			"unable to count unread notifications")
+	}
+
+	if unread {
+		f["read"] = false
+	}
+
+	if cursor != "" {
+		oID, err := bson.ObjectIDFromHex(cursor)
+		if err != nil {
+			return nil, errors.Wrap(err, errors.ErrInvalidParameter,
+				"invalid notification cursor",
+				"cursor", cursor)
+		}
+
+		f["_id"] = bson.M{"$lt": oID}
+	}
+
+	cur, err := c.Find(ctx, f,
+		options.Find().SetSort(bson.D{{Key: "_id", Value: -1}}).
+			SetLimit(size))
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrDatabase,
+			"unable to find notifications")
+	}
+
+	if err := cur.All(ctx, &res.Notifications); err != nil {
+		return nil, errors.Wrap(err, errors.ErrDatabase,
+			"unable to decode notifications")
+	}
+
+	if n := len(res.Notifications); int64(n) == size {
+		res.Cursor = res.Notifications[n-1].Seq.Hex()
+	}
+
+	return res, nil
+}
+
+// readNotifications marks notifications of the current user as read, and
+// returns the number of notifications updated.
+func (s *Server) readNotifications(ctx context.Context,
+	ids []string,
+) (int64, error) {
+	aID, err := request.ContextAccountID(ctx)
+	if err != nil {
+		return 0, errors.New(errors.ErrUnauthorized,
+			"unable to get account id from context")
+	}
+
+	uID, err := request.ContextUserID(ctx)
+	if err != nil {
+		return 0, errors.New(errors.ErrUnauthorized,
+			"unable to get user id from context")
+	}
+
+	f := bson.M{"account_id": aID, "user_id": uID, "read": false}
+
+	if len(ids) > 0 {
+		f["id"] = bson.M{"$in": ids}
+	}
+
+	c, err := s.collection(ctx, "notifications")
+	if err != nil {
+		return 0, err
+	}
+
+	res, err := c.UpdateMany(ctx, f,
+		bson.M{"$set": bson.M{"read": true}})
+	if err != nil {
+		return 0, errors.Wrap(err, errors.ErrDatabase,
+			"unable to mark notifications as read")
+	}
+
+	return res.ModifiedCount, nil
+}
+
+// getNotificationsHandler is the get handler used to retrieve the
+// notifications of the current user.
+func (s *Server) getNotificationsHandler(w http.ResponseWriter,
+	r *http.Request,
+) {
+	ctx := r.Context()
+
+	if err := s.checkScope(ctx, request.ScopeUserRead); err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	q := r.URL.Query()
+
+	var size int64
+
+	if v := q.Get("size"); v != "" {
+		i, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || i < 0 {
+			s.error(errors.New(errors.ErrInvalidParameter,
+				"invalid notification size",
+				"size", v), w, r)
+
+			return
+		}
+
+		size = i
+	}
+
+	unread, _ := strconv.ParseBool(q.Get("unread"))
+
+	res, err := s.getNotifications(ctx, q.Get("cursor"), size, unread)
+	if err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	if err := s.encode(w, r, res); err != nil {
+		s.error(err, w, r)
+	}
+}
+
+// postNotificationsReadHandler is the post handler used to mark notifications
+// of the current user as read.
+func (s *Server) postNotificationsReadHandler(w http.ResponseWriter,
+	r *http.Request,
+) {
+	ctx := r.Context()
+
+	if err := s.checkScope(ctx, request.ScopeUserWrite); err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	req := &NotificationRead{}
+
+	if r.ContentLength != 0 {
+		if err := s.decode(r, &req); err != nil {
+			s.error(errors.Wrap(err, errors.ErrInvalidRequest,
+				"unable to decode request"), w, r)
+
+			return
+		}
+	}
+
+	n, err := s.readNotifications(ctx, req.IDs)
+	if err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	if err := s.encode(w, r, map[string]int64{"updated": n}); err != nil {
+		s.error(err, w, r)
+	}
+}
+
+// getNotificationPreferencesHandler is the get handler used to retrieve the
+// notification preferences of the current user.
+func (s *Server) getNotificationPreferencesHandler(w http.ResponseWriter,
+	r *http.Request,
+) {
+	ctx := r.Context()
+
+	if err := s.checkScope(ctx, request.ScopeUserRead); err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	res, err := s.getNotificationPreferences(ctx)
+	if err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	if err := s.encode(w, r, res); err != nil {
+		s.error(err, w, r)
+	}
+}
+
+// putNotificationPreferencesHandler is the put handler used to update the
+// notification preferences of the current user.
+func (s *Server) putNotificationPreferencesHandler(w http.ResponseWriter,
+	r *http.Request,
+) {
+	ctx := r.Context()
+
+	if err := s.checkScope(ctx, request.ScopeUserWrite); err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	req := &NotificationPreferences{}
+
+	if err := s.decode(r, &req); err != nil {
+		s.error(errors.Wrap(err, errors.ErrInvalidRequest,
+			"unable to decode request"), w, r)
+
+		return
+	}
+
+	res, err := s.setNotificationPreferences(ctx, req)
+	if err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	if err := s.encode(w, r, res); err != nil {
+		s.error(err, w, r)
+	}
+}
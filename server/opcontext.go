@@ -0,0 +1,88 @@
+package server
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/dhaifley/game2d/request"
+)
+
+// opClass values are classes of operations, which determine the timeouts of
+// the contexts given to their database and outbound calls.
+type opClass int
+
+// Operation classes.
+const (
+	// opDB operations are database calls made outside of requests.
+	opDB opClass = iota
+
+	// opOutbound operations are calls to other services, such as webhooks,
+	// notifications and identity providers.
+	opOutbound
+
+	// opTask operations are single runs of periodic background tasks.
+	opTask
+
+	// opImport operations import games from, or back them up to,
+	// repositories.
+	opImport
+
+	// opPrompt operations send prompts to the AI, and save their results.
+	opPrompt
+)
+
+// opTimeout returns the configured timeout of an operation class.
+func (s *Server) opTimeout(c opClass) time.Duration {
+	switch c {
+	case opOutbound:
+		return s.cfg.ServerOutboundTimeout()
+	case opTask:
+		return s.cfg.ServerTaskTimeout()
+	case opImport:
+		return s.cfg.ImportTimeout()
+	case opPrompt:
+		return s.cfg.ServerPromptTimeout()
+	default:
+		return s.cfg.DBTimeout()
+	}
+}
+
+// opContext returns a context for an operation, derived from an existing
+// context, which is canceled with it, or after the timeout of the operation
+// class, whichever is first.
+func (s *Server) opContext(ctx context.Context,
+	c opClass,
+) (context.Context, context.CancelFunc) {
+	return s.trackContext(request.ContextWithTimeout(ctx, s.opTimeout(c)))
+}
+
+// detachedContext returns a context for an operation which continues after
+// the request which started it. It keeps the request values, but is only
+// canceled after the timeout of the operation class.
+func (s *Server) detachedContext(ctx context.Context,
+	c opClass,
+) (context.Context, context.CancelFunc) {
+	return s.trackContext(request.ContextReplaceTimeout(ctx, s.opTimeout(c)))
+}
+
+// trackContext counts an operation context as open until it is canceled, if
+// the server has a counter of open contexts, which is only set by tests.
+func (s *Server) trackContext(ctx context.Context,
+	cancel context.CancelFunc,
+) (context.Context, context.CancelFunc) {
+	n := s.openContexts.Load()
+	if n == nil {
+		return ctx, cancel
+	}
+
+	n.Add(1)
+
+	var once sync.Once
+
+	return ctx, func() {
+		once.Do(func() { n.Add(-1) })
+
+		cancel()
+	}
+}
@@ -0,0 +1,44 @@
+package server
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+
+	"github.com/dhaifley/game2d/config"
+)
+
+func TestOpContextTracking(t *testing.T) {
+	t.Parallel()
+
+	svr, err := NewServer(config.NewDefault(), nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+
+	// Contexts are not counted unless the server has a counter.
+	_, cancel := svr.opContext(ctx, opDB)
+
+	var open atomic.Int64
+
+	svr.SetOpenContexts(&open)
+
+	cancel()
+
+	_, oCancel := svr.opContext(ctx, opDB)
+	_, dCancel := svr.detachedContext(ctx, opOutbound)
+
+	if n := open.Load(); n != 2 {
+		t.Errorf("Expected open contexts: 2, got: %v", n)
+	}
+
+	oCancel()
+	oCancel()
+	dCancel()
+
+	if n := open.Load(); n != 0 {
+		t.Errorf("Expected open contexts: 0, got: %v", n)
+	}
+}
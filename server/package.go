@@ -0,0 +1,135 @@
+package server
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"html/template"
+	"net/http"
+
+	"github.com/dhaifley/game2d/errors"
+	"github.com/dhaifley/game2d/request"
+	"github.com/dhaifley/game2d/static"
+	"github.com/go-chi/chi/v5"
+)
+
+// playableGame returns the JSON encoded game definition used by the client to
+// play a game, which excludes account, prompt, and audit data.
+func playableGame(g *Game) (string, error) {
+	b, err := json.Marshal(&Game{
+		ID:          g.ID,
+		Name:        g.Name,
+		Version:     g.Version,
+		Description: g.Description,
+		Icon:        g.Icon,
+		Controls:    g.Controls,
+		Debug:       g.Debug,
+		W:           g.W,
+		H:           g.H,
+		Subject:     g.Subject,
+		Objects:     g.Objects,
+		Images:      g.Images,
+		Script:      g.Script,
+		Bindings:    g.Bindings,
+		Locales:     g.Locales,
+	})
+	if err != nil {
+		return "", errors.Wrap(err, errors.ErrServer,
+			"unable to encode game",
+			"id", g.ID.Value)
+	}
+
+	return string(b), nil
+}
+
+// packageGame creates a self-contained HTML file, which contains the game
+// definition and the WASM client runtime, so that the game can be played
+// without the game2d API.
+func (s *Server) packageGame(g *Game) ([]byte, error) {
+	if g == nil {
+		return nil, errors.New(errors.ErrInvalidRequest,
+			"missing game")
+	}
+
+	gb, err := playableGame(g)
+	if err != nil {
+		return nil, err
+	}
+
+	rb, err := static.FS.ReadFile("scripts/wasm_exec.js")
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrServer,
+			"unable to read client runtime script")
+	}
+
+	wb, err := static.FS.ReadFile("game2d.wasm")
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrServer,
+			"unable to read client runtime")
+	}
+
+	tb, err := static.FS.ReadFile("package.html")
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrServer,
+			"unable to read package template")
+	}
+
+	t, err := template.New("package").Parse(string(tb))
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrServer,
+			"unable to parse package template")
+	}
+
+	buf := &bytes.Buffer{}
+
+	if err := t.Execute(buf, map[string]any{
+		"Name":    g.Name.Value,
+		"Runtime": template.JS(rb),
+		"WASM":    base64.StdEncoding.EncodeToString(wb),
+		"Game":    gb,
+	}); err != nil {
+		return nil, errors.Wrap(err, errors.ErrServer,
+			"unable to create game package",
+			"id", g.ID.Value)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// getGamePackageHandler is the handler function for downloading a game as a
+// self-contained HTML file.
+func (s *Server) getGamePackageHandler(w http.ResponseWriter,
+	r *http.Request,
+) {
+	ctx := r.Context()
+
+	if err := s.checkScope(ctx, request.ScopeGamesRead); err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+
+	g, err := s.getGame(ctx, id)
+	if err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	b, err := s.packageGame(g)
+	if err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=UTF-8")
+	w.Header().Set("Content-Disposition",
+		`attachment; filename="game2d-`+g.ID.Value+`.html"`)
+
+	if _, err := w.Write(b); err != nil {
+		s.error(err, w, r)
+	}
+}
@@ -0,0 +1,361 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/dhaifley/game2d/cache"
+	"github.com/dhaifley/game2d/errors"
+	"github.com/dhaifley/game2d/request"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// Account plan names.
+const (
+	PlanFree = "free"
+	PlanPro  = "pro"
+	PlanTeam = "team"
+)
+
+// Entitlement names, for features which are only included in some plans.
+const (
+	EntitlementPublicGames = "public_games"
+)
+
+// Entitlements values describe the limits and features included in an account
+// plan. A game limit, storage limit or AI token limit of zero uses the server
+// default. Storage limits are in bytes.
+type Entitlements struct {
+	GameLimit    int64 `json:"game_limit"    yaml:"game_limit"`
+	StorageLimit int64 `json:"storage_limit" yaml:"storage_limit"`
+	AITokens     int64 `json:"ai_tokens"     yaml:"ai_tokens"`
+	PublicGames  bool  `json:"public_games"  yaml:"public_games"`
+}
+
+// Plans contains the entitlements included in each account plan.
+var Plans = map[string]Entitlements{
+	PlanFree: {
+		AITokens: 32000,
+	},
+	PlanPro: {
+		GameLimit:    100,
+		StorageLimit: 1024 * 1024 * 1024,
+		AITokens:     64000,
+		PublicGames:  true,
+	},
+	PlanTeam: {
+		GameLimit:    1000,
+		StorageLimit: 1024 * 1024 * 1024 * 10,
+		AITokens:     64000,
+		PublicGames:  true,
+	},
+}
+
+// AccountPlan values represent the plan of an account, the entitlements it
+// includes, and the current usage of those entitlements.
+type AccountPlan struct {
+	Plan         request.FieldString `json:"plan"         yaml:"plan"`
+	Entitlements *Entitlements       `json:"entitlements" yaml:"entitlements"`
+	GameCount    int64               `json:"game_count"   yaml:"game_count"`
+	StorageUsed  int64               `json:"storage_used" yaml:"storage_used"`
+}
+
+// accountPlan returns the plan of an account, or the default plan, if the
+// account has not been assigned a known plan.
+func (s *Server) accountPlan(a *Account) string {
+	if _, ok := Plans[a.Plan.Value]; ok {
+		return a.Plan.Value
+	}
+
+	if _, ok := Plans[s.cfg.PlanDefault()]; ok {
+		return s.cfg.PlanDefault()
+	}
+
+	return PlanFree
+}
+
+// accountEntitlements resolves the entitlements of an account from its plan.
+// A game limit set for the account is used if it exceeds the plan limit.
+// Accounts whose data is not stored in the shared collections can not share
+// games, since public and shared games are only found in those collections.
+func (s *Server) accountEntitlements(a *Account) *Entitlements {
+	e := Plans[s.accountPlan(a)]
+
+	if a.Tenancy.Value != "" && a.Tenancy.Value != TenancyShared {
+		e.PublicGames = false
+	}
+
+	if e.GameLimit == 0 {
+		e.GameLimit = s.cfg.GameLimitDefault()
+	}
+
+	e.GameLimit = max(e.GameLimit, a.GameLimit.Value)
+
+	if e.StorageLimit == 0 {
+		e.StorageLimit = s.cfg.StorageLimit()
+	}
+
+	return &e
+}
+
+// getEntitlements retrieves the entitlements of the current account.
+func (s *Server) getEntitlements(ctx context.Context) (*Entitlements, error) {
+	a, err := s.getAccount(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	if a == nil {
+		return nil, errors.New(errors.ErrNotFound,
+			"account not found")
+	}
+
+	return s.accountEntitlements(a), nil
+}
+
+// checkEntitlement returns an error if the plan of the current account does
+// not include a feature. The system account is entitled to all features.
+func (s *Server) checkEntitlement(ctx context.Context, name string) error {
+	aID, err := request.ContextAccountID(ctx)
+	if err != nil {
+		return errors.New(errors.ErrUnauthorized,
+			"unable to get account id from context")
+	}
+
+	if aID == request.SystemAccount {
+		return nil
+	}
+
+	e, err := s.getEntitlements(ctx)
+	if err != nil {
+		return err
+	}
+
+	entitled := false
+
+	switch name {
+	case EntitlementPublicGames:
+		entitled = e.PublicGames
+	}
+
+	if !entitled {
+		return errors.New(errors.ErrForbidden,
+			"account plan does not include feature",
+			"account_id", aID,
+			"entitlement", name).
+			WithReason(errors.ReasonPlanUpgradeRequired)
+	}
+
+	return nil
+}
+
+// countActiveGames counts the active games of an account.
+func (s *Server) countActiveGames(ctx context.Context,
+	aID string,
+) (int64, error) {
+	c, err := s.collection(ctx, "games")
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := c.CountDocuments(ctx, bson.M{
+		"account_id": aID,
+		"status":     request.StatusActive,
+	}, options.Count())
+	if err != nil {
+		return 0, errors.Wrap(err, errors.ErrDatabase,
+			"unable to count games",
+			"account_id", aID)
+	}
+
+	return n, nil
+}
+
+// checkGameLimit returns an error if the current account has reached the game
+// limit of its plan, and so can not create another game.
+func (s *Server) checkGameLimit(ctx context.Context) error {
+	aID, err := request.ContextAccountID(ctx)
+	if err != nil {
+		return errors.New(errors.ErrUnauthorized,
+			"unable to get account id from context")
+	}
+
+	e, err := s.getEntitlements(ctx)
+	if err != nil {
+		return err
+	}
+
+	if e.GameLimit <= 0 {
+		return nil
+	}
+
+	n, err := s.countActiveGames(ctx, aID)
+	if err != nil {
+		return err
+	}
+
+	if n >= e.GameLimit {
+		return errors.New(errors.ErrorRateLimit,
+			"account game limit reached",
+			"account_id", aID,
+			"game_limit", e.GameLimit,
+			"game_count", n).
+			WithReason(errors.ReasonGameLimitReached).
+			WithRateLimit(e.GameLimit, 0, time.Time{})
+	}
+
+	return nil
+}
+
+// getAccountPlan retrieves the plan of the current account and its usage.
+func (s *Server) getAccountPlan(ctx context.Context) (*AccountPlan, error) {
+	a, err := s.getAccount(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	if a == nil {
+		return nil, errors.New(errors.ErrNotFound,
+			"account not found")
+	}
+
+	n, err := s.countActiveGames(ctx, a.ID.Value)
+	if err != nil {
+		return nil, err
+	}
+
+	used, err := s.assetUsage(ctx, a.ID.Value)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AccountPlan{
+		Plan: request.FieldString{
+			Set: true, Valid: true, Value: s.accountPlan(a),
+		},
+		Entitlements: s.accountEntitlements(a),
+		GameCount:    n,
+		StorageUsed:  used,
+	}, nil
+}
+
+// setAccountPlan assigns a plan to an account.
+func (s *Server) setAccountPlan(ctx context.Context,
+	id, plan string,
+) (*Account, error) {
+	if _, ok := Plans[plan]; !ok {
+		return nil, errors.New(errors.ErrInvalidRequest,
+			"invalid plan",
+			"plan", plan)
+	}
+
+	var res *Account
+
+	if err := s.DB().Collection("accounts").FindOneAndUpdate(ctx,
+		bson.M{"id": id},
+		bson.M{"$set": bson.M{
+			"plan":       plan,
+			"updated_at": time.Now().Unix(),
+		}},
+		options.FindOneAndUpdate().SetProjection(bson.M{"_id": 0}).
+			SetReturnDocument(options.After)).
+		Decode(&res); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, errors.New(errors.ErrNotFound,
+				"account not found",
+				"id", id)
+		}
+
+		return nil, errors.Wrap(err, errors.ErrDatabase,
+			"unable to update account plan",
+			"id", id,
+			"plan", plan)
+	}
+
+	s.deleteCache(ctx, cache.KeyAccount(id))
+
+	return res, nil
+}
+
+// getAccountPlanHandler is the get handler used to retrieve the plan of the
+// current account.
+func (s *Server) getAccountPlanHandler(w http.ResponseWriter,
+	r *http.Request,
+) {
+	ctx := r.Context()
+
+	if err := s.checkScope(ctx, request.ScopeAccountRead); err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	res, err := s.getAccountPlan(ctx)
+	if err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	if err := s.encode(w, r, res); err != nil {
+		s.error(err, w, r)
+	}
+}
+
+// putAccountPlanHandler is the put handler used to assign a plan to the
+// current account. Only superusers are able to change plans.
+func (s *Server) putAccountPlanHandler(w http.ResponseWriter,
+	r *http.Request,
+) {
+	ctx := r.Context()
+
+	if err := s.checkScope(ctx, request.ScopeSuperuser); err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	aID, err := request.ContextAccountID(ctx)
+	if err != nil {
+		s.error(errors.New(errors.ErrUnauthorized,
+			"unable to get account id from context"), w, r)
+
+		return
+	}
+
+	req := &AccountPlan{}
+
+	if err := s.decode(r, &req); err != nil {
+		s.error(errors.Wrap(err, errors.ErrInvalidRequest,
+			"unable to decode request"), w, r)
+
+		return
+	}
+
+	a, err := s.setAccountPlan(ctx, aID, req.Plan.Value)
+	if err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	if err := s.enforcePlanLimits(ctx, a); err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	res, err := s.getAccountPlan(ctx)
+	if err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	if err := s.encode(w, r, res); err != nil {
+		s.error(err, w, r)
+	}
+}
@@ -3,6 +3,7 @@ package server
 import (
 	"context"
 	"encoding/json"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
@@ -12,25 +13,39 @@ import (
 	"github.com/anthropics/anthropic-sdk-go/option"
 	"github.com/dhaifley/game2d/errors"
 	"github.com/dhaifley/game2d/logger"
+	"github.com/dhaifley/game2d/notify"
 	"github.com/dhaifley/game2d/request"
 	"github.com/dhaifley/game2d/static"
 	"go.mongodb.org/mongo-driver/v2/bson"
 	"go.mongodb.org/mongo-driver/v2/mongo/options"
 )
 
-// Prompt values represent a single AI prompt and response.
+// Default prompt token limits, used unless the account sets its own.
+const (
+	defaultPromptMaxTokens = 64000
+	defaultPromptBudget    = 16000
+)
+
+// Prompt values represent a single AI prompt and response, and any reference
+// assets attached to the prompt.
 type Prompt struct {
-	Prompt   request.FieldString `bson:"prompt"   json:"prompt"   yaml:"prompt"`
-	Response request.FieldString `bson:"response" json:"response" yaml:"response"`
-	Thinking request.FieldString `bson:"thinking" json:"thinking" yaml:"thinking"`
+	Prompt      request.FieldString `bson:"prompt"      json:"prompt"                yaml:"prompt"`
+	Response    request.FieldString `bson:"response"    json:"response"              yaml:"response"`
+	Thinking    request.FieldString `bson:"thinking"    json:"thinking"              yaml:"thinking"`
+	Attachments []PromptAttachment  `bson:"attachments" json:"attachments,omitempty" yaml:"attachments,omitempty"`
 }
 
-// Prompts values contain the AI prompt data for a game.
+// Prompts values contain the AI prompt data for a game. Games only keep their
+// current prompt, and the ID of their conversation, the history of which is
+// stored apart from them. The history is only included for the prompts sent
+// to the AI service, and for games created before histories were stored apart
+// from them.
 type Prompts struct {
-	Current Prompt              `bson:"current" json:"current" yaml:"current"`
-	History []Prompt            `bson:"history" json:"history" yaml:"history"`
-	Error   request.FieldString `bson:"error"   json:"error"   yaml:"error"`
-	GameID  request.FieldString `bson:"game_id" json:"game_id" yaml:"game_id"`
+	Current   Prompt              `bson:"current"    json:"current"           yaml:"current"`
+	History   []Prompt            `bson:"history"    json:"history,omitempty" yaml:"history,omitempty"`
+	HistoryID request.FieldString `bson:"history_id" json:"history_id"        yaml:"history_id"`
+	Error     request.FieldString `bson:"error"      json:"error"             yaml:"error"`
+	GameID    request.FieldString `bson:"game_id"    json:"game_id"           yaml:"game_id"`
 }
 
 // Copy creates a copy of the Prompts struct.
@@ -40,19 +55,27 @@ func (p *Prompts) Copy() *Prompts {
 	}
 
 	return &Prompts{
-		Current: p.Current,
-		History: p.History,
-		Error:   p.Error,
-		GameID:  p.GameID,
+		Current:   p.Current,
+		History:   slices.Clone(p.History),
+		HistoryID: p.HistoryID,
+		Error:     p.Error,
+		GameID:    p.GameID,
 	}
 }
 
-// promptsToFieldJSON converts a Prompts struct to a FieldJSON value.
+// promptsToFieldJSON converts a Prompts struct to a FieldJSON value, without
+// the history, if it is stored apart from the game.
 func promptsToFieldJSON(p *Prompts) (request.FieldJSON, error) {
 	if p == nil {
 		return request.FieldJSON{}, nil
 	}
 
+	if p.HistoryID.Value != "" {
+		p = p.Copy()
+
+		p.History = nil
+	}
+
 	b, err := json.Marshal(p)
 	if err != nil {
 		return request.FieldJSON{}, err
@@ -89,6 +112,51 @@ func promptsFromFieldJSON(f request.FieldJSON) (*Prompts, error) {
 	return p, nil
 }
 
+// nextPrompts creates the prompts for the next prompt about a game, by moving
+// the current prompt of the game into its history, and trimming the history to
+// the configured size. Games without a stored history start a new one.
+func (s *Server) nextPrompts(ctx context.Context,
+	g *Game,
+	current Prompt,
+) (*Prompts, error) {
+	prompts, err := s.gamePrompts(ctx, g)
+	if err != nil {
+		return nil, err
+	}
+
+	if prompts.HistoryID.Value == "" {
+		prompts.HistoryID = newHistoryID()
+	}
+
+	if prompts.Current.Prompt.Value != "" {
+		hp := prompts.Current
+
+		hp.Thinking = request.FieldString{}
+		hp.Attachments = nil
+		prompts.History = append(prompts.History, hp)
+	}
+
+	for len(prompts.History) > 1 {
+		hb, err := json.Marshal(prompts.History)
+		if err != nil {
+			return nil, errors.Wrap(err, errors.ErrServer,
+				"unable to encode prompt history",
+				"game_id", g.ID.Value)
+		}
+
+		if len(hb) <= int(s.cfg.PromptHistorySize()) {
+			break
+		}
+
+		prompts.History = prompts.History[1:]
+	}
+
+	prompts.Current = current
+	prompts.Error = request.FieldString{}
+
+	return prompts, nil
+}
+
 // sendPrompt sends a prompt to the AI service and updates the game state with
 // the response. It is called as a goroutine to run the the background, and will
 // block until the prompt is complete.
@@ -103,6 +171,39 @@ func (s *Server) sendPrompt(ctx context.Context, g *Game, prompts *Prompts) {
 		return
 	}
 
+	defer func() {
+		ad := map[string]any{}
+
+		if prompts.Error.Value != "" {
+			ad["error"] = prompts.Error.Value
+		}
+
+		s.recordActivity(ctx, ActivityPrompt, g.ID.Value, ad)
+
+		s.runHooks(ctx, HookPromptCompleted, g.ID.Value, ad)
+
+		s.triggerAutomations(ctx, AutomationPromptCompleted, g.ID.Value, ad)
+
+		uID, _ := request.ContextUserID(ctx)
+
+		typ, msg := NotificationPromptCompleted, &notify.Message{
+			Title: "Game prompt completed",
+			Body: "The prompt for the game " + strconv.Quote(g.Name.Value) +
+				" has completed.",
+		}
+
+		if prompts.Error.Value != "" {
+			typ, msg = NotificationPromptFailed, &notify.Message{
+				Title: "Game prompt failed",
+				Body: "The prompt for the game " +
+					strconv.Quote(g.Name.Value) + " has failed: " +
+					prompts.Error.Value,
+			}
+		}
+
+		s.notifyUser(ctx, uID, typ, g.ID.Value, msg)
+	}()
+
 	updateGame := func(g *Game) {
 		if _, err := s.updateGame(ctx, g); err != nil {
 			s.log.Log(ctx, logger.LvlError,
@@ -153,7 +254,15 @@ func (s *Server) sendPrompt(ctx context.Context, g *Game, prompts *Prompts) {
 		return
 	}
 
-	err := p.Prompt(ctx, prompts, g)
+	// The response stream has a shorter timeout than the prompt, so that
+	// there is still time to save the result if it times out.
+	sctx, cancel := request.ContextWithTimeout(ctx,
+		s.cfg.ServerPromptStreamTimeout())
+
+	err := p.Prompt(sctx, prompts, g)
+
+	cancel()
+
 	if err != nil {
 		prompts.Error = request.FieldString{
 			Set: true, Valid: true, Value: err.Error(),
@@ -183,7 +292,11 @@ func (s *Server) updateGamePrompts(ctx context.Context,
 ) context.CancelFunc {
 	ctx, cancel := context.WithCancel(ctx)
 
+	s.jobs.Add(1)
+
 	go func(ctx context.Context) {
+		defer s.jobs.Done()
+
 		tick := time.NewTimer(time.Second)
 
 		for {
@@ -206,6 +319,9 @@ func (s *Server) updateGamePrompts(ctx context.Context,
 					wg.Add(1)
 
 					go func(ctx context.Context, accountID string) {
+						ctx, cancel := s.opContext(ctx, opTask)
+						defer cancel()
+
 						ctx = context.WithValue(ctx, request.CtxKeyAccountID,
 							accountID)
 						ctx = context.WithValue(ctx, request.CtxKeyUserID,
@@ -257,7 +373,12 @@ func (s *Server) updatePrompts(ctx context.Context) (int, error) {
 
 	pro := bson.M{"id": 1}
 
-	cur, err := s.DB().Collection("games").Find(ctx, f,
+	c, err := s.collection(ctx, "games")
+	if err != nil {
+		return 0, err
+	}
+
+	cur, err := c.Find(ctx, f,
 		options.Find().SetProjection(pro))
 	if err != nil {
 		return 0, errors.Wrap(err, errors.ErrDatabase,
@@ -327,16 +448,25 @@ func (s *Server) initPrompter() error {
 			return nil
 		}
 
-		maxTokens := int64(64000)
+		maxTokens := int64(defaultPromptMaxTokens)
 		if a.AIMaxTokens.Value > 0 {
 			maxTokens = a.AIMaxTokens.Value
 		}
 
-		budgetTokens := int64(16000)
+		if e := s.accountEntitlements(a); e.AITokens > 0 {
+			maxTokens = min(maxTokens, e.AITokens)
+		}
+
+		budgetTokens := int64(defaultPromptBudget)
 		if a.AIThinkingBudget.Value > 0 {
 			budgetTokens = a.AIThinkingBudget.Value
 		}
 
+		// The thinking budget must be less than the maximum tokens.
+		if budgetTokens >= maxTokens {
+			budgetTokens = maxTokens / 2
+		}
+
 		return NewAnthropicPrompter(s, a.AIAPIKey.Value,
 			maxTokens, budgetTokens)
 	}
@@ -408,20 +538,16 @@ func (p *anthropicPrompter) Prompt(ctx context.Context,
 		Set: true, Valid: true, Value: "",
 	}
 
-	gameFile, err := static.FS.ReadFile("game.json")
+	game.Prompts = request.FieldJSON{}
+
+	system, err := promptSystem()
 	if err != nil {
-		return errors.Wrap(err, errors.ErrServer,
-			"unable to read game JSON schema source",
-			"file", "game.json")
+		return err
 	}
 
-	game.Prompts = request.FieldJSON{}
-
-	gb, err := json.MarshalIndent(game, "  ", "  ")
+	messages, err := promptMessages(prompts, game)
 	if err != nil {
-		return errors.Wrap(err, errors.ErrServer,
-			"unable to encode game for prompt",
-			"game_id", game.ID.Value)
+		return err
 	}
 
 	select {
@@ -430,36 +556,7 @@ func (p *anthropicPrompter) Prompt(ctx context.Context,
 	default:
 	}
 
-	messages := []anthropic.MessageParam{}
-
-	for _, m := range prompts.History {
-		if m.Prompt.Set && m.Prompt.Valid {
-			messages = append(messages, anthropic.NewUserMessage(
-				anthropic.NewTextBlock(m.Prompt.Value)))
-		}
-
-		if m.Response.Set && m.Response.Valid {
-			messages = append(messages, anthropic.NewAssistantMessage(
-				anthropic.NewTextBlock(m.Response.Value)))
-		}
-	}
-
-	messages = append(messages, anthropic.NewUserMessage(
-		anthropic.NewTextBlock("Here is the current game definition:\n"+
-			"\n<document source=\"game2d.json\">\n"+string(gb)+
-			"\n</document>\n\n"+prompts.Current.Prompt.Value)))
-
-	count, err := p.cli.Messages.CountTokens(ctx,
-		anthropic.MessageCountTokensParams{
-			Model: anthropic.F(anthropic.ModelClaude3_7SonnetLatest),
-			Thinking: anthropic.F(anthropic.ThinkingConfigParamUnion(
-				&anthropic.ThinkingConfigEnabledParam{
-					BudgetTokens: anthropic.F(p.budget),
-					Type: anthropic.F(
-						anthropic.ThinkingConfigEnabledTypeEnabled),
-				})),
-			Messages: anthropic.F(messages),
-		})
+	count, err := p.countTokens(ctx, system, messages)
 	if err != nil {
 		return errors.Wrap(err, errors.ErrServer,
 			"unable to count tokens for prompt",
@@ -467,20 +564,20 @@ func (p *anthropicPrompter) Prompt(ctx context.Context,
 			"prompt", prompts.Current.Prompt.Value)
 	}
 
-	prompts.Current.Thinking.Value += strconv.FormatInt(count.InputTokens, 10) +
+	prompts.Current.Thinking.Value += strconv.FormatInt(count, 10) +
 		" tokens input\n\n"
 
 	p.s.log.Log(ctx, logger.LvlDebug,
 		"prompt token count",
 		"game_id", game.ID.Value,
 		"prompt", prompts.Current.Prompt.Value,
-		"input_tokens", count.InputTokens)
+		"input_tokens", count)
 
 	if err := updateGame(game, prompts); err != nil {
 		return errors.Wrap(err, errors.ErrServer,
 			"unable to update game with prompt token count",
 			"game_id", game.ID.Value,
-			"count", count.InputTokens)
+			"count", count)
 	}
 
 	select {
@@ -499,74 +596,7 @@ func (p *anthropicPrompter) Prompt(ctx context.Context,
 					anthropic.ThinkingConfigEnabledTypeEnabled),
 			})),
 		Messages: anthropic.F(messages),
-		System: anthropic.F([]anthropic.TextBlockParam{
-			anthropic.NewTextBlock(`You are an expert 2D game developer and an
-expert in the Lua programming language. You work with game2d, a framework which
-let's you express 2D games as game definitions in a JSON format. The following
-document contains the JSON schema of the game definition you will create. You
-should reference this schema carefully when generating the game definition
-to make sure it will work when run using the client. The description of the keys
-field contains the key codes used by the game client which must be used in the
-game Lua script to recognize which keys are being pressed by the user. There is
-only keyboard input in the game client, there is no mouse or other input.` +
-				"\n\n<document source=\"game.json\">\n" +
-				string(gameFile) + "\n</document>\n" +
-				`The JSON schema for the game definition contains a map, keyed
-by id, of “objects”, another or “images”, and also a “script” field.
-
-Objects are the entities which comprise the game, and contain predefined
-fields for identification, position and other things. They also contain a
-data map field for use storing game data between game loop update phases. Each
-object also has an image attribute, containing the id of the image in the
-game.images map that is rendered for the object during the game loop draw
-phase.
-
-The game definition contains a "subject" field, which is just a special object
-that is used to represent the player in the game. It is identical to other game
-objects, but is always rendered last in the game loop draw phase.
-
-Images are assets used by the client game engine, and are rendered for objects
-during the game loop draw phase. Images contain id and name fields, and data
-fields containing base64 encoded SVG image data. This data is read by the game
-client SVG reader and rasterized into sprites for use in the game. The client
-SVG reader uses the Go github.com/srwiley/oksvg library and the ReadIconStream()
-function to read and the SVG images. This means only a limited subset of SVG is
-supported. Restrict all SVG images to only use simple rectangles, circles, and
-paths. No text or other SVG objects should be used.
-
-The game "script" field is a string which contains the base64 encoded Lua script
-which is run during the game loop update phase. The Lua game script must contain
-a single, global Update function. If any other functions are needed, they must
-be defined as global and their name must begin with a capital letter. The
-Update function is called once per game loop update phase, and is used to
-update the game state. The Update function must accept a single parameter named
-"game", which is a Lua table containing the game definition. It also returns the
-same game table, after updating its contents. The game engine client updates the
-game state based on the contents of this returned value.
-
-You must create one of these game definitions based on the user's prompt. Your
-response must include the created game definition. The game definition must be
-at the end of the response and must be immediately preceded by the text "` +
-				"```" + `game definition\n" and immediately followed by the text
-"\n` + "```" + `\n". The game definition "id" field must be a UUID and can be
-random. The game definition should also contain a "name" field, a "description"
-field, which contains the game controls and features, and add an "icon" field,
-which contains a base64 encoded SVG image of an icon for the game.
-
-The history of messages between you and the user has had any previous game
-definitions replaced with the text "{{game definition}}". But, the current game
-definition is always appended to the most recent user message. This most recent
-definition, can be reviewed if the user is reporting any errors in the game. Do
-not rewrite the game from scratch if you can learn from, and improve the game
-definition submitted with the users prompt.
-
-Your responses to the user will be rendered in plain monospaced text. Do not
-use any markdown in your responses.
-
-Think through the process of creating the game definition very carefully. Make
-sure it is complete and all SVG images and the Lua game script are free of
-errors and correctly encoded and formatted.`),
-		}),
+		System:   anthropic.F(system),
 	})
 
 	message := anthropic.Message{}
@@ -615,6 +645,14 @@ errors and correctly encoded and formatted.`),
 			"prompt", prompts.Current.Prompt.Value)
 	}
 
+	if message.StopReason == anthropic.MessageStopReasonMaxTokens {
+		return errors.New(errors.ErrPrompt,
+			"prompt response exceeded the maximum tokens",
+			"max_tokens", p.max,
+			"prompt", prompts.Current.Prompt.Value).
+			WithReason(errors.ReasonPromptBudgetExceeded)
+	}
+
 	if len(message.Content) == 0 {
 		return errors.New(errors.ErrPrompt,
 			"prompt response is empty",
@@ -688,6 +726,179 @@ errors and correctly encoded and formatted.`),
 	return nil
 }
 
+// countTokens counts the input tokens of a prompt, including the system prompt
+// and the message history.
+func (p *anthropicPrompter) countTokens(ctx context.Context,
+	system []anthropic.TextBlockParam,
+	messages []anthropic.MessageParam,
+) (int64, error) {
+	count, err := p.cli.Messages.CountTokens(ctx,
+		anthropic.MessageCountTokensParams{
+			Model: anthropic.F(anthropic.ModelClaude3_7SonnetLatest),
+			Thinking: anthropic.F(anthropic.ThinkingConfigParamUnion(
+				&anthropic.ThinkingConfigEnabledParam{
+					BudgetTokens: anthropic.F(p.budget),
+					Type: anthropic.F(
+						anthropic.ThinkingConfigEnabledTypeEnabled),
+				})),
+			System: anthropic.F(
+				anthropic.MessageCountTokensParamsSystemUnion(
+					anthropic.MessageCountTokensParamsSystemArray(system))),
+			Messages: anthropic.F(messages),
+		})
+	if err != nil {
+		return 0, err
+	}
+
+	return count.InputTokens, nil
+}
+
+// promptMessages creates the messages sent for a prompt, from the prompt
+// history, and the current prompt with the game definition appended, following
+// any attachments of the current prompt.
+func promptMessages(prompts *Prompts,
+	game *Game,
+) ([]anthropic.MessageParam, error) {
+	pg := *game
+
+	pg.Prompts = request.FieldJSON{}
+
+	gb, err := json.MarshalIndent(&pg, "  ", "  ")
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrServer,
+			"unable to encode game for prompt",
+			"game_id", game.ID.Value)
+	}
+
+	messages := []anthropic.MessageParam{}
+
+	for _, m := range prompts.History {
+		if m.Prompt.Set && m.Prompt.Valid {
+			messages = append(messages, anthropic.NewUserMessage(
+				anthropic.NewTextBlock(m.Prompt.Value)))
+		}
+
+		if m.Response.Set && m.Response.Valid {
+			messages = append(messages, anthropic.NewAssistantMessage(
+				anthropic.NewTextBlock(m.Response.Value)))
+		}
+	}
+
+	blocks := append(promptAttachmentBlocks(prompts.Current.Attachments),
+		anthropic.NewTextBlock("Here is the current game definition:\n"+
+			"\n<document source=\"game2d.json\">\n"+string(gb)+
+			"\n</document>\n\n"+prompts.Current.Prompt.Value))
+
+	messages = append(messages, anthropic.NewUserMessage(blocks...))
+
+	return messages, nil
+}
+
+// promptSystem returns the system prompt sent with every game prompt, which
+// contains the game definition schema and the Lua API description.
+func promptSystem() ([]anthropic.TextBlockParam, error) {
+	gameFile, err := static.FS.ReadFile("game.json")
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrServer,
+			"unable to read game JSON schema source",
+			"file", "game.json")
+	}
+
+	luaFile, err := luaAPIDocument()
+	if err != nil {
+		return nil, err
+	}
+
+	return []anthropic.TextBlockParam{
+		anthropic.NewTextBlock(`You are an expert 2D game developer and an
+expert in the Lua programming language. You work with game2d, a framework which
+let's you express 2D games as game definitions in a JSON format. The following
+document contains the JSON schema of the game definition you will create. You
+should reference this schema carefully when generating the game definition
+to make sure it will work when run using the client. The description of the keys
+field contains the key codes used by the game client which must be used in the
+game Lua script to recognize which keys are being pressed by the user. There is
+only keyboard input in the game client, there is no mouse or other input.` +
+			"\n\n<document source=\"game.json\">\n" +
+			string(gameFile) + "\n</document>\n" +
+			`The following document describes the Lua environment in which
+the game Lua script is run by the game client, including the fields of the game
+table, the key codes, and the only Lua library functions which are available.` +
+			"\n\n<document source=\"lua-api.json\">\n" +
+			string(luaFile) + "\n</document>\n" +
+			`The JSON schema for the game definition contains a map, keyed
+by id, of “objects”, another or “images”, and also a “script” field.
+
+Objects are the entities which comprise the game, and contain predefined
+fields for identification, position and other things. They also contain a
+data map field for use storing game data between game loop update phases. Each
+object also has an image attribute, containing the id of the image in the
+game.images map that is rendered for the object during the game loop draw
+phase.
+
+The game definition contains a "subject" field, which is just a special object
+that is used to represent the player in the game. It is identical to other game
+objects, but is always rendered last in the game loop draw phase.
+
+Images are assets used by the client game engine, and are rendered for objects
+during the game loop draw phase. Images contain id and name fields, and data
+fields containing base64 encoded SVG image data. This data is read by the game
+client SVG reader and rasterized into sprites for use in the game. The client
+SVG reader uses the Go github.com/srwiley/oksvg library and the ReadIconStream()
+function to read and the SVG images. This means only a limited subset of SVG is
+supported. Restrict all SVG images to only use simple rectangles, circles, and
+paths. No text or other SVG objects should be used.
+
+The game "script" field is a string which contains the base64 encoded Lua script
+which is run during the game loop update phase. The Lua game script must contain
+a single, global Update function. If any other functions are needed, they must
+be defined as global and their name must begin with a capital letter. The
+Update function is called once per game loop update phase, and is used to
+update the game state. The Update function must accept a single parameter named
+"game", which is a Lua table containing the game definition. It also returns the
+same game table, after updating its contents. The game engine client updates the
+game state based on the contents of this returned value.
+
+The game definition "bindings" field maps the name of each game action, such as
+"jump" or "left", to a list of the names of the keys bound to it by default. The
+game table passed to the Update function contains an "actions" table, which has
+a true value for each action with a bound key being pressed. Scripts should use
+these actions, rather than the raw key codes in the "keys" table, so that
+players are able to remap the keys used to play the game.
+
+The game definition "locales" field maps language tags, such as "en" or "fr",
+to the text of the game in that language, keyed by text key. The global
+GetText function takes a text key, and returns the text for the language chosen
+by the player, falling back to English, and then to the key itself. Scripts
+should use GetText for any text shown to the player, so that games are able to
+support multiple languages.
+
+You must create one of these game definitions based on the user's prompt. Your
+response must include the created game definition. The game definition must be
+at the end of the response and must be immediately preceded by the text "` +
+			"```" + `game definition\n" and immediately followed by the text
+"\n` + "```" + `\n". The game definition "id" field must be a UUID and can be
+random. The game definition should also contain a "name" field, a "description"
+field, which contains the game features, a "controls" field, which contains
+instructions for the game controls, and add an "icon" field, which contains a
+base64 encoded SVG image of an icon for the game.
+
+The history of messages between you and the user has had any previous game
+definitions replaced with the text "{{game definition}}". But, the current game
+definition is always appended to the most recent user message. This most recent
+definition, can be reviewed if the user is reporting any errors in the game. Do
+not rewrite the game from scratch if you can learn from, and improve the game
+definition submitted with the users prompt.
+
+Your responses to the user will be rendered in plain monospaced text. Do not
+use any markdown in your responses.
+
+Think through the process of creating the game definition very carefully. Make
+sure it is complete and all SVG images and the Lua game script are free of
+errors and correctly encoded and formatted.`),
+	}, nil
+}
+
 // mockPrompter is a mock implementation of the Prompter interface.
 type mockPrompter struct {
 	s     *Server
@@ -716,13 +927,6 @@ func (m *mockPrompter) Prompt(ctx context.Context,
 ) error {
 	res := "The AI has responded."
 
-	if prompts.Current.Prompt.Value != "" {
-		hp := prompts.Current
-
-		hp.Thinking = request.FieldString{Set: true}
-		prompts.History = append(prompts.History, hp)
-	}
-
 	prompts.Current = Prompt{
 		Prompt:   prompts.Current.Prompt,
 		Response: request.FieldString{Set: true, Valid: true, Value: res},
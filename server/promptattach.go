@@ -0,0 +1,217 @@
+package server
+
+import (
+	"encoding/base64"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/dhaifley/game2d/errors"
+	"github.com/dhaifley/game2d/request"
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	// maxPromptAttachments is the maximum number of attachments of a prompt.
+	maxPromptAttachments = 4
+
+	// maxPromptAttachmentSize is the maximum size of an attachment of a
+	// prompt, before it is encoded.
+	maxPromptAttachmentSize = 256 * 1024
+)
+
+// promptImageTypes are the media types of attachments which are sent to the AI
+// service as images. Attachments of any other allowed type are sent as text.
+var promptImageTypes = map[string]bool{
+	"image/png":  true,
+	"image/jpeg": true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+// promptTextTypes are the media types of attachments which are sent to the AI
+// service as text, such as example Lua scripts.
+var promptTextTypes = map[string]bool{
+	"text/plain": true,
+	"text/x-lua": true,
+}
+
+// PromptAttachment values represent reference assets attached to a prompt,
+// such as images, or example Lua scripts. The data is base64 encoded.
+type PromptAttachment struct {
+	Name string `bson:"name" json:"name" yaml:"name"`
+	Type string `bson:"type" json:"type" yaml:"type"`
+	Data string `bson:"data" json:"data" yaml:"data"`
+}
+
+// Validate checks that the value contains valid data.
+func (a *PromptAttachment) Validate() error {
+	if a.Name == "" {
+		return errors.New(errors.ErrInvalidRequest,
+			"missing attachment name")
+	}
+
+	if !promptImageTypes[a.Type] && !promptTextTypes[a.Type] {
+		return errors.New(errors.ErrInvalidRequest,
+			"invalid attachment type",
+			"name", a.Name,
+			"type", a.Type)
+	}
+
+	b, err := base64.StdEncoding.DecodeString(a.Data)
+	if err != nil {
+		return errors.Wrap(err, errors.ErrInvalidRequest,
+			"invalid attachment data",
+			"name", a.Name)
+	}
+
+	if len(b) == 0 || len(b) > maxPromptAttachmentSize {
+		return errors.New(errors.ErrInvalidRequest,
+			"invalid attachment size",
+			"name", a.Name,
+			"size", len(b),
+			"max", maxPromptAttachmentSize)
+	}
+
+	return nil
+}
+
+// validatePromptAttachments checks that the attachments of a prompt are valid.
+func validatePromptAttachments(as []PromptAttachment) error {
+	if len(as) > maxPromptAttachments {
+		return errors.New(errors.ErrInvalidRequest,
+			"too many attachments",
+			"attachments", len(as),
+			"max", maxPromptAttachments)
+	}
+
+	for i := range as {
+		if err := as[i].Validate(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// attachmentType returns the media type of an uploaded attachment, from its
+// file name or its part header, or else from its contents.
+func attachmentType(fh *multipart.FileHeader, b []byte) string {
+	if strings.EqualFold(path.Ext(fh.Filename), ".lua") {
+		return "text/x-lua"
+	}
+
+	ct := fh.Header.Get("Content-Type")
+
+	if mt, _, err := mime.ParseMediaType(ct); err == nil &&
+		(promptImageTypes[mt] || promptTextTypes[mt]) {
+		return mt
+	}
+
+	mt, _, _ := mime.ParseMediaType(http.DetectContentType(b))
+
+	return mt
+}
+
+// uploadedPrompts assembles the prompts of a game from a multipart form. The
+// prompt part contains the text of the prompt, and any attachment parts
+// contain its attachments.
+func uploadedPrompts(form *multipart.Form, gameID string) (*Prompts, error) {
+	req := &Prompts{
+		GameID: request.FieldString{Set: true, Valid: true, Value: gameID},
+	}
+
+	if v := form.Value["prompt"]; len(v) > 0 {
+		req.Current.Prompt = request.FieldString{
+			Set: true, Valid: true, Value: v[0],
+		}
+	}
+
+	for _, fh := range form.File["attachment"] {
+		if fh.Size > maxPromptAttachmentSize {
+			return nil, errors.New(errors.ErrInvalidRequest,
+				"invalid attachment size",
+				"name", fh.Filename,
+				"size", fh.Size,
+				"max", maxPromptAttachmentSize)
+		}
+
+		b, err := readUploadPart(fh)
+		if err != nil {
+			return nil, err
+		}
+
+		req.Current.Attachments = append(req.Current.Attachments,
+			PromptAttachment{
+				Name: path.Base(fh.Filename),
+				Type: attachmentType(fh, b),
+				Data: base64.StdEncoding.EncodeToString(b),
+			})
+	}
+
+	return req, nil
+}
+
+// promptAttachmentBlocks creates the content blocks sent to the AI service for
+// the attachments of a prompt. Images are sent as image blocks, and text as
+// documents, named by the attachment names.
+func promptAttachmentBlocks(as []PromptAttachment,
+) []anthropic.ContentBlockParamUnion {
+	res := []anthropic.ContentBlockParamUnion{}
+
+	for _, a := range as {
+		if promptImageTypes[a.Type] {
+			res = append(res, anthropic.NewImageBlockBase64(a.Type, a.Data))
+
+			continue
+		}
+
+		b, err := base64.StdEncoding.DecodeString(a.Data)
+		if err != nil {
+			continue
+		}
+
+		res = append(res, anthropic.NewTextBlock(
+			"Here is an attached reference file:\n"+
+				"\n<document source=\""+a.Name+"\">\n"+string(b)+
+				"\n</document>\n"))
+	}
+
+	return res
+}
+
+// postGamePromptHandler is the post handler used to prompt the AI about a
+// game using a multipart form, consisting of the prompt, and any reference
+// images or example Lua scripts attached to it.
+func (s *Server) postGamePromptHandler(w http.ResponseWriter,
+	r *http.Request,
+) {
+	ctx := r.Context()
+
+	if err := s.checkScope(ctx, request.ScopeGamesWrite); err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	if err := r.ParseMultipartForm(DefaultUploadMemory); err != nil {
+		s.error(errors.Wrap(err, errors.ErrInvalidRequest,
+			"unable to decode multipart request"), w, r)
+
+		return
+	}
+
+	defer r.MultipartForm.RemoveAll()
+
+	req, err := uploadedPrompts(r.MultipartForm, chi.URLParam(r, "id"))
+	if err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	s.servePrompt(w, r, req)
+}
@@ -0,0 +1,260 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/dhaifley/game2d/errors"
+	"github.com/dhaifley/game2d/request"
+	"github.com/go-chi/chi/v5"
+)
+
+// Prompt prices, in US dollars per million tokens, of the model used for
+// prompts. Thinking tokens are billed as output tokens.
+const (
+	promptInputPrice  = 3.0
+	promptOutputPrice = 15.0
+)
+
+// PromptEstimate values are the estimated token counts and costs of a prompt
+// about a game. Input tokens are counted by the AI provider, while output
+// tokens are estimated from the size of the game definition, the size of the
+// previous responses and the thinking budget. The maximum cost is the cost if
+// the response uses the maximum output tokens.
+type PromptEstimate struct {
+	GameID          string  `json:"game_id"           yaml:"game_id"`
+	Model           string  `json:"model"             yaml:"model"`
+	InputTokens     int64   `json:"input_tokens"      yaml:"input_tokens"`
+	OutputTokens    int64   `json:"output_tokens"     yaml:"output_tokens"`
+	MaxOutputTokens int64   `json:"max_output_tokens" yaml:"max_output_tokens"`
+	InputCost       float64 `json:"input_cost"        yaml:"input_cost"`
+	OutputCost      float64 `json:"output_cost"       yaml:"output_cost"`
+	Cost            float64 `json:"cost"              yaml:"cost"`
+	MaxCost         float64 `json:"max_cost"          yaml:"max_cost"`
+	Currency        string  `json:"currency"          yaml:"currency"`
+}
+
+// price sets the costs of the estimate from its token counts.
+func (e *PromptEstimate) price() {
+	cost := func(tokens int64, price float64) float64 {
+		// Costs are rounded to hundredths of a cent.
+		return math.Round(float64(tokens)*price/1e6*1e4) / 1e4
+	}
+
+	e.InputCost = cost(e.InputTokens, promptInputPrice)
+	e.OutputCost = cost(e.OutputTokens, promptOutputPrice)
+	e.Cost = e.InputCost + e.OutputCost
+	e.MaxCost = e.InputCost + cost(e.MaxOutputTokens, promptOutputPrice)
+	e.Currency = "USD"
+}
+
+// PromptEstimator values are prompters which are able to estimate the tokens
+// used by a prompt, without sending it.
+type PromptEstimator interface {
+	Estimate(ctx context.Context,
+		prompts *Prompts,
+		game *Game,
+	) (*PromptEstimate, error)
+}
+
+// estimateOutputTokens estimates the output tokens of a prompt response. The
+// response is expected to contain a new game definition, similar in size to
+// the current one, text similar in size to the previous responses, and the
+// full thinking budget.
+func estimateOutputTokens(prompts *Prompts,
+	game *Game,
+	budget, maxTokens int64,
+) (int64, error) {
+	pg := *game
+
+	pg.Prompts = request.FieldJSON{}
+
+	b, err := json.Marshal(&pg)
+	if err != nil {
+		return 0, errors.Wrap(err, errors.ErrServer,
+			"unable to encode game for prompt estimate",
+			"game_id", game.ID.Value)
+	}
+
+	n := int64(len(b))/promptBytesPerToken + budget
+
+	var rb, rn int64
+
+	for _, h := range prompts.History {
+		if h.Response.Value != "" {
+			rb += int64(len(h.Response.Value))
+			rn++
+		}
+	}
+
+	if rn > 0 {
+		n += rb / rn / promptBytesPerToken
+	}
+
+	return min(n, maxTokens), nil
+}
+
+// Estimate counts the input tokens of a prompt using the Anthropic API, and
+// estimates its output tokens.
+func (p *anthropicPrompter) Estimate(ctx context.Context,
+	prompts *Prompts,
+	game *Game,
+) (*PromptEstimate, error) {
+	system, err := promptSystem()
+	if err != nil {
+		return nil, err
+	}
+
+	messages, err := promptMessages(prompts, game)
+	if err != nil {
+		return nil, err
+	}
+
+	count, err := p.countTokens(ctx, system, messages)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrServer,
+			"unable to count tokens for prompt",
+			"game_id", game.ID.Value,
+			"prompt", prompts.Current.Prompt.Value)
+	}
+
+	out, err := estimateOutputTokens(prompts, game, p.budget, p.max)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PromptEstimate{
+		Model:           string(anthropic.ModelClaude3_7SonnetLatest),
+		InputTokens:     count,
+		OutputTokens:    out,
+		MaxOutputTokens: p.max,
+	}, nil
+}
+
+// Estimate estimates the tokens of a prompt from the size of its messages,
+// without counting them.
+func (m *mockPrompter) Estimate(ctx context.Context,
+	prompts *Prompts,
+	game *Game,
+) (*PromptEstimate, error) {
+	messages, err := promptMessages(prompts, game)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := json.Marshal(messages)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrServer,
+			"unable to encode prompt messages",
+			"game_id", game.ID.Value)
+	}
+
+	out, err := estimateOutputTokens(prompts, game, defaultPromptBudget,
+		defaultPromptMaxTokens)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PromptEstimate{
+		Model:           "mock",
+		InputTokens:     int64(len(b)) / promptBytesPerToken,
+		OutputTokens:    out,
+		MaxOutputTokens: defaultPromptMaxTokens,
+	}, nil
+}
+
+// estimatePrompt estimates the tokens and cost of a prompt about a game,
+// including the prompt history it would be sent with.
+func (s *Server) estimatePrompt(ctx context.Context,
+	id string,
+	req *Prompts,
+) (*PromptEstimate, error) {
+	if req == nil || req.Current.Prompt.Value == "" {
+		return nil, errors.New(errors.ErrInvalidRequest,
+			"missing prompt",
+			"game_id", id)
+	}
+
+	if s.getPrompter == nil {
+		if err := s.initPrompter(); err != nil {
+			return nil, errors.Wrap(err, errors.ErrUnavailable,
+				"unable to initialize prompter")
+		}
+	}
+
+	g, err := s.getGame(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if g == nil {
+		return nil, errors.New(errors.ErrNotFound,
+			"game not found for prompt estimate",
+			"game_id", id)
+	}
+
+	prompts, err := s.nextPrompts(ctx, g, req.Current)
+	if err != nil {
+		return nil, err
+	}
+
+	p := s.getPrompter(ctx)
+	if p == nil {
+		return nil, errors.New(errors.ErrInvalidRequest,
+			"account AI API key not set")
+	}
+
+	pe, ok := p.(PromptEstimator)
+	if !ok {
+		return nil, errors.New(errors.ErrServer,
+			"AI provider does not support prompt estimates")
+	}
+
+	res, err := pe.Estimate(ctx, prompts, g)
+	if err != nil {
+		return nil, err
+	}
+
+	res.GameID = g.ID.Value
+
+	res.price()
+
+	return res, nil
+}
+
+// postGamePromptEstimateHandler is the post handler used to estimate the
+// tokens and cost of a prompt about a game, without sending it.
+func (s *Server) postGamePromptEstimateHandler(w http.ResponseWriter,
+	r *http.Request,
+) {
+	ctx := r.Context()
+
+	if err := s.checkScope(ctx, request.ScopeGamesRead); err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	req := &Prompts{}
+
+	if err := s.decode(r, &req); err != nil {
+		s.error(errors.Wrap(err, errors.ErrInvalidRequest,
+			"unable to decode request"), w, r)
+
+		return
+	}
+
+	res, err := s.estimatePrompt(ctx, chi.URLParam(r, "id"), req)
+	if err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	if err := s.encode(w, r, res); err != nil {
+		s.error(err, w, r)
+	}
+}
@@ -0,0 +1,379 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/dhaifley/game2d/errors"
+	"github.com/dhaifley/game2d/logger"
+	"github.com/dhaifley/game2d/request"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// PromptHistory values represent the prompts, and their responses, of the AI
+// conversation about a game which are no longer current. They are stored
+// apart from the games, so that game documents do not grow with the length of
+// their conversations. The game ID is that of the game revision for which the
+// prompt was current, and is empty for prompts carried over from elsewhere,
+// such as reset summaries. Any attachments of the prompts are kept, for the
+// provenance of the games, but not sent again to the AI service.
+type PromptHistory struct {
+	Seq         bson.ObjectID      `bson:"_id,omitempty"         json:"-"                     yaml:"-"`
+	AccountID   string             `bson:"account_id"            json:"-"                     yaml:"-"`
+	HistoryID   string             `bson:"history_id"            json:"history_id"            yaml:"history_id"`
+	GameID      string             `bson:"game_id"               json:"game_id,omitempty"     yaml:"game_id,omitempty"`
+	Prompt      string             `bson:"prompt"                json:"prompt"                yaml:"prompt"`
+	Response    string             `bson:"response"              json:"response"              yaml:"response"`
+	Attachments []PromptAttachment `bson:"attachments,omitempty" json:"attachments,omitempty" yaml:"attachments,omitempty"`
+	CreatedAt   int64              `bson:"created_at"            json:"created_at"            yaml:"created_at"`
+}
+
+// prompt returns the history entry as a prompt.
+func (h *PromptHistory) prompt() Prompt {
+	return Prompt{
+		Prompt:   request.FieldString{Set: true, Valid: true, Value: h.Prompt},
+		Response: request.FieldString{Set: true, Valid: true, Value: h.Response},
+	}
+}
+
+// newHistoryID creates the ID of a new AI conversation.
+func newHistoryID() request.FieldString {
+	return request.FieldString{Set: true, Valid: true, Value: uuid.NewString()}
+}
+
+// gamePrompts retrieves the prompts of a game, with the most recent prompts of
+// its conversation history, up to the configured history size, not including
+// the current prompt. Games created before prompt histories were stored apart
+// from them keep their history inline, until they are next prompted.
+func (s *Server) gamePrompts(ctx context.Context, g *Game) (*Prompts, error) {
+	prompts, err := promptsFromFieldJSON(g.Prompts)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrInvalidRequest,
+			"unable to decode prompts",
+			"game_id", g.ID.Value)
+	}
+
+	if prompts == nil {
+		prompts = &Prompts{}
+	}
+
+	if prompts.HistoryID.Value == "" {
+		return prompts, nil
+	}
+
+	aID, err := request.ContextAccountID(ctx)
+	if err != nil {
+		return nil, errors.New(errors.ErrUnauthorized,
+			"unable to get account id from context")
+	}
+
+	f := bson.M{"account_id": aID, "history_id": prompts.HistoryID.Value}
+
+	c, err := s.collection(ctx, "prompt_history")
+	if err != nil {
+		return nil, err
+	}
+
+	cur, err := c.Find(ctx, f,
+		options.Find().SetSort(bson.D{{Key: "_id", Value: -1}}))
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrDatabase,
+			"unable to find prompt history",
+			"game_id", g.ID.Value)
+	}
+
+	defer func() {
+		if err := cur.Close(ctx); err != nil {
+			s.log.Log(ctx, logger.LvlError,
+				"unable to close cursor",
+				"err", err)
+		}
+	}()
+
+	history, n := []Prompt{}, 0
+
+	for cur.Next(ctx) {
+		var h *PromptHistory
+
+		if err := cur.Decode(&h); err != nil {
+			return nil, errors.Wrap(err, errors.ErrDatabase,
+				"unable to decode prompt history",
+				"game_id", g.ID.Value)
+		}
+
+		// The current prompt of the game is already stored if the game
+		// was prompted before, and is not part of its own history.
+		if h.GameID == g.ID.Value {
+			continue
+		}
+
+		if n += len(h.Prompt) + len(h.Response); n >
+			int(s.cfg.PromptHistorySize()) && len(history) > 0 {
+			break
+		}
+
+		history = append(history, h.prompt())
+	}
+
+	if err := cur.Err(); err != nil {
+		return nil, errors.Wrap(err, errors.ErrDatabase,
+			"unable to find prompt history",
+			"game_id", g.ID.Value)
+	}
+
+	slices.Reverse(history)
+
+	prompts.History = history
+
+	return prompts, nil
+}
+
+// archivePrompts stores the current prompt of a game in the history of the
+// conversation, when the game is prompted again. Any history kept inline by
+// the game is stored first. The current prompt is only stored once, however
+// many times the game is prompted.
+func (s *Server) archivePrompts(ctx context.Context,
+	g *Game,
+	historyID string,
+) error {
+	prompts, err := promptsFromFieldJSON(g.Prompts)
+	if err != nil {
+		return errors.Wrap(err, errors.ErrInvalidRequest,
+			"unable to decode prompts",
+			"game_id", g.ID.Value)
+	}
+
+	if prompts == nil {
+		return nil
+	}
+
+	if prompts.HistoryID.Value == "" {
+		if err := s.insertPromptHistory(ctx, historyID,
+			prompts.History); err != nil {
+			return err
+		}
+	}
+
+	if prompts.Current.Prompt.Value == "" {
+		return nil
+	}
+
+	aID, err := request.ContextAccountID(ctx)
+	if err != nil {
+		return errors.New(errors.ErrUnauthorized,
+			"unable to get account id from context")
+	}
+
+	f := bson.M{
+		"account_id": aID,
+		"history_id": historyID,
+		"game_id":    g.ID.Value,
+	}
+
+	doc := bson.M{
+		"_id":        bson.NewObjectID(),
+		"prompt":     prompts.Current.Prompt.Value,
+		"response":   prompts.Current.Response.Value,
+		"created_at": time.Now().Unix(),
+	}
+
+	if len(prompts.Current.Attachments) > 0 {
+		doc["attachments"] = prompts.Current.Attachments
+	}
+
+	c, err := s.collection(ctx, "prompt_history")
+	if err != nil {
+		return err
+	}
+
+	if _, err := c.UpdateOne(ctx, f,
+		bson.M{"$setOnInsert": doc},
+		options.UpdateOne().SetUpsert(true)); err != nil {
+		return errors.Wrap(err, errors.ErrDatabase,
+			"unable to store prompt history",
+			"game_id", g.ID.Value)
+	}
+
+	return nil
+}
+
+// insertPromptHistory stores prompts, which are not current for any game, in
+// the history of a conversation.
+func (s *Server) insertPromptHistory(ctx context.Context,
+	historyID string,
+	prompts []Prompt,
+) error {
+	if len(prompts) == 0 {
+		return nil
+	}
+
+	aID, err := request.ContextAccountID(ctx)
+	if err != nil {
+		return errors.New(errors.ErrUnauthorized,
+			"unable to get account id from context")
+	}
+
+	now := time.Now().Unix()
+
+	docs := make([]*PromptHistory, 0, len(prompts))
+
+	for _, p := range prompts {
+		docs = append(docs, &PromptHistory{
+			Seq:         bson.NewObjectID(),
+			AccountID:   aID,
+			HistoryID:   historyID,
+			Prompt:      p.Prompt.Value,
+			Response:    p.Response.Value,
+			Attachments: p.Attachments,
+			CreatedAt:   now,
+		})
+	}
+
+	c, err := s.collection(ctx, "prompt_history")
+	if err != nil {
+		return err
+	}
+
+	if _, err := c.InsertMany(ctx,
+		docs); err != nil {
+		return errors.Wrap(err, errors.ErrDatabase,
+			"unable to store prompt history",
+			"history_id", historyID)
+	}
+
+	return nil
+}
+
+// getPromptHistory retrieves a page of the conversation history of a game
+// owned by the current account, from the newest to the oldest prompt, and the
+// total number of prompts in the history.
+func (s *Server) getPromptHistory(ctx context.Context,
+	id string,
+	query *request.Query,
+) ([]*PromptHistory, int64, error) {
+	aID, err := request.ContextAccountID(ctx)
+	if err != nil {
+		return nil, 0, errors.New(errors.ErrUnauthorized,
+			"unable to get account id from context")
+	}
+
+	g, err := s.getGame(context.WithValue(ctx, CtxKeyGameMinData, true), id)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if g.AccountID.Value != aID {
+		return nil, 0, errors.New(errors.ErrNotFound,
+			"game not found",
+			"id", id).WithReason(errors.ReasonGameNotFound)
+	}
+
+	if query == nil {
+		query = request.NewQuery()
+	}
+
+	if query.Size <= 0 {
+		query.Size = s.cfg.DBDefaultSize()
+	}
+
+	query.Size = min(query.Size, s.cfg.DBMaxSize())
+
+	prompts, err := promptsFromFieldJSON(g.Prompts)
+	if err != nil {
+		return nil, 0, errors.Wrap(err, errors.ErrInvalidRequest,
+			"unable to decode prompts",
+			"game_id", id)
+	}
+
+	res := []*PromptHistory{}
+
+	if prompts == nil {
+		return res, 0, nil
+	}
+
+	// Inline history is paged in memory, until it is stored apart.
+	if prompts.HistoryID.Value == "" {
+		n := int64(len(prompts.History))
+
+		for i := n - 1 - query.Skip; i >= 0 &&
+			int64(len(res)) < query.Size; i-- {
+			res = append(res, &PromptHistory{
+				Prompt:   prompts.History[i].Prompt.Value,
+				Response: prompts.History[i].Response.Value,
+			})
+		}
+
+		return res, n, nil
+	}
+
+	f := bson.M{"account_id": aID, "history_id": prompts.HistoryID.Value}
+
+	c, err := s.collection(ctx, "prompt_history")
+	if err != nil {
+		return nil, 0, err
+	}
+
+	cur, err := c.Find(ctx, f,
+		options.Find().SetSort(bson.D{{Key: "_id", Value: -1}}).
+			SetSkip(query.Skip).SetLimit(query.Size))
+	if err != nil {
+		return nil, 0, errors.Wrap(err, errors.ErrDatabase,
+			"unable to find prompt history",
+			"game_id", id)
+	}
+
+	if err := cur.All(ctx, &res); err != nil {
+		return nil, 0, errors.Wrap(err, errors.ErrDatabase,
+			"unable to decode prompt history",
+			"game_id", id)
+	}
+
+	n, err := c.CountDocuments(ctx, f)
+	if err != nil {
+		return nil, 0, errors.Wrap(err, errors.ErrDatabase,
+			"unable to count prompt history",
+			"game_id", id)
+	}
+
+	return res, n, nil
+}
+
+// getGamePromptsHandler is the get handler used to retrieve a page of the AI
+// conversation history of a game.
+func (s *Server) getGamePromptsHandler(w http.ResponseWriter,
+	r *http.Request,
+) {
+	ctx := r.Context()
+
+	if err := s.checkScope(ctx, request.ScopeGamesRead); err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	query, err := request.ParseQuery(r.URL.Query())
+	if err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	res, n, err := s.getPromptHistory(ctx, chi.URLParam(r, "id"), query)
+	if err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	w.Header().Add("X-Total-Count", strconv.FormatInt(n, 10))
+
+	if err := s.encode(w, r, res); err != nil {
+		s.error(err, w, r)
+	}
+}
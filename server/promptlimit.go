@@ -0,0 +1,194 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/dhaifley/game2d/errors"
+	"github.com/dhaifley/game2d/logger"
+	"github.com/dhaifley/game2d/request"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// promptWindow is the period over which prompt rate limits are counted.
+const promptWindow = time.Hour
+
+// PromptEvent values record prompts sent, so that limits on prompt frequency
+// can be enforced. Because each prompt creates a new revision of a game, the
+// lineage ID identifies the game across all of its revisions.
+type PromptEvent struct {
+	AccountID string    `bson:"account_id" json:"account_id" yaml:"account_id"`
+	GameID    string    `bson:"game_id"    json:"game_id"    yaml:"game_id"`
+	LineageID string    `bson:"lineage_id" json:"lineage_id" yaml:"lineage_id"`
+	UserID    string    `bson:"user_id"    json:"user_id"    yaml:"user_id"`
+	CreatedAt int64     `bson:"created_at" json:"created_at" yaml:"created_at"`
+	ExpiresAt time.Time `bson:"expires_at" json:"-"          yaml:"-"`
+}
+
+// countPrompts returns the number of prompt events matching a filter within
+// the prompt window, and the time the oldest of them was sent.
+func (s *Server) countPrompts(ctx context.Context,
+	f bson.M,
+	since int64,
+) (int64, int64, error) {
+	f["created_at"] = bson.M{"$gt": since}
+
+	c, err := s.collection(ctx, "prompt_events")
+	if err != nil {
+		return 0, 0, err
+	}
+
+	n, err := c.CountDocuments(ctx, f,
+		options.Count())
+	if err != nil || n == 0 {
+		return n, 0, err
+	}
+
+	var pe PromptEvent
+
+	if err := c.FindOne(ctx, f,
+		options.FindOne().SetSort(bson.D{{Key: "created_at", Value: 1}})).
+		Decode(&pe); err != nil {
+		return n, 0, err
+	}
+
+	return n, pe.CreatedAt, nil
+}
+
+// checkPromptLimits determines whether a prompt may be sent for a game under
+// the configured cooldown and per game and per account prompt limits. The
+// lineage ID of the game is returned, for use in recording the prompt.
+func (s *Server) checkPromptLimits(ctx context.Context,
+	g *Game,
+) (string, error) {
+	lineage := g.ID.Value
+
+	if s.DB() == nil {
+		return lineage, nil
+	}
+
+	var last PromptEvent
+
+	c, err := s.collection(ctx, "prompt_events")
+	if err != nil {
+		return "", err
+	}
+
+	if err := c.FindOne(ctx,
+		bson.M{"game_id": g.ID.Value},
+		options.FindOne().SetSort(bson.D{{Key: "created_at", Value: -1}})).
+		Decode(&last); err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return lineage, errors.Wrap(err, errors.ErrDatabase,
+			"unable to get last prompt event",
+			"game_id", g.ID.Value)
+	}
+
+	if last.LineageID != "" {
+		lineage = last.LineageID
+	}
+
+	if aID, _ := request.ContextAccountID(ctx); aID == request.SystemAccount {
+		return lineage, nil
+	}
+
+	now := time.Now()
+
+	if cd := s.cfg.PromptCooldown(); cd > 0 && last.CreatedAt > 0 {
+		next := time.Unix(last.CreatedAt, 0).Add(cd)
+
+		if now.Before(next) {
+			return lineage, errors.New(errors.ErrorRateLimit,
+				"prompt sent for game too recently",
+				"game_id", g.ID.Value,
+				"cooldown", cd.String()).
+				WithReason(errors.ReasonPromptCooldown).
+				WithRateLimit(0, 0, next)
+		}
+	}
+
+	since := now.Add(-promptWindow).Unix()
+
+	limits := []struct {
+		scope string
+		limit int64
+		f     bson.M
+	}{{
+		scope: "game",
+		limit: s.cfg.PromptGameLimit(),
+		f:     bson.M{"lineage_id": lineage},
+	}, {
+		scope: "account",
+		limit: s.cfg.PromptAccountLimit(),
+		f:     bson.M{"account_id": g.AccountID.Value},
+	}}
+
+	for _, l := range limits {
+		if l.limit <= 0 {
+			continue
+		}
+
+		n, oldest, err := s.countPrompts(ctx, l.f, since)
+		if err != nil {
+			return lineage, errors.Wrap(err, errors.ErrDatabase,
+				"unable to count prompt events",
+				"game_id", g.ID.Value,
+				"scope", l.scope)
+		}
+
+		if n >= l.limit {
+			return lineage, errors.New(errors.ErrorRateLimit,
+				"prompt limit reached",
+				"game_id", g.ID.Value,
+				"scope", l.scope,
+				"limit", l.limit,
+				"window", promptWindow.String()).
+				WithReason(errors.ReasonPromptRateLimit).
+				WithRateLimit(l.limit, 0,
+					time.Unix(oldest, 0).Add(promptWindow))
+		}
+	}
+
+	return lineage, nil
+}
+
+// recordPrompt records a prompt sent for a game revision. Failures are logged,
+// but otherwise ignored, so that recording never causes the prompt to fail.
+func (s *Server) recordPrompt(ctx context.Context,
+	lineage string,
+	g *Game,
+) {
+	if s.DB() == nil {
+		return
+	}
+
+	uID, _ := request.ContextUserID(ctx)
+
+	now := time.Now()
+
+	pe := &PromptEvent{
+		AccountID: g.AccountID.Value,
+		GameID:    g.ID.Value,
+		LineageID: lineage,
+		UserID:    uID,
+		CreatedAt: now.Unix(),
+		ExpiresAt: now.Add(promptWindow),
+	}
+
+	// Prompts are recorded even if the request is canceled.
+	ctx, cancel := s.detachedContext(ctx, opDB)
+	defer cancel()
+
+	c, err := s.collection(ctx, "prompt_events")
+	if err == nil {
+		_, err = c.InsertOne(ctx, pe)
+	}
+
+	if err != nil {
+		s.log.Log(ctx, logger.LvlWarn,
+			"unable to record prompt event",
+			"error", err,
+			"game_id", g.ID.Value)
+	}
+}
@@ -0,0 +1,195 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/dhaifley/game2d/errors"
+	"github.com/dhaifley/game2d/request"
+	"github.com/go-chi/chi/v5"
+)
+
+// PromptResetRequest values are requests to reset the AI conversation of a
+// game. If a summary is requested, without its text, it is created from the
+// prompts of the previous conversation.
+type PromptResetRequest struct {
+	Summary bool   `json:"summary"        yaml:"summary"`
+	Text    string `json:"text,omitempty" yaml:"text,omitempty"`
+}
+
+// promptSummary creates the summary of a conversation carried over when the
+// conversation is reset, from the prompts in its history, keeping the most
+// recent prompts if they exceed the history size.
+func promptSummary(prompts *Prompts, size int) string {
+	items := []string{}
+
+	for _, p := range append(prompts.History, prompts.Current) {
+		if v := strings.TrimSpace(p.Prompt.Value); v != "" {
+			items = append(items, v)
+		}
+	}
+
+	if len(items) == 0 {
+		return ""
+	}
+
+	for {
+		b := strings.Builder{}
+
+		for i, v := range items {
+			b.WriteString(strconv.Itoa(i+1) + ". " + v + "\n")
+		}
+
+		if b.Len() <= size || len(items) == 1 {
+			return b.String()
+		}
+
+		items = items[1:]
+	}
+}
+
+// resetPrompts resets the AI conversation of a game. The conversation is
+// archived in a new revision of the game, the previous revision of which
+// retains the prompt history, so that the reset can be undone. The new
+// revision starts a new conversation, with an empty history, or a summary of
+// the previous one.
+func (s *Server) resetPrompts(ctx context.Context,
+	id string,
+	req *PromptResetRequest,
+) (*Prompts, error) {
+	ctx = context.WithValue(ctx, CtxKeyGameAllowTags, true)
+	ctx = context.WithValue(ctx, CtxKeyGameAllowPreviousID, true)
+
+	g, err := s.getGame(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if g == nil {
+		return nil, errors.New(errors.ErrNotFound,
+			"game not found for prompt reset",
+			"game_id", id)
+	}
+
+	if g.Status.Value == request.StatusUpdating {
+		return nil, errors.New(errors.ErrInvalidRequest,
+			"unable to reset prompts for games with a prompt in progress",
+			"game_id", id)
+	}
+
+	if g.Status.Value == request.StatusInactive {
+		return nil, errors.New(errors.ErrInvalidRequest,
+			"unable to reset prompts for inactive games",
+			"game_id", id)
+	}
+
+	if g.ReadOnly.Value {
+		return nil, errReadOnlyGame(g.ID.Value)
+	}
+
+	prompts, err := s.gamePrompts(ctx, g)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &Prompts{HistoryID: newHistoryID(), History: []Prompt{}}
+
+	if req != nil && req.Summary {
+		text := strings.TrimSpace(req.Text)
+		if text == "" {
+			text = promptSummary(prompts, int(s.cfg.PromptHistorySize()))
+		}
+
+		if len(text) > int(s.cfg.PromptHistorySize()) {
+			return nil, errors.New(errors.ErrInvalidRequest,
+				"prompt summary exceeds the prompt history size",
+				"game_id", id,
+				"size", s.cfg.PromptHistorySize())
+		}
+
+		if text != "" {
+			res.History = append(res.History, Prompt{
+				Prompt: request.FieldString{
+					Set: true, Valid: true,
+					Value: "This is a summary of the previous " +
+						"conversation about this game:\n\n" + text,
+				},
+				Response: request.FieldString{
+					Set: true, Valid: true,
+					Value: "The summary of the previous conversation " +
+						"has been noted.",
+				},
+			})
+		}
+	}
+
+	if err := s.insertPromptHistory(ctx, res.HistoryID.Value,
+		res.History); err != nil {
+		return nil, err
+	}
+
+	ps, err := promptsToFieldJSON(res)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrServer,
+			"unable to encode prompts",
+			"game_id", id)
+	}
+
+	ng, err := s.createGame(ctx, gameRevision(g, g.Status.Value, ps))
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrDatabase,
+			"unable to create new game from prompt reset",
+			"game_id", id)
+	}
+
+	res.GameID = request.FieldString{
+		Set: true, Valid: true, Value: ng.ID.Value,
+	}
+
+	s.recordActivity(ctx, ActivityPrompt, ng.ID.Value, map[string]any{
+		"reset":       true,
+		"previous_id": g.ID.Value,
+	})
+
+	return res, nil
+}
+
+// postGamePromptResetHandler is the post handler used to reset the AI
+// conversation of a game.
+func (s *Server) postGamePromptResetHandler(w http.ResponseWriter,
+	r *http.Request,
+) {
+	ctx := r.Context()
+
+	if err := s.checkScope(ctx, request.ScopeGamesWrite); err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	req := &PromptResetRequest{}
+
+	if r.ContentLength != 0 {
+		if err := s.decode(r, &req); err != nil {
+			s.error(errors.Wrap(err, errors.ErrInvalidRequest,
+				"unable to decode request"), w, r)
+
+			return
+		}
+	}
+
+	res, err := s.resetPrompts(ctx, chi.URLParam(r, "id"), req)
+	if err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+
+	if err := s.encode(w, r, res); err != nil {
+		s.error(err, w, r)
+	}
+}
@@ -0,0 +1,137 @@
+package server
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/dhaifley/game2d/errors"
+	"github.com/dhaifley/game2d/logger"
+	"github.com/dhaifley/game2d/request"
+	"github.com/google/uuid"
+)
+
+// Provisioner values create the initial data required by the server, such as
+// the default account. Because provisioning is performed every time the server
+// starts, it must be idempotent.
+type Provisioner interface {
+	Provision(ctx context.Context) error
+}
+
+// ProvisionerFunc values are functions which implement the Provisioner
+// interface.
+type ProvisionerFunc func(ctx context.Context) error
+
+// Provision calls the provisioner function.
+func (f ProvisionerFunc) Provision(ctx context.Context) error {
+	return f(ctx)
+}
+
+// accountProvisioner provisions the configured account, and a guest user if
+// the GUEST_USER and GUEST_USER_PASSWORD environment variables are set.
+type accountProvisioner struct {
+	s *Server
+}
+
+// NewAccountProvisioner creates the default provisioner used by a server,
+// which creates the configured account and optional guest user.
+func NewAccountProvisioner(s *Server) Provisioner {
+	return &accountProvisioner{s: s}
+}
+
+// Provision creates the configured account and guest user, if they do not
+// already exist.
+func (p *accountProvisioner) Provision(ctx context.Context) error {
+	a, err := p.s.createAccount(ctx, &Account{
+		ID: request.FieldString{
+			Set: true, Valid: true, Value: p.s.cfg.AccountID(),
+		},
+		Name: request.FieldString{
+			Set: true, Valid: true, Value: p.s.cfg.AccountName(),
+		},
+		Secret: request.FieldString{
+			Set: true, Valid: true, Value: uuid.NewString(),
+		},
+	})
+	if err != nil {
+		return errors.Wrap(err, errors.ErrDatabase,
+			"unable to create account",
+			"account_id", p.s.cfg.AccountID())
+	}
+
+	gu, gp := os.Getenv("GUEST_USER"), os.Getenv("GUEST_USER_PASSWORD")
+	if gu == "" || gp == "" {
+		return nil
+	}
+
+	if _, err := p.s.createUser(ctx, &User{
+		AccountID: request.FieldString{
+			Set: true, Valid: true, Value: a.ID.Value,
+		},
+		ID: request.FieldString{
+			Set: true, Valid: true, Value: gu,
+		},
+		Scopes: request.FieldString{
+			Set: true, Valid: true,
+			Value: request.ScopeAccountRead + " " + request.ScopeUserRead +
+				" " + request.ScopeGamesRead,
+		},
+		Password: &gp,
+	}); err != nil {
+		return errors.Wrap(err, errors.ErrDatabase,
+			"unable to create initial guest user",
+			"account_id", a.ID.Value,
+			"user_id", gu)
+	}
+
+	return nil
+}
+
+// SetProvisioner sets the provisioner used to create the initial data
+// required by the server. Provisioning is disabled if it is nil.
+func (s *Server) SetProvisioner(p Provisioner) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.provisioner = p
+}
+
+// Provision waits for the database to be connected, then creates the initial
+// data required by the server using its provisioner. It returns early if the
+// context is canceled.
+func (s *Server) Provision(ctx context.Context) error {
+	s.RLock()
+
+	p := s.provisioner
+
+	s.RUnlock()
+
+	if p == nil {
+		return nil
+	}
+
+	for s.DB() == nil {
+		select {
+		case <-ctx.Done():
+			return errors.Wrap(ctx.Err(), errors.ErrUnavailable,
+				"provisioning canceled before database connection")
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+
+	ctx = context.WithValue(ctx, request.CtxKeyScopes,
+		request.ScopeSuperuser)
+	ctx = context.WithValue(ctx, request.CtxKeyAccountID,
+		request.SystemAccount)
+	ctx = context.WithValue(ctx, request.CtxKeyUserID, request.SystemUser)
+
+	if err := p.Provision(ctx); err != nil {
+		return err
+	}
+
+	s.log.Log(ctx, logger.LvlInfo,
+		"provisioned initial data",
+		"account_id", s.cfg.AccountID())
+
+	return nil
+}
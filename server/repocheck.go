@@ -0,0 +1,253 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/dhaifley/game2d/errors"
+	"github.com/dhaifley/game2d/repo"
+	"github.com/dhaifley/game2d/request"
+	"gopkg.in/yaml.v3"
+)
+
+// repoValidateTimeout is the maximum time allowed for validating an import
+// repository.
+const repoValidateTimeout = time.Minute
+
+// Diagnostic check results.
+const (
+	CheckPassed  = "passed"
+	CheckFailed  = "failed"
+	CheckSkipped = "skipped"
+)
+
+// Check values are the results of a single diagnostic check.
+type Check struct {
+	Name     string `json:"name"            yaml:"name"`
+	Status   string `json:"status"          yaml:"status"`
+	Error    string `json:"error,omitempty" yaml:"error,omitempty"`
+	Duration int64  `json:"duration_ms"     yaml:"duration_ms"`
+}
+
+// RepoValidationRequest values are requests to validate an import repository.
+// If the repository URL is empty, the account import repository is validated.
+type RepoValidationRequest struct {
+	Repo string `json:"repo,omitempty" yaml:"repo,omitempty"`
+}
+
+// RepoValidation values are the results of validating an import repository,
+// without importing any games from it.
+type RepoValidation struct {
+	Repo      string           `json:"repo"                 yaml:"repo"`
+	Valid     bool             `json:"valid"                yaml:"valid"`
+	Commit    string           `json:"commit,omitempty"     yaml:"commit,omitempty"`
+	Files     int              `json:"files"                yaml:"files"`
+	File      string           `json:"file,omitempty"       yaml:"file,omitempty"`
+	RateLimit *ImportRateLimit `json:"rate_limit,omitempty" yaml:"rate_limit,omitempty"`
+	Checks    []*Check         `json:"checks"               yaml:"checks"`
+}
+
+// checkError returns a description of an error for a diagnostic check, which
+// includes the messages of the errors it wraps.
+func checkError(err error) string {
+	var e *errors.Error
+
+	if !errors.As(err, &e) {
+		return err.Error()
+	}
+
+	msgs := []string{}
+
+	for ; e != nil; e = e.Err {
+		if e.Msg != "" && (len(msgs) == 0 || msgs[len(msgs)-1] != e.Msg) {
+			msgs = append(msgs, e.Msg)
+		}
+	}
+
+	return strings.Join(msgs, ": ")
+}
+
+// runCheck runs a diagnostic check, unless a previous check has failed, and
+// adds the result to the list of checks. It returns whether the check passed.
+func runCheck(checks *[]*Check, name string, fn func() error) bool {
+	c := &Check{Name: name, Status: CheckSkipped}
+
+	*checks = append(*checks, c)
+
+	for _, pc := range (*checks)[:len(*checks)-1] {
+		if pc.Status == CheckFailed {
+			return false
+		}
+	}
+
+	start := time.Now()
+
+	err := fn()
+
+	c.Duration = time.Since(start).Milliseconds()
+
+	if err != nil {
+		c.Status, c.Error = CheckFailed, checkError(err)
+
+		return false
+	}
+
+	c.Status = CheckPassed
+
+	return true
+}
+
+// validateRepo validates an import repository by creating a client for it,
+// retrieving its commit hash, listing its games directory, and retrieving and
+// parsing one of its game files. Failed checks are reported in the result,
+// rather than returned as errors.
+func (s *Server) validateRepo(ctx context.Context,
+	req *RepoValidationRequest,
+) (*RepoValidation, error) {
+	repoURL := ""
+
+	if req != nil {
+		repoURL = strings.TrimSpace(req.Repo)
+	}
+
+	if repoURL == "" {
+		a, err := s.getAccount(ctx, "")
+		if err != nil {
+			return nil, err
+		}
+
+		repoURL = a.Repo.Value
+	}
+
+	if repoURL == "" {
+		return nil, errors.New(errors.ErrInvalidRequest,
+			"account import repository not set")
+	}
+
+	res := &RepoValidation{Repo: repoURL, Checks: []*Check{}}
+
+	if u, err := url.Parse(repoURL); err == nil {
+		res.Repo = u.Redacted()
+	}
+
+	ctx, cancel := request.ContextWithTimeout(ctx, repoValidateTimeout)
+	defer cancel()
+
+	var (
+		cli   repo.Client
+		files []repo.Item
+	)
+
+	runCheck(&res.Checks, "client", func() error {
+		var err error
+
+		cli, err = s.getRepoClient(repoURL)
+
+		return err
+	})
+
+	runCheck(&res.Checks, "commit", func() error {
+		var err error
+
+		res.Commit, err = cli.Commit(ctx)
+
+		return err
+	})
+
+	runCheck(&res.Checks, "list", func() error {
+		items, err := cli.ListAll(ctx, "games/")
+		if err != nil {
+			return err
+		}
+
+		for _, i := range items {
+			if i.Type == "file" || i.Type == "commit_file" {
+				files = append(files, i)
+			}
+		}
+
+		res.Files = len(files)
+
+		if len(files) == 0 {
+			return errors.New(errors.ErrImport,
+				"no game files found",
+				"path", "games/")
+		}
+
+		return nil
+	})
+
+	runCheck(&res.Checks, "fetch", func() error {
+		res.File = "games/" + strings.TrimPrefix(
+			strings.TrimPrefix(files[0].Path, "/"), "games/")
+
+		b, err := cli.Get(ctx, res.File)
+		if err != nil {
+			return err
+		}
+
+		var g *Game
+
+		if err := yaml.Unmarshal(b, &g); err != nil {
+			return errors.Wrap(err, errors.ErrImport,
+				"unable to parse game repository file",
+				"file", res.File)
+		}
+
+		return nil
+	})
+
+	if rl, ok := cli.(repo.RateLimiter); ok {
+		if r := rl.RateLimit(); r != nil {
+			res.RateLimit = &ImportRateLimit{
+				Limit:     int64(r.Limit),
+				Remaining: int64(r.Remaining),
+				Reset:     r.Reset.Unix(),
+			}
+		}
+	}
+
+	res.Valid = res.Checks[len(res.Checks)-1].Status == CheckPassed
+
+	return res, nil
+}
+
+// postRepoValidateHandler is the post handler used to validate an import
+// repository, so that repository URLs and access tokens can be checked
+// without starting an import.
+func (s *Server) postRepoValidateHandler(w http.ResponseWriter,
+	r *http.Request,
+) {
+	ctx := r.Context()
+
+	if err := s.checkScope(ctx, request.ScopeAccountAdmin); err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	req := &RepoValidationRequest{}
+
+	if r.ContentLength != 0 {
+		if err := s.decode(r, &req); err != nil {
+			s.error(errors.Wrap(err, errors.ErrInvalidRequest,
+				"unable to decode request"), w, r)
+
+			return
+		}
+	}
+
+	res, err := s.validateRepo(ctx, req)
+	if err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	if err := s.encode(w, r, res); err != nil {
+		s.error(err, w, r)
+	}
+}
@@ -0,0 +1,319 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/dhaifley/game2d/errors"
+	"github.com/dhaifley/game2d/request"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const (
+	// errorReportRetention is how long client error reports are kept.
+	errorReportRetention = time.Hour * 24 * 30
+
+	// defaultErrorReportSize is the default number of error reports returned
+	// in a page.
+	defaultErrorReportSize = 50
+
+	// maxErrorReportSize is the maximum number of error reports returned in a
+	// page.
+	maxErrorReportSize = 500
+
+	// maxErrorLength is the maximum length of a reported error message.
+	maxErrorLength = 4096
+
+	// maxStackLength is the maximum length of a reported stack trace.
+	maxStackLength = 32768
+)
+
+// ErrorReport values represent an error or crash which occurred while a game
+// was being played by a client.
+type ErrorReport struct {
+	Seq       bson.ObjectID `bson:"_id,omitempty"        json:"-"                    yaml:"-"`
+	ID        string        `bson:"id"                   json:"id"                   yaml:"id"`
+	AccountID string        `bson:"account_id"           json:"account_id"           yaml:"account_id"`
+	UserID    string        `bson:"user_id"              json:"user_id"              yaml:"user_id"`
+	GameID    string        `bson:"game_id"              json:"game_id"              yaml:"game_id"`
+	SessionID string        `bson:"session_id,omitempty" json:"session_id,omitempty" yaml:"session_id,omitempty"`
+	Error     string        `bson:"error"                json:"error"                yaml:"error"`
+	Stack     string        `bson:"stack,omitempty"      json:"stack,omitempty"      yaml:"stack,omitempty"`
+	Revision  int64         `bson:"revision"             json:"revision"             yaml:"revision"`
+	Platform  string        `bson:"platform,omitempty"   json:"platform,omitempty"   yaml:"platform,omitempty"`
+	Panic     bool          `bson:"panic"                json:"panic"                yaml:"panic"`
+	CreatedAt int64         `bson:"created_at"           json:"created_at"           yaml:"created_at"`
+	ExpiresAt time.Time     `bson:"expires_at"           json:"-"                    yaml:"-"`
+}
+
+// Validate checks that the value contains valid data.
+func (e *ErrorReport) Validate() error {
+	if e.Error == "" {
+		return errors.New(errors.ErrInvalidRequest,
+			"missing error")
+	}
+
+	if len(e.Error) > maxErrorLength {
+		return errors.New(errors.ErrInvalidRequest,
+			"error too long",
+			"max", maxErrorLength)
+	}
+
+	if len(e.Stack) > maxStackLength {
+		return errors.New(errors.ErrInvalidRequest,
+			"stack too long",
+			"max", maxStackLength)
+	}
+
+	if e.SessionID != "" {
+		if _, err := uuid.Parse(e.SessionID); err != nil {
+			return errors.New(errors.ErrInvalidRequest,
+				"invalid session_id",
+				"session_id", e.SessionID)
+		}
+	}
+
+	if e.Revision < 0 {
+		return errors.New(errors.ErrInvalidRequest,
+			"invalid revision",
+			"revision", e.Revision)
+	}
+
+	return nil
+}
+
+// ErrorReportPage values represent a page of error reports for a game, from
+// the newest to the oldest report. The cursor is used to retrieve the next
+// page, and is empty if there are no more reports.
+type ErrorReportPage struct {
+	Errors []*ErrorReport `json:"errors"           yaml:"errors"`
+	Cursor string         `json:"cursor,omitempty" yaml:"cursor,omitempty"`
+}
+
+// createErrorReport stores an error report for a game, which may be played
+// by any user able to retrieve it.
+func (s *Server) createErrorReport(ctx context.Context,
+	id string,
+	v *ErrorReport,
+) (*ErrorReport, error) {
+	aID, err := request.ContextAccountID(ctx)
+	if err != nil {
+		return nil, errors.New(errors.ErrUnauthorized,
+			"unable to get account id from context")
+	}
+
+	uID, err := request.ContextUserID(ctx)
+	if err != nil {
+		return nil, errors.New(errors.ErrUnauthorized,
+			"unable to get user id from context")
+	}
+
+	if v == nil {
+		return nil, errors.New(errors.ErrInvalidRequest,
+			"missing error report")
+	}
+
+	if err := v.Validate(); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.getGame(context.WithValue(ctx, CtxKeyGameMinData, true),
+		id); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	v.Seq = bson.ObjectID{}
+	v.ID = uuid.NewString()
+	v.AccountID = aID
+	v.UserID = uID
+	v.GameID = id
+	v.CreatedAt = now.Unix()
+	v.ExpiresAt = now.Add(errorReportRetention)
+
+	c, err := s.collection(ctx, "error_reports")
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := c.InsertOne(ctx,
+		v); err != nil {
+		return nil, errors.Wrap(err, errors.ErrDatabase,
+			"unable to create error report",
+			"id", id)
+	}
+
+	if s.metric != nil {
+		s.metric.Increment(ctx, "game_error_reports", "game_id:"+id)
+	}
+
+	return v, nil
+}
+
+// getErrorReports retrieves a page of error reports for a game owned by the
+// current account.
+func (s *Server) getErrorReports(ctx context.Context,
+	id, cursor string,
+	size int64,
+) (*ErrorReportPage, error) {
+	aID, err := request.ContextAccountID(ctx)
+	if err != nil {
+		return nil, errors.New(errors.ErrUnauthorized,
+			"unable to get account id from context")
+	}
+
+	if !request.ValidGameID(id) {
+		return nil, errors.New(errors.ErrInvalidRequest,
+			"invalid game id",
+			"id", id)
+	}
+
+	games, err := s.collection(ctx, "games")
+	if err != nil {
+		return nil, err
+	}
+
+	reports, err := s.collection(ctx, "error_reports")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := games.FindOne(ctx,
+		bson.M{"id": id, "account_id": aID},
+		options.FindOne().SetProjection(bson.M{"_id": 1})).
+		Err(); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, errors.New(errors.ErrNotFound,
+				"game not found",
+				"id", id)
+		}
+
+		return nil, errors.Wrap(err, errors.ErrDatabase,
+			"unable to get game",
+			"id", id)
+	}
+
+	if size <= 0 {
+		size = defaultErrorReportSize
+	}
+
+	size = min(size, maxErrorReportSize)
+
+	f := bson.M{"game_id": id}
+
+	if cursor != "" {
+		oID, err := bson.ObjectIDFromHex(cursor)
+		if err != nil {
+			return nil, errors.Wrap(err, errors.ErrInvalidParameter,
+				"invalid error report cursor",
+				"cursor", cursor)
+		}
+
+		f["_id"] = bson.M{"$lt": oID}
+	}
+
+	cur, err := reports.Find(ctx, f,
+		options.Find().SetSort(bson.D{{Key: "_id", Value: -1}}).
+			SetLimit(size))
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrDatabase,
+			"unable to find error reports",
+			"id", id)
+	}
+
+	res := &ErrorReportPage{Errors: []*ErrorReport{}}
+
+	if err := cur.All(ctx, &res.Errors); err != nil {
+		return nil, errors.Wrap(err, errors.ErrDatabase,
+			"unable to decode error reports",
+			"id", id)
+	}
+
+	if n := len(res.Errors); int64(n) == size {
+		res.Cursor = res.Errors[n-1].Seq.Hex()
+	}
+
+	return res, nil
+}
+
+// postGameErrorHandler is the post handler used to report a client error
+// which occurred while playing a game.
+func (s *Server) postGameErrorHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if err := s.checkScope(ctx, request.ScopeGamesRead); err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	req := &ErrorReport{}
+
+	if err := s.decode(r, &req); err != nil {
+		s.error(errors.Wrap(err, errors.ErrInvalidRequest,
+			"unable to decode request"), w, r)
+
+		return
+	}
+
+	res, err := s.createErrorReport(ctx, chi.URLParam(r, "id"), req)
+	if err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+
+	if err := s.encode(w, r, res); err != nil {
+		s.error(err, w, r)
+	}
+}
+
+// getGameErrorsHandler is the get handler used to retrieve the client error
+// reports of a game.
+func (s *Server) getGameErrorsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if err := s.checkScope(ctx, request.ScopeGamesRead); err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	q := r.URL.Query()
+
+	var size int64
+
+	if v := q.Get("size"); v != "" {
+		i, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || i < 0 {
+			s.error(errors.New(errors.ErrInvalidParameter,
+				"invalid error report size",
+				"size", v), w, r)
+
+			return
+		}
+
+		size = i
+	}
+
+	res, err := s.getErrorReports(ctx, chi.URLParam(r, "id"),
+		q.Get("cursor"), size)
+	if err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	if err := s.encode(w, r, res); err != nil {
+		s.error(err, w, r)
+	}
+}
@@ -0,0 +1,204 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/dhaifley/game2d/errors"
+	"github.com/dhaifley/game2d/request"
+	"github.com/go-chi/chi/v5"
+)
+
+// maxRestoreVersions is the maximum number of previous versions of a game
+// searched for a snapshot to restore.
+const maxRestoreVersions = 100
+
+// parseRestoreTime parses a restore time, which can be either a Unix time, or
+// an RFC 3339 formatted time.
+func parseRestoreTime(v string) (int64, error) {
+	if v == "" {
+		return 0, errors.New(errors.ErrInvalidParameter,
+			"missing restore time")
+	}
+
+	if at, err := strconv.ParseInt(v, 10, 64); err == nil && at > 0 {
+		return at, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return 0, errors.Wrap(err, errors.ErrInvalidParameter,
+			"invalid restore time",
+			"at", v)
+	}
+
+	return t.Unix(), nil
+}
+
+// gameSnapshot returns the most recent snapshot of a game taken at or before
+// a time, from the previous versions of the game and the account backups.
+func (s *Server) gameSnapshot(ctx context.Context,
+	id string,
+	at int64,
+) (*Game, error) {
+	var (
+		res     *Game
+		resTime int64
+	)
+
+	g, err := s.getGame(ctx, id)
+	if err != nil && !errors.Has(err, errors.ErrNotFound) {
+		return nil, err
+	}
+
+	// Versions are searched from the newest to the oldest, so the first one
+	// updated before the restore time is the most recent.
+	seen := map[string]bool{}
+
+	for i := 0; g != nil && i < maxRestoreVersions; i++ {
+		if seen[g.ID.Value] {
+			break
+		}
+
+		seen[g.ID.Value] = true
+
+		if g.UpdatedAt.Value <= at {
+			res, resTime = g, g.UpdatedAt.Value
+
+			break
+		}
+
+		if g.PreviousID.Value == "" {
+			break
+		}
+
+		if g, err = s.getGame(ctx, g.PreviousID.Value); err != nil {
+			if errors.Has(err, errors.ErrNotFound) {
+				break
+			}
+
+			return nil, err
+		}
+	}
+
+	// A backup is only used if it was created after the most recent version,
+	// since it then reflects the state of the game at a later time.
+	cli, w, err := s.backupTarget(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if w != nil {
+		m, err := s.getBackupManifest(ctx, cli)
+		if err != nil {
+			return nil, err
+		}
+
+	BackupLoop:
+		for i := len(m.Backups) - 1; i >= 0; i-- {
+			e := m.Backups[i]
+
+			if e.CreatedAt <= resTime {
+				break
+			}
+
+			if e.CreatedAt > at {
+				continue
+			}
+
+			b, _, err := s.getBackup(ctx, e.CreatedAt)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, bg := range b.Games {
+				if bg != nil && bg.ID.Value == id {
+					res = bg
+
+					break BackupLoop
+				}
+			}
+		}
+	}
+
+	if res == nil {
+		return nil, errors.New(errors.ErrNotFound,
+			"game snapshot not found",
+			"id", id,
+			"at", at)
+	}
+
+	return res, nil
+}
+
+// restoreGame restores a game to its most recent snapshot taken at or before a
+// time. The snapshot is restored as a new revision of the game, which is
+// recreated if it has been deleted.
+func (s *Server) restoreGame(ctx context.Context,
+	id string,
+	at int64,
+) (*Game, error) {
+	aID, err := request.ContextAccountID(ctx)
+	if err != nil {
+		return nil, errors.New(errors.ErrUnauthorized,
+			"unable to get account id from context")
+	}
+
+	if !request.ValidGameID(id) {
+		return nil, errors.New(errors.ErrInvalidRequest,
+			"invalid game id",
+			"id", id)
+	}
+
+	snap, err := s.gameSnapshot(ctx, id, at)
+	if err != nil {
+		return nil, err
+	}
+
+	g := *snap
+
+	g.AccountID = request.FieldString{Set: true, Valid: true, Value: aID}
+	g.ID = request.FieldString{Set: true, Valid: true, Value: id}
+	g.PreviousID = request.FieldString{}
+	g.Status = request.FieldString{
+		Set: true, Valid: true, Value: request.StatusActive,
+	}
+
+	ctx = context.WithValue(ctx, CtxKeyGameAllowTags, true)
+
+	return s.createGame(ctx, &g)
+}
+
+// postGameRestoreHandler is the post handler used to restore a game to a
+// previous point in time.
+func (s *Server) postGameRestoreHandler(w http.ResponseWriter,
+	r *http.Request,
+) {
+	ctx := r.Context()
+
+	if err := s.checkScope(ctx, request.ScopeGamesWrite); err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	at, err := parseRestoreTime(r.URL.Query().Get("at"))
+	if err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	res, err := s.restoreGame(ctx, chi.URLParam(r, "id"), at)
+	if err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	if err := s.encode(w, r, res); err != nil {
+		s.error(err, w, r)
+	}
+}
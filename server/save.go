@@ -0,0 +1,380 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/dhaifley/game2d/errors"
+	"github.com/dhaifley/game2d/request"
+	"github.com/go-chi/chi/v5"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// maxSaveSlots is the maximum number of save slots a user may have for each
+// game.
+const maxSaveSlots = 20
+
+// saveSlotPattern matches valid save slot names.
+var saveSlotPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// GameSave values represent a game state saved by a user in a named save
+// slot. Save slots belong to the user which saved them, so players of public
+// games are able to keep their own saves.
+type GameSave struct {
+	AccountID string         `bson:"account_id"     json:"account_id"     yaml:"account_id"`
+	UserID    string         `bson:"user_id"        json:"user_id"        yaml:"user_id"`
+	GameID    string         `bson:"game_id"        json:"game_id"        yaml:"game_id"`
+	Slot      string         `bson:"slot"           json:"slot"           yaml:"slot"`
+	Revision  int64          `bson:"revision"       json:"revision"       yaml:"revision"`
+	Size      int64          `bson:"size"           json:"size"           yaml:"size"`
+	Data      map[string]any `bson:"data,omitempty" json:"data,omitempty" yaml:"data,omitempty"`
+	CreatedAt int64          `bson:"created_at"     json:"created_at"     yaml:"created_at"`
+	UpdatedAt int64          `bson:"updated_at"     json:"updated_at"     yaml:"updated_at"`
+}
+
+// validSaveSlot checks that a save slot name is valid.
+func validSaveSlot(slot string) error {
+	if !saveSlotPattern.MatchString(slot) {
+		return errors.New(errors.ErrInvalidRequest,
+			"invalid save slot",
+			"slot", slot)
+	}
+
+	return nil
+}
+
+// saveFilter returns the filter used to find the save slots of the current
+// user for a game.
+func saveFilter(ctx context.Context, id string) (bson.M, error) {
+	aID, err := request.ContextAccountID(ctx)
+	if err != nil {
+		return nil, errors.New(errors.ErrUnauthorized,
+			"unable to get account id from context")
+	}
+
+	uID, err := request.ContextUserID(ctx)
+	if err != nil {
+		return nil, errors.New(errors.ErrUnauthorized,
+			"unable to get user id from context")
+	}
+
+	if !request.ValidGameID(id) {
+		return nil, errors.New(errors.ErrInvalidRequest,
+			"invalid game id",
+			"id", id)
+	}
+
+	return bson.M{"account_id": aID, "user_id": uID, "game_id": id}, nil
+}
+
+// getGameSaves retrieves the save slots of the current user for a game,
+// without their data, from the most recently updated.
+func (s *Server) getGameSaves(ctx context.Context,
+	id string,
+) ([]*GameSave, error) {
+	f, err := saveFilter(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := s.collection(ctx, "saves")
+	if err != nil {
+		return nil, err
+	}
+
+	cur, err := c.Find(ctx, f,
+		options.Find().SetProjection(bson.M{"_id": 0, "data": 0}).
+			SetSort(bson.D{{Key: "updated_at", Value: -1}}).
+			SetLimit(maxSaveSlots))
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrDatabase,
+			"unable to find game saves",
+			"id", id)
+	}
+
+	res := []*GameSave{}
+
+	if err := cur.All(ctx, &res); err != nil {
+		return nil, errors.Wrap(err, errors.ErrDatabase,
+			"unable to decode game saves",
+			"id", id)
+	}
+
+	return res, nil
+}
+
+// getGameSave retrieves a save slot of the current user for a game.
+func (s *Server) getGameSave(ctx context.Context,
+	id, slot string,
+) (*GameSave, error) {
+	f, err := saveFilter(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validSaveSlot(slot); err != nil {
+		return nil, err
+	}
+
+	f["slot"] = slot
+
+	var res *GameSave
+
+	c, err := s.collection(ctx, "saves")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.FindOne(ctx, f,
+		options.FindOne().SetProjection(bson.M{"_id": 0})).
+		Decode(&res); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, errors.New(errors.ErrNotFound,
+				"game save not found",
+				"id", id,
+				"slot", slot)
+		}
+
+		return nil, errors.Wrap(err, errors.ErrDatabase,
+			"unable to get game save",
+			"id", id,
+			"slot", slot)
+	}
+
+	return res, nil
+}
+
+// putGameSave stores a game state in a save slot of the current user for a
+// game, replacing any state previously saved in the slot.
+func (s *Server) putGameSave(ctx context.Context,
+	id, slot string,
+	v *GameSave,
+) (*GameSave, error) {
+	f, err := saveFilter(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validSaveSlot(slot); err != nil {
+		return nil, err
+	}
+
+	if v == nil || len(v.Data) == 0 {
+		return nil, errors.New(errors.ErrInvalidRequest,
+			"missing game save data",
+			"id", id,
+			"slot", slot)
+	}
+
+	g, err := s.getGame(context.WithValue(ctx, CtxKeyGameMinData, true), id)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := bson.Marshal(v.Data)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrInvalidRequest,
+			"unable to encode game save data",
+			"id", id,
+			"slot", slot)
+	}
+
+	if len(b) > maxGameSize {
+		return nil, errors.New(errors.ErrTooLarge,
+			"game save data exceeds 16MB size limit",
+			"id", id,
+			"slot", slot,
+			"size", len(b))
+	}
+
+	c, err := s.collection(ctx, "saves")
+	if err != nil {
+		return nil, err
+	}
+
+	n, err := c.CountDocuments(ctx,
+		bson.M{
+			"account_id": f["account_id"],
+			"user_id":    f["user_id"],
+			"game_id":    id,
+			"slot":       bson.M{"$ne": slot},
+		})
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrDatabase,
+			"unable to count game saves",
+			"id", id)
+	}
+
+	if n >= maxSaveSlots {
+		return nil, errors.New(errors.ErrConflict,
+			"too many game save slots",
+			"id", id,
+			"max", maxSaveSlots)
+	}
+
+	now := time.Now().Unix()
+
+	f["slot"] = slot
+
+	if _, err := c.UpdateOne(ctx, f, bson.M{
+		"$set": bson.M{
+			"revision":   g.Revision.Value,
+			"size":       int64(len(b)),
+			"data":       v.Data,
+			"updated_at": now,
+		},
+		"$setOnInsert": bson.M{"created_at": now},
+	}, options.UpdateOne().SetUpsert(true)); err != nil {
+		return nil, errors.Wrap(err, errors.ErrDatabase,
+			"unable to save game",
+			"id", id,
+			"slot", slot)
+	}
+
+	return s.getGameSave(ctx, id, slot)
+}
+
+// deleteGameSave deletes a save slot of the current user for a game.
+func (s *Server) deleteGameSave(ctx context.Context,
+	id, slot string,
+) error {
+	f, err := saveFilter(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if err := validSaveSlot(slot); err != nil {
+		return err
+	}
+
+	f["slot"] = slot
+
+	c, err := s.collection(ctx, "saves")
+	if err != nil {
+		return err
+	}
+
+	res, err := c.DeleteOne(ctx, f)
+	if err != nil {
+		return errors.Wrap(err, errors.ErrDatabase,
+			"unable to delete game save",
+			"id", id,
+			"slot", slot)
+	}
+
+	if res.DeletedCount == 0 {
+		return errors.New(errors.ErrNotFound,
+			"game save not found",
+			"id", id,
+			"slot", slot)
+	}
+
+	return nil
+}
+
+// getGameSavesHandler is the get handler used to list the save slots of a
+// game.
+func (s *Server) getGameSavesHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if err := s.checkScope(ctx, request.ScopeGamesRead); err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	res, err := s.getGameSaves(ctx, chi.URLParam(r, "id"))
+	if err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	if err := s.encode(w, r, res); err != nil {
+		s.error(err, w, r)
+	}
+}
+
+// getGameSaveHandler is the get handler used to retrieve a save slot of a
+// game.
+func (s *Server) getGameSaveHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if err := s.checkScope(ctx, request.ScopeGamesRead); err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	res, err := s.getGameSave(ctx, chi.URLParam(r, "id"),
+		chi.URLParam(r, "slot"))
+	if err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	if err := s.encode(w, r, res); err != nil {
+		s.error(err, w, r)
+	}
+}
+
+// putGameSaveHandler is the put handler used to store a game state in a save
+// slot of a game.
+func (s *Server) putGameSaveHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if err := s.checkScope(ctx, request.ScopeGamesRead); err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	req := &GameSave{}
+
+	if err := s.decode(r, &req); err != nil {
+		s.error(errors.Wrap(err, errors.ErrInvalidRequest,
+			"unable to decode request"), w, r)
+
+		return
+	}
+
+	res, err := s.putGameSave(ctx, chi.URLParam(r, "id"),
+		chi.URLParam(r, "slot"), req)
+	if err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	if err := s.encode(w, r, res); err != nil {
+		s.error(err, w, r)
+	}
+}
+
+// deleteGameSaveHandler is the delete handler used to delete a save slot of a
+// game.
+func (s *Server) deleteGameSaveHandler(w http.ResponseWriter,
+	r *http.Request,
+) {
+	ctx := r.Context()
+
+	if err := s.checkScope(ctx, request.ScopeGamesRead); err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	if err := s.deleteGameSave(ctx, chi.URLParam(r, "id"),
+		chi.URLParam(r, "slot")); err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,41 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/dhaifley/game2d/client/luaapi"
+	"github.com/dhaifley/game2d/errors"
+	"github.com/go-chi/chi/v5"
+)
+
+// schemaHandler performs routing for schema requests.
+func (s *Server) schemaHandler() http.Handler {
+	r := chi.NewRouter()
+
+	r.With(s.stat, s.trace).Get("/lua-api", s.getLuaAPIHandler)
+
+	return r
+}
+
+// luaAPIDocument returns the JSON description of the Lua environment in
+// which game scripts are run by the client.
+func luaAPIDocument() ([]byte, error) {
+	b, err := json.Marshal(luaapi.Describe())
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrServer,
+			"unable to encode lua api description")
+	}
+
+	return b, nil
+}
+
+// getLuaAPIHandler is the get handler used to retrieve a description of the
+// Lua environment in which game scripts are run by the client.
+func (s *Server) getLuaAPIHandler(w http.ResponseWriter,
+	r *http.Request,
+) {
+	if err := s.encode(w, r, luaapi.Describe()); err != nil {
+		s.error(err, w, r)
+	}
+}
@@ -2,26 +2,29 @@
 package server
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
-	"net"
+	"fmt"
 	"net/http"
 	"net/http/pprof"
 	"os"
 	"path"
 	"reflect"
+	"runtime/debug"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/dhaifley/game2d/app"
 	"github.com/dhaifley/game2d/cache"
 	"github.com/dhaifley/game2d/config"
 	"github.com/dhaifley/game2d/errors"
+	"github.com/dhaifley/game2d/lifecycle"
 	"github.com/dhaifley/game2d/logger"
 	"github.com/dhaifley/game2d/metric"
+	"github.com/dhaifley/game2d/notify"
 	"github.com/dhaifley/game2d/repo"
 	"github.com/dhaifley/game2d/request"
 	"github.com/dhaifley/game2d/static"
@@ -33,6 +36,7 @@ import (
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/crypto/acme/autocert"
 )
 
 // The server version.
@@ -42,22 +46,51 @@ var Version = ""
 type Server struct {
 	http.Server
 	sync.RWMutex
-	health        uint32
-	addr          []string
-	cancels       []context.CancelFunc
-	prompts       map[string]context.CancelFunc
-	cfg           *config.Config
-	log           logger.Logger
-	metric        metric.Recorder
-	tracer        trace.Tracer
-	r             chi.Router
-	db            *mongo.Client
-	cache         cache.Accessor
-	dbOnce        sync.Once
-	authOnce      sync.Once
-	gameOnce      sync.Once
-	getRepoClient func(repoURL string) (repo.Client, error)
-	getPrompter   func(ctx context.Context) Prompter
+	redirect       *http.Server
+	health         uint32
+	started        time.Time
+	addr           []string
+	closing        bool
+	cancels        []context.CancelFunc
+	prompts        map[string]context.CancelFunc
+	jobs           sync.WaitGroup
+	lc             *lifecycle.Manager
+	serving        chan error
+	cfg            *config.Config
+	log            logger.Logger
+	metric         metric.Recorder
+	tracer         trace.Tracer
+	r              chi.Router
+	routes         []string
+	db             *mongo.Client
+	cache          cache.Accessor
+	dbOnce         sync.Once
+	authOnce       sync.Once
+	originOnce     sync.Once
+	gameOnce       sync.Once
+	backupOnce     sync.Once
+	secretOnce     sync.Once
+	automationOnce sync.Once
+	assetOnce      sync.Once
+	workerOnce     sync.Once
+	worker         []byte
+	workerErr      error
+	getRepoClient  func(repoURL string) (repo.Client, error)
+	getPrompter    func(ctx context.Context) Prompter
+	transcriber    Transcriber
+	embedder       Embedder
+	certs          *autocert.Manager
+	dr             chi.Router
+	domains        domainCache
+	origins        originCache
+	notifiers      map[string]notify.Sender
+	provisioner    Provisioner
+	statusHooks    []GameStatusHook
+	hooks          []Hook
+	chainLocks     sync.Map
+	tenants        sync.Map
+	streams        sync.Map
+	openContexts   atomic.Pointer[atomic.Int64]
 }
 
 // NewServer creates a new HTTP server.
@@ -90,6 +123,7 @@ func NewServer(cfg *config.Config,
 		addr:    strings.Split(cfg.ServerAddress(), " "),
 		prompts: make(map[string]context.CancelFunc),
 		health:  http.StatusOK,
+		started: time.Now(),
 		log:     log,
 		tracer:  tracer,
 		metric:  metric,
@@ -103,6 +137,20 @@ func NewServer(cfg *config.Config,
 		s.Server.ReadHeaderTimeout = s.cfg.ServerIdleTimeout()
 	}
 
+	if err := s.initProtocols(); err != nil {
+		return nil, err
+	}
+
+	s.initTLS()
+
+	if err := s.initNotifiers(); err != nil {
+		return nil, err
+	}
+
+	if err := s.initHooks(); err != nil {
+		return nil, err
+	}
+
 	if len(s.cfg.CacheServers()) > 0 {
 		s.cache = cache.NewClient(s.cfg, s.log, s.metric, s.tracer)
 
@@ -112,9 +160,19 @@ func NewServer(cfg *config.Config,
 	}
 
 	s.getRepoClient = func(repoURL string) (repo.Client, error) {
+		if err := s.checkRepoURL(repoURL); err != nil {
+			return nil, err
+		}
+
 		return repo.NewClient(repoURL, s.metric, s.tracer)
 	}
 
+	s.provisioner = NewAccountProvisioner(s)
+
+	if err := s.initLifecycle(); err != nil {
+		return nil, err
+	}
+
 	s.initRouter()
 
 	s.Server.Handler = s.r
@@ -122,6 +180,35 @@ func NewServer(cfg *config.Config,
 	return s, nil
 }
 
+// initProtocols configures the protocols served by the server.
+func (s *Server) initProtocols() error {
+	p := &http.Protocols{}
+
+	for _, v := range s.cfg.ServerProtocols() {
+		switch strings.ToLower(v) {
+		case "http1":
+			p.SetHTTP1(true)
+		case "http2":
+			p.SetHTTP2(true)
+		case "h2c":
+			p.SetUnencryptedHTTP2(true)
+		default:
+			return errors.New(errors.ErrConfiguration,
+				"invalid server protocol",
+				"protocol", v)
+		}
+	}
+
+	if !p.HTTP1() && !p.HTTP2() && !p.UnencryptedHTTP2() {
+		return errors.New(errors.ErrConfiguration,
+			"no server protocols configured")
+	}
+
+	s.Server.Protocols = p
+
+	return nil
+}
+
 // Health gets the status code for the current server health.
 func (s *Server) Health() uint32 {
 	s.RLock()
@@ -152,6 +239,35 @@ func (s *Server) SetPrompter(p Prompter) {
 	}
 }
 
+// SetTranscriber sets the speech to text interface client used for voice
+// prompts.
+func (s *Server) SetTranscriber(t Transcriber) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.transcriber = t
+}
+
+// SetEmbedder sets the embedding interface client used to find similar games.
+func (s *Server) SetEmbedder(e Embedder) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.embedder = e
+}
+
+// SetNotifier sets the notification sender used for a delivery channel.
+func (s *Server) SetNotifier(channel string, sd notify.Sender) {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.notifiers == nil {
+		s.notifiers = map[string]notify.Sender{}
+	}
+
+	s.notifiers[channel] = sd
+}
+
 // Cache gets the server cache for a specific request.
 func (s *Server) Cache(ctx context.Context) cache.Accessor {
 	s.RLock()
@@ -251,212 +367,488 @@ func (s *Server) Mux(w http.ResponseWriter, r *http.Request) {
 	s.r.ServeHTTP(w, r)
 }
 
-// ConnectDB connects to the NoSQL database.
-func (s *Server) ConnectDB() {
+// ConnectDB connects to the NoSQL database. Connection is attempted in the
+// background, and retried with increasing delays until it succeeds or the
+// context is canceled.
+func (s *Server) ConnectDB(ctx context.Context) {
 	s.dbOnce.Do(func() {
-		go func(ctx context.Context) {
-			if s.db != nil {
-				return
-			}
+		go s.connectDB(ctx)
+	})
+}
 
-			retry := false
+// connectDB attempts to connect to the NoSQL database until it succeeds or the
+// context is canceled, then creates the database indexes.
+func (s *Server) connectDB(ctx context.Context) {
+	wait := s.cfg.DBRetryMin()
 
-			for {
-				if retry {
-					time.Sleep(time.Second * 2)
-				} else {
-					retry = true
-				}
+	for s.DB() == nil {
+		c, err := mongo.Connect(options.Client().ApplyURI(s.cfg.DBConn()),
+			options.Client().SetMaxPoolSize(uint64(s.cfg.DBMaxPoolSize())),
+			options.Client().SetMinPoolSize(uint64(s.cfg.DBMinPoolSize())),
+			options.Client().SetTimeout(s.cfg.DBTimeout()))
+		if err == nil {
+			s.Lock()
 
-				if s.db != nil {
-					break
-				}
+			s.db = c
 
-				c, err := mongo.Connect(options.Client().ApplyURI(
-					s.cfg.DBConn()),
-					options.Client().SetMaxPoolSize(
-						uint64(s.cfg.DBMaxPoolSize())),
-					options.Client().SetMinPoolSize(
-						uint64(s.cfg.DBMinPoolSize())))
-				if err != nil {
-					s.log.Log(ctx, logger.LvlError,
-						"unable to connect to NoSQL database",
-						"error", err)
+			s.Unlock()
 
-					continue
-				}
+			break
+		}
 
-				s.Lock()
+		s.log.Log(ctx, logger.LvlError,
+			"unable to connect to NoSQL database",
+			"error", err,
+			"retry_in", wait.String())
 
-				s.db = c
+		select {
+		case <-ctx.Done():
+			s.log.Log(ctx, logger.LvlWarn,
+				"database connection canceled",
+				"error", ctx.Err())
 
-				s.Unlock()
+			return
+		case <-time.After(wait):
+		}
 
-				if _, err := s.db.Database(s.cfg.DBDatabase()).
-					Collection("accounts").Indexes().CreateMany(ctx,
-					[]mongo.IndexModel{{
-						Keys:    bson.D{{Key: "id", Value: 1}},
-						Options: options.Index().SetUnique(true),
-					}, {
-						Keys: bson.D{{Key: "name", Value: 1}},
-					}}); err != nil {
-					s.log.Log(ctx, logger.LvlError,
-						"unable to create account indexes",
-						"error", err,
-						"database", s.cfg.DBDatabase())
-				}
+		wait = min(wait*2, max(s.cfg.DBRetryMax(), s.cfg.DBRetryMin()))
+	}
 
-				if _, err := s.db.Database(s.cfg.DBDatabase()).
-					Collection("users").Indexes().CreateMany(ctx,
-					[]mongo.IndexModel{{
-						Keys: bson.D{
-							{Key: "account_id", Value: 1},
-							{Key: "id", Value: 1},
-						},
-						Options: options.Index().SetUnique(true),
-					}, {
-						Keys: bson.D{
-							{Key: "account_id", Value: 1},
-							{Key: "email", Value: 1},
-						},
-					}}); err != nil {
-					s.log.Log(ctx, logger.LvlError,
-						"unable to create user indexes",
-						"error", err,
-						"database", s.cfg.DBDatabase())
-				}
+	s.createIndexes(ctx, sharedTenant)
 
-				if _, err := s.db.Database(s.cfg.DBDatabase()).
-					Collection("games").Indexes().CreateMany(ctx,
-					[]mongo.IndexModel{{
-						Keys: bson.D{
-							{Key: "account_id", Value: 1},
-							{Key: "id", Value: 1},
-						},
-						Options: options.Index().SetUnique(true),
-					}, {
-						Keys: bson.D{
-							{Key: "account_id", Value: 1},
-							{Key: "previous_id", Value: 1},
-						},
-					}, {
-						Keys: bson.D{
-							{Key: "account_id", Value: 1},
-							{Key: "name", Value: 1},
-						},
-					}, {
-						Keys: bson.D{
-							{Key: "account_id", Value: 1},
-							{Key: "status", Value: 1},
-						},
-					}, {
-						Keys: bson.D{
-							{Key: "account_id", Value: 1},
-							{Key: "source", Value: 1},
-							{Key: "commit_hash", Value: 1},
-						},
-					}, {
-						Keys: bson.D{
-							{Key: "account_id", Value: 1},
-							{Key: "tags", Value: 1},
-						},
-					}, {
-						Keys: bson.D{
-							{Key: "account_id", Value: 1},
-							{Key: "updated_by", Value: 1},
-							{Key: "updated_at", Value: -1},
-						},
-					}}); err != nil {
-					s.log.Log(ctx, logger.LvlError,
-						"unable to create game indexes",
-						"error", err,
-						"database", s.cfg.DBDatabase())
-				}
+	s.log.Log(ctx, logger.LvlInfo,
+		"connected to database",
+		"database", s.cfg.DBDatabase())
+}
 
-				s.log.Log(ctx, logger.LvlInfo,
-					"connected to database",
-					"database", s.cfg.DBDatabase())
-
-				ctx = context.WithValue(ctx, request.CtxKeyScopes,
-					request.ScopeSuperuser)
-				ctx = context.WithValue(ctx, request.CtxKeyAccountID,
-					request.SystemAccount)
-				ctx = context.WithValue(ctx, request.CtxKeyUserID,
-					request.SystemUser)
-
-				a, err := s.createAccount(ctx, &Account{
-					ID: request.FieldString{
-						Set: true, Valid: true,
-						Value: s.cfg.AccountID(),
-					},
-					Name: request.FieldString{
-						Set: true, Valid: true,
-						Value: s.cfg.AccountName(),
-					},
-					Secret: request.FieldString{
-						Set: true, Valid: true, Value: uuid.NewString(),
-					},
-				})
-				if err != nil {
-					s.log.Log(ctx, logger.LvlError,
-						"unable to create account",
-						"error", err)
-				}
+// createIndexes creates the indexes used by the NoSQL database collections of
+// a tenant.
+func (s *Server) createIndexes(ctx context.Context, t *tenant) {
+	if _, err := s.tenantCollection(t, "accounts").Indexes().CreateMany(ctx,
+		[]mongo.IndexModel{{
+			Keys:    bson.D{{Key: "id", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		}, {
+			Keys: bson.D{{Key: "name", Value: 1}},
+		}, {
+			Keys: bson.D{{Key: "allowed_origins", Value: 1}},
+		}}); err != nil {
+		s.log.Log(ctx, logger.LvlError,
+			"unable to create account indexes",
+			"error", err,
+			"database", s.cfg.DBDatabase())
+	}
 
-				if su := os.Getenv("SUPERUSER"); su != "" {
-					if sp := os.Getenv("SUPERUSER_PASSWORD"); sp != "" {
-						if a != nil {
-							if _, err := s.createUser(ctx, &User{
-								AccountID: request.FieldString{
-									Set: true, Valid: true, Value: a.ID.Value,
-								},
-								ID: request.FieldString{
-									Set: true, Valid: true, Value: su,
-								},
-								Scopes: request.FieldString{
-									Set: true, Valid: true,
-									Value: request.ScopeSuperuser,
-								},
-								Password: &sp,
-							}); err != nil {
-								s.log.Log(ctx, logger.LvlError,
-									"unable to create initial superuser",
-									"error", err)
-							}
-						}
-					}
-				}
+	if _, err := s.tenantCollection(t, "users").Indexes().CreateMany(ctx,
+		[]mongo.IndexModel{{
+			Keys: bson.D{
+				{Key: "account_id", Value: 1},
+				{Key: "id", Value: 1},
+			},
+			Options: options.Index().SetUnique(true),
+		}, {
+			Keys: bson.D{
+				{Key: "account_id", Value: 1},
+				{Key: "email", Value: 1},
+			},
+		}}); err != nil {
+		s.log.Log(ctx, logger.LvlError,
+			"unable to create user indexes",
+			"error", err,
+			"database", s.cfg.DBDatabase())
+	}
 
-				if su := os.Getenv("GUEST_USER"); su != "" {
-					if sp := os.Getenv("GUEST_USER_PASSWORD"); sp != "" {
-						if a != nil {
-							if _, err := s.createUser(ctx, &User{
-								AccountID: request.FieldString{
-									Set: true, Valid: true, Value: a.ID.Value,
-								},
-								ID: request.FieldString{
-									Set: true, Valid: true, Value: su,
-								},
-								Scopes: request.FieldString{
-									Set: true, Valid: true,
-									Value: request.ScopeAccountRead + " " +
-										request.ScopeUserRead + " " +
-										request.ScopeGamesRead,
-								},
-								Password: &sp,
-							}); err != nil {
-								s.log.Log(ctx, logger.LvlError,
-									"unable to create initial guest user",
-									"error", err)
-							}
-						}
-					}
-				}
+	if _, err := s.tenantCollection(t, "games").Indexes().CreateMany(ctx,
+		[]mongo.IndexModel{{
+			Keys: bson.D{
+				{Key: "account_id", Value: 1},
+				{Key: "id", Value: 1},
+			},
+			Options: options.Index().SetUnique(true),
+		}, {
+			Keys: bson.D{
+				{Key: "account_id", Value: 1},
+				{Key: "previous_id", Value: 1},
+			},
+		}, {
+			Keys: bson.D{
+				{Key: "account_id", Value: 1},
+				{Key: "name", Value: 1},
+			},
+		}, {
+			Keys: bson.D{
+				{Key: "account_id", Value: 1},
+				{Key: "status", Value: 1},
+			},
+		}, {
+			Keys: bson.D{
+				{Key: "account_id", Value: 1},
+				{Key: "source", Value: 1},
+				{Key: "commit_hash", Value: 1},
+			},
+		}, {
+			Keys: bson.D{
+				{Key: "account_id", Value: 1},
+				{Key: "tags", Value: 1},
+			},
+		}, {
+			Keys: bson.D{
+				{Key: "public", Value: 1},
+				{Key: "tags", Value: 1},
+			},
+		}, {
+			Keys: bson.D{
+				{Key: "account_id", Value: 1},
+				{Key: "updated_by", Value: 1},
+				{Key: "updated_at", Value: -1},
+			},
+		}, {
+			Keys: bson.D{
+				{Key: "account_id", Value: 1},
+				{Key: "created_at", Value: -1},
+			},
+		}, {
+			Keys: bson.D{
+				{Key: "account_id", Value: 1},
+				{Key: "updated_at", Value: -1},
+			},
+		}}); err != nil {
+		s.log.Log(ctx, logger.LvlError,
+			"unable to create game indexes",
+			"error", err,
+			"database", s.cfg.DBDatabase())
+	}
 
-				break
-			}
-		}(context.Background())
-	})
+	if _, err := s.tenantCollection(t, "assets").Indexes().CreateMany(ctx,
+		[]mongo.IndexModel{{
+			Keys: bson.D{
+				{Key: "account_id", Value: 1},
+				{Key: "hash", Value: 1},
+			},
+			Options: options.Index().SetUnique(true),
+		}}); err != nil {
+		s.log.Log(ctx, logger.LvlError,
+			"unable to create asset indexes",
+			"error", err,
+			"database", s.cfg.DBDatabase())
+	}
+
+	if _, err := s.tenantCollection(t, "activity").Indexes().CreateMany(ctx,
+		[]mongo.IndexModel{{
+			Keys: bson.D{
+				{Key: "account_id", Value: 1},
+				{Key: "_id", Value: -1},
+			},
+		}, {
+			Keys: bson.D{
+				{Key: "account_id", Value: 1},
+				{Key: "type", Value: 1},
+				{Key: "_id", Value: -1},
+			},
+		}, {
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		}}); err != nil {
+		s.log.Log(ctx, logger.LvlError,
+			"unable to create activity indexes",
+			"error", err,
+			"database", s.cfg.DBDatabase())
+	}
+
+	if _, err := s.tenantCollection(t, "play_sessions").
+		Indexes().CreateMany(ctx,
+		[]mongo.IndexModel{{
+			Keys: bson.D{
+				{Key: "game_id", Value: 1},
+				{Key: "session_id", Value: 1},
+			},
+			Options: options.Index().SetUnique(true),
+		}, {
+			Keys: bson.D{
+				{Key: "game_id", Value: 1},
+				{Key: "expires_at", Value: 1},
+			},
+		}, {
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		}}); err != nil {
+		s.log.Log(ctx, logger.LvlError,
+			"unable to create play session indexes",
+			"error", err,
+			"database", s.cfg.DBDatabase())
+	}
+
+	if _, err := s.tenantCollection(t, "play_history").
+		Indexes().CreateMany(ctx,
+		[]mongo.IndexModel{{
+			Keys: bson.D{
+				{Key: "user_id", Value: 1},
+				{Key: "game_id", Value: 1},
+			},
+			Options: options.Index().SetUnique(true),
+		}, {
+			Keys: bson.D{
+				{Key: "user_id", Value: 1},
+				{Key: "last_played_at", Value: -1},
+			},
+		}}); err != nil {
+		s.log.Log(ctx, logger.LvlError,
+			"unable to create play history indexes",
+			"error", err,
+			"database", s.cfg.DBDatabase())
+	}
+
+	if _, err := s.tenantCollection(t, "game_embeddings").
+		Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "game_id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	}); err != nil {
+		s.log.Log(ctx, logger.LvlError,
+			"unable to create game embedding indexes",
+			"error", err,
+			"database", s.cfg.DBDatabase())
+	}
+
+	if _, err := s.tenantCollection(t, "domains").Indexes().CreateMany(ctx,
+		[]mongo.IndexModel{{
+			Keys: bson.D{{Key: "domain", Value: 1}},
+			Options: options.Index().SetUnique(true).
+				SetPartialFilterExpression(bson.M{
+					"status": request.StatusActive,
+				}),
+		}, {
+			Keys:    bson.D{{Key: "account_id", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		}, {
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		}}); err != nil {
+		s.log.Log(ctx, logger.LvlError,
+			"unable to create domain indexes",
+			"error", err,
+			"database", s.cfg.DBDatabase())
+	}
+
+	if _, err := s.tenantCollection(t, "import_hashes").
+		Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "account_id", Value: 1},
+			{Key: "path", Value: 1},
+		},
+		Options: options.Index().SetUnique(true),
+	}); err != nil {
+		s.log.Log(ctx, logger.LvlError,
+			"unable to create import hash indexes",
+			"error", err,
+			"database", s.cfg.DBDatabase())
+	}
+
+	if _, err := s.tenantCollection(t, "prompt_events").
+		Indexes().CreateMany(ctx,
+		[]mongo.IndexModel{{
+			Keys: bson.D{
+				{Key: "game_id", Value: 1},
+				{Key: "created_at", Value: -1},
+			},
+		}, {
+			Keys: bson.D{
+				{Key: "lineage_id", Value: 1},
+				{Key: "created_at", Value: 1},
+			},
+		}, {
+			Keys: bson.D{
+				{Key: "account_id", Value: 1},
+				{Key: "created_at", Value: 1},
+			},
+		}, {
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		}}); err != nil {
+		s.log.Log(ctx, logger.LvlError,
+			"unable to create prompt event indexes",
+			"error", err,
+			"database", s.cfg.DBDatabase())
+	}
+
+	if _, err := s.tenantCollection(t, "saves").Indexes().CreateMany(ctx,
+		[]mongo.IndexModel{{
+			Keys: bson.D{
+				{Key: "account_id", Value: 1},
+				{Key: "user_id", Value: 1},
+				{Key: "game_id", Value: 1},
+				{Key: "slot", Value: 1},
+			},
+			Options: options.Index().SetUnique(true),
+		}}); err != nil {
+		s.log.Log(ctx, logger.LvlError,
+			"unable to create game save indexes",
+			"error", err,
+			"database", s.cfg.DBDatabase())
+	}
+
+	if _, err := s.tenantCollection(t, "challenges").Indexes().CreateMany(ctx,
+		[]mongo.IndexModel{{
+			Keys:    bson.D{{Key: "id", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		}, {
+			Keys: bson.D{
+				{Key: "account_id", Value: 1},
+				{Key: "game_id", Value: 1},
+				{Key: "start_at", Value: -1},
+			},
+		}}); err != nil {
+		s.log.Log(ctx, logger.LvlError,
+			"unable to create challenge indexes",
+			"error", err,
+			"database", s.cfg.DBDatabase())
+	}
+
+	if _, err := s.tenantCollection(t, "challenge_scores").
+		Indexes().CreateMany(ctx,
+		[]mongo.IndexModel{{
+			Keys: bson.D{
+				{Key: "challenge_id", Value: 1},
+				{Key: "created_at", Value: 1},
+			},
+		}}); err != nil {
+		s.log.Log(ctx, logger.LvlError,
+			"unable to create challenge score indexes",
+			"error", err,
+			"database", s.cfg.DBDatabase())
+	}
+
+	if _, err := s.tenantCollection(t, "automations").Indexes().CreateMany(ctx,
+		[]mongo.IndexModel{{
+			Keys:    bson.D{{Key: "id", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		}, {
+			Keys: bson.D{
+				{Key: "account_id", Value: 1},
+				{Key: "trigger", Value: 1},
+			},
+		}}); err != nil {
+		s.log.Log(ctx, logger.LvlError,
+			"unable to create automation indexes",
+			"error", err,
+			"database", s.cfg.DBDatabase())
+	}
+
+	if _, err := s.tenantCollection(t, "automation_jobs").
+		Indexes().CreateMany(ctx,
+		[]mongo.IndexModel{{
+			Keys:    bson.D{{Key: "id", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		}, {
+			Keys: bson.D{
+				{Key: "status", Value: 1},
+				{Key: "created_at", Value: 1},
+			},
+		}, {
+			Keys: bson.D{
+				{Key: "automation_id", Value: 1},
+				{Key: "created_at", Value: -1},
+			},
+		}, {
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		}}); err != nil {
+		s.log.Log(ctx, logger.LvlError,
+			"unable to create automation job indexes",
+			"error", err,
+			"database", s.cfg.DBDatabase())
+	}
+
+	if _, err := s.tenantCollection(t, "error_reports").
+		Indexes().CreateMany(ctx,
+		[]mongo.IndexModel{{
+			Keys: bson.D{
+				{Key: "game_id", Value: 1},
+				{Key: "_id", Value: -1},
+			},
+		}, {
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		}}); err != nil {
+		s.log.Log(ctx, logger.LvlError,
+			"unable to create error report indexes",
+			"error", err,
+			"database", s.cfg.DBDatabase())
+	}
+
+	if _, err := s.tenantCollection(t, "prompt_history").
+		Indexes().CreateMany(ctx,
+		[]mongo.IndexModel{{
+			Keys: bson.D{
+				{Key: "account_id", Value: 1},
+				{Key: "history_id", Value: 1},
+				{Key: "_id", Value: -1},
+			},
+		}, {
+			Keys: bson.D{
+				{Key: "account_id", Value: 1},
+				{Key: "history_id", Value: 1},
+				{Key: "game_id", Value: 1},
+			},
+		}}); err != nil {
+		s.log.Log(ctx, logger.LvlError,
+			"unable to create prompt history indexes",
+			"error", err,
+			"database", s.cfg.DBDatabase())
+	}
+
+	if _, err := s.tenantCollection(t, "notifications").
+		Indexes().CreateMany(ctx,
+		[]mongo.IndexModel{{
+			Keys: bson.D{
+				{Key: "account_id", Value: 1},
+				{Key: "user_id", Value: 1},
+				{Key: "_id", Value: -1},
+			},
+		}, {
+			Keys: bson.D{
+				{Key: "account_id", Value: 1},
+				{Key: "user_id", Value: 1},
+				{Key: "read", Value: 1},
+			},
+		}, {
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		}}); err != nil {
+		s.log.Log(ctx, logger.LvlError,
+			"unable to create notification indexes",
+			"error", err,
+			"database", s.cfg.DBDatabase())
+	}
+
+	if _, err := s.tenantCollection(t, "notification_preferences").
+		Indexes().CreateMany(ctx,
+		[]mongo.IndexModel{{
+			Keys: bson.D{
+				{Key: "account_id", Value: 1},
+				{Key: "user_id", Value: 1},
+			},
+			Options: options.Index().SetUnique(true),
+		}}); err != nil {
+		s.log.Log(ctx, logger.LvlError,
+			"unable to create notification preference indexes",
+			"error", err,
+			"database", s.cfg.DBDatabase())
+	}
+
+	if _, err := s.tenantCollection(t, "flags").Indexes().CreateMany(ctx,
+		[]mongo.IndexModel{{
+			Keys: bson.D{
+				{Key: "account_id", Value: 1},
+				{Key: "user_id", Value: 1},
+				{Key: "name", Value: 1},
+			},
+			Options: options.Index().SetUnique(true),
+		}}); err != nil {
+		s.log.Log(ctx, logger.LvlError,
+			"unable to create feature flag indexes",
+			"error", err,
+			"database", s.cfg.DBDatabase())
+	}
 }
 
 // UpdateAuthConfig retrieves and begins periodic update of authentication
@@ -490,6 +882,20 @@ func (s *Server) UpdateGameImports() {
 	})
 }
 
+// UpdateGameBackups periodically backs up account games to their import
+// repositories, if they can be written to.
+func (s *Server) UpdateGameBackups() {
+	s.backupOnce.Do(func() {
+		go func() {
+			for s.db == nil {
+				time.Sleep(100 * time.Millisecond)
+			}
+
+			s.addCancelFunc(s.updateGameBackups(context.Background()))
+		}()
+	})
+}
+
 // UpdateGamePrompts periodically updates pending game prompts.
 func (s *Server) UpdateGamePrompts() {
 	s.gameOnce.Do(func() {
@@ -503,62 +909,75 @@ func (s *Server) UpdateGamePrompts() {
 	})
 }
 
-// Serve listens for and processes HTTP requests.
-func (s *Server) Serve() error {
-	ctx := context.Background()
-
-	s.RLock()
-
-	addr := s.addr
-
-	s.RUnlock()
-
-	s.log.Log(ctx, logger.LvlDebug, "starting server",
-		"address", addr)
+// UpdateSecrets periodically reads configuration secret files and resolves
+// configuration secret references again, so that rotated secrets are used.
+func (s *Server) UpdateSecrets() {
+	s.secretOnce.Do(func() {
+		if s.cfg.SecretRotateInterval() <= 0 {
+			return
+		}
 
-	if len(addr) == 0 {
-		return errors.New(errors.ErrConfiguration,
-			"no servers configured")
-	}
+		s.addCancelFunc(s.updateSecrets(context.Background()))
+	})
+}
 
-	ech := make(chan error, len(addr))
+// updateSecrets starts rotating configuration secrets, returning a function
+// which stops it.
+func (s *Server) updateSecrets(ctx context.Context) context.CancelFunc {
+	ctx, cancel := context.WithCancel(ctx)
 
-	var wg sync.WaitGroup
+	s.jobs.Add(1)
 
-	for _, a := range addr {
-		wg.Add(1)
+	go func(ctx context.Context) {
+		defer s.jobs.Done()
 
-		go func(addr string) {
-			defer wg.Done()
+		tick := time.NewTicker(s.cfg.SecretRotateInterval())
 
-			lis, err := net.Listen("tcp", addr)
-			if err != nil {
-				ech <- errors.Wrap(err, errors.ErrServer,
-					"server unable to start listening on "+addr)
+		defer tick.Stop()
 
+		for {
+			select {
+			case <-ctx.Done():
 				return
-			}
+			case <-tick.C:
+				changed, err := s.cfg.RotateSecrets()
+				if err != nil {
+					s.log.Log(ctx, logger.LvlError,
+						"unable to rotate configuration secrets",
+						"error", err)
+				}
 
-			s.log.Log(ctx, logger.LvlInfo, "server listening",
-				"address", addr)
+				if !changed {
+					break
+				}
 
-			if err = s.Server.Serve(lis); err != nil {
-				if err != http.ErrServerClosed {
-					ech <- errors.Wrap(err, errors.ErrServer,
-						"server error")
+				s.log.Log(ctx, logger.LvlInfo,
+					"configuration secrets rotated")
 
-					return
+				if s.metric != nil {
+					s.metric.Increment(ctx, "secrets_rotated")
 				}
 			}
+		}
+	}(ctx)
 
-			ech <- nil
-		}(a)
+	return cancel
+}
+
+// Serve starts the server, if it was not started, and processes HTTP requests
+// until the HTTP servers are stopped, returning the first error serving them.
+func (s *Server) Serve() error {
+	ctx := context.Background()
+
+	if err := s.Start(ctx); err != nil {
+		return err
 	}
 
-	go func() {
-		wg.Wait()
-		close(ech)
-	}()
+	s.RLock()
+
+	ech := s.serving
+
+	s.RUnlock()
 
 	for err := range ech {
 		if err != nil {
@@ -572,7 +991,10 @@ func (s *Server) Serve() error {
 	return nil
 }
 
-// Close releases all server games immediately.
+// Close releases all server games immediately. Its components are stopped
+// in the reverse order of their dependencies, without waiting for active
+// requests to complete. Components used without Start, such as a database
+// connected by ConnectDB, are stopped too.
 func (s *Server) Close() {
 	ctx := context.Background()
 
@@ -581,41 +1003,22 @@ func (s *Server) Close() {
 	s.log.Log(ctx, logger.LvlInfo, "server closing")
 
 	s.health = http.StatusServiceUnavailable
+	s.closing = true
 
 	s.Unlock()
 
-	s.RLock()
-
-	defer s.RUnlock()
-
-	if err := s.Server.Close(); err != nil {
-		s.log.Log(ctx, logger.LvlError,
-			"error during server close",
+	if err := s.lc.StopAll(ctx); err != nil {
+		s.log.Log(ctx, logger.LvlError, "error during server close",
 			"error", err)
 	}
-
-	for _, cancel := range s.prompts {
-		if cancel != nil {
-			cancel()
-		}
-	}
-
-	for _, cancel := range s.cancels {
-		if cancel != nil {
-			cancel()
-		}
-	}
-
-	if s.db != nil {
-		if err := s.db.Disconnect(ctx); err != nil {
-			s.log.Log(ctx, logger.LvlError,
-				"error during database disconnect",
-				"error", err)
-		}
-	}
 }
 
-// Shutdown releases all server games gracefully.
+// Shutdown releases all server games gracefully. The server first reports
+// itself as unhealthy and stops keeping connections alive for the configured
+// drain delay, then waits up to the drain timeout for active requests to
+// complete. HTTP/2 clients are sent a GOAWAY frame as the server shuts down.
+// The background jobs are then stopped, and only then are the cache and
+// database closed.
 func (s *Server) Shutdown(ctx context.Context) {
 	s.Lock()
 
@@ -625,44 +1028,21 @@ func (s *Server) Shutdown(ctx context.Context) {
 
 	s.Unlock()
 
-	s.RLock()
-
-	defer s.RUnlock()
+	s.Server.SetKeepAlivesEnabled(false)
 
-	ctx, cancel := context.WithTimeout(ctx, s.cfg.ServerTimeout())
+	if d := s.cfg.ServerDrainDelay(); d > 0 {
+		s.log.Log(ctx, logger.LvlInfo, "server draining connections",
+			"delay", d)
 
-	defer cancel()
-
-	if err := s.Server.Shutdown(ctx); err != nil {
-		s.log.Log(ctx, logger.LvlError, "error during server shutdown",
-			"error", err)
-
-		if err := s.Server.Close(); err != nil {
-			s.log.Log(ctx, logger.LvlError, "error during server close",
-				"error", err)
-		}
-
-		return
-	}
-
-	for _, cancel := range s.prompts {
-		if cancel != nil {
-			cancel()
+		select {
+		case <-ctx.Done():
+		case <-time.After(d):
 		}
 	}
 
-	for _, cancel := range s.cancels {
-		if cancel != nil {
-			cancel()
-		}
-	}
-
-	if s.db != nil {
-		if err := s.db.Disconnect(ctx); err != nil {
-			s.log.Log(ctx, logger.LvlError,
-				"error during database disconnect",
-				"error", err)
-		}
+	if err := s.lc.StopAll(ctx); err != nil {
+		s.log.Log(ctx, logger.LvlError, "error during server shutdown",
+			"error", err)
 	}
 }
 
@@ -670,6 +1050,12 @@ func (s *Server) Shutdown(ctx context.Context) {
 func (s *Server) initRouter() {
 	base := chi.NewRouter()
 
+	s.initDomainRouter()
+
+	base.Use(s.customDomain)
+
+	base.NotFound(s.notFound)
+
 	r := chi.NewRouter()
 
 	base.Mount(s.cfg.ServerPathPrefix(), r)
@@ -678,6 +1064,7 @@ func (s *Server) initRouter() {
 		s.context,
 		s.header,
 		s.logger,
+		s.recoverer,
 	)
 
 	r.NotFound(s.notFound)
@@ -695,18 +1082,54 @@ func (s *Server) initRouter() {
 	r.Get("/debug/mutex", pprof.Handler("mutex").ServeHTTP)
 	r.Get("/debug/pprof", pprof.Index)
 
-	r.Mount("/healthz", s.HealthHandler())
-	r.Mount("/health", s.HealthHandler())
-	r.Mount("/account", s.accountHandler())
-	r.Mount("/user", s.userHandler())
-	r.Mount("/login", s.loginHandler())
-	r.Mount("/games", s.gamesHandler())
-
-	s.initStaticRoutes(base)
+	r.With(s.cors(http.MethodGet, http.MethodPost, http.MethodPut,
+		http.MethodPatch)).Mount("/healthz", s.HealthHandler())
+	r.With(s.cors(http.MethodGet, http.MethodPost, http.MethodPut,
+		http.MethodPatch)).Mount("/health", s.HealthHandler())
+	r.With(s.cors(http.MethodGet)).Mount("/readyz", s.ReadyHandler())
+	r.With(s.cors(http.MethodGet)).Mount("/ready", s.ReadyHandler())
+	r.With(s.cors(http.MethodGet, http.MethodPost, http.MethodPut,
+		http.MethodDelete)).Mount("/account", s.accountHandler())
+	r.With(s.cors(http.MethodPost)).Mount("/billing", s.billingHandler())
+	r.With(s.cors(http.MethodGet, http.MethodPut, http.MethodPatch,
+		http.MethodDelete)).Mount("/user", s.userHandler())
+	r.With(s.cors(http.MethodPost)).Mount("/login", s.loginHandler())
+	r.With(s.cors(http.MethodGet, http.MethodPost, http.MethodPut,
+		http.MethodPatch, http.MethodDelete)).
+		Mount("/games", s.gamesHandler())
+	r.With(s.cors(http.MethodGet, http.MethodPost, http.MethodPut,
+		http.MethodPatch, http.MethodDelete)).
+		Mount("/challenges", s.challengesHandler())
+	r.With(s.cors(http.MethodGet, http.MethodPost, http.MethodPut,
+		http.MethodPatch, http.MethodDelete)).
+		Mount("/automations", s.automationsHandler())
+	r.With(s.cors(http.MethodGet, http.MethodPut, http.MethodDelete)).
+		Mount("/flags", s.flagsHandler())
+	r.With(s.cors(http.MethodGet, http.MethodPost)).
+		Mount("/graphql", s.graphqlHandler())
+	r.With(s.cors(http.MethodGet)).Mount("/errors", s.errorsHandler())
+	r.With(s.cors(http.MethodGet)).Mount("/schema", s.schemaHandler())
+	r.With(s.cors(http.MethodGet, http.MethodPost)).
+		Mount("/admin", s.adminHandler())
+
+	base.With(s.context, s.header, s.logger, s.recoverer,
+		s.cors(http.MethodGet)).
+		Mount("/embed", s.embedHandler())
+
+	base.With(s.context, s.header, s.logger, s.recoverer, s.dbAvail, s.stat,
+		s.trace).Get("/sitemap.xml", s.getSitemapHandler)
+
+	base.With(s.context, s.header, s.logger, s.recoverer,
+		s.cors(http.MethodGet)).
+		Mount("/assets", s.assetsHandler())
+
+	s.initStaticRoutes(base.With(s.context, s.header, s.logger, s.recoverer))
+
+	routes := routePatterns(base, s.cfg.ServerPathPrefix())
 
 	s.Lock()
 
-	s.r = base
+	s.r, s.routes = base, routes
 
 	s.Unlock()
 }
@@ -715,139 +1138,49 @@ func (s *Server) initRouter() {
 func (s *Server) initStaticRoutes(r chi.Router) {
 	r.Get(path.Join(s.cfg.ServerPathPrefix(), "openapi.json"),
 		func(w http.ResponseWriter, r *http.Request) {
-			v, err := static.FS.ReadFile("openapi.json")
-			if err != nil {
-				s.error(err, w, r)
-
-				return
-			}
-
-			w.Header().Set("Content-Type", "application/json; charset=UTF-8")
-
-			if _, err := w.Write(v); err != nil {
-				s.error(err, w, r)
-
-				return
-			}
+			s.serveFile(w, r, static.FS, "openapi.json",
+				"application/json; charset=UTF-8")
 		})
 
 	r.Get(path.Join(s.cfg.ServerPathPrefix(), "openapi.yaml"),
 		func(w http.ResponseWriter, r *http.Request) {
-			v, err := static.FS.ReadFile("openapi.yaml")
-			if err != nil {
-				s.error(err, w, r)
-
-				return
-			}
-
-			w.Header().Set("Content-Type", "text/html; charset=UTF-8")
-
-			if _, err := w.Write(v); err != nil {
-				s.error(err, w, r)
-
-				return
-			}
+			s.serveFile(w, r, static.FS, "openapi.yaml",
+				"text/html; charset=UTF-8")
 		})
 
 	r.Get(path.Join(s.cfg.ServerPathPrefix(), "docs"),
 		func(w http.ResponseWriter, r *http.Request) {
-			v, err := static.FS.ReadFile("index.html")
-			if err != nil {
-				s.error(err, w, r)
-
-				return
-			}
-
-			w.Header().Set("Content-Type", "text/html; charset=UTF-8")
-
-			if _, err := w.Write(v); err != nil {
-				s.error(err, w, r)
-
-				return
-			}
+			s.serveFile(w, r, static.FS, "index.html",
+				"text/html; charset=UTF-8")
 		})
 
 	r.Get("/scripts/wasm_exec.js",
 		func(w http.ResponseWriter, r *http.Request) {
-			v, err := static.FS.ReadFile("scripts/wasm_exec.js")
-			if err != nil {
-				s.error(err, w, r)
-
-				return
-			}
-
-			w.Header().Set("Content-Type", "text/javascript; charset=UTF-8")
-
-			if _, err := w.Write(v); err != nil {
-				s.error(err, w, r)
-
-				return
-			}
+			s.serveFile(w, r, static.FS, "scripts/wasm_exec.js",
+				"text/javascript; charset=UTF-8")
 		})
 
 	r.Get("/game2d.wasm",
 		func(w http.ResponseWriter, r *http.Request) {
-			v, err := static.FS.ReadFile("game2d.wasm")
-			if err != nil {
-				s.error(err, w, r)
-
-				return
-			}
-
-			w.Header().Set("Content-Type", "application/wasm")
-
-			if _, err := w.Write(v); err != nil {
-				s.error(err, w, r)
-
-				return
-			}
+			s.serveFile(w, r, static.FS, "game2d.wasm", "application/wasm")
 		})
 
+	r.Get("/sw.js", s.getServiceWorkerHandler)
+
 	r.Get("/client",
 		func(w http.ResponseWriter, r *http.Request) {
-			v, err := static.FS.ReadFile("client.html")
-			if err != nil {
-				s.error(err, w, r)
-
-				return
-			}
-
-			w.Header().Set("Content-Type", "text/html; charset=UTF-8")
-
-			if _, err := w.Write(v); err != nil {
-				s.error(err, w, r)
-
-				return
-			}
+			s.serveFile(w, r, static.FS, "client.html",
+				"text/html; charset=UTF-8")
 		})
 
 	r.Get("/", func(w http.ResponseWriter, r *http.Request) {
-		v, err := app.FS.ReadFile("dist/index.html")
-		if err != nil {
-			s.error(err, w, r)
-
-			return
-		}
-
-		w.Header().Set("Content-Type", "text/html; charset=UTF-8")
-
-		if _, err := w.Write(v); err != nil {
-			s.error(err, w, r)
-
-			return
-		}
+		s.serveFile(w, r, app.FS, "dist/index.html",
+			"text/html; charset=UTF-8")
 	})
 
 	r.Get("/*", func(w http.ResponseWriter, r *http.Request) {
 		filePath := "dist" + r.URL.Path
 
-		content, err := app.FS.ReadFile(filePath)
-		if err != nil {
-			s.error(err, w, r)
-
-			return
-		}
-
 		contentType := "application/octet-stream"
 
 		switch {
@@ -869,11 +1202,7 @@ func (s *Server) initStaticRoutes(r chi.Router) {
 			contentType = "image/x-icon"
 		}
 
-		w.Header().Set("Content-Type", contentType)
-
-		if _, err := w.Write(content); err != nil {
-			s.error(err, w, r)
-		}
+		s.serveFile(w, r, app.FS, filePath, contentType)
 	})
 }
 
@@ -894,9 +1223,7 @@ func (s *Server) getCache(ctx context.Context,
 			"error", err,
 			"cache_key", key)
 	} else if ci != nil {
-		buf := bytes.NewBuffer(ci.Value)
-
-		if err := json.NewDecoder(buf).Decode(&value); err != nil {
+		if err := json.Unmarshal(ci.Value, &value); err != nil {
 			s.log.Log(ctx, logger.LvlError,
 				"unable to decode account cache value",
 				"error", err,
@@ -992,34 +1319,37 @@ func (s *Server) context(next http.Handler) http.Handler {
 		}
 
 		if r.Body != nil {
-			r.Body = http.MaxBytesReader(w, r.Body,
-				s.cfg.ServerMaxRequestSize())
+			r.Body = http.MaxBytesReader(w, r.Body, s.maxRequestSize(r))
 		}
 
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
+// maxRequestSize returns the maximum allowable size in bytes of a request,
+// which depends on the route group of the request.
+func (s *Server) maxRequestSize(r *http.Request) int64 {
+	p := strings.TrimPrefix(r.URL.Path, s.cfg.ServerPathPrefix())
+
+	group, rest, _ := strings.Cut(strings.TrimPrefix(p, "/"), "/")
+
+	switch group {
+	case "login", "account", "user":
+		return s.cfg.ServerMaxAuthRequestSize()
+	case "games":
+		if rest == "tags" || strings.HasSuffix(rest, "/tags") {
+			return s.cfg.ServerMaxTagsRequestSize()
+		}
+
+		return s.cfg.ServerMaxGamesRequestSize()
+	default:
+		return s.cfg.ServerMaxRequestSize()
+	}
+}
+
 // header wraps request handlers with default header values.
 func (s *Server) header(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		wd := s.cfg.ServerHost()
-
-		if strings.HasSuffix(r.Header.Get("Origin"), "."+wd) ||
-			r.Header.Get("Origin") == wd ||
-			r.Header.Get("Origin") == "https://"+wd ||
-			r.Header.Get("Origin") == "http://"+wd {
-			originStr := r.Header.Get("Origin")
-
-			w.Header().Set("Access-Control-Allow-Origin", originStr)
-			w.Header().Set("Access-Control-Allow-Credentials", "true")
-			w.Header().Set("Access-Control-Allow-Headers",
-				"Origin, X-Requested-With, X-HTTP-Method-Override, "+
-					"Content-Type, Accept, Referer, User-Agent")
-			w.Header().Set("Access-Control-Allow-Methods",
-				"GET, PUT, POST, OPTIONS")
-		}
-
 		host, err := os.Hostname()
 		if err != nil {
 			host = "unknown"
@@ -1027,8 +1357,8 @@ func (s *Server) header(next http.Handler) http.Handler {
 
 		w.Header().Set("X-Server", host)
 		w.Header().Set("X-Version", Version)
-		w.Header().Set("Vary", "Accept-Encoding, Origin")
-		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.Header().Set("Vary", "Accept, Accept-Encoding, Origin")
+		w.Header().Set("Content-Type", contentType(responseType(r)))
 
 		if s.cfg.ServiceMaintenance() {
 			s.error(errors.New(errors.ErrMaintenance,
@@ -1038,12 +1368,6 @@ func (s *Server) header(next http.Handler) http.Handler {
 			return
 		}
 
-		if r.Method == http.MethodOptions {
-			s.noContent(w, r)
-
-			return
-		}
-
 		next.ServeHTTP(w, r)
 	})
 }
@@ -1106,13 +1430,62 @@ func (s *Server) logger(next http.Handler) http.Handler {
 	})
 }
 
+// recoverer wraps request handlers to recover from panics. The stack of the
+// panic is logged with a generated error ID, which is included in the error
+// response, so that reported failures can be matched to the logs.
+func (s *Server) recoverer(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			v := recover()
+			if v == nil {
+				return
+			}
+
+			if v == http.ErrAbortHandler {
+				panic(v)
+			}
+
+			ctx := r.Context()
+
+			eID := uuid.NewString()
+
+			route := "not found"
+
+			if rc := chi.RouteContext(ctx); rc != nil {
+				route = rc.RoutePattern()
+			}
+
+			s.log.Log(ctx, logger.LvlError, "panic recovered",
+				"error_id", eID,
+				"panic", fmt.Sprint(v),
+				"stack", string(debug.Stack()),
+				"kind", r.Method,
+				"uri", r.RequestURI)
+
+			if mr := s.metric; mr != nil {
+				mr.Increment(ctx, "panics", "route:"+route)
+			}
+
+			w.Header().Set("X-Error-ID", eID)
+
+			s.error(errors.New(errors.ErrServer,
+				"An unexpected error occurred, please contact support "+
+					"with the error ID",
+				"error_id", eID), w, r)
+		}()
+
+		next.ServeHTTP(w, r)
+	})
+}
+
 // dbAvail wraps request handlers with a check to ensure the database is up.
 func (s *Server) dbAvail(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if s.DB() == nil {
 			s.error(errors.New(errors.ErrUnavailable,
 				"The service database is currently unavailable, "+
-					"please try back later"), w, r)
+					"please try back later").
+				WithReason(errors.ReasonDatabaseUnavailable), w, r)
 
 			return
 		}
@@ -1136,15 +1509,38 @@ func (s *Server) error(err error, w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	if e.Reason == "" {
+		e.Reason = errors.ReasonOf(e)
+	}
+
 	// Store the status code in context
 	r.Header.Set("X-Status-Code", strconv.FormatInt(int64(e.Code.Status), 10))
 
+	// Errors are always encoded as JSON.
+	w.Header().Set("Content-Type", ContentTypeJSON+"; charset=utf-8")
+
+	// Rate limit errors tell clients when they may retry.
+	if rl := e.Rate; rl != nil {
+		if rl.Limit > 0 {
+			w.Header().Set("X-RateLimit-Limit",
+				strconv.FormatInt(rl.Limit, 10))
+			w.Header().Set("X-RateLimit-Remaining",
+				strconv.FormatInt(rl.Remaining, 10))
+		}
+
+		if rl.Reset > 0 {
+			w.Header().Set("X-RateLimit-Reset",
+				strconv.FormatInt(rl.Reset, 10))
+			w.Header().Set("Retry-After", strconv.FormatInt(
+				int64(rl.RetryAfter(time.Now()).Seconds()), 10))
+		}
+	}
+
 	// Send information to the user if the service is under maintenance.
 	if e.Code.Name == "Maintenance" {
-		w.WriteHeader(e.Code.Status)
-
-		if err := json.NewEncoder(w).Encode(map[string]string{
+		if err := writeJSON(w, e.Code.Status, map[string]string{
 			"status": "The service is currently undergoing maintenance",
+			"reason": e.Reason,
 		}); err != nil {
 			s.log.Log(ctx, logger.LvlError,
 				"unable to encode error into JSON",
@@ -1211,9 +1607,7 @@ func (s *Server) error(err error, w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	w.WriteHeader(e.Code.Status)
-
-	if err := json.NewEncoder(w).Encode(e); err != nil {
+	if err := writeJSON(w, e.Code.Status, e); err != nil {
 		s.log.Log(ctx, logger.LvlError,
 			"unable to encode error into JSON",
 			"error", err)
@@ -1226,12 +1620,6 @@ func (s *Server) noContent(w http.ResponseWriter, _ *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
-// notFound is the handler function for 404 errors.
-func (s *Server) notFound(w http.ResponseWriter, r *http.Request) {
-	s.error(errors.New(errors.ErrNotFound,
-		"game not found"), w, r)
-}
-
 // methodNotAllowed is the handler function for 405 errors.
 func (s *Server) methodNotAllowed(w http.ResponseWriter, r *http.Request) {
 	s.error(errors.New(errors.ErrNotAllowed,
@@ -3,17 +3,25 @@ package server_test
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/dhaifley/game2d/cache"
+	"github.com/dhaifley/game2d/cbor"
 	"github.com/dhaifley/game2d/config"
+	"github.com/dhaifley/game2d/errors"
 	"github.com/dhaifley/game2d/logger"
 	"github.com/dhaifley/game2d/server"
+	"github.com/dhaifley/game2d/server/servertest"
 )
 
 const (
@@ -22,60 +30,136 @@ const (
 	TestUUID = "11223344-5566-7788-9900-aabbccddeeff"
 	TestName = "test"
 	basePath = config.DefaultServerPathPrefix
+
+	TestBootstrapToken = "test-bootstrap-token"
+
+	TestAssetHash = "9f86d081884c7d659a2feaa0c55ad015" +
+		"a3bf4f1b2b0b822cd15d6c15b0f00a08"
 )
 
 var servicesLock sync.Mutex
 
+// testHost is the host of the URLs used by the integration tests, which is
+// replaced by that of the test server.
+const testHost = "http://localhost:8080"
+
+// testServer is the server used by the integration tests, which is nil if
+// they are skipped.
+var testServer *servertest.Server
+
 func TestMain(m *testing.M) {
+	short := false
+
 	for _, arg := range os.Args {
 		if arg == "-test.short=true" {
-			// Skipping integration tests.
-			os.Exit(0)
+			short = true
 		}
 	}
 
-	os.Setenv("SUPERUSER", "admin")
-	os.Setenv("SUPERUSER_PASSWORD", "admin")
+	if !short {
+		startTestServer()
+	}
+
+	code := m.Run()
+
+	if testServer != nil {
+		testServer.Close()
+	}
+
+	if n := waitContexts(10 * time.Second); n != 0 && code == 0 {
+		fmt.Println("leaked contexts:", n)
+
+		code = 1
+	}
+
+	os.Exit(code)
+}
+
+// openContexts is the number of operation contexts created by the test server
+// which have not been canceled.
+var openContexts atomic.Int64
+
+// waitContexts waits for the operation contexts created by the tests to be
+// canceled, and returns the number which were not, and so leaked.
+func waitContexts(d time.Duration) int64 {
+	for end := time.Now().Add(d); time.Now().Before(end); {
+		if openContexts.Load() == 0 {
+			return 0
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	return openContexts.Load()
+}
+
+// startTestServer starts the server used by the integration tests, which are
+// skipped by running the tests in short mode. The tests fail if it can not be
+// started.
+func startTestServer() {
+	os.Setenv("AUTH_BOOTSTRAP_TOKEN", TestBootstrapToken)
 	os.Setenv("GUEST_USER", "guest")
 	os.Setenv("GUEST_USER_PASSWORD", "guest")
 
 	cfg := config.NewDefault()
 
-	svr, err := server.NewServer(cfg,
-		logger.New(cfg.LogOut(), cfg.LogFormat(), cfg.LogLevel()), nil, nil)
+	ts, err := servertest.NewServer(context.Background(), &servertest.Options{
+		Config: cfg,
+		Log:    logger.New(cfg.LogOut(), cfg.LogFormat(), cfg.LogLevel()),
+	})
 	if err != nil {
-		fmt.Println("server init error", err)
+		fmt.Println("test server error", err)
 
 		os.Exit(1)
 	}
 
-	svr.SetPrompter(server.NewMockPrompter(svr, "The AI has responded.", 0))
+	testServer = ts
 
-	svr.ConnectDB()
+	ts.API.SetOpenContexts(&openContexts)
 
-	for svr.DB() == nil {
-		time.Sleep(time.Millisecond * 100)
+	ts.API.UpdateGameImports()
+}
+
+// testURL returns the URL of a test request on the test server.
+func testURL(u string) string {
+	return strings.Replace(u, testHost, testServer.URL, 1)
+}
+
+func TestProvision(t *testing.T) {
+	t.Parallel()
+
+	cfg := config.NewDefault()
+
+	svr, err := server.NewServer(cfg, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
 	}
 
-	svr.UpdateGameImports()
+	ctx, cancel := context.WithCancel(context.Background())
 
-	ctx := context.Background()
+	cancel()
 
-	go func() {
-		if err := svr.Serve(); err != nil {
-			fmt.Println("server error", err)
+	called := false
 
-			os.Exit(1)
-		}
-	}()
+	svr.SetProvisioner(server.ProvisionerFunc(func(ctx context.Context) error {
+		called = true
 
-	time.Sleep(time.Second)
+		return nil
+	}))
 
-	code := m.Run()
+	if err := svr.Provision(ctx); err == nil {
+		t.Errorf("Expected error provisioning without a database")
+	}
 
-	svr.Shutdown(ctx)
+	if called {
+		t.Errorf("Expected provisioner not to be called")
+	}
 
-	os.Exit(code)
+	svr.SetProvisioner(nil)
+
+	if err := svr.Provision(ctx); err != nil {
+		t.Errorf("Unexpected error with provisioning disabled: %v", err)
+	}
 }
 
 func BenchmarkServerPostGame(b *testing.B) {
@@ -92,7 +176,7 @@ func BenchmarkServerPostGame(b *testing.B) {
 		b.Fatal(err)
 	}
 
-	svr.ConnectDB()
+	svr.ConnectDB(context.Background())
 
 	for svr.DB() == nil {
 		time.Sleep(time.Millisecond * 100)
@@ -146,7 +230,7 @@ func BenchmarkServerGetGame(b *testing.B) {
 		b.Fatal(err)
 	}
 
-	svr.ConnectDB()
+	svr.ConnectDB(context.Background())
 
 	for svr.DB() == nil {
 		time.Sleep(time.Millisecond * 100)
@@ -177,3 +261,174 @@ func BenchmarkServerGetGame(b *testing.B) {
 		svr.Mux(w, r)
 	}
 }
+
+func TestEncoding(t *testing.T) {
+	t.Parallel()
+
+	svr, err := server.NewServer(config.NewDefault(), nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jb, err := json.Marshal(errors.Catalog)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cb, err := cbor.Marshal(errors.Catalog)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		url    string
+		accept string
+		status int
+		body   []byte
+	}{{
+		name:   "json",
+		url:    basePath + "/errors/catalog",
+		accept: server.ContentTypeJSON,
+		status: http.StatusOK,
+		body:   append(jb, '\n'),
+	}, {
+		name:   "cbor",
+		url:    basePath + "/errors/catalog",
+		accept: server.ContentTypeCBOR,
+		status: http.StatusOK,
+		body:   cb,
+	}, {
+		name:   "error",
+		url:    basePath + "/unknown",
+		accept: server.ContentTypeCBOR,
+		status: http.StatusNotFound,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			r := httptest.NewRequest(http.MethodGet, tt.url, nil)
+
+			r.Header.Set("Accept", tt.accept)
+
+			w := httptest.NewRecorder()
+
+			svr.Mux(w, r)
+
+			if w.Code != tt.status {
+				t.Errorf("Expected status: %v, got: %v", tt.status, w.Code)
+			}
+
+			if tt.body != nil && !bytes.Equal(w.Body.Bytes(), tt.body) {
+				t.Errorf("Expected body: %s, got: %s", tt.body, w.Body)
+			}
+
+			exp := strconv.Itoa(w.Body.Len())
+
+			if cl := w.Header().Get("Content-Length"); cl != exp {
+				t.Errorf("Expected content length: %v, got: %v", exp, cl)
+			}
+		})
+	}
+}
+
+// panicCache values are caches which panic when they are pinged.
+type panicCache struct {
+	cache.MockCache
+}
+
+func (c *panicCache) Ping(ctx context.Context) error {
+	panic("test panic")
+}
+
+// countRecorder values are metric recorders which count the increments of
+// each metric.
+type countRecorder struct {
+	sync.Mutex
+	counts map[string]int
+}
+
+func (r *countRecorder) Add(ctx context.Context, name string, value int64,
+	tags ...string,
+) {
+}
+
+func (r *countRecorder) Increment(ctx context.Context, name string,
+	tags ...string,
+) {
+	r.Lock()
+	defer r.Unlock()
+
+	if r.counts == nil {
+		r.counts = map[string]int{}
+	}
+
+	r.counts[name]++
+}
+
+func (r *countRecorder) Set(ctx context.Context, name string, value int64,
+	tags ...string,
+) {
+}
+
+func (r *countRecorder) RecordDuration(ctx context.Context, name string,
+	value time.Duration, tags ...string,
+) {
+}
+
+func (r *countRecorder) RecordValue(ctx context.Context, name string,
+	value float64, tags ...string,
+) {
+}
+
+func (r *countRecorder) count(name string) int {
+	r.Lock()
+	defer r.Unlock()
+
+	return r.counts[name]
+}
+
+func TestRecoverer(t *testing.T) {
+	t.Parallel()
+
+	mr := &countRecorder{}
+
+	svr, err := server.NewServer(config.NewDefault(), nil, mr, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	svr.SetCache(&panicCache{})
+
+	r := httptest.NewRequest(http.MethodGet, basePath+"/ready", nil)
+
+	w := httptest.NewRecorder()
+
+	svr.Mux(w, r)
+
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("Expected status: %v, got: %v",
+			http.StatusInternalServerError, w.Code)
+	}
+
+	eID := w.Header().Get("X-Error-ID")
+	if eID == "" {
+		t.Fatal("Expected X-Error-ID header")
+	}
+
+	res := &errors.Error{}
+
+	if err := json.NewDecoder(w.Body).Decode(res); err != nil {
+		t.Fatalf("Unexpected error decoding response: %v", err)
+	}
+
+	if v, _ := res.Data["error_id"].(string); v != eID {
+		t.Errorf("Expected error_id: %v, got: %v", eID, res.Data["error_id"])
+	}
+
+	if n := mr.count("panics"); n != 1 {
+		t.Errorf("Expected panics metric: 1, got: %v", n)
+	}
+}
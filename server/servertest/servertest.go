@@ -0,0 +1,287 @@
+// Package servertest provides game2d API servers for black-box tests of the
+// API handlers, and for integration tests of API clients. Each server runs
+// the full router in process, on a local port, using a mock cache, a mock AI
+// prompter and a mock speech to text transcriber.
+//
+// The store is not mocked. The API handlers query MongoDB directly, so each
+// server uses its own database, created using the configured MongoDB
+// connection and dropped when the server is closed. A MongoDB server, such as
+// the one started by make start, is required, and tests using test servers
+// fail if it is not available. Such tests should be skipped in short mode.
+package servertest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dhaifley/game2d/cache"
+	"github.com/dhaifley/game2d/config"
+	"github.com/dhaifley/game2d/errors"
+	"github.com/dhaifley/game2d/logger"
+	"github.com/dhaifley/game2d/server"
+	"github.com/google/uuid"
+)
+
+// Test server defaults.
+const (
+	// BootstrapToken is the bootstrap token used by test servers, if one is
+	// not configured.
+	BootstrapToken = "servertest-bootstrap-token"
+
+	// AdminUser and AdminPassword are the credentials of the superuser
+	// created by bootstrapping test servers.
+	AdminUser     = "admin"
+	AdminPassword = "admin"
+
+	// PromptResponse is the response of the mock AI prompter, if no other
+	// response is specified.
+	PromptResponse = "The AI has responded."
+
+	// Transcript is the transcript of every audio clip transcribed by the
+	// mock speech to text transcriber.
+	Transcript = "Make the player jump higher."
+
+	// DatabasePrefix is the prefix of the names of test server databases.
+	DatabasePrefix = "game2d_test_"
+
+	// DefaultTimeout is the time allowed for the database to be available,
+	// if no other timeout is specified.
+	DefaultTimeout = 5 * time.Second
+)
+
+// Options values configure test servers.
+type Options struct {
+	// Config is the server configuration, which defaults to the
+	// configuration loaded from environment variables. Its database name is
+	// always replaced by that of the test server database.
+	Config *config.Config
+
+	// Log is the server logger, which defaults to discarding log entries.
+	Log logger.Logger
+
+	// PromptResponse and PromptDelay configure the mock AI prompter.
+	PromptResponse string
+	PromptDelay    time.Duration
+
+	// Timeout is the time allowed for the database to be available.
+	Timeout time.Duration
+}
+
+// Server values are game2d API servers listening on a local port. The token
+// is the API key of the superuser created by bootstrapping the server, which
+// is empty if the server has no bootstrap token.
+type Server struct {
+	*httptest.Server
+	API      *server.Server
+	Cache    *cache.MockCache
+	Config   *config.Config
+	Database string
+	Token    string
+}
+
+// New starts a test server, which is closed when the test completes. The test
+// fails if the database is not available.
+func New(tb testing.TB, opts *Options) *Server {
+	tb.Helper()
+
+	s, err := NewServer(context.Background(), opts)
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	tb.Cleanup(s.Close)
+
+	return s
+}
+
+// NewServer starts a test server, which must be closed by the caller. An
+// error with the ErrUnavailable code is returned if the database is not
+// available.
+func NewServer(ctx context.Context, opts *Options) (*Server, error) {
+	if opts == nil {
+		opts = &Options{}
+	}
+
+	cfg := opts.Config
+	if cfg == nil {
+		cfg = &config.Config{}
+
+		cfg.Load([]byte("auth:\n  bootstrap_token: " + BootstrapToken + "\n"))
+	}
+
+	name := DatabasePrefix + strings.ReplaceAll(uuid.NewString(), "-", "")
+
+	cfg.SetDB(&config.DBConfig{
+		Conn:        cfg.DBConn(),
+		Database:    name,
+		MinPoolSize: cfg.DBMinPoolSize(),
+		MaxPoolSize: cfg.DBMaxPoolSize(),
+		DefaultSize: cfg.DBDefaultSize(),
+		MaxSize:     cfg.DBMaxSize(),
+		Timeout:     cfg.DBTimeout(),
+		RetryMin:    cfg.DBRetryMin(),
+		RetryMax:    cfg.DBRetryMax(),
+	})
+
+	api, err := server.NewServer(cfg, opts.Log, nil, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrServer,
+			"unable to create test server")
+	}
+
+	res := opts.PromptResponse
+	if res == "" {
+		res = PromptResponse
+	}
+
+	api.SetPrompter(server.NewMockPrompter(api, res, opts.PromptDelay))
+	api.SetTranscriber(server.NewMockTranscriber(Transcript))
+
+	mc := &cache.MockCache{}
+
+	api.SetCache(mc)
+
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+
+	if err := connect(ctx, api, timeout); err != nil {
+		api.Close()
+
+		return nil, err
+	}
+
+	s := &Server{
+		API:      api,
+		Cache:    mc,
+		Config:   cfg,
+		Database: name,
+	}
+
+	if err := api.Provision(ctx); err != nil {
+		s.Close()
+
+		return nil, errors.Wrap(err, errors.ErrServer,
+			"unable to provision test server")
+	}
+
+	s.Server = httptest.NewServer(http.HandlerFunc(api.Mux))
+
+	if cfg.AuthBootstrapToken() != "" {
+		if err := s.bootstrap(ctx); err != nil {
+			s.Close()
+
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+// connect connects the server to its database, and waits for the database to
+// respond.
+func connect(ctx context.Context,
+	api *server.Server,
+	timeout time.Duration,
+) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	api.ConnectDB(ctx)
+
+	for api.DB() == nil {
+		select {
+		case <-ctx.Done():
+			return errors.Wrap(ctx.Err(), errors.ErrUnavailable,
+				"test server database connection timed out")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+
+	if err := api.DB().Client().Ping(ctx, nil); err != nil {
+		return errors.Wrap(err, errors.ErrUnavailable,
+			"unable to reach test server database")
+	}
+
+	return nil
+}
+
+// bootstrap creates the superuser of the test server, and sets the server
+// token to its API key.
+func (s *Server) bootstrap(ctx context.Context) error {
+	b, err := json.Marshal(&server.BootstrapRequest{
+		UserID:   AdminUser,
+		Password: AdminPassword,
+	})
+	if err != nil {
+		return errors.Wrap(err, errors.ErrServer,
+			"unable to encode bootstrap request")
+	}
+
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		s.Path("/admin/bootstrap"), bytes.NewReader(b))
+	if err != nil {
+		return errors.Wrap(err, errors.ErrServer,
+			"unable to create bootstrap request")
+	}
+
+	r.Header.Set("Authorization", "Bearer "+s.Config.AuthBootstrapToken())
+	r.Header.Set("Content-Type", "application/json")
+
+	res, err := s.Client().Do(r)
+	if err != nil {
+		return errors.Wrap(err, errors.ErrServer,
+			"unable to bootstrap test server")
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK &&
+		res.StatusCode != http.StatusCreated {
+		return errors.New(errors.ErrServer,
+			"unable to bootstrap test server",
+			"status", res.StatusCode)
+	}
+
+	bs := &server.Bootstrap{}
+
+	if err := json.NewDecoder(res.Body).Decode(bs); err != nil {
+		return errors.Wrap(err, errors.ErrServer,
+			"unable to decode bootstrap response")
+	}
+
+	s.Token = bs.APIKey
+
+	return nil
+}
+
+// Path returns the URL of an API path, such as /games, on the test server.
+func (s *Server) Path(p string) string {
+	return s.URL + s.Config.ServerPathPrefix() + p
+}
+
+// Close stops the test server, and drops its database.
+func (s *Server) Close() {
+	if s.Server != nil {
+		s.Server.Close()
+	}
+
+	if db := s.API.DB(); db != nil && strings.HasPrefix(db.Name(),
+		DatabasePrefix) {
+		ctx, cancel := context.WithTimeout(context.Background(),
+			DefaultTimeout)
+
+		_ = db.Drop(ctx)
+
+		cancel()
+	}
+
+	s.API.Close()
+}
@@ -0,0 +1,71 @@
+package servertest_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/dhaifley/game2d/server/servertest"
+)
+
+func TestServer(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration tests")
+	}
+
+	s := servertest.New(t, nil)
+
+	if !strings.HasPrefix(s.Database, servertest.DatabasePrefix) {
+		t.Errorf("Database expected prefix: %v, got: %v",
+			servertest.DatabasePrefix, s.Database)
+	}
+
+	if s.Token == "" {
+		t.Errorf("Expected superuser token")
+	}
+
+	tests := []struct {
+		name  string
+		path  string
+		token string
+		expC  int
+	}{{
+		name: "health",
+		path: "/health",
+		expC: http.StatusOK,
+	}, {
+		name: "account unauthorized",
+		path: "/account",
+		expC: http.StatusUnauthorized,
+	}, {
+		name:  "account",
+		path:  "/account",
+		token: s.Token,
+		expC:  http.StatusOK,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := http.NewRequest(http.MethodGet, s.Path(tt.path), nil)
+			if err != nil {
+				t.Fatal("Failed to initialize request", err)
+			}
+
+			if tt.token != "" {
+				r.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+
+			res, err := s.Client().Do(r)
+			if err != nil {
+				t.Fatalf("Unexpected client error: %v", err)
+			}
+
+			defer res.Body.Close()
+
+			if res.StatusCode != tt.expC {
+				t.Errorf("Status code expected: %v, got: %v",
+					tt.expC, res.StatusCode)
+			}
+		})
+	}
+}
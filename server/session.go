@@ -0,0 +1,352 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dhaifley/game2d/errors"
+	"github.com/dhaifley/game2d/request"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// sessionTimeout is the length of time after the last heartbeat for which a
+// play session is counted as live.
+const sessionTimeout = 90 * time.Second
+
+// maxLiveGames is the maximum number of games for which live player counts
+// can be requested at once.
+const maxLiveGames = 100
+
+// Heartbeat values represent a play session heartbeat sent by a client.
+type Heartbeat struct {
+	SessionID string `json:"session_id" yaml:"session_id"`
+	Ended     bool   `json:"ended"      yaml:"ended"`
+}
+
+// Validate checks that the value contains valid data.
+func (h *Heartbeat) Validate() error {
+	if _, err := uuid.Parse(h.SessionID); err != nil {
+		return errors.New(errors.ErrInvalidRequest,
+			"invalid session_id",
+			"session_id", h.SessionID)
+	}
+
+	return nil
+}
+
+// LivePlayers values represent the number of live players of a game.
+type LivePlayers struct {
+	GameID  string `bson:"_id"     json:"game_id" yaml:"game_id"`
+	Players int64  `bson:"players" json:"players" yaml:"players"`
+}
+
+// countLivePlayers counts the live play sessions for a game, and records the
+// count as a metric.
+func (s *Server) countLivePlayers(ctx context.Context,
+	id string,
+) (*LivePlayers, error) {
+	c, err := s.collection(ctx, "play_sessions")
+	if err != nil {
+		return nil, err
+	}
+
+	n, err := c.CountDocuments(ctx, bson.M{
+		"game_id":    id,
+		"expires_at": bson.M{"$gt": time.Now()},
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrDatabase,
+			"unable to count live players",
+			"id", id)
+	}
+
+	if s.metric != nil {
+		s.metric.Set(ctx, "game_live_players", n, "game_id:"+id)
+	}
+
+	return &LivePlayers{GameID: id, Players: n}, nil
+}
+
+// heartbeat records a heartbeat for a play session of a game, or ends the
+// session, and returns the number of live players of the game.
+func (s *Server) heartbeat(ctx context.Context,
+	id string,
+	v *Heartbeat,
+) (*LivePlayers, error) {
+	aID, err := request.ContextAccountID(ctx)
+	if err != nil {
+		return nil, errors.New(errors.ErrUnauthorized,
+			"unable to get account id from context")
+	}
+
+	uID, err := request.ContextUserID(ctx)
+	if err != nil {
+		return nil, errors.New(errors.ErrUnauthorized,
+			"unable to get user id from context")
+	}
+
+	if v == nil {
+		return nil, errors.New(errors.ErrInvalidRequest,
+			"missing heartbeat")
+	}
+
+	if err := v.Validate(); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.getGame(context.WithValue(ctx, CtxKeyGameMinData, true),
+		id); err != nil {
+		return nil, err
+	}
+
+	sessions, err := s.collection(ctx, "play_sessions")
+	if err != nil {
+		return nil, err
+	}
+
+	history, err := s.collection(ctx, "play_history")
+	if err != nil {
+		return nil, err
+	}
+
+	f := bson.M{"game_id": id, "session_id": v.SessionID}
+
+	if v.Ended {
+		if _, err := sessions.DeleteOne(ctx, f); err != nil {
+			return nil, errors.Wrap(err, errors.ErrDatabase,
+				"unable to end play session",
+				"id", id,
+				"session_id", v.SessionID)
+		}
+	} else {
+		now := time.Now()
+
+		if _, err := sessions.UpdateOne(ctx, f,
+			bson.M{
+				"$set": bson.M{
+					"updated_at": now.Unix(),
+					"expires_at": now.Add(sessionTimeout),
+				},
+				"$setOnInsert": bson.M{
+					"account_id": aID,
+					"user_id":    uID,
+					"started_at": now.Unix(),
+				},
+			}, options.UpdateOne().SetUpsert(true)); err != nil {
+			return nil, errors.Wrap(err, errors.ErrDatabase,
+				"unable to update play session",
+				"id", id,
+				"session_id", v.SessionID)
+		}
+
+		if _, err := history.UpdateOne(ctx,
+			bson.M{"user_id": uID, "game_id": id},
+			bson.M{
+				"$set": bson.M{"last_played_at": now.Unix()},
+				"$setOnInsert": bson.M{
+					"account_id":      aID,
+					"first_played_at": now.Unix(),
+				},
+			}, options.UpdateOne().SetUpsert(true)); err != nil {
+			return nil, errors.Wrap(err, errors.ErrDatabase,
+				"unable to update play history",
+				"id", id,
+				"session_id", v.SessionID)
+		}
+	}
+
+	if s.metric != nil {
+		s.metric.Increment(ctx, "game_heartbeats", "game_id:"+id)
+	}
+
+	return s.countLivePlayers(ctx, id)
+}
+
+// getLivePlayers retrieves the number of live players of a game.
+func (s *Server) getLivePlayers(ctx context.Context,
+	id string,
+) (*LivePlayers, error) {
+	if _, err := s.getGame(context.WithValue(ctx, CtxKeyGameMinData, true),
+		id); err != nil {
+		return nil, err
+	}
+
+	return s.countLivePlayers(ctx, id)
+}
+
+// getAllLivePlayers retrieves the number of live players of each game, of
+// those specified, that is visible to the current account. Games without live
+// players are omitted.
+func (s *Server) getAllLivePlayers(ctx context.Context,
+	ids []string,
+) ([]*LivePlayers, error) {
+	aID, err := request.ContextAccountID(ctx)
+	if err != nil {
+		return nil, errors.New(errors.ErrUnauthorized,
+			"unable to get account id from context")
+	}
+
+	if len(ids) > maxLiveGames {
+		return nil, errors.New(errors.ErrInvalidRequest,
+			"too many game ids",
+			"max", maxLiveGames)
+	}
+
+	for _, id := range ids {
+		if !request.ValidGameID(id) {
+			return nil, errors.New(errors.ErrInvalidRequest,
+				"invalid game id",
+				"id", id)
+		}
+	}
+
+	gf := bson.M{"$or": bson.A{
+		bson.D{{Key: "public", Value: true}},
+		bson.D{{Key: "account_id", Value: aID}},
+	}}
+
+	if len(ids) > 0 {
+		gf["id"] = bson.M{"$in": ids}
+	} else {
+		gf = bson.M{"account_id": aID}
+	}
+
+	games, err := s.collection(ctx, "games")
+	if err != nil {
+		return nil, err
+	}
+
+	sessions, err := s.collection(ctx, "play_sessions")
+	if err != nil {
+		return nil, err
+	}
+
+	var gIDs []string
+
+	if err := games.Distinct(ctx, "id", gf).Decode(&gIDs); err != nil {
+		return nil, errors.Wrap(err, errors.ErrDatabase,
+			"unable to find games")
+	}
+
+	res := []*LivePlayers{}
+
+	if len(gIDs) == 0 {
+		return res, nil
+	}
+
+	cur, err := sessions.Aggregate(ctx,
+		mongo.Pipeline{
+			bson.D{{Key: "$match", Value: bson.M{
+				"game_id":    bson.M{"$in": gIDs},
+				"expires_at": bson.M{"$gt": time.Now()},
+			}}},
+			bson.D{{Key: "$group", Value: bson.M{
+				"_id":     "$game_id",
+				"players": bson.M{"$sum": 1},
+			}}},
+			bson.D{{Key: "$sort", Value: bson.M{"_id": 1}}},
+		})
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrDatabase,
+			"unable to count live players")
+	}
+
+	if err := cur.All(ctx, &res); err != nil {
+		return nil, errors.Wrap(err, errors.ErrDatabase,
+			"unable to decode live players")
+	}
+
+	return res, nil
+}
+
+// postGameHeartbeatHandler is the post handler used to record play session
+// heartbeats for a game.
+func (s *Server) postGameHeartbeatHandler(w http.ResponseWriter,
+	r *http.Request,
+) {
+	ctx := r.Context()
+
+	if err := s.checkScope(ctx, request.ScopeGamesRead); err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	req := &Heartbeat{}
+
+	if err := s.decode(r, &req); err != nil {
+		s.error(errors.Wrap(err, errors.ErrInvalidRequest,
+			"unable to decode request"), w, r)
+
+		return
+	}
+
+	res, err := s.heartbeat(ctx, chi.URLParam(r, "id"), req)
+	if err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	if err := s.encode(w, r, res); err != nil {
+		s.error(err, w, r)
+	}
+}
+
+// getGameLiveHandler is the get handler used to retrieve the number of live
+// players of a game.
+func (s *Server) getGameLiveHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if err := s.checkScope(ctx, request.ScopeGamesRead); err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	res, err := s.getLivePlayers(ctx, chi.URLParam(r, "id"))
+	if err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	if err := s.encode(w, r, res); err != nil {
+		s.error(err, w, r)
+	}
+}
+
+// getGamesLiveHandler is the get handler used to retrieve the number of live
+// players of multiple games.
+func (s *Server) getGamesLiveHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if err := s.checkScope(ctx, request.ScopeGamesRead); err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	var ids []string
+
+	if qp := r.URL.Query().Get("id"); qp != "" {
+		ids = strings.Split(qp, ",")
+	}
+
+	res, err := s.getAllLivePlayers(ctx, ids)
+	if err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	if err := s.encode(w, r, res); err != nil {
+		s.error(err, w, r)
+	}
+}
@@ -0,0 +1,336 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"slices"
+
+	"github.com/dhaifley/game2d/errors"
+	"github.com/dhaifley/game2d/request"
+	"github.com/go-chi/chi/v5"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const (
+	// defaultSimilarSize is the default number of similar games returned.
+	defaultSimilarSize = 10
+
+	// maxSimilarSize is the maximum number of similar games returned.
+	maxSimilarSize = 50
+
+	// maxSimilarCandidates is the maximum number of games compared to find
+	// similar games, from the most recently updated.
+	maxSimilarCandidates = 500
+
+	// maxRecommendPlays is the maximum number of recently played games used
+	// to recommend games to a user.
+	maxRecommendPlays = 20
+)
+
+// SimilarGame values represent games which are similar to a game, or which
+// are recommended to a user, and their similarity score, from -1 to 1.
+type SimilarGame struct {
+	ID          string   `json:"id"                    yaml:"id"`
+	Name        string   `json:"name"                  yaml:"name"`
+	Description string   `json:"description,omitempty" yaml:"description,omitempty"`
+	Icon        string   `json:"icon,omitempty"        yaml:"icon,omitempty"`
+	Tags        []string `json:"tags,omitempty"        yaml:"tags,omitempty"`
+	Public      bool     `json:"public"                yaml:"public"`
+	Score       float64  `json:"score"                 yaml:"score"`
+}
+
+// similarGame returns a game as a similar game with a score.
+func similarGame(g *Game, score float64) *SimilarGame {
+	return &SimilarGame{
+		ID:          g.ID.Value,
+		Name:        g.Name.Value,
+		Description: g.Description.Value,
+		Icon:        g.Icon.Value,
+		Tags:        g.Tags.Value,
+		Public:      g.Public.Value,
+		Score:       score,
+	}
+}
+
+// similarSize returns the number of similar games to return for a query.
+func similarSize(query *request.Query) int {
+	if query == nil || query.Size <= 0 {
+		return defaultSimilarSize
+	}
+
+	return int(min(query.Size, maxSimilarSize))
+}
+
+// findEmbeddingGames finds the active games matching a filter with the data
+// used to embed them, from the most recently updated.
+func (s *Server) findEmbeddingGames(ctx context.Context,
+	f bson.M,
+	limit int64,
+) ([]*Game, error) {
+	f["status"] = request.StatusActive
+
+	c, err := s.collection(ctx, "games")
+	if err != nil {
+		return nil, err
+	}
+
+	cur, err := c.Find(ctx, f, options.Find().
+		SetLimit(limit).
+		SetSort(bson.D{{Key: "updated_at", Value: -1}}).
+		SetProjection(bson.M{
+			"_id":         0,
+			"id":          1,
+			"account_id":  1,
+			"public":      1,
+			"name":        1,
+			"description": 1,
+			"controls":    1,
+			"icon":        1,
+			"tags":        1,
+			"script":      1,
+		}))
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrDatabase,
+			"unable to find games")
+	}
+
+	res := []*Game{}
+
+	if err := cur.All(ctx, &res); err != nil {
+		return nil, errors.Wrap(err, errors.ErrDatabase,
+			"unable to decode games")
+	}
+
+	return res, nil
+}
+
+// rankGames returns the games most similar to a vector, in order of
+// similarity, excluding those with an excluded ID.
+func rankGames(games []*Game,
+	vs map[string][]float64,
+	v []float64,
+	exclude map[string]bool,
+	size int,
+) []*SimilarGame {
+	res := []*SimilarGame{}
+
+	for _, g := range games {
+		if exclude[g.ID.Value] {
+			continue
+		}
+
+		res = append(res, similarGame(g, similarity(v, vs[g.ID.Value])))
+	}
+
+	slices.SortStableFunc(res, func(a, b *SimilarGame) int {
+		switch {
+		case a.Score > b.Score:
+			return -1
+		case a.Score < b.Score:
+			return 1
+		}
+
+		return 0
+	})
+
+	return res[:min(size, len(res))]
+}
+
+// getSimilarGames retrieves the games most similar to a game, from the games
+// of the current account and the public games.
+func (s *Server) getSimilarGames(ctx context.Context,
+	id string,
+	query *request.Query,
+) ([]*SimilarGame, error) {
+	aID, err := request.ContextAccountID(ctx)
+	if err != nil {
+		return nil, errors.New(errors.ErrUnauthorized,
+			"unable to get account id from context")
+	}
+
+	g, err := s.getGame(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if g == nil {
+		return nil, errors.New(errors.ErrNotFound,
+			"game not found",
+			"id", id).WithReason(errors.ReasonGameNotFound)
+	}
+
+	games, err := s.findEmbeddingGames(ctx, bson.M{
+		"id": bson.M{"$ne": id},
+		"$or": bson.A{
+			bson.D{{Key: "public", Value: true}},
+			bson.D{{Key: "account_id", Value: aID}},
+		},
+	}, maxSimilarCandidates)
+	if err != nil {
+		return nil, err
+	}
+
+	vs, err := s.gameEmbeddings(ctx, append(games, g))
+	if err != nil {
+		return nil, err
+	}
+
+	return rankGames(games, vs, vs[id], nil, similarSize(query)), nil
+}
+
+// getRecommendedGames retrieves the public games recommended to the current
+// user, which are those most similar to the games the user played most
+// recently, and not yet played. Users who have not played any games, or if
+// similar games are disabled, are recommended the most recently updated public
+// games.
+func (s *Server) getRecommendedGames(ctx context.Context,
+	query *request.Query,
+) ([]*SimilarGame, error) {
+	uID, err := request.ContextUserID(ctx)
+	if err != nil {
+		return nil, errors.New(errors.ErrUnauthorized,
+			"unable to get user id from context")
+	}
+
+	c, err := s.collection(ctx, "play_history")
+	if err != nil {
+		return nil, err
+	}
+
+	cur, err := c.Find(ctx,
+		bson.M{"user_id": uID}, options.Find().
+			SetLimit(maxRecommendPlays).
+			SetSort(bson.D{{Key: "last_played_at", Value: -1}}).
+			SetProjection(bson.M{"_id": 0, "game_id": 1}))
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrDatabase,
+			"unable to find play history",
+			"user_id", uID)
+	}
+
+	var plays []struct {
+		GameID string `bson:"game_id"`
+	}
+
+	if err := cur.All(ctx, &plays); err != nil {
+		return nil, errors.Wrap(err, errors.ErrDatabase,
+			"unable to decode play history",
+			"user_id", uID)
+	}
+
+	played := make(map[string]bool, len(plays))
+	ids := make([]string, 0, len(plays))
+
+	for _, p := range plays {
+		played[p.GameID] = true
+		ids = append(ids, p.GameID)
+	}
+
+	games, err := s.findEmbeddingGames(ctx, bson.M{"public": true},
+		maxSimilarCandidates)
+	if err != nil {
+		return nil, err
+	}
+
+	size := similarSize(query)
+
+	if len(ids) == 0 || s.getEmbedder() == nil {
+		return rankGames(games, nil, nil, nil, size), nil
+	}
+
+	pg, err := s.findEmbeddingGames(ctx, bson.M{"id": bson.M{"$in": ids}},
+		maxRecommendPlays)
+	if err != nil {
+		return nil, err
+	}
+
+	vs, err := s.gameEmbeddings(ctx, append(games, pg...))
+	if err != nil {
+		return nil, err
+	}
+
+	var profile []float64
+
+	for _, g := range pg {
+		v := vs[g.ID.Value]
+
+		if profile == nil {
+			profile = make([]float64, len(v))
+		}
+
+		if len(v) != len(profile) {
+			continue
+		}
+
+		for i := range v {
+			profile[i] += v[i]
+		}
+	}
+
+	return rankGames(games, vs, normalize(profile), played, size), nil
+}
+
+// getGameSimilarHandler is the get handler used to retrieve the games most
+// similar to a game.
+func (s *Server) getGameSimilarHandler(w http.ResponseWriter,
+	r *http.Request,
+) {
+	ctx := r.Context()
+
+	if err := s.checkScope(ctx, request.ScopeGamesRead); err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	query, err := request.ParseQuery(r.URL.Query())
+	if err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	res, err := s.getSimilarGames(ctx, chi.URLParam(r, "id"), query)
+	if err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	if err := s.encode(w, r, res); err != nil {
+		s.error(err, w, r)
+	}
+}
+
+// getGamesRecommendedHandler is the get handler used to retrieve the public
+// games recommended to the current user.
+func (s *Server) getGamesRecommendedHandler(w http.ResponseWriter,
+	r *http.Request,
+) {
+	ctx := r.Context()
+
+	if err := s.checkScope(ctx, request.ScopeGamesRead); err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	query, err := request.ParseQuery(r.URL.Query())
+	if err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	res, err := s.getRecommendedGames(ctx, query)
+	if err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	if err := s.encode(w, r, res); err != nil {
+		s.error(err, w, r)
+	}
+}
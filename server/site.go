@@ -0,0 +1,351 @@
+package server
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/dhaifley/game2d/errors"
+	"github.com/dhaifley/game2d/logger"
+	"github.com/dhaifley/game2d/request"
+	"github.com/dhaifley/game2d/static"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// maxSiteGames is the maximum number of games exported to a static site.
+const maxSiteGames = 1000
+
+// galleryGame values are the games listed in a gallery page, and the URL of
+// the page which plays them.
+type galleryGame struct {
+	ID          string
+	Name        string
+	Description string
+	Controls    string
+	Icon        template.URL
+	URL         string
+}
+
+// newGalleryGame returns a game as a gallery game linked to a URL.
+func newGalleryGame(g *Game, url string) *galleryGame {
+	res := &galleryGame{
+		ID:          g.ID.Value,
+		Name:        g.Name.Value,
+		Description: g.Description.Value,
+		Controls:    g.Controls.Value,
+		URL:         url,
+	}
+
+	// Icons are stored base64 encoded, so they are safe in data URLs.
+	if g.Icon.Value != "" {
+		res.Icon = template.URL("data:image/svg+xml;base64," + g.Icon.Value)
+	}
+
+	return res
+}
+
+// renderPage renders a static HTML template with data.
+func renderPage(name string, data map[string]any) ([]byte, error) {
+	tb, err := static.FS.ReadFile(name + ".html")
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrServer,
+			"unable to read "+name+" template")
+	}
+
+	t, err := template.New(name).Parse(string(tb))
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrServer,
+			"unable to parse "+name+" template")
+	}
+
+	buf := &bytes.Buffer{}
+
+	if err := t.Execute(buf, data); err != nil {
+		return nil, errors.Wrap(err, errors.ErrServer,
+			"unable to create "+name+" page")
+	}
+
+	return buf.Bytes(), nil
+}
+
+// embedPage renders the page which plays a single game. The root is the path
+// of the client runtime files relative to the page.
+func embedPage(g *Game, root string, meta *pageMeta) ([]byte, error) {
+	gb, err := playableGame(g)
+	if err != nil {
+		return nil, err
+	}
+
+	if meta == nil {
+		meta = &pageMeta{}
+	}
+
+	return renderPage("embed", map[string]any{
+		"Name":        g.Name.Value,
+		"Description": g.Description.Value,
+		"URL":         meta.URL,
+		"Image":       meta.Image,
+		"Worker":      meta.Worker,
+		"Root":        root,
+		"Game":        gb,
+	})
+}
+
+// publicGames retrieves the public active games of an account, in order of
+// name, with only the data listed in galleries.
+func (s *Server) publicGames(ctx context.Context,
+	accountID string,
+	limit int64,
+) ([]*Game, error) {
+	c, err := s.collection(ctx, "games")
+	if err != nil {
+		return nil, err
+	}
+
+	cur, err := c.Find(ctx, bson.M{
+		"account_id": accountID,
+		"public":     true,
+		"status":     request.StatusActive,
+	}, options.Find().SetLimit(limit).
+		SetSort(bson.D{{Key: "name", Value: 1}}).
+		SetProjection(bson.M{
+			"_id":         0,
+			"id":          1,
+			"name":        1,
+			"description": 1,
+			"controls":    1,
+			"icon":        1,
+			"tags":        1,
+		}))
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrDatabase,
+			"unable to find games",
+			"account_id", accountID)
+	}
+
+	res := []*Game{}
+
+	if err := cur.All(ctx, &res); err != nil {
+		return nil, errors.Wrap(err, errors.ErrDatabase,
+			"unable to decode games",
+			"account_id", accountID)
+	}
+
+	return res, nil
+}
+
+// siteWriter values write the files of an exported static site.
+type siteWriter func(name string, b []byte) error
+
+// exportSite renders the public gallery of the current account, the pages of
+// its public games, and their embeds, as a static site which can be hosted
+// without the game2d API. The gallery is index.html, the game pages are in
+// games, with the game definitions, and the embeds are in embed. It returns
+// the number of games exported.
+func (s *Server) exportSite(ctx context.Context,
+	write siteWriter,
+) (int, error) {
+	a, err := s.getAccount(ctx, "")
+	if err != nil {
+		return 0, err
+	}
+
+	games, err := s.publicGames(ctx, a.ID.Value, maxSiteGames)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, name := range []string{"game2d.wasm", "scripts/wasm_exec.js"} {
+		b, err := static.FS.ReadFile(name)
+		if err != nil {
+			return 0, errors.Wrap(err, errors.ErrServer,
+				"unable to read client runtime",
+				"name", name)
+		}
+
+		if err := write(name, b); err != nil {
+			return 0, err
+		}
+	}
+
+	gg := make([]*galleryGame, 0, len(games))
+
+	for _, pg := range games {
+		g, err := s.getGame(ctx, pg.ID.Value)
+		if err != nil {
+			return 0, err
+		}
+
+		gb, err := playableGame(g)
+		if err != nil {
+			return 0, err
+		}
+
+		if err := write("games/"+g.ID.Value+".json", []byte(gb)); err != nil {
+			return 0, err
+		}
+
+		b, err := embedPage(g, "../", nil)
+		if err != nil {
+			return 0, err
+		}
+
+		if err := write("embed/"+g.ID.Value+".html", b); err != nil {
+			return 0, err
+		}
+
+		e := newGalleryGame(g, g.ID.Value+".html")
+
+		if b, err = renderPage("game", map[string]any{
+			"Name":  a.Name.Value,
+			"Game":  e,
+			"Embed": "../embed/" + g.ID.Value + ".html",
+		}); err != nil {
+			return 0, err
+		}
+
+		if err := write("games/"+g.ID.Value+".html", b); err != nil {
+			return 0, err
+		}
+
+		e.URL = "games/" + e.URL
+
+		gg = append(gg, e)
+	}
+
+	b, err := renderPage("gallery", map[string]any{
+		"Name":  a.Name.Value,
+		"Games": gg,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	if err := write("index.html", b); err != nil {
+		return 0, err
+	}
+
+	sg := make([]*SimilarGame, 0, len(games))
+
+	for _, g := range games {
+		sg = append(sg, similarGame(g, 0))
+	}
+
+	if b, err = json.Marshal(sg); err != nil {
+		return 0, errors.Wrap(err, errors.ErrServer,
+			"unable to encode games")
+	}
+
+	if err := write("games.json", b); err != nil {
+		return 0, err
+	}
+
+	return len(games), nil
+}
+
+// ExportSite exports the public gallery of an account, the pages of its public
+// games, and their embeds, as a static site in a directory, connecting to the
+// database if needed. It returns the number of games exported.
+func (s *Server) ExportSite(ctx context.Context,
+	accountID, dir string,
+) (int, error) {
+	if !request.ValidAccountID(accountID) {
+		return 0, errors.New(errors.ErrInvalidRequest,
+			"invalid account id",
+			"account_id", accountID)
+	}
+
+	if s.DB() == nil {
+		s.connectDB(ctx)
+	}
+
+	if s.DB() == nil {
+		return 0, errors.New(errors.ErrUnavailable,
+			"database unavailable")
+	}
+
+	ctx = context.WithValue(ctx, request.CtxKeyAccountID, accountID)
+
+	return s.exportSite(ctx, func(name string, b []byte) error {
+		fn := filepath.Join(dir, filepath.FromSlash(name))
+
+		if err := os.MkdirAll(filepath.Dir(fn), 0o755); err != nil {
+			return errors.Wrap(err, errors.ErrServer,
+				"unable to create site directory",
+				"dir", filepath.Dir(fn))
+		}
+
+		if err := os.WriteFile(fn, b, 0o644); err != nil {
+			return errors.Wrap(err, errors.ErrServer,
+				"unable to write site file",
+				"file", fn)
+		}
+
+		return nil
+	})
+}
+
+// postAccountSiteHandler is the post handler used to export the public gallery
+// of the current account as a static site, in a zip archive.
+func (s *Server) postAccountSiteHandler(w http.ResponseWriter,
+	r *http.Request,
+) {
+	ctx := r.Context()
+
+	if err := s.checkScope(ctx, request.ScopeAccountAdmin); err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	buf := &bytes.Buffer{}
+
+	zw := zip.NewWriter(buf)
+
+	n, err := s.exportSite(ctx, func(name string, b []byte) error {
+		f, err := zw.Create(name)
+		if err != nil {
+			return errors.Wrap(err, errors.ErrServer,
+				"unable to create site archive file",
+				"file", name)
+		}
+
+		if _, err := f.Write(b); err != nil {
+			return errors.Wrap(err, errors.ErrServer,
+				"unable to write site archive file",
+				"file", name)
+		}
+
+		return nil
+	})
+	if err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	if err := zw.Close(); err != nil {
+		s.error(errors.Wrap(err, errors.ErrServer,
+			"unable to create site archive"), w, r)
+
+		return
+	}
+
+	s.log.Log(ctx, logger.LvlInfo,
+		"static site exported",
+		"games", n)
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition",
+		`attachment; filename="game2d-site.zip"`)
+
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		s.error(err, w, r)
+	}
+}
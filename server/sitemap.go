@@ -0,0 +1,177 @@
+package server
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/xml"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/dhaifley/game2d/errors"
+	"github.com/dhaifley/game2d/request"
+	"github.com/dhaifley/game2d/static"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const (
+	// maxSitemapGames is the maximum number of games listed in a sitemap,
+	// which may contain at most 50,000 URLs.
+	maxSitemapGames = 49999
+
+	// sitemapNamespace is the XML namespace of sitemaps.
+	sitemapNamespace = "http://www.sitemaps.org/schemas/sitemap/0.9"
+)
+
+// pageMeta values contain the absolute URLs of a page, and of its thumbnail
+// image, used in the OpenGraph and Twitter card metadata of the page, so that
+// shared links to it unfurl, and the URL of the service worker which caches
+// it. Pages exported as static sites have none of them.
+type pageMeta struct {
+	URL    string
+	Image  string
+	Worker string
+}
+
+// pageURL returns the absolute URL of a path on the host a request was
+// received on.
+func pageURL(r *http.Request, p string) string {
+	scheme := "http"
+
+	if r.TLS != nil ||
+		strings.EqualFold(r.Header.Get("X-Forwarded-Proto"), "https") {
+		scheme = "https"
+	}
+
+	return (&url.URL{Scheme: scheme, Host: r.Host, Path: p}).String()
+}
+
+// sitemapURL values are the pages listed in a sitemap.
+type sitemapURL struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+// sitemap values are sitemaps listing the public pages of the server.
+type sitemap struct {
+	XMLName xml.Name      `xml:"urlset"`
+	XMLNS   string        `xml:"xmlns,attr"`
+	URLs    []*sitemapURL `xml:"url"`
+}
+
+// sitemapGames retrieves the public active games listed in a sitemap, from
+// the most recently updated. On custom domains, only the games of the account
+// owning the domain are listed.
+func (s *Server) sitemapGames(ctx context.Context) ([]*Game, error) {
+	f := bson.M{"public": true, "status": request.StatusActive}
+
+	// Public games are found in the configured database, except those of the
+	// account owning a custom domain, which are stored with its tenancy.
+	aID, _ := ctx.Value(CtxKeyDomainAccountID).(string)
+	if aID != "" {
+		f["account_id"] = aID
+	}
+
+	c, err := s.collection(context.WithValue(ctx, request.CtxKeyAccountID,
+		aID), "games")
+	if err != nil {
+		return nil, err
+	}
+
+	cur, err := c.Find(ctx, f, options.Find().
+		SetLimit(maxSitemapGames).
+		SetSort(bson.D{{Key: "updated_at", Value: -1}}).
+		SetProjection(bson.M{"_id": 0, "id": 1, "updated_at": 1}))
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrDatabase,
+			"unable to find sitemap games")
+	}
+
+	res := []*Game{}
+
+	if err := cur.All(ctx, &res); err != nil {
+		return nil, errors.Wrap(err, errors.ErrDatabase,
+			"unable to decode sitemap games")
+	}
+
+	return res, nil
+}
+
+// getSitemapHandler serves a sitemap listing the pages of the public games,
+// so that they can be found by search engines.
+func (s *Server) getSitemapHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	games, err := s.sitemapGames(ctx)
+	if err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	sm := &sitemap{
+		XMLNS: sitemapNamespace,
+		URLs:  []*sitemapURL{{Loc: pageURL(r, "/")}},
+	}
+
+	for _, g := range games {
+		u := &sitemapURL{Loc: pageURL(r, "/embed/"+g.ID.Value)}
+
+		if g.UpdatedAt.Value > 0 {
+			u.LastMod = time.Unix(g.UpdatedAt.Value, 0).UTC().
+				Format(time.DateOnly)
+		}
+
+		sm.URLs = append(sm.URLs, u)
+	}
+
+	b, err := xml.Marshal(sm)
+	if err != nil {
+		s.error(errors.Wrap(err, errors.ErrServer,
+			"unable to encode sitemap"), w, r)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/xml; charset=UTF-8")
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+
+	if _, err := w.Write(append([]byte(xml.Header), b...)); err != nil {
+		s.error(err, w, r)
+	}
+}
+
+// getEmbedIconHandler serves the icon of an embedded game, which is used as
+// the thumbnail image of shared links to the game. Games without an icon are
+// served the default icon.
+func (s *Server) getEmbedIconHandler(w http.ResponseWriter, r *http.Request) {
+	_, g, err := s.embedGame(r)
+	if err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	b, err := base64.StdEncoding.DecodeString(g.Icon.Value)
+	if err != nil || len(b) == 0 {
+		if b, err = static.FS.ReadFile("icon.svg"); err != nil {
+			s.error(errors.Wrap(err, errors.ErrServer,
+				"unable to read default icon"), w, r)
+
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "image/svg+xml")
+	w.Header().Set("Cache-Control", "no-cache")
+
+	// Icons are generated, so they must not run scripts if opened directly.
+	w.Header().Set("Content-Security-Policy",
+		"default-src 'none'; style-src 'unsafe-inline'; sandbox")
+
+	if _, err := w.Write(b); err != nil {
+		s.error(err, w, r)
+	}
+}
@@ -0,0 +1,428 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dhaifley/game2d/errors"
+	"github.com/dhaifley/game2d/logger"
+	"github.com/dhaifley/game2d/request"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"golang.org/x/net/websocket"
+)
+
+// Spectate defaults.
+const (
+	// maxSnapshotSize is the maximum size of a compressed game state snapshot.
+	maxSnapshotSize = 1 << 20
+
+	// maxSpectators is the maximum number of spectators of each stream.
+	maxSpectators = 50
+)
+
+// gzipMagic is the header which begins every gzip compressed snapshot.
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// SpectateStream values describe the live game state streams published by the
+// players of a game, which may be watched by spectators.
+type SpectateStream struct {
+	AccountID  string `json:"account_id" yaml:"account_id"`
+	UserID     string `json:"user_id"    yaml:"user_id"`
+	GameID     string `json:"game_id"    yaml:"game_id"`
+	SessionID  string `json:"session_id" yaml:"session_id"`
+	Spectators int    `json:"spectators" yaml:"spectators"`
+	StartedAt  int64  `json:"started_at" yaml:"started_at"`
+	UpdatedAt  int64  `json:"updated_at" yaml:"updated_at"`
+}
+
+// spectateStream values relay the snapshots published for a play session to
+// its spectators. Only the most recent snapshot is kept, and spectators which
+// are too slow to receive every snapshot skip to the most recent one.
+type spectateStream struct {
+	sync.Mutex
+	info SpectateStream
+	last []byte
+	subs map[chan []byte]struct{}
+}
+
+// describe returns a description of the stream.
+func (st *spectateStream) describe() *SpectateStream {
+	st.Lock()
+	defer st.Unlock()
+
+	res := st.info
+
+	res.Spectators = len(st.subs)
+
+	return &res
+}
+
+// publish sends a snapshot to every spectator of the stream.
+func (st *spectateStream) publish(b []byte) {
+	st.Lock()
+	defer st.Unlock()
+
+	st.last = b
+	st.info.UpdatedAt = time.Now().Unix()
+
+	for ch := range st.subs {
+		select {
+		case <-ch:
+		default:
+		}
+
+		ch <- b
+	}
+}
+
+// subscribe adds a spectator to the stream, and returns the channel on which
+// it receives snapshots, starting with the most recent snapshot, if any.
+func (st *spectateStream) subscribe() (chan []byte, error) {
+	st.Lock()
+	defer st.Unlock()
+
+	if st.subs == nil {
+		return nil, errors.New(errors.ErrNotFound,
+			"spectate stream ended",
+			"session_id", st.info.SessionID)
+	}
+
+	if len(st.subs) >= maxSpectators {
+		return nil, errors.New(errors.ErrConflict,
+			"too many spectators",
+			"session_id", st.info.SessionID,
+			"max", maxSpectators)
+	}
+
+	ch := make(chan []byte, 1)
+
+	if st.last != nil {
+		ch <- st.last
+	}
+
+	st.subs[ch] = struct{}{}
+
+	return ch, nil
+}
+
+// unsubscribe removes a spectator from the stream.
+func (st *spectateStream) unsubscribe(ch chan []byte) {
+	st.Lock()
+	defer st.Unlock()
+
+	if _, ok := st.subs[ch]; ok {
+		delete(st.subs, ch)
+		close(ch)
+	}
+}
+
+// end disconnects every spectator of the stream.
+func (st *spectateStream) end() {
+	st.Lock()
+	defer st.Unlock()
+
+	for ch := range st.subs {
+		close(ch)
+	}
+
+	st.subs = nil
+}
+
+// spectateSession validates a game and play session ID, and checks that the
+// game is visible to the current account.
+func (s *Server) spectateSession(ctx context.Context,
+	id, session string,
+) error {
+	if _, err := uuid.Parse(session); err != nil {
+		return errors.New(errors.ErrInvalidRequest,
+			"invalid session_id",
+			"session_id", session)
+	}
+
+	if _, err := s.getGame(context.WithValue(ctx, CtxKeyGameMinData, true),
+		id); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// getSpectateStreams retrieves the live streams of a game published by users
+// of the current account.
+func (s *Server) getSpectateStreams(ctx context.Context,
+	id string,
+) ([]*SpectateStream, error) {
+	aID, err := request.ContextAccountID(ctx)
+	if err != nil {
+		return nil, errors.New(errors.ErrUnauthorized,
+			"unable to get account id from context")
+	}
+
+	if _, err := s.getGame(context.WithValue(ctx, CtxKeyGameMinData, true),
+		id); err != nil {
+		return nil, err
+	}
+
+	res := []*SpectateStream{}
+
+	s.streams.Range(func(_, v any) bool {
+		if st, ok := v.(*spectateStream); ok {
+			if info := st.describe(); info.AccountID == aID &&
+				info.GameID == id {
+				res = append(res, info)
+			}
+		}
+
+		return true
+	})
+
+	slices.SortFunc(res, func(a, b *SpectateStream) int {
+		return strings.Compare(a.SessionID, b.SessionID)
+	})
+
+	return res, nil
+}
+
+// getSpectateStream retrieves the live stream of a play session of a game, if
+// it was published by a user of the current account.
+func (s *Server) getSpectateStream(ctx context.Context,
+	id, session string,
+) (*spectateStream, error) {
+	aID, err := request.ContextAccountID(ctx)
+	if err != nil {
+		return nil, errors.New(errors.ErrUnauthorized,
+			"unable to get account id from context")
+	}
+
+	if err := s.spectateSession(ctx, id, session); err != nil {
+		return nil, err
+	}
+
+	if v, ok := s.streams.Load(session); ok {
+		if st, ok := v.(*spectateStream); ok {
+			if info := st.describe(); info.AccountID == aID &&
+				info.GameID == id {
+				return st, nil
+			}
+		}
+	}
+
+	return nil, errors.New(errors.ErrNotFound,
+		"spectate stream not found",
+		"id", id,
+		"session_id", session)
+}
+
+// startSpectateStream registers the live stream of a play session of a game,
+// published by the current user.
+func (s *Server) startSpectateStream(ctx context.Context,
+	id, session string,
+) (*spectateStream, error) {
+	aID, err := request.ContextAccountID(ctx)
+	if err != nil {
+		return nil, errors.New(errors.ErrUnauthorized,
+			"unable to get account id from context")
+	}
+
+	uID, err := request.ContextUserID(ctx)
+	if err != nil {
+		return nil, errors.New(errors.ErrUnauthorized,
+			"unable to get user id from context")
+	}
+
+	now := time.Now().Unix()
+
+	st := &spectateStream{
+		info: SpectateStream{
+			AccountID: aID,
+			UserID:    uID,
+			GameID:    id,
+			SessionID: session,
+			StartedAt: now,
+			UpdatedAt: now,
+		},
+		subs: map[chan []byte]struct{}{},
+	}
+
+	if _, loaded := s.streams.LoadOrStore(session, st); loaded {
+		return nil, errors.New(errors.ErrConflict,
+			"spectate stream already published",
+			"id", id,
+			"session_id", session)
+	}
+
+	return st, nil
+}
+
+// endSpectateStream removes the live stream of a play session, and
+// disconnects its spectators.
+func (s *Server) endSpectateStream(st *spectateStream) {
+	s.streams.CompareAndDelete(st.info.SessionID, st)
+
+	st.end()
+}
+
+// publishSnapshots receives the snapshots published for a stream, until the
+// publisher disconnects, or stops publishing for longer than the session
+// timeout. Snapshots which are not gzip compressed are ignored.
+func (s *Server) publishSnapshots(ws *websocket.Conn, st *spectateStream) {
+	defer s.endSpectateStream(st)
+
+	ws.MaxPayloadBytes = maxSnapshotSize
+
+	for {
+		if err := ws.SetReadDeadline(time.Now().Add(
+			sessionTimeout)); err != nil {
+			return
+		}
+
+		var b []byte
+
+		if err := websocket.Message.Receive(ws, &b); err != nil {
+			if !errors.Is(err, io.EOF) {
+				s.log.Log(ws.Request().Context(), logger.LvlDebug,
+					"spectate stream publisher disconnected",
+					"error", err,
+					"session_id", st.info.SessionID)
+			}
+
+			return
+		}
+
+		if !bytes.HasPrefix(b, gzipMagic) {
+			continue
+		}
+
+		st.publish(b)
+	}
+}
+
+// sendSnapshots sends the snapshots of a stream to a spectator, until either
+// the spectator disconnects, or the stream ends. Any messages sent by the
+// spectator are discarded.
+func (s *Server) sendSnapshots(ws *websocket.Conn, ch chan []byte) {
+	closed := make(chan struct{})
+
+	go func() {
+		defer close(closed)
+
+		_, _ = io.Copy(io.Discard, ws)
+	}()
+
+	ws.PayloadType = websocket.BinaryFrame
+
+	for {
+		select {
+		case <-closed:
+			return
+		case b, ok := <-ch:
+			if !ok {
+				return
+			}
+
+			if err := websocket.Message.Send(ws, b); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// getSpectateStreamsHandler is the get handler used to list the live streams
+// of a game.
+func (s *Server) getSpectateStreamsHandler(w http.ResponseWriter,
+	r *http.Request,
+) {
+	ctx := r.Context()
+
+	if err := s.checkScope(ctx, request.ScopeGamesRead); err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	res, err := s.getSpectateStreams(ctx, chi.URLParam(r, "id"))
+	if err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	if err := s.encode(w, r, res); err != nil {
+		s.error(err, w, r)
+	}
+}
+
+// getSpectateHandler is the get handler used to watch the live stream of a
+// play session of a game over a WebSocket connection.
+func (s *Server) getSpectateHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if err := s.checkScope(ctx, request.ScopeGamesRead); err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	st, err := s.getSpectateStream(ctx, chi.URLParam(r, "id"),
+		chi.URLParam(r, "session"))
+	if err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	ch, err := st.subscribe()
+	if err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	defer st.unsubscribe(ch)
+
+	websocket.Server{Handler: func(ws *websocket.Conn) {
+		s.sendSnapshots(ws, ch)
+	}}.ServeHTTP(w, r)
+}
+
+// getSpectatePublishHandler is the get handler used to publish the live
+// stream of a play session of a game over a WebSocket connection.
+func (s *Server) getSpectatePublishHandler(w http.ResponseWriter,
+	r *http.Request,
+) {
+	ctx := r.Context()
+
+	if err := s.checkScope(ctx, request.ScopeGamesRead); err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	id, session := chi.URLParam(r, "id"), chi.URLParam(r, "session")
+
+	if err := s.spectateSession(ctx, id, session); err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	st, err := s.startSpectateStream(ctx, id, session)
+	if err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	defer s.endSpectateStream(st)
+
+	websocket.Server{Handler: func(ws *websocket.Conn) {
+		s.publishSnapshots(ws, st)
+	}}.ServeHTTP(w, r)
+}
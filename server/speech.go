@@ -0,0 +1,278 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strings"
+
+	"github.com/dhaifley/game2d/errors"
+	"github.com/dhaifley/game2d/request"
+	"github.com/go-chi/chi/v5"
+)
+
+// SpeechProviderOpenAI is the speech to text provider using the OpenAI audio
+// transcription API, or any API compatible with it.
+const SpeechProviderOpenAI = "openai"
+
+// speechTypes are the media types of the audio clips of voice prompts, and the
+// file extensions used to send them to the speech to text provider.
+var speechTypes = map[string]string{
+	"audio/webm":      ".webm",
+	"video/webm":      ".webm",
+	"audio/ogg":       ".ogg",
+	"application/ogg": ".ogg",
+	"audio/mpeg":      ".mp3",
+	"audio/mp4":       ".mp4",
+	"audio/x-m4a":     ".m4a",
+	"audio/wav":       ".wav",
+	"audio/wave":      ".wav",
+	"audio/x-wav":     ".wav",
+	"audio/flac":      ".flac",
+}
+
+// Transcriber values are able to transcribe speech to text.
+type Transcriber interface {
+	Transcribe(ctx context.Context,
+		audio []byte,
+		mediaType string,
+	) (string, error)
+}
+
+// getTranscriber returns the transcriber used for voice prompts, or nil if
+// voice prompts are disabled.
+func (s *Server) getTranscriber() Transcriber {
+	s.RLock()
+
+	t := s.transcriber
+
+	s.RUnlock()
+
+	if t != nil {
+		return t
+	}
+
+	switch s.cfg.SpeechProvider() {
+	case SpeechProviderOpenAI:
+		return &openAITranscriber{s: s}
+	}
+
+	return nil
+}
+
+// openAITranscriber values are able to transcribe speech to text using the
+// OpenAI audio transcription API. The configuration is read for every request,
+// so that rotated API keys are used.
+type openAITranscriber struct {
+	s *Server
+}
+
+// Transcribe transcribes an audio clip to text.
+func (t *openAITranscriber) Transcribe(ctx context.Context,
+	audio []byte,
+	mediaType string,
+) (string, error) {
+	body := &bytes.Buffer{}
+
+	mw := multipart.NewWriter(body)
+
+	if err := mw.WriteField("model", t.s.cfg.SpeechModel()); err != nil {
+		return "", errors.Wrap(err, errors.ErrServer,
+			"unable to encode transcription request")
+	}
+
+	if err := mw.WriteField("response_format", "json"); err != nil {
+		return "", errors.Wrap(err, errors.ErrServer,
+			"unable to encode transcription request")
+	}
+
+	fw, err := mw.CreateFormFile("file", "prompt"+speechTypes[mediaType])
+	if err != nil {
+		return "", errors.Wrap(err, errors.ErrServer,
+			"unable to encode transcription request")
+	}
+
+	if _, err := fw.Write(audio); err != nil {
+		return "", errors.Wrap(err, errors.ErrServer,
+			"unable to encode transcription request")
+	}
+
+	if err := mw.Close(); err != nil {
+		return "", errors.Wrap(err, errors.ErrServer,
+			"unable to encode transcription request")
+	}
+
+	ctx, cancel := t.s.opContext(ctx, opOutbound)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		t.s.cfg.SpeechURL(), body)
+	if err != nil {
+		return "", errors.Wrap(err, errors.ErrClient,
+			"unable to create transcription request")
+	}
+
+	req.Header.Set("Authorization", "Bearer "+t.s.cfg.SpeechAPIKey())
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+
+	cli := &http.Client{Timeout: t.s.cfg.ServerOutboundTimeout()}
+
+	res, err := cli.Do(req)
+	if err != nil {
+		return "", errors.Wrap(err, errors.ErrClient,
+			"unable to send transcription request")
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return "", errors.New(errors.ErrClient,
+			"unexpected transcription response status",
+			"status", res.StatusCode)
+	}
+
+	var tr struct {
+		Text string `json:"text"`
+	}
+
+	if err := json.NewDecoder(res.Body).Decode(&tr); err != nil {
+		return "", errors.Wrap(err, errors.ErrClient,
+			"unable to decode transcription response")
+	}
+
+	return tr.Text, nil
+}
+
+// NewMockTranscriber creates a new mock transcriber, which transcribes any
+// audio clip to the given text.
+func NewMockTranscriber(text string) Transcriber {
+	return &mockTranscriber{text: text}
+}
+
+// mockTranscriber values are transcribers used for testing.
+type mockTranscriber struct {
+	text string
+}
+
+// Transcribe returns the text of the mock transcriber.
+func (m *mockTranscriber) Transcribe(ctx context.Context,
+	audio []byte,
+	mediaType string,
+) (string, error) {
+	return m.text, nil
+}
+
+// speechType returns the media type of an uploaded audio clip, from its part
+// header, or else from its contents, if it is a supported type.
+func speechType(fh *multipart.FileHeader, b []byte) (string, bool) {
+	ct := fh.Header.Get("Content-Type")
+
+	if mt, _, err := mime.ParseMediaType(ct); err == nil {
+		if _, ok := speechTypes[mt]; ok {
+			return mt, true
+		}
+	}
+
+	mt, _, _ := mime.ParseMediaType(http.DetectContentType(b))
+
+	_, ok := speechTypes[mt]
+
+	return mt, ok
+}
+
+// postGamePromptAudioHandler is the post handler used to prompt the AI about a
+// game by voice, using a multipart form. The audio part contains a short audio
+// clip, which is transcribed to text and used as the prompt. Attachments may
+// be included as for other prompts.
+func (s *Server) postGamePromptAudioHandler(w http.ResponseWriter,
+	r *http.Request,
+) {
+	ctx := r.Context()
+
+	if err := s.checkScope(ctx, request.ScopeGamesWrite); err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	t := s.getTranscriber()
+	if t == nil {
+		s.error(errors.New(errors.ErrUnavailable,
+			"voice prompts are not available"), w, r)
+
+		return
+	}
+
+	if err := r.ParseMultipartForm(DefaultUploadMemory); err != nil {
+		s.error(errors.Wrap(err, errors.ErrInvalidRequest,
+			"unable to decode multipart request"), w, r)
+
+		return
+	}
+
+	defer r.MultipartForm.RemoveAll()
+
+	fhs := r.MultipartForm.File["audio"]
+	if len(fhs) == 0 {
+		s.error(errors.New(errors.ErrInvalidRequest,
+			"missing audio"), w, r)
+
+		return
+	}
+
+	if fhs[0].Size > s.cfg.SpeechMaxSize() {
+		s.error(errors.New(errors.ErrInvalidRequest,
+			"audio too large",
+			"size", fhs[0].Size,
+			"max", s.cfg.SpeechMaxSize()), w, r)
+
+		return
+	}
+
+	b, err := readUploadPart(fhs[0])
+	if err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	mt, ok := speechType(fhs[0], b)
+	if !ok {
+		s.error(errors.New(errors.ErrInvalidRequest,
+			"invalid audio type",
+			"type", mt), w, r)
+
+		return
+	}
+
+	req, err := uploadedPrompts(r.MultipartForm, chi.URLParam(r, "id"))
+	if err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	text, err := t.Transcribe(ctx, b, mt)
+	if err != nil {
+		s.error(errors.Wrap(err, errors.ErrClient,
+			"unable to transcribe audio"), w, r)
+
+		return
+	}
+
+	if text = strings.TrimSpace(text); text == "" {
+		s.error(errors.New(errors.ErrInvalidRequest,
+			"no speech found in audio"), w, r)
+
+		return
+	}
+
+	req.Current.Prompt = request.FieldString{
+		Set: true, Valid: true, Value: text,
+	}
+
+	s.servePrompt(w, r, req)
+}
@@ -0,0 +1,120 @@
+package server
+
+import (
+	"io/fs"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/dhaifley/game2d/errors"
+)
+
+// staticEncodings are the content encodings, in order of preference, of the
+// pre-compressed static files, which are embedded next to the uncompressed
+// files with the file extension of their encoding.
+var staticEncodings = []struct {
+	encoding, ext string
+}{{
+	encoding: "br", ext: ".br",
+}, {
+	encoding: "gzip", ext: ".gz",
+}}
+
+// acceptsEncoding returns whether a request accepts a content encoding, either
+// by name, or, if it is not named, by the * wildcard. Encodings with a quality
+// value of zero are not accepted.
+func acceptsEncoding(r *http.Request, encoding string) bool {
+	wildcard := false
+
+	for _, v := range r.Header.Values("Accept-Encoding") {
+		for _, ae := range strings.Split(v, ",") {
+			name, params, _ := strings.Cut(ae, ";")
+
+			name = strings.TrimSpace(name)
+
+			switch {
+			case strings.EqualFold(name, encoding):
+				return acceptsQuality(params)
+			case name == "*":
+				wildcard = acceptsQuality(params)
+			}
+		}
+	}
+
+	return wildcard
+}
+
+// acceptsQuality returns whether the parameters of an accepted value have a
+// quality value which is not zero. Values without a quality value are
+// accepted.
+func acceptsQuality(params string) bool {
+	for _, p := range strings.Split(params, ";") {
+		q, ok := strings.CutPrefix(strings.TrimSpace(p), "q=")
+		if !ok {
+			continue
+		}
+
+		f, err := strconv.ParseFloat(q, 64)
+
+		return err == nil && f > 0
+	}
+
+	return true
+}
+
+// addVary adds a request header name to the Vary header of a response, unless
+// it is already listed, keeping any names set by the header middleware.
+func addVary(h http.Header, name string) {
+	for _, v := range h.Values("Vary") {
+		for _, n := range strings.Split(v, ",") {
+			if n = strings.TrimSpace(n); n == "*" || strings.EqualFold(n, name) {
+				return
+			}
+		}
+	}
+
+	h.Add("Vary", name)
+}
+
+// serveFile responds to the current request with a static file. If the request
+// accepts it, a pre-compressed variant of the file is served instead.
+func (s *Server) serveFile(w http.ResponseWriter,
+	r *http.Request,
+	fsys fs.ReadFileFS,
+	name, contentType string,
+) {
+	w.Header().Set("Content-Type", contentType)
+	addVary(w.Header(), "Accept-Encoding")
+
+	for _, se := range staticEncodings {
+		if !acceptsEncoding(r, se.encoding) {
+			continue
+		}
+
+		v, err := fsys.ReadFile(name + se.ext)
+		if err != nil {
+			continue
+		}
+
+		w.Header().Set("Content-Encoding", se.encoding)
+
+		if _, err := w.Write(v); err != nil {
+			s.error(err, w, r)
+		}
+
+		return
+	}
+
+	v, err := fsys.ReadFile(name)
+	if err != nil {
+		s.error(errors.Wrap(err, errors.ErrNotFound,
+			"file not found",
+			"file", name), w, r)
+
+		return
+	}
+
+	if _, err := w.Write(v); err != nil {
+		s.error(err, w, r)
+	}
+}
@@ -0,0 +1,255 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"testing/fstest"
+
+	"github.com/dhaifley/game2d/config"
+)
+
+func TestAcceptsEncoding(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		accept   []string
+		encoding string
+		exp      bool
+	}{{
+		name:     "none",
+		encoding: "gzip",
+	}, {
+		name:     "named",
+		accept:   []string{"gzip, deflate, br"},
+		encoding: "br",
+		exp:      true,
+	}, {
+		name:     "not named",
+		accept:   []string{"gzip, deflate"},
+		encoding: "br",
+	}, {
+		name:     "case insensitive",
+		accept:   []string{"GZip"},
+		encoding: "gzip",
+		exp:      true,
+	}, {
+		name:     "quality",
+		accept:   []string{"br;q=0.5"},
+		encoding: "br",
+		exp:      true,
+	}, {
+		name:     "quality zero",
+		accept:   []string{"gzip, br;q=0"},
+		encoding: "br",
+	}, {
+		name:     "quality zero with spaces",
+		accept:   []string{"br ; q=0.0"},
+		encoding: "br",
+	}, {
+		name:     "quality invalid",
+		accept:   []string{"br;q=high"},
+		encoding: "br",
+	}, {
+		name:     "multiple headers",
+		accept:   []string{"deflate", "gzip"},
+		encoding: "gzip",
+		exp:      true,
+	}, {
+		name:     "wildcard",
+		accept:   []string{"*"},
+		encoding: "br",
+		exp:      true,
+	}, {
+		name:     "wildcard quality zero",
+		accept:   []string{"*;q=0"},
+		encoding: "gzip",
+	}, {
+		name:     "named quality zero overrides wildcard",
+		accept:   []string{"*, br;q=0"},
+		encoding: "br",
+	}, {
+		name:     "named overrides wildcard quality zero",
+		accept:   []string{"br, *;q=0"},
+		encoding: "br",
+		exp:      true,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+
+			for _, v := range tt.accept {
+				r.Header.Add("Accept-Encoding", v)
+			}
+
+			if v := acceptsEncoding(r, tt.encoding); v != tt.exp {
+				t.Errorf("Expected accepts %v: %v, got: %v",
+					tt.encoding, tt.exp, v)
+			}
+		})
+	}
+}
+
+func TestAddVary(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		vary []string
+		exp  []string
+	}{{
+		name: "empty",
+		exp:  []string{"Accept-Encoding"},
+	}, {
+		name: "middleware",
+		vary: []string{"Accept, Accept-Encoding, Origin"},
+		exp:  []string{"Accept, Accept-Encoding, Origin"},
+	}, {
+		name: "other names",
+		vary: []string{"Accept, Origin"},
+		exp:  []string{"Accept, Origin", "Accept-Encoding"},
+	}, {
+		name: "wildcard",
+		vary: []string{"*"},
+		exp:  []string{"*"},
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			h := http.Header{}
+
+			for _, v := range tt.vary {
+				h.Add("Vary", v)
+			}
+
+			addVary(h, "Accept-Encoding")
+
+			if v := h.Values("Vary"); !slices.Equal(v, tt.exp) {
+				t.Errorf("Expected vary: %v, got: %v", tt.exp, v)
+			}
+		})
+	}
+}
+
+func TestServeFile(t *testing.T) {
+	t.Parallel()
+
+	svr, err := NewServer(config.NewDefault(), nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fsys := fstest.MapFS{
+		"test.js":    {Data: []byte("identity")},
+		"test.js.br": {Data: []byte("br")},
+		"test.js.gz": {Data: []byte("gzip")},
+		"gzip.js":    {Data: []byte("identity")},
+		"gzip.js.gz": {Data: []byte("gzip")},
+	}
+
+	tests := []struct {
+		name     string
+		file     string
+		accept   string
+		status   int
+		encoding string
+		body     string
+	}{{
+		name:   "identity",
+		file:   "test.js",
+		status: http.StatusOK,
+		body:   "identity",
+	}, {
+		name:     "br preferred",
+		file:     "test.js",
+		accept:   "gzip, br",
+		status:   http.StatusOK,
+		encoding: "br",
+		body:     "br",
+	}, {
+		name:     "gzip",
+		file:     "test.js",
+		accept:   "gzip",
+		status:   http.StatusOK,
+		encoding: "gzip",
+		body:     "gzip",
+	}, {
+		name:     "br excluded",
+		file:     "test.js",
+		accept:   "br;q=0, gzip",
+		status:   http.StatusOK,
+		encoding: "gzip",
+		body:     "gzip",
+	}, {
+		name:     "wildcard",
+		file:     "test.js",
+		accept:   "*",
+		status:   http.StatusOK,
+		encoding: "br",
+		body:     "br",
+	}, {
+		name:   "all excluded",
+		file:   "test.js",
+		accept: "br;q=0, gzip;q=0",
+		status: http.StatusOK,
+		body:   "identity",
+	}, {
+		name:     "br not available",
+		file:     "gzip.js",
+		accept:   "br, gzip",
+		status:   http.StatusOK,
+		encoding: "gzip",
+		body:     "gzip",
+	}, {
+		name:   "unsupported encoding",
+		file:   "gzip.js",
+		accept: "deflate",
+		status: http.StatusOK,
+		body:   "identity",
+	}, {
+		name:   "not found",
+		file:   "missing.js",
+		accept: "gzip",
+		status: http.StatusNotFound,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			r := httptest.NewRequest(http.MethodGet, "/"+tt.file, nil)
+
+			if tt.accept != "" {
+				r.Header.Set("Accept-Encoding", tt.accept)
+			}
+
+			w := httptest.NewRecorder()
+
+			svr.serveFile(w, r, fsys, tt.file, "text/javascript")
+
+			if w.Code != tt.status {
+				t.Errorf("Expected status: %v, got: %v", tt.status, w.Code)
+			}
+
+			if v := w.Header().Get("Content-Encoding"); v != tt.encoding {
+				t.Errorf("Expected content encoding: %v, got: %v",
+					tt.encoding, v)
+			}
+
+			if v := w.Header().Get("Vary"); v != "Accept-Encoding" {
+				t.Errorf("Expected vary: Accept-Encoding, got: %v", v)
+			}
+
+			if tt.body != "" && w.Body.String() != tt.body {
+				t.Errorf("Expected body: %v, got: %v", tt.body, w.Body)
+			}
+		})
+	}
+}
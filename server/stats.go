@@ -2,7 +2,6 @@ package server
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"net/http"
 	"runtime"
@@ -31,26 +30,144 @@ func (s *Server) HealthHandler() http.Handler {
 	return r
 }
 
+// ReadyHandler returns a route handler for /ready requests, which report
+// whether the server is ready to receive traffic.
+func (s *Server) ReadyHandler() http.Handler {
+	r := chi.NewRouter()
+
+	r.With(s.stat, s.trace).Get("/", s.getReadyCheckHandler)
+
+	return r
+}
+
+// Readiness statuses of server dependencies.
+const (
+	ReadyOK          = "ok"
+	ReadyConnecting  = "connecting"
+	ReadyUnavailable = "unavailable"
+)
+
 // HealthCheck values represent return information from health checks.
 type HealthCheck struct {
-	Service   string `json:"service,omitempty"`
-	Version   string `json:"version,omitempty"`
-	CommitID  string `json:"commit_id,omitempty"`
-	BuildTime string `json:"build_time,omitempty"`
-	Health    uint32 `json:"health,omitempty"`
+	Service   string `json:"service,omitempty"    yaml:"service,omitempty"`
+	Version   string `json:"version,omitempty"    yaml:"version,omitempty"`
+	CommitID  string `json:"commit_id,omitempty"  yaml:"commit_id,omitempty"`
+	BuildTime string `json:"build_time,omitempty" yaml:"build_time,omitempty"`
+	Health    uint32 `json:"health,omitempty"     yaml:"health,omitempty"`
+	Database  string `json:"database,omitempty"   yaml:"database,omitempty"`
+	Cache     string `json:"cache,omitempty"      yaml:"cache,omitempty"`
+}
+
+// liveness returns the status code reported by health checks. Until the
+// database is connected, the server is only reported as healthy during the
+// startup grace period, so that instances which can not connect are
+// restarted.
+func (s *Server) liveness() uint32 {
+	h := s.Health()
+
+	if h != http.StatusOK || s.DB() != nil {
+		return h
+	}
+
+	if time.Since(s.started) > s.cfg.ServerStartupGrace() {
+		return http.StatusServiceUnavailable
+	}
+
+	return h
+}
+
+// readyDB returns the readiness status of the database.
+func (s *Server) readyDB(ctx context.Context) string {
+	db := s.DB()
+	if db == nil {
+		return ReadyConnecting
+	}
+
+	ctx, cancel := s.opContext(ctx, opDB)
+	defer cancel()
+
+	if err := db.Client().Ping(ctx, nil); err != nil {
+		s.log.Log(ctx, logger.LvlWarn,
+			"database not ready",
+			"error", err)
+
+		return ReadyUnavailable
+	}
+
+	return ReadyOK
+}
+
+// readyCache returns the readiness status of the cache, which is empty if no
+// cache is used.
+func (s *Server) readyCache(ctx context.Context) string {
+	s.RLock()
+
+	c := s.cache
+
+	s.RUnlock()
+
+	if c == nil {
+		return ""
+	}
+
+	p, ok := c.(interface {
+		Ping(ctx context.Context) error
+	})
+	if !ok {
+		return ReadyOK
+	}
+
+	ctx, cancel := request.ContextWithTimeout(ctx, s.cfg.CacheTimeout())
+	defer cancel()
+
+	if err := p.Ping(ctx); err != nil {
+		s.log.Log(ctx, logger.LvlWarn,
+			"cache not ready",
+			"error", err)
+
+		return ReadyUnavailable
+	}
+
+	return ReadyOK
+}
+
+// getReadyCheckHandler is the handler function for the readiness check path.
+// The server is only ready once it is healthy, and connected to the database
+// and cache.
+func (s *Server) getReadyCheckHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	res := &HealthCheck{
+		Service:  s.cfg.ServiceName(),
+		Health:   s.Health(),
+		Version:  Version,
+		Database: s.readyDB(ctx),
+		Cache:    s.readyCache(ctx),
+	}
+
+	if res.Database != ReadyOK ||
+		(res.Cache != "" && res.Cache != ReadyOK) {
+		res.Health = http.StatusServiceUnavailable
+	}
+
+	w.WriteHeader(int(res.Health))
+
+	if err := s.encode(w, r, res); err != nil {
+		s.error(err, w, r)
+	}
 }
 
 // getHealthCheckHandler is the handler function for the health check path.
 func (s *Server) getHealthCheckHandler(w http.ResponseWriter, r *http.Request) {
 	res := &HealthCheck{
 		Service: s.cfg.ServiceName(),
-		Health:  s.Health(),
+		Health:  s.liveness(),
 		Version: Version,
 	}
 
 	w.WriteHeader(int(res.Health))
 
-	if err := json.NewEncoder(w).Encode(res); err != nil {
+	if err := s.encode(w, r, res); err != nil {
 		s.error(err, w, r)
 	}
 }
@@ -68,7 +185,7 @@ func (s *Server) putHealthCheckHandler(w http.ResponseWriter, r *http.Request) {
 
 	req := &HealthCheck{}
 
-	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+	if err := s.decode(r, req); err != nil {
 		switch e := err.(type) {
 		case *errors.Error:
 			s.error(e, w, r)
@@ -89,7 +206,7 @@ func (s *Server) putHealthCheckHandler(w http.ResponseWriter, r *http.Request) {
 
 	w.WriteHeader(int(res.Health))
 
-	if err := json.NewEncoder(w).Encode(res); err != nil {
+	if err := s.encode(w, r, res); err != nil {
 		s.error(err, w, r)
 	}
 }
@@ -108,7 +225,11 @@ func (s *Server) UpdateMetrics(ctx context.Context,
 
 	interval := time.Duration(0)
 
+	s.jobs.Add(1)
+
 	go func(ctx context.Context) {
+		defer s.jobs.Done()
+
 		for {
 			tick := time.NewTimer(interval)
 
@@ -5,12 +5,78 @@ import (
 	"encoding/json"
 	"io"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"strings"
 	"sync"
 	"testing"
+
+	"github.com/dhaifley/game2d/cbor"
+	"github.com/dhaifley/game2d/config"
+	"github.com/dhaifley/game2d/server"
 )
 
+func TestReadiness(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		config string
+		url    string
+		status int
+		db     string
+	}{{
+		name:   "health during startup",
+		url:    basePath + "/health",
+		status: http.StatusOK,
+	}, {
+		name:   "health after startup",
+		config: "server:\n  startup_grace: 1ns\n",
+		url:    basePath + "/health",
+		status: http.StatusServiceUnavailable,
+	}, {
+		name:   "ready during startup",
+		url:    basePath + "/ready",
+		status: http.StatusServiceUnavailable,
+		db:     server.ReadyConnecting,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			cfg := &config.Config{}
+
+			cfg.Load([]byte(tt.config))
+
+			svr, err := server.NewServer(cfg, nil, nil, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			r := httptest.NewRequest(http.MethodGet, tt.url, nil)
+
+			w := httptest.NewRecorder()
+
+			svr.Mux(w, r)
+
+			if w.Code != tt.status {
+				t.Errorf("Expected status: %v, got: %v", tt.status, w.Code)
+			}
+
+			hc := &server.HealthCheck{}
+
+			if err := json.NewDecoder(w.Body).Decode(hc); err != nil {
+				t.Errorf("Unexpected error decoding response: %v", err)
+			}
+
+			if hc.Database != tt.db {
+				t.Errorf("Expected database: %v, got: %v", tt.db, hc.Database)
+			}
+		})
+	}
+}
+
 func TestStatsServer(t *testing.T) {
 	if testing.Short() {
 		t.Skip("skipping integration tests")
@@ -59,6 +125,244 @@ func TestStatsServer(t *testing.T) {
 			data["health"] = health
 			dataLock.Unlock()
 		},
+	}, {
+		name:   "health yaml",
+		url:    "http://localhost:8080/api/v1/health",
+		method: http.MethodGet,
+		header: map[string]string{"Accept": "application/yaml"},
+		resp: func(t *testing.T, res *http.Response) {
+			expT := "application/yaml"
+
+			if v := res.Header.Get("Content-Type"); !strings.HasPrefix(v,
+				expT) {
+				t.Errorf("Content type expected: %v, got: %v", expT, v)
+			}
+
+			b, err := io.ReadAll(res.Body)
+			if err != nil {
+				t.Errorf("Unexpected response error: %v", err)
+			}
+
+			expB := "health: "
+
+			if !strings.Contains(string(b), expB) {
+				t.Errorf("Expected body to contain: %v, got: %v",
+					expB, string(b))
+			}
+		},
+	}, {
+		name:   "health cbor",
+		url:    "http://localhost:8080/api/v1/health",
+		method: http.MethodGet,
+		header: map[string]string{"Accept": "application/cbor"},
+		resp: func(t *testing.T, res *http.Response) {
+			expT := "application/cbor"
+
+			if v := res.Header.Get("Content-Type"); v != expT {
+				t.Errorf("Content type expected: %v, got: %v", expT, v)
+			}
+
+			b, err := io.ReadAll(res.Body)
+			if err != nil {
+				t.Errorf("Unexpected response error: %v", err)
+			}
+
+			var v map[string]any
+
+			if err := cbor.Unmarshal(b, &v); err != nil {
+				t.Errorf("Unexpected decode error: %v", err)
+			}
+
+			if _, ok := v["health"]; !ok {
+				t.Errorf("Expected health in body, got: %v", v)
+			}
+		},
+	}, {
+		name:   "ready",
+		url:    "http://localhost:8080/api/v1/ready",
+		method: http.MethodGet,
+		resp: func(t *testing.T, res *http.Response) {
+			expC := http.StatusOK
+
+			if res.StatusCode != expC {
+				t.Errorf("Status code expected: %v, got: %v",
+					expC, res.StatusCode)
+			}
+
+			hc := &server.HealthCheck{}
+
+			if err := json.NewDecoder(res.Body).Decode(hc); err != nil {
+				t.Errorf("Unexpected error decoding response: %v", err)
+			}
+
+			if hc.Database != server.ReadyOK {
+				t.Errorf("Expected database: %v, got: %v",
+					server.ReadyOK, hc.Database)
+			}
+		},
+	}, {
+		name:   "error catalog",
+		url:    "http://localhost:8080/api/v1/errors/catalog",
+		method: http.MethodGet,
+		resp: func(t *testing.T, res *http.Response) {
+			expC := http.StatusOK
+
+			if res.StatusCode != expC {
+				t.Errorf("Status code expected: %v, got: %v",
+					expC, res.StatusCode)
+			}
+
+			b, err := io.ReadAll(res.Body)
+			if err != nil {
+				t.Errorf("Unexpected response error: %v", err)
+			}
+
+			expB := `"reason":"GAME_TOO_LARGE"`
+
+			if !strings.Contains(string(b), expB) {
+				t.Errorf("Expected body to contain: %v, got: %v",
+					expB, string(b))
+			}
+		},
+	}, {
+		name:   "lua api",
+		url:    "http://localhost:8080/api/v1/schema/lua-api",
+		method: http.MethodGet,
+		resp: func(t *testing.T, res *http.Response) {
+			expC := http.StatusOK
+
+			if res.StatusCode != expC {
+				t.Errorf("Status code expected: %v, got: %v",
+					expC, res.StatusCode)
+			}
+
+			b, err := io.ReadAll(res.Body)
+			if err != nil {
+				t.Errorf("Unexpected response error: %v", err)
+			}
+
+			expB := `"name":"ArrowUp"`
+
+			if !strings.Contains(string(b), expB) {
+				t.Errorf("Expected body to contain: %v, got: %v",
+					expB, string(b))
+			}
+		},
+	}, {
+		name:   "error reason",
+		url:    "http://localhost:8080/api/v1/invalid",
+		method: http.MethodGet,
+		resp: func(t *testing.T, res *http.Response) {
+			expC := http.StatusNotFound
+
+			if res.StatusCode != expC {
+				t.Errorf("Status code expected: %v, got: %v",
+					expC, res.StatusCode)
+			}
+
+			b, err := io.ReadAll(res.Body)
+			if err != nil {
+				t.Errorf("Unexpected response error: %v", err)
+			}
+
+			expB := `"reason":"NOT_FOUND"`
+
+			if !strings.Contains(string(b), expB) {
+				t.Errorf("Expected body to contain: %v, got: %v",
+					expB, string(b))
+			}
+		},
+	}, {
+		name:   "admin config unauthorized",
+		url:    "http://localhost:8080/api/v1/admin/config",
+		method: http.MethodGet,
+		resp: func(t *testing.T, res *http.Response) {
+			expC := http.StatusUnauthorized
+
+			if res.StatusCode != expC {
+				t.Errorf("Status code expected: %v, got: %v",
+					expC, res.StatusCode)
+			}
+		},
+	}, {
+		name:   "admin bootstrap invalid token",
+		url:    "http://localhost:8080/api/v1/admin/bootstrap",
+		method: http.MethodPost,
+		header: map[string]string{"Authorization": "Bearer invalid"},
+		body:   map[string]any{"user_id": "admin"},
+		resp: func(t *testing.T, res *http.Response) {
+			expC := http.StatusUnauthorized
+
+			if res.StatusCode != expC {
+				t.Errorf("Status code expected: %v, got: %v",
+					expC, res.StatusCode)
+			}
+		},
+	}, {
+		name:   "admin chains repair unauthorized",
+		url:    "http://localhost:8080/api/v1/admin/chains/repair",
+		method: http.MethodPost,
+		header: map[string]string{"Authorization": "Bearer invalid"},
+		resp: func(t *testing.T, res *http.Response) {
+			expC := http.StatusUnauthorized
+
+			if res.StatusCode != expC {
+				t.Errorf("Status code expected: %v, got: %v",
+					expC, res.StatusCode)
+			}
+		},
+	}, {
+		name:   "billing webhook not configured",
+		url:    "http://localhost:8080/api/v1/billing/webhook",
+		method: http.MethodPost,
+		header: map[string]string{"Stripe-Signature": "t=1,v1=test"},
+		body:   map[string]any{"type": "customer.subscription.deleted"},
+		resp: func(t *testing.T, res *http.Response) {
+			expC := http.StatusServiceUnavailable
+
+			if res.StatusCode != expC {
+				t.Errorf("Status code expected: %v, got: %v",
+					expC, res.StatusCode)
+			}
+		},
+	}, {
+		name:   "cors preflight",
+		url:    "http://localhost:8080/api/v1/health",
+		method: http.MethodOptions,
+		header: map[string]string{
+			"Origin":                        "https://app.game2d.ai",
+			"Access-Control-Request-Method": http.MethodGet,
+		},
+		resp: func(t *testing.T, res *http.Response) {
+			expC := http.StatusNoContent
+
+			if res.StatusCode != expC {
+				t.Errorf("Status code expected: %v, got: %v",
+					expC, res.StatusCode)
+			}
+
+			expO := "https://app.game2d.ai"
+
+			if v := res.Header.Get("Access-Control-Allow-Origin"); v != expO {
+				t.Errorf("Allow origin expected: %v, got: %v", expO, v)
+			}
+
+			if v := res.Header.Get("Access-Control-Allow-Methods"); v == "" {
+				t.Errorf("Expected allow methods header")
+			}
+		},
+	}, {
+		name:   "cors disallowed origin",
+		url:    "http://localhost:8080/api/v1/health",
+		method: http.MethodGet,
+		header: map[string]string{
+			"Origin": "https://example.com",
+		},
+		resp: func(t *testing.T, res *http.Response) {
+			if v := res.Header.Get("Access-Control-Allow-Origin"); v != "" {
+				t.Errorf("Allow origin expected: none, got: %v", v)
+			}
+		},
 	}}
 
 	for _, tt := range tests {
@@ -106,7 +410,7 @@ func TestStatsServer(t *testing.T) {
 				br = buf
 			}
 
-			r, err := http.NewRequest(tt.method, tt.url, br)
+			r, err := http.NewRequest(tt.method, testURL(tt.url), br)
 			if err != nil {
 				t.Fatal("Failed to initialize request", err)
 			}
@@ -0,0 +1,197 @@
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+
+	"github.com/dhaifley/game2d/errors"
+	"github.com/dhaifley/game2d/request"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// Account tenancies, which determine where the data of accounts is stored.
+// The data of shared accounts is stored in the collections of the configured
+// database, of database accounts in a database of their own, and of prefix
+// accounts in collections of their own, in the configured database.
+const (
+	TenancyShared   = "shared"
+	TenancyDatabase = "database"
+	TenancyPrefix   = "prefix"
+)
+
+// sharedCollections are the collections which are always stored in the
+// configured database, whatever the tenancy of the account, because they
+// identify accounts and users, or are used by all accounts. Games shared
+// publicly are only found in the configured database, so only shared accounts
+// are entitled to share games publicly.
+var sharedCollections = map[string]bool{
+	"accounts":        true,
+	"users":           true,
+	"flags":           true,
+	"automation_jobs": true,
+	"domains":         true,
+}
+
+// tenant values identify where the data of an account is stored. The store is
+// the name of the database, or the prefix of the collections, of the account.
+type tenant struct {
+	Tenancy string `bson:"tenancy"`
+	Store   string `bson:"tenant_store"`
+}
+
+// sharedTenant is the tenant of accounts whose data is stored in the
+// configured database.
+var sharedTenant = &tenant{Tenancy: TenancyShared}
+
+// validTenancy returns whether a tenancy is known.
+func validTenancy(tenancy string) bool {
+	switch tenancy {
+	case TenancyShared, TenancyDatabase, TenancyPrefix:
+		return true
+	}
+
+	return false
+}
+
+// tenantStore returns the name of the database, or the prefix of the
+// collections, in which the data of an account with a tenancy is stored. The
+// names are derived from a hash of the account ID, since account IDs may
+// contain characters which are not valid in database names.
+func (s *Server) tenantStore(tenancy, accountID string) string {
+	h := sha256.Sum256([]byte(accountID))
+
+	name := "t" + hex.EncodeToString(h[:8])
+
+	switch tenancy {
+	case TenancyDatabase:
+		return s.cfg.DBDatabase() + "_" + name
+	case TenancyPrefix:
+		return name + "_"
+	}
+
+	return ""
+}
+
+// accountTenant returns where the data of the account in a context is stored.
+// Since tenancies are only set when accounts are created, they are kept once
+// found.
+func (s *Server) accountTenant(ctx context.Context) (*tenant, error) {
+	aID, err := request.ContextAccountID(ctx)
+	if err != nil || aID == "" || aID == request.SystemAccount {
+		return sharedTenant, nil
+	}
+
+	if v, ok := s.tenants.Load(aID); ok {
+		if t, ok := v.(*tenant); ok {
+			return t, nil
+		}
+	}
+
+	db := s.DB()
+	if db == nil {
+		return sharedTenant, nil
+	}
+
+	var res *tenant
+
+	if err := db.Collection("accounts").FindOne(ctx,
+		bson.M{"id": aID},
+		options.FindOne().SetProjection(bson.M{
+			"_id":          0,
+			"tenancy":      1,
+			"tenant_store": 1,
+		})).Decode(&res); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return sharedTenant, nil
+		}
+
+		return nil, errors.Wrap(err, errors.ErrDatabase,
+			"unable to get account tenancy",
+			"account_id", aID)
+	}
+
+	if res.Tenancy == "" || res.Store == "" {
+		res = sharedTenant
+	}
+
+	s.tenants.Store(aID, res)
+
+	return res, nil
+}
+
+// tenantCollection returns a database collection of a tenant.
+func (s *Server) tenantCollection(t *tenant, name string) *mongo.Collection {
+	s.RLock()
+
+	c := s.db
+
+	s.RUnlock()
+
+	if c == nil {
+		return nil
+	}
+
+	if t == nil || sharedCollections[name] {
+		return c.Database(s.cfg.DBDatabase()).Collection(name)
+	}
+
+	switch t.Tenancy {
+	case TenancyDatabase:
+		return c.Database(t.Store).Collection(name)
+	case TenancyPrefix:
+		return c.Database(s.cfg.DBDatabase()).Collection(t.Store + name)
+	}
+
+	return c.Database(s.cfg.DBDatabase()).Collection(name)
+}
+
+// collection returns a database collection for the account in a context,
+// stored according to the tenancy of the account. An error is returned if the
+// tenancy of the account can not be found, so that its data is never read
+// from, or written to, the collections of another tenant.
+func (s *Server) collection(ctx context.Context,
+	name string,
+) (*mongo.Collection, error) {
+	t := sharedTenant
+
+	if !sharedCollections[name] {
+		var err error
+
+		if t, err = s.accountTenant(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	c := s.tenantCollection(t, name)
+	if c == nil {
+		return nil, errors.New(errors.ErrUnavailable,
+			"database unavailable",
+			"collection", name).
+			WithReason(errors.ReasonDatabaseUnavailable)
+	}
+
+	return c, nil
+}
+
+// initTenant keeps the tenancy of a created account, and creates the indexes
+// of its collections, if they are not shared.
+func (s *Server) initTenant(ctx context.Context, a *Account) {
+	if a == nil || !validTenancy(a.Tenancy.Value) {
+		return
+	}
+
+	t := &tenant{Tenancy: a.Tenancy.Value, Store: a.TenantStore.Value}
+
+	if t.Tenancy == TenancyShared || t.Store == "" {
+		s.tenants.Store(a.ID.Value, sharedTenant)
+
+		return
+	}
+
+	if _, ok := s.tenants.Swap(a.ID.Value, t); !ok {
+		s.createIndexes(ctx, t)
+	}
+}
@@ -0,0 +1,94 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dhaifley/game2d/config"
+	"github.com/dhaifley/game2d/request"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+func TestCollectionTenancyUnavailable(t *testing.T) {
+	t.Parallel()
+
+	svr, err := NewServer(config.NewDefault(), nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The shared database can not be reached, so the tenancy of the account
+	// can not be found.
+	db, err := mongo.Connect(options.Client().
+		SetHosts([]string{"127.0.0.1:1"}).
+		SetServerSelectionTimeout(100 * time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { _ = db.Disconnect(context.Background()) })
+
+	svr.SetDB(db)
+
+	ctx := context.WithValue(context.Background(), request.CtxKeyAccountID,
+		"tenancy-test")
+
+	if _, err := svr.accountTenant(ctx); err == nil {
+		t.Fatal("Expected error getting account tenancy")
+	}
+
+	if c, err := svr.collection(ctx, "games"); err == nil {
+		t.Fatalf("Expected error getting collection, got: %v", c.Name())
+	}
+
+	if _, ok := svr.tenants.Load("tenancy-test"); ok {
+		t.Error("Expected account tenancy not to be kept")
+	}
+
+	c, err := svr.collection(ctx, "accounts")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if c.Database().Client() != db {
+		t.Error("Expected shared collection to use the shared database")
+	}
+
+	svr.SetDB(nil)
+
+	if _, err := svr.collection(ctx, "accounts"); err == nil {
+		t.Error("Expected error getting collection without a database")
+	}
+}
+
+func TestTenancyEntitlements(t *testing.T) {
+	t.Parallel()
+
+	svr, err := NewServer(config.NewDefault(), nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for tenancy, exp := range map[string]bool{
+		"":              true,
+		TenancyShared:   true,
+		TenancyDatabase: false,
+		TenancyPrefix:   false,
+	} {
+		e := svr.accountEntitlements(&Account{
+			Plan: request.FieldString{
+				Set: true, Valid: true, Value: PlanPro,
+			},
+			Tenancy: request.FieldString{
+				Set: true, Valid: true, Value: tenancy,
+			},
+		})
+
+		if e.PublicGames != exp {
+			t.Errorf("Expected public games for tenancy %q: %v, got: %v",
+				tenancy, exp, e.PublicGames)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package server
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// useTLS returns whether the server terminates TLS connections.
+func (s *Server) useTLS() bool {
+	return s.cfg.ServerAutocert() ||
+		(s.cfg.ServerCert() != "" && s.cfg.ServerKey() != "")
+}
+
+// initTLS configures TLS termination for the server. If automatic certificates
+// are enabled, certificates for the server host, and for the verified custom
+// domains of accounts, are obtained using ACME. If a redirect address is
+// configured, plaintext HTTP requests received on it are redirected to HTTPS.
+func (s *Server) initTLS() {
+	if !s.useTLS() {
+		return
+	}
+
+	var h http.Handler = http.HandlerFunc(s.redirectHTTPS)
+
+	if s.cfg.ServerAutocert() {
+		m := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: s.domainHostPolicy,
+			Cache:      autocert.DirCache(s.cfg.ServerAutocertDir()),
+			Email:      s.cfg.ServerAutocertEmail(),
+		}
+
+		s.certs = m
+
+		s.Server.TLSConfig = m.TLSConfig()
+
+		// The HTTP handler also responds to ACME HTTP-01 challenges.
+		h = m.HTTPHandler(h)
+	}
+
+	if addr := s.cfg.ServerRedirectAddress(); addr != "" {
+		s.redirect = &http.Server{
+			Addr:              addr,
+			Handler:           h,
+			IdleTimeout:       s.Server.IdleTimeout,
+			ReadHeaderTimeout: s.Server.ReadHeaderTimeout,
+		}
+	}
+}
+
+// redirectHTTPS redirects a plaintext HTTP request to the HTTPS server.
+func (s *Server) redirectHTTPS(w http.ResponseWriter, r *http.Request) {
+	host := r.Host
+
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	if len(s.addr) > 0 {
+		if _, port, err := net.SplitHostPort(s.addr[0]); err == nil &&
+			port != "" && port != "443" {
+			host = net.JoinHostPort(host, port)
+		}
+	}
+
+	u := url.URL{
+		Scheme:   "https",
+		Host:     host,
+		Path:     r.URL.Path,
+		RawQuery: r.URL.RawQuery,
+	}
+
+	http.Redirect(w, r, u.String(), http.StatusPermanentRedirect)
+}
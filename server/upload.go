@@ -0,0 +1,184 @@
+package server
+
+import (
+	"encoding/base64"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/dhaifley/game2d/errors"
+	"github.com/dhaifley/game2d/request"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultUploadMemory is the maximum number of bytes of an upload request which
+// are stored in memory, with the remainder stored in temporary files.
+const DefaultUploadMemory = 8 * 1024 * 1024
+
+// readUploadPart reads the contents of an uploaded file.
+func readUploadPart(fh *multipart.FileHeader) ([]byte, error) {
+	f, err := fh.Open()
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrInvalidRequest,
+			"unable to open uploaded file",
+			"file", fh.Filename)
+	}
+
+	defer f.Close()
+
+	b, err := io.ReadAll(f)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrInvalidRequest,
+			"unable to read uploaded file",
+			"file", fh.Filename)
+	}
+
+	return b, nil
+}
+
+// uploadedGame assembles a game from a multipart form. The definition part
+// contains the game definition, as YAML or JSON. The optional script part
+// contains the Lua script, and any image parts contain SVG images, which are
+// added to the game using their file names, without extensions, as their IDs.
+func uploadedGame(form *multipart.Form) (*Game, error) {
+	var db []byte
+
+	if fhs := form.File["definition"]; len(fhs) > 0 {
+		b, err := readUploadPart(fhs[0])
+		if err != nil {
+			return nil, err
+		}
+
+		db = b
+	} else if v := form.Value["definition"]; len(v) > 0 {
+		db = []byte(v[0])
+	}
+
+	if len(db) == 0 {
+		return nil, errors.New(errors.ErrInvalidRequest,
+			"missing game definition")
+	}
+
+	g := &Game{}
+
+	// YAML is a superset of JSON, so either format can be decoded.
+	if err := yaml.Unmarshal(db, g); err != nil {
+		return nil, errors.Wrap(err, errors.ErrInvalidRequest,
+			"unable to decode game definition")
+	}
+
+	if fhs := form.File["script"]; len(fhs) > 0 {
+		b, err := readUploadPart(fhs[0])
+		if err != nil {
+			return nil, err
+		}
+
+		g.Script = request.FieldString{
+			Set: true, Valid: true,
+			Value: base64.StdEncoding.EncodeToString(b),
+		}
+	}
+
+	if fhs := form.File["image"]; len(fhs) > 0 {
+		if !g.Images.Valid || g.Images.Value == nil {
+			g.Images = request.FieldJSON{
+				Set: true, Valid: true, Value: map[string]any{},
+			}
+		}
+
+		for _, fh := range fhs {
+			b, err := readUploadPart(fh)
+			if err != nil {
+				return nil, err
+			}
+
+			name := path.Base(fh.Filename)
+			id := strings.TrimSuffix(name, path.Ext(name))
+
+			if id == "" || id == "." || id == "/" {
+				return nil, errors.New(errors.ErrInvalidRequest,
+					"invalid image file name",
+					"file", fh.Filename)
+			}
+
+			g.Images.Value[id] = map[string]any{
+				"id":   id,
+				"name": id,
+				"data": base64.StdEncoding.EncodeToString(b),
+			}
+		}
+	}
+
+	return g, nil
+}
+
+// postGameUploadHandler is the handler function for creating a game from a
+// multipart form upload, consisting of a game definition, and separate script
+// and image files.
+func (s *Server) postGameUploadHandler(w http.ResponseWriter,
+	r *http.Request,
+) {
+	ctx := r.Context()
+
+	if err := s.checkScope(ctx, request.ScopeGamesWrite); err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	if err := r.ParseMultipartForm(DefaultUploadMemory); err != nil {
+		s.error(errors.Wrap(err, errors.ErrInvalidRequest,
+			"unable to decode multipart request"), w, r)
+
+		return
+	}
+
+	defer r.MultipartForm.RemoveAll()
+
+	req, err := uploadedGame(r.MultipartForm)
+	if err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	aID, err := request.ContextAccountID(ctx)
+	if err != nil {
+		s.error(errors.New(errors.ErrUnauthorized,
+			"unable to get account id from context"), w, r)
+
+		return
+	}
+
+	req.AccountID = request.FieldString{
+		Set: true, Valid: true, Value: aID,
+	}
+
+	res, err := s.createGame(ctx, req)
+	if err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	scheme := "https"
+	if strings.Contains(r.Host, "localhost") {
+		scheme = "http"
+	}
+
+	loc := &url.URL{
+		Scheme: scheme,
+		Host:   r.Host,
+		Path:   path.Join(path.Dir(r.URL.Path), res.ID.Value),
+	}
+
+	w.Header().Set("Location", loc.String())
+	w.WriteHeader(http.StatusCreated)
+
+	if err := s.encode(w, r, res); err != nil {
+		s.error(err, w, r)
+	}
+}
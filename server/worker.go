@@ -0,0 +1,113 @@
+package server
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"text/template"
+
+	"github.com/dhaifley/game2d/errors"
+	"github.com/dhaifley/game2d/static"
+)
+
+// maxOfflineGames is the maximum number of recently played public games which
+// are cached by the service worker for offline play.
+const maxOfflineGames = 20
+
+// workerFiles are the static files precached by the service worker, and the
+// paths they are served on.
+var workerFiles = []struct {
+	name, path string
+}{{
+	name: "game2d.wasm", path: "/game2d.wasm",
+}, {
+	name: "scripts/wasm_exec.js", path: "/scripts/wasm_exec.js",
+}}
+
+// serviceWorker returns the service worker script of the web client. The
+// script is generated once, with a version derived from the files it
+// precaches, so that browsers replace their caches whenever the files change.
+// Files which are not embedded in the server are not precached.
+func (s *Server) serviceWorker() ([]byte, error) {
+	s.workerOnce.Do(func() {
+		h := sha256.New()
+
+		precache := []string{}
+
+		for _, f := range workerFiles {
+			b, err := static.FS.ReadFile(f.name)
+			if err != nil {
+				continue
+			}
+
+			h.Write(b)
+
+			precache = append(precache, f.path)
+		}
+
+		pb, err := json.Marshal(precache)
+		if err != nil {
+			s.workerErr = errors.Wrap(err, errors.ErrServer,
+				"unable to encode service worker files")
+
+			return
+		}
+
+		tb, err := static.FS.ReadFile("sw.js")
+		if err != nil {
+			s.workerErr = errors.Wrap(err, errors.ErrServer,
+				"unable to read service worker template")
+
+			return
+		}
+
+		t, err := template.New("sw").Parse(string(tb))
+		if err != nil {
+			s.workerErr = errors.Wrap(err, errors.ErrServer,
+				"unable to parse service worker template")
+
+			return
+		}
+
+		h.Write(tb)
+
+		buf := &bytes.Buffer{}
+
+		if err := t.Execute(buf, map[string]any{
+			"Version":  hex.EncodeToString(h.Sum(nil)[:8]),
+			"Precache": string(pb),
+			"MaxGames": maxOfflineGames,
+		}); err != nil {
+			s.workerErr = errors.Wrap(err, errors.ErrServer,
+				"unable to create service worker")
+
+			return
+		}
+
+		s.worker = buf.Bytes()
+	})
+
+	return s.worker, s.workerErr
+}
+
+// getServiceWorkerHandler serves the service worker script of the web client.
+// It is not cached, so that browsers find new versions of it.
+func (s *Server) getServiceWorkerHandler(w http.ResponseWriter,
+	r *http.Request,
+) {
+	b, err := s.serviceWorker()
+	if err != nil {
+		s.error(err, w, r)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/javascript; charset=UTF-8")
+	w.Header().Set("Cache-Control", "no-cache")
+
+	if _, err := w.Write(b); err != nil {
+		s.error(err, w, r)
+	}
+}
@@ -0,0 +1,80 @@
+// The game2d service worker caches the WASM client runtime, so that games load
+// instantly, and the most recently played public games, so that they can be
+// played again offline. It is generated by the server, and its version changes
+// whenever the runtime changes, so that the previous caches are replaced.
+const CACHE = "game2d-runtime-{{.Version}}";
+const GAMES = "game2d-games";
+const PRECACHE = {{.Precache}};
+const MAX_GAMES = {{.MaxGames}};
+
+self.addEventListener("install", (event) => {
+  event.waitUntil(caches.open(CACHE)
+    .then((cache) => cache.addAll(PRECACHE))
+    .then(() => self.skipWaiting()));
+});
+
+self.addEventListener("activate", (event) => {
+  event.waitUntil(caches.keys()
+    .then((keys) => Promise.all(keys
+      .filter((key) => key !== CACHE && key !== GAMES)
+      .map((key) => caches.delete(key))))
+    .then(() => self.clients.claim()));
+});
+
+// trimGames removes the least recently played games from the cache, since
+// games are cached again whenever they are played.
+async function trimGames(cache) {
+  const keys = await cache.keys();
+
+  for (const key of keys.slice(0, Math.max(keys.length - MAX_GAMES, 0))) {
+    await cache.delete(key);
+  }
+}
+
+// playGame fetches the page of a public game, caching it, or else serves the
+// cached page when offline.
+async function playGame(request) {
+  const cache = await caches.open(GAMES);
+
+  try {
+    const response = await fetch(request);
+
+    if (response.ok) {
+      await cache.delete(request);
+      await cache.put(request, response.clone());
+      await trimGames(cache);
+    }
+
+    return response;
+  } catch (err) {
+    const cached = await cache.match(request);
+
+    if (cached) {
+      return cached;
+    }
+
+    throw err;
+  }
+}
+
+self.addEventListener("fetch", (event) => {
+  const url = new URL(event.request.url);
+
+  if (event.request.method !== "GET" || url.origin !== self.location.origin) {
+    return;
+  }
+
+  if (PRECACHE.includes(url.pathname)) {
+    event.respondWith(caches.match(event.request,
+      { cacheName: CACHE, ignoreSearch: true })
+      .then((cached) => cached || fetch(event.request)));
+
+    return;
+  }
+
+  // Private games are played using share tokens, and are never cached.
+  if (/^\/embed\/[^/]+$/.test(url.pathname) &&
+    !url.searchParams.has("token")) {
+    event.respondWith(playGame(event.request));
+  }
+});